@@ -1,23 +1,193 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver/azureauth"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver/kube"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver/report"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver/server"
+	_ "github.com/Azure/karpenter-provider-azure/pkg/resolver/zstd" // zstd-compressed traces and workload files
+	"k8s.io/apimachinery/pkg/api/resource"
+	_ "modernc.org/sqlite" // driver of --sqlite; pure Go, no cgo required
 )
 
 func main() {
+	code, _ := run(os.Args[1:], os.Stderr) // run reports its own errors
+	os.Exit(code)
+}
+
+/*
+run runs the CLI with args and returns its exit code (see resolver.ExitOK and the other exit
+codes) and the error that caused it, which it has already reported on stderr: as text, or as a
+resolver.FailureSummary with --output=json.
+*/
+func run(args []string, stderr io.Writer) (code int, err error) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			alert, err := runDiff(args[1:], os.Stdout, stderr)
+			if err != nil {
+				fmt.Fprintf(stderr, "diff failed: %v\n", err)
+				return resolver.ExitInputError, err
+			}
+			if alert {
+				return resolver.ExitDistributionShift, nil
+			}
+			return resolver.ExitOK, nil
+		case "run":
+			code, err := runScenario(args[1:], stderr)
+			if err != nil {
+				fmt.Fprintf(stderr, "run failed: %v\n", err)
+			}
+			return code, err
+		case "replay":
+			code, err := runReplay(args[1:], os.Stdout)
+			if err != nil {
+				fmt.Fprintf(stderr, "replay failed: %v\n", err)
+			}
+			return code, err
+		case "sku-diff":
+			exceeded, err := runSKUDiff(args[1:], os.Stdout, stderr)
+			if err != nil {
+				fmt.Fprintf(stderr, "sku-diff failed: %v\n", err)
+				return resolver.ExitInputError, err
+			}
+			if exceeded {
+				return resolver.ExitPriceIncrease, nil
+			}
+			return resolver.ExitOK, nil
+		case "convert":
+			if err := runConvert(args[1:], os.Stdout, stderr); err != nil {
+				fmt.Fprintf(stderr, "convert failed: %v\n", err)
+				return resolver.ExitInputError, err
+			}
+			return resolver.ExitOK, nil
+		case "whatif":
+			if err := runWhatIf(args[1:], os.Stdout, stderr); err != nil {
+				fmt.Fprintf(stderr, "whatif failed: %v\n", err)
+				return resolver.ExitInputError, err
+			}
+			return resolver.ExitOK, nil
+		case "recommend":
+			if err := runRecommend(args[1:], os.Stdout, stderr); err != nil {
+				fmt.Fprintf(stderr, "recommend failed: %v\n", err)
+				return resolver.ExitInputError, err
+			}
+			return resolver.ExitOK, nil
+		case "select":
+			if err := runSelect(args[1:], os.Stdout, stderr); err != nil {
+				fmt.Fprintf(stderr, "select failed: %v\n", err)
+				return resolver.ExitInputError, err
+			}
+			return resolver.ExitOK, nil
+		case "selftest":
+			if err := resolver.SelfTest(os.Stdout); err != nil {
+				return resolver.ExitUnpacked, err // SelfTest printed the failures
+			}
+			return resolver.ExitOK, nil
+		}
+	}
+	fs := flag.NewFlagSet("instance-selection-sim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
 	var (
-		traceSource   = flag.String("trace", "google", "Trace source: google|azure|alibaba|custom")
-		skuFile       = flag.String("sku", "azure_skus.json", "Path to Azure SKU JSON file")
-		maxRows       = flag.Int("max", 1000, "Max workloads to simulate")
-		outFile       = flag.String("out", "", "Optional: output CSV file for results")
-		workloadsFile = flag.String("workloads", "", "Optional: path to custom workloads JSON file")
-		quotaFile     = flag.String("quota", "", "Optional: path to quota JSON file")
+		traceSource   = fs.String("trace", "google", "Trace source: google|azure|alibaba|custom")
+		traceSAS      = fs.String("trace-sas", "", "Optional: SAS token appended to Azure blob trace URLs (default $TRACE_SAS_TOKEN)")
+		traceAuth     = fs.Bool("trace-azure-auth", false, "Authenticate Azure blob trace downloads with the default Azure credential, for private containers")
+		traceURL      = fs.String("trace-url", "", "Optional: download the selected trace from this URL, e.g. an internal mirror (default $TRACE_URL_<SOURCE>, then the public URL)")
+		strategy      = fs.String("strategy", "general", "Selection strategy: general|cpu|memory|io|auto (auto classifies each workload)")
+		algorithm     = fs.String("algorithm", resolver.DefaultPackingAlgorithm, "Packing algorithm: "+strings.Join(resolver.PackingAlgorithms(), "|")+" (online packs in arrival order without sorting)")
+		skuFile       = fs.String("sku", "azure_skus.json", "Path to Azure SKU JSON file, or a comma-separated list of files priced in the same currency")
+		maxRows       = fs.Int("max", 1000, "Max workloads to simulate")
+		outFile       = fs.String("out", "", "Optional: output CSV file for results")
+		markdownFile  = fs.String("markdown", "", "Optional: output markdown report file")
+		jsonFile      = fs.String("json", "", "Optional: output JSON report file")
+		sqliteFile    = fs.String("sqlite", "", "Optional: SQLite database to append the run to")
+		eventsFile    = fs.String("events", "", "Optional: output JSONL file for the event log of the --load-profile hours")
+		hoursPerMonth = fs.Float64("hours-per-month", resolver.DefaultHoursPerMonth, "Uptime per month used for cost projections")
+		spotDiscount  = fs.Float64("spot-discount", 0, "Spot discount off list prices for cost projections, e.g. 0.8")
+		reservedCov   = fs.Float64("reserved-coverage", 0, "Share of on-demand spend covered by reservations for cost projections, e.g. 0.5")
+		reservedDisc  = fs.Float64("reserved-discount", 0.4, "Reservation discount off on-demand prices for cost projections")
+		explain       = fs.Bool("explain-packing", false, "Print why each VM's instance type was selected")
+		costByLabel   = fs.String("cost-by-label", "", "Optional: attribute cost to the values of this workload label, e.g. team")
+		serveAddr     = fs.String("serve", "", "Optional: serve the REST API on this address (e.g. :8080) instead of simulating")
+		families      = fs.String("families", "", "Optional: comma-separated VM families or series the REST API may select, e.g. D,E")
+		maxBody       = fs.Int64("max-body-bytes", server.DefaultMaxBodyBytes, "Maximum REST API request body size")
+		workloadsFile = fs.String("workloads", "", "Optional: path to custom workloads JSON file")
+		workloadFmt   = fs.String("workload-format", "profile", "Schema of --workloads: profile (WorkloadProfile objects, .jsonl for one per line) or preprocessed (workloads_preprocessed.json)")
+		quotaFile     = fs.String("quota", "", "Optional: path to quota JSON file, a family to vCPUs map or az vm list-usage output")
+		strict        = fs.Bool("strict", false, "Fail instead of warn when some workloads cannot run on any SKU, the workloads cannot fit under --quota, or the SKU data looks wrong")
+		quotaFamilies = fs.Bool("quota-strict", false, "Only select VM families with vCPUs left in --quota, as if the others were not enabled")
+		reservedFile  = fs.String("reservations", "", "Optional: path to capacity reservations JSON file, filled before pay-as-you-go VMs")
+		baselineFile  = fs.String("baseline", "", "Optional: path to a JSON list of {SKU, Count} of the current fleet, to compare the simulated packing's cost and capacity to")
+		headroom      = fs.String("headroom", "", "Optional: spare capacity to keep, e.g. cpu=10%,memory=10%")
+		splitMaxCPU   = fs.Int("split-max-cpu", 0, "Optional: split workloads requesting more vCPUs into equal replicas (0 = never)")
+		splitMaxMem   = fs.Float64("split-max-mem", 0, "Optional: split workloads requesting more GiB of memory into equal replicas (0 = never)")
+		minZones      = fs.Int("min-zones", 0, "Optional: only select SKUs offered in at least this many availability zones, and warn about VMs of SKUs in fewer (0 = off)")
+		fitMargin     = fs.String("fit-margin", "", "Optional: share of each VM to keep free when packing, e.g. cpu=5%,memory=5%")
+		disable       = fs.String("disable-filters", "", "Optional: comma-separated selection filters to turn off, e.g. zone to pack zone-pinned workloads onto any SKU")
+		unknownGPU    = fs.Bool("allow-unknown-gpu-type", false, "Let typed GPU workloads select SKUs whose GPU model is missing from the SKU data and cannot be inferred, at a lower score")
+		basis         = fs.String("basis", "requests", "Size workloads by: requests|usage (observed usage, for a rightsizing estimate compared with requests)")
+		basisMargin   = fs.Float64("margin", 0, "Optional: safety margin in percent added to usage with --basis=usage, e.g. 20")
+		overcommit    = fs.String("overcommit", "requests", "Bound packing by workload limits: requests (ignore limits)|limits (size workloads by their limits)|capped (by requests, with the limits on each VM capped at --limit-ratio)")
+		limitRatio    = fs.Float64("limit-ratio", 0, "Most a VM's workload limits may add up to with --overcommit=capped, as a multiple of its vCPUs and memory, e.g. 2")
+		minFit        = fs.Float64("min-fit", 0, "Optional: leave workloads unpacked whose best instance type they would use less than this share of, e.g. 0.1 rejects VMs 10x oversized (0 = off)")
+		sensitivity   = fs.String("sensitivity", "", "Optional: comma-separated price perturbations in percent, e.g. 5,10, to repack under and report SKU mix and cost robustness for")
+		loadProfile   = fs.String("load-profile", "", "Optional: path to a daily load profile JSON file, {\"hourly\": [24 multipliers from 0 to 1]}, to report hourly VM counts and a 24h cost under")
+		approx        = fs.Bool("approx", false, "Pack shape-identical workloads as groups, for huge traces of small workloads, and report how much more than exact packing that may cost")
+		archCompare   = fs.Bool("arch-compare", false, "Also pack onto amd64 SKUs only and report the saving of letting arch-agnostic workloads run on arm64 SKUs, and which workloads would need arm64 images")
+		maxPerVM      = fs.Int("max-workloads-per-vm", 0, "Optional: pack at most this many workloads onto one VM, or the SKU's max pods where lower (0 = unlimited)")
+		exploreTopK   = fs.Int("explore-top-k", 0, "Optional: pick randomly among the top K candidates weighted by score (0 = always pick the best)")
+		exploreTemp   = fs.Float64("explore-temperature", 0.1, "Softmax temperature for --explore-top-k (0 = always pick the best)")
+		seed          = fs.Int64("seed", 1, "Random seed for exploration and --sensitivity perturbations")
+		sortKey       = fs.String("sort-key", "legacy", "Order workloads are packed in, largest first: legacy (vCPUs plus GiB)|drf (dominant share of the largest SKU)|cpu|memory")
+		scoreVersion  = fs.String("score-version", "legacy", "Scoring formula: legacy|normalized (scores in [0,1], comparable across runs)")
+		weightsPreset = fs.String("weights-preset", "", weightsPresetUsage())
+		weightsFile   = fs.String("weights", "", "Optional: path to a JSON file of ScoringWeights fields, overriding those of --weights-preset, e.g. {\"Cost\": 0.6}")
+		selCache      = fs.String("selection-cache", "", "Optional: file persisting instance type rankings between runs, reused while the SKUs, filters and weights are unchanged")
+		preferFamily  = fs.String("prefer-families", "", "Optional: comma-separated preferred VM families in order, e.g. D,E")
+		histogram     = fs.String("histogram-buckets", "", "Optional: utilization histogram buckets, a count (e.g. 10) or ascending edges in percent (e.g. 0,50,80,100)")
+		failUnpacked  = fs.Bool("fail-on-unpacked", true, "Exit with status 1 when some workloads were not packed (a packing that places nothing always fails)")
+		outputFormat  = fs.String("output", "text", "Format of the failure summary on stderr: text|json")
+		verbose       = fs.Bool("verbose", false, "Print the run's metadata: tool version, git ref ($GIT_REF), hostname, start and end time and duration")
 	)
-	flag.Parse()
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return resolver.ExitOK, nil
+		}
+		return resolver.ExitInputError, err
+	}
+	if *outputFormat != "text" && *outputFormat != "json" {
+		err := fmt.Errorf("unknown --output %q", *outputFormat)
+		fmt.Fprintln(stderr, err)
+		return resolver.ExitInputError, err
+	}
+	var summary resolver.FailureSummary
+	defer func() {
+		if err == nil {
+			return
+		}
+		if *outputFormat == "json" {
+			s := resolver.NewFailureSummary(code, err)
+			s.Unpacked, s.Reasons = summary.Unpacked, summary.Reasons
+			s.Write(stderr)
+			return
+		}
+		fmt.Fprintln(stderr, err)
+	}()
 
 	var src resolver.TraceSource
 	switch *traceSource {
@@ -28,52 +198,778 @@ func main() {
 	case "alibaba":
 		src = resolver.TraceAlibaba
 	case "custom":
-		src = resolver.TraceSource("custom")
+		src = resolver.TraceCustom
+	default:
+		return resolver.ExitInputError, fmt.Errorf("unknown trace source: %s", *traceSource)
+	}
+
+	switch s := resolver.SelectionStrategy(*strategy); s {
+	case resolver.StrategyGeneralPurpose, resolver.StrategyCPUIntensive, resolver.StrategyMemoryIntensive, resolver.StrategyIOIntensive, resolver.StrategyAuto:
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown trace source: %s\n", *traceSource)
-		os.Exit(1)
+		return resolver.ExitInputError, fmt.Errorf("unknown strategy: %s", s)
+	}
+
+	if *quotaFamilies && *quotaFile == "" {
+		return resolver.ExitInputError, fmt.Errorf("--quota-strict requires --quota")
+	}
+	quota, quotaWarnings, err := resolver.LoadQuotaWithWarnings(*quotaFile)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("failed to load quota: %w", err)
+	}
+	for _, w := range quotaWarnings {
+		fmt.Fprintf(stderr, "Warning: %s\n", w)
+	}
+	reservations, err := resolver.LoadCapacityReservations(*reservedFile)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("failed to load capacity reservations: %w", err)
+	}
+	baseline, err := resolver.LoadBaselineFleet(*baselineFile)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("failed to load baseline fleet: %w", err)
+	}
+	headroomSpec, err := resolver.ParseHeadroomSpec(*headroom)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --headroom: %w", err)
+	}
+	margin, err := resolver.ParseFitMargin(*fitMargin)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --fit-margin: %w", err)
+	}
+	if err := resolver.ValidateMinFit(*minFit); err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --min-fit: %w", err)
+	}
+	if err := resolver.ValidatePackingAlgorithm(*algorithm); err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --algorithm: %w", err)
+	}
+	if err := resolver.ValidateMaxWorkloadsPerVM(*maxPerVM); err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --max-workloads-per-vm: %w", err)
+	}
+	version, err := resolver.ParseScoreVersion(*scoreVersion)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --score-version: %w", err)
+	}
+	weights, err := resolver.LoadScoringWeights(*weightsPreset, *weightsFile)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --weights-preset or --weights: %w", err)
+	}
+	order, err := resolver.ParseSortKey(*sortKey)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --sort-key: %w", err)
+	}
+	packingBasis, err := resolver.ParsePackingBasis(*basis, *basisMargin)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --basis: %w", err)
+	}
+	overcommitPolicy, err := resolver.ParseOvercommitPolicy(*overcommit, *limitRatio)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --overcommit: %w", err)
+	}
+	if overcommitPolicy.Limits && packingBasis.Usage {
+		return resolver.ExitInputError, fmt.Errorf("--overcommit=limits cannot be combined with --basis=usage")
+	}
+	perturbations, err := resolver.ParseSensitivity(*sensitivity)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --sensitivity: %w", err)
+	}
+	var profile *resolver.LoadProfile
+	if *loadProfile != "" {
+		if profile, err = resolver.ReadLoadProfile(*loadProfile); err != nil {
+			return resolver.ExitInputError, err
+		}
+	} else if *eventsFile != "" {
+		return resolver.ExitInputError, fmt.Errorf("--events requires --load-profile")
+	}
+	workloadFormat, err := resolver.ParseWorkloadFormat(*workloadFmt)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --workload-format: %w", err)
+	}
+	histogramEdges, err := resolver.ParseHistogramEdges(*histogram)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("invalid --histogram-buckets: %w", err)
+	}
+	cfg := resolver.Config{
+		Algorithm:              *algorithm,
+		Approx:                 *approx,
+		Strategy:               resolver.SelectionStrategy(*strategy),
+		Quota:                  quota,
+		StrictQuota:            *strict,
+		StrictRequirements:     *strict,
+		StrictSKUs:             *strict,
+		QuotaFamiliesOnly:      *quotaFamilies,
+		CapacityReservations:   reservations,
+		Headroom:               headroomSpec,
+		FitMarginPercent:       margin,
+		MinFit:                 *minFit,
+		MaxWorkloadsPerVM:      *maxPerVM,
+		AllowUnknownGPUType:    *unknownGPU,
+		MinZones:               *minZones,
+		SplitMaxCPU:            *splitMaxCPU,
+		SplitMaxMemoryGiB:      *splitMaxMem,
+		ExplorationTopK:        *exploreTopK,
+		ExplorationTemperature: *exploreTemp,
+		Seed:                   *seed,
+		SortKey:                order,
+		ScoreVersion:           version,
+		Weights:                weights,
+		WorkloadFormat:         workloadFormat,
+		Basis:                  packingBasis,
+		Overcommit:             overcommitPolicy,
+		Sensitivity:            perturbations,
+		LoadProfile:            profile,
+		ArchComparison:         *archCompare,
+		Baseline:               baseline,
+		CostLabelKey:           *costByLabel,
+		WithAudit:              *explain,
+		HistogramEdges:         histogramEdges,
+		Projection: resolver.ProjectionOptions{
+			HoursPerMonth: *hoursPerMonth,
+			SpotDiscount:  *spotDiscount,
+			Reserved:      resolver.ReservedCoverage{Fraction: *reservedCov, Discount: *reservedDisc},
+		},
+	}
+	if *preferFamily != "" {
+		cfg.FamilyPreferences = strings.Split(*preferFamily, ",")
+	}
+	if *disable != "" {
+		cfg.DisableFilters = strings.Split(*disable, ",")
+	}
+	if *traceURL != "" {
+		cfg.Trace.URLs = map[resolver.TraceSource]string{src: *traceURL}
+	}
+	cfg.Trace.SASToken = *traceSAS
+	if *traceAuth {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return resolver.ExitInputError, fmt.Errorf("azure credential: %w", err)
+		}
+		cfg.Trace.Credential = azureauth.TokenSource(cred)
 	}
 
+	if *serveAddr != "" {
+		if err := serve(*serveAddr, *skuFile, *families, *maxBody, cfg); err != nil {
+			return resolver.ExitInputError, fmt.Errorf("server failed: %w", err)
+		}
+		return resolver.ExitOK, nil
+	}
+	if *selCache != "" {
+		if cfg.SelectionCache, err = resolver.LoadSelectionCache(*selCache); err != nil {
+			return resolver.ExitInputError, err
+		}
+	}
+	out := outputs{csv: *outFile, markdown: *markdownFile, json: *jsonFile, sqlite: *sqliteFile, events: *eventsFile, failOnUnpacked: *failUnpacked, verbose: *verbose}
+
 	// If custom workloads file is provided, use it
-	if src == "custom" && *workloadsFile != "" {
-		result, naive, err := resolver.RunCustomWorkloadSimulationWithQuota(*workloadsFile, *skuFile, *quotaFile)
+	start := time.Now()
+	var result, naive resolver.SimulationResult
+	if src == resolver.TraceCustom && *workloadsFile != "" {
+		result, naive, err = resolver.RunCustomWorkloadSimulationWithConfig(*workloadsFile, *skuFile, cfg)
+	} else {
+		result, naive, err = resolver.RunTraceSimulationWithConfig(src, *skuFile, *maxRows, cfg)
+	}
+	if err != nil {
+		return resolver.ExitCodeFor(err), fmt.Errorf("simulation failed: %w", err)
+	}
+	if cfg.SelectionCache != nil {
+		if err := cfg.SelectionCache.Save(); err != nil {
+			return resolver.ExitOutputError, err
+		}
+	}
+	summary = summary.WithUnpacked(result)
+	return writeOutputs(out, result, naive, resolver.NewRunMetadata(start, time.Now()))
+}
+
+/*
+runDiff implements "diff [-sku skus.json] [-result name] [-alert-distance d] before.json after.json":
+it compares the per-VM detail of one result of two JSON reports (see -json) with
+ComparePackingResults and reports whether the SKU distribution shifted by more than d. The
+metadata of both reports is printed first, to tell which builds produced them.
+*/
+func runDiff(args []string, stdout, stderr io.Writer) (bool, error) {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	skuFile := fs.String("sku", "", "Optional: SKU JSON file(s) to look up VM capacity for utilization deltas")
+	name := fs.String("result", "NewAlgorithm", "Result of each report to compare")
+	alertDistance := fs.Float64("alert-distance", -1, "Optional: exit with status 5 when the SKU distribution distance exceeds this, from 0 to 1 (negative disables)")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	if fs.NArg() != 2 {
+		return false, fmt.Errorf("usage: diff [-sku skus.json] [-result name] [-alert-distance d] before.json after.json")
+	}
+	var skus []resolver.AzureInstanceSpec
+	if *skuFile != "" {
+		ds, err := resolver.LoadSKUDatasets(*skuFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Simulation failed: %v\n", err)
-			os.Exit(2)
+			return false, fmt.Errorf("load skus: %w", err)
 		}
-		if *outFile != "" {
-			f, err := os.Create(*outFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to create output file: %v\n", err)
-				os.Exit(3)
-			}
-			defer f.Close()
-			fmt.Fprintf(f, "Strategy,VMs Used,Total Cost,Avg CPU Util (%),Avg Mem Util (%)\n")
-			fmt.Fprintf(f, "NewAlgorithm,%d,%.2f,%.1f,%.1f\n", result.VMsUsed, result.TotalCost, result.AvgCPU, result.AvgMem)
-			fmt.Fprintf(f, "Naive,%d,%.2f,%.1f,%.1f\n", naive.VMsUsed, naive.TotalCost, naive.AvgCPU, naive.AvgMem)
-			fmt.Printf("Results written to %s\n", *outFile)
+		skus = ds.SKUs
+	}
+	var packings [2]resolver.PackingResult
+	for i, path := range fs.Args() {
+		result, metadata, err := readReportResult(path, *name)
+		if err != nil {
+			return false, err
 		}
-		return
+		packings[i] = resolver.PackingFromDetail(result, skus)
+		printMetadata(stdout, []string{"Before", "After"}[i], metadata)
+	}
+	c := resolver.ComparePackingResults(packings[0], packings[1])
+	fmt.Fprint(stdout, c)
+	if *alertDistance >= 0 && c.DistributionDistance > *alertDistance {
+		fmt.Fprintf(stderr, "SKU distribution shifted by %.3f, more than %.3f\n", c.DistributionDistance, *alertDistance)
+		return true, nil
+	}
+	return false, nil
+}
+
+/*
+runConvert implements "convert -trace source [-in trace.csv] [-out workloads.json] [flags]": it
+converts a trace, downloaded unless -in is given, to a workloads file the -workloads flag
+loads, written to stdout without -out. See resolver.ConvertTrace.
+*/
+func runConvert(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	traceSource := fs.String("trace", "google", "Trace source: "+strings.Join(resolver.TraceSources(), "|")+" (custom is a CSV with cpu and memory columns)")
+	in := fs.String("in", "", "Optional: trace file to convert, may be gzipped (default: download the trace)")
+	out := fs.String("out", "", "Optional: workloads file to write (default stdout)")
+	format := fs.String("format", "", "Output format: json|jsonl (default jsonl for a .jsonl -out, else json)")
+	maxRows := fs.Int("max", 0, "Optional: max trace rows to read (0 = all)")
+	sample := fs.Float64("sample", 0, "Optional: share of the rows read to keep, e.g. 0.1 (0 = all)")
+	seed := fs.Int64("seed", 1, "Random seed for -sample")
+	cpuUnit := fs.String("cpu-unit", "", "Optional: unit of the CPU column: cores|millicores (default: the source's)")
+	memUnit := fs.String("memory-unit", "", "Optional: unit of the memory column: GiB|MiB|KiB|bytes|GB|MB (default: the source's)")
+	timeUnit := fs.String("time-unit", "", "Optional: unit of the time and duration columns: s|ms|us (default: the source's)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: convert -trace source [-in trace.csv] [-out workloads.json] [flags]")
+	}
+	src := resolver.TraceSource(*traceSource)
+	if !slices.Contains(resolver.TraceSources(), *traceSource) {
+		return fmt.Errorf("unknown trace source: %s", *traceSource)
+	}
+	jsonl := strings.HasSuffix(*out, ".jsonl")
+	switch *format {
+	case "":
+	case "json", "jsonl":
+		jsonl = *format == "jsonl"
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	path := *in
+	if path == "" {
+		if src == resolver.TraceCustom {
+			return fmt.Errorf("-trace custom requires -in")
+		}
+		var err error
+		if path, err = resolver.DownloadTrace(src, ".trace_cache"); err != nil {
+			return err
+		}
+	}
+	workloads, err := resolver.ConvertTraceFile(path, src, resolver.ConvertOptions{
+		MaxRows: *maxRows, Sample: *sample, Seed: *seed,
+		CPUUnit: *cpuUnit, MemoryUnit: *memUnit, TimeUnit: *timeUnit,
+	})
+	if err != nil {
+		return fmt.Errorf("convert %s: %w", path, err)
+	}
+	if *out == "" {
+		return resolver.WriteWorkloads(stdout, workloads, jsonl)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := resolver.WriteWorkloads(f, workloads, jsonl); err != nil {
+		f.Close()
+		return err
 	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Wrote %d workloads to %s\n", len(workloads), *out)
+	return nil
+}
 
-	// Run simulation and capture results
-	result, naive, err := resolver.RunTraceSimulationWithQuota(src, *skuFile, *maxRows, *quotaFile)
+/*
+runWhatIf implements "whatif [-sku skus.json] [-strategy s] state.json [pods.json]": it loads a
+cluster state exported with kubectl (see kube.LoadClusterState) and prints how the resolver
+would have packed its pods compared to the actual nodes (see resolver.CompareToActual).
+*/
+func runWhatIf(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("whatif", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	skuFile := fs.String("sku", "azure_skus.json", "Path to Azure SKU JSON file, or a comma-separated list of files priced in the same currency")
+	strategy := fs.String("strategy", "general", "Selection strategy: general|cpu|memory|io|auto (auto classifies each workload)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return fmt.Errorf("usage: whatif [-sku skus.json] [-strategy s] state.json | nodes.json pods.json")
+	}
+	switch s := resolver.SelectionStrategy(*strategy); s {
+	case resolver.StrategyGeneralPurpose, resolver.StrategyCPUIntensive, resolver.StrategyMemoryIntensive, resolver.StrategyIOIntensive, resolver.StrategyAuto:
+	default:
+		return fmt.Errorf("unknown strategy: %s", s)
+	}
+	ds, err := resolver.LoadSKUDatasets(*skuFile)
+	if err != nil {
+		return fmt.Errorf("load skus: %w", err)
+	}
+	state, err := kube.LoadClusterState(ds.SKUs, fs.Args()...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Simulation failed: %v\n", err)
-		os.Exit(2)
+		return err
 	}
+	report := resolver.CompareToActual(state, ds.SKUs, resolver.Config{Strategy: resolver.SelectionStrategy(*strategy)})
+	fmt.Fprint(stdout, report)
+	return nil
+}
+
+// stringsFlag collects the values of a flag that may be repeated, e.g. --require spot --require confidential.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// selectQuery is a single-workload query parsed from the flags of "select".
+type selectQuery struct {
+	skuFile  string
+	strategy resolver.SelectionStrategy
+	top      int
+	workload resolver.WorkloadProfile
+}
 
-	// Optionally write results to CSV
-	if *outFile != "" {
-		f, err := os.Create(*outFile)
+/*
+parseSelect maps the flags of "select" to a query. --cpu and --memory take Kubernetes
+quantities as in a pod's requests, e.g. "500m" or "16Gi": a bare --memory number is bytes.
+--require is repeated for spot, ephemeral-os, nested-virt or confidential, and --capability for
+Azure capabilities as key=value, e.g. --capability AcceleratedNetworking=true.
+*/
+func parseSelect(args []string, stderr io.Writer) (selectQuery, error) {
+	fs := flag.NewFlagSet("select", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var requires, capabilities stringsFlag
+	var (
+		skuFile  = fs.String("sku", "azure_skus.json", "Path to Azure SKU JSON file, or a comma-separated list of files priced in the same currency")
+		strategy = fs.String("strategy", "general", "Selection strategy: general|cpu|memory|io")
+		top      = fs.Int("top", 10, "Number of ranked instance types to list; 0 lists all")
+		cpu      = fs.String("cpu", "1", "CPU request as a Kubernetes quantity, e.g. 4 or 500m")
+		memory   = fs.String("memory", "1Gi", "Memory request as a Kubernetes quantity, e.g. 16Gi")
+		gpu      = fs.Int("gpu", 0, "Number of GPUs")
+		gpuType  = fs.String("gpu-type", "", "GPU model, or comma-separated models in order of preference, e.g. A100")
+		zone     = fs.String("zone", "", "Availability zone, e.g. 2 or eastus-2")
+		arch     = fs.String("arch", "", "Architecture the images are built for: amd64|arm64")
+	)
+	fs.Var(&requires, "require", "Required feature, repeatable: spot|ephemeral-os|nested-virt|confidential")
+	fs.Var(&capabilities, "capability", "Required Azure capability as key=value, repeatable, e.g. AcceleratedNetworking=true")
+	if err := fs.Parse(args); err != nil {
+		return selectQuery{}, err
+	}
+	if fs.NArg() > 0 {
+		return selectQuery{}, fmt.Errorf("usage: select [-sku skus.json] [-strategy s] [-top n] [-cpu q] [-memory q] [-gpu n] [-gpu-type t] [-zone z] [-arch a] [-require r]... [-capability k=v]...")
+	}
+	switch s := resolver.SelectionStrategy(*strategy); s {
+	case resolver.StrategyGeneralPurpose, resolver.StrategyCPUIntensive, resolver.StrategyMemoryIntensive, resolver.StrategyIOIntensive:
+	default:
+		return selectQuery{}, fmt.Errorf("unknown strategy: %s", s)
+	}
+	cpuQ, err := resource.ParseQuantity(*cpu)
+	if err != nil {
+		return selectQuery{}, fmt.Errorf("-cpu: %w", err)
+	}
+	memQ, err := resource.ParseQuantity(*memory)
+	if err != nil {
+		return selectQuery{}, fmt.Errorf("-memory: %w", err)
+	}
+	w := resolver.WorkloadProfile{
+		CPURequirements:    int(math.Ceil(float64(cpuQ.MilliValue()) / 1000)),
+		MemoryRequirements: float64(memQ.Value()) / (1 << 30),
+		GPURequirements:    *gpu,
+		GPUType:            *gpuType,
+		Architecture:       *arch,
+	}
+	if *zone != "" {
+		w.Zone = resolver.NodeZone(*zone)
+	}
+	for _, r := range requires {
+		switch r {
+		case "spot":
+			w.RequireSpot = true
+		case "ephemeral-os":
+			w.RequireEphemeralOS = true
+		case "nested-virt":
+			w.RequireNestedVirt = true
+		case "confidential":
+			w.RequireConfidential = true
+		default:
+			return selectQuery{}, fmt.Errorf("unknown -require %q, expected spot, ephemeral-os, nested-virt or confidential", r)
+		}
+	}
+	for _, c := range capabilities {
+		key, value, ok := strings.Cut(c, "=")
+		if !ok || key == "" {
+			return selectQuery{}, fmt.Errorf("-capability %q is not key=value", c)
+		}
+		if w.Capabilities == nil {
+			w.Capabilities = map[string]string{}
+		}
+		w.Capabilities[key] = value
+	}
+	return selectQuery{skuFile: *skuFile, strategy: resolver.SelectionStrategy(*strategy), top: *top, workload: w}, nil
+}
+
+/*
+runSelect implements "select [-sku skus.json] [-cpu q] [-memory q] [-require r]... ...": it
+builds one workload from its flags (see parseSelect) and prints the instance types the resolver
+would pick for it, best first, with the filters that trimmed the candidates (see
+resolver.ExplainSelection).
+*/
+func runSelect(args []string, stdout, stderr io.Writer) error {
+	q, err := parseSelect(args, stderr)
+	if err != nil {
+		return err
+	}
+	ds, err := resolver.LoadSKUDatasets(q.skuFile)
+	if err != nil {
+		return fmt.Errorf("load skus: %w", err)
+	}
+	e := resolver.ExplainSelection(ds.SKUs, q.workload, resolver.Config{Strategy: q.strategy}, q.top)
+	return report.WriteSelection(stdout, e, ds.Currency)
+}
+
+/*
+runRecommend implements "recommend [-sku skus.json] [-target 0.8] [-top n] workloads.json": it
+prints the SKUs that pack the workloads most cheaply as a homogeneous fleet at the target
+utilization (see resolver.RecommendInstanceShapes).
+*/
+func runRecommend(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("recommend", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	skuFile := fs.String("sku", "azure_skus.json", "Path to Azure SKU JSON file, or a comma-separated list of files priced in the same currency")
+	workloadFmt := fs.String("workload-format", "profile", "Schema of the workloads file: profile|preprocessed")
+	target := fs.Float64("target", 0.8, "Share of each VM's vCPUs and memory to fill, from 0 to 1")
+	top := fs.Int("top", 5, "Number of SKUs to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: recommend [-sku skus.json] [-workload-format f] [-target 0.8] [-top n] workloads.json")
+	}
+	if *target <= 0 || *target > 1 {
+		return fmt.Errorf("-target must be in (0, 1], got %g", *target)
+	}
+	format, err := resolver.ParseWorkloadFormat(*workloadFmt)
+	if err != nil {
+		return err
+	}
+	ds, err := resolver.LoadSKUDatasets(*skuFile)
+	if err != nil {
+		return fmt.Errorf("load skus: %w", err)
+	}
+	workloads, err := resolver.LoadWorkloads(fs.Arg(0), format)
+	if err != nil {
+		return err
+	}
+	recs := resolver.RecommendInstanceShapes(workloads, ds.SKUs, *target)
+	if len(recs) == 0 || recs[0].Unpacked > 0 {
+		return fmt.Errorf("no single SKU holds all %d workloads at %.0f%% utilization", len(workloads), *target*100)
+	}
+	best := recs[0]
+	fmt.Fprintf(stdout, "Your workloads would fit best on %d vCPU / %g GiB nodes (%s) at %.0f%% target utilization:\n",
+		best.VCpus, best.MemoryGiB, best.SKU, *target*100)
+	for i, r := range recs {
+		if i == *top {
+			break
+		}
+		fmt.Fprintf(stdout, "%2d. %s\n", i+1, r)
+	}
+	return nil
+}
+
+// runScenario implements "run [-fail-on-unpacked=false] [-manifest] [-verbose] scenario.yaml": it
+// runs a scenario file (see resolver.Scenario) and writes the outputs it names, embedding the
+// resolved scenario in the JSON report.
+func runScenario(args []string, stderr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	failUnpacked := fs.Bool("fail-on-unpacked", true, "Exit with status 1 when some workloads were not packed")
+	manifest := fs.Bool("manifest", false, "Embed a manifest of the SKUs, workloads, quota and settings in the JSON report, for replay")
+	verbose := fs.Bool("verbose", false, "Print the run's metadata: tool version, git ref ($GIT_REF), hostname, start and end time and duration")
+	if err := fs.Parse(args); err != nil {
+		return resolver.ExitInputError, err
+	}
+	if fs.NArg() != 1 {
+		return resolver.ExitInputError, fmt.Errorf("usage: run [-fail-on-unpacked=false] [-manifest] [-verbose] scenario.yaml")
+	}
+	sc, err := resolver.LoadScenario(fs.Arg(0))
+	if err != nil {
+		return resolver.ExitInputError, err
+	}
+	sc.Manifest = sc.Manifest || *manifest
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	run, err := resolver.RunScenario(ctx, sc)
+	if err != nil {
+		return resolver.ExitCodeFor(err), err
+	}
+	o := sc.Outputs
+	return writeRun(outputs{csv: o.CSV, markdown: o.Markdown, json: o.JSON, sqlite: o.SQLite, failOnUnpacked: *failUnpacked, verbose: *verbose}, run)
+}
+
+/*
+runReplay implements "replay run.json": it re-runs the manifest of a JSON report written by
+"run" with a manifest and exits with resolver.ExitReplayMismatch, printing the differences,
+unless every result is identical to the recorded one. It prints the metadata of the recorded and the replayed run.
+*/
+func runReplay(args []string, stdout io.Writer) (int, error) {
+	if len(args) != 1 {
+		return resolver.ExitInputError, fmt.Errorf("usage: replay run.json")
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return resolver.ExitInputError, err
+	}
+	defer f.Close()
+	recorded, err := report.ReadJSON(f)
+	if err != nil {
+		return resolver.ExitInputError, fmt.Errorf("%s: %w", args[0], err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	replayed, err := resolver.Replay(ctx, recorded)
+	var mismatch *resolver.ReplayMismatchError
+	if err == nil || errors.As(err, &mismatch) {
+		printMetadata(stdout, "Recorded", recorded.Metadata)
+		printMetadata(stdout, "Replayed", replayed.Metadata)
+	}
+	if mismatch != nil {
+		return resolver.ExitReplayMismatch, err
+	}
+	if err != nil {
+		return resolver.ExitCodeFor(err), err
+	}
+	fmt.Fprintf(stdout, "Replayed %d results of manifest %s: identical\n", len(replayed.Results), recorded.Manifest.Hash)
+	return resolver.ExitOK, nil
+}
+
+/*
+runSKUDiff implements "sku-diff [-fail-on-price-increase percent] old.json new.json": it prints
+DiffInstanceSpecs of two SKU files and reports whether a price rose by more than the threshold,
+so CI can gate price regressions on the exit code.
+*/
+func runSKUDiff(args []string, stdout, stderr io.Writer) (bool, error) {
+	fs := flag.NewFlagSet("sku-diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	threshold := fs.Float64("fail-on-price-increase", -1, "Optional: exit with status 6 when a price rose by more than this percentage (negative disables)")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	if fs.NArg() != 2 {
+		return false, fmt.Errorf("usage: sku-diff [-fail-on-price-increase percent] old.json new.json")
+	}
+	var datasets [2]resolver.SKUDataset
+	for i, path := range fs.Args() {
+		ds, err := resolver.LoadSKUDataset(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to create output file: %v\n", err)
-			os.Exit(3)
+			return false, fmt.Errorf("load %s: %w", path, err)
 		}
-		defer f.Close()
-		fmt.Fprintf(f, "Strategy,VMs Used,Total Cost,Avg CPU Util (%),Avg Mem Util (%)\n")
-		fmt.Fprintf(f, "NewAlgorithm,%d,%.2f,%.1f,%.1f\n", result.VMsUsed, result.TotalCost, result.AvgCPU, result.AvgMem)
-		fmt.Fprintf(f, "Naive,%d,%.2f,%.1f,%.1f\n", naive.VMsUsed, naive.TotalCost, naive.AvgCPU, naive.AvgMem)
-		fmt.Printf("Results written to %s\n", *outFile)
+		datasets[i] = ds
+	}
+	if datasets[0].Currency != datasets[1].Currency {
+		return false, fmt.Errorf("cannot compare prices in %s and %s", datasets[0].Currency, datasets[1].Currency)
+	}
+	diff := resolver.DiffInstanceSpecs(datasets[0].SKUs, datasets[1].SKUs)
+	fmt.Fprint(stdout, diff)
+	if *threshold < 0 {
+		return false, nil
+	}
+	over := diff.PriceIncreasesAbove(*threshold)
+	for _, c := range over {
+		fmt.Fprintf(stderr, "price of %s rose %.1f%%, more than %.1f%%\n", c.SKU, c.Percent(), *threshold)
+	}
+	return len(over) > 0, nil
+}
+
+// readReportResult reads the result called name from a JSON report, with the report's
+// metadata, nil for reports without it.
+func readReportResult(path, name string) (resolver.SimulationResult, *resolver.RunMetadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return resolver.SimulationResult{}, nil, err
+	}
+	defer f.Close()
+	run, err := report.ParseRun(f)
+	if err != nil {
+		return resolver.SimulationResult{}, nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	for _, nr := range run.Results {
+		if nr.Name == name {
+			return nr.Result, run.Metadata, nil
+		}
+	}
+	return resolver.SimulationResult{}, nil, fmt.Errorf("%s has no result %q", path, name)
+}
+
+// weightsPresetUsage is the help of --weights-preset, listing each preset with its intent.
+func weightsPresetUsage() string {
+	var b strings.Builder
+	b.WriteString("Optional: named scoring weights replacing the strategy's, best with --score-version=normalized:")
+	for _, p := range resolver.ListPresets() {
+		fmt.Fprintf(&b, "\n  %s: %s", p.Name, p.Intent)
+	}
+	return b.String()
+}
+
+// printMetadata prints the metadata of the run labelled label, or that it has none.
+func printMetadata(w io.Writer, label string, m *resolver.RunMetadata) {
+	if m == nil {
+		fmt.Fprintf(w, "%s: no run metadata\n", label)
+		return
+	}
+	fmt.Fprintf(w, "%s: %s\n", label, m)
+}
+
+// serve loads the SKUs once and serves the REST API until interrupted.
+func serve(addr, skuFile, families string, maxBodyBytes int64, cfg resolver.Config) error {
+	skus, err := resolver.LoadSKUDatasets(skuFile)
+	if err != nil {
+		return fmt.Errorf("load skus: %w", err)
+	}
+	cfg.Currency = skus.Currency
+	var filters []func(resolver.AzureInstanceSpec) bool
+	if families != "" {
+		allowed := strings.Split(families, ",")
+		filters = append(filters, func(vm resolver.AzureInstanceSpec) bool {
+			return resolver.FamilyPreferenceRank(vm, allowed) < len(allowed)
+		})
+	}
+	svc := resolver.NewSelectorService(skus.SKUs, cfg, filters...)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Serving %d SKUs on %s\n", len(svc.SKUs()), addr)
+	return server.New(svc, maxBodyBytes).ListenAndServe(ctx, addr)
+}
+
+// outputs holds the optional output file paths, and whether unpacked workloads fail the run.
+type outputs struct {
+	csv, markdown, json, sqlite, events string
+	failOnUnpacked                      bool
+	verbose                             bool // print the run's metadata
+}
+
+// writeOutputs is writeRun for the results of a flag-driven simulation.
+func writeOutputs(out outputs, result, naive resolver.SimulationResult, metadata resolver.RunMetadata) (int, error) {
+	return writeRun(out, resolver.SimulationRun{Metadata: &metadata, Results: []resolver.NamedResult{
+		{Name: "NewAlgorithm", Result: result},
+		{Name: "Naive", Result: naive},
+	}})
+}
+
+// writeRun prints the packing explanation, cost projection and attribution of run's first result,
+// and the run's metadata under out.verbose, and writes the optional outputs. It returns resolver.ExitOutputError when an output cannot be
+// written and resolver.ExitUnpacked when workloads were left unpacked, unless out allows it and
+// some were packed. The CSV summarizes every result, ending with the naive baseline.
+func writeRun(out outputs, run resolver.SimulationRun) (int, error) {
+	result := run.Results[0].Result
+	if out.verbose && run.Metadata != nil {
+		fmt.Printf("Run: %s\n", run.Metadata)
+	}
+	if err := report.WriteExplanation(os.Stdout, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print packing explanation: %v\n", err)
+	}
+	p := result.Projection
+	fmt.Printf("Projected cost: %s/month, %s/year (%.0f h/month)\n",
+		resolver.FormatCurrency(result.Currency, p.Monthly, 2), resolver.FormatCurrency(result.Currency, p.Annual, 2), p.HoursPerMonth)
+	if result.CostByLabel != nil {
+		printCostByLabel(*result.CostByLabel, result.Currency)
+	}
+	if len(result.StrategyMix) > 0 {
+		fmt.Printf("Workload classes:")
+		for _, c := range result.StrategyMix {
+			fmt.Printf(" %s=%d", c.Strategy, c.Workloads)
+		}
+		fmt.Println()
+	}
+	if cmp := result.ArchComparison; cmp != nil && len(cmp.Migrations) > 0 {
+		fmt.Printf("Workloads needing arm64 images (%s/h saved):\n", resolver.FormatCurrency(result.Currency, -cmp.CostDelta, 4))
+		for _, m := range cmp.Migrations {
+			fmt.Printf("  %-30s %s (%s)\n", m.Workload, m.VM, m.SKU)
+		}
+	}
+	if len(result.ExcludedFamilies) > 0 {
+		fmt.Printf("Families without quota, excluded: %s\n", strings.Join(result.ExcludedFamilies, ", "))
+	}
+	for _, nr := range run.Results {
+		for _, zw := range nr.Result.ZoneWarnings {
+			fmt.Printf("Warning: %s VM %d (%s) is offered in %d zone(s), %d required\n", nr.Name, zw.VM, zw.SKU, zw.Zones, zw.Required)
+		}
+	}
+	if result.VMsUsed > 0 {
+		if err := report.WriteHistogram(os.Stdout, result.Histogram); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print utilization histogram: %v\n", err)
+		}
+	}
+	files := []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{out.csv, func(w io.Writer) error { return report.WriteCSV(w, run) }},
+		{out.markdown, func(w io.Writer) error { return report.WriteMarkdown(w, run) }},
+		{out.json, func(w io.Writer) error { return report.WriteJSON(w, run) }},
+		{out.events, func(w io.Writer) error { return result.Events.WriteJSONL(w) }},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		if err := writeFile(f.path, f.write); err != nil {
+			return resolver.ExitOutputError, err
+		}
+	}
+	if out.sqlite != "" {
+		if err := report.ExportSQLite(out.sqlite, run); err != nil {
+			return resolver.ExitOutputError, fmt.Errorf("export to %s: %w", out.sqlite, err)
+		}
+		fmt.Printf("Results appended to %s\n", out.sqlite)
+	}
+	// An empty packing costs nothing; make sure it does not pass for a good result
+	if err := result.CheckPacked(); err != nil {
+		return resolver.ExitUnpacked, err
+	}
+	if result.Unpacked > 0 && out.failOnUnpacked {
+		return resolver.ExitUnpacked, fmt.Errorf("%d workloads were not packed", result.Unpacked)
+	}
+	return resolver.ExitOK, nil
+}
+
+// printCostByLabel prints the hourly cost per label value, most expensive first.
+func printCostByLabel(a resolver.CostAttribution, currency string) {
+	fmt.Printf("Cost by label %q:\n", a.LabelKey)
+	for _, v := range a.Values() {
+		fmt.Printf("  %-20s %s/hr\n", v, resolver.FormatCurrency(currency, a.Costs[v], 4))
+	}
+}
+
+// writeFile creates path and fills it with write. Errors closing the file are returned too, as
+// they can be the first to report a full disk.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
 	}
+	fmt.Printf("Results written to %s\n", path)
+	return nil
 }
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver/report"
+)
+
+// writeFixtures writes a one-SKU file and a workloads file with the given CPU requests to dir.
+func writeFixtures(t *testing.T, dir string, cpus ...int) (skuFile, workloadsFile string) {
+	t.Helper()
+	skus := []resolver.AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2", "3"}}}
+	var workloads resolver.WorkloadSet
+	for _, c := range cpus {
+		workloads = append(workloads, resolver.WorkloadProfile{CPURequirements: c, MemoryRequirements: 1})
+	}
+	skuFile, workloadsFile = filepath.Join(dir, "skus.json"), filepath.Join(dir, "workloads.json")
+	for path, v := range map[string]any{skuFile: skus, workloadsFile: workloads} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return skuFile, workloadsFile
+}
+
+func TestRunExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	skus, packable := writeFixtures(t, t.TempDir(), 1, 2)
+	_, partly := writeFixtures(t, t.TempDir(), 1, 64)
+	_, none := writeFixtures(t, t.TempDir(), 64)
+	custom := func(workloads string, args ...string) []string {
+		return append([]string{"-trace", "custom", "-sku", skus, "-workloads", workloads}, args...)
+	}
+	for _, tc := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"all packed", custom(packable), resolver.ExitOK},
+		{"some unpacked", custom(partly), resolver.ExitUnpacked},
+		{"unpacked allowed", custom(partly, "-fail-on-unpacked=false"), resolver.ExitOK},
+		{"nothing packed", custom(none, "-fail-on-unpacked=false"), resolver.ExitUnpacked},
+		{"unknown trace", []string{"-trace", "bogus"}, resolver.ExitInputError},
+		{"invalid trace URL", []string{"-trace", "azure", "-trace-url", "ftp://mirror/trace.csv"}, resolver.ExitInputError},
+		{"unknown flag", []string{"-bogus"}, resolver.ExitInputError},
+		{"unknown diff flag", []string{"diff", "-bogus"}, resolver.ExitInputError},
+		{"unknown sku-diff flag", []string{"sku-diff", "-bogus"}, resolver.ExitInputError},
+		{"unknown convert flag", []string{"convert", "-bogus"}, resolver.ExitInputError},
+		{"unknown whatif flag", []string{"whatif", "-bogus"}, resolver.ExitInputError},
+		{"unknown recommend flag", []string{"recommend", "-bogus"}, resolver.ExitInputError},
+		{"weights preset", custom(packable, "-weights-preset", "consolidation", "-score-version", "normalized"), resolver.ExitOK},
+		{"unknown weights preset", custom(packable, "-weights-preset", "fastest"), resolver.ExitInputError},
+		{"missing weights file", custom(packable, "-weights", filepath.Join(dir, "weights.json")), resolver.ExitInputError},
+		{"quota-strict without quota", custom(packable, "-quota-strict"), resolver.ExitInputError},
+		{"impossible workload under strict", custom(partly, "-strict"), resolver.ExitInputError},
+		{"events without load profile", custom(packable, "-events", filepath.Join(dir, "events.jsonl")), resolver.ExitInputError},
+		{"missing workloads", custom(filepath.Join(dir, "missing.json")), resolver.ExitInputError},
+		{"missing skus", []string{"-trace", "custom", "-sku", filepath.Join(dir, "missing.json"), "-workloads", packable}, resolver.ExitInputError},
+		{"unwritable report", custom(packable, "-json", filepath.Join(dir, "missing", "run.json")), resolver.ExitOutputError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			code, err := run(tc.args, &stderr)
+			if code != tc.want {
+				t.Errorf("expected exit code %d, got %d (%v)", tc.want, code, err)
+			}
+			if (code == resolver.ExitOK) != (err == nil) {
+				t.Errorf("expected an error exactly when failing, got code %d and %v", code, err)
+			}
+		})
+	}
+}
+
+func TestRunSKUDiffExitCode(t *testing.T) {
+	dir := t.TempDir()
+	old, updated := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+	for path, price := range map[string]float64{old: 0.1, updated: 0.2} {
+		data, err := json.Marshal([]resolver.AzureInstanceSpec{{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: price}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stderr bytes.Buffer
+	if code, err := run([]string{"sku-diff", "-fail-on-price-increase", "50", old, updated}, &stderr); code != resolver.ExitPriceIncrease {
+		t.Errorf("expected exit code %d for a doubled price, got %d (%v)", resolver.ExitPriceIncrease, code, err)
+	}
+	stderr.Reset()
+	if exceeded, err := runSKUDiff([]string{"-fail-on-price-increase", "50", old, updated}, io.Discard, &stderr); !exceeded || err != nil {
+		t.Errorf("expected the threshold exceeded, got %v, %v", exceeded, err)
+	}
+	if !strings.Contains(stderr.String(), "price of Standard_D4s_v5 rose") {
+		t.Errorf("expected the price alert on the given stderr, got %q", stderr.String())
+	}
+	if code, err := run([]string{"sku-diff", "-fail-on-price-increase", "150", old, updated}, &stderr); code != resolver.ExitOK {
+		t.Errorf("expected exit code %d below the threshold, got %d (%v)", resolver.ExitOK, code, err)
+	}
+}
+
+func TestRunDownloadError(t *testing.T) {
+	// No cached trace, and a proxy that refuses connections
+	t.Chdir(t.TempDir())
+	t.Setenv("HTTPS_PROXY", "http://127.0.0.1:1")
+	var stderr bytes.Buffer
+	if code, err := run([]string{"-trace", "azure"}, &stderr); code != resolver.ExitDownloadError {
+		t.Errorf("expected exit code %d, got %d (%v)", resolver.ExitDownloadError, code, err)
+	}
+}
+
+func TestRunJSONFailureSummary(t *testing.T) {
+	skus, workloads := writeFixtures(t, t.TempDir(), 1, 64, 64)
+	var stderr bytes.Buffer
+	code, _ := run([]string{"-trace", "custom", "-sku", skus, "-workloads", workloads, "-output", "json"}, &stderr)
+	var s resolver.FailureSummary
+	if err := json.Unmarshal(stderr.Bytes(), &s); err != nil {
+		t.Fatalf("expected a JSON failure summary on stderr, got %q: %v", stderr.String(), err)
+	}
+	if s.ExitCode != code || s.Class != "unpacked" || s.Unpacked != 2 || len(s.Reasons) != 1 {
+		t.Errorf("expected an unpacked summary of 2 workloads with one reason and exit code %d, got %+v", code, s)
+	}
+}
+
+func TestRunSelfTest(t *testing.T) {
+	var stderr bytes.Buffer
+	if code, err := run([]string{"selftest"}, &stderr); code != resolver.ExitOK {
+		t.Errorf("expected the selftest to pass, got exit code %d (%v)", code, err)
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	dir := t.TempDir()
+	skus, _ := writeFixtures(t, dir)
+	trace, converted := filepath.Join(dir, "trace.csv"), filepath.Join(dir, "workloads.jsonl")
+	if err := os.WriteFile(trace, []byte("name,cpu,memory,priority\nweb,2,2048,100\napi,1,512,0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	if code, err := run([]string{"convert", "-trace", "custom", "-in", trace, "-memory-unit", "MiB", "-out", converted}, &stderr); code != resolver.ExitOK {
+		t.Fatalf("expected the conversion to succeed, got exit code %d (%v)", code, err)
+	}
+	if code, err := run([]string{"-trace", "custom", "-sku", skus, "-workloads", converted}, &stderr); code != resolver.ExitOK {
+		t.Errorf("expected the converted workloads to load and pack, got exit code %d (%v)", code, err)
+	}
+	if code, _ := run([]string{"convert", "-trace", "custom", "-in", trace, "-cpu-unit", "mcpu"}, &stderr); code != resolver.ExitInputError {
+		t.Errorf("expected exit code %d for an unknown unit, got %d", resolver.ExitInputError, code)
+	}
+}
+
+func TestRunWhatIf(t *testing.T) {
+	skus := filepath.Join(t.TempDir(), "skus.json")
+	data, err := json.Marshal([]resolver.AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192},
+		{Name: "Standard_E16s_v5", Family: "ESv5", VCpus: 16, MemoryGiB: 128, PricePerHour: 1.008},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(skus, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	state := filepath.Join("..", "..", "pkg", "resolver", "kube", "testdata", "cluster", "karpenter.json")
+	if err := runWhatIf([]string{"-sku", skus, state}, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"simulated: 1 VMs, 0.1920/h", "3 oversized nodes:", "skipped: node default-j7k8l has unknown instance type Standard_D8s_v5 (0 pods)"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected the output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+	if err := runWhatIf([]string{"-sku", skus, "-strategy", "bogus", state}, io.Discard, io.Discard); err == nil || !strings.Contains(err.Error(), "unknown strategy") {
+		t.Errorf("expected an unknown strategy error, got %v", err)
+	}
+}
+
+func TestRunReplay(t *testing.T) {
+	dir := t.TempDir()
+	skus, workloads := writeFixtures(t, dir, 1, 2, 3)
+	scenario, runFile := filepath.Join(dir, "scenario.yaml"), filepath.Join(dir, "run.json")
+	body := "sku: " + skus + "\nworkloads: " + workloads + "\noutputs:\n  json: " + runFile + "\n"
+	if err := os.WriteFile(scenario, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	if code, err := run([]string{"run", "-manifest", scenario}, &stderr); code != resolver.ExitOK {
+		t.Fatalf("expected the scenario to run, got exit code %d (%v)", code, err)
+	}
+	// The replay does not need the input files.
+	if err := os.Remove(skus); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if code, err := runReplay([]string{runFile}, &out); code != resolver.ExitOK || !strings.Contains(out.String(), "identical") {
+		t.Fatalf("expected an identical replay, got exit code %d (%v) and %q", code, err, out.String())
+	}
+	for _, line := range []string{"Recorded: version dev", "Replayed: version dev"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected the output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+
+	f, err := os.Open(runFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := report.ReadJSON(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded.Manifest.Workloads[0].CPURequirements = 4
+	tampered := filepath.Join(dir, "tampered.json")
+	if err := writeFile(tampered, func(w io.Writer) error { return report.WriteJSON(w, recorded) }); err != nil {
+		t.Fatal(err)
+	}
+	if code, err := runReplay([]string{tampered}, &out); code != resolver.ExitInputError || !errors.Is(err, resolver.ErrManifestModified) {
+		t.Errorf("expected exit code %d for a tampered manifest, got %d (%v)", resolver.ExitInputError, code, err)
+	}
+}
+
+func TestRunMetadata(t *testing.T) {
+	t.Setenv(resolver.GitRefEnv, "1a2b3c4d")
+	dir := t.TempDir()
+	skus, workloads := writeFixtures(t, dir, 1, 2)
+	runFile := filepath.Join(dir, "run.json")
+	var stderr bytes.Buffer
+	if code, err := run([]string{"-trace", "custom", "-sku", skus, "-workloads", workloads, "-json", runFile}, &stderr); code != resolver.ExitOK {
+		t.Fatalf("expected the simulation to succeed, got exit code %d (%v)", code, err)
+	}
+	f, err := os.Open(runFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := report.ReadJSON(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := recorded.Metadata; m == nil || m.Version != "dev" || m.GitRef != "1a2b3c4d" || m.Start.IsZero() || m.End.Before(m.Start) {
+		t.Errorf("expected the run's metadata in the JSON report, got %+v", m)
+	}
+}
+
+func TestRunRecommend(t *testing.T) {
+	skus, workloads := writeFixtures(t, t.TempDir(), 1, 2)
+	var out bytes.Buffer
+	if err := runRecommend([]string{"-sku", skus, workloads}, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"fit best on 4 vCPU / 16 GiB nodes (Standard_D4s_v5) at 80% target utilization", " 1. 4 vCPU / 16 GiB (Standard_D4s_v5): 1 VMs, 0.1920/h"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected the output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+	if err := runRecommend([]string{"-sku", skus, "-target", "0.4", workloads}, &out, io.Discard); err == nil {
+		t.Error("expected an error when no SKU holds the workloads at the target")
+	}
+}
+
+func TestParseSelect(t *testing.T) {
+	q, err := parseSelect([]string{"-cpu", "3500m", "-memory", "16Gi", "-gpu", "1", "-gpu-type", "A100", "-zone", "eastus-2",
+		"-require", "spot", "-require", "confidential", "-capability", "AcceleratedNetworking=true", "-capability", "MaxPods=110"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := q.workload
+	if w.CPURequirements != 4 || w.MemoryRequirements != 16 || w.GPURequirements != 1 || w.GPUType != "A100" || w.Zone != "2" {
+		t.Errorf("expected 4 vCPU, 16 GiB and one A100 in zone 2, got %+v", w)
+	}
+	if !w.RequireSpot || !w.RequireConfidential || w.RequireEphemeralOS || w.RequireNestedVirt {
+		t.Errorf("expected spot and confidential to be required only, got %+v", w)
+	}
+	if want := map[string]string{"AcceleratedNetworking": "true", "MaxPods": "110"}; !maps.Equal(w.Capabilities, want) {
+		t.Errorf("expected capabilities %v, got %v", want, w.Capabilities)
+	}
+	if q, err := parseSelect([]string{"-memory", "512Mi"}, io.Discard); err != nil || q.workload.MemoryRequirements != 0.5 || q.workload.Capabilities != nil {
+		t.Errorf("expected 0.5 GiB without capabilities, got %+v: %v", q.workload, err)
+	}
+	for _, args := range [][]string{
+		{"-memory", "16GB?"},
+		{"-require", "gpu"},
+		{"-capability", "TrustedLaunch"},
+		{"-strategy", "auto"},
+	} {
+		if _, err := parseSelect(args, io.Discard); err == nil {
+			t.Errorf("expected %v to be rejected", args)
+		}
+	}
+}
+
+func TestRunSelect(t *testing.T) {
+	skus, _ := writeFixtures(t, t.TempDir())
+	var out bytes.Buffer
+	if err := runSelect([]string{"-sku", skus, "-cpu", "2", "-memory", "8Gi", "-zone", "1"}, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "   1  Standard_D4s_v5") {
+		t.Errorf("expected Standard_D4s_v5 ranked first, got:\n%s", out.String())
+	}
+	out.Reset()
+	if err := runSelect([]string{"-sku", skus, "-cpu", "8"}, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "no instance type can hold the workload") {
+		t.Errorf("expected the limiting requirement for an oversized workload, got:\n%s", out.String())
+	}
+}
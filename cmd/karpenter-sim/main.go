@@ -1,14 +1,64 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
+	"os"
+	"sort"
 	"time"
 
-	"pkg/resolver"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
 )
 
 func main() {
+	code, _ := run(os.Args[1:], os.Stdout, os.Stderr) // run reports its own errors
+	os.Exit(code)
+}
+
+/*
+run simulates packing example workloads, writes the VMs to stdout and returns the exit code (see
+resolver.ExitOK and the other exit codes) and the error that caused it, which it has already
+reported on stderr. With --output=json both the VMs and the error (as a
+resolver.FailureSummary) are written as JSON. Nothing is downloaded, so it never returns
+resolver.ExitDownloadError.
+*/
+func run(args []string, stdout, stderr io.Writer) (code int, err error) {
+	fs := flag.NewFlagSet("karpenter-sim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	skuFile := fs.String("sku", "", "Optional: SKU JSON file to select from instead of the example instance types")
+	seed := fs.Int64("seed", 0, "Random seed for the example workloads (0 = current time)")
+	failUnpacked := fs.Bool("fail-on-unpacked", true, "Exit with status 1 when some workloads were not packed (a packing that places nothing always fails)")
+	outputFormat := fs.String("output", "text", "Format of the results on stdout and the failure summary on stderr: text|json")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return resolver.ExitOK, nil
+		}
+		return resolver.ExitInputError, err
+	}
+	if *outputFormat != "text" && *outputFormat != "json" {
+		err := fmt.Errorf("unknown --output %q", *outputFormat)
+		fmt.Fprintln(stderr, err)
+		return resolver.ExitInputError, err
+	}
+	var summary resolver.FailureSummary
+	defer func() {
+		if err == nil {
+			return
+		}
+		if *outputFormat == "json" {
+			s := resolver.NewFailureSummary(code, err)
+			s.Unpacked, s.Reasons = summary.Unpacked, summary.Reasons
+			s.Write(stderr)
+			return
+		}
+		fmt.Fprintln(stderr, err)
+	}()
+
 	// Example Azure instance types (in real use, load from file or API)
 	instanceTypes := []resolver.AzureInstanceSpec{
 		{
@@ -45,7 +95,7 @@ func main() {
 			AvailabilityZones:     []string{"1", "2"},
 			EphemeralOSDisk:       false,
 			NestedVirtualization:  false,
-			SpotSupported:         true,
+			SpotSupported:         false, // GPU sizes rarely have spot capacity
 			ConfidentialComputing: false,
 			TrustedLaunch:         false,
 			AcceleratedNetworking: true,
@@ -55,56 +105,158 @@ func main() {
 		},
 		// Add more instance types as needed
 	}
+	if *skuFile != "" {
+		ds, err := resolver.LoadSKUDataset(*skuFile)
+		if err != nil {
+			return resolver.ExitInputError, fmt.Errorf("load skus: %w", err)
+		}
+		instanceTypes = ds.SKUs
+	}
 
 	// Example workloads (in real use, load from file or generate)
 	workloads := make([]resolver.WorkloadProfile, 0, 10)
-	rand.Seed(time.Now().UnixNano())
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rand := rand.New(rand.NewSource(*seed))
 	for i := 0; i < 10; i++ {
 		workloads = append(workloads, resolver.WorkloadProfile{
-			CPURequirements:    rand.Intn(3) + 1, // 1-3 vCPU
-			MemoryRequirements: float64(rand.Intn(8) + 2), // 2-9 GiB
-			IORequirements:     float64(rand.Intn(20)),    // 0-19 GiB
-			GPURequirements:    0,
-			GPUType:            "",
-			Zone:               "",
-			RequireEphemeralOS: rand.Intn(2) == 0,
-			RequireNestedVirt:  rand.Intn(2) == 0,
-			RequireSpot:        rand.Intn(2) == 0,
+			Name:                fmt.Sprintf("pod-%d", i),
+			CPURequirements:     rand.Intn(3) + 1,          // 1-3 vCPU
+			MemoryRequirements:  float64(rand.Intn(8) + 2), // 2-9 GiB
+			IORequirements:      float64(rand.Intn(20)),    // 0-19 GiB
+			GPURequirements:     0,
+			GPUType:             "",
+			Zone:                "",
+			RequireEphemeralOS:  rand.Intn(2) == 0,
+			RequireNestedVirt:   rand.Intn(2) == 0,
+			RequireSpot:         rand.Intn(2) == 0,
 			RequireConfidential: false,
-			Capabilities:       map[string]string{},
+			Capabilities:        map[string]string{},
 		})
 	}
 	// Add a GPU workload
 	workloads = append(workloads, resolver.WorkloadProfile{
-		CPURequirements:    4,
-		MemoryRequirements: 32,
-		IORequirements:     100,
-		GPURequirements:    1,
-		GPUType:            "NVIDIA",
-		Zone:               "1",
-		RequireEphemeralOS: false,
-		RequireNestedVirt:  false,
-		RequireSpot:        false,
+		Name:                "gpu-pod",
+		CPURequirements:     4,
+		MemoryRequirements:  32,
+		IORequirements:      100,
+		GPURequirements:     1,
+		GPUType:             "NVIDIA",
+		Zone:                "1",
+		RequireEphemeralOS:  false,
+		RequireNestedVirt:   false,
+		RequireSpot:         false,
 		RequireConfidential: false,
-		Capabilities:       map[string]string{"AcceleratedNetworking": "true"},
+		Capabilities:        map[string]string{"AcceleratedNetworking": "true"},
 	})
 
 	// Run the simulation
 	result := resolver.BinPackWorkloads(workloads, instanceTypes, resolver.StrategyGeneralPurpose)
 
 	// Output results
-	fmt.Printf("Simulation Results:\n")
-	fmt.Printf("Total VMs used: %d\n", len(result.VMs))
-	totalCost := 0.0
+	if *outputFormat == "json" {
+		if err := writeJSON(stdout, result); err != nil {
+			return resolver.ExitOutputError, err
+		}
+	} else {
+		writeText(stdout, result)
+	}
+	summary = summary.WithPacking(result)
+	if err := resolver.CheckPacked(result); err != nil {
+		return resolver.ExitUnpacked, err
+	}
+	if len(result.Unpacked) > 0 && *failUnpacked {
+		return resolver.ExitUnpacked, fmt.Errorf("%d workloads were not packed", len(result.Unpacked))
+	}
+	return resolver.ExitOK, nil
+}
+
+// writeText writes every VM with its zone, capacity type and workloads, then the VMs per zone
+// and per capacity type.
+func writeText(w io.Writer, result resolver.PackingResult) {
+	fmt.Fprintf(w, "Simulation Results:\n")
+	fmt.Fprintf(w, "Total VMs used: %d\n", len(result.VMs))
 	for i, vm := range result.VMs {
-		vmCost := vm.InstanceType.PricePerHour
-		fmt.Printf("VM #%d: %s (vCPUs: %d, Mem: %.1f GiB, GPU: %d, Price: $%.2f/hr)\n",
-			i+1, vm.InstanceType.Name, vm.InstanceType.VCpus, vm.InstanceType.MemoryGiB, vm.InstanceType.GPUCount, vmCost)
-		fmt.Printf("  Workloads packed: %d\n", len(vm.Workloads))
-		for _, w := range vm.Workloads {
-			fmt.Printf("    - CPU: %d, Mem: %.1f GiB, GPU: %d\n", w.CPURequirements, w.MemoryRequirements, w.GPURequirements)
+		fmt.Fprintf(w, "VM #%d: %s (vCPUs: %d, Mem: %.1f GiB, GPU: %d, Price: $%.2f/hr, Zone: %s, %s)\n",
+			i+1, vm.InstanceType.Name, vm.InstanceType.VCpus, vm.InstanceType.MemoryGiB, vm.InstanceType.GPUCount, vm.InstanceType.PricePerHour, zoneName(vm.Zone), vm.CapacityType)
+		fmt.Fprintf(w, "  Workloads packed: %d\n", len(vm.Workloads))
+		for _, wl := range vm.Workloads {
+			fmt.Fprintf(w, "    - CPU: %d, Mem: %.1f GiB, GPU: %d\n", wl.CPURequirements, wl.MemoryRequirements, wl.GPURequirements)
 		}
-		totalCost += vmCost
 	}
-	fmt.Printf("Total hourly cost: $%.2f\n", totalCost)
+	fmt.Fprintf(w, "Total hourly cost: $%.2f\n", resolver.TotalCost(result.VMs))
+	byZone, byCapacityType := resolver.PlacementSummary(result.VMs)
+	fmt.Fprintf(w, "VMs per zone:\n")
+	for _, z := range sortedKeys(byZone) {
+		fmt.Fprintf(w, "  zone %s: %d VMs, $%.2f/hr\n", zoneName(z), byZone[z].VMs, byZone[z].CostPerHour)
+	}
+	fmt.Fprintf(w, "VMs per capacity type:\n")
+	for _, ct := range sortedKeys(byCapacityType) {
+		fmt.Fprintf(w, "  %s: %d VMs, $%.2f/hr\n", ct, byCapacityType[ct].VMs, byCapacityType[ct].CostPerHour)
+	}
+}
+
+// jsonVM is one VM of the --output=json results.
+type jsonVM struct {
+	SKU          string                `json:"sku"`
+	VCpus        int                   `json:"vcpus"`
+	MemoryGiB    float64               `json:"memoryGiB"`
+	GPUs         int                   `json:"gpus"`
+	PricePerHour float64               `json:"pricePerHour"`
+	Zone         string                `json:"zone"`
+	CapacityType resolver.CapacityType `json:"capacityType"`
+	Workloads    []string              `json:"workloads"`
+}
+
+// jsonResults are the --output=json results.
+type jsonResults struct {
+	VMs              []jsonVM                                          `json:"vms"`
+	TotalCostPerHour float64                                           `json:"totalCostPerHour"`
+	ByZone           map[string]resolver.PlacementCount                `json:"byZone"`
+	ByCapacityType   map[resolver.CapacityType]resolver.PlacementCount `json:"byCapacityType"`
+}
+
+// writeJSON writes the results as one indented JSON document.
+func writeJSON(w io.Writer, result resolver.PackingResult) error {
+	out := jsonResults{VMs: []jsonVM{}, TotalCostPerHour: math.Round(resolver.TotalCost(result.VMs)*1e4) / 1e4}
+	for _, vm := range result.VMs {
+		v := jsonVM{SKU: vm.InstanceType.Name, VCpus: vm.InstanceType.VCpus, MemoryGiB: vm.InstanceType.MemoryGiB, GPUs: vm.InstanceType.GPUCount,
+			PricePerHour: vm.InstanceType.PricePerHour, Zone: vm.Zone, CapacityType: vm.CapacityType, Workloads: []string{}}
+		for _, wl := range vm.Workloads {
+			v.Workloads = append(v.Workloads, wl.Name)
+		}
+		out.VMs = append(out.VMs, v)
+	}
+	byZone, byCapacityType := resolver.PlacementSummary(result.VMs)
+	out.ByZone, out.ByCapacityType = roundCosts(byZone), roundCosts(byCapacityType)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// zoneName returns z, or "none" for a VM of a SKU without zones.
+func zoneName(z string) string {
+	if z == "" {
+		return "none"
+	}
+	return z
+}
+
+// roundCosts rounds the costs in m to four decimals so float noise does not reach the JSON.
+func roundCosts[K comparable](m map[K]resolver.PlacementCount) map[K]resolver.PlacementCount {
+	for k, c := range m {
+		c.CostPerHour = math.Round(c.CostPerHour*1e4) / 1e4
+		m[k] = c
+	}
+	return m
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
 }
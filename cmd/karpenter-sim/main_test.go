@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// writeSKUs writes skus to a file in a temporary directory and returns its path.
+func writeSKUs(t *testing.T, skus []resolver.AzureInstanceSpec) string {
+	t.Helper()
+	data, err := json.Marshal(skus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "skus.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunExitCodes(t *testing.T) {
+	// Only the GPU workload needs more than 3 vCPUs
+	noGPU := writeSKUs(t, []resolver.AzureInstanceSpec{{Name: "Standard_D32s_v3", Family: "Dsv3", VCpus: 32, MemoryGiB: 128, PricePerHour: 1.6,
+		AvailabilityZones: []string{"1", "2", "3"}, EphemeralOSDisk: true, NestedVirtualization: true, SpotSupported: true, MaxPods: 110}})
+	tiny := writeSKUs(t, []resolver.AzureInstanceSpec{{Name: "Standard_B1s", Family: "BS", VCpus: 1, MemoryGiB: 1, PricePerHour: 0.01}})
+	for _, tc := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"example instance types", []string{"-seed", "1"}, resolver.ExitOK},
+		{"some unpacked", []string{"-seed", "1", "-sku", noGPU}, resolver.ExitUnpacked},
+		{"unpacked allowed", []string{"-seed", "1", "-sku", noGPU, "-fail-on-unpacked=false"}, resolver.ExitOK},
+		{"nothing packed", []string{"-seed", "1", "-sku", tiny, "-fail-on-unpacked=false"}, resolver.ExitUnpacked},
+		{"missing skus", []string{"-sku", filepath.Join(t.TempDir(), "missing.json")}, resolver.ExitInputError},
+		{"unknown output", []string{"-output", "yaml"}, resolver.ExitInputError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			code, err := run(tc.args, io.Discard, &stderr)
+			if code != tc.want {
+				t.Errorf("expected exit code %d, got %d (%v)", tc.want, code, err)
+			}
+			if (code == resolver.ExitOK) != (err == nil) {
+				t.Errorf("expected an error exactly when failing, got code %d and %v", code, err)
+			}
+		})
+	}
+}
+
+func TestRunJSONFailureSummary(t *testing.T) {
+	tiny := writeSKUs(t, []resolver.AzureInstanceSpec{{Name: "Standard_B1s", Family: "BS", VCpus: 1, MemoryGiB: 1, PricePerHour: 0.01}})
+	var stderr bytes.Buffer
+	code, _ := run([]string{"-seed", "1", "-sku", tiny, "-output", "json"}, io.Discard, &stderr)
+	var s resolver.FailureSummary
+	if err := json.Unmarshal(stderr.Bytes(), &s); err != nil {
+		t.Fatalf("expected a JSON failure summary on stderr, got %q: %v", stderr.String(), err)
+	}
+	if s.ExitCode != code || s.Class != "unpacked" || s.Unpacked != 11 {
+		t.Errorf("expected an unpacked summary of all 11 workloads and exit code %d, got %+v", code, s)
+	}
+}
+
+// TestRunGoldenOutput pins the text and JSON results of the example instance types.
+func TestRunGoldenOutput(t *testing.T) {
+	for _, tc := range []struct {
+		output, golden string
+	}{
+		{"text", "seed1.txt"},
+		{"json", "seed1.json"},
+	} {
+		t.Run(tc.output, func(t *testing.T) {
+			var stdout bytes.Buffer
+			if code, err := run([]string{"-seed", "1", "-output", tc.output}, &stdout, io.Discard); code != resolver.ExitOK {
+				t.Fatalf("expected exit code %d, got %d (%v)", resolver.ExitOK, code, err)
+			}
+			path := filepath.Join("testdata", tc.golden)
+			if *update {
+				if err := os.WriteFile(path, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(stdout.Bytes(), want) {
+				t.Errorf("output differs from %s (run with -update to accept):\n%s", path, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PackFunc is the signature shared by the packing algorithms.
+type PackFunc func(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) PackingResult
+
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = map[string]PackFunc{
+		// First-fit decreasing; delegates to the quota packer when Config.Quota is set.
+		"ffd": BinPackWorkloadsWithConfig,
+		// The quota-aware packer, also without any quota configured.
+		"quota": func(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) PackingResult {
+			if cfg.Quota == nil {
+				cfg.Quota = QuotaMap{}
+			}
+			return BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+		},
+		// Online first-fit in arrival order, without sorting.
+		"online": BinPackWorkloadsOnline,
+	}
+)
+
+/*
+RegisterPackingAlgorithm makes a packing algorithm available by name, e.g. to CLIs and to the
+shared invariant tests, which every registered algorithm must pass: each workload is either
+packed exactly once or reported in PackingResult.Unpacked, no VM is overcommitted, every VM has
+a unique PackedVM.ID, and the result is deterministic for a fixed Config. It panics if name is already registered.
+*/
+func RegisterPackingAlgorithm(name string, fn PackFunc) {
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+	if _, dup := algorithms[name]; dup {
+		panic(fmt.Sprintf("resolver: packing algorithm %q registered twice", name))
+	}
+	algorithms[name] = fn
+}
+
+// PackingAlgorithm returns the packing algorithm registered under name.
+func PackingAlgorithm(name string) (PackFunc, bool) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+	fn, ok := algorithms[name]
+	return fn, ok
+}
+
+// DefaultPackingAlgorithm is the packing algorithm Config.Algorithm defaults to.
+const DefaultPackingAlgorithm = "ffd"
+
+// ValidatePackingAlgorithm returns an error unless name is empty or a registered packing
+// algorithm.
+func ValidatePackingAlgorithm(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := PackingAlgorithm(name); !ok {
+		return fmt.Errorf("unknown packing algorithm %q (want one of %s)", name, strings.Join(PackingAlgorithms(), ", "))
+	}
+	return nil
+}
+
+// PackingAlgorithms returns the names of all registered packing algorithms, sorted.
+func PackingAlgorithms() []string {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,229 @@
+package resolver
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+PackApprox packs workloads approximately, for traces of so many small workloads that packing
+each one is needlessly slow. It aggregates shape-identical workloads, those with equal ShapeHash
+and headroom marker, into groups, selects an instance type once per group, and fills VMs of it
+with as many of the group's workloads as fit on one, counted by integer division of the VM's
+capacity by the shape. Groups are taken largest first by vCPUs plus GiB, and a VM never holds
+more than one group, so each group leaves at most one partially filled VM.
+
+Not mixing groups is the whole approximation: exact packing also fills the space one group
+leaves on a VM with workloads of other groups. NewApproxReport bounds what that costs. Quotas,
+limits and capacity reservations depend on the order VMs are provisioned in, so under any of
+them PackApprox packs exactly with the algorithm of cfg.Algorithm instead.
+*/
+func PackApprox(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) PackingResult {
+	if !cfg.approximable() {
+		cfg.Approx = false
+		return cfg.packer()(workloads, candidates, cfg)
+	}
+	cfg = cfg.forRun()
+	workloads = withHeadroom(workloads, cfg.Headroom)
+	var result PackingResult
+	for _, group := range shapeGroups(workloads) {
+		w := group[0]
+		vm, score := AzureInstanceSpec{}, 0.0
+		if len(candidates) > 0 {
+			vm, score = selectWithConfig(candidates, w, cfg)
+		}
+		var reason string
+		perVM := 0
+		switch {
+		case len(candidates) == 0:
+			reason = ReasonNoInstanceTypes
+		case vm.Name == "":
+			reason = ReasonNoCandidates
+		case cfg.poorFit(vm, w, -1):
+			reason = ReasonPoorFit
+		default:
+			perVM = shapesPerVM(cfg.vmCapacity(vm), w, len(group))
+			if perVM == 0 {
+				reason = ReasonSelectedTooSmall
+			}
+		}
+		if reason != "" {
+			for _, u := range group {
+				result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: u, Reason: reason})
+			}
+			continue
+		}
+		for start := 0; start < len(group); start += perVM {
+			end := minInt(start+perVM, len(group))
+			id := VMID(len(result.VMs) + 1)
+			result.VMs = append(result.VMs, PackedVM{
+				ID:           id,
+				InstanceType: vm,
+				Workloads:    group[start:end:end],
+				Decision:     cfg.audit(candidates, w, vm, score, id),
+				CapacityType: capacityTypeFor(vm, w, false),
+			})
+		}
+	}
+	assignZones(result.VMs)
+	return result
+}
+
+// approximable reports whether PackApprox approximates under c rather than packing exactly.
+func (c Config) approximable() bool {
+	return c.Quota == nil && c.Limits == (Limits{}) && len(c.CapacityReservations) == 0
+}
+
+// groupKey identifies the group of a workload in PackApprox. ShapeHash leaves out the
+// headroom marker, which changes how a workload counts against Config.MaxWorkloadsPerVM.
+type groupKey struct {
+	hash     uint64
+	headroom bool
+}
+
+// shapeGroups returns the workloads grouped by groupKey, in the order of their first workload,
+// then real workloads before headroom buffers and by descending vCPUs plus GiB.
+func shapeGroups(workloads WorkloadSet) []WorkloadSet {
+	index := make(map[groupKey]int)
+	var groups []WorkloadSet
+	for _, w := range workloads {
+		key := groupKey{hash: w.ShapeHash(), headroom: w.Headroom}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], w)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i][0], groups[j][0]
+		if a.Headroom != b.Headroom {
+			return !a.Headroom
+		}
+		return float64(a.CPURequirements)+a.MemoryRequirements > float64(b.CPURequirements)+b.MemoryRequirements
+	})
+	return groups
+}
+
+// shapesPerVM returns how many workloads of w's shape, at most n, fit in the capacity free: the
+// fewest any resource holds by integer division, settled against the fit epsilon with holds.
+func shapesPerVM(free capacity, w WorkloadProfile, n int) int {
+	k := float64(n)
+	for _, d := range shapeDemands(free, w) {
+		if d.request > 0 {
+			k = min(k, math.Floor(d.available/d.request))
+		}
+	}
+	if !w.Headroom {
+		k = min(k, math.Floor(free.workloads))
+	}
+	count := int(max(k, 0))
+	for count > 0 && !free.holds(w, count) {
+		count--
+	}
+	for count < n && free.holds(w, count+1) {
+		count++
+	}
+	return count
+}
+
+// shapeDemand is a workload's request of one resource and the capacity available for it.
+type shapeDemand struct {
+	request, available float64
+}
+
+// shapeDemands returns the request of w and the capacity of c for each resource fits checks,
+// bar the workload count.
+func shapeDemands(c capacity, w WorkloadProfile) []shapeDemand {
+	limitCPU, limitMem := limitsOf(w)
+	return []shapeDemand{
+		{float64(w.CPURequirements), c.cpu},
+		{w.MemoryRequirements, c.memoryGiB},
+		{w.NetworkRequirementsMbps, c.bandwidthMbps},
+		{w.IOPSRequirements, c.diskIOPS},
+		{w.ThroughputMBpsRequirements, c.diskMBps},
+		{limitCPU, c.limitCPU},
+		{limitMem, c.limitMemoryGiB},
+	}
+}
+
+// holds reports whether k workloads of w's shape fit in the remaining capacity together.
+func (c capacity) holds(w WorkloadProfile, k int) bool {
+	n := float64(k)
+	for _, d := range shapeDemands(c, w) {
+		if !fitsWithin(n*d.request, d.available) {
+			return false
+		}
+	}
+	return w.Headroom || c.workloads >= n
+}
+
+// ApproxReport is the error bound of a PackApprox packing.
+type ApproxReport struct {
+	Workloads int // workloads packed
+	Groups    int // shape groups they were aggregated into
+	// LowerBound is an hourly cost no packing of the packed workloads goes below, the exact
+	// one included; ErrorBound is the packing's cost less it, so the approximation costs at most
+	// ErrorBound more than exact packing.
+	LowerBound float64
+	ErrorBound float64
+}
+
+/*
+NewApproxReport returns the error bound of result, a PackApprox packing of candidates under cfg.
+
+The lower bound holds for any packing that places the same workloads. Every workload on a VM
+takes a share of its capacity in each resource, and those shares add up to at most the whole
+VM, so a VM costs at least its price times the shares of its workloads in any one resource.
+Charging each workload, per resource, the cheapest such share among the candidates that can
+hold it therefore underestimates the cost of any packing, and the largest of these per-resource
+sums is the lower bound. Workload slots count as a resource under Config.MaxWorkloadsPerVM.
+*/
+func NewApproxReport(result PackingResult, candidates []AzureInstanceSpec, cfg Config) ApproxReport {
+	var packed WorkloadSet
+	for _, vm := range result.VMs {
+		packed = append(packed, vm.Workloads...)
+	}
+	groups := shapeGroups(packed)
+	capacities := make([]capacity, len(candidates))
+	for i, vm := range candidates {
+		capacities[i] = cfg.vmCapacity(vm)
+	}
+	// One sum per resource of shapeDemands, and one for workload slots.
+	var sums [8]float64
+	for _, group := range groups {
+		w := group[0]
+		cheapest := [8]float64{}
+		for i := range cheapest {
+			cheapest[i] = math.Inf(1)
+		}
+		for i, vm := range candidates {
+			free := capacities[i]
+			if !free.fits(w) {
+				continue
+			}
+			shares := [8]float64{}
+			for r, d := range shapeDemands(free, w) {
+				shares[r] = share(d.request, d.available)
+			}
+			if !w.Headroom {
+				shares[7] = share(1, free.workloads)
+			}
+			for r, s := range shares {
+				cheapest[r] = min(cheapest[r], vm.PricePerHour*s)
+			}
+		}
+		for r, c := range cheapest {
+			if !math.IsInf(c, 1) {
+				sums[r] += float64(len(group)) * c
+			}
+		}
+	}
+	report := ApproxReport{Workloads: len(packed), Groups: len(groups)}
+	for _, s := range sums {
+		report.LowerBound = max(report.LowerBound, s)
+	}
+	report.ErrorBound = max(TotalCost(result.VMs)-report.LowerBound, 0)
+	return report
+}
@@ -0,0 +1,94 @@
+package resolver
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPackApprox(t *testing.T) {
+	skus := []AzureInstanceSpec{{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := make(WorkloadSet, 10)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{Name: "w" + string(rune('a'+i)), CPURequirements: 1, MemoryRequirements: 2}
+	}
+	result := PackApprox(workloads, skus, Config{})
+	var perVM []int
+	for _, vm := range result.VMs {
+		perVM = append(perVM, len(vm.Workloads))
+	}
+	if !reflect.DeepEqual(perVM, []int{4, 4, 2}) || len(result.Unpacked) != 0 {
+		t.Errorf("expected 10 one-vCPU workloads on VMs of 4, 4 and 2, got %v with %d unpacked", perVM, len(result.Unpacked))
+	}
+	report := NewApproxReport(result, skus, Config{})
+	if report.Workloads != 10 || report.Groups != 1 || math.Abs(report.LowerBound-0.5) > 1e-9 || math.Abs(report.ErrorBound-0.1) > 1e-9 {
+		t.Errorf("expected one group of 10 with a lower bound of 0.5/h and an error bound of 0.1/h, got %+v", report)
+	}
+
+	result = PackApprox(workloads, skus, Config{MaxWorkloadsPerVM: 3})
+	if len(result.VMs) != 4 || len(result.VMs[0].Workloads) != 3 {
+		t.Errorf("expected 4 VMs of at most 3 workloads, got %d", len(result.VMs))
+	}
+	workloads[0].CPURequirements = 8
+	result = PackApprox(workloads, skus, Config{})
+	if len(result.Unpacked) != 1 || result.Unpacked[0].Workload.Name != "wa" || result.Unpacked[0].Reason != ReasonSelectedTooSmall {
+		t.Errorf("expected the 8-vCPU workload unpacked as too big for the D4s_v5, got %+v", result.Unpacked)
+	}
+}
+
+func TestPackApproxWithinErrorBound(t *testing.T) {
+	skus, err := LoadAzureInstanceSpecs(filepath.Join("testdata", "golden", "skus.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workloads, err := loadCustomWorkloads(filepath.Join("testdata", "golden", "workloads.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive} {
+		cfg := Config{Strategy: strategy}
+		exact := BinPackWorkloadsWithConfig(workloads, skus, cfg)
+		approx := PackApprox(workloads, skus, cfg)
+		report := NewApproxReport(approx, skus, cfg)
+		if report.Workloads+len(approx.Unpacked) != len(workloads) || report.Groups >= report.Workloads {
+			t.Errorf("%s: expected %d workloads in fewer groups, got %+v with %d unpacked", strategy, len(workloads), report, len(approx.Unpacked))
+		}
+		exactCost, approxCost := TotalCost(exact.VMs), TotalCost(approx.VMs)
+		if exactCost < report.LowerBound-1e-9 {
+			t.Errorf("%s: exact packing costs %.4f/h, below the lower bound %.4f/h", strategy, exactCost, report.LowerBound)
+		}
+		if approxCost-exactCost > report.ErrorBound+1e-9 {
+			t.Errorf("%s: approximation costs %.4f/h, more than %.4f/h above exact packing at %.4f/h", strategy, approxCost, report.ErrorBound, exactCost)
+		}
+	}
+}
+
+// TestPackApproxInvariants checks PackApprox against the shared packing invariants, and its
+// error bound against exact packing, on the random cases of the invariant tests.
+func TestPackApproxInvariants(t *testing.T) {
+	for seed := int64(0); seed < int64(*invariantRounds); seed++ {
+		workloads, skus, cfg := invariantCase(seed)
+		cfg.ExplorationTopK = 0
+		if v := checkInvariants(PackApprox, workloads, skus, cfg); v != "" {
+			t.Errorf("seed %d: %s", seed, v)
+		}
+		if !cfg.approximable() {
+			continue
+		}
+		approx := PackApprox(workloads, skus, cfg)
+		exact := BinPackWorkloadsWithConfig(workloads, skus, cfg)
+		report := NewApproxReport(approx, skus, cfg)
+		if diff := TotalCost(approx.VMs) - TotalCost(exact.VMs); diff > report.ErrorBound+1e-9 {
+			t.Errorf("seed %d: approximation costs %.4f/h more than exact packing, above its error bound %.4f/h", seed, diff, report.ErrorBound)
+		}
+	}
+}
+
+func TestPackApproxFallsBackUnderQuota(t *testing.T) {
+	workloads, skus, cfg := invariantCase(7)
+	cfg.Quota = QuotaMap{"D": 16}
+	if got, want := PackApprox(workloads, skus, cfg), BinPackWorkloadsWithConfig(workloads, skus, cfg); !reflect.DeepEqual(got, want) {
+		t.Error("expected PackApprox to pack exactly under a quota")
+	}
+}
@@ -0,0 +1,100 @@
+package resolver
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CPU architectures of AzureInstanceSpec.Architecture and WorkloadProfile.Architecture, as
+// Kubernetes names them in the kubernetes.io/arch label.
+const (
+	ArchAMD64 = "amd64"
+	ArchARM64 = "arm64"
+)
+
+/*
+InstanceArchitecture returns the CPU architecture of vm: its Architecture, or else the one its
+size name implies. Azure marks Arm-based sizes with the additive feature "p", as in
+Standard_D4ps_v5 or Standard_E8pds_v5; all others are amd64.
+*/
+func InstanceArchitecture(vm AzureInstanceSpec) string {
+	if vm.Architecture != "" {
+		return vm.Architecture
+	}
+	s := strings.TrimPrefix(vm.Name, "Standard_")
+	if i := strings.IndexByte(s, '_'); i >= 0 {
+		s = s[:i]
+	}
+	// The features follow the vCPU count, e.g. "ps" in "D4ps"
+	start := strings.IndexFunc(s, unicode.IsDigit)
+	if start < 0 {
+		return ArchAMD64
+	}
+	features := strings.TrimLeftFunc(s[start:], unicode.IsDigit)
+	if strings.ContainsRune(features, 'p') {
+		return ArchARM64
+	}
+	return ArchAMD64
+}
+
+// FilterByArchitecture rejects instance types of another architecture than the workload's
+// images are built for. Workloads without an Architecture run on either.
+func FilterByArchitecture(inst AzureInstanceSpec, workload WorkloadProfile) bool {
+	return workload.Architecture == "" || strings.EqualFold(InstanceArchitecture(inst), workload.Architecture)
+}
+
+// ArchMigration is a workload the multi-arch packing of RunArchComparison placed on an arm64 VM,
+// which it needs an arm64 image for.
+type ArchMigration struct {
+	Workload string // WorkloadProfile.ID
+	VM       string // PackedVM.ID
+	SKU      string
+}
+
+// ArchComparison is the outcome of RunArchComparison.
+type ArchComparison struct {
+	// AMD64 packs onto the amd64 candidates only; MultiArch lets the workloads without an
+	// Architecture run on arm64 candidates too.
+	AMD64     SimulationResult
+	MultiArch SimulationResult
+	// CostDelta is the hourly cost of MultiArch less that of AMD64, negative when arm64 saves;
+	// SavingsPercent is the saving relative to AMD64.
+	CostDelta      float64
+	SavingsPercent float64
+	// Migrations lists the workloads MultiArch placed on arm64 VMs, in VM order.
+	Migrations []ArchMigration `json:",omitempty"`
+}
+
+/*
+RunArchComparison packs workloads twice with cfg's packing algorithm: once onto the amd64
+candidates only, as a cluster without arm64 images runs, and once onto all candidates, where
+workloads without an Architecture may also land on arm64 instance types (see
+InstanceArchitecture). It returns both results, the cost delta and the workloads that would
+need arm64 images. Workloads requiring arm64 are unpacked in the amd64 run.
+*/
+func RunArchComparison(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) ArchComparison {
+	var amd64 []AzureInstanceSpec
+	for _, c := range candidates {
+		if InstanceArchitecture(c) == ArchAMD64 {
+			amd64 = append(amd64, c)
+		}
+	}
+	pack := cfg.packer()
+	single, multi := pack(workloads, amd64, cfg), pack(workloads, candidates, cfg)
+	cmp := ArchComparison{AMD64: cfg.Summarize(single), MultiArch: cfg.Summarize(multi)}
+	cmp.CostDelta = cmp.MultiArch.TotalCost - cmp.AMD64.TotalCost
+	if cmp.AMD64.TotalCost > 0 {
+		cmp.SavingsPercent = -100 * cmp.CostDelta / cmp.AMD64.TotalCost
+	}
+	for _, vm := range multi.VMs {
+		if InstanceArchitecture(vm.InstanceType) != ArchARM64 {
+			continue
+		}
+		for _, w := range vm.Workloads {
+			if !w.Headroom && w.Architecture == "" {
+				cmp.Migrations = append(cmp.Migrations, ArchMigration{Workload: w.ID(), VM: vm.ID, SKU: vm.InstanceType.Name})
+			}
+		}
+	}
+	return cmp
+}
@@ -0,0 +1,63 @@
+package resolver
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestInstanceArchitecture(t *testing.T) {
+	for name, want := range map[string]string{
+		"Standard_D4s_v5":     ArchAMD64,
+		"Standard_D4ps_v5":    ArchARM64,
+		"Standard_D4pls_v5":   ArchARM64,
+		"Standard_E8pds_v5":   ArchARM64,
+		"Standard_D4_v3":      ArchAMD64,
+		"Standard_NC6":        ArchAMD64,
+		"Standard_HB120rs_v3": ArchAMD64,
+	} {
+		if got := InstanceArchitecture(AzureInstanceSpec{Name: name}); got != want {
+			t.Errorf("%s: expected %s, got %s", name, want, got)
+		}
+	}
+	if got := InstanceArchitecture(AzureInstanceSpec{Name: "custom", Architecture: ArchARM64}); got != ArchARM64 {
+		t.Errorf("expected the explicit architecture, got %s", got)
+	}
+}
+
+func TestRunArchComparison(t *testing.T) {
+	// The Dpsv5 sizes cost 20% less than the Dsv5 sizes of the same shape.
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		{Name: "Standard_D4ps_v5", Family: "Dpsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.16},
+	}
+	workloads := WorkloadSet{
+		{Name: "web", Namespace: "shop", CPURequirements: 4, MemoryRequirements: 8},
+		{Name: "legacy", Namespace: "shop", CPURequirements: 4, MemoryRequirements: 8, Architecture: ArchAMD64},
+	}
+	cmp := RunArchComparison(workloads, candidates, Config{})
+	if cmp.AMD64.TotalCost != 0.4 || math.Abs(cmp.MultiArch.TotalCost-0.36) > 1e-9 {
+		t.Fatalf("expected 0.40/h amd64-only and 0.36/h multi-arch, got %.4f and %.4f", cmp.AMD64.TotalCost, cmp.MultiArch.TotalCost)
+	}
+	if math.Abs(cmp.CostDelta+0.04) > 1e-9 || math.Abs(cmp.SavingsPercent-10) > 1e-9 {
+		t.Errorf("expected a delta of -0.04/h (10%% savings), got %.4f (%.1f%%)", cmp.CostDelta, cmp.SavingsPercent)
+	}
+	want := []ArchMigration{{Workload: "shop/web", VM: cmp.MultiArch.VMs[0].ID, SKU: "Standard_D4ps_v5"}}
+	if cmp.MultiArch.VMs[0].SKU != "Standard_D4ps_v5" {
+		want[0].VM = cmp.MultiArch.VMs[1].ID
+	}
+	if !reflect.DeepEqual(cmp.Migrations, want) {
+		t.Errorf("expected migrations %+v, got %+v", want, cmp.Migrations)
+	}
+	for _, vm := range cmp.AMD64.VMs {
+		if vm.SKU != "Standard_D4s_v5" {
+			t.Errorf("expected only amd64 VMs without arm64, got %s", vm.SKU)
+		}
+	}
+
+	// An arm64-only workload cannot run without arm64 candidates.
+	arm := RunArchComparison(WorkloadSet{{Name: "arm", CPURequirements: 2, MemoryRequirements: 4, Architecture: ArchARM64}}, candidates, Config{})
+	if arm.AMD64.Unpacked != 1 || arm.MultiArch.Unpacked != 0 || len(arm.Migrations) != 0 {
+		t.Errorf("expected the arm64 workload unpacked only without arm64, and no migration, got %+v", arm)
+	}
+}
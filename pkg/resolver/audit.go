@@ -0,0 +1,129 @@
+package resolver
+
+// auditAlternatives is the number of top-ranked candidates recorded in a Decision.
+const auditAlternatives = 3
+
+// Decision explains why a VM's instance type was selected.
+type Decision struct {
+	VM           string          // PackedVM.ID of the VM the decision provisioned
+	SeedWorkload WorkloadProfile // the workload the instance type was selected for
+	Selected     string
+	Score        float64
+	Explored     bool          // picked by weighted-random exploration rather than the best score
+	Candidates   int           // candidates before filtering
+	FilterSteps  []FilterStep  // filters that removed candidates, in evaluation order
+	Alternatives []Alternative // top-ranked candidates, best first
+	Preferences  []string      // keys of the seed workload's Preferences the selected instance type satisfies
+}
+
+// FilterStep records how much one filter trimmed the candidate set.
+type FilterStep struct {
+	Filter    string
+	Removed   int
+	Remaining int
+}
+
+// Alternative is a ranked candidate considered for a Decision.
+type Alternative struct {
+	Name         string
+	Score        float64
+	PricePerHour float64
+}
+
+/*
+audit reconstructs the decision that selected vm for the seed workload and provisioned the VM
+with ID id, or returns nil when auditing is disabled. It replays the filter chain one filter at
+a time and re-ranks the remaining candidates, so it only runs once per provisioned VM and never
+on the selection hot path.
+*/
+func (c Config) audit(candidates []AzureInstanceSpec, seed WorkloadProfile, vm AzureInstanceSpec, score float64, id string) *Decision {
+	if !c.WithAudit {
+		return nil
+	}
+	d := &Decision{
+		VM:           id,
+		SeedWorkload: seed,
+		Selected:     vm.Name,
+		Score:        score,
+		Explored:     c.exploring(),
+		Candidates:   len(candidates),
+		Preferences:  SatisfiedPreferences(vm, seed),
+	}
+	var remaining []AzureInstanceSpec
+	d.FilterSteps, remaining = c.filterSteps(candidates, seed)
+	scoreFunc := func(vm AzureInstanceSpec, w WorkloadProfile) float64 {
+		return ScoreInstanceWithConfig(vm, w, c)
+	}
+	entries := rankEntries(remaining, seed, scoreFunc, c.FamilyPreferences)
+	for i := 0; i < len(entries) && i < auditAlternatives; i++ {
+		alt := remaining[entries[i].idx]
+		d.Alternatives = append(d.Alternatives, Alternative{Name: alt.Name, Score: entries[i].score, PricePerHour: alt.PricePerHour})
+	}
+	return d
+}
+
+// filterSteps replays the filter chain and PruneTopN over candidates for w one step at a time,
+// returning the steps that removed candidates and the candidates left.
+func (c Config) filterSteps(candidates []AzureInstanceSpec, w WorkloadProfile) ([]FilterStep, []AzureInstanceSpec) {
+	var steps []FilterStep
+	remaining := candidates
+	for _, f := range c.filters() {
+		kept := FilterInstanceTypes(remaining, w, f.Filter)
+		if removed := len(remaining) - len(kept); removed > 0 {
+			steps = append(steps, FilterStep{Filter: f.Name, Removed: removed, Remaining: len(kept)})
+		}
+		remaining = kept
+	}
+	if c.PruneTopN > 0 {
+		kept := pruneCheapest(remaining, w, c.PruneTopN)
+		if removed := len(remaining) - len(kept); removed > 0 {
+			steps = append(steps, FilterStep{Filter: "prune-cheapest", Removed: removed, Remaining: len(kept)})
+		}
+		remaining = kept
+	}
+	return steps, remaining
+}
+
+// SelectionExplanation explains how one workload's candidates were ranked (see ExplainSelection).
+type SelectionExplanation struct {
+	Workload    WorkloadProfile
+	Candidates  int               // candidates before filtering
+	FilterSteps []FilterStep      // filters that removed candidates, in evaluation order
+	Ranked      []RankedCandidate // candidates able to hold the workload, best first
+	// Preferences holds, per entry of Ranked, the keys of the workload's Preferences it satisfies.
+	Preferences [][]string
+	// Doomed says which requirement no candidate meets when Ranked is empty, nil otherwise.
+	Doomed *DoomedWorkload
+}
+
+/*
+ExplainSelection ranks up to n candidates for workload the way SelectTopN does under cfg, and
+records how the filters trimmed the candidates on the way, e.g. to answer "what would the
+resolver pick for this pod?" from the CLI. n <= 0 ranks all of them. When no candidate can hold
+the workload, Doomed names the limiting requirement (see PrecheckWorkloads).
+*/
+func ExplainSelection(candidates []AzureInstanceSpec, workload WorkloadProfile, cfg Config, n int) SelectionExplanation {
+	e := SelectionExplanation{Workload: workload, Candidates: len(candidates)}
+	cfg.PruneTopN = 0 // SelectTopN ranks every candidate that passes the filters
+	steps, remaining := cfg.filterSteps(candidates, workload)
+	fit := 0
+	for _, vm := range remaining {
+		if capacityOf(vm, cfg.FitMarginPercent).fits(workload) {
+			fit++
+		}
+	}
+	if removed := len(remaining) - fit; removed > 0 {
+		steps = append(steps, FilterStep{Filter: "capacity", Removed: removed, Remaining: fit})
+	}
+	e.FilterSteps = steps
+	e.Ranked = cfg.topN(candidates, workload, n)
+	for _, r := range e.Ranked {
+		e.Preferences = append(e.Preferences, SatisfiedPreferences(r.Instance, workload))
+	}
+	if len(e.Ranked) == 0 {
+		if doomed := precheck(WorkloadSet{workload}, candidates, cfg.filters()); len(doomed) > 0 {
+			e.Doomed = &doomed[0]
+		}
+	}
+	return e
+}
@@ -0,0 +1,66 @@
+package resolver
+
+import "testing"
+
+func TestBinPackWorkloadsWithConfig_Audit(t *testing.T) {
+	candidates := dummyInstanceTypes()
+	names := make(map[string]bool)
+	for _, c := range candidates {
+		names[c.Name] = true
+	}
+	workloads := WorkloadSet{
+		{CPURequirements: 1, MemoryRequirements: 2},
+		{CPURequirements: 1, MemoryRequirements: 2, Zone: "2"},
+		{CPURequirements: 1, MemoryRequirements: 4, GPURequirements: 1},
+	}
+	for _, cfg := range []Config{{WithAudit: true}, {WithAudit: true, Quota: QuotaMap{}}} {
+		result := BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+		if len(result.VMs) == 0 {
+			t.Fatalf("expected VMs to be provisioned")
+		}
+		for i, vm := range result.VMs {
+			d := vm.Decision
+			if d == nil {
+				t.Fatalf("VM %d has no decision", i)
+			}
+			if d.Selected != vm.InstanceType.Name || d.Candidates != len(candidates) {
+				t.Errorf("VM %d: unexpected decision %+v", i, d)
+			}
+			if len(d.Alternatives) == 0 || d.Alternatives[0].Name != d.Selected {
+				t.Errorf("VM %d: expected the selected SKU to rank first, got %+v", i, d.Alternatives)
+			}
+			for _, alt := range d.Alternatives {
+				if !names[alt.Name] {
+					t.Errorf("VM %d: alternative %q is not a candidate", i, alt.Name)
+				}
+			}
+			if d.SeedWorkload.GPURequirements > 0 && (len(d.FilterSteps) == 0 || d.FilterSteps[0].Filter != "gpu") {
+				t.Errorf("VM %d: expected the gpu filter to trim candidates, got %+v", i, d.FilterSteps)
+			}
+		}
+	}
+	if vm := BinPackWorkloadsWithConfig(workloads, candidates, Config{}).VMs[0]; vm.Decision != nil {
+		t.Errorf("expected no decision without WithAudit")
+	}
+}
+
+func TestExplainSelection(t *testing.T) {
+	candidates := dummyInstanceTypes()
+	workload := WorkloadProfile{CPURequirements: 1, MemoryRequirements: 4, GPURequirements: 1}
+	e := ExplainSelection(candidates, workload, Config{}, 2)
+	top := SelectTopN(candidates, workload, "", 2)
+	if len(e.Ranked) != len(top) || len(e.Ranked) == 0 || e.Ranked[0].Instance.Name != top[0].Instance.Name {
+		t.Fatalf("expected the ranking of SelectTopN %+v, got %+v", top, e.Ranked)
+	}
+	if e.Candidates != len(candidates) || len(e.FilterSteps) == 0 || e.FilterSteps[0].Filter != "gpu" {
+		t.Errorf("expected the gpu filter to trim %d candidates, got %+v", len(candidates), e.FilterSteps)
+	}
+	if len(e.Preferences) != len(e.Ranked) || e.Doomed != nil {
+		t.Errorf("expected preferences per ranked candidate and nothing doomed, got %+v", e)
+	}
+
+	e = ExplainSelection(candidates, WorkloadProfile{CPURequirements: 1, MemoryRequirements: 1, Zone: "9"}, Config{}, 0)
+	if len(e.Ranked) != 0 || e.Doomed == nil || e.Doomed.Constraint != "zone" {
+		t.Errorf("expected the zone to be the limiting requirement, got %+v", e.Doomed)
+	}
+}
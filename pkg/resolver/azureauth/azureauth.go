@@ -0,0 +1,36 @@
+/*
+Package azureauth adapts Azure SDK credentials to the resolver, so that the resolver itself
+does not depend on the Azure SDK. Use it to authenticate trace downloads from private blob
+containers:
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	...
+	cfg.Trace.Credential = azureauth.TokenSource(cred)
+*/
+package azureauth
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// TokenSource returns a resolver.TokenSource issuing the tokens of cred.
+func TokenSource(cred azcore.TokenCredential) resolver.TokenSource {
+	return tokenSource{cred}
+}
+
+type tokenSource struct {
+	cred azcore.TokenCredential
+}
+
+func (s tokenSource) Token(ctx context.Context, scope string) (string, error) {
+	tok, err := s.cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		return "", err
+	}
+	return tok.Token, nil
+}
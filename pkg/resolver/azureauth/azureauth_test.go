@@ -0,0 +1,31 @@
+package azureauth
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+type staticCredential struct {
+	scopes []string
+}
+
+func (c *staticCredential) GetToken(_ context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.scopes = opts.Scopes
+	return azcore.AccessToken{Token: "tok", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestTokenSource(t *testing.T) {
+	cred := &staticCredential{}
+	tok, err := TokenSource(cred).Token(context.Background(), "https://storage.azure.com/.default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok != "tok" || !slices.Equal(cred.scopes, []string{"https://storage.azure.com/.default"}) {
+		t.Errorf("expected the credential's token for the storage scope, got %q for %v", tok, cred.scopes)
+	}
+}
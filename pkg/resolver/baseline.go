@@ -0,0 +1,183 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// FleetEntry is a number of VMs of one SKU in a BaselineFleet.
+type FleetEntry struct {
+	SKU   string
+	Count int
+}
+
+// BaselineFleet is a fixed fleet of VMs, e.g. a cluster's current nodes, to compare a simulated
+// packing against (see Config.Baseline and CompareToBaseline).
+type BaselineFleet []FleetEntry
+
+// LoadBaselineFleet loads a JSON list of FleetEntry, e.g. [{"SKU": "Standard_D8s_v5", "Count": 12}].
+// An empty path means none.
+func LoadBaselineFleet(path string) (BaselineFleet, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	var fleet BaselineFleet
+	if err := json.Unmarshal(data, &fleet); err != nil {
+		return nil, err
+	}
+	for i, e := range fleet {
+		if e.SKU == "" || e.Count < 0 {
+			return nil, fmt.Errorf("baseline entry %d: SKU required, count must not be negative", i)
+		}
+	}
+	return fleet, nil
+}
+
+// FleetCapacity is the size and hourly cost of a fleet of VMs.
+type FleetCapacity struct {
+	VMs         int
+	VCpus       int
+	MemoryGiB   float64
+	GPUs        int
+	CostPerHour float64
+}
+
+// fleetCapacity sums the capacity and prices of vms.
+func fleetCapacity(vms []AzureInstanceSpec) FleetCapacity {
+	c := FleetCapacity{VMs: len(vms)}
+	for _, vm := range vms {
+		c.VCpus += vm.VCpus
+		c.MemoryGiB += vm.MemoryGiB
+		c.GPUs += vm.GPUCount
+		c.CostPerHour += vm.PricePerHour
+	}
+	return c
+}
+
+// BaselineComparison compares a simulated packing to a BaselineFleet (see CompareToBaseline).
+type BaselineComparison struct {
+	Baseline  FleetCapacity
+	Simulated FleetCapacity
+	// RequestedVCpus, RequestedMemoryGiB and RequestedGPUs are what the workloads request; the
+	// headroom of a fleet is its capacity less these.
+	RequestedVCpus     int
+	RequestedMemoryGiB float64
+	RequestedGPUs      int
+	// Workloads is the number of workloads compared and Unplaced the number of those that do
+	// not fit on the baseline fleet.
+	Workloads int
+	Unplaced  int
+	// CostDelta is the simulated hourly cost less the baseline's, negative when the simulated
+	// packing saves; SavingsPercent is the saving relative to the baseline.
+	CostDelta      float64
+	SavingsPercent float64
+}
+
+// Fits reports whether every workload fits on the baseline fleet.
+func (c BaselineComparison) Fits() bool {
+	return c.Unplaced == 0
+}
+
+/*
+CompareToBaseline compares the simulated VMs that hold workloads to a fixed fleet: the cost and
+capacity of both, priced and sized from the SKUs of candidates, and whether the workloads fit on
+the fleet at all. The fit is checked by first-fit decreasing onto the fleet's VMs under cfg's
+filters, fit margin and per-VM workload limit, so a fleet it reports as too small may still be
+too small in a real cluster, and one it reports as fitting may need a better packing than the
+scheduler finds. It fails for fleet SKUs missing from candidates.
+*/
+func CompareToBaseline(fleet BaselineFleet, workloads WorkloadSet, candidates []AzureInstanceSpec, simulated []PackedVM, cfg Config) (BaselineComparison, error) {
+	skus := make(map[string]AzureInstanceSpec, len(candidates))
+	for _, c := range candidates {
+		skus[c.Name] = c
+	}
+	var baseline []AzureInstanceSpec
+	for _, e := range fleet {
+		sku, ok := skus[e.SKU]
+		if !ok {
+			return BaselineComparison{}, fmt.Errorf("baseline SKU %s is not in the SKU data", e.SKU)
+		}
+		for i := 0; i < e.Count; i++ {
+			baseline = append(baseline, sku)
+		}
+	}
+	sim := make([]AzureInstanceSpec, len(simulated))
+	for i, vm := range simulated {
+		sim[i] = vm.InstanceType
+	}
+	c := BaselineComparison{Baseline: fleetCapacity(baseline), Simulated: fleetCapacity(sim)}
+	var real WorkloadSet
+	for _, w := range workloads {
+		if w.Headroom {
+			continue
+		}
+		real = append(real, w)
+		c.RequestedVCpus += w.CPURequirements
+		c.RequestedMemoryGiB += w.MemoryRequirements
+		c.RequestedGPUs += w.gpusRequired()
+	}
+	c.Workloads = len(real)
+	c.Unplaced = cfg.unplacedOnFleet(real, baseline)
+	c.CostDelta = c.Simulated.CostPerHour - c.Baseline.CostPerHour
+	if c.Baseline.CostPerHour > 0 {
+		c.SavingsPercent = -100 * c.CostDelta / c.Baseline.CostPerHour
+	}
+	return c, nil
+}
+
+// unplacedOnFleet packs workloads onto the VMs of fleet by first-fit decreasing and returns how
+// many do not fit.
+func (c Config) unplacedOnFleet(workloads WorkloadSet, fleet []AzureInstanceSpec) int {
+	sorted := make(WorkloadSet, len(workloads))
+	copy(sorted, workloads)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return float64(sorted[i].CPURequirements)+sorted[i].MemoryRequirements >
+			float64(sorted[j].CPURequirements)+sorted[j].MemoryRequirements
+	})
+	free := make([]capacity, len(fleet))
+	for i, vm := range fleet {
+		free[i] = c.vmCapacity(vm)
+	}
+	filters := c.filterFuncs()
+	unplaced := 0
+	for _, w := range sorted {
+		allowed := make(map[string]bool) // by SKU, as the fleet repeats SKUs
+		placed := false
+		for i, vm := range fleet {
+			ok, seen := allowed[vm.Name]
+			if !seen {
+				ok = len(FilterInstanceTypes([]AzureInstanceSpec{vm}, w, filters...)) == 1
+				allowed[vm.Name] = ok
+			}
+			if ok && free[i].fits(w) {
+				free[i].take(w)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			unplaced++
+		}
+	}
+	return unplaced
+}
+
+// simulatedVMs rebuilds the VMs of a summarized packing from candidates, at the prices the
+// packing charged, e.g. a capacity reservation's.
+func simulatedVMs(result SimulationResult, candidates []AzureInstanceSpec) []PackedVM {
+	skus := make(map[string]AzureInstanceSpec, len(candidates))
+	for _, c := range candidates {
+		skus[c.Name] = c
+	}
+	vms := make([]PackedVM, len(result.VMs))
+	for i, d := range result.VMs {
+		vms[i] = PackedVM{ID: d.ID, InstanceType: skus[d.SKU]}
+		vms[i].InstanceType.Name, vms[i].InstanceType.PricePerHour = d.SKU, d.PricePerHour
+	}
+	return vms
+}
@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareToBaseline(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		{Name: "Standard_D8s_v5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.4},
+	}
+	workloads := WorkloadSet{{CPURequirements: 3, MemoryRequirements: 8}, {CPURequirements: 3, MemoryRequirements: 8}, {CPURequirements: 2, MemoryRequirements: 4}}
+	cfg := Config{Baseline: BaselineFleet{{SKU: "Standard_D8s_v5", Count: 3}}}
+	result, _, err := simulate(workloads, candidates, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmp := result.Baseline
+	if cmp == nil {
+		t.Fatal("expected a baseline comparison")
+	}
+	if !cmp.Fits() || cmp.Baseline.VMs != 3 || cmp.Baseline.VCpus != 24 || math.Abs(cmp.Baseline.CostPerHour-1.2) > 1e-9 {
+		t.Errorf("expected the workloads to fit on 3 D8s_v5 with 24 vCPUs at 1.2/h, got %+v", cmp)
+	}
+	if cmp.Simulated.CostPerHour != result.TotalCost || cmp.RequestedVCpus != 8 || cmp.RequestedMemoryGiB != 20 || math.Abs(cmp.SavingsPercent-50) > 1e-9 {
+		t.Errorf("expected the simulated packing to save over the baseline, got %+v", cmp)
+	}
+
+	// One D4s_v5 holds the 3- and the 2-vCPU workload at most, never both 3-vCPU ones.
+	small, err := CompareToBaseline(BaselineFleet{{SKU: "Standard_D4s_v5", Count: 2}}, workloads, candidates, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small.Fits() || small.Unplaced != 1 || small.Workloads != 3 {
+		t.Errorf("expected one workload not to fit on two D4s_v5, got %+v", small)
+	}
+	if _, err := CompareToBaseline(BaselineFleet{{SKU: "Standard_E4s_v5", Count: 1}}, workloads, candidates, nil, cfg); err == nil {
+		t.Error("expected an error for a baseline SKU missing from the SKU data")
+	}
+}
+
+func TestLoadBaselineFleet(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fleet.json")
+	if err := os.WriteFile(path, []byte(`[{"SKU": "Standard_D8s_v5", "Count": 12}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fleet, err := LoadBaselineFleet(path)
+	if err != nil || len(fleet) != 1 || fleet[0] != (FleetEntry{SKU: "Standard_D8s_v5", Count: 12}) {
+		t.Errorf("expected one entry of 12 D8s_v5, got %+v: %v", fleet, err)
+	}
+	if err := os.WriteFile(path, []byte(`[{"Count": 1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBaselineFleet(path); err == nil || !strings.Contains(err.Error(), "SKU required") {
+		t.Errorf("expected an entry without SKU to be rejected, got %v", err)
+	}
+	if fleet, err := LoadBaselineFleet(""); fleet != nil || err != nil {
+		t.Errorf("expected no baseline without a path, got %+v: %v", fleet, err)
+	}
+}
@@ -0,0 +1,125 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+PackingBasis is what workloads are sized by when packing (see Config.Basis): their requests,
+or for a rightsizing estimate their observed usage (UsageCPU and UsageMemory), optionally
+plus a safety margin. Workloads without recorded usage keep their requests under every basis.
+The zero value is BasisRequests.
+*/
+type PackingBasis struct {
+	// Usage sizes workloads by their observed usage instead of their requests.
+	Usage bool
+	// MarginPercent is added on top of usage, e.g. 20 sizes a workload at 1.2x its usage.
+	MarginPercent float64
+}
+
+// BasisRequests sizes workloads by their requests, BasisUsage by their observed usage.
+var (
+	BasisRequests = PackingBasis{}
+	BasisUsage    = PackingBasis{Usage: true}
+)
+
+// BasisUsagePlusMargin sizes workloads by their observed usage plus marginPercent.
+func BasisUsagePlusMargin(marginPercent float64) PackingBasis {
+	return PackingBasis{Usage: true, MarginPercent: marginPercent}
+}
+
+// ParsePackingBasis parses the --basis and --margin flags: "requests" or "usage", and the
+// margin in percent added to usage.
+func ParsePackingBasis(basis string, marginPercent float64) (PackingBasis, error) {
+	if !(marginPercent >= 0 && marginPercent <= 1000) {
+		return PackingBasis{}, fmt.Errorf("margin must be between 0 and 1000 percent, got %v", marginPercent)
+	}
+	switch basis {
+	case "", "requests":
+		if marginPercent != 0 {
+			return PackingBasis{}, fmt.Errorf("a margin only applies to the usage basis")
+		}
+		return BasisRequests, nil
+	case "usage":
+		return BasisUsagePlusMargin(marginPercent), nil
+	}
+	return PackingBasis{}, fmt.Errorf("unknown basis %q, expected requests or usage", basis)
+}
+
+// String returns "requests", "usage" or e.g. "usage+20%".
+func (b PackingBasis) String() string {
+	switch {
+	case !b.Usage:
+		return "requests"
+	case b.MarginPercent == 0:
+		return "usage"
+	}
+	return fmt.Sprintf("usage+%g%%", b.MarginPercent)
+}
+
+/*
+Apply returns workloads sized by b. Under a usage basis, a workload's CPU requirement becomes
+its UsageCPU plus the margin rounded up to whole vCPUs, and its memory requirement its
+UsageMemory plus the margin; a resource without recorded usage (0) keeps its request. Usage
+above the request is kept too, since rightsizing such a workload means raising its request.
+Under the request basis workloads itself is returned.
+*/
+func (b PackingBasis) Apply(workloads WorkloadSet) WorkloadSet {
+	if !b.Usage {
+		return workloads
+	}
+	scale := 1 + b.MarginPercent/100
+	sized := make(WorkloadSet, len(workloads))
+	for i, w := range workloads {
+		if w.UsageCPU > 0 {
+			w.CPURequirements = int(math.Ceil(w.UsageCPU*scale - 1e-9)) // float noise must not add a vCPU
+		}
+		if w.UsageMemory > 0 {
+			w.MemoryRequirements = w.UsageMemory * scale
+		}
+		sized[i] = w
+	}
+	return sized
+}
+
+// BasisCost is the outcome of packing a workload set under one PackingBasis.
+type BasisCost struct {
+	Basis     string
+	VMsUsed   int
+	TotalCost float64
+	Unpacked  int
+	// SavingsPercent is how much cheaper than the requests basis the packing is; negative
+	// when it is more expensive.
+	SavingsPercent float64
+}
+
+/*
+CompareBases packs workloads with cfg under BasisRequests and each of bases, and returns the
+cost of each, the requests first. cfg.Basis is ignored.
+*/
+func CompareBases(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config, bases ...PackingBasis) []BasisCost {
+	var costs []BasisCost
+	for _, b := range append([]PackingBasis{BasisRequests}, bases...) {
+		sized := SplitOversized(b.Apply(workloads), cfg.SplitMaxCPU, cfg.SplitMaxMemoryGiB)
+		result := BinPackWorkloadsWithConfig(sized, candidates, cfg)
+		c := BasisCost{Basis: b.String(), VMsUsed: len(result.VMs), TotalCost: TotalCost(result.VMs), Unpacked: len(result.Unpacked)}
+		if len(costs) > 0 && costs[0].TotalCost > 0 {
+			c.SavingsPercent = 100 * (costs[0].TotalCost - c.TotalCost) / costs[0].TotalCost
+		}
+		costs = append(costs, c)
+	}
+	return costs
+}
+
+// comparedBases returns the bases simulate compares with the requests under b: usage alone,
+// and usage with the margin when there is one.
+func (b PackingBasis) comparedBases() []PackingBasis {
+	if !b.Usage {
+		return nil
+	}
+	if b.MarginPercent == 0 {
+		return []PackingBasis{BasisUsage}
+	}
+	return []PackingBasis{BasisUsage, b}
+}
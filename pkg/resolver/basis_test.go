@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+)
+
+// overprovisioned returns workloads requesting 4 vCPUs and 16 GiB each but using a fifth of it.
+func overprovisioned(n int) WorkloadSet {
+	workloads := make(WorkloadSet, n)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{Name: fmt.Sprintf("w%d", i), CPURequirements: 4, MemoryRequirements: 16, UsageCPU: 0.8, UsageMemory: 3.2}
+	}
+	return workloads
+}
+
+func TestPackingBasisApply(t *testing.T) {
+	workloads := WorkloadSet{
+		{Name: "used", CPURequirements: 4, MemoryRequirements: 16, UsageCPU: 1.5, UsageMemory: 5},
+		{Name: "unknown", CPURequirements: 2, MemoryRequirements: 8},
+		{Name: "exact", CPURequirements: 4, MemoryRequirements: 8, UsageCPU: 2.5, UsageMemory: 2},
+	}
+	usage := BasisUsagePlusMargin(20).Apply(workloads)
+	// 1.5*1.2 = 1.8 rounds up to 2 vCPUs, 2.5*1.2 = 3 stays 3 despite float noise
+	if w := usage[0]; w.CPURequirements != 2 || w.MemoryRequirements != 6 {
+		t.Errorf("expected 2 vCPUs and 6 GiB for usage plus 20%%, got %+v", w)
+	}
+	if w := usage[1]; w.CPURequirements != 2 || w.MemoryRequirements != 8 {
+		t.Errorf("expected a workload without usage to keep its requests, got %+v", w)
+	}
+	if w := usage[2]; w.CPURequirements != 3 {
+		t.Errorf("expected exactly 3 vCPUs, got %+v", w)
+	}
+	if workloads[0].CPURequirements != 4 {
+		t.Error("expected Apply to leave its input unchanged")
+	}
+	if got := BasisRequests.Apply(workloads); got[0].CPURequirements != 4 {
+		t.Errorf("expected the requests basis to keep requests, got %+v", got[0])
+	}
+}
+
+func TestCompareBases_UsageCheaper(t *testing.T) {
+	skus := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192},
+		{Name: "Standard_D16s_v5", Family: "DSv5", VCpus: 16, MemoryGiB: 64, PricePerHour: 0.768},
+	}
+	costs := CompareBases(overprovisioned(10), skus, Config{}, BasisUsage, BasisUsagePlusMargin(50))
+	if len(costs) != 3 || costs[0].Basis != "requests" || costs[1].Basis != "usage" || costs[2].Basis != "usage+50%" {
+		t.Fatalf("expected requests, usage and usage+50%% costs, got %+v", costs)
+	}
+	requests, usage, margin := costs[0], costs[1], costs[2]
+	if !(usage.TotalCost < requests.TotalCost && usage.SavingsPercent > 50) {
+		t.Errorf("expected packing by usage a fifth of the requests to save over half the cost, got %+v", costs)
+	}
+	// The margin rounds 0.8 vCPUs up to 2 instead of 1
+	if !(margin.TotalCost > usage.TotalCost && margin.TotalCost < requests.TotalCost) {
+		t.Errorf("expected the margin to cost between usage and requests, got %+v", costs)
+	}
+	for _, c := range costs {
+		if c.Unpacked != 0 {
+			t.Errorf("expected every workload packed, got %+v", c)
+		}
+	}
+}
+
+func TestSimulate_BasisComparison(t *testing.T) {
+	skus := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	result, _, err := simulate(overprovisioned(4), skus, Config{Basis: BasisUsage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.VMsUsed != 1 || len(result.BasisComparison) != 2 || result.BasisComparison[0].VMsUsed != 4 {
+		t.Errorf("expected 4 workloads on one VM by usage, compared with 4 VMs by requests, got %d VMs and %+v", result.VMsUsed, result.BasisComparison)
+	}
+	if result, _, _ := simulate(overprovisioned(4), skus, Config{}); result.VMsUsed != 4 || result.BasisComparison != nil {
+		t.Errorf("expected the default to pack by requests without a comparison, got %d VMs and %+v", result.VMsUsed, result.BasisComparison)
+	}
+}
+
+func TestParsePackingBasis(t *testing.T) {
+	for _, tc := range []struct {
+		basis  string
+		margin float64
+		want   PackingBasis
+	}{
+		{"", 0, BasisRequests},
+		{"requests", 0, BasisRequests},
+		{"usage", 0, BasisUsage},
+		{"usage", 15, BasisUsagePlusMargin(15)},
+	} {
+		if got, err := ParsePackingBasis(tc.basis, tc.margin); err != nil || got != tc.want {
+			t.Errorf("ParsePackingBasis(%q, %v) = %+v, %v; expected %+v", tc.basis, tc.margin, got, err, tc.want)
+		}
+	}
+	for _, tc := range []struct {
+		basis  string
+		margin float64
+	}{{"limits", 0}, {"requests", 10}, {"usage", -5}} {
+		if _, err := ParsePackingBasis(tc.basis, tc.margin); err == nil {
+			t.Errorf("expected an error for basis %q with margin %v", tc.basis, tc.margin)
+		}
+	}
+}
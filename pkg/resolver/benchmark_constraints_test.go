@@ -0,0 +1,152 @@
+package resolver
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+/*
+Constraint benchmarks: a standing performance baseline for the packing paths beyond plain
+selection, each run against 10, 100 and 1000 synthetic SKUs so that scaling with the candidate
+count shows. Run them with
+
+    go test -run '^$' -bench 'Constraints' -benchmem ./pkg/resolver
+
+and compare runs with benchstat, e.g. before and after a change:
+
+    go test -run '^$' -bench 'Constraints' -benchmem -count 10 ./pkg/resolver > old.txt
+    benchstat old.txt new.txt
+
+ns/op is the time to pack the whole workload set (or, for Select, to rank one workload), so it
+is comparable only within a sub-benchmark; B/op and allocs/op show allocation regressions. The
+inputs are seeded and do not change between runs.
+*/
+
+// benchSKUCounts are the candidate counts every constraint benchmark is run against.
+var benchSKUCounts = []int{10, 100, 1000}
+
+// benchSKUs returns n synthetic SKUs cycling through families, with zones, network and disk limits.
+func benchSKUs(n int, families []string) []AzureInstanceSpec {
+	r := rand.New(rand.NewSource(int64(n)))
+	zones := [][]string{{"1", "2", "3"}, {"1", "2"}, {"2", "3"}, {"1"}}
+	skus := make([]AzureInstanceSpec, n)
+	for i := range skus {
+		vcpus := 2 << r.Intn(6) // 2 to 64
+		skus[i] = AzureInstanceSpec{
+			Name:                 fmt.Sprintf("Standard_B%d_%d", vcpus, i),
+			Family:               families[i%len(families)],
+			VCpus:                vcpus,
+			MemoryGiB:            float64(vcpus * (2 << r.Intn(3))),
+			PricePerHour:         float64(vcpus)*0.04 + r.Float64()*0.05,
+			AvailabilityZones:    zones[i%len(zones)],
+			NetworkBandwidthMbps: float64(vcpus * 500),
+			UncachedDiskIOPS:     float64(vcpus * 1600),
+			DiskMBps:             float64(vcpus * 24),
+			MaxPods:              110,
+		}
+	}
+	return skus
+}
+
+// benchWorkloads returns 1000 seeded workloads; pinned of every 10 require a zone.
+func benchWorkloads(pinned int, vector bool) WorkloadSet {
+	r := rand.New(rand.NewSource(7))
+	workloads := make(WorkloadSet, 1000)
+	for i := range workloads {
+		w := WorkloadProfile{CPURequirements: r.Intn(8) + 1, MemoryRequirements: float64(r.Intn(32) + 1)}
+		if i%10 < pinned {
+			w.Zone = fmt.Sprint(i%3 + 1)
+		}
+		if vector {
+			w.NetworkRequirementsMbps = float64(r.Intn(4000))
+			w.IOPSRequirements = float64(r.Intn(12000))
+			w.ThroughputMBpsRequirements = float64(r.Intn(200))
+		}
+		workloads[i] = w
+	}
+	return workloads
+}
+
+// benchQuota loads the az vm list-usage fixture and returns it with its families, sorted.
+func benchQuota(b *testing.B) (QuotaMap, []string) {
+	quota, err := LoadQuota("testdata/quota/az_vm_list_usage.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var families []string
+	for f := range quota {
+		if f != SpotQuotaKey {
+			families = append(families, f)
+		}
+	}
+	sort.Strings(families)
+	return quota, append(families, "Fsv2") // and one family without quota
+}
+
+// BenchmarkConstraintsQuota packs under the quota of an az vm list-usage file.
+func BenchmarkConstraintsQuota(b *testing.B) {
+	quota, families := benchQuota(b)
+	workloads := benchWorkloads(0, false)
+	for _, n := range benchSKUCounts {
+		skus := benchSKUs(n, families)
+		b.Run(fmt.Sprintf("SKUs=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota)
+			}
+		})
+	}
+}
+
+// BenchmarkConstraintsVector packs workloads that also request network bandwidth, disk IOPS and
+// throughput, so every dimension of a VM's capacity constrains the packing.
+func BenchmarkConstraintsVector(b *testing.B) {
+	workloads := benchWorkloads(0, true)
+	for _, n := range benchSKUCounts {
+		skus := benchSKUs(n, []string{"Dsv5", "Esv5", "Fsv2"})
+		b.Run(fmt.Sprintf("SKUs=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				BinPackWorkloadsWithConfig(workloads, skus, Config{})
+			}
+		})
+	}
+}
+
+// BenchmarkConstraintsZones packs a mix where 3 in 10 workloads are pinned to a zone and
+// candidates are offered in different zone subsets.
+func BenchmarkConstraintsZones(b *testing.B) {
+	workloads := benchWorkloads(3, false)
+	for _, n := range benchSKUCounts {
+		skus := benchSKUs(n, []string{"Dsv5", "Esv5", "Fsv2"})
+		b.Run(fmt.Sprintf("SKUs=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				BinPackWorkloadsWithConfig(workloads, skus, Config{MinZones: 1})
+			}
+		})
+	}
+}
+
+// BenchmarkConstraintsService measures the SelectorService behind the REST API: Pack packs a
+// whole batch of workloads, Select ranks the candidates of one.
+func BenchmarkConstraintsService(b *testing.B) {
+	workloads := benchWorkloads(3, false)
+	for _, n := range benchSKUCounts {
+		svc := NewSelectorService(benchSKUs(n, []string{"Dsv5", "Esv5", "Fsv2"}), Config{})
+		b.Run(fmt.Sprintf("Pack/SKUs=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				svc.Pack(workloads)
+			}
+		})
+		b.Run(fmt.Sprintf("Select/SKUs=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				svc.Select(workloads[i%len(workloads)], 5)
+			}
+		})
+	}
+}
@@ -1,11 +1,10 @@
 package resolver
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
-	"fmt"
 )
 
 /*
@@ -19,26 +18,14 @@ Running `go test` on a single file (e.g., ..._test.go) will not include
 other non-test files in the package. Always run tests at the package level.
 */
 
-// WorkloadJSON is the struct for loading workloads_preprocessed.json
-type WorkloadJSON struct {
-	Name              string             `json:"name"`
-	CPURequest        int                `json:"cpu_request"`
-	MemoryRequestGiB  float64            `json:"memory_request_gib"`
-	CPUUsage          float64            `json:"cpu_usage"`
-	MemUsage          float64            `json:"mem_usage"`
-	StartTime         string             `json:"start_time"`
-	EndTime           string             `json:"end_time"`
-	Labels            map[string]string  `json:"labels"`
-	Annotations       map[string]string  `json:"annotations"`
-}
-
 /*
-Helper to load workloads_preprocessed.json and convert to []WorkloadProfile.
-If limit > 0, returns at most limit workloads. If limit == 0, returns all.
+Helper to load workloads_preprocessed.json (see LoadPreprocessedWorkloads), looking in the
+parent and testdata directories too. If limit > 0, returns at most limit workloads. If
+limit == 0, returns all.
 */
 func loadWorkloadsFromJSONWithLimit(path string, limit int) ([]WorkloadProfile, error) {
 	// Try the provided path first
-	f, err := os.Open(path)
+	_, err := os.Stat(path)
 	if err != nil {
 		// If not found, try looking in parent directory and in testdata/
 		altPaths := []string{
@@ -47,8 +34,8 @@ func loadWorkloadsFromJSONWithLimit(path string, limit int) ([]WorkloadProfile,
 			filepath.Join("..", "testdata", path),
 		}
 		for _, alt := range altPaths {
-			f, err = os.Open(alt)
-			if err == nil {
+			if _, err = os.Stat(alt); err == nil {
+				path = alt
 				break
 			}
 		}
@@ -56,28 +43,19 @@ func loadWorkloadsFromJSONWithLimit(path string, limit int) ([]WorkloadProfile,
 			return nil, err
 		}
 	}
-	defer f.Close()
-	var raw []WorkloadJSON
-	if err := json.NewDecoder(f).Decode(&raw); err != nil {
+	workloads, err := LoadPreprocessedWorkloads(path)
+	if err != nil {
 		return nil, err
 	}
-	var out []WorkloadProfile
-	count := 0
-	for _, w := range raw {
-		if limit > 0 && count >= limit {
-			break
+	if limit > 0 && len(workloads) > limit {
+		workloads = workloads[:limit]
+	}
+	for i, w := range workloads {
+		workloads[i].Capabilities = map[string]string{
+			"workload_type": w.Labels["workload_type"],
 		}
-		out = append(out, WorkloadProfile{
-			CPURequirements:    w.CPURequest,
-			MemoryRequirements: w.MemoryRequestGiB,
-			// Optionally, you could use CPUUsage/MemUsage for more advanced benchmarking
-			Capabilities: map[string]string{
-				"workload_type": w.Labels["workload_type"],
-			},
-		})
-		count++
 	}
-	return out, nil
+	return workloads, nil
 }
 
 // Backward-compatible: original function loads all workloads
@@ -137,6 +115,11 @@ func BinPackWorkloadsFFD(workloads WorkloadSet, candidates []AzureInstanceSpec,
 	return BinPackWorkloads(workloads, candidates, strategy)
 }
 
+// Online first-fit in arrival order, without sorting
+func BinPackWorkloadsOnlineAlgo(workloads WorkloadSet, candidates []AzureInstanceSpec, strategy SelectionStrategy) PackingResult {
+	return BinPackWorkloadsOnline(workloads, candidates, Config{Strategy: strategy})
+}
+
 // Naive one-workload-per-VM (worst case, for comparison)
 func BinPackWorkloadsNaiveAlgo(workloads WorkloadSet, candidates []AzureInstanceSpec, strategy SelectionStrategy) PackingResult {
 	var result PackingResult
@@ -173,6 +156,7 @@ func BenchmarkBinPacking_RealTrace(b *testing.B) {
 		fn   BinPackingAlgorithm
 	}{
 		{"FirstFitDecreasing", BinPackWorkloadsFFD},
+		{"OnlineFirstFit", BinPackWorkloadsOnlineAlgo},
 		{"NaiveOnePerVM", BinPackWorkloadsNaiveAlgo},
 	}
 
@@ -195,6 +179,9 @@ func TestPackingEfficiencyAndCostReport_RealTrace(t *testing.T) {
 		}
 	}
 	workloads, err := loadWorkloadsFromJSONWithLimit("workloads_preprocessed.json", limit)
+	if os.IsNotExist(err) {
+		t.Skipf("Skipping: workloads_preprocessed.json not found (generate it with scripts/preprocess_azure_traces.py)")
+	}
 	if err != nil {
 		t.Fatalf("failed to load workloads: %v", err)
 	}
@@ -249,3 +236,25 @@ func TestPackingEfficiencyAndCostReport_RealTrace(t *testing.T) {
 	}
 	t.Logf("Test completed successfully, packed %d VMs", len(result.VMs))
 }
+
+// BenchmarkBinPackScoreCache_RealTrace compares packing the real trace with and without the score cache.
+func BenchmarkBinPackScoreCache_RealTrace(b *testing.B) {
+	workloads, err := loadWorkloadsFromJSONWithLimit("workloads_preprocessed.json", 5000)
+	if err != nil {
+		b.Skipf("real trace not available: %v", err)
+	}
+	candidates := dummyInstanceTypes()
+	for _, bc := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"Uncached", Config{DisableScoreCache: true}},
+		{"Cached", Config{}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				BinPackWorkloadsWithConfig(workloads, candidates, bc.cfg)
+			}
+		})
+	}
+}
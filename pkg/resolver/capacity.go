@@ -0,0 +1,85 @@
+package resolver
+
+import "math"
+
+/*
+capacityEpsilon is the relative tolerance of every capacity comparison. Requests and
+capacities are decimal fractions, e.g. 0.1 GiB, that floats only approximate, so the sum of
+workloads exactly filling a VM can exceed its capacity by rounding error: 16 x 0.1 GiB adds up
+to 1.6000000000000003. Amounts within capacityEpsilon of each other count as equal, far below
+any meaningful request (1 MiB is 1e-3 GiB).
+*/
+const capacityEpsilon = 1e-9
+
+// fitsWithin reports whether request fits in available, tolerating rounding error (see
+// capacityEpsilon). All fit checks of the selection filters and packers go through it.
+func fitsWithin(request, available float64) bool {
+	return request <= available+capacityEpsilon*max(1, math.Abs(available))
+}
+
+// capacity is the remaining capacity of a VM in every dimension the packers track.
+// A workload fits only if it fits in all of them, so no dimension is ever oversubscribed.
+// Disk IOPS and throughput are only tracked for SKUs that declare them, and the workload
+// count only under Config.MaxWorkloadsPerVM, and the limits of the workloads only under a
+// capped Config.Overcommit (see Config.vmCapacity). Headroom buffers do not count as workloads.
+type capacity struct {
+	cpu            float64
+	memoryGiB      float64
+	bandwidthMbps  float64
+	diskIOPS       float64
+	diskMBps       float64
+	workloads      float64
+	limitCPU       float64
+	limitMemoryGiB float64
+}
+
+// capacityOf returns the capacity of vm available to workloads, i.e. without the margin.
+func capacityOf(vm AzureInstanceSpec, margin FitMargin) capacity {
+	cpu, mem := margin.usable(vm)
+	return capacity{
+		cpu:            cpu,
+		memoryGiB:      mem,
+		bandwidthMbps:  ExpectedBandwidthMbps(vm),
+		diskIOPS:       knownOrUnlimited(vm.UncachedDiskIOPS),
+		diskMBps:       knownOrUnlimited(vm.DiskMBps),
+		workloads:      math.Inf(1),
+		limitCPU:       math.Inf(1),
+		limitMemoryGiB: math.Inf(1),
+	}
+}
+
+// knownOrUnlimited returns v, or +Inf for an unknown (zero) capacity.
+func knownOrUnlimited(v float64) float64 {
+	if v <= 0 {
+		return math.Inf(1)
+	}
+	return v
+}
+
+// fits reports whether w fits in the remaining capacity.
+func (c capacity) fits(w WorkloadProfile) bool {
+	limitCPU, limitMem := limitsOf(w)
+	return fitsWithin(float64(w.CPURequirements), c.cpu) &&
+		fitsWithin(w.MemoryRequirements, c.memoryGiB) &&
+		fitsWithin(w.NetworkRequirementsMbps, c.bandwidthMbps) &&
+		fitsWithin(w.IOPSRequirements, c.diskIOPS) &&
+		fitsWithin(w.ThroughputMBpsRequirements, c.diskMBps) &&
+		fitsWithin(limitCPU, c.limitCPU) &&
+		fitsWithin(limitMem, c.limitMemoryGiB) &&
+		(w.Headroom || c.workloads >= 1)
+}
+
+// take subtracts w from the remaining capacity.
+func (c *capacity) take(w WorkloadProfile) {
+	c.cpu -= float64(w.CPURequirements)
+	c.memoryGiB -= w.MemoryRequirements
+	c.bandwidthMbps -= w.NetworkRequirementsMbps
+	c.diskIOPS -= w.IOPSRequirements
+	c.diskMBps -= w.ThroughputMBpsRequirements
+	limitCPU, limitMem := limitsOf(w)
+	c.limitCPU -= limitCPU
+	c.limitMemoryGiB -= limitMem
+	if !w.Headroom {
+		c.workloads--
+	}
+}
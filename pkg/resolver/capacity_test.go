@@ -0,0 +1,62 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCapacity_FloatNoise packs workloads that exactly fill VMs with amounts floats cannot
+// represent exactly: 16 x 0.1 GiB sums to 1.6000000000000003, more than 1.6.
+func TestCapacity_FloatNoise(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_Tiny", VCpus: 16, MemoryGiB: 1.6, PricePerHour: 0.1}}
+	var workloads WorkloadSet
+	for i := range 16 {
+		workloads = append(workloads, WorkloadProfile{Name: fmt.Sprintf("w%d", i), CPURequirements: 1, MemoryRequirements: 0.1})
+	}
+	for _, name := range PackingAlgorithms() {
+		pack, _ := PackingAlgorithm(name)
+		if got := pack(workloads, candidates, Config{}); len(got.VMs) != 1 || len(got.Unpacked) != 0 {
+			t.Errorf("%s: expected the workloads to fill one VM exactly, got %d VMs and %d unpacked", name, len(got.VMs), len(got.Unpacked))
+		}
+	}
+	if got := SimulateArrivals(workloads, candidates, Config{}).Packing; len(got.VMs) != 1 {
+		t.Errorf("arrivals: expected one VM, got %d", len(got.VMs))
+	}
+
+	// Three 0.1 GiB VMs exactly reach a 0.3 GiB limit.
+	small := []AzureInstanceSpec{{Name: "Standard_Tiny", VCpus: 1, MemoryGiB: 0.1, PricePerHour: 0.01}}
+	cfg := Config{Limits: Limits{MemoryGiB: 0.3}}
+	if got := BinPackWorkloadsWithConfig(workloads[:3], small, cfg); len(got.VMs) != 3 || len(got.Unpacked) != 0 {
+		t.Errorf("expected 3 VMs within the memory limit, got %d VMs and %d unpacked", len(got.VMs), len(got.Unpacked))
+	}
+
+	// Three workloads of 0.1 GiB and 0.1 Mbps fill a 0.3 GiB, 0.3 Mbps SKU.
+	narrow := []AzureInstanceSpec{{Name: "Standard_Narrow", VCpus: 3, MemoryGiB: 0.3, NetworkBandwidthMbps: 0.3, PricePerHour: 0.01}}
+	var net WorkloadSet
+	for range 3 {
+		net = append(net, WorkloadProfile{CPURequirements: 1, MemoryRequirements: 0.1, NetworkRequirementsMbps: 0.1})
+	}
+	if got := BinPackWorkloadsWithConfig(net, narrow, Config{}); len(got.VMs) != 1 || len(got.Unpacked) != 0 {
+		t.Errorf("expected one VM with memory and bandwidth filled exactly, got %d VMs and %d unpacked", len(got.VMs), len(got.Unpacked))
+	}
+	if v := invariantViolation(workloads, BinPackWorkloadsWithConfig(workloads, candidates, Config{}), WorkloadProfile.ID); v != "" {
+		t.Errorf("expected an exactly filled VM to pass the invariants, got %s", v)
+	}
+}
+
+func TestFitsWithin(t *testing.T) {
+	for _, tc := range []struct {
+		request, available float64
+		want               bool
+	}{
+		{1.6000000000000003, 1.6, true},
+		{1.6001, 1.6, false},
+		{4096.000000000001, 4096, true},
+		{0, 0, true},
+		{1e-6, 0, false},
+	} {
+		if got := fitsWithin(tc.request, tc.available); got != tc.want {
+			t.Errorf("fitsWithin(%v, %v) = %v, expected %v", tc.request, tc.available, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package resolver
+
+import "sort"
+
+// StrategyAuto selects a strategy per workload with ClassifyWorkload instead of one for all.
+const StrategyAuto SelectionStrategy = "auto"
+
+// Defaults of StrategyThresholds, in line with Azure's families: compute optimized F-series
+// have 2 GiB per vCPU, general purpose D-series 4 and memory optimized E-series 8.
+const (
+	DefaultMemoryHeavyGiBPerCPU = 6
+	DefaultCPUHeavyGiBPerCPU    = 3
+	DefaultIOHeavyGiB           = 100
+	DefaultIOHeavyIOPS          = 5000
+)
+
+// StrategyThresholds configures ClassifyWorkload. Zero values use the defaults above.
+type StrategyThresholds struct {
+	// MemoryHeavyGiBPerCPU classifies workloads requesting more memory per vCPU as memory intensive.
+	MemoryHeavyGiBPerCPU float64
+	// CPUHeavyGiBPerCPU classifies workloads requesting less memory per vCPU as CPU intensive.
+	CPUHeavyGiBPerCPU float64
+	// IOHeavyGiB and IOHeavyIOPS classify workloads requesting at least this much IO or disk
+	// IOPS as IO intensive, regardless of their memory to CPU ratio.
+	IOHeavyGiB  float64
+	IOHeavyIOPS float64
+}
+
+func (t StrategyThresholds) withDefaults() StrategyThresholds {
+	if t.MemoryHeavyGiBPerCPU <= 0 {
+		t.MemoryHeavyGiBPerCPU = DefaultMemoryHeavyGiBPerCPU
+	}
+	if t.CPUHeavyGiBPerCPU <= 0 {
+		t.CPUHeavyGiBPerCPU = DefaultCPUHeavyGiBPerCPU
+	}
+	if t.IOHeavyGiB <= 0 {
+		t.IOHeavyGiB = DefaultIOHeavyGiB
+	}
+	if t.IOHeavyIOPS <= 0 {
+		t.IOHeavyIOPS = DefaultIOHeavyIOPS
+	}
+	return t
+}
+
+// ClassifyWorkload returns the strategy suited to w under the default thresholds.
+func ClassifyWorkload(w WorkloadProfile) SelectionStrategy {
+	return ClassifyWorkloadWithThresholds(w, StrategyThresholds{})
+}
+
+/*
+ClassifyWorkloadWithThresholds returns the strategy suited to w: IO intensive for large IO or
+IOPS requests, otherwise memory intensive when it requests more than MemoryHeavyGiBPerCPU GiB
+per vCPU, CPU intensive when it requests less than CPUHeavyGiBPerCPU, and general purpose in
+between or on either boundary. A workload requesting memory but no CPU is memory intensive;
+one requesting neither is general purpose.
+*/
+func ClassifyWorkloadWithThresholds(w WorkloadProfile, t StrategyThresholds) SelectionStrategy {
+	t = t.withDefaults()
+	if w.IORequirements >= t.IOHeavyGiB || w.IOPSRequirements >= t.IOHeavyIOPS {
+		return StrategyIOIntensive
+	}
+	if w.CPURequirements <= 0 {
+		if w.MemoryRequirements > 0 {
+			return StrategyMemoryIntensive
+		}
+		return StrategyGeneralPurpose
+	}
+	ratio := w.MemoryRequirements / float64(w.CPURequirements)
+	switch {
+	case ratio > t.MemoryHeavyGiBPerCPU:
+		return StrategyMemoryIntensive
+	case ratio < t.CPUHeavyGiBPerCPU:
+		return StrategyCPUIntensive
+	default:
+		return StrategyGeneralPurpose
+	}
+}
+
+// StrategyCount is how many workloads of a packing one strategy was used for.
+type StrategyCount struct {
+	Strategy  SelectionStrategy
+	Workloads int
+}
+
+// strategyMix counts the real workloads of result per strategy ClassifyWorkloadWithThresholds
+// assigns them, most common first.
+func strategyMix(result PackingResult, t StrategyThresholds) []StrategyCount {
+	counts := make(map[SelectionStrategy]int)
+	count := func(w WorkloadProfile) {
+		if !w.Headroom {
+			counts[ClassifyWorkloadWithThresholds(w, t)]++
+		}
+	}
+	for _, vm := range result.VMs {
+		for _, w := range vm.Workloads {
+			count(w)
+		}
+	}
+	for _, u := range result.Unpacked {
+		count(u.Workload)
+	}
+	mix := make([]StrategyCount, 0, len(counts))
+	for s, n := range counts {
+		mix = append(mix, StrategyCount{Strategy: s, Workloads: n})
+	}
+	sort.Slice(mix, func(i, j int) bool {
+		if mix[i].Workloads != mix[j].Workloads {
+			return mix[i].Workloads > mix[j].Workloads
+		}
+		return mix[i].Strategy < mix[j].Strategy
+	})
+	return mix
+}
@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyWorkload(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		w    WorkloadProfile
+		want SelectionStrategy
+	}{
+		{"memory heavy", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 12.5}, StrategyMemoryIntensive},
+		{"memory boundary", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 12}, StrategyGeneralPurpose},
+		{"balanced", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 8}, StrategyGeneralPurpose},
+		{"cpu boundary", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 6}, StrategyGeneralPurpose},
+		{"cpu heavy", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 5.5}, StrategyCPUIntensive},
+		{"io boundary", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 32, IORequirements: 100}, StrategyIOIntensive},
+		{"below io", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 32, IORequirements: 99}, StrategyMemoryIntensive},
+		{"iops", WorkloadProfile{CPURequirements: 2, MemoryRequirements: 2, IOPSRequirements: 5000}, StrategyIOIntensive},
+		{"memory only", WorkloadProfile{MemoryRequirements: 1}, StrategyMemoryIntensive},
+		{"empty", WorkloadProfile{}, StrategyGeneralPurpose},
+	} {
+		if got := ClassifyWorkload(tc.w); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+
+	custom := StrategyThresholds{MemoryHeavyGiBPerCPU: 4, CPUHeavyGiBPerCPU: 1}
+	if got := ClassifyWorkloadWithThresholds(WorkloadProfile{CPURequirements: 2, MemoryRequirements: 10}, custom); got != StrategyMemoryIntensive {
+		t.Errorf("expected 5 GiB/vCPU to be memory intensive above a threshold of 4, got %s", got)
+	}
+	if got := ClassifyWorkloadWithThresholds(WorkloadProfile{CPURequirements: 2, MemoryRequirements: 3}, custom); got != StrategyGeneralPurpose {
+		t.Errorf("expected 1.5 GiB/vCPU to be general purpose above a threshold of 1, got %s", got)
+	}
+}
+
+func TestStrategyAuto(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_F8s_v2", VCpus: 8, MemoryGiB: 16, PricePerHour: 0.34},
+		{Name: "Standard_D8s_v5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.38},
+		{Name: "Standard_E8s_v5", VCpus: 8, MemoryGiB: 64, PricePerHour: 0.50},
+	}
+	var workloads WorkloadSet
+	for i := 0; i < 4; i++ {
+		workloads = append(workloads,
+			WorkloadProfile{CPURequirements: 4, MemoryRequirements: 4},  // cpu
+			WorkloadProfile{CPURequirements: 2, MemoryRequirements: 24}, // memory
+		)
+	}
+	skus := func(result PackingResult) map[string]int {
+		counts := make(map[string]int)
+		for _, vm := range result.VMs {
+			counts[vm.InstanceType.Name]++
+		}
+		return counts
+	}
+	// General purpose favours the cheap compute optimized SKU, too small for the memory heavy
+	// workloads; auto scores those with the memory strategy and picks a larger SKU.
+	autoResult := BinPackWorkloads(workloads, candidates, StrategyAuto)
+	general := skus(BinPackWorkloads(workloads, candidates, StrategyGeneralPurpose))
+	auto := skus(autoResult)
+	if reflect.DeepEqual(general, auto) {
+		t.Errorf("expected auto classification to change the VM mix, got %v for both", auto)
+	}
+	if len(autoResult.Unpacked) != 0 {
+		t.Errorf("expected auto to pack every workload, got %d unpacked", len(autoResult.Unpacked))
+	}
+
+	cfg := Config{Strategy: StrategyAuto}
+	sim := cfg.Summarize(BinPackWorkloadsWithConfig(workloads, candidates, cfg))
+	want := []StrategyCount{{Strategy: StrategyCPUIntensive, Workloads: 4}, {Strategy: StrategyMemoryIntensive, Workloads: 4}}
+	if !reflect.DeepEqual(sim.StrategyMix, want) {
+		t.Errorf("expected strategy mix %+v, got %+v", want, sim.StrategyMix)
+	}
+}
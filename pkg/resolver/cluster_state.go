@@ -0,0 +1,62 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClusterState is a running cluster, as kube.LoadClusterState imports it from kubectl output.
+type ClusterState struct {
+	// Nodes are the nodes of known instance types, in file order, with the pods bound to them.
+	Nodes []ClusterNode
+	// Pending lists the pods not bound to a node yet.
+	Pending WorkloadSet
+	// UnknownNodes lists the nodes whose instance type is missing or not among the SKUs, and
+	// the pods bound to nodes absent from the input, so nothing is dropped silently.
+	UnknownNodes []UnknownNode
+}
+
+// ClusterNode is a node of a ClusterState as the VM it runs on.
+type ClusterNode struct {
+	Name string
+	PackedVM
+}
+
+// UnknownNode is a node kube.LoadClusterState could not map to a SKU. InstanceType is its label
+// value, empty when it has none; Missing is set for a node that pods are bound to but that the
+// input does not list.
+type UnknownNode struct {
+	Name         string
+	InstanceType string
+	Missing      bool
+	Pods         int
+}
+
+func (n UnknownNode) String() string {
+	switch {
+	case n.Missing:
+		return fmt.Sprintf("node %s is not in the cluster state but runs %d pods", n.Name, n.Pods)
+	case n.InstanceType == "":
+		return fmt.Sprintf("node %s has no %s label (%d pods)", n.Name, LabelInstanceType, n.Pods)
+	}
+	return fmt.Sprintf("node %s has unknown instance type %s (%d pods)", n.Name, n.InstanceType, n.Pods)
+}
+
+// VMs returns the VMs of the known nodes.
+func (s ClusterState) VMs() []PackedVM {
+	vms := make([]PackedVM, len(s.Nodes))
+	for i, n := range s.Nodes {
+		vms[i] = n.PackedVM
+	}
+	return vms
+}
+
+// NodeZone returns the SKU zone of a topology.kubernetes.io/zone value: "1" for "eastus2-1",
+// and "" for AKS's "0" of nodes outside zones.
+func NodeZone(label string) string {
+	zone := label[strings.LastIndex(label, "-")+1:]
+	if zone == "0" {
+		return ""
+	}
+	return zone
+}
@@ -0,0 +1,11 @@
+package resolver
+
+import "testing"
+
+func TestNodeZone(t *testing.T) {
+	for label, want := range map[string]string{"eastus2-1": "1", "westeurope-3": "3", "0": "", "": "", "2": "2"} {
+		if got := NodeZone(label); got != want {
+			t.Errorf("NodeZone(%q) = %q, expected %q", label, got, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SKUCountDelta is how many VMs of one SKU each packing provisioned.
+type SKUCountDelta struct {
+	SKU  string
+	A, B int
+}
+
+// MovedWorkload is a workload whose assignment differs between two packings.
+type MovedWorkload struct {
+	Workload WorkloadProfile
+	Key      string // name, or shape and occurrence, the workloads were matched by
+	// FromVM and ToVM index the VMs of a and b; -1 means unpacked.
+	FromVM, ToVM   int
+	FromSKU, ToSKU string // "" when unpacked
+}
+
+// PackingComparison is the structured difference from packing a to packing b of the same workloads.
+// Deltas are b minus a.
+type PackingComparison struct {
+	CostDelta     float64 // hourly cost
+	VMCountDelta  int
+	UnpackedDelta int
+	SKUCounts     []SKUCountDelta // SKUs whose VM count differs, by name
+	AddedSKUs     []string        // SKUs used only by b
+	RemovedSKUs   []string        // SKUs used only by a
+	Moved         []MovedWorkload
+	// UtilizationA and UtilizationB are the per-resource utilization of each packing.
+	UtilizationA, UtilizationB Utilization
+	UtilizationDelta           Utilization
+	// DistributionDistance is how far the SKU selection distribution shifted, see DistributionDistance.
+	DistributionDistance float64
+}
+
+// Identical reports whether both packings provision the same VMs with the same workloads.
+func (c PackingComparison) Identical() bool {
+	return c.VMCountDelta == 0 && c.UnpackedDelta == 0 && len(c.SKUCounts) == 0 && len(c.Moved) == 0
+}
+
+/*
+ComparePackingResults compares two packings of the same workload set.
+
+Workloads are matched by WorkloadProfile.ID when set. Unnamed workloads are matched by index
+among the workloads of the same shape, in VM order and then unpacked order, so identical
+packings always compare equal. A workload has moved when it is on a VM with a different index
+or SKU, or was packed in one result and unpacked in the other.
+*/
+func ComparePackingResults(a, b PackingResult) PackingComparison {
+	c := PackingComparison{
+		CostDelta:     TotalCost(b.VMs) - TotalCost(a.VMs),
+		VMCountDelta:  len(b.VMs) - len(a.VMs),
+		UnpackedDelta: len(b.Unpacked) - len(a.Unpacked),
+		UtilizationA:  AverageUtilizationV2(a.VMs),
+		UtilizationB:  AverageUtilizationV2(b.VMs),
+	}
+	c.DistributionDistance = DistributionDistance(SelectionDistribution(a), SelectionDistribution(b))
+	c.UtilizationDelta = Utilization{
+		CPU:      c.UtilizationB.CPU - c.UtilizationA.CPU,
+		Memory:   c.UtilizationB.Memory - c.UtilizationA.Memory,
+		GPU:      c.UtilizationB.GPU - c.UtilizationA.GPU,
+		Storage:  c.UtilizationB.Storage - c.UtilizationA.Storage,
+		PodSlots: c.UtilizationB.PodSlots - c.UtilizationA.PodSlots,
+	}
+
+	countA, countB := skuCounts(a.VMs), skuCounts(b.VMs)
+	for _, sku := range unionKeys(countA, countB) {
+		na, nb := countA[sku], countB[sku]
+		if na != nb {
+			c.SKUCounts = append(c.SKUCounts, SKUCountDelta{SKU: sku, A: na, B: nb})
+		}
+		if na == 0 {
+			c.AddedSKUs = append(c.AddedSKUs, sku)
+		} else if nb == 0 {
+			c.RemovedSKUs = append(c.RemovedSKUs, sku)
+		}
+	}
+
+	placesA, placesB := workloadPlacements(a), workloadPlacements(b)
+	for _, key := range unionKeys(placesA, placesB) {
+		pa, okA := placesA[key]
+		pb, okB := placesB[key]
+		if !okA {
+			pa = placement{vm: -1, workload: pb.workload}
+		}
+		if !okB {
+			pb = placement{vm: -1, workload: pa.workload}
+		}
+		if pa.vm != pb.vm || pa.sku != pb.sku {
+			c.Moved = append(c.Moved, MovedWorkload{Workload: pa.workload, Key: key, FromVM: pa.vm, ToVM: pb.vm, FromSKU: pa.sku, ToSKU: pb.sku})
+		}
+	}
+	return c
+}
+
+// SelectionDistribution returns the fraction of result's VMs of each SKU.
+func SelectionDistribution(result PackingResult) map[string]float64 {
+	dist := make(map[string]float64)
+	for sku, n := range skuCounts(result.VMs) {
+		dist[sku] = float64(n) / float64(len(result.VMs))
+	}
+	return dist
+}
+
+/*
+DistributionDistance returns the total variation distance between two SKU distributions: half
+the sum of the absolute differences of every SKU's fraction. It is 0 for identical distributions
+and 1 for disjoint ones, and a distance of d means a fraction d of the VMs would have to change
+SKU to turn one into the other. An empty distribution is at distance 1 from any non-empty one.
+*/
+func DistributionDistance(a, b map[string]float64) float64 {
+	if (len(a) == 0) != (len(b) == 0) {
+		return 1
+	}
+	var sum float64
+	for _, sku := range unionKeys(a, b) {
+		sum += math.Abs(a[sku] - b[sku])
+	}
+	return sum / 2
+}
+
+func skuCounts(vms []PackedVM) map[string]int {
+	counts := make(map[string]int)
+	for _, vm := range vms {
+		counts[vm.InstanceType.Name]++
+	}
+	return counts
+}
+
+// placement is where one workload ended up.
+type placement struct {
+	workload WorkloadProfile
+	vm       int // -1 when unpacked
+	sku      string
+}
+
+// workloadPlacements keys every workload of result as described in ComparePackingResults.
+func workloadPlacements(result PackingResult) map[string]placement {
+	places := make(map[string]placement)
+	seen := make(map[string]int)
+	add := func(w WorkloadProfile, vm int, sku string) {
+		key := w.ID()
+		if key == "" {
+			shape := workloadKey(w)
+			key = fmt.Sprintf("%s#%d", shape, seen[shape])
+			seen[shape]++
+		}
+		places[key] = placement{workload: w, vm: vm, sku: sku}
+	}
+	for i, vm := range result.VMs {
+		for _, w := range vm.Workloads {
+			add(w, i, vm.InstanceType.Name)
+		}
+	}
+	for _, u := range result.Unpacked {
+		add(u.Workload, -1, "")
+	}
+	return places
+}
+
+// workloadKey describes an unnamed workload's shape for matching and display.
+func workloadKey(w WorkloadProfile) string {
+	key := fmt.Sprintf("%dcpu/%.4gGiB", w.CPURequirements, w.MemoryRequirements)
+	if w.GPURequirements > 0 {
+		key += fmt.Sprintf("/%dgpu", w.GPURequirements)
+	}
+	if w.Zone != "" {
+		key += "@" + w.Zone
+	}
+	if w.Headroom {
+		key += "(headroom)"
+	}
+	return key
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+PackingFromDetail rebuilds a PackingResult from the per-VM and per-workload detail of a
+SimulationResult, e.g. one read back from a JSON report. Instance types are looked up by SKU
+name in skus so utilization can be compared; SKUs missing from it keep only their name and price.
+*/
+func PackingFromDetail(result SimulationResult, skus []AzureInstanceSpec) PackingResult {
+	byName := make(map[string]AzureInstanceSpec, len(skus))
+	for _, s := range skus {
+		byName[s.Name] = s
+	}
+	var packing PackingResult
+	for _, d := range result.VMs {
+		spec, ok := byName[d.SKU]
+		if !ok {
+			spec = AzureInstanceSpec{Name: d.SKU}
+		}
+		spec.PricePerHour = d.PricePerHour
+		packing.VMs = append(packing.VMs, PackedVM{ID: d.ID, InstanceType: spec, Reserved: d.Reserved})
+	}
+	for _, d := range result.Workloads {
+		w := WorkloadProfile{
+			Name:               d.Name,
+			Namespace:          d.Namespace,
+			Parent:             d.Parent,
+			CPURequirements:    d.CPU,
+			MemoryRequirements: d.MemoryGiB,
+			GPURequirements:    d.GPU,
+			Zone:               d.Zone,
+			Headroom:           d.Headroom,
+		}
+		if d.VM < 0 || d.VM >= len(packing.VMs) {
+			packing.Unpacked = append(packing.Unpacked, UnpackedWorkload{Workload: w, Reason: d.UnpackedReason})
+			continue
+		}
+		packing.VMs[d.VM].Workloads = append(packing.VMs[d.VM].Workloads, w)
+	}
+	return packing
+}
+
+// String formats the comparison for humans, most significant changes first.
+func (c PackingComparison) String() string {
+	var b strings.Builder
+	if c.Identical() {
+		b.WriteString("packings are identical\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "cost: %+.4f/h, VMs: %+d, unpacked: %+d\n", c.CostDelta, c.VMCountDelta, c.UnpackedDelta)
+	fmt.Fprintf(&b, "utilization: CPU %+.1f%%, memory %+.1f%%\n", c.UtilizationDelta.CPU, c.UtilizationDelta.Memory)
+	fmt.Fprintf(&b, "SKU distribution distance: %.3f\n", c.DistributionDistance)
+	for _, d := range c.SKUCounts {
+		fmt.Fprintf(&b, "  %-24s %d -> %d\n", d.SKU, d.A, d.B)
+	}
+	if len(c.Moved) > 0 {
+		fmt.Fprintf(&b, "%d workloads moved:\n", len(c.Moved))
+	}
+	for _, m := range c.Moved {
+		fmt.Fprintf(&b, "  %-24s %s -> %s\n", m.Key, placementString(m.FromVM, m.FromSKU), placementString(m.ToVM, m.ToSKU))
+	}
+	return b.String()
+}
+
+func placementString(vm int, sku string) string {
+	if vm < 0 {
+		return "unpacked"
+	}
+	return fmt.Sprintf("VM %d (%s)", vm, sku)
+}
@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestComparePackingResultsIdentical(t *testing.T) {
+	workloads := WorkloadSet{{CPURequirements: 2, MemoryRequirements: 4}, {CPURequirements: 2, MemoryRequirements: 4}, {CPURequirements: 1, MemoryRequirements: 1}}
+	a := BinPackWorkloads(workloads, dummyInstanceTypes(), StrategyGeneralPurpose)
+	b := BinPackWorkloads(workloads, dummyInstanceTypes(), StrategyGeneralPurpose)
+	if c := ComparePackingResults(a, b); !c.Identical() || c.CostDelta != 0 {
+		t.Errorf("expected identical packings, got %+v", c)
+	}
+}
+
+func TestComparePackingResultsSKUsAndMoves(t *testing.T) {
+	d2 := AzureInstanceSpec{Name: "Standard_D2s_v5", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1}
+	d4 := AzureInstanceSpec{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}
+	e4 := AzureInstanceSpec{Name: "Standard_E4s_v5", VCpus: 4, MemoryGiB: 32, PricePerHour: 0.25}
+	web := WorkloadProfile{Name: "web", CPURequirements: 1, MemoryRequirements: 2}
+	api := WorkloadProfile{Name: "api", CPURequirements: 1, MemoryRequirements: 2}
+	cache := WorkloadProfile{Name: "cache", CPURequirements: 2, MemoryRequirements: 12}
+	batch := WorkloadProfile{CPURequirements: 1, MemoryRequirements: 1}
+
+	a := PackingResult{
+		VMs: []PackedVM{
+			{InstanceType: d4, Workloads: []WorkloadProfile{web, api, batch}},
+			{InstanceType: d2, Workloads: []WorkloadProfile{batch}},
+		},
+		Unpacked: []UnpackedWorkload{{Workload: cache, Reason: ReasonNoCandidates}},
+	}
+	b := PackingResult{VMs: []PackedVM{
+		{InstanceType: d4, Workloads: []WorkloadProfile{web, api, batch, batch}},
+		{InstanceType: e4, Workloads: []WorkloadProfile{cache}},
+	}}
+	c := ComparePackingResults(a, b)
+
+	if math.Abs(c.CostDelta-0.15) > 1e-9 || c.VMCountDelta != 0 || c.UnpackedDelta != -1 {
+		t.Errorf("expected +0.15/h, 0 VMs, -1 unpacked, got %+.2f, %+d, %+d", c.CostDelta, c.VMCountDelta, c.UnpackedDelta)
+	}
+	if len(c.AddedSKUs) != 1 || c.AddedSKUs[0] != "Standard_E4s_v5" || len(c.RemovedSKUs) != 1 || c.RemovedSKUs[0] != "Standard_D2s_v5" {
+		t.Errorf("expected E4s_v5 added and D2s_v5 removed, got %v and %v", c.AddedSKUs, c.RemovedSKUs)
+	}
+	if len(c.SKUCounts) != 2 || c.SKUCounts[0] != (SKUCountDelta{"Standard_D2s_v5", 1, 0}) || c.SKUCounts[1] != (SKUCountDelta{"Standard_E4s_v5", 0, 1}) {
+		t.Errorf("unexpected SKU count deltas %+v", c.SKUCounts)
+	}
+
+	moved := make(map[string]MovedWorkload)
+	for _, m := range c.Moved {
+		moved[m.Key] = m
+	}
+	if len(moved) != 2 {
+		t.Fatalf("expected the cache and the second batch workload to move, got %+v", c.Moved)
+	}
+	if m := moved["cache"]; m.FromVM != -1 || m.ToVM != 1 || m.ToSKU != "Standard_E4s_v5" {
+		t.Errorf("expected cache to move from unpacked to VM 1, got %+v", m)
+	}
+	if m := moved["1cpu/1GiB#1"]; m.FromVM != 1 || m.FromSKU != "Standard_D2s_v5" || m.ToVM != 0 {
+		t.Errorf("expected the second unnamed batch workload to move from VM 1 to VM 0, got %+v", m)
+	}
+	if _, ok := moved["web"]; ok {
+		t.Error("expected web to stay on VM 0")
+	}
+	if c.UtilizationDelta.CPU != c.UtilizationB.CPU-c.UtilizationA.CPU {
+		t.Errorf("expected the CPU utilization delta to be B - A, got %+v", c)
+	}
+	if out := c.String(); !strings.Contains(out, "cache") || !strings.Contains(out, "unpacked -> VM 1 (Standard_E4s_v5)") {
+		t.Errorf("unexpected comparison text:\n%s", out)
+	}
+}
+
+func TestPackingFromDetailRoundTrip(t *testing.T) {
+	workloads := WorkloadSet{
+		{Name: "a", CPURequirements: 2, MemoryRequirements: 4},
+		{Name: "b", CPURequirements: 1, MemoryRequirements: 2},
+		{Name: "huge", CPURequirements: 1000, MemoryRequirements: 1},
+	}
+	skus := dummyInstanceTypes()
+	packing := BinPackWorkloads(workloads, skus, StrategyGeneralPurpose)
+	rebuilt := PackingFromDetail(NewSimulationResult(packing), skus)
+	if c := ComparePackingResults(packing, rebuilt); !c.Identical() || c.UtilizationDelta != (Utilization{}) {
+		t.Errorf("expected the rebuilt packing to match, got:\n%s", c)
+	}
+}
+
+func TestDistributionDistance(t *testing.T) {
+	d2 := AzureInstanceSpec{Name: "Standard_D2s_v5"}
+	d4 := AzureInstanceSpec{Name: "Standard_D4s_v5"}
+	e4 := AzureInstanceSpec{Name: "Standard_E4s_v5"}
+	packing := func(skus ...AzureInstanceSpec) PackingResult {
+		var r PackingResult
+		for _, sku := range skus {
+			r.VMs = append(r.VMs, PackedVM{InstanceType: sku})
+		}
+		return r
+	}
+	a := SelectionDistribution(packing(d2, d2, d4, d4))
+	if a["Standard_D2s_v5"] != 0.5 || a["Standard_D4s_v5"] != 0.5 {
+		t.Fatalf("expected an even distribution, got %v", a)
+	}
+	cases := []struct {
+		name string
+		b    map[string]float64
+		want float64
+	}{
+		{"identical", SelectionDistribution(packing(d4, d2)), 0},
+		{"disjoint", SelectionDistribution(packing(e4, e4)), 1},
+		{"partial", SelectionDistribution(packing(d2, d2, d4, e4)), 0.25},
+		{"empty", SelectionDistribution(PackingResult{}), 1},
+	}
+	for _, c := range cases {
+		if got := DistributionDistance(a, c.b); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s: expected distance %v, got %v", c.name, c.want, got)
+		}
+	}
+	if got := DistributionDistance(nil, nil); got != 0 {
+		t.Errorf("expected two empty distributions to be identical, got %v", got)
+	}
+	if c := ComparePackingResults(packing(d2, d4), packing(d4, e4)); c.DistributionDistance != 0.5 {
+		t.Errorf("expected comparison distance 0.5, got %v", c.DistributionDistance)
+	}
+}
@@ -0,0 +1,109 @@
+package resolver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// decompressor reads one compressed format, recognized by the magic bytes it starts with.
+type decompressor struct {
+	name      string
+	magic     []byte
+	newReader func(io.Reader) (io.ReadCloser, error)
+}
+
+// zstdMagic starts every zstd frame. decompress recognizes it even without a zstd decompressor
+// registered, to say what is missing.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = []decompressor{
+		{"gzip", []byte{0x1f, 0x8b}, func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }},
+	}
+)
+
+/*
+RegisterDecompressor makes the loaders read input starting with magic through newReader, e.g.
+for zstd, which the resolver does not depend on: importing the
+github.com/Azure/karpenter-provider-azure/pkg/resolver/zstd package registers it. gzip is
+always supported. It is meant to be called from init functions.
+*/
+func RegisterDecompressor(name string, magic []byte, newReader func(io.Reader) (io.ReadCloser, error)) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	decompressors = append(decompressors, decompressor{name, bytes.Clone(magic), newReader})
+}
+
+/*
+decompress returns r decompressed by the registered format its first bytes identify, or r as
+is for anything else. Inputs are sniffed rather than trusted by their extension, so a gzipped
+Azure trace, a .gz that a proxy delivered uncompressed and a .zst all read the same.
+*/
+func decompress(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	decompressorsMu.RLock()
+	formats := decompressors
+	decompressorsMu.RUnlock()
+	for _, d := range formats {
+		if magic, _ := br.Peek(len(d.magic)); bytes.Equal(magic, d.magic) {
+			return d.newReader(br)
+		}
+	}
+	if magic, _ := br.Peek(len(zstdMagic)); bytes.Equal(magic, zstdMagic) {
+		return nil, fmt.Errorf("zstd input needs the zstd decompressor, see RegisterDecompressor")
+	}
+	return io.NopCloser(br), nil
+}
+
+// openInput opens the input file at path, decompressed when it is compressed, whatever its name.
+func openInput(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("decompress %s: %w", path, err)
+	}
+	return decompressedFile{ReadCloser: r, f: f}, nil
+}
+
+// decompressedFile is a decompressing reader that closes its file with it.
+type decompressedFile struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (d decompressedFile) Close() error {
+	d.ReadCloser.Close()
+	return d.f.Close()
+}
+
+// readInput reads the whole input file at path, decompressed like openInput.
+func readInput(path string) ([]byte, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// trimCompressionExt strips a .gz or .zst extension from path, leaving the extension of the
+// format inside, e.g. ".jsonl" of "workloads.jsonl.gz".
+func trimCompressionExt(path string) string {
+	for _, ext := range []string{".gz", ".zst"} {
+		if strings.HasSuffix(path, ext) {
+			return strings.TrimSuffix(path, ext)
+		}
+	}
+	return path
+}
@@ -0,0 +1,81 @@
+package resolver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadWorkloadsFromTrace_Gzip(t *testing.T) {
+	for _, source := range []TraceSource{TraceGoogle, TraceAzure, TraceAlibaba, TraceCustom} {
+		plain := filepath.Join("testdata", "traces", string(source)+".csv")
+		want, err := LoadWorkloadsFromTrace(plain, source, 100)
+		if err != nil || len(want) == 0 {
+			t.Fatalf("%s: expected workloads from the plain trace, got %d, %v", source, len(want), err)
+		}
+		data, err := os.ReadFile(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		// The content decides, not the name: a gzipped .csv and an uncompressed .gz load too.
+		for name, content := range map[string][]byte{"trace.csv.gz": buf.Bytes(), "trace.csv": buf.Bytes(), "plain.csv.gz": data} {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadWorkloadsFromTrace(path, source, 100)
+			if err != nil || !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %s: expected the workloads of the plain trace, got %+v, %v", source, name, got, err)
+			}
+			if converted, err := ConvertTraceFile(path, source, ConvertOptions{}); err != nil || len(converted) == 0 {
+				t.Errorf("%s %s: expected the trace to convert, got %d workloads, %v", source, name, len(converted), err)
+			}
+		}
+	}
+}
+
+func TestLoadCustomWorkloads_Compressed(t *testing.T) {
+	workloads := WorkloadSet{{Name: "a", CPURequirements: 2, MemoryRequirements: 4}, {Name: "b", CPURequirements: 1, MemoryRequirements: 1}}
+	var jsonl, buf bytes.Buffer
+	if err := WriteWorkloads(&jsonl, workloads, true); err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(&buf)
+	zw.Write(jsonl.Bytes())
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "workloads.jsonl.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loadCustomWorkloads(path); err != nil || !reflect.DeepEqual(got, workloads) {
+		t.Errorf("expected the gzipped JSONL workloads, got %+v, %v", got, err)
+	}
+}
+
+func TestDecompress_UnregisteredZstd(t *testing.T) {
+	decompressorsMu.RLock()
+	for _, d := range decompressors {
+		if d.name == "zstd" {
+			t.Skip("zstd is registered")
+		}
+	}
+	decompressorsMu.RUnlock()
+	_, err := decompress(bytes.NewReader(append(zstdMagic, 0, 0, 0)))
+	if err == nil || !strings.Contains(err.Error(), "RegisterDecompressor") {
+		t.Errorf("expected an error pointing at RegisterDecompressor for zstd input, got %v", err)
+	}
+	if r, err := decompress(strings.NewReader("a")); err != nil {
+		t.Errorf("expected input shorter than any magic to pass through, got %v", err)
+	} else if data, _ := io.ReadAll(r); string(data) != "a" {
+		t.Errorf("expected %q, got %q", "a", data)
+	}
+}
@@ -0,0 +1,373 @@
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+)
+
+/*
+Config controls how workloads are selected and packed.
+
+The zero value reproduces the behaviour of BinPackWorkloads with
+StrategyGeneralPurpose and no quota, so callers only need to set the
+options they care about.
+*/
+type Config struct {
+	// Algorithm names the registered packing algorithm (see PackingAlgorithms) the simulation
+	// functions pack with; empty means DefaultPackingAlgorithm. The packers ignore it.
+	Algorithm string
+	// Approx makes the simulation functions pack with PackApprox, aggregating shape-identical
+	// workloads, and report its error bound in SimulationResult.Approx. The packers ignore it.
+	Approx bool
+	// SortKey is the order the decreasing packers take workloads in (see SortKey). The zero
+	// value is SortKeyLegacy.
+	SortKey SortKey
+	// Strategy is the selection strategy used for every workload.
+	// An empty value means StrategyGeneralPurpose.
+	Strategy SelectionStrategy
+	// StrategyThresholds classifies workloads when Strategy is StrategyAuto.
+	StrategyThresholds StrategyThresholds
+	// Quota enforces per-family vCPU quotas when non-nil.
+	Quota QuotaMap
+	// StrictQuota makes simulations fail instead of warn when CheckQuotaFeasibility finds
+	// that the workloads cannot fit under Quota.
+	StrictQuota bool
+	// StrictRequirements makes simulations fail instead of warn when PrecheckWorkloads finds
+	// workloads no candidate can run.
+	StrictRequirements bool
+	// StrictSKUs makes the simulation functions loading SKU files fail instead of warn when
+	// the SKU data looks wrong (see LoadAzureInstanceSpecsWithWarnings).
+	StrictSKUs bool
+	// QuotaFamiliesOnly restricts the candidates to the families Quota grants vCPUs to (see
+	// AllowedFamiliesFromQuota), as for a subscription without the other series enabled.
+	QuotaFamiliesOnly bool
+	// Preemption lets SimulateArrivals evict lower-priority workloads to place higher-priority
+	// ones once Limits or Quota are exhausted.
+	Preemption bool
+	// WarmPool keeps standby VMs running from the start of SimulateArrivals when non-nil.
+	WarmPool *WarmPoolSpec
+	// Availability makes provisioning fail during outages in SimulateArrivals when non-nil,
+	// excluding failed SKUs for a while (see AvailabilityModel).
+	Availability *AvailabilityModel
+	// Headroom appends synthetic buffer workloads before packing when non-nil.
+	Headroom *HeadroomSpec
+	// Limits stops provisioning new VMs once their total capacity would exceed it,
+	// like NodePool.spec.limits. Remaining workloads are reported as unpacked.
+	Limits Limits
+	// FitMarginPercent is the share of each VM's capacity that must stay free: instance types that
+	// cannot hold a workload with the margin left free are not selected, and packing stops
+	// admitting workloads onto a VM at the margin. The zero value packs VMs up to 100%.
+	FitMarginPercent FitMargin
+	// MinFit is the smallest share of its instance type a workload may use by itself (see
+	// SelectionFit), from 0 to 1: a workload whose best instance type it would use less of is
+	// unpacked with ReasonPoorFit instead of provisioning a grossly oversized VM. 0 accepts any fit.
+	MinFit float64
+	// MaxWorkloadsPerVM caps the workloads packed onto one VM, e.g. to model coarse jobs
+	// rather than pods. Where the SKU's or node class's MaxPods is lower, that stricter limit
+	// applies. 0 leaves the count unlimited.
+	MaxWorkloadsPerVM int
+	// SplitMaxCPU and SplitMaxMemoryGiB split simulated workloads above them into replicas
+	// before packing (see SplitOversized). 0 leaves workloads whole.
+	SplitMaxCPU       int
+	SplitMaxMemoryGiB float64
+	// AllowUnknownGPUType lets workloads requesting a GPUType select SKUs with enough GPUs whose
+	// model neither the SKU data nor its family tells (see InferGPUType). Such SKUs score below
+	// SKUs known to carry the model instead of being filtered out.
+	AllowUnknownGPUType bool
+	// Filters replaces the default filter chain (see DefaultFilters) when non-nil, e.g. with a
+	// relaxed version of a built-in filter. The filters of the options above and below are still
+	// appended to it.
+	Filters []NamedFilter
+	// DisableFilters removes the filters with these names from the chain, e.g. "zone" to pack
+	// zone-pinned workloads onto any SKU in a global capacity study. The simulation functions
+	// reject names the chain does not contain.
+	DisableFilters []string
+	// NodeClass applies the AKSNodeClass settings of the simulated nodes (see
+	// ConstraintsFromAKSNodeClass). The zero value constrains nothing.
+	NodeClass NodeClassConstraints
+	// MinZones rejects instance types offered in fewer availability zones, on top of each
+	// workload's own MinZones, and is the zone count SimulationResult.ZoneWarnings checks
+	// VMs against. 0 disables the constraint.
+	MinZones int
+	// CapacityReservations are filled, in order, before any pay-as-you-go VM is created.
+	// Reserved VMs are priced at the reservation's rate and do not count against Quota.
+	CapacityReservations []CapacityReservation
+	// PruneTopN keeps only the N cheapest candidates able to hold a workload before
+	// scoring. 0 disables pruning.
+	PruneTopN int
+	// VerifyPruning also scores the unpruned candidate set and counts selections whose
+	// winner changed in PruneStats. It removes the speedup and is meant for validation.
+	VerifyPruning bool
+	// PruneStats receives pruning metrics when non-nil. With the score cache enabled,
+	// only selections that miss the cache are counted.
+	PruneStats *PruneStats
+	// ExplorationTopK enables exploration: instead of always taking the top-scoring
+	// candidate, one of the top K is drawn at random, weighted by score. Values <= 1
+	// disable exploration.
+	ExplorationTopK int
+	// ExplorationTemperature controls how strongly the draw favours higher scores
+	// (softmax temperature). 0 disables exploration and selects the argmax exactly.
+	ExplorationTemperature float64
+	// Seed seeds the random source used by exploration, making runs reproducible.
+	Seed int64
+	// FamilyPreferences is an ordered list of preferred VM families or series (e.g. "D", "E").
+	// It biases scoring with a small bonus and breaks exact ties, but never filters.
+	FamilyPreferences []string
+	// PreferenceWeights is the score bonus of satisfying each key of WorkloadProfile.Preferences.
+	// Keys without a weight get DefaultPreferenceBonus. Preferences never filter.
+	PreferenceWeights map[string]float64
+	// CostLabelKey attributes the simulated cost to the values of this workload label
+	// (see AttributeCosts) in SimulationResult.CostByLabel, and groups SimulationResult.Groups by
+	// it. Empty disables attribution and groups by WorkloadTypeLabel when workloads carry it.
+	CostLabelKey string
+
+	// Trace overrides where the RunTraceSimulation functions download traces from.
+	Trace TraceOptions
+	// Basis sizes workloads by their requests or, for a rightsizing estimate, their observed
+	// usage (see PackingBasis). The simulation functions apply it and report the cost under
+	// each basis in SimulationResult.BasisComparison; the packers take workloads as given.
+	Basis PackingBasis
+	// Overcommit bounds packing by the limits of workloads (see OvercommitPolicy). The
+	// simulation functions size workloads by their limits under OvercommitLimits, after Basis;
+	// every packer caps the limits on each VM under a capped policy. The zero value packs by requests.
+	Overcommit OvercommitPolicy
+	// Sensitivity lists price perturbations in percent, e.g. 5 and 10, under which the
+	// simulation functions repack the workloads and report how robust the packing is in
+	// SimulationResult.Sensitivity (see SensitivityAnalysis).
+	Sensitivity []float64
+	// LoadProfile makes the simulation functions also pack the workloads hour by hour under
+	// a daily load pattern and report it in SimulationResult.LoadProfile when non-nil (see
+	// SimulateLoadProfile).
+	LoadProfile *LoadProfile
+	// ArchComparison makes the simulation functions also pack the workloads onto the amd64
+	// candidates only and report the saving arm64 allows in SimulationResult.ArchComparison
+	// (see RunArchComparison).
+	ArchComparison bool
+	// Baseline makes the simulation functions also compare the packing to a fixed fleet, e.g.
+	// the current nodes, in SimulationResult.Baseline when non-nil (see CompareToBaseline).
+	Baseline BaselineFleet
+	// WorkloadFormat is the schema of the files the RunCustomWorkloadSimulation functions load.
+	WorkloadFormat WorkloadFormat
+
+	// Currency is the ISO 4217 code of the candidates' prices, reported in SimulationResult.Currency.
+	// Empty means DefaultCurrency.
+	Currency string
+	// Projection configures the monthly/annual cost projection in SimulationResult.Projection.
+	Projection ProjectionOptions
+	// HistogramEdges are the bucket edges of SimulationResult.Histogram (see
+	// ParseHistogramEdges). Empty means DefaultHistogramBuckets evenly spaced buckets.
+	HistogramEdges []float64
+	// WithAudit records on every PackedVM the Decision that selected its instance type.
+	// The audit is computed only for provisioned VMs, so it costs nothing when disabled.
+	WithAudit bool
+	// ScoreVersion selects the scoring formula. The zero value is ScoreVersionLegacy, whose
+	// scores are unbounded and only comparable within one candidate set. To migrate to
+	// ScoreVersionNormalized, whose scores are in [0,1], re-derive any thresholds on scores
+	// (e.g. ExplorationTemperature, which is relative to score differences) and expect
+	// selections to favour fit over price more than before: cheap SKUs no longer win on a
+	// cost term that dwarfs every fit term.
+	ScoreVersion ScoreVersion
+	// Weights replaces the built-in weights of the strategy, for every workload, when non-nil
+	// (see ScoringWeights and LoadScoringWeights).
+	Weights *ScoringWeights
+	// DisableScoreCache turns off memoization of candidate rankings per workload shape.
+	DisableScoreCache bool
+	// ScoreCacheStats receives score cache hit/miss counts when non-nil.
+	ScoreCacheStats *ScoreCacheStats
+	// SelectionCache persists the score cache's rankings between runs when non-nil (see
+	// LoadSelectionCache). It is unused when DisableScoreCache is set, and with a Filters
+	// override, whose functions its keys cannot capture.
+	SelectionCache *SelectionCache
+
+	rng      *rand.Rand  // shared by all selections of one packing run
+	cache    *scoreCache // rankings of one packing run, keyed by workload shape
+	progress io.Writer   // where the simulation functions report progress; nil means stdout
+}
+
+// printf reports simulation progress.
+func (c Config) printf(format string, args ...any) {
+	w := c.progress
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+// strategy returns the configured strategy, defaulting to general purpose.
+func (c Config) strategy() SelectionStrategy {
+	if c.Strategy == "" {
+		return StrategyGeneralPurpose
+	}
+	return c.Strategy
+}
+
+// strategyFor returns the strategy to score instance types for w with: the configured
+// strategy, or w's class when it is StrategyAuto.
+func (c Config) strategyFor(w WorkloadProfile) SelectionStrategy {
+	if s := c.strategy(); s != StrategyAuto {
+		return s
+	}
+	return ClassifyWorkloadWithThresholds(w, c.StrategyThresholds)
+}
+
+// exploring reports whether weighted-random exploration is enabled.
+func (c Config) exploring() bool {
+	return c.ExplorationTopK > 1 && c.ExplorationTemperature > 0
+}
+
+// forRun returns a copy of the Config with the per-run state set up: a random source seeded
+// from Seed, so that all selections of one packing run draw from the same sequence, and a
+// fresh score cache, so cached rankings never outlive the Config they were computed with (the
+// SelectionCache keys its rankings by the Config's hash for the same reason).
+func (c Config) forRun() Config {
+	if c.exploring() && c.rng == nil {
+		c.rng = rand.New(rand.NewSource(c.Seed))
+	}
+	if !c.DisableScoreCache {
+		var key []byte
+		persistent := c.SelectionCache
+		if c.Filters != nil {
+			persistent = nil
+		}
+		if persistent != nil {
+			key = c.selectionKey()
+		}
+		c.cache = newScoreCache(c.ScoreCacheStats, persistent, key)
+	}
+	return c
+}
+
+// packer returns PackApprox under Approx, else the packing algorithm named by Algorithm,
+// falling back to the default for an unknown name (see ValidatePackingAlgorithm).
+func (c Config) packer() PackFunc {
+	if c.Approx {
+		return PackApprox
+	}
+	if pack, ok := PackingAlgorithm(c.Algorithm); ok {
+		return pack
+	}
+	pack, _ := PackingAlgorithm(DefaultPackingAlgorithm)
+	return pack
+}
+
+// random returns the run's random source, or a fresh one seeded from Seed.
+func (c Config) random() *rand.Rand {
+	if c.rng != nil {
+		return c.rng
+	}
+	return rand.New(rand.NewSource(c.Seed))
+}
+
+// Summarize is NewSimulationResult plus the Config-dependent fields (currency, limit utilization,
+// cost projection, cost attribution and grouping).
+func (c Config) Summarize(result PackingResult) SimulationResult {
+	sim := NewSimulationResult(result)
+	sim.Currency = currencyOrDefault(c.Currency)
+	sim.LimitCPUUtil, sim.LimitMemUtil = c.Limits.Utilization(result.VMs)
+	sim.Projection = CostProjection(result, c.Projection.HoursPerMonth, c.Projection.SpotDiscount, c.Projection.Reserved)
+	sim.ZoneWarnings = ZoneWarnings(result, c.MinZones)
+	if c.strategy() == StrategyAuto {
+		sim.StrategyMix = strategyMix(result, c.StrategyThresholds)
+	}
+	if len(c.HistogramEdges) > 0 {
+		sim.Histogram = UtilizationHistogramWithEdges(result, c.HistogramEdges)
+	}
+	if c.CostLabelKey != "" {
+		attribution := AttributeCosts(result, c.CostLabelKey)
+		sim.CostByLabel = &attribution
+	}
+	if key := c.groupLabelKey(result); key != "" {
+		groups := SummarizeByLabel(result, key)
+		sim.Groups = &groups
+	}
+	return sim
+}
+
+// groupLabelKey returns the label SimulationResult.Groups breaks result down by: CostLabelKey,
+// or WorkloadTypeLabel when some workload carries it.
+func (c Config) groupLabelKey(result PackingResult) string {
+	if c.CostLabelKey != "" {
+		return c.CostLabelKey
+	}
+	if hasLabel(result, WorkloadTypeLabel) {
+		return WorkloadTypeLabel
+	}
+	return ""
+}
+
+// filters returns the selection filter chain: Filters or defaultFilters, with the lenient GPU
+// filter when AllowUnknownGPUType is set, plus the fit margin, the minimum zone count and the
+// node class constraints when configured, less DisableFilters.
+func (c Config) filters() []NamedFilter {
+	filters := defaultFilters
+	if c.Filters != nil {
+		filters = c.Filters
+	}
+	extend := func(f NamedFilter) {
+		filters = append(filters[:len(filters):len(filters)], f)
+	}
+	if c.AllowUnknownGPUType {
+		filters = slices.Clone(filters)
+		for i, f := range filters {
+			if f.Name == "gpu" {
+				filters[i].Filter = filterByGPUAllowUnknown
+			}
+		}
+	}
+	if c.FitMarginPercent.enabled() {
+		extend(NamedFilter{"fit-margin", c.FitMarginPercent.filter})
+	}
+	if c.Overcommit.capped() {
+		extend(NamedFilter{"overcommit", c.Overcommit.filter})
+	}
+	if c.MinZones > 0 {
+		extend(NamedFilter{"config-min-zones", minZonesFilter(c.MinZones)})
+	}
+	if c.NodeClass.OSDiskSizeGB > 0 {
+		extend(NamedFilter{"ephemeral-os-size", ephemeralOSDiskFilter(c.NodeClass.OSDiskSizeGB)})
+	}
+	if c.NodeClass.MaxPods > 0 {
+		extend(NamedFilter{"node-class-max-pods", nodeClassMaxPodsFilter(c.NodeClass.MaxPods)})
+	}
+	if len(c.DisableFilters) > 0 {
+		filters = slices.DeleteFunc(slices.Clone(filters), func(f NamedFilter) bool {
+			return slices.Contains(c.DisableFilters, f.Name)
+		})
+	}
+	return filters
+}
+
+// checkDisabledFilters returns an error naming the first of DisableFilters that is not in the
+// filter chain, most likely a typo that would otherwise disable nothing.
+func (c Config) checkDisabledFilters() error {
+	enabled := c
+	enabled.DisableFilters = nil
+	filters := enabled.filters()
+	for _, name := range c.DisableFilters {
+		if !slices.ContainsFunc(filters, func(f NamedFilter) bool { return f.Name == name }) {
+			names := make([]string, len(filters))
+			for i, f := range filters {
+				names[i] = f.Name
+			}
+			return fmt.Errorf("cannot disable unknown filter %q, expected one of %s", name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// filterFuncs is filters without the names.
+func (c Config) filterFuncs() []FilterFunc {
+	filters := c.filters()
+	if len(filters) == len(defaultFilters) && c.Filters == nil && !c.AllowUnknownGPUType && len(c.DisableFilters) == 0 {
+		return defaultFilterFuncs
+	}
+	fns := make([]FilterFunc, len(filters))
+	for i, f := range filters {
+		fns[i] = f.Filter
+	}
+	return fns
+}
@@ -0,0 +1,349 @@
+package resolver
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConvertOptions configure ConvertTrace.
+type ConvertOptions struct {
+	MaxRows int     // data rows to read, 0 reads them all
+	Sample  float64 // share of the rows read to keep, drawn with Seed; 0 keeps every row
+	Seed    int64
+	// CPUUnit, MemoryUnit and TimeUnit override the units of the trace's CPU, memory and time
+	// columns, e.g. "millicores", "MiB" or "ms" (see cpuUnits, memoryUnits and timeUnits).
+	// Empty keeps the source's units.
+	CPUUnit, MemoryUnit, TimeUnit string
+}
+
+// Units of trace columns, by what a value must be divided by to convert it to cores, GiB and
+// seconds.
+var (
+	cpuUnits    = map[string]float64{"cores": 1, "millicores": 1000}
+	memoryUnits = map[string]float64{"GiB": 1, "MiB": 1 << 10, "KiB": 1 << 20, "bytes": 1 << 30, "GB": (1 << 30) / 1e9, "MB": (1 << 30) / 1e6}
+	timeUnits   = map[string]float64{"s": 1, "ms": 1e3, "us": 1e6}
+)
+
+// traceUnits are the units of each source's CPU, memory and time columns.
+var traceUnits = map[TraceSource]struct{ cpu, memory, time string }{
+	TraceGoogle:  {"millicores", "MiB", "us"},
+	TraceAzure:   {"cores", "GiB", "s"},
+	TraceAlibaba: {"cores", "GiB", "s"},
+	TraceCustom:  {"cores", "GiB", "s"},
+}
+
+/*
+ConvertTrace reads a trace CSV stream of source into canonical workloads, the JSON that custom
+workload files hold (see WriteWorkloads). Besides CPU and memory it carries the columns the
+trace has of name, namespace, priority, GPU count and type, zone, arrival and duration, by
+their normalized header: case, "_", "-" and spaces are ignored. Arrivals are read from an
+"arrival" column in time units, or as the offset of a "start_time" or "time" column from its
+first value; durations from a "duration" column, or as "end_time" minus "start_time".
+
+Rows are parsed like LoadWorkloadsFromTrace does and named after their data row, so sampling
+keeps the names of the rows it keeps.
+*/
+func ConvertTrace(r io.Reader, source TraceSource, opts ConvertOptions) (WorkloadSet, error) {
+	if opts.MaxRows < 0 {
+		return nil, fmt.Errorf("max rows must not be negative, got %d", opts.MaxRows)
+	}
+	if !(opts.Sample >= 0 && opts.Sample <= 1) {
+		return nil, fmt.Errorf("sample must be between 0 and 1, got %v", opts.Sample)
+	}
+	for _, u := range []struct {
+		kind, unit string
+		units      map[string]float64
+	}{{"cpu", opts.CPUUnit, cpuUnits}, {"memory", opts.MemoryUnit, memoryUnits}, {"time", opts.TimeUnit, timeUnits}} {
+		if _, ok := u.units[u.unit]; u.unit != "" && !ok {
+			return nil, fmt.Errorf("unknown %s unit %q, expected one of %s", u.kind, u.unit, strings.Join(sortedKeys(u.units), ", "))
+		}
+	}
+	maxRows := opts.MaxRows
+	if maxRows == 0 {
+		maxRows = math.MaxInt
+	}
+	return parseTrace(r, source, maxRows, opts, true)
+}
+
+// ConvertTraceFile is ConvertTrace of the trace file at path, which may be gzip or zstd compressed.
+func ConvertTraceFile(path string, source TraceSource, opts ConvertOptions) (WorkloadSet, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ConvertTrace(r, source, opts)
+}
+
+/*
+WriteWorkloads writes workloads as a JSON array, or with jsonl one object per line, in the
+format the -workloads flag of the simulator loads.
+*/
+func WriteWorkloads(w io.Writer, workloads WorkloadSet, jsonl bool) error {
+	if !jsonl {
+		if workloads == nil {
+			workloads = WorkloadSet{}
+		}
+		data, err := json.MarshalIndent(workloads, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, wl := range workloads {
+		if err := enc.Encode(wl); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// traceColumns are the column indexes of a trace, -1 for absent ones.
+type traceColumns struct {
+	cpu, mem                                      int
+	name, namespace, priority, gpu, gpuType, zone int
+	arrival, start, end, duration                 int
+	cpuDiv, memDiv, timeDiv                       float64
+}
+
+// normalizeColumn lower-cases a header and strips separators, so "GPU_Type" matches "gputype".
+func normalizeColumn(col string) string {
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(col)))
+}
+
+// findColumn returns the index of the first header whose normalized name is one of names, or -1.
+func findColumn(header []string, names ...string) int {
+	for i, col := range header {
+		for _, n := range names {
+			if normalizeColumn(col) == n {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// resourceColumns finds the CPU and memory columns of source, as LoadWorkloadsFromTrace always has.
+func resourceColumns(header []string, source TraceSource) (cpuIdx, memIdx int, err error) {
+	cpuIdx, memIdx = -1, -1
+	switch source {
+	case TraceGoogle:
+		// Google trace: columns: ... requested_cpu, requested_memory, ... OR cpu_request, memory_request, ...
+		// Try to find either set of columns for robustness
+		for i, col := range header {
+			lc := strings.ToLower(col)
+			if lc == "requested_cpu" || lc == "cpu_request" {
+				cpuIdx = i
+			}
+			if lc == "requested_memory" || lc == "memory_request" {
+				memIdx = i
+			}
+		}
+		if cpuIdx == -1 || memIdx == -1 {
+			return 0, 0, fmt.Errorf("could not find requested_cpu/requested_memory or cpu_request/memory_request columns (found header: %v)", header)
+		}
+	case TraceAzure:
+		// Azure trace: columns: vCPUs, memoryGB, ...
+		for i, col := range header {
+			if strings.Contains(strings.ToLower(col), "vcpu") {
+				cpuIdx = i
+			}
+			if strings.Contains(strings.ToLower(col), "memory") {
+				memIdx = i
+			}
+		}
+		if cpuIdx == -1 || memIdx == -1 {
+			return 0, 0, errors.New("could not find vCPU/memory columns")
+		}
+	case TraceAlibaba:
+		// Alibaba trace: columns: ... cpu, mem, ...
+		for i, col := range header {
+			if strings.ToLower(col) == "cpu" {
+				cpuIdx = i
+			}
+			if strings.ToLower(col) == "mem" {
+				memIdx = i
+			}
+		}
+		if cpuIdx == -1 || memIdx == -1 {
+			return 0, 0, errors.New("could not find cpu/mem columns")
+		}
+	case TraceCustom:
+		cpuIdx = findColumn(header, "cpu", "cpus", "vcpus", "cpurequirements", "cpurequest")
+		memIdx = findColumn(header, "memory", "mem", "memorygib", "memoryrequirements", "memoryrequest")
+		if cpuIdx == -1 || memIdx == -1 {
+			return 0, 0, fmt.Errorf("could not find cpu/memory columns (found header: %v)", header)
+		}
+	default:
+		return 0, 0, errors.New("unknown trace source")
+	}
+	return cpuIdx, memIdx, nil
+}
+
+// traceLayout returns the columns of header, with the units of source unless opts overrides them.
+func traceLayout(header []string, source TraceSource, opts ConvertOptions) (traceColumns, error) {
+	cpuIdx, memIdx, err := resourceColumns(header, source)
+	if err != nil {
+		return traceColumns{}, err
+	}
+	units := traceUnits[source]
+	unit := func(override, def string) string {
+		if override != "" {
+			return override
+		}
+		return def
+	}
+	return traceColumns{
+		cpu:       cpuIdx,
+		mem:       memIdx,
+		name:      findColumn(header, "name"),
+		namespace: findColumn(header, "namespace"),
+		priority:  findColumn(header, "priority"),
+		gpu:       findColumn(header, "gpu", "gpus", "gpucount", "gpurequirements"),
+		gpuType:   findColumn(header, "gputype"),
+		zone:      findColumn(header, "zone"),
+		arrival:   findColumn(header, "arrival", "arrivalseconds"),
+		start:     findColumn(header, "starttime", "time", "vmcreated"),
+		end:       findColumn(header, "endtime", "vmdeleted"),
+		duration:  findColumn(header, "duration", "durationseconds"),
+		cpuDiv:    cpuUnits[unit(opts.CPUUnit, units.cpu)],
+		memDiv:    memoryUnits[unit(opts.MemoryUnit, units.memory)],
+		timeDiv:   timeUnits[unit(opts.TimeUnit, units.time)],
+	}, nil
+}
+
+/*
+parseTrace reads up to maxRows data rows of a trace CSV stream, keeping a sample of them when
+opts.Sample is set, and reads the optional columns of traceLayout if optional is set.
+*/
+func parseTrace(r io.Reader, source TraceSource, maxRows int, opts ConvertOptions, optional bool) (WorkloadSet, error) {
+	workloads := make(WorkloadSet, 0, minInt(maxRows, maxTracePrealloc))
+	csvr := csv.NewReader(r)
+	csvr.FieldsPerRecord = -1 // rows are bounds-checked below
+	csvr.LazyQuotes = true
+	csvr.ReuseRecord = true
+	header, err := csvr.Read()
+	if err != nil {
+		return nil, err
+	}
+	cols, err := traceLayout(header, source, opts)
+	if err != nil {
+		return nil, err
+	}
+	var rng *rand.Rand
+	if opts.Sample > 0 && opts.Sample < 1 {
+		rng = rand.New(rand.NewSource(opts.Seed))
+	}
+	firstStart := math.NaN()
+	for i := 0; i < maxRows; i++ {
+		row, err := csvr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(row) <= maxInt(cols.cpu, cols.mem) {
+			continue
+		}
+		field := func(idx int) string {
+			if idx < 0 || idx >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[idx])
+		}
+		start, hasStart := traceTime(field(cols.start))
+		if hasStart && math.IsNaN(firstStart) {
+			firstStart = start // arrivals count from the first row read, sampled or not
+		}
+		if rng != nil && rng.Float64() >= opts.Sample {
+			continue
+		}
+		cpu := traceFloat(row[cols.cpu]) / cols.cpuDiv
+		mem := traceFloat(row[cols.mem]) / cols.memDiv
+		if cpu == 0 && mem == 0 {
+			continue
+		}
+		w := WorkloadProfile{
+			Name:               traceWorkloadName(source, i),
+			CPURequirements:    int(cpu), // truncated to whole cores
+			MemoryRequirements: mem,
+		}
+		if optional {
+			if name := field(cols.name); name != "" {
+				w.Name = name
+			}
+			w.Namespace = field(cols.namespace)
+			w.Priority = traceInt(field(cols.priority))
+			w.GPURequirements = traceInt(field(cols.gpu))
+			w.GPUType = field(cols.gpuType)
+			w.Zone = field(cols.zone)
+			if t, ok := traceTime(field(cols.arrival)); ok {
+				w.ArrivalSeconds = t / cols.timeDiv
+			} else if hasStart {
+				w.ArrivalSeconds = (start - firstStart) / cols.timeDiv
+			}
+			if d, ok := traceTime(field(cols.duration)); ok {
+				w.DurationSeconds = d / cols.timeDiv
+			} else if end, ok := traceTime(field(cols.end)); ok && hasStart && end >= start {
+				w.DurationSeconds = (end - start) / cols.timeDiv
+			}
+		}
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+// traceTime parses a trace time or duration, which unlike traceFloat may exceed maxTraceValue,
+// e.g. microsecond timestamps. It reports false for empty, unparsable, non-finite or negative values.
+func traceTime(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(v >= 0 && v <= math.MaxFloat64) { // also rejects NaN
+		return 0, false
+	}
+	return v, true
+}
+
+// readWorkloadsJSONL reads one WorkloadProfile object per line, skipping blank lines.
+func readWorkloadsJSONL(r io.Reader) (WorkloadSet, error) {
+	var workloads WorkloadSet
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var w WorkloadProfile
+		if err := json.Unmarshal([]byte(text), &w); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		workloads = append(workloads, w)
+	}
+	return workloads, scanner.Err()
+}
+
+// TraceSources returns the trace sources ConvertTrace reads, sorted.
+func TraceSources() []string {
+	sources := make([]string, 0, len(traceUnits))
+	for s := range traceUnits {
+		sources = append(sources, string(s))
+	}
+	sort.Strings(sources)
+	return sources
+}
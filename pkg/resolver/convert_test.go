@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertTrace_Sources(t *testing.T) {
+	for _, tc := range []struct {
+		source TraceSource
+		want   WorkloadProfile // the first workload
+		n      int
+	}{
+		// Google reads millicores, MiB and microsecond times; the all-zero row is skipped
+		{TraceGoogle, WorkloadProfile{Name: "google-0", CPURequirements: 2, MemoryRequirements: 4, Priority: 200}, 3},
+		// Azure reads vmcreated and vmdeleted in seconds
+		{TraceAzure, WorkloadProfile{Name: "azure-0", CPURequirements: 2, MemoryRequirements: 8, DurationSeconds: 3600}, 3},
+		{TraceAlibaba, WorkloadProfile{Name: "alibaba-0", CPURequirements: 2, MemoryRequirements: 4, DurationSeconds: 60}, 3},
+		{TraceCustom, WorkloadProfile{Name: "train", Namespace: "ml", CPURequirements: 8, MemoryRequirements: 56, GPURequirements: 1, GPUType: "V100", Priority: 1000, Zone: "1", DurationSeconds: 7200}, 3},
+	} {
+		workloads, err := ConvertTraceFile(filepath.Join("testdata", "traces", string(tc.source)+".csv"), tc.source, ConvertOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.source, err)
+		}
+		if len(workloads) != tc.n || !reflect.DeepEqual(workloads[0], tc.want) {
+			t.Errorf("%s: expected %d workloads starting with %+v, got %+v", tc.source, tc.n, tc.want, workloads)
+			continue
+		}
+		for _, jsonl := range []bool{false, true} {
+			path := filepath.Join(t.TempDir(), "workloads.json")
+			if jsonl {
+				path += "l"
+			}
+			var buf bytes.Buffer
+			if err := WriteWorkloads(&buf, workloads, jsonl); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := loadCustomWorkloads(path)
+			if err != nil {
+				t.Fatalf("%s: load %s: %v", tc.source, filepath.Base(path), err)
+			}
+			if !reflect.DeepEqual(loaded, workloads) {
+				t.Errorf("%s: %s does not load back as written:\n%+v\n%+v", tc.source, filepath.Base(path), workloads, loaded)
+			}
+		}
+	}
+}
+
+func TestConvertTrace_Times(t *testing.T) {
+	google, err := ConvertTraceFile("testdata/traces/google.csv", TraceGoogle, ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Arrivals count from the first row, skipped or not
+	if got := []float64{google[0].ArrivalSeconds, google[1].ArrivalSeconds, google[2].ArrivalSeconds}; !reflect.DeepEqual(got, []float64{0, 0.5, 2}) {
+		t.Errorf("expected arrivals 0, 0.5 and 2 seconds, got %v", got)
+	}
+	if google[2].Name != "google-3" {
+		t.Errorf("expected names to follow the data row, got %s", google[2].Name)
+	}
+	azure, err := ConvertTraceFile("testdata/traces/azure.csv", TraceAzure, ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if azure[1].ArrivalSeconds != 300 || azure[1].DurationSeconds != 0 {
+		t.Errorf("expected a VM without vmdeleted to arrive at 300s with an unknown duration, got %+v", azure[1])
+	}
+	custom, err := ConvertTraceFile("testdata/traces/custom.csv", TraceCustom, ConvertOptions{TimeUnit: "ms", MemoryUnit: "MiB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := custom[2]; w.ArrivalSeconds != 0.06 || w.DurationSeconds != 0.6 || w.MemoryRequirements != 4.0/1024 {
+		t.Errorf("expected unit overrides to apply, got %+v", w)
+	}
+}
+
+func TestConvertTrace_Options(t *testing.T) {
+	var in strings.Builder
+	in.WriteString("cpu,memory\n")
+	for i := 0; i < 1000; i++ {
+		in.WriteString("2,8\n")
+	}
+	convert := func(opts ConvertOptions) WorkloadSet {
+		t.Helper()
+		workloads, err := ConvertTrace(strings.NewReader(in.String()), TraceCustom, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return workloads
+	}
+	if n := len(convert(ConvertOptions{MaxRows: 10})); n != 10 {
+		t.Errorf("expected 10 rows with MaxRows, got %d", n)
+	}
+	sample := convert(ConvertOptions{Sample: 0.2, Seed: 7})
+	if len(sample) < 150 || len(sample) > 250 {
+		t.Errorf("expected about 200 of 1000 rows sampled, got %d", len(sample))
+	}
+	if again := convert(ConvertOptions{Sample: 0.2, Seed: 7}); !reflect.DeepEqual(again, sample) {
+		t.Error("expected the same seed to sample the same rows")
+	}
+	if n := len(convert(ConvertOptions{MaxRows: 100, Sample: 0.5, Seed: 7})); n > 100 {
+		t.Errorf("expected MaxRows to bound the rows read before sampling, got %d", n)
+	}
+	for _, opts := range []ConvertOptions{{MaxRows: -1}, {Sample: 1.5}, {CPUUnit: "mcpu"}, {MemoryUnit: "TiB"}, {TimeUnit: "min"}} {
+		if _, err := ConvertTrace(strings.NewReader(in.String()), TraceCustom, opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestConvertTraceFile_Gzip(t *testing.T) {
+	data, err := os.ReadFile("testdata/traces/alibaba.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+	path := filepath.Join(t.TempDir(), "alibaba.csv.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	workloads, err := ConvertTraceFile(path, TraceAlibaba, ConvertOptions{})
+	if err != nil || len(workloads) != 3 {
+		t.Errorf("expected 3 workloads from a gzipped trace, got %d, %v", len(workloads), err)
+	}
+}
@@ -0,0 +1,180 @@
+package resolver
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// UnallocatedLabel receives the idle share of every VM's cost.
+	UnallocatedLabel = "unallocated"
+	// UnlabeledLabel receives the cost of workloads that lack the attributed label.
+	UnlabeledLabel = "unlabeled"
+	// HeadroomLabel receives the cost of headroom buffer workloads.
+	HeadroomLabel = "headroom"
+	// WorkloadTypeLabel is the label preprocessed traces classify workloads by, e.g. "Interactive".
+	WorkloadTypeLabel = "workload_type"
+)
+
+// CostAttribution splits simulated spend by the value of one workload label.
+type CostAttribution struct {
+	LabelKey string
+	Costs    map[string]float64 // hourly cost per label value, including UnallocatedLabel
+	VMs      []VMCostShares     // per-VM breakdown, in PackingResult order
+}
+
+// VMCostShares is the hourly cost of one VM split by label value.
+type VMCostShares struct {
+	SKU          string
+	PricePerHour float64
+	Shares       map[string]float64 // sums to PricePerHour
+	// Workloads is the hourly cost of each named workload on the VM, by WorkloadProfile.ID.
+	Workloads map[string]float64 `json:",omitempty"`
+}
+
+// Values returns the attributed label values sorted by descending cost.
+func (a CostAttribution) Values() []string {
+	values := make([]string, 0, len(a.Costs))
+	for v := range a.Costs {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if a.Costs[values[i]] != a.Costs[values[j]] {
+			return a.Costs[values[i]] > a.Costs[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
+
+/*
+AttributeCosts divides each VM's hourly cost among its packed workloads proportionally to
+their dominant resource share (the larger of their CPU and memory fraction of the VM) and
+aggregates the result by the value of the labelKey label.
+
+The share of the VM left idle is attributed to UnallocatedLabel. When the dominant shares
+of a VM's workloads add up to more than the whole VM, they are scaled down so the shares
+still sum to the VM price.
+*/
+func AttributeCosts(result PackingResult, labelKey string) CostAttribution {
+	attribution := CostAttribution{LabelKey: labelKey, Costs: make(map[string]float64)}
+	for _, vm := range result.VMs {
+		it := vm.InstanceType
+		shares := make(map[string]float64)
+		var named map[string]float64
+		var total float64
+		for _, w := range vm.Workloads {
+			share := dominantShare(w, it)
+			shares[attributionLabel(w, labelKey)] += share
+			total += share
+			if id := w.ID(); id != "" {
+				if named == nil {
+					named = make(map[string]float64)
+				}
+				named[id] += share
+			}
+		}
+		scale := it.PricePerHour
+		if total > 1 {
+			scale /= total
+		} else {
+			shares[UnallocatedLabel] += 1 - total
+		}
+		for v, share := range shares {
+			shares[v] = share * scale
+			attribution.Costs[v] += shares[v]
+		}
+		for id, share := range named {
+			named[id] = share * scale
+		}
+		attribution.VMs = append(attribution.VMs, VMCostShares{SKU: it.Name, PricePerHour: it.PricePerHour, Shares: shares, Workloads: named})
+	}
+	return attribution
+}
+
+// dominantShare returns the larger of the CPU and memory fraction w occupies on vm.
+func dominantShare(w WorkloadProfile, vm AzureInstanceSpec) float64 {
+	var share float64
+	if vm.VCpus > 0 {
+		share = float64(w.CPURequirements) / float64(vm.VCpus)
+	}
+	if vm.MemoryGiB > 0 {
+		share = math.Max(share, w.MemoryRequirements/vm.MemoryGiB)
+	}
+	return share
+}
+
+// attributionLabel returns the label value the cost of w is attributed to.
+func attributionLabel(w WorkloadProfile, labelKey string) string {
+	if w.Headroom {
+		return HeadroomLabel
+	}
+	if v, ok := w.Labels[labelKey]; ok && v != "" {
+		return v
+	}
+	return UnlabeledLabel
+}
+
+// GroupSummary breaks a packing down by the value of one workload label (see SummarizeByLabel).
+type GroupSummary struct {
+	LabelKey string
+	Groups   []GroupStats // by descending cost, as CostAttribution.Values
+}
+
+// GroupStats is the share of a packing taken by the workloads with one label value.
+type GroupStats struct {
+	Value     string
+	Workloads int
+	VMs       int     // VMs running at least one of the workloads
+	Cost      float64 // attributed hourly cost (see AttributeCosts)
+	CostShare float64 // percentage of the total cost
+	// CPUUtilization and MemoryUtilization are the average utilization of the VMs running
+	// the workloads, in percent (see AverageUtilization).
+	CPUUtilization, MemoryUtilization float64
+}
+
+/*
+SummarizeByLabel groups result by the value of the labelKey label: how many workloads and VMs
+each value has, the cost AttributeCosts attributes to it and its share of the total, and how
+well its VMs are utilized. The idle share of the VMs is its own UnallocatedLabel group without
+workloads or VMs, so the cost shares sum to 100.
+*/
+func SummarizeByLabel(result PackingResult, labelKey string) GroupSummary {
+	attribution := AttributeCosts(result, labelKey)
+	total := TotalCost(result.VMs)
+	workloads := make(map[string]int)
+	vms := make(map[string][]PackedVM)
+	for _, vm := range result.VMs {
+		seen := make(map[string]bool)
+		for _, w := range vm.Workloads {
+			v := attributionLabel(w, labelKey)
+			workloads[v]++
+			if !seen[v] {
+				seen[v] = true
+				vms[v] = append(vms[v], vm)
+			}
+		}
+	}
+	summary := GroupSummary{LabelKey: labelKey}
+	for _, v := range attribution.Values() {
+		g := GroupStats{Value: v, Workloads: workloads[v], VMs: len(vms[v]), Cost: attribution.Costs[v]}
+		if total > 0 {
+			g.CostShare = 100 * g.Cost / total
+		}
+		g.CPUUtilization, g.MemoryUtilization = AverageUtilization(vms[v])
+		summary.Groups = append(summary.Groups, g)
+	}
+	return summary
+}
+
+// hasLabel reports whether any workload of result carries the label key.
+func hasLabel(result PackingResult, key string) bool {
+	for _, vm := range result.VMs {
+		for _, w := range vm.Workloads {
+			if w.Labels[key] != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
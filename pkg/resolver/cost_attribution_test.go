@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAttributeCosts(t *testing.T) {
+	result := PackingResult{VMs: []PackedVM{
+		{
+			InstanceType: AzureInstanceSpec{Name: "a", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.4},
+			Workloads: []WorkloadProfile{
+				{CPURequirements: 2, MemoryRequirements: 4, Labels: map[string]string{"team": "web"}},   // 0.5
+				{CPURequirements: 1, MemoryRequirements: 2, Labels: map[string]string{"team": "batch"}}, // 0.25
+			},
+		},
+		{
+			// Dominant shares add up to 1.5 and are scaled down to the whole VM.
+			InstanceType: AzureInstanceSpec{Name: "b", VCpus: 2, MemoryGiB: 8, PricePerHour: 1.0},
+			Workloads: []WorkloadProfile{
+				{CPURequirements: 2, MemoryRequirements: 1, Labels: map[string]string{"team": "web"}}, // 1.0
+				{CPURequirements: 0, MemoryRequirements: 4},                                           // 0.5
+			},
+		},
+	}}
+	attribution := AttributeCosts(result, "team")
+
+	for i, vm := range attribution.VMs {
+		var sum float64
+		for _, c := range vm.Shares {
+			sum += c
+		}
+		if math.Abs(sum-vm.PricePerHour) > 1e-9 {
+			t.Errorf("VM %d: shares sum to %v, expected %v", i, sum, vm.PricePerHour)
+		}
+	}
+	for label, want := range map[string]float64{
+		"web":            0.2 + 1.0/1.5,
+		"batch":          0.1,
+		UnlabeledLabel:   0.5 / 1.5,
+		UnallocatedLabel: 0.1,
+	} {
+		if got := attribution.Costs[label]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", label, want, got)
+		}
+	}
+	if values := attribution.Values(); values[0] != "web" {
+		t.Errorf("expected web to be the most expensive label, got %v", values)
+	}
+}
+
+func TestAttributeCosts_Headroom(t *testing.T) {
+	result := PackingResult{VMs: []PackedVM{{
+		InstanceType: AzureInstanceSpec{VCpus: 4, MemoryGiB: 16, PricePerHour: 1},
+		Workloads:    []WorkloadProfile{{CPURequirements: 1, MemoryRequirements: 1, Headroom: true}},
+	}}}
+	attribution := AttributeCosts(result, "team")
+	if math.Abs(attribution.Costs[HeadroomLabel]-0.25) > 1e-9 || math.Abs(attribution.Costs[UnallocatedLabel]-0.75) > 1e-9 {
+		t.Errorf("unexpected headroom attribution: %v", attribution.Costs)
+	}
+}
+
+func TestAttributeCosts_Workloads(t *testing.T) {
+	result := PackingResult{VMs: []PackedVM{{
+		InstanceType: AzureInstanceSpec{VCpus: 4, MemoryGiB: 16, PricePerHour: 1},
+		Workloads: []WorkloadProfile{
+			{Name: "web", Namespace: "shop", CPURequirements: 2, MemoryRequirements: 1},
+			{CPURequirements: 1, MemoryRequirements: 1},
+		},
+	}}}
+	attribution := AttributeCosts(result, "team")
+	got := attribution.VMs[0].Workloads
+	if len(got) != 1 || math.Abs(got["shop/web"]-0.5) > 1e-9 {
+		t.Errorf("expected only shop/web to be attributed 0.5, got %v", got)
+	}
+}
+
+func TestSummarizeByLabel_WorkloadTypes(t *testing.T) {
+	workloads, err := LoadPreprocessedWorkloads("testdata/preprocessed/workloads.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	skus := []AzureInstanceSpec{{Name: "Standard_D8s_v5", Family: "DSv5", VCpus: 8, MemoryGiB: 64, PricePerHour: 0.4}}
+	result := (Config{}).Summarize(BinPackWorkloadsWithConfig(workloads, skus, Config{}))
+	// Without a CostLabelKey the workloads are grouped by their workload_type label.
+	g := result.Groups
+	if g == nil || g.LabelKey != WorkloadTypeLabel {
+		t.Fatalf("expected a summary by %s, got %+v", WorkloadTypeLabel, g)
+	}
+	var cost, share float64
+	workloadCount := 0
+	types := make(map[string]bool)
+	for _, s := range g.Groups {
+		cost += s.Cost
+		share += s.CostShare
+		workloadCount += s.Workloads
+		if s.Value != UnallocatedLabel {
+			types[s.Value] = true
+			if s.VMs == 0 || s.CPUUtilization <= 0 || s.CPUUtilization > 100 {
+				t.Errorf("%s: expected VMs and a utilization in (0,100], got %+v", s.Value, s)
+			}
+		}
+	}
+	if len(types) != 3 || workloadCount != len(workloads) {
+		t.Errorf("expected 3 workload types holding %d workloads, got %v holding %d", len(workloads), types, workloadCount)
+	}
+	if math.Abs(cost-result.TotalCost) > 1e-9 || math.Abs(share-100) > 1e-9 {
+		t.Errorf("expected costs summing to %.4f and shares to 100, got %.4f and %.4f", result.TotalCost, cost, share)
+	}
+	for i := 1; i < len(g.Groups); i++ {
+		if g.Groups[i].Cost > g.Groups[i-1].Cost {
+			t.Errorf("expected groups by descending cost, got %+v", g.Groups)
+		}
+	}
+
+	if result := (Config{}).Summarize(BinPackWorkloadsWithConfig(WorkloadSet{{CPURequirements: 1, MemoryRequirements: 1}}, skus, Config{})); result.Groups != nil {
+		t.Errorf("expected no summary without workload types, got %+v", result.Groups)
+	}
+}
@@ -0,0 +1,135 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultCurrency is the currency of SKU files that do not declare one, matching the Azure Retail Prices API default.
+const DefaultCurrency = "USD"
+
+/*
+SKUDataset is a set of SKUs whose prices share one currency.
+
+On disk it is either a plain JSON array of AzureInstanceSpec, priced in DefaultCurrency, or an
+object {"Currency": "EUR", "SKUs": [...]} as written by scripts/fetch_azure_skus.py --currency.
+*/
+type SKUDataset struct {
+	// Currency is the ISO 4217 code of every PricePerHour in SKUs.
+	Currency string
+	SKUs     []AzureInstanceSpec
+	// Warnings flag suspicious SKU data found while loading, see LoadAzureInstanceSpecsWithWarnings.
+	Warnings []string `json:"-"`
+}
+
+// ErrNoSKUs is returned for SKU files without SKUs, e.g. from a failed fetch, which would
+// otherwise simulate as zero VMs at zero cost.
+var ErrNoSKUs = errors.New("no SKUs")
+
+// LoadSKUDataset loads a SKU file, see SKUDataset for the accepted formats. A file without
+// SKUs is an error wrapping ErrNoSKUs.
+func LoadSKUDataset(path string) (SKUDataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SKUDataset{}, err
+	}
+	ds, err := parseSKUDataset(data)
+	if err != nil {
+		return SKUDataset{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(ds.SKUs) == 0 {
+		return SKUDataset{}, fmt.Errorf("%s: %w", path, ErrNoSKUs)
+	}
+	return ds, nil
+}
+
+// LoadSKUDatasets loads a comma-separated list of SKU files and merges them with MergeSKUDatasets.
+func LoadSKUDatasets(paths string) (SKUDataset, error) {
+	var datasets []SKUDataset
+	for _, path := range strings.Split(paths, ",") {
+		ds, err := LoadSKUDataset(strings.TrimSpace(path))
+		if err != nil {
+			return SKUDataset{}, err
+		}
+		datasets = append(datasets, ds)
+	}
+	return MergeSKUDatasets(datasets...)
+}
+
+// parseSKUDataset decodes either SKU file format and validates the SKUs.
+func parseSKUDataset(data []byte) (SKUDataset, error) {
+	ds := SKUDataset{Currency: DefaultCurrency}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &ds); err != nil {
+			return SKUDataset{}, err
+		}
+		ds.Currency = currencyOrDefault(strings.ToUpper(strings.TrimSpace(ds.Currency)))
+	} else if err := json.Unmarshal(data, &ds.SKUs); err != nil {
+		return SKUDataset{}, err
+	}
+	for i, s := range ds.SKUs {
+		if s.VCpus < 0 || s.MemoryGiB < 0 || s.PricePerHour < 0 || s.GPUCount < 0 || s.MaxPods < 0 {
+			return SKUDataset{}, fmt.Errorf("sku %d (%q): negative capacity or price", i, s.Name)
+		}
+	}
+	ds.Warnings = checkSKUs(ds.SKUs)
+	return ds, nil
+}
+
+// MergeSKUDatasets concatenates the SKUs of several datasets. Prices in different currencies
+// cannot be compared, so datasets declaring different currencies are an error.
+func MergeSKUDatasets(datasets ...SKUDataset) (SKUDataset, error) {
+	merged := SKUDataset{Currency: DefaultCurrency}
+	for i, ds := range datasets {
+		currency := currencyOrDefault(ds.Currency)
+		if i > 0 && currency != merged.Currency {
+			return SKUDataset{}, fmt.Errorf("cannot merge SKUs priced in %s with SKUs priced in %s", currency, merged.Currency)
+		}
+		merged.Currency = currency
+		merged.SKUs = append(merged.SKUs, ds.SKUs...)
+		merged.Warnings = append(merged.Warnings, ds.Warnings...)
+	}
+	return merged, nil
+}
+
+// currencySymbols maps the currencies of the Azure Retail Prices API that have an unambiguous symbol.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"INR": "₹",
+	"KRW": "₩",
+}
+
+// CurrencySymbol returns the symbol of an ISO 4217 currency code, or the code itself when it has no
+// unambiguous symbol (e.g. "CHF", or "AUD" and "CAD" which would otherwise read as US dollars).
+// An empty code means DefaultCurrency.
+func CurrencySymbol(code string) string {
+	code = currencyOrDefault(code)
+	if symbol, ok := currencySymbols[code]; ok {
+		return symbol
+	}
+	return code
+}
+
+// currencyOrDefault returns code, or DefaultCurrency when it is empty.
+func currencyOrDefault(code string) string {
+	if code == "" {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// FormatCurrency formats amount with the given number of decimals: prefixed by the currency's
+// symbol when it has one ("€12.50"), otherwise followed by its code ("12.50 CHF").
+func FormatCurrency(currency string, amount float64, decimals int) string {
+	if symbol, ok := currencySymbols[currencyOrDefault(currency)]; ok {
+		return fmt.Sprintf("%s%.*f", symbol, decimals, amount)
+	}
+	return fmt.Sprintf("%.*f %s", decimals, amount, currency)
+}
@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSKUDataset(t *testing.T) {
+	ds, err := parseSKUDataset([]byte(`[{"Name": "Standard_D2_v3", "VCpus": 2, "PricePerHour": 0.1}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ds.Currency != DefaultCurrency || len(ds.SKUs) != 1 {
+		t.Errorf("expected 1 SKU in %s, got %d in %s", DefaultCurrency, len(ds.SKUs), ds.Currency)
+	}
+	ds, err = parseSKUDataset([]byte(` {"Currency": "eur", "SKUs": [{"Name": "Standard_D2_v3", "VCpus": 2, "PricePerHour": 0.09}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ds.Currency != "EUR" || len(ds.SKUs) != 1 || ds.SKUs[0].PricePerHour != 0.09 {
+		t.Errorf("expected 1 SKU at 0.09 EUR, got %+v", ds)
+	}
+	if _, err := parseSKUDataset([]byte(`{"Currency": "EUR", "SKUs": [{"Name": "x", "PricePerHour": -1}]}`)); err == nil {
+		t.Error("expected an error for a negative price")
+	}
+}
+
+func TestLoadSKUDatasetsMixedCurrencies(t *testing.T) {
+	dir := t.TempDir()
+	usd := filepath.Join(dir, "usd.json")
+	eur := filepath.Join(dir, "eur.json")
+	eur2 := filepath.Join(dir, "eur2.json")
+	for path, data := range map[string]string{
+		usd:  `[{"Name": "Standard_D2_v3", "VCpus": 2}]`,
+		eur:  `{"Currency": "EUR", "SKUs": [{"Name": "Standard_D4_v3", "VCpus": 4}]}`,
+		eur2: `{"Currency": "EUR", "SKUs": [{"Name": "Standard_E4_v3", "VCpus": 4}]}`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ds, err := LoadSKUDatasets(eur + "," + eur2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ds.Currency != "EUR" || len(ds.SKUs) != 2 {
+		t.Errorf("expected 2 SKUs in EUR, got %d in %s", len(ds.SKUs), ds.Currency)
+	}
+
+	_, err = LoadSKUDatasets(usd + "," + eur)
+	if err == nil || !strings.Contains(err.Error(), "EUR") || !strings.Contains(err.Error(), "USD") {
+		t.Errorf("expected a mixed-currency error naming both currencies, got %v", err)
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	for _, tc := range []struct {
+		currency string
+		want     string
+	}{
+		{"", "$12.50"},
+		{"USD", "$12.50"},
+		{"EUR", "€12.50"},
+		{"CHF", "12.50 CHF"},
+	} {
+		if got := FormatCurrency(tc.currency, 12.5, 2); got != tc.want {
+			t.Errorf("FormatCurrency(%q) = %q, want %q", tc.currency, got, tc.want)
+		}
+	}
+}
+
+func TestSummarizeCurrency(t *testing.T) {
+	workloads := WorkloadSet{{CPURequirements: 1, MemoryRequirements: 1}}
+	cfg := Config{Currency: "EUR"}
+	result := cfg.Summarize(BinPackWorkloadsWithConfig(workloads, dummyInstanceTypes(), cfg))
+	if result.Currency != "EUR" {
+		t.Errorf("expected EUR, got %q", result.Currency)
+	}
+	if got := NewSimulationResult(PackingResult{}).Currency; got != DefaultCurrency {
+		t.Errorf("expected %s by default, got %q", DefaultCurrency, got)
+	}
+}
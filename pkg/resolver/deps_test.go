@@ -0,0 +1,40 @@
+package resolver
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// allowedModules are the modules the resolver and its report and server packages may depend on
+// besides the standard library. Kubernetes, Karpenter and Azure SDK code belongs in the kube and
+// azureauth subpackages, and database drivers in the programs that need them.
+var allowedModules = map[string]bool{
+	"github.com/Azure/karpenter-provider-azure": true,
+	"gopkg.in/yaml.v2":                          true,
+}
+
+func TestDependencies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go list")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	const self = "github.com/Azure/karpenter-provider-azure/pkg/resolver"
+	out, err := exec.Command(goTool, "list", "-deps", "-f", "{{if .Module}}{{.ImportPath}} {{.Module.Path}}{{end}}",
+		self, self+"/report", self+"/server").Output()
+	if err != nil {
+		t.Fatalf("go list: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		pkg, module, _ := strings.Cut(line, " ")
+		if !allowedModules[module] {
+			t.Errorf("%s is in module %s, which the resolver must not depend on", pkg, module)
+		}
+		if module == "github.com/Azure/karpenter-provider-azure" && pkg != self && !strings.HasPrefix(pkg, self+"/") {
+			t.Errorf("the resolver depends on %s outside pkg/resolver", pkg)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package resolver
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"strings"
+)
+
+/*
+PackDeterministic packs workloads onto candidates with cfg's packing algorithm (see
+Config.Algorithm) as a pure function, for fuzzing and differential tests between algorithm
+versions. It reports no progress, leaves cfg's ScoreCacheStats and SelectionCache untouched,
+draws exploration from a random source seeded from cfg.Seed alone and does not modify its
+arguments. The result is canonical (see PackingResult.Canonical), so equal inputs marshal to
+byte-identical JSON.
+*/
+func PackDeterministic(cfg Config, workloads WorkloadSet, candidates []AzureInstanceSpec) PackingResult {
+	cfg.progress = io.Discard
+	cfg.ScoreCacheStats = nil
+	cfg.SelectionCache = nil
+	cfg.rng, cfg.cache = nil, nil
+	result := cfg.packer()(slices.Clone(workloads), slices.Clone(candidates), cfg)
+	return result.Canonical()
+}
+
+/*
+Canonical returns a copy of r in a canonical order, for comparing packings independently of the
+order the packer produced them in: VMs by ID in creation order, the workloads of each VM and the
+unpacked workloads by ID (see WorkloadProfile.ID), then by size, keeping the packer's order of
+otherwise equal workloads.
+*/
+func (r PackingResult) Canonical() PackingResult {
+	c := r
+	c.VMs = slices.Clone(r.VMs)
+	slices.SortStableFunc(c.VMs, func(a, b PackedVM) int {
+		// VMID pads to four digits; longer IDs were created later.
+		return cmp.Or(cmp.Compare(len(a.ID), len(b.ID)), strings.Compare(a.ID, b.ID))
+	})
+	for i := range c.VMs {
+		c.VMs[i].Workloads = slices.Clone(c.VMs[i].Workloads)
+		slices.SortStableFunc(c.VMs[i].Workloads, compareWorkloads)
+	}
+	c.Unpacked = slices.Clone(r.Unpacked)
+	slices.SortStableFunc(c.Unpacked, func(a, b UnpackedWorkload) int {
+		return cmp.Or(compareWorkloads(a.Workload, b.Workload), strings.Compare(a.Reason, b.Reason))
+	})
+	return c
+}
+
+// compareWorkloads orders workloads by ID, then by vCPUs, memory and GPUs.
+func compareWorkloads(a, b WorkloadProfile) int {
+	return cmp.Or(
+		strings.Compare(a.ID(), b.ID()),
+		cmp.Compare(a.CPURequirements, b.CPURequirements),
+		cmp.Compare(a.MemoryRequirements, b.MemoryRequirements),
+		cmp.Compare(a.GPURequirements, b.GPURequirements),
+	)
+}
@@ -0,0 +1,81 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPackDeterministic_ByteIdentical(t *testing.T) {
+	for seed := int64(1); seed <= int64(*invariantRounds); seed++ {
+		workloads, skus, cfg := invariantCase(seed)
+		cfg.WithAudit = true
+		cfg.ScoreCacheStats = &ScoreCacheStats{}
+		for i := range workloads[:len(workloads)/2] {
+			workloads[i].Preferences = map[string]string{"AcceleratedNetworking": "true", "EphemeralOSDisk": "true", "zone": "1"}
+		}
+		before := append(WorkloadSet{}, workloads...)
+		var first []byte
+		for run := 0; run < 3; run++ {
+			data, err := json.Marshal(PackDeterministic(cfg, workloads, skus))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run == 0 {
+				first = data
+			} else if !bytes.Equal(first, data) {
+				t.Fatalf("seed %d: run %d marshals differently from the first", seed, run)
+			}
+		}
+		if !reflect.DeepEqual(workloads, before) {
+			t.Fatalf("seed %d: expected the workloads to be left unchanged", seed)
+		}
+		if s := cfg.ScoreCacheStats; s.Hits() != 0 || s.Misses() != 0 {
+			t.Fatalf("seed %d: expected the caller's score cache stats untouched, got %d hits, %d misses", seed, s.Hits(), s.Misses())
+		}
+	}
+}
+
+// TestPackDeterministic_Differential checks that the deterministic path packs like the
+// packers it wraps, which are equivalent for the same Config.
+func TestPackDeterministic_Differential(t *testing.T) {
+	for seed := int64(1); seed <= int64(*invariantRounds); seed++ {
+		workloads, skus, cfg := invariantCase(seed)
+		for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive} {
+			legacy := NewSimulationResult(BinPackWorkloads(workloads, skus, strategy))
+			got := NewSimulationResult(PackDeterministic(Config{Strategy: strategy}, workloads, skus))
+			if legacy.VMsUsed != got.VMsUsed || legacy.TotalCost != got.TotalCost || legacy.Unpacked != got.Unpacked {
+				t.Errorf("seed %d, %s: legacy packs %d VMs for %.4f/h with %d unpacked, deterministic %d VMs for %.4f/h with %d unpacked",
+					seed, strategy, legacy.VMsUsed, legacy.TotalCost, legacy.Unpacked, got.VMsUsed, got.TotalCost, got.Unpacked)
+			}
+		}
+		for _, algorithm := range PackingAlgorithms() {
+			cfg.Algorithm = algorithm
+			pack, _ := PackingAlgorithm(algorithm)
+			if want, got := pack(workloads, skus, cfg).Canonical(), PackDeterministic(cfg, workloads, skus); !reflect.DeepEqual(want, got) {
+				t.Errorf("seed %d, %s: expected the canonical packing of the algorithm, got a different one", seed, algorithm)
+			}
+		}
+	}
+}
+
+func TestPackingResultCanonical(t *testing.T) {
+	r := PackingResult{
+		VMs: []PackedVM{
+			{ID: VMID(10000), Workloads: WorkloadSet{{Name: "b"}, {Name: "a", CPURequirements: 2}, {Name: "a", CPURequirements: 1}}},
+			{ID: VMID(2)},
+		},
+		Unpacked: []UnpackedWorkload{{Workload: WorkloadProfile{Name: "z"}}, {Workload: WorkloadProfile{Name: "y"}, Reason: ReasonNoCandidates}},
+	}
+	c := r.Canonical()
+	if c.VMs[0].ID != VMID(2) || c.VMs[1].ID != VMID(10000) {
+		t.Errorf("expected VMs in creation order, got %s, %s", c.VMs[0].ID, c.VMs[1].ID)
+	}
+	if w := c.VMs[1].Workloads; w[0].CPURequirements != 1 || w[1].CPURequirements != 2 || w[2].Name != "b" {
+		t.Errorf("expected workloads by name, then size, got %+v", w)
+	}
+	if c.Unpacked[0].Workload.Name != "y" || r.Unpacked[0].Workload.Name != "z" || r.VMs[0].Workloads[0].Name != "b" {
+		t.Errorf("expected a sorted copy leaving the result unchanged, got %+v from %+v", c.Unpacked, r.Unpacked)
+	}
+}
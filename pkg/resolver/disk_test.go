@@ -0,0 +1,90 @@
+package resolver
+
+import "testing"
+
+// diskSKUs are 8 vCPU SKUs with their documented uncached disk limits and East US list prices.
+func diskSKUs() []AzureInstanceSpec {
+	return []AzureInstanceSpec{
+		{Name: "Standard_D8_v3", Family: "Dv3", VCpus: 8, MemoryGiB: 32, StorageGiB: 200, PricePerHour: 0.384, UncachedDiskIOPS: 8000, DiskMBps: 128},
+		{Name: "Standard_D8s_v3", Family: "Dsv3", VCpus: 8, MemoryGiB: 32, StorageGiB: 64, PricePerHour: 0.384, UncachedDiskIOPS: 12800, DiskMBps: 192},
+		{Name: "Standard_D8s_v5", Family: "Dsv5", VCpus: 8, MemoryGiB: 32, StorageGiB: 0, PricePerHour: 0.384, UncachedDiskIOPS: 12800, DiskMBps: 290},
+		{Name: "Standard_L8s_v3", Family: "Lsv3", VCpus: 8, MemoryGiB: 64, StorageGiB: 1788, PricePerHour: 0.624, UncachedDiskIOPS: 12800, DiskMBps: 290},
+	}
+}
+
+func TestIOFitLimitedByWorstDimension(t *testing.T) {
+	vm := AzureInstanceSpec{StorageGiB: 100, UncachedDiskIOPS: 5000, DiskMBps: 100}
+	for _, tc := range []struct {
+		name     string
+		vm       AzureInstanceSpec
+		workload WorkloadProfile
+		want     float64
+	}{
+		{"no requirements", vm, WorkloadProfile{}, 1},
+		{"capacity", vm, WorkloadProfile{IORequirements: 200}, 0.5},
+		{"iops", vm, WorkloadProfile{IORequirements: 50, IOPSRequirements: 20000}, 0.25},
+		{"throughput", vm, WorkloadProfile{IOPSRequirements: 5000, ThroughputMBpsRequirements: 400}, 0.25},
+		{"undeclared performance", AzureInstanceSpec{StorageGiB: 100}, WorkloadProfile{IOPSRequirements: 1}, 0},
+	} {
+		if got := ioFit(tc.vm, tc.workload); got != tc.want {
+			t.Errorf("%s: ioFit = %.2f, want %.2f", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIOStrategyFavorsModernDiskSKUs(t *testing.T) {
+	// A database needing 12k IOPS and 250 MB/s on remote disks: only Dsv5 and Lsv3 deliver
+	// both, and Dsv5 is cheaper.
+	db := WorkloadProfile{CPURequirements: 4, MemoryRequirements: 16, IOPSRequirements: 12000, ThroughputMBpsRequirements: 250}
+	if got := SelectBestInstanceWithStrategy(diskSKUs(), db, StrategyIOIntensive); got.Name != "Standard_D8s_v5" {
+		t.Errorf("expected Standard_D8s_v5 for remote-disk IO, got %q", got.Name)
+	}
+	// Adding 1 TiB of local scratch space leaves only Lsv3.
+	db.IORequirements = 1024
+	if got := SelectBestInstanceWithStrategy(diskSKUs(), db, StrategyIOIntensive); got.Name != "Standard_L8s_v3" {
+		t.Errorf("expected Standard_L8s_v3 for local-disk IO, got %q", got.Name)
+	}
+	// Capacity alone, the old metric, would have favored the older SKU's larger temp disk.
+	capacityOnly := WorkloadProfile{CPURequirements: 4, MemoryRequirements: 16, IORequirements: 150}
+	if got := SelectBestInstanceWithStrategy(diskSKUs(), capacityOnly, StrategyIOIntensive); got.Name != "Standard_D8_v3" {
+		t.Errorf("expected Standard_D8_v3 for capacity-only IO, got %q", got.Name)
+	}
+}
+
+func TestPackingNeverOversubscribesDiskIOPS(t *testing.T) {
+	candidates := []AzureInstanceSpec{diskSKUs()[2]}
+	workloads := make(WorkloadSet, 4)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2, IOPSRequirements: 5000, ThroughputMBpsRequirements: 50}
+	}
+	result := BinPackWorkloads(workloads, candidates, StrategyIOIntensive)
+	if len(result.VMs) != 2 || len(result.Unpacked) != 0 {
+		t.Fatalf("expected 2 VMs of two 5000 IOPS workloads each, got %d VMs and %d unpacked", len(result.VMs), len(result.Unpacked))
+	}
+	for i, vm := range result.VMs {
+		var iops float64
+		for _, w := range vm.Workloads {
+			iops += w.IOPSRequirements
+		}
+		if iops > vm.InstanceType.UncachedDiskIOPS {
+			t.Errorf("VM %d oversubscribed: %.0f IOPS on %.0f", i, iops, vm.InstanceType.UncachedDiskIOPS)
+		}
+	}
+}
+
+func TestFilterByDiskPerformance(t *testing.T) {
+	w := WorkloadProfile{IOPSRequirements: 10000, ThroughputMBpsRequirements: 200}
+	for _, tc := range []struct {
+		vm   AzureInstanceSpec
+		want bool
+	}{
+		{diskSKUs()[0], false}, // 8000 IOPS
+		{diskSKUs()[1], false}, // 192 MB/s
+		{diskSKUs()[2], true},
+		{AzureInstanceSpec{Name: "undeclared"}, true},
+	} {
+		if got := FilterByDiskPerformance(tc.vm, w); got != tc.want {
+			t.Errorf("FilterByDiskPerformance(%s) = %v, want %v", tc.vm.Name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+/*
+Package resolver selects Azure instance types for workloads and packs workloads onto them,
+simulating how Karpenter would provision a cluster.
+
+Exported functions treat their arguments as read-only: candidate and workload slices, packed
+VMs and the slices and maps inside a Config are never modified, nor appended to in place, so a
+candidate list can be shared between goroutines, e.g. by SelectorService or
+RunStrategyComparison. Results are not always independent of the arguments, though: a function
+with nothing to change may return its input slice itself (SplitOversized, PackingBasis.Apply),
+and the Labels and Capabilities maps of returned values are shared with the inputs. Copy a
+result before modifying it.
+*/
+package resolver
@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	// EventProvision is a new VM; Detail is its SKU.
+	EventProvision EventType = "provision"
+	// EventPlace is a workload placed on a VM.
+	EventPlace EventType = "place"
+	// EventEvict is a workload preempted from a VM, or a spot VM evicted.
+	EventEvict EventType = "evict"
+	// EventConsolidate is a VM removed because the workloads no longer need it.
+	EventConsolidate EventType = "consolidate"
+	// EventExpire is the end of a SKU's exclusion after a provisioning failure; Detail is the SKU.
+	EventExpire EventType = "expire"
+)
+
+// EventTypes lists the event types in the order reports show them.
+var EventTypes = []EventType{EventProvision, EventPlace, EventEvict, EventConsolidate, EventExpire}
+
+// Event is one entry of an EventLog. VMID and WorkloadName are empty when the event has none.
+type Event struct {
+	Time         float64 // seconds from the start of the simulation
+	Type         EventType
+	VMID         string `json:",omitempty"`
+	WorkloadName string `json:",omitempty"`
+	Detail       string `json:",omitempty"`
+}
+
+/*
+EventLog is the event stream of a timeline simulation, in time order: SimulateArrivals records
+provisioning, placements, preemptions and the expiry of SKU exclusions, SimulateLoadProfile the
+VMs each hour provisions and consolidates, and SimulateSpotEvictions the evictions it counts.
+*/
+type EventLog []Event
+
+func (l *EventLog) add(t float64, typ EventType, vmID, workload, detail string) {
+	*l = append(*l, Event{Time: t, Type: typ, VMID: vmID, WorkloadName: workload, Detail: detail})
+}
+
+// sort orders l by time, keeping the recorded order of simultaneous events.
+func (l EventLog) sort() {
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Time < l[j].Time })
+}
+
+// WriteJSONL writes l as one JSON object per line.
+func (l EventLog) WriteJSONL(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, e := range l {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// HourlyEvents counts the events of one hour of an EventLog by type.
+type HourlyEvents struct {
+	Hour   int // hours from the start of the simulation
+	Counts map[EventType]int
+}
+
+// Hourly counts the events of l per type for each hour that has any, in hour order.
+func (l EventLog) Hourly() []HourlyEvents {
+	var hours []HourlyEvents
+	for _, e := range l {
+		h := int(e.Time / 3600)
+		if n := len(hours); n == 0 || hours[n-1].Hour != h {
+			hours = append(hours, HourlyEvents{Hour: h, Counts: make(map[EventType]int)})
+		}
+		hours[len(hours)-1].Counts[e.Type]++
+	}
+	return hours
+}
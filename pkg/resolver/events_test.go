@@ -0,0 +1,120 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLog_ScriptedTimeline(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D2s_v5", Family: "Dsv5", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1},
+		{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+	}
+	// The D2s_v5 fails to provision for a, so a and b share a D4s_v5, the only VM the limit
+	// allows; api then evicts a, which cannot be placed again.
+	workloads := WorkloadSet{
+		{Name: "a", CPURequirements: 2, MemoryRequirements: 2},
+		{Name: "b", CPURequirements: 2, MemoryRequirements: 2, ArrivalSeconds: 10},
+		{Name: "api", CPURequirements: 2, MemoryRequirements: 2, Priority: 10, ArrivalSeconds: 100},
+	}
+	cfg := Config{
+		Limits:       Limits{VMs: 1},
+		Preemption:   true,
+		Availability: &AvailabilityModel{Outages: []CapacityOutage{{SKU: "Standard_D2s_v5", EndSeconds: 60}}, TTLSeconds: 30},
+	}
+	got := SimulateArrivals(workloads, candidates, cfg).Events
+	want := EventLog{
+		{Time: 0, Type: EventProvision, VMID: "vm-0001", Detail: "Standard_D4s_v5"},
+		{Time: 0, Type: EventPlace, VMID: "vm-0001", WorkloadName: "a"},
+		{Time: 10, Type: EventPlace, VMID: "vm-0001", WorkloadName: "b"},
+		{Time: 30, Type: EventExpire, Detail: "Standard_D2s_v5"},
+		{Time: 100, Type: EventEvict, VMID: "vm-0001", WorkloadName: "a", Detail: "preempted by api"},
+		{Time: 100, Type: EventPlace, VMID: "vm-0001", WorkloadName: "api"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events\n%+v\ngot\n%+v", want, got)
+	}
+
+	// Half the workloads are active in the second half of the day: the hour scaling down
+	// consolidates VMs, and hour 0 provisions them again.
+	var day WorkloadSet
+	for range 4 {
+		day = append(day, WorkloadProfile{CPURequirements: 4, MemoryRequirements: 8})
+	}
+	profile := LoadProfile{Hourly: make([]float64, 24)}
+	for h := range profile.Hourly {
+		profile.Hourly[h] = 1
+		if h >= 12 {
+			profile.Hourly[h] = 0.5
+		}
+	}
+	lp, err := SimulateLoadProfile(day, candidates[1:], profile, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[EventType]bool)
+	for _, log := range []EventLog{got, lp.Events} {
+		for i, e := range log {
+			seen[e.Type] = true
+			if i > 0 && e.Time < log[i-1].Time {
+				t.Errorf("event %d at %gs follows one at %gs", i, e.Time, log[i-1].Time)
+			}
+		}
+	}
+	for _, typ := range EventTypes {
+		if !seen[typ] {
+			t.Errorf("expected a %s event, got %+v and %+v", typ, got, lp.Events)
+		}
+	}
+	hourly := lp.Events.Hourly()
+	if len(hourly) != 2 || hourly[0].Hour != 0 || hourly[0].Counts[EventProvision] != 2 || hourly[1].Hour != 12 || hourly[1].Counts[EventConsolidate] != 2 {
+		t.Errorf("expected 2 VMs provisioned at hour 0 and consolidated at hour 12, got %+v", hourly)
+	}
+}
+
+func TestSimulateSpotEvictions_Events(t *testing.T) {
+	packing := PackingResult{VMs: []PackedVM{
+		{ID: "vm-0001", InstanceType: AzureInstanceSpec{Name: "Standard_D4s_v5", PricePerHour: 0.2}},
+		{ID: "vm-0002", InstanceType: AzureInstanceSpec{Name: "Standard_D4s_v5", PricePerHour: 0.2}},
+	}}
+	schedule := []SpotEviction{{VMID: "vm-0002", At: 2 * time.Hour}, {VMID: "vm-0001", At: time.Hour}}
+	sim, err := SimulateSpotEvictions(packing, schedule, SpotSimulationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := EventLog{
+		{Time: 3600, Type: EventEvict, VMID: "vm-0001", Detail: "Delete"},
+		{Time: 7200, Type: EventEvict, VMID: "vm-0002", Detail: "Delete"},
+	}
+	if !reflect.DeepEqual(sim.Events, want) {
+		t.Errorf("expected events %+v, got %+v", want, sim.Events)
+	}
+}
+
+func TestEventLog_WriteJSONL(t *testing.T) {
+	log := EventLog{
+		{Time: 0, Type: EventProvision, VMID: "vm-0001", Detail: "Standard_D4s_v5"},
+		{Time: 1.5, Type: EventPlace, VMID: "vm-0001", WorkloadName: "shop/web"},
+	}
+	var buf bytes.Buffer
+	if err := log.WriteJSONL(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(log) {
+		t.Fatalf("expected %d lines, got %q", len(log), buf.String())
+	}
+	for i, line := range lines {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if e != log[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, log[i], e)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Exit codes of the simulation CLIs, so scripts can tell failure classes apart.
+const (
+	ExitOK            = 0
+	ExitUnpacked      = 1 // some workloads were not packed
+	ExitInputError    = 2 // bad flags or invalid input files
+	ExitDownloadError = 3 // a trace could not be downloaded
+	ExitOutputError   = 4 // a report could not be written
+	// The gates of the diff, sku-diff and replay subcommands, failing without an error.
+	ExitDistributionShift = 5 // diff: the SKU distribution shifted by more than -alert-distance
+	ExitPriceIncrease     = 6 // sku-diff: a price rose by more than -fail-on-price-increase
+	ExitReplayMismatch    = 7 // replay: a replayed result differs from the recorded one
+)
+
+// ErrDownload is wrapped by simulation errors caused by a failed trace download.
+var ErrDownload = errors.New("download trace")
+
+// ExitCodeFor returns the exit code for a simulation error: ExitDownloadError for download
+// failures, ExitUnpacked when nothing was packed and ExitInputError otherwise.
+func ExitCodeFor(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrDownload):
+		return ExitDownloadError
+	case errors.Is(err, ErrNothingPacked):
+		return ExitUnpacked
+	default:
+		return ExitInputError
+	}
+}
+
+// FailureSummary is the terse failure report the CLIs write to stderr as JSON with --output=json.
+type FailureSummary struct {
+	ExitCode int    `json:"exitCode"`
+	Class    string `json:"class"`
+	Error    string `json:"error,omitempty"`
+	// Unpacked and Reasons count the unpacked workloads, in total and by reason.
+	Unpacked int            `json:"unpacked,omitempty"`
+	Reasons  map[string]int `json:"reasons,omitempty"`
+}
+
+// NewFailureSummary returns the summary of exiting with code because of err, which may be nil.
+func NewFailureSummary(code int, err error) FailureSummary {
+	s := FailureSummary{ExitCode: code, Class: exitClass(code)}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+// WithUnpacked adds the unpacked workloads of result to s.
+func (s FailureSummary) WithUnpacked(result SimulationResult) FailureSummary {
+	s.Unpacked = result.Unpacked
+	for _, d := range result.Workloads {
+		if d.VM >= 0 {
+			continue
+		}
+		if s.Reasons == nil {
+			s.Reasons = make(map[string]int)
+		}
+		s.Reasons[d.UnpackedReason]++
+	}
+	return s
+}
+
+// WithPacking is WithUnpacked for an unsummarized packing.
+func (s FailureSummary) WithPacking(result PackingResult) FailureSummary {
+	s.Unpacked = len(result.Unpacked)
+	for _, u := range result.Unpacked {
+		if s.Reasons == nil {
+			s.Reasons = make(map[string]int)
+		}
+		s.Reasons[u.Reason]++
+	}
+	return s
+}
+
+// Write writes s to w as one line of JSON.
+func (s FailureSummary) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+func exitClass(code int) string {
+	switch code {
+	case ExitOK:
+		return "ok"
+	case ExitUnpacked:
+		return "unpacked"
+	case ExitInputError:
+		return "input"
+	case ExitDownloadError:
+		return "download"
+	case ExitOutputError:
+		return "output"
+	case ExitDistributionShift:
+		return "distribution-shift"
+	case ExitPriceIncrease:
+		return "price-increase"
+	case ExitReplayMismatch:
+		return "replay-mismatch"
+	default:
+		return "unknown"
+	}
+}
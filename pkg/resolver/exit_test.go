@@ -0,0 +1,34 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{fmt.Errorf("%w: %w", ErrDownload, errors.New("connection refused")), ExitDownloadError},
+		{nothingPacked([]string{ReasonNoCandidates}), ExitUnpacked},
+		{errors.New("load skus: no such file"), ExitInputError},
+	} {
+		if got := ExitCodeFor(tc.err); got != tc.want {
+			t.Errorf("ExitCodeFor(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestExitCodesDistinct(t *testing.T) {
+	classes := make(map[string]int)
+	for _, code := range []int{ExitOK, ExitUnpacked, ExitInputError, ExitDownloadError, ExitOutputError, ExitDistributionShift, ExitPriceIncrease, ExitReplayMismatch} {
+		class := exitClass(code)
+		if prev, ok := classes[class]; ok || class == "unknown" {
+			t.Errorf("exit code %d: expected a class of its own, got %q (shared with %d)", code, class, prev)
+		}
+		classes[class] = code
+	}
+}
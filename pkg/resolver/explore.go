@@ -0,0 +1,60 @@
+package resolver
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+explore selects one of the top-K ranked candidates (best first) at random, weighted by a softmax over
+their scores with the configured temperature:
+
+	weight_i = exp((score_i - score_max) / temperature)
+
+Low temperatures concentrate the draw on the best candidate, high temperatures approach a
+uniform choice among the top K. Spreading selections this way avoids herding every workload
+onto the single top-scoring SKU, which in real clusters leads to capacity exhaustion.
+*/
+func explore(ranked []RankedCandidate, cfg Config) (AzureInstanceSpec, float64) {
+	if len(ranked) == 0 {
+		return AzureInstanceSpec{}, -1
+	}
+	if len(ranked) > cfg.ExplorationTopK {
+		ranked = ranked[:cfg.ExplorationTopK]
+	}
+	weights := make([]float64, len(ranked))
+	var total float64
+	for i, c := range ranked {
+		weights[i] = math.Exp((c.Score - ranked[0].Score) / cfg.ExplorationTemperature)
+		total += weights[i]
+	}
+	r := cfg.random().Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return ranked[i].Instance, ranked[i].Score
+		}
+		r -= w
+	}
+	last := ranked[len(ranked)-1]
+	return last.Instance, last.Score
+}
+
+// SKUDiversity returns the number of distinct SKUs used by a packing and the Shannon
+// entropy (in bits) of the SKU distribution across its VMs. Higher entropy means the
+// VMs are spread more evenly across SKUs.
+func SKUDiversity(vms []PackedVM) (distinct int, entropy float64) {
+	counts := make(map[string]int)
+	for _, vm := range vms {
+		counts[vm.InstanceType.Name]++
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names) // fixed summation order keeps the result deterministic
+	for _, name := range names {
+		p := float64(counts[name]) / float64(len(vms))
+		entropy -= p * math.Log2(p)
+	}
+	return len(counts), entropy
+}
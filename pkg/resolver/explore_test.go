@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func explorationFixture() ([]AzureInstanceSpec, WorkloadSet) {
+	workloads := make(WorkloadSet, 0, 40)
+	for i := 0; i < 40; i++ {
+		workloads = append(workloads, WorkloadProfile{CPURequirements: 1 + i%2, MemoryRequirements: float64(2 + i%4*2)})
+	}
+	return dummyInstanceTypes(), workloads
+}
+
+func skuNames(result PackingResult) []string {
+	names := make([]string, 0, len(result.VMs))
+	for _, vm := range result.VMs {
+		names = append(names, vm.InstanceType.Name)
+	}
+	return names
+}
+
+func TestExploration_ZeroTemperatureIsArgmax(t *testing.T) {
+	candidates, workloads := explorationFixture()
+	want := BinPackWorkloads(workloads, candidates, StrategyGeneralPurpose)
+	got := BinPackWorkloadsWithConfig(workloads, candidates, Config{ExplorationTopK: 5, ExplorationTemperature: 0, Seed: 7})
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected temperature 0 to match argmax packing:\nwant %v\ngot  %v", skuNames(want), skuNames(got))
+	}
+	for _, w := range workloads {
+		wantVM, wantScore := selectWithStrategy(candidates, w, StrategyGeneralPurpose)
+		gotVM, gotScore := selectWithConfig(candidates, w, Config{ExplorationTopK: 5, Seed: 7})
+		if wantVM.Name != gotVM.Name || wantScore != gotScore {
+			t.Fatalf("expected %s (%v), got %s (%v)", wantVM.Name, wantScore, gotVM.Name, gotScore)
+		}
+	}
+}
+
+func TestExploration_FixedSeedIsDeterministic(t *testing.T) {
+	candidates, workloads := explorationFixture()
+	cfg := Config{ExplorationTopK: 4, ExplorationTemperature: 1, Seed: 42}
+	first := BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+	second := BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected identical packings for the same seed:\n%v\n%v", skuNames(first), skuNames(second))
+	}
+}
+
+func TestExploration_IncreasesSKUDiversity(t *testing.T) {
+	candidates, workloads := explorationFixture()
+	argmax := NewSimulationResult(BinPackWorkloads(workloads, candidates, StrategyGeneralPurpose))
+	explored := NewSimulationResult(BinPackWorkloadsWithConfig(workloads, candidates, Config{ExplorationTopK: 4, ExplorationTemperature: 1, Seed: 42}))
+	if explored.DistinctSKUs <= argmax.DistinctSKUs {
+		t.Errorf("expected exploration to use more SKUs than argmax (%d), got %d", argmax.DistinctSKUs, explored.DistinctSKUs)
+	}
+	if explored.SKUEntropy <= argmax.SKUEntropy {
+		t.Errorf("expected higher SKU entropy with exploration, got %.3f <= %.3f", explored.SKUEntropy, argmax.SKUEntropy)
+	}
+	t.Logf("argmax: %d SKUs, $%.2f/h; exploration: %d SKUs, $%.2f/h", argmax.DistinctSKUs, argmax.TotalCost, explored.DistinctSKUs, explored.TotalCost)
+}
+
+func TestSKUDiversity(t *testing.T) {
+	vms := []PackedVM{
+		{InstanceType: AzureInstanceSpec{Name: "a"}},
+		{InstanceType: AzureInstanceSpec{Name: "a"}},
+		{InstanceType: AzureInstanceSpec{Name: "b"}},
+		{InstanceType: AzureInstanceSpec{Name: "c"}},
+	}
+	distinct, entropy := SKUDiversity(vms)
+	if distinct != 3 || entropy != 1.5 {
+		t.Errorf("expected 3 SKUs with entropy 1.5, got %d and %v", distinct, entropy)
+	}
+	if distinct, entropy := SKUDiversity(nil); distinct != 0 || entropy != 0 {
+		t.Errorf("expected no diversity for an empty packing, got %d and %v", distinct, entropy)
+	}
+}
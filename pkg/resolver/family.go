@@ -0,0 +1,53 @@
+package resolver
+
+import (
+	"strings"
+	"unicode"
+)
+
+// familyPreferenceBonusMax is the bonus for the most preferred family. It is small compared
+// to the cost term of ScoreInstance, so preferences only decide near-ties.
+const familyPreferenceBonusMax = 0.05
+
+/*
+FamilySeries returns the VM series of an instance, e.g. "D" for family "Dsv3" or
+"Standard_D4s_v3", and "NC" for "NCasv3". It is the leading run of upper-case letters
+of the family (or of the name when the family is empty), ignoring a "Standard_" prefix.
+*/
+func FamilySeries(vm AzureInstanceSpec) string {
+	s := vm.Family
+	if s == "" {
+		s = vm.Name
+	}
+	s = strings.TrimPrefix(s, "Standard_")
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsUpper(r) })
+	if end == -1 {
+		return s
+	}
+	return s[:end]
+}
+
+/*
+FamilyPreferenceRank returns the position of the instance's family in preferences, or
+len(preferences) when it is not listed. An entry matches either the series (see
+FamilySeries) or the full family name, case-insensitively.
+*/
+func FamilyPreferenceRank(vm AzureInstanceSpec, preferences []string) int {
+	series := FamilySeries(vm)
+	for i, p := range preferences {
+		if strings.EqualFold(p, series) || strings.EqualFold(p, vm.Family) {
+			return i
+		}
+	}
+	return len(preferences)
+}
+
+// FamilyPreferenceBonus returns a score bonus that decreases linearly with preference rank,
+// from familyPreferenceBonusMax for the first entry down to 0 for unlisted families.
+func FamilyPreferenceBonus(vm AzureInstanceSpec, preferences []string) float64 {
+	if len(preferences) == 0 {
+		return 0
+	}
+	rank := FamilyPreferenceRank(vm, preferences)
+	return familyPreferenceBonusMax * float64(len(preferences)-rank) / float64(len(preferences))
+}
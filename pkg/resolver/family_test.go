@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"testing"
+)
+
+func TestFamilySeries(t *testing.T) {
+	for _, tc := range []struct {
+		vm   AzureInstanceSpec
+		want string
+	}{
+		{AzureInstanceSpec{Family: "Dsv3"}, "D"},
+		{AzureInstanceSpec{Family: "NCasv3"}, "NC"},
+		{AzureInstanceSpec{Family: "Standard_E"}, "E"},
+		{AzureInstanceSpec{Name: "Standard_F16s_v2"}, "F"},
+	} {
+		if got := FamilySeries(tc.vm); got != tc.want {
+			t.Errorf("FamilySeries(%+v): expected %q, got %q", tc.vm, tc.want, got)
+		}
+	}
+}
+
+func TestFamilyPreferenceRankAndBonus(t *testing.T) {
+	prefs := []string{"D", "E"}
+	d := AzureInstanceSpec{Name: "Standard_D4s_v3", Family: "Dsv3"}
+	e := AzureInstanceSpec{Name: "Standard_E4s_v3", Family: "Esv3"}
+	f := AzureInstanceSpec{Name: "Standard_F4s_v2", Family: "Fsv2"}
+	if FamilyPreferenceRank(d, prefs) != 0 || FamilyPreferenceRank(e, prefs) != 1 || FamilyPreferenceRank(f, prefs) != 2 {
+		t.Errorf("unexpected ranks: D=%d E=%d F=%d", FamilyPreferenceRank(d, prefs), FamilyPreferenceRank(e, prefs), FamilyPreferenceRank(f, prefs))
+	}
+	if !(FamilyPreferenceBonus(d, prefs) > FamilyPreferenceBonus(e, prefs) && FamilyPreferenceBonus(e, prefs) > FamilyPreferenceBonus(f, prefs)) {
+		t.Errorf("expected bonus to decrease with rank")
+	}
+	if FamilyPreferenceBonus(f, prefs) != 0 || FamilyPreferenceBonus(d, nil) != 0 {
+		t.Errorf("expected no bonus for unlisted families or empty preferences")
+	}
+}
+
+func TestFamilyPreferences_NearTieAndExpensive(t *testing.T) {
+	workload := WorkloadProfile{CPURequirements: 4, MemoryRequirements: 8}
+	cfg := Config{FamilyPreferences: []string{"D", "E"}}
+	f := AzureInstanceSpec{Name: "Standard_F4s_v2", Family: "Fsv2", VCpus: 4, MemoryGiB: 8, PricePerHour: 0.200}
+
+	nearTie := []AzureInstanceSpec{f, {Name: "Standard_D4_v3", Family: "Dv3", VCpus: 4, MemoryGiB: 8, PricePerHour: 0.201}}
+	if best, _ := selectWithConfig(nearTie, workload, Config{}); best.Name != "Standard_F4s_v2" {
+		t.Fatalf("expected the cheaper F-series to win without preferences, got %s", best.Name)
+	}
+	if best, _ := selectWithConfig(nearTie, workload, cfg); best.Name != "Standard_D4_v3" {
+		t.Errorf("expected the preferred D-series to win a near-tie, got %s", best.Name)
+	}
+
+	expensive := []AzureInstanceSpec{f, {Name: "Standard_D4_v3", Family: "Dv3", VCpus: 4, MemoryGiB: 8, PricePerHour: 0.30}}
+	if best, _ := selectWithConfig(expensive, workload, cfg); best.Name != "Standard_F4s_v2" {
+		t.Errorf("expected the much cheaper F-series to beat the preferred family, got %s", best.Name)
+	}
+}
+
+func TestRankInstanceTypesWithPreferences_ExactTies(t *testing.T) {
+	workload := WorkloadProfile{CPURequirements: 2}
+	candidates := []AzureInstanceSpec{
+		{Name: "f-cheap", Family: "Fsv2", PricePerHour: 0.1},
+		{Name: "e", Family: "Esv3", PricePerHour: 0.3},
+		{Name: "d", Family: "Dsv3", PricePerHour: 0.5},
+		{Name: "f", Family: "Fsv2", PricePerHour: 0.2},
+	}
+	constant := func(AzureInstanceSpec, WorkloadProfile) float64 { return 1 }
+
+	ranked := RankInstanceTypesWithPreferences(candidates, workload, constant, []string{"D", "E"})
+	want := []string{"d", "e", "f-cheap", "f"}
+	for i, c := range ranked {
+		if c.Name != want[i] {
+			t.Fatalf("ranked[%d]: expected %s, got %s", i, want[i], c.Name)
+		}
+	}
+	// Without preferences exact ties fall back to price.
+	if ranked := RankInstanceTypes(candidates, workload, constant); ranked[0].Name != "f-cheap" || ranked[3].Name != "d" {
+		t.Errorf("expected price order without preferences, got %s ... %s", ranked[0].Name, ranked[3].Name)
+	}
+}
@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDisableFilters_Zone(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1"}},
+		{Name: "Standard_D4as_v5", Family: "DASv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.172},
+	}
+	workloads := WorkloadSet{
+		{Name: "east", CPURequirements: 2, MemoryRequirements: 4, Zone: "3"},
+		{Name: "west", CPURequirements: 2, MemoryRequirements: 4, Zone: "2"},
+	}
+	if result := BinPackWorkloadsWithConfig(workloads, candidates, Config{}); len(result.Unpacked) != 2 {
+		t.Fatalf("expected no SKU offered in the pinned zones, got %d VMs", len(result.VMs))
+	}
+	result := BinPackWorkloadsWithConfig(workloads, candidates, Config{DisableFilters: []string{"zone"}})
+	if len(result.Unpacked) != 0 || len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != "Standard_D4as_v5" {
+		t.Errorf("expected both workloads on the cheapest SKU without the zone filter, got %+v", result)
+	}
+	for _, f := range (Config{DisableFilters: []string{"zone"}, MinZones: 2}).filters() {
+		if f.Name == "zone" {
+			t.Errorf("expected the zone filter to be removed, got %v", f.Name)
+		}
+	}
+}
+
+func TestFiltersOverride(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192},
+		{Name: "Standard_D4ps_v5", Family: "DPSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.154, Architecture: "arm64"},
+	}
+	w := WorkloadProfile{Name: "w", CPURequirements: 2, MemoryRequirements: 4}
+	noARM := NamedFilter{"no-arm", func(inst AzureInstanceSpec, _ WorkloadProfile) bool { return inst.Architecture != "arm64" }}
+	cfg := Config{Filters: append(DefaultFilters(), noARM)}
+	if vm, _ := selectWithConfig(candidates, w, cfg.forRun()); vm.Name != "Standard_D4s_v5" {
+		t.Errorf("expected the appended filter to rule out the arm64 SKU, got %q", vm.Name)
+	}
+	if len(DefaultFilters()) != len(defaultFilters) || &DefaultFilters()[0] == &defaultFilters[0] {
+		t.Error("expected DefaultFilters to return a copy of the default chain")
+	}
+
+	// An empty override filters nothing but the configured options.
+	pinned := WorkloadProfile{Name: "pinned", CPURequirements: 2, MemoryRequirements: 4, Zone: "1"}
+	if vm, _ := selectWithConfig(candidates, pinned, Config{Filters: []NamedFilter{}}.forRun()); vm.Name != "Standard_D4ps_v5" {
+		t.Errorf("expected no zone filter in an empty chain, got %q", vm.Name)
+	}
+	if got := (Config{Filters: []NamedFilter{}, MinZones: 2}).filters(); len(got) != 1 || got[0].Name != "config-min-zones" {
+		t.Errorf("expected only the configured min-zones filter, got %d filters", len(got))
+	}
+
+	// The persistent selection cache cannot tell filter functions apart.
+	cache, err := LoadSelectionCache(t.TempDir() + "/cache.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := &ScoreCacheStats{}
+	BinPackWorkloadsWithConfig(WorkloadSet{w}, candidates, Config{Filters: cfg.Filters, SelectionCache: cache, ScoreCacheStats: stats})
+	if len(cache.used) != 0 {
+		t.Errorf("expected a Filters override to bypass the selection cache, got %d sections used", len(cache.used))
+	}
+}
+
+func TestDisableFilters_Unknown(t *testing.T) {
+	skus := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	cfg := Config{DisableFilters: []string{"zones"}, progress: io.Discard}
+	_, _, err := simulate(WorkloadSet{{Name: "w", CPURequirements: 1, MemoryRequirements: 1}}, skus, cfg)
+	if err == nil || !strings.Contains(err.Error(), `"zones"`) {
+		t.Errorf("expected the unknown filter name to be rejected, got %v", err)
+	}
+	cfg.DisableFilters = []string{"zone", "architecture"}
+	if _, _, err := simulate(WorkloadSet{{Name: "w", CPURequirements: 1, MemoryRequirements: 1}}, skus, cfg); err != nil {
+		t.Errorf("expected built-in filters to be disabled, got %v", err)
+	}
+	if cfg.FitMarginPercent = (FitMargin{CPU: 5}); !slices.ContainsFunc(cfg.filters(), func(f NamedFilter) bool { return f.Name == "fit-margin" }) {
+		t.Error("expected the fit margin filter to stay when other filters are disabled")
+	}
+}
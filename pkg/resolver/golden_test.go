@@ -0,0 +1,162 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenResult is what the golden files record for one strategy.
+type goldenResult struct {
+	Strategy     SelectionStrategy
+	ScoreVersion ScoreVersion     `json:",omitempty"`
+	Summary      SimulationResult // without timing and per-VM/per-workload detail
+	SKUCounts    map[string]int   // VMs per SKU; encoding/json sorts the keys
+	Rankings     []goldenRanking  // top candidates for the first distinct workload shapes
+}
+
+// goldenRanking records the scores behind selection decisions, so that a change to the
+// scoring weights shows up even when it does not change which SKUs get picked.
+type goldenRanking struct {
+	CPU, MemoryGiB int
+	Zone           string   `json:",omitempty"`
+	GPU            int      `json:",omitempty"`
+	Top            []string // "<sku> <score>"
+}
+
+// goldenRankings ranks the first n distinct workload shapes with the production selection path.
+func goldenRankings(workloads WorkloadSet, skus []AzureInstanceSpec, cfg Config, n int) []goldenRanking {
+	var out []goldenRanking
+	seen := make(map[workloadShape]bool)
+	for _, w := range workloads {
+		if len(out) == n {
+			break
+		}
+		if seen[shapeOf(w)] {
+			continue
+		}
+		seen[shapeOf(w)] = true
+		r := goldenRanking{CPU: w.CPURequirements, MemoryGiB: int(w.MemoryRequirements), Zone: w.Zone, GPU: w.GPURequirements}
+		ranked := NewSelectorService(skus, cfg).Select(w, 3)
+		for _, c := range ranked {
+			r.Top = append(r.Top, fmt.Sprintf("%s %.6f", c.Instance.Name, c.Score))
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+/*
+TestGoldenSimulation runs the full pipeline (load SKUs and workloads, pack, summarize) for every
+strategy and score version on the committed fixtures and compares the results with
+testdata/golden/<strategy>.json, or <strategy>-normalized.json for ScoreVersionNormalized.
+After an intentional change to selection or packing, regenerate the files with
+
+	go test ./pkg/resolver -run TestGoldenSimulation -update
+*/
+func TestGoldenSimulation(t *testing.T) {
+	skus, err := LoadAzureInstanceSpecs(filepath.Join("testdata", "golden", "skus.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workloads, err := loadCustomWorkloads(filepath.Join("testdata", "golden", "workloads.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []ScoreVersion{ScoreVersionLegacy, ScoreVersionNormalized} {
+		for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive} {
+			name := string(strategy)
+			if version != ScoreVersionLegacy {
+				name += "-" + string(version)
+			}
+			t.Run(name, func(t *testing.T) {
+				testGoldenSimulation(t, workloads, skus, Config{Strategy: strategy, ScoreVersion: version, Seed: 1}, name)
+			})
+		}
+	}
+}
+
+// testGoldenSimulation packs with cfg and compares the result with testdata/golden/<name>.json.
+func testGoldenSimulation(t *testing.T, workloads WorkloadSet, skus []AzureInstanceSpec, cfg Config, name string) {
+	packing := BinPackWorkloadsWithConfig(workloads, skus, cfg)
+	got := goldenResult{Strategy: cfg.Strategy, ScoreVersion: cfg.ScoreVersion, Summary: cfg.Summarize(packing), SKUCounts: make(map[string]int)}
+	got.Summary.Timing = TimingReport{}
+	got.Summary.VMs, got.Summary.Workloads = nil, nil
+	for _, vm := range packing.VMs {
+		got.SKUCounts[vm.InstanceType.Name]++
+	}
+	got.Rankings = goldenRankings(workloads, skus, cfg, 10)
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(want, data) {
+		t.Errorf("simulation result differs from %s (run with -update if the change is intended):\n%s", path, lineDiff(string(want), string(data)))
+	}
+}
+
+// lineDiff returns a minimal line diff of two texts (longest common subsequence), showing at
+// most 20 changed lines.
+func lineDiff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// lcs[i][j] is the LCS length of wl[i:] and gl[j:].
+	lcs := make([][]int, len(wl)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(gl)+1)
+	}
+	for i := len(wl) - 1; i >= 0; i-- {
+		for j := len(gl) - 1; j >= 0; j-- {
+			if wl[i] == gl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var b strings.Builder
+	shown := 0
+	emit := func(prefix string, line int, text string) {
+		if shown < 20 {
+			fmt.Fprintf(&b, "%s line %d: %s\n", prefix, line, strings.TrimSpace(text))
+		} else if shown == 20 {
+			b.WriteString("...\n")
+		}
+		shown++
+	}
+	i, j := 0, 0
+	for i < len(wl) || j < len(gl) {
+		switch {
+		case i < len(wl) && j < len(gl) && wl[i] == gl[j]:
+			i, j = i+1, j+1
+		case j < len(gl) && (i == len(wl) || lcs[i][j+1] >= lcs[i+1][j]):
+			emit("+", j+1, gl[j])
+			j++
+		default:
+			emit("-", i+1, wl[i])
+			i++
+		}
+	}
+	return b.String()
+}
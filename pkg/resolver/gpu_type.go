@@ -0,0 +1,218 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// familyGPUTypes maps GPU VM families, normalized by normalizeFamily, to the GPU model they carry.
+var familyGPUTypes = map[string]string{
+	"nc":          "K80",
+	"ncv2":        "P100",
+	"ncsv2":       "P100",
+	"ncv3":        "V100",
+	"ncsv3":       "V100",
+	"ncast4v3":    "T4",
+	"ncasv3t4":    "T4", // quota family "standardNCASv3_T4Family"
+	"ncadsa100v4": "A100",
+	"ncadsh100v5": "H100",
+	"nd":          "P40",
+	"nds":         "P40",
+	"ndv2":        "V100",
+	"ndsv2":       "V100",
+	"ndasra100v4": "A100",
+	"nv":          "M60",
+	"nvsv3":       "M60",
+	"nvsv4":       "MI25",
+	"nvadsa10v5":  "A10",
+}
+
+// gpuMemoryGiB maps GPU models to the memory of one GPU, as their Azure SKUs carry them.
+var gpuMemoryGiB = map[string]float64{
+	"K80":  12,
+	"P100": 16,
+	"P40":  24,
+	"V100": 16,
+	"T4":   16,
+	"A10":  24,
+	"A100": 80,
+	"H100": 94,
+	"M60":  8,
+	"MI25": 16,
+}
+
+// familyGPUMemoryGiB overrides gpuMemoryGiB for the families, normalized by normalizeFamily,
+// that carry another memory size of their model.
+var familyGPUMemoryGiB = map[string]float64{
+	"ndv2":        32,
+	"ndsv2":       32,
+	"ndasra100v4": 40,
+}
+
+// nameGPUTypes are GPU models recognised as a part of a SKU name, e.g. "T4" in
+// "Standard_NC4as_T4_v3".
+var nameGPUTypes = []string{"A10", "A100", "H100", "T4", "V100"}
+
+// normalizeFamily lower-cases family and strips the "standard" prefix, the "family" suffix
+// and separators, so that "NCasT4_v3" and "standardNCASv3_T4Family" both match.
+func normalizeFamily(family string) string {
+	f := strings.ToLower(family)
+	f = strings.TrimPrefix(f, "standard")
+	f = strings.TrimSuffix(f, "family")
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(f)
+}
+
+/*
+InferGPUType returns the GPU model of vm: its GPUType, or for SKU data that omits it, the
+model of its family or the model named in its SKU name. It returns "" for VMs without GPUs
+and when the model cannot be inferred.
+*/
+func InferGPUType(vm AzureInstanceSpec) string {
+	if vm.GPUType != "" || vm.GPUCount == 0 {
+		return vm.GPUType
+	}
+	if t, ok := familyGPUTypes[normalizeFamily(vm.Family)]; ok {
+		return t
+	}
+	for _, part := range strings.Split(strings.ToUpper(vm.Name), "_") {
+		for _, t := range nameGPUTypes {
+			if part == t {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+/*
+InferGPUMemoryGiB returns the memory of one GPU of vm: its GPUMemoryGiBPerGPU, or for SKU data
+that omits it, the memory of its family's GPUs or of its GPU model (see InferGPUType). It
+returns 0 for VMs without GPUs and when the memory cannot be inferred.
+*/
+func InferGPUMemoryGiB(vm AzureInstanceSpec) float64 {
+	if vm.GPUMemoryGiBPerGPU > 0 || vm.GPUCount == 0 {
+		return vm.GPUMemoryGiBPerGPU
+	}
+	if mem, ok := familyGPUMemoryGiB[normalizeFamily(vm.Family)]; ok {
+		return mem
+	}
+	return gpuMemoryGiB[strings.ToUpper(InferGPUType(vm))]
+}
+
+// gpusRequired returns the GPUs w needs: its GPURequirements, or one when it only requests
+// GPU memory.
+func (w WorkloadProfile) gpusRequired() int {
+	if w.GPURequirements == 0 && w.GPUMemoryGiB > 0 {
+		return 1
+	}
+	return w.GPURequirements
+}
+
+/*
+gpuMemoryFit returns how tightly the GPU memory of vm fits w's GPUMemoryGiB: the requested
+share of the memory of all its GPUs, so the smallest configuration that holds the request
+fits best. It is 1 for workloads without a GPU memory request, 0 when vm has too little, and
+unknownGPUTypeFit when its GPU memory is unknown.
+*/
+func gpuMemoryFit(vm AzureInstanceSpec, w WorkloadProfile) float64 {
+	if w.GPUMemoryGiB <= 0 {
+		return 1
+	}
+	total := totalGPUMemoryGiB(vm)
+	switch {
+	case total == 0:
+		return unknownGPUTypeFit
+	case !fitsWithin(w.GPUMemoryGiB, total):
+		return 0
+	}
+	return min(w.GPUMemoryGiB/total, 1)
+}
+
+// totalGPUMemoryGiB returns the memory of all GPUs of vm together, 0 when it is unknown.
+func totalGPUMemoryGiB(vm AzureInstanceSpec) float64 {
+	return InferGPUMemoryGiB(vm) * float64(vm.GPUCount)
+}
+
+/*
+GPUTypes returns the GPU models w accepts, most preferred first: its GPUType split at commas,
+e.g. "A100, V100" accepts either and prefers A100. It is empty when any model will do.
+*/
+func (w WorkloadProfile) GPUTypes() []string {
+	var types []string
+	for _, t := range strings.Split(w.GPUType, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// gpuTypeMatch is how a VM's GPU model matches a workload's GPUType.
+type gpuTypeMatch int
+
+const (
+	gpuTypeMismatch gpuTypeMatch = iota
+	gpuTypeUnknown               // the SKU data does not say, see InferGPUType
+	gpuTypeMatches
+)
+
+// matchGPUType compares the workload's GPU types with vm's inferred GPU model. For a match it
+// also returns the position of the model in the workload's list, 0 for the most preferred.
+func matchGPUType(vm AzureInstanceSpec, workload WorkloadProfile) (gpuTypeMatch, int) {
+	types := workload.GPUTypes()
+	if len(types) == 0 {
+		return gpuTypeMatches, 0
+	}
+	t := InferGPUType(vm)
+	if t == "" {
+		return gpuTypeUnknown, 0
+	}
+	for rank, want := range types {
+		if strings.EqualFold(t, want) {
+			return gpuTypeMatches, rank
+		}
+	}
+	return gpuTypeMismatch, 0
+}
+
+// unknownGPUTypeFit is gpuFit for a VM whose GPU model is unknown: a soft mismatch that ranks it
+// below VMs known to carry the requested model.
+const unknownGPUTypeFit = 0.5
+
+// gpuTypePreferenceStep is how much gpuFit drops per position a VM's GPU model comes later in
+// the workload's list of GPU types, down to gpuTypePreferenceFloor. Weighted into the score,
+// this is mild: it decides between otherwise similar SKUs, not over a real price difference.
+const (
+	gpuTypePreferenceStep  = 0.1
+	gpuTypePreferenceFloor = 0.6
+)
+
+// filterByGPUAllowUnknown is FilterByGPU letting through VMs whose GPU model or GPU memory is
+// unknown (see Config.AllowUnknownGPUType).
+func filterByGPUAllowUnknown(inst AzureInstanceSpec, workload WorkloadProfile) bool {
+	if workload.gpusRequired() == 0 {
+		return true
+	}
+	match, _ := matchGPUType(inst, workload)
+	return inst.GPUCount >= workload.gpusRequired() && match != gpuTypeMismatch && gpuMemoryFit(inst, workload) > 0
+}
+
+/*
+GPUTypeWarnings returns a warning per SKU with GPUs but no GPUType, saying whether the model
+was inferred from the family or name (see InferGPUType) or is unknown, in which case typed GPU
+requests only get the SKU with Config.AllowUnknownGPUType.
+*/
+func GPUTypeWarnings(skus []AzureInstanceSpec) []string {
+	var warnings []string
+	for _, s := range skus {
+		if s.GPUCount == 0 || s.GPUType != "" {
+			continue
+		}
+		if t := InferGPUType(s); t != "" {
+			warnings = append(warnings, fmt.Sprintf("sku %s has %d GPUs but no GPUType, assuming %s from its family or name", s.Name, s.GPUCount, t))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("sku %s has %d GPUs of unknown type, typed GPU requests will not select it unless unknown GPU types are allowed", s.Name, s.GPUCount))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,182 @@
+package resolver
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// untypedGPUSKUs are GPU SKUs as some SKU dumps list them, without GPUType.
+func untypedGPUSKUs() []AzureInstanceSpec {
+	return []AzureInstanceSpec{
+		{Name: "Standard_NC4as_T4_v3", Family: "NCasT4_v3", VCpus: 4, MemoryGiB: 28, GPUCount: 1, PricePerHour: 0.526},
+		{Name: "Standard_NC24ads_A100_v4", Family: "", VCpus: 24, MemoryGiB: 220, GPUCount: 1, PricePerHour: 3.673},
+		{Name: "Standard_NX6_v1", Family: "NXv1", VCpus: 6, MemoryGiB: 56, GPUCount: 1, PricePerHour: 0.9},
+	}
+}
+
+func TestInferGPUType(t *testing.T) {
+	skus := untypedGPUSKUs()
+	for i, want := range []string{"T4", "A100", ""} {
+		if got := InferGPUType(skus[i]); got != want {
+			t.Errorf("%s: expected %q, got %q", skus[i].Name, want, got)
+		}
+	}
+	if got := InferGPUType(AzureInstanceSpec{Family: "NCasT4_v3", GPUCount: 1, GPUType: "custom"}); got != "custom" {
+		t.Errorf("expected the declared GPUType to win, got %q", got)
+	}
+	if got := InferGPUType(AzureInstanceSpec{Family: "standardNCASv3_T4Family", GPUCount: 1}); got != "T4" {
+		t.Errorf("expected the quota family name to be recognised, got %q", got)
+	}
+}
+
+func TestUnknownGPUTypeStrictAndLenient(t *testing.T) {
+	skus := untypedGPUSKUs()
+	t4 := WorkloadProfile{Name: "t4", CPURequirements: 2, MemoryRequirements: 8, GPURequirements: 1, GPUType: "T4"}
+	unknown := WorkloadProfile{Name: "v620", CPURequirements: 2, MemoryRequirements: 8, GPURequirements: 1, GPUType: "V620"}
+
+	// The family mapping recovers the T4 SKU in both modes
+	for _, lenient := range []bool{false, true} {
+		cfg := Config{AllowUnknownGPUType: lenient}
+		if result := BinPackWorkloadsWithConfig(WorkloadSet{t4}, skus, cfg); len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != "Standard_NC4as_T4_v3" {
+			t.Errorf("lenient=%v: expected the T4 workload on Standard_NC4as_T4_v3, got %+v", lenient, result)
+		}
+	}
+
+	// Strict: only the SKU of unknown model could carry a V620, and it is filtered out
+	result := BinPackWorkloadsWithConfig(WorkloadSet{unknown}, skus, Config{})
+	if len(result.Unpacked) != 1 || result.Unpacked[0].Reason != ReasonNoCandidates {
+		t.Errorf("strict: expected the V620 workload unpacked, got %+v", result)
+	}
+	// Lenient: the SKU of unknown model is selected, known mismatches still are not
+	result = BinPackWorkloadsWithConfig(WorkloadSet{unknown}, skus, Config{AllowUnknownGPUType: true})
+	if len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != "Standard_NX6_v1" {
+		t.Errorf("lenient: expected the V620 workload on the SKU of unknown GPU model, got %+v", result)
+	}
+
+	// A SKU of unknown model scores below an otherwise equal one known to match
+	untyped := skus[2]
+	known := untyped
+	known.Name, known.GPUType = "Standard_NX6_v1_typed", "V620"
+	if gpuFit(untyped, unknown) != unknownGPUTypeFit || gpuFit(known, unknown) != 1 {
+		t.Errorf("expected a soft GPU fit of %g for the unknown model, got %g", unknownGPUTypeFit, gpuFit(untyped, unknown))
+	}
+	top := Config{AllowUnknownGPUType: true}.topN([]AzureInstanceSpec{untyped, known}, unknown, 0)
+	if len(top) != 2 || top[0].Instance.Name != known.Name {
+		t.Errorf("expected the SKU known to carry a V620 ranked first, got %+v", top)
+	}
+}
+
+func TestGPUTypeWarnings(t *testing.T) {
+	warnings := GPUTypeWarnings(append(untypedGPUSKUs(), AzureInstanceSpec{Name: "Standard_D2s_v5", VCpus: 2}, AzureInstanceSpec{Name: "Standard_NV6", GPUCount: 1, GPUType: "M60"}))
+	if len(warnings) != 3 {
+		t.Fatalf("expected a warning per untyped GPU SKU, got %q", warnings)
+	}
+	if !strings.Contains(warnings[0], "assuming T4") || !strings.Contains(warnings[2], "unknown type") {
+		t.Errorf("expected the inferred and the unknown model called out, got %q", warnings)
+	}
+}
+
+func TestGPUTypes(t *testing.T) {
+	for gpuType, want := range map[string][]string{
+		"":                nil,
+		"A100":            {"A100"},
+		"A100, V100":      {"A100", "V100"},
+		" H100 ,, A100 ,": {"H100", "A100"},
+	} {
+		if got := (WorkloadProfile{GPUType: gpuType}).GPUTypes(); !slices.Equal(got, want) {
+			t.Errorf("GPUTypes of %q: expected %q, got %q", gpuType, want, got)
+		}
+	}
+}
+
+func TestMultipleGPUTypes(t *testing.T) {
+	// Two SKUs alike but for the GPU model, so only the preference order tells them apart.
+	skus := []AzureInstanceSpec{
+		{Name: "Standard_NC24ads_A100_v4", Family: "NCADS_A100_v4", VCpus: 24, MemoryGiB: 220, GPUCount: 1, GPUType: "A100", PricePerHour: 3.5},
+		{Name: "Standard_NC24s_v3", Family: "NCSv3", VCpus: 24, MemoryGiB: 220, GPUCount: 1, GPUType: "V100", PricePerHour: 3.5},
+		{Name: "Standard_NC4as_T4_v3", Family: "NCasT4_v3", VCpus: 4, MemoryGiB: 28, GPUCount: 1, GPUType: "T4", PricePerHour: 0.526},
+	}
+	workload := func(gpuType string) WorkloadProfile {
+		return WorkloadProfile{Name: "train", CPURequirements: 16, MemoryRequirements: 64, GPURequirements: 1, GPUType: gpuType}
+	}
+	for _, tc := range []struct {
+		gpuType string
+		allowed []string // SKUs passing FilterByGPU
+		best    string
+	}{
+		{"V100", []string{"Standard_NC24s_v3"}, "Standard_NC24s_v3"},
+		{"A100,V100", []string{"Standard_NC24ads_A100_v4", "Standard_NC24s_v3"}, "Standard_NC24ads_A100_v4"},
+		{"V100, A100", []string{"Standard_NC24ads_A100_v4", "Standard_NC24s_v3"}, "Standard_NC24s_v3"},
+		{"H100,T4", []string{"Standard_NC4as_T4_v3"}, ""},
+		{"", []string{"Standard_NC24ads_A100_v4", "Standard_NC24s_v3", "Standard_NC4as_T4_v3"}, ""},
+	} {
+		w := workload(tc.gpuType)
+		var allowed []string
+		for _, sku := range skus {
+			if FilterByGPU(sku, w) {
+				allowed = append(allowed, sku.Name)
+			}
+		}
+		if !slices.Equal(allowed, tc.allowed) {
+			t.Errorf("%q: expected %v to pass the GPU filter, got %v", tc.gpuType, tc.allowed, allowed)
+		}
+		if tc.best == "" {
+			continue
+		}
+		for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive} {
+			result := BinPackWorkloadsWithConfig(WorkloadSet{w}, skus, Config{Strategy: strategy})
+			if len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != tc.best {
+				t.Errorf("%q with %s: expected one %s, got %+v", tc.gpuType, strategy, tc.best, result)
+			}
+		}
+	}
+	if a100, v100 := gpuFit(skus[0], workload("V100,A100")), gpuFit(skus[1], workload("V100,A100")); !(a100 < v100 && a100 >= gpuTypePreferenceFloor) {
+		t.Errorf("expected a mild preference for V100, got fits %v (A100) and %v (V100)", a100, v100)
+	}
+}
+
+func TestInferGPUMemoryGiB(t *testing.T) {
+	skus := untypedGPUSKUs()
+	for i, want := range []float64{16, 80, 0} {
+		if got := InferGPUMemoryGiB(skus[i]); got != want {
+			t.Errorf("%s: expected %g GiB per GPU, got %g", skus[i].Name, want, got)
+		}
+	}
+	if got := InferGPUMemoryGiB(AzureInstanceSpec{Name: "Standard_ND96asr_v4", Family: "NDasrA100_v4", GPUCount: 8, GPUType: "A100"}); got != 40 {
+		t.Errorf("expected the 40 GiB A100s of the ND A100 v4 family, got %g", got)
+	}
+	if got := InferGPUMemoryGiB(AzureInstanceSpec{GPUCount: 1, GPUType: "T4", GPUMemoryGiBPerGPU: 15}); got != 15 {
+		t.Errorf("expected the declared GPU memory to win, got %g", got)
+	}
+}
+
+func TestGPUMemoryRequirement(t *testing.T) {
+	a100 := AzureInstanceSpec{Name: "Standard_NC24ads_A100_v4", VCpus: 24, MemoryGiB: 220, GPUCount: 1, GPUType: "A100", GPUMemoryGiBPerGPU: 40, PricePerHour: 3.673}
+	t4x4 := AzureInstanceSpec{Name: "Standard_NC64as_T4_v3", Family: "NCasT4_v3", VCpus: 64, MemoryGiB: 440, GPUCount: 4, PricePerHour: 4.352}
+	t4 := AzureInstanceSpec{Name: "Standard_NC4as_T4_v3", Family: "NCasT4_v3", VCpus: 4, MemoryGiB: 28, GPUCount: 1, PricePerHour: 0.526}
+	w := WorkloadProfile{Name: "llm", CPURequirements: 4, MemoryRequirements: 16, GPUMemoryGiB: 40}
+
+	if FilterByGPU(t4, w) || !FilterByGPU(t4x4, w) || !FilterByGPU(a100, w) {
+		t.Errorf("expected 4x16 GiB and 1x40 GiB to hold 40 GiB of GPU memory and 1x16 GiB not to")
+	}
+	if got := gpuMemoryFit(t4x4, w); got != 40.0/64 {
+		t.Errorf("expected the 4 T4s to fit 40 of their 64 GiB, got %g", got)
+	}
+	result := BinPackWorkloadsWithConfig(WorkloadSet{w}, []AzureInstanceSpec{t4, t4x4, a100}, Config{})
+	if len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != a100.Name {
+		t.Fatalf("expected the 40 GiB A100 for 40 GiB of GPU memory, got %+v", result.VMs)
+	}
+
+	// At the same price, the tighter GPU memory still decides.
+	t4x4.PricePerHour = a100.PricePerHour
+	if vm, _ := selectWithConfig([]AzureInstanceSpec{t4x4, a100}, w, Config{}.forRun()); vm.Name != a100.Name {
+		t.Errorf("expected the A100 over 4 T4s at the same price, got %s", vm.Name)
+	}
+	// Without the memory known, the A100 only passes with unknown GPU data allowed.
+	a100.GPUMemoryGiBPerGPU, a100.GPUType, a100.Family = 0, "", ""
+	a100.Name = "Standard_NC24_custom"
+	if FilterByGPU(a100, w) || !filterByGPUAllowUnknown(a100, w) {
+		t.Error("expected an unknown GPU memory to be rejected strictly and let through leniently")
+	}
+}
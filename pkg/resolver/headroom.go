@@ -0,0 +1,145 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+HeadroomSpec describes spare capacity that should be kept on top of the real workloads,
+similar to the "overprovisioning" pattern of running low-priority pause pods in a cluster.
+
+Headroom can be expressed as a percentage of the total CPU/memory requested by the real
+workloads, as explicit buffer workload shapes, or both. Buffer workloads are marked with
+WorkloadProfile.Headroom and are packed after every real workload, so they only fill gaps
+or provision extra VMs once the real demand is placed.
+*/
+type HeadroomSpec struct {
+	CPUPercent    float64           // percentage of total requested vCPUs to reserve
+	MemoryPercent float64           // percentage of total requested memory to reserve
+	Buffers       []WorkloadProfile // explicit buffer workload shapes
+}
+
+// headroomMemoryUnitGiB caps the memory of a single percentage-derived buffer workload,
+// so large memory buffers are split into pods that can actually be packed.
+const headroomMemoryUnitGiB = 4.0
+
+/*
+ParseHeadroomSpec parses a CLI headroom value such as "cpu=10%,memory=10%".
+The "%" suffix is optional; "mem" is accepted as an alias for "memory".
+*/
+func ParseHeadroomSpec(s string) (*HeadroomSpec, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	cpu, mem, err := parseResourcePercents(s, "headroom")
+	if err != nil {
+		return nil, err
+	}
+	return &HeadroomSpec{CPUPercent: cpu, MemoryPercent: mem}, nil
+}
+
+// parseResourcePercents parses "cpu=10%,memory=10%" style values; what names the value in errors.
+func parseResourcePercents(s, what string) (cpu, mem float64, err error) {
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return 0, 0, fmt.Errorf("invalid %s entry %q, expected key=value", what, part)
+		}
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(kv[1]), "%"), 64)
+		if err != nil || pct < 0 {
+			return 0, 0, fmt.Errorf("invalid %s percentage %q", what, kv[1])
+		}
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "cpu":
+			cpu = pct
+		case "memory", "mem":
+			mem = pct
+		default:
+			return 0, 0, fmt.Errorf("unknown %s resource %q", what, kv[0])
+		}
+	}
+	return cpu, mem, nil
+}
+
+/*
+BufferWorkloads returns the synthetic buffer workloads for the given real workloads.
+
+Percentage buffers are split into pods of at most 1 vCPU and 4 GiB each so they can
+be spread across VMs; explicit buffer shapes are returned as-is. All returned workloads
+have Headroom set to true.
+*/
+func (h *HeadroomSpec) BufferWorkloads(workloads WorkloadSet) WorkloadSet {
+	if h == nil {
+		return nil
+	}
+	var totalCPU, totalMem float64
+	for _, w := range workloads {
+		if w.Headroom {
+			continue
+		}
+		totalCPU += float64(w.CPURequirements)
+		totalMem += w.MemoryRequirements
+	}
+	bufCPU := totalCPU * h.CPUPercent / 100
+	bufMem := totalMem * h.MemoryPercent / 100
+
+	var buffers WorkloadSet
+	n := int(math.Max(math.Ceil(bufCPU), math.Ceil(bufMem/headroomMemoryUnitGiB)))
+	cpuPods := int(math.Ceil(bufCPU))
+	for i := 0; i < n; i++ {
+		b := WorkloadProfile{
+			MemoryRequirements: bufMem / float64(n),
+			Headroom:           true,
+		}
+		if i < cpuPods {
+			b.CPURequirements = 1
+		}
+		buffers = append(buffers, b)
+	}
+	for _, b := range h.Buffers {
+		b.Headroom = true
+		buffers = append(buffers, b)
+	}
+	return buffers
+}
+
+// withHeadroom returns workloads with the buffer workloads of spec appended.
+func withHeadroom(workloads WorkloadSet, spec *HeadroomSpec) WorkloadSet {
+	buffers := spec.BufferWorkloads(workloads)
+	if len(buffers) == 0 {
+		return workloads
+	}
+	out := make(WorkloadSet, 0, len(workloads)+len(buffers))
+	out = append(out, workloads...)
+	return append(out, buffers...)
+}
+
+/*
+HeadroomCost returns the hourly cost attributable to headroom buffer workloads.
+Each VM contributes its price multiplied by the dominant (CPU or memory) share
+its buffer workloads occupy.
+*/
+func HeadroomCost(vms []PackedVM) float64 {
+	var sum float64
+	for _, vm := range vms {
+		var cpu, mem float64
+		for _, w := range vm.Workloads {
+			if w.Headroom {
+				cpu += float64(w.CPURequirements)
+				mem += w.MemoryRequirements
+			}
+		}
+		share := 0.0
+		if vm.InstanceType.VCpus > 0 {
+			share = cpu / float64(vm.InstanceType.VCpus)
+		}
+		if vm.InstanceType.MemoryGiB > 0 {
+			share = math.Max(share, mem/vm.InstanceType.MemoryGiB)
+		}
+		sum += vm.InstanceType.PricePerHour * math.Min(share, 1.0)
+	}
+	return sum
+}
@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseHeadroomSpec(t *testing.T) {
+	spec, err := ParseHeadroomSpec("cpu=10%,memory=20%")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spec.CPUPercent != 10 || spec.MemoryPercent != 20 {
+		t.Errorf("expected cpu=10 memory=20, got %+v", spec)
+	}
+	if spec, err := ParseHeadroomSpec(""); err != nil || spec != nil {
+		t.Errorf("expected nil spec for empty input, got %+v, %v", spec, err)
+	}
+	for _, bad := range []string{"cpu", "cpu=abc", "gpu=10%", "cpu=-5%"} {
+		if _, err := ParseHeadroomSpec(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestBinPackWorkloadsWithConfig_Headroom(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "small", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.1},
+		{Name: "large", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.2},
+	}
+	workloads := WorkloadSet{
+		{CPURequirements: 4, MemoryRequirements: 16},
+		{CPURequirements: 4, MemoryRequirements: 16},
+		{CPURequirements: 2, MemoryRequirements: 8},
+	}
+	cfg := Config{Headroom: &HeadroomSpec{CPUPercent: 40, MemoryPercent: 40}}
+	result := BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+
+	buffers := cfg.Headroom.BufferWorkloads(workloads)
+	if len(buffers) == 0 {
+		t.Fatalf("expected buffer workloads to be generated")
+	}
+	real, headroom := 0, 0
+	for _, vm := range result.VMs {
+		for _, w := range vm.Workloads {
+			if w.Headroom {
+				headroom++
+			} else {
+				real++
+			}
+		}
+	}
+	if real != len(workloads) || headroom != len(buffers) {
+		t.Fatalf("expected %d real and %d headroom workloads packed, got %d and %d", len(workloads), len(buffers), real, headroom)
+	}
+
+	sim := NewSimulationResult(result)
+	if sim.HeadroomCost <= 0 || sim.HeadroomCost >= sim.TotalCost {
+		t.Errorf("expected headroom cost in (0, %.2f), got %.2f", sim.TotalCost, sim.HeadroomCost)
+	}
+	marked := 0
+	for _, d := range sim.VMs {
+		marked += d.HeadroomWorkloads
+	}
+	if marked != len(buffers) {
+		t.Errorf("expected %d headroom workloads in per-VM detail, got %d", len(buffers), marked)
+	}
+
+	// Utilization must only count real workloads.
+	var capCPU, capMem float64
+	for _, vm := range result.VMs {
+		capCPU += float64(vm.InstanceType.VCpus)
+		capMem += vm.InstanceType.MemoryGiB
+	}
+	if want := 10 / capCPU * 100; math.Abs(sim.AvgCPU-want) > 1e-9 {
+		t.Errorf("expected real CPU utilization %.2f, got %.2f", want, sim.AvgCPU)
+	}
+	if want := 40 / capMem * 100; math.Abs(sim.AvgMem-want) > 1e-9 {
+		t.Errorf("expected real memory utilization %.2f, got %.2f", want, sim.AvgMem)
+	}
+}
+
+func TestBinPackWorkloadsWithConfig_ExplicitBuffers(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "small", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.1}}
+	workloads := WorkloadSet{{CPURequirements: 2, MemoryRequirements: 8}}
+	cfg := Config{Headroom: &HeadroomSpec{Buffers: []WorkloadProfile{{CPURequirements: 4, MemoryRequirements: 16}}}}
+	result := BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+	if len(result.VMs) != 2 {
+		t.Fatalf("expected the buffer to require its own VM, got %d VMs", len(result.VMs))
+	}
+	if !result.VMs[1].Workloads[0].Headroom {
+		t.Errorf("expected the buffer to be packed after the real workload")
+	}
+	if cost := HeadroomCost(result.VMs); math.Abs(cost-0.1) > 1e-9 {
+		t.Errorf("expected headroom cost 0.1, got %v", cost)
+	}
+}
@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultHistogramBuckets is the number of evenly spaced buckets of a result's utilization histogram.
+const DefaultHistogramBuckets = 10
+
+/*
+Histogram counts VMs by CPU and memory utilization, which shows a bimodal packing (half the
+VMs nearly full, half nearly empty) that the averages hide.
+
+Bucket i covers utilizations in [Edges[i], Edges[i+1]); the last bucket also includes its upper
+edge, so a full VM counts in the top bucket. Utilizations outside the edges count in the first
+or last bucket.
+*/
+type Histogram struct {
+	Edges  []float64 // ascending percentages, one more than the buckets
+	CPU    []int
+	Memory []int
+}
+
+// EvenHistogramEdges returns the edges of buckets evenly spaced from 0% to 100%.
+func EvenHistogramEdges(buckets int) []float64 {
+	if buckets < 1 {
+		buckets = 1
+	}
+	edges := make([]float64, buckets+1)
+	for i := range edges {
+		edges[i] = 100 * float64(i) / float64(buckets)
+	}
+	return edges
+}
+
+// UtilizationHistogram counts the VMs of result in buckets evenly spaced from 0% to 100%.
+func UtilizationHistogram(result PackingResult, buckets int) Histogram {
+	return UtilizationHistogramWithEdges(result, EvenHistogramEdges(buckets))
+}
+
+// UtilizationHistogramWithEdges counts the VMs of result in the buckets between edges (see
+// Histogram). Per-VM utilization is as in VMDetail; edges must be ascending, see ParseHistogramEdges.
+func UtilizationHistogramWithEdges(result PackingResult, edges []float64) Histogram {
+	h := Histogram{Edges: edges}
+	if len(edges) < 2 {
+		return h
+	}
+	h.CPU = make([]int, len(edges)-1)
+	h.Memory = make([]int, len(edges)-1)
+	for _, vm := range result.VMs {
+		cpu, mem := AverageUtilization([]PackedVM{vm})
+		h.CPU[h.bucket(cpu)]++
+		h.Memory[h.bucket(mem)]++
+	}
+	return h
+}
+
+// bucket returns the index of the bucket utilization falls in.
+func (h Histogram) bucket(utilization float64) int {
+	last := len(h.Edges) - 2
+	for i := 0; i < last; i++ {
+		if utilization < h.Edges[i+1] {
+			return i
+		}
+	}
+	return last
+}
+
+// Label returns the range of bucket i, e.g. "10-20%".
+func (h Histogram) Label(i int) string {
+	return fmt.Sprintf("%g-%g%%", h.Edges[i], h.Edges[i+1])
+}
+
+/*
+ParseHistogramEdges parses a histogram bucket spec: either a bucket count, e.g. "10" for
+buckets evenly spaced from 0% to 100%, or comma-separated ascending edges in percent, e.g.
+"0,50,80,100". An empty spec means DefaultHistogramBuckets.
+*/
+func ParseHistogramEdges(s string) ([]float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return EvenHistogramEdges(DefaultHistogramBuckets), nil
+	}
+	if !strings.Contains(s, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid bucket count %q", s)
+		}
+		return EvenHistogramEdges(n), nil
+	}
+	var edges []float64
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid edge %q", part)
+		}
+		if len(edges) > 0 && v <= edges[len(edges)-1] {
+			return nil, fmt.Errorf("edges must be ascending, got %g after %g", v, edges[len(edges)-1])
+		}
+		edges = append(edges, v)
+	}
+	return edges, nil
+}
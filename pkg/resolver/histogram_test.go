@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+// vmAt returns a 10 vCPU, 10 GiB VM running cpu vCPU and mem GiB.
+func vmAt(cpu int, mem float64) PackedVM {
+	return PackedVM{
+		InstanceType: AzureInstanceSpec{Name: "Standard_D10", VCpus: 10, MemoryGiB: 10},
+		Workloads:    []WorkloadProfile{{CPURequirements: cpu, MemoryRequirements: mem}},
+	}
+}
+
+func TestUtilizationHistogram(t *testing.T) {
+	result := PackingResult{VMs: []PackedVM{
+		vmAt(0, 0),      // 0%: first bucket
+		vmAt(1, 0.999),  // 10% CPU is a lower edge; 9.99% memory is not
+		vmAt(5, 4.99),   // 50% CPU, 49.9% memory
+		vmAt(9, 9.999),  // 90% CPU, 99.99% memory
+		vmAt(10, 10),    // 100%: the last bucket includes its upper edge
+		vmAt(10, 10.01), // oversubscribed memory counts in the last bucket
+	}}
+	h := UtilizationHistogram(result, 10)
+	if want := []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}; !reflect.DeepEqual(h.Edges, want) {
+		t.Fatalf("expected edges %v, got %v", want, h.Edges)
+	}
+	if want := []int{1, 1, 0, 0, 0, 1, 0, 0, 0, 3}; !reflect.DeepEqual(h.CPU, want) {
+		t.Errorf("CPU: expected %v, got %v", want, h.CPU)
+	}
+	if want := []int{2, 0, 0, 0, 1, 0, 0, 0, 0, 3}; !reflect.DeepEqual(h.Memory, want) {
+		t.Errorf("memory: expected %v, got %v", want, h.Memory)
+	}
+
+	// Uneven edges separate the nearly full VMs from the rest.
+	h = UtilizationHistogramWithEdges(result, []float64{0, 50, 80, 100})
+	if want := []int{2, 1, 3}; !reflect.DeepEqual(h.CPU, want) {
+		t.Errorf("CPU with custom edges: expected %v, got %v", want, h.CPU)
+	}
+	if got := h.Label(1); got != "50-80%" {
+		t.Errorf("expected label 50-80%%, got %q", got)
+	}
+}
+
+func TestParseHistogramEdges(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    []float64
+		wantErr bool
+	}{
+		{in: "", want: EvenHistogramEdges(DefaultHistogramBuckets)},
+		{in: "4", want: []float64{0, 25, 50, 75, 100}},
+		{in: "0, 50,80,100", want: []float64{0, 50, 80, 100}},
+		{in: "0", wantErr: true},
+		{in: "ten", wantErr: true},
+		{in: "0,80,50", wantErr: true},
+		{in: "0,50,50", wantErr: true},
+		{in: "0,x", wantErr: true},
+	} {
+		got, err := ParseHistogramEdges(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tc.in, tc.wantErr, err)
+			continue
+		}
+		if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: expected %v, got %v", tc.in, tc.want, got)
+		}
+	}
+}
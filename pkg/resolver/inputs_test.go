@@ -0,0 +1,146 @@
+package resolver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+// withSpare returns s with spare capacity holding a sentinel, so appending to the slice in
+// place would show up in its fingerprint.
+func withSpare[T any](s []T, sentinel T) []T {
+	full := append(append(make([]T, 0, len(s)+2), s...), sentinel, sentinel)
+	return full[:len(s)]
+}
+
+// fingerprint hashes s up to its capacity, including the maps and slices of its elements.
+func fingerprint[T any](t *testing.T, s []T) [32]byte {
+	t.Helper()
+	data, err := json.Marshal(s[:cap(s)])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha256.Sum256(data)
+}
+
+// immutabilityInputs returns candidates and workloads exercising every packing feature that
+// builds derived candidate or workload slices: zones, GPUs, spot, labels, capabilities,
+// oversized workloads and arrivals.
+func immutabilityInputs() ([]AzureInstanceSpec, WorkloadSet) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D8s_v5", Family: "standardDSv5Family", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.384, AvailabilityZones: []string{"2", "1", "3"}, SpotSupported: true, MaxPods: 30},
+		{Name: "Standard_D2s_v5", Family: "standardDSv5Family", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.096, AvailabilityZones: []string{"1"}},
+		{Name: "Standard_E4s_v5", Family: "standardESv5Family", VCpus: 4, MemoryGiB: 32, PricePerHour: 0.252, AvailabilityZones: []string{"3", "1"}, SpotSupported: true},
+		{Name: "Standard_NC6s_v3", Family: "standardNCSv3Family", VCpus: 6, MemoryGiB: 112, PricePerHour: 3.06, GPUCount: 1, GPUType: "V100"},
+	}
+	workloads := WorkloadSet{
+		{Name: "web", CPURequirements: 1, MemoryRequirements: 2, Zone: "1", Labels: map[string]string{"team": "a"}, ArrivalSeconds: 30},
+		{Name: "batch", CPURequirements: 12, MemoryRequirements: 24, RequireSpot: true, Priority: -1, ArrivalSeconds: 10},
+		{Name: "cache", CPURequirements: 2, MemoryRequirements: 20, PreferredZone: "3", Capabilities: map[string]string{"MaxPods": "20"}, Priority: 10},
+		{Name: "train", CPURequirements: 4, MemoryRequirements: 16, GPURequirements: 1, GPUType: "V100", UsageCPU: 2, UsageMemory: 8},
+		{Name: "huge", CPURequirements: 64, MemoryRequirements: 512},
+	}
+	return candidates, workloads
+}
+
+/*
+TestExportedFunctionsDoNotMutateInputs calls every exported function taking candidate or
+workload slices and checks that neither the slices, their spare capacity, nor the maps and
+slices of their elements changed, as the package documentation guarantees.
+*/
+func TestExportedFunctionsDoNotMutateInputs(t *testing.T) {
+	candidates, workloads := immutabilityInputs()
+	candidates = withSpare(candidates, AzureInstanceSpec{Name: "sentinel"})
+	workloads = withSpare(workloads, WorkloadProfile{Name: "sentinel"})
+	wantCandidates, wantWorkloads := fingerprint(t, candidates), fingerprint(t, workloads)
+
+	configs := map[string]Config{
+		"default":      {},
+		"quota":        {Quota: QuotaMap{"standardDSv5Family": 8, SpotQuotaKey: 8}},
+		"reservations": {CapacityReservations: []CapacityReservation{{SKU: "Standard_E4s_v5", Zone: "3", Count: 1}}},
+		"headroom":     {Headroom: &HeadroomSpec{CPUPercent: 20, MemoryPercent: 20}},
+		"limits":       {Limits: Limits{CPU: 16}, FitMarginPercent: FitMargin{CPU: 10}},
+		"auto":         {Strategy: StrategyAuto, FamilyPreferences: []string{"E", "D"}, MinFit: 0.1, MaxWorkloadsPerVM: 2},
+		"explore":      {ExplorationTopK: 3, ExplorationTemperature: 1, Seed: 7, MinZones: 1},
+		"split":        {SplitMaxCPU: 8, SplitMaxMemoryGiB: 64, Basis: BasisUsage, WithAudit: true},
+	}
+	calls := map[string]func(cfg Config){
+		"FilterInstanceTypes": func(Config) {
+			for _, w := range workloads {
+				FilterInstanceTypes(candidates, w, defaultFilterFuncs...)
+			}
+		},
+		"RankInstanceTypesWithPreferences": func(cfg Config) {
+			for _, w := range workloads {
+				RankInstanceTypesWithPreferences(candidates, w, func(vm AzureInstanceSpec, w WorkloadProfile) float64 {
+					return ScoreInstanceWithConfig(vm, w, cfg)
+				}, cfg.FamilyPreferences)
+			}
+		},
+		"SelectTopN": func(cfg Config) {
+			for _, w := range workloads {
+				SelectTopN(candidates, w, cfg.Strategy, 2)
+				SelectBestInstanceWithStrategy(candidates, w, cfg.Strategy)
+			}
+		},
+		"BinPackWorkloadsWithConfig": func(cfg Config) { BinPackWorkloadsWithConfig(workloads, candidates, cfg) },
+		"BinPackWorkloadsWithQuota":  func(cfg Config) { BinPackWorkloadsWithQuota(workloads, candidates, cfg.Strategy, cfg.Quota) },
+		"BinPackWorkloadsNaive":      func(Config) { BinPackWorkloadsNaive(workloads, candidates) },
+		"SimulateArrivals": func(cfg Config) {
+			cfg.Preemption = true
+			cfg.WarmPool = &WarmPoolSpec{SKU: "Standard_D8s_v5", Count: 1, Zones: []string{"1"}}
+			cfg.Availability = &AvailabilityModel{Outages: []CapacityOutage{{SKU: "Standard_D2s_v5", EndSeconds: 60}}}
+			SimulateArrivals(workloads, candidates, cfg)
+		},
+		"SimulateLoadProfile": func(cfg Config) {
+			profile := LoadProfile{Hourly: make([]float64, 24)}
+			for h := range profile.Hourly {
+				profile.Hourly[h] = float64(h%3) / 2
+			}
+			if _, err := SimulateLoadProfile(workloads, candidates, profile, cfg); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"SimulateNodePools": func(cfg Config) {
+			pools := []SimulatedNodePool{
+				{Name: "spot", Matches: func(w WorkloadProfile) bool { return w.RequireSpot }, Weight: 10},
+				{Name: "default", Strategy: cfg.Strategy, InstanceFilter: func(vm AzureInstanceSpec) bool { return vm.GPUCount == 0 }},
+			}
+			SimulateNodePools(pools, workloads, candidates)
+		},
+		"CompareBases":          func(cfg Config) { CompareBases(workloads, candidates, cfg, BasisUsagePlusMargin(20)) },
+		"SensitivityAnalysis":   func(cfg Config) { SensitivityAnalysis(workloads, candidates, cfg.Strategy, []float64{10}) },
+		"CheckQuotaFeasibility": func(cfg Config) { CheckQuotaFeasibility(workloads, candidates, cfg.Quota) },
+		"SplitOversized":        func(cfg Config) { SplitOversized(workloads, 8, 64) },
+		"PackingBasis.Apply":    func(Config) { BasisUsagePlusMargin(50).Apply(workloads) },
+		"RecommendWarmPoolSize": func(Config) { RecommendWarmPoolSize(workloads, candidates[0], time.Minute) },
+		"DiffInstanceSpecs":     func(Config) { DiffInstanceSpecs(candidates, candidates[1:]) },
+		"GPUTypeWarnings":       func(Config) { GPUTypeWarnings(candidates) },
+		"SelectorService": func(cfg Config) {
+			svc := NewSelectorService(candidates, cfg, func(vm AzureInstanceSpec) bool { return vm.GPUCount == 0 })
+			svc.Select(workloads[0], 0)
+			svc.Pack(workloads)
+			svc.SKUs()
+		},
+		"RunStrategyComparison": func(cfg Config) {
+			cfg.progress = io.Discard
+			if _, err := RunStrategyComparison(context.Background(), workloads, candidates, []Config{cfg, {Strategy: StrategyMemoryIntensive}}, 2, io.Discard); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+	for cfgName, cfg := range configs {
+		for name, call := range calls {
+			call(cfg)
+			if fingerprint(t, candidates) != wantCandidates {
+				t.Fatalf("%s with the %s config modified its candidates", name, cfgName)
+			}
+			if fingerprint(t, workloads) != wantWorkloads {
+				t.Fatalf("%s with the %s config modified its workloads", name, cfgName)
+			}
+		}
+	}
+}
@@ -1,8 +1,8 @@
 package resolver
 
 import (
-	"strings"
 	"fmt"
+	"slices"
 	"sort"
 )
 
@@ -60,25 +60,31 @@ These can be modeled as additional fields and filter functions.
 */
 
 type AzureInstanceSpec struct {
-	Name                   string
-	VCpus                  int
-	MemoryGiB              float64
-	StorageGiB             float64
-	PricePerHour           float64
-	Family                 string
-	Capabilities           map[string]string
-	GPUCount               int
-	GPUType                string
-	AvailabilityZones      []string
-	EphemeralOSDisk        bool
-	NestedVirtualization   bool
-	SpotSupported          bool
-	ConfidentialComputing  bool
-	TrustedLaunch          bool // TTs: Trusted Launch support
-	AcceleratedNetworking  bool
-	MaxPods                int
-	UltraSSDEnabled        bool
-	ProximityPlacement     bool
+	Name                  string
+	VCpus                 int
+	MemoryGiB             float64
+	StorageGiB            float64
+	PricePerHour          float64
+	Family                string
+	Capabilities          map[string]string
+	GPUCount              int
+	GPUType               string
+	GPUMemoryGiBPerGPU    float64 // memory of one GPU; 0 derives it from the family or GPU model (see InferGPUMemoryGiB)
+	AvailabilityZones     []string
+	EphemeralOSDisk       bool
+	NestedVirtualization  bool
+	SpotSupported         bool
+	ConfidentialComputing bool
+	TrustedLaunch         bool // TTs: Trusted Launch support
+	AcceleratedNetworking bool
+	MaxPods               int
+	UltraSSDEnabled       bool
+	ProximityPlacement    bool
+	NetworkBandwidthMbps  float64 // expected NIC bandwidth; 0 derives it from the size (see ExpectedBandwidthMbps)
+	UncachedDiskIOPS      float64 // max uncached data disk IOPS; 0 means unknown
+	DiskMBps              float64 // max uncached data disk throughput in MB/s; 0 means unknown
+	MaxEphemeralOSDiskGB  float64 // largest ephemeral OS disk the cache or temp disk holds; 0 means unknown
+	Architecture          string  // ArchAMD64 or ArchARM64; "" derives it from the name (see InstanceArchitecture)
 	// Add more fields as needed for filtering (e.g., AcceleratedNetworking, MaxPods, etc.)
 }
 
@@ -100,43 +106,108 @@ Capabilities map can be used for Azure-specific requirements, e.g.:
 - MaxPods: "30"
 - UltraSSDEnabled: "true"
 - ProximityPlacement: "true"
+
+Preferences takes the same keys, and node labels, for capabilities the workload favours but can
+do without: each satisfied one adds a small bonus to the score (see PreferenceBonus), but an
+instance type lacking it is never filtered out.
 */
 type WorkloadProfile struct {
-	CPURequirements    int
-	MemoryRequirements float64
-	IORequirements     float64 // optional, can be 0
-	GPURequirements    int     // optional, can be 0
-	GPUType            string  // optional, can be ""
-	Zone               string  // optional, can be ""
-	RequireEphemeralOS bool
-	RequireNestedVirt  bool
-	RequireSpot        bool
-	RequireConfidential bool
-	Capabilities       map[string]string // Azure-specific requirements
+	Name                       string // optional, identifies the workload, e.g. the pod name or trace row
+	Namespace                  string // optional, e.g. the pod namespace
+	Parent                     string // name of the workload this replica was split from (see SplitOversized)
+	CPURequirements            int
+	MemoryRequirements         float64
+	IORequirements             float64 // optional, can be 0
+	StorageRequirements        float64 // optional, GiB of local storage, can be 0
+	NetworkRequirementsMbps    float64 // optional, expected network bandwidth, can be 0
+	IOPSRequirements           float64 // optional, disk IOPS, can be 0
+	ThroughputMBpsRequirements float64 // optional, disk throughput in MB/s, can be 0
+	GPURequirements            int     // optional, can be 0
+	GPUMemoryGiB               float64 // optional, GPU memory needed across all GPUs, e.g. 40 for "40 GiB VRAM"; implies one GPU when GPURequirements is 0
+	GPUType                    string  // optional, a GPU model or comma-separated models in order of preference (see GPUTypes), can be ""
+	Zone                       string  // optional, can be ""
+	PreferredZone              string  // optional, a zone favoured but not required, e.g. for data locality; can be ""
+	MinZones                   int     // optional, minimum zones the SKU must be offered in; 0 means any
+	Priority                   int     // optional, like a PriorityClass value; higher preempts lower (see SimulateArrivals)
+	ArrivalSeconds             float64 // optional, when the workload arrives, in seconds from the start of the trace
+	DurationSeconds            float64 // optional, how long the workload runs, in seconds; 0 if unknown
+	UsageCPU                   float64 // optional, observed vCPUs in use, for rightsizing (see PackingBasis); 0 if unknown
+	UsageMemory                float64 // optional, observed GiB in use, for rightsizing (see PackingBasis); 0 if unknown
+	LimitCPU                   float64 // optional, vCPUs the workload may burst to (see OvercommitPolicy); 0 if unlimited
+	LimitMemory                float64 // optional, GiB the workload may grow to (see OvercommitPolicy); 0 if unlimited
+	RequireEphemeralOS         bool
+	RequireNestedVirt          bool
+	RequireSpot                bool
+	RequireConfidential        bool
+	Capabilities               map[string]string // Azure-specific requirements
+	Architecture               string            // optional, ArchAMD64 or ArchARM64 when the images are built for one only
+	Preferences                map[string]string // optional, capabilities or node labels favoured but never required (see PreferenceSatisfied)
+	Headroom                   bool              // set on synthetic buffer workloads (see HeadroomSpec)
+	Labels                     map[string]string // optional, e.g. "namespace" or "team"; used for cost attribution
 	// Add more fields as needed for filtering (e.g., labels, taints, etc.)
 }
 
+// ID returns "namespace/name", the name alone without a namespace, or "" for an anonymous workload.
+func (w WorkloadProfile) ID() string {
+	if w.Namespace != "" && w.Name != "" {
+		return w.Namespace + "/" + w.Name
+	}
+	return w.Name
+}
+
 // WorkloadSet represents a set of workloads (pods) to be scheduled.
 type WorkloadSet []WorkloadProfile
 
 // PackingResult represents the result of bin-packing: which workloads are assigned to which VMs.
 type PackingResult struct {
-	VMs []PackedVM
+	VMs          []PackedVM
+	Unpacked     []UnpackedWorkload // workloads that could not be placed, with the reason
+	Reservations []ReservationUsage // usage of Config.CapacityReservations, nil when none are configured
+	QuotaUsage   *QuotaUsage        // vCPUs charged against Config.Quota, nil when no quota is configured
+}
+
+// Reasons reported in UnpackedWorkload.Reason besides the limit reasons (see Limits).
+const (
+	ReasonNoCandidates       = "no instance type satisfies the workload's requirements"
+	ReasonSelectedTooSmall   = "selected instance type cannot hold the workload"
+	ReasonQuotaExhausted     = "quota exhausted for every suitable family"
+	ReasonSpotQuotaExhausted = "spot vCPU quota exhausted"
+	ReasonGPUQuotaExhausted  = "GPU quota exhausted for every suitable instance type"
+	ReasonNoInstanceTypes    = "no instance types to select from"
+	ReasonPoorFit            = "best instance type is oversized beyond the minimum fit"
+)
+
+// UnpackedWorkload is a workload the packer could not place.
+type UnpackedWorkload struct {
+	Workload WorkloadProfile
+	Reason   string
 }
 
 type PackedVM struct {
+	// ID identifies the VM within its packing: VMID of its creation ordinal, so identical runs
+	// give the same IDs, or the node name of a ClusterState.
+	ID           string
 	InstanceType AzureInstanceSpec
 	Workloads    []WorkloadProfile
+	Decision     *Decision    // why InstanceType was chosen; set when Config.WithAudit is true
+	Reserved     bool         // fills a slot of a CapacityReservation; InstanceType carries its price
+	Zone         string       // availability zone the VM is placed in; "" for SKUs without zones
+	CapacityType CapacityType // spot or on-demand
+}
+
+// VMID returns the ID of the VM created ordinal-th in a packing, counting from 1: "vm-0001".
+func VMID(ordinal int) string {
+	return fmt.Sprintf("vm-%04d", ordinal)
 }
 
 // SelectionStrategy defines the type of selection algorithm.
 type SelectionStrategy string
 
 const (
-	StrategyGeneralPurpose SelectionStrategy = "general"
-	StrategyCPUIntensive   SelectionStrategy = "cpu"
+	StrategyGeneralPurpose  SelectionStrategy = "general"
+	StrategyCPUIntensive    SelectionStrategy = "cpu"
 	StrategyMemoryIntensive SelectionStrategy = "memory"
-	StrategyIOIntensive    SelectionStrategy = "io"
+	StrategyIOIntensive     SelectionStrategy = "io"
 )
 
 /*
@@ -183,17 +254,23 @@ func FilterByZone(inst AzureInstanceSpec, workload WorkloadProfile) bool {
 	return false
 }
 
+// FilterByGPU rejects instance types with fewer GPUs than the workload requests, with less
+// memory on all their GPUs together than its GPUMemoryGiB, or, when it requests GPU types (see
+// WorkloadProfile.GPUTypes), without one of those GPU models. SKUs that omit GPUType or
+// GPUMemoryGiBPerGPU are matched by their family and model (see InferGPUType and
+// InferGPUMemoryGiB); those of unknown model or memory are rejected.
 func FilterByGPU(inst AzureInstanceSpec, workload WorkloadProfile) bool {
-	if workload.GPURequirements == 0 {
+	if workload.gpusRequired() == 0 {
 		return true
 	}
-	if inst.GPUCount < workload.GPURequirements {
+	if inst.GPUCount < workload.gpusRequired() {
 		return false
 	}
-	if workload.GPUType != "" && !strings.EqualFold(inst.GPUType, workload.GPUType) {
+	if workload.GPUMemoryGiB > 0 && !fitsWithin(workload.GPUMemoryGiB, totalGPUMemoryGiB(inst)) {
 		return false
 	}
-	return true
+	match, _ := matchGPUType(inst, workload)
+	return match == gpuTypeMatches
 }
 
 func FilterByEphemeralOS(inst AzureInstanceSpec, workload WorkloadProfile) bool {
@@ -230,25 +307,109 @@ func FilterByMaxPods(inst AzureInstanceSpec, workload WorkloadProfile) bool {
 	return true
 }
 
+// FilterByDiskPerformance rejects instance types whose declared uncached disk IOPS or throughput
+// is below the workload's requirement. SKUs that do not declare them pass, like MaxPods.
+func FilterByDiskPerformance(inst AzureInstanceSpec, workload WorkloadProfile) bool {
+	if inst.UncachedDiskIOPS > 0 && !fitsWithin(workload.IOPSRequirements, inst.UncachedDiskIOPS) {
+		return false
+	}
+	if inst.DiskMBps > 0 && !fitsWithin(workload.ThroughputMBpsRequirements, inst.DiskMBps) {
+		return false
+	}
+	return true
+}
+
 // Add more filters as needed (e.g., spot, confidential, family, etc.)
 
+// NamedFilter pairs a filter with the name reported in selection audit trails and precheck
+// results, and by which Config.DisableFilters removes it.
+type NamedFilter struct {
+	Name   string
+	Filter FilterFunc
+}
+
+// defaultFilters is the filter chain applied to every selection unless Config.Filters overrides it.
+var defaultFilters = []NamedFilter{
+	{"zone", FilterByZone},
+	{"min-zones", FilterByMinZones},
+	{"gpu", FilterByGPU},
+	{"ephemeral-os", FilterByEphemeralOS},
+	{"trusted-launch", FilterByTrustedLaunch},
+	{"accelerated-networking", FilterByAcceleratedNetworking},
+	{"max-pods", FilterByMaxPods},
+	{"network-bandwidth", FilterByBandwidth},
+	{"disk-performance", FilterByDiskPerformance},
+	{"architecture", FilterByArchitecture},
+	// Add more filters here
+}
+
+/*
+DefaultFilters returns the filter chain applied to every selection: zone, zone count, GPUs, ephemeral OS
+disks, trusted launch, accelerated networking, max pods, network and disk performance, and
+architecture, in that order. The result is a copy, for composing a Config.Filters override,
+e.g. to append a filter or to replace one by name.
+*/
+func DefaultFilters() []NamedFilter {
+	return slices.Clone(defaultFilters)
+}
+
+// defaultFilterFuncs is defaultFilters without the names.
+var defaultFilterFuncs = func() []FilterFunc {
+	fns := make([]FilterFunc, len(defaultFilters))
+	for i, f := range defaultFilters {
+		fns[i] = f.Filter
+	}
+	return fns
+}()
+
 // RankInstanceTypes sorts instance types by score (descending).
+// Exact score ties are broken by price, cheapest first.
 func RankInstanceTypes(candidates []AzureInstanceSpec, workload WorkloadProfile, score ScoreFunc) []AzureInstanceSpec {
-	// Simple selection sort for demonstration; replace with sort.Slice for production.
-	out := make([]AzureInstanceSpec, len(candidates))
-	copy(out, candidates)
-	for i := 0; i < len(out); i++ {
-		best := i
-		for j := i + 1; j < len(out); j++ {
-			if score(out[j], workload) > score(out[best], workload) {
-				best = j
-			}
-		}
-		out[i], out[best] = out[best], out[i]
+	return RankInstanceTypesWithPreferences(candidates, workload, score, nil)
+}
+
+/*
+RankInstanceTypesWithPreferences sorts instance types by score (descending), breaking exact
+ties by family preference order (see FamilyPreferenceRank) and then by price.
+Each candidate is scored once; candidates that tie on all keys keep their input order.
+*/
+func RankInstanceTypesWithPreferences(candidates []AzureInstanceSpec, workload WorkloadProfile, score ScoreFunc, familyPreferences []string) []AzureInstanceSpec {
+	entries := rankEntries(candidates, workload, score, familyPreferences)
+	out := make([]AzureInstanceSpec, len(entries))
+	for i, e := range entries {
+		out[i] = candidates[e.idx]
 	}
 	return out
 }
 
+// rankEntry is the sort key of one candidate. Sorting these small entries rather than
+// the (large) specs themselves keeps ranking cheap.
+type rankEntry struct {
+	idx   int
+	score float64
+	pref  int
+	price float64
+}
+
+// rankEntries scores every candidate once and returns them in RankInstanceTypesWithPreferences order.
+func rankEntries(candidates []AzureInstanceSpec, workload WorkloadProfile, score ScoreFunc, familyPreferences []string) []rankEntry {
+	entries := make([]rankEntry, len(candidates))
+	for i, c := range candidates {
+		entries[i] = rankEntry{idx: i, score: score(c, workload), pref: FamilyPreferenceRank(c, familyPreferences), price: c.PricePerHour}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.pref != b.pref {
+			return a.pref < b.pref
+		}
+		return a.price < b.price
+	})
+	return entries
+}
+
 // GeneralPurposeSelector implements InstanceSelector for general workloads.
 type GeneralPurposeSelector struct{}
 
@@ -282,23 +443,99 @@ selectWithStrategy is a helper to select the best instance with a given strategy
 This now uses filtering and ranking, similar to AWS Karpenter.
 */
 func selectWithStrategy(candidates []AzureInstanceSpec, workload WorkloadProfile, strategy SelectionStrategy) (AzureInstanceSpec, float64) {
-	// Compose filters (add more as needed)
-	filters := []FilterFunc{
-		FilterByZone,
-		FilterByGPU,
-		FilterByEphemeralOS,
-		FilterByTrustedLaunch,
-		FilterByAcceleratedNetworking,
-		FilterByMaxPods,
-		// Add more filters here
-	}
-	filtered := FilterInstanceTypes(candidates, workload, filters...)
+	return selectWithConfig(candidates, workload, Config{Strategy: strategy})
+}
+
+// selectWithConfig is selectWithStrategy driven by a Config (strategy, pruning, exploration).
+// Rankings are memoized per workload shape when the Config carries a score cache.
+func selectWithConfig(candidates []AzureInstanceSpec, workload WorkloadProfile, cfg Config) (AzureInstanceSpec, float64) {
+	top, ok := cfg.cache.get(candidates, workload)
+	if !ok {
+		top = rankTop(candidates, workload, cfg)
+		cfg.cache.put(candidates, workload, top)
+	}
+	if len(top) == 0 {
+		return AzureInstanceSpec{}, -1
+	}
+	if cfg.exploring() {
+		return explore(top, cfg)
+	}
+	return top[0].Instance, top[0].Score
+}
+
+// rankTop filters, optionally prunes, and ranks candidates for workload, returning the
+// candidates selectWithConfig may pick from: the best one, or the top K when exploring.
+func rankTop(candidates []AzureInstanceSpec, workload WorkloadProfile, cfg Config) []RankedCandidate {
+	filtered := FilterInstanceTypes(candidates, workload, cfg.filterFuncs()...)
 
 	// Choose scoring function based on strategy
 	scoreFunc := func(vm AzureInstanceSpec, w WorkloadProfile) float64 {
-		return ScoreInstance(vm, w, strategy)
+		return ScoreInstanceWithConfig(vm, w, cfg)
 	}
-	ranked := RankInstanceTypes(filtered, workload, scoreFunc)
+	if cfg.PruneTopN > 0 {
+		pruned := pruneCheapest(filtered, workload, cfg.PruneTopN)
+		if cfg.PruneStats != nil {
+			cfg.PruneStats.record(len(filtered), len(pruned))
+			if cfg.VerifyPruning {
+				prunedBest, _ := bestOf(pruned, workload, scoreFunc, cfg.FamilyPreferences)
+				fullBest, _ := bestOf(filtered, workload, scoreFunc, cfg.FamilyPreferences)
+				cfg.PruneStats.verify(prunedBest.Name != fullBest.Name)
+			}
+		}
+		filtered = pruned
+	}
+	entries := rankEntries(filtered, workload, scoreFunc, cfg.FamilyPreferences)
+	n := 1
+	if cfg.exploring() {
+		n = cfg.ExplorationTopK
+	}
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	top := make([]RankedCandidate, len(entries))
+	for i, e := range entries {
+		top[i] = RankedCandidate{Instance: filtered[e.idx], Score: e.score}
+	}
+	return top
+}
+
+/*
+SelectTopN returns up to n instance types able to hold workload, ranked best first with their
+scores, e.g. as fallbacks when the best instance type has no capacity, the way Karpenter hands
+several instance type options to the cloud provider. Candidates go through the same filters and
+ranking as SelectBestInstanceWithStrategy, so the first result is its choice whenever that
+choice can hold the workload. Fewer than n are returned when fewer survive filtering; n <= 0
+returns all of them.
+*/
+func SelectTopN(candidates []AzureInstanceSpec, workload WorkloadProfile, strategy SelectionStrategy, n int) []RankedCandidate {
+	return Config{Strategy: strategy}.topN(candidates, workload, n)
+}
+
+// topN is SelectTopN driven by a Config: its filters, fit margin, scoring and family preferences.
+func (c Config) topN(candidates []AzureInstanceSpec, workload WorkloadProfile, n int) []RankedCandidate {
+	var feasible []AzureInstanceSpec
+	for _, vm := range FilterInstanceTypes(candidates, workload, c.filterFuncs()...) {
+		if capacityOf(vm, c.FitMarginPercent).fits(workload) {
+			feasible = append(feasible, vm)
+		}
+	}
+	scoreFunc := func(vm AzureInstanceSpec, w WorkloadProfile) float64 {
+		return ScoreInstanceWithConfig(vm, w, c)
+	}
+	entries := rankEntries(feasible, workload, scoreFunc, c.FamilyPreferences)
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	top := make([]RankedCandidate, len(entries))
+	for i, e := range entries {
+		top[i] = RankedCandidate{Instance: feasible[e.idx], Score: e.score}
+	}
+	return top
+}
+
+// bestOf returns the highest-ranked candidate and its score, or an empty spec and -1.
+func bestOf(candidates []AzureInstanceSpec, workload WorkloadProfile, scoreFunc ScoreFunc, familyPreferences []string) (AzureInstanceSpec, float64) {
+	ranked := RankInstanceTypesWithPreferences(candidates, workload, scoreFunc, familyPreferences)
 	if len(ranked) == 0 {
 		return AzureInstanceSpec{}, -1
 	}
@@ -306,33 +543,98 @@ func selectWithStrategy(candidates []AzureInstanceSpec, workload WorkloadProfile
 	return best, scoreFunc(best, workload)
 }
 
-// ScoreInstance scores a VM for a workload and strategy.
+// ScoreInstance scores a VM for a workload and strategy with the legacy formula, whose cost
+// term grows without bound for cheap SKUs. StrategyAuto scores with the workload's class (see
+// ClassifyWorkload).
 func ScoreInstance(vm AzureInstanceSpec, workload WorkloadProfile, strategy SelectionStrategy) float64 {
-	// Cost efficiency: lower is better
-	costEfficiency := 1.0 / (vm.PricePerHour + 0.01)
-	resourceFit := ComputeFit(vm, workload)
-	availabilityScore := zoneScore(vm, workload.Zone)
-	gpuScore := gpuFit(vm, workload)
-	ephemeralScore := boolScore(vm.EphemeralOSDisk, workload.RequireEphemeralOS)
-	nestedVirtScore := boolScore(vm.NestedVirtualization, workload.RequireNestedVirt)
-	spotScore := boolScore(vm.SpotSupported, workload.RequireSpot)
-	confidentialScore := boolScore(vm.ConfidentialComputing, workload.RequireConfidential)
-
-	// Strategy-specific weighting
+	return weightedScore(vm, workload, strategyWeights(strategy, workload), legacyCostEfficiency(vm))
+}
+
+/*
+ScoreInstanceNormalized scores a VM for a workload and strategy in [0,1], so that scores are
+comparable across runs and candidate sets. It weighs the same components as ScoreInstance, but
+cost efficiency is a logistic transform of the log price, 1/(1 + price/normalizedCostMidpoint),
+which is 1 for free SKUs and 0.5 at the midpoint. Every strategy's weights sum to 1.
+*/
+func ScoreInstanceNormalized(vm AzureInstanceSpec, workload WorkloadProfile, strategy SelectionStrategy) float64 {
+	return weightedScore(vm, workload, strategyWeights(strategy, workload), normalizedCostEfficiency(vm))
+}
+
+// normalizedCostMidpoint is the hourly price whose normalized cost efficiency is 0.5.
+const normalizedCostMidpoint = 0.25
+
+// legacyCostEfficiency is the cost term of ScoreInstance: lower prices are better.
+func legacyCostEfficiency(vm AzureInstanceSpec) float64 {
+	return 1.0 / (vm.PricePerHour + 0.01)
+}
+
+// normalizedCostEfficiency is the cost term of ScoreInstanceNormalized, in [0,1].
+func normalizedCostEfficiency(vm AzureInstanceSpec) float64 {
+	return 1.0 / (1.0 + max(vm.PricePerHour, 0)/normalizedCostMidpoint)
+}
+
+// strategyWeights returns the built-in weights of strategy, or of the workload's class under
+// StrategyAuto. Every strategy's weights sum to 1.
+func strategyWeights(strategy SelectionStrategy, workload WorkloadProfile) ScoringWeights {
+	if strategy == StrategyAuto {
+		strategy = ClassifyWorkload(workload)
+	}
 	switch strategy {
 	case StrategyCPUIntensive:
-		return 0.5*cpuFit(vm, workload) + 0.2*costEfficiency + 0.1*resourceFit + 0.1*availabilityScore + 0.1*gpuScore
+		return ScoringWeights{CPUFit: 0.5, Cost: 0.2, Fit: 0.1, Availability: 0.1, GPU: 0.1}
 	case StrategyMemoryIntensive:
-		return 0.5*memFit(vm, workload) + 0.2*costEfficiency + 0.1*resourceFit + 0.1*availabilityScore + 0.1*gpuScore
+		return ScoringWeights{MemoryFit: 0.5, Cost: 0.2, Fit: 0.1, Availability: 0.1, GPU: 0.1}
 	case StrategyIOIntensive:
-		return 0.5*ioFit(vm, workload) + 0.2*costEfficiency + 0.1*resourceFit + 0.1*availabilityScore + 0.1*gpuScore
+		return ScoringWeights{IOFit: 0.4, NetworkFit: 0.1, Cost: 0.2, Fit: 0.1, Availability: 0.1, GPU: 0.1}
 	default:
 		// General purpose: balance all
-		return 0.3*costEfficiency + 0.2*resourceFit + 0.1*availabilityScore + 0.1*gpuScore +
-			0.1*ephemeralScore + 0.1*nestedVirtScore + 0.05*spotScore + 0.05*confidentialScore
+		return ScoringWeights{Cost: 0.3, Fit: 0.2, Availability: 0.1, GPU: 0.1, EphemeralOS: 0.1, NestedVirt: 0.1, Spot: 0.05, Confidential: 0.05}
 	}
 }
 
+// weightedScore combines costEfficiency with the fit components of vm for workload, weighted
+// by weights. The terms are summed in a fixed order, so weights with zeros in place of a term
+// score exactly like weights without it.
+func weightedScore(vm AzureInstanceSpec, workload WorkloadProfile, weights ScoringWeights, costEfficiency float64) float64 {
+	availabilityScore := 0.5*zoneScore(vm, workload.Zone) + 0.5*zonePreference(vm, workload.PreferredZone)
+	return weights.CPUFit*cpuFit(vm, workload) + weights.MemoryFit*memFit(vm, workload) +
+		weights.IOFit*ioFit(vm, workload) + weights.NetworkFit*networkFit(vm, workload) +
+		weights.Cost*costEfficiency + weights.Fit*ComputeFit(vm, workload) +
+		weights.Availability*availabilityScore + weights.GPU*gpuFit(vm, workload) +
+		weights.EphemeralOS*boolScore(vm.EphemeralOSDisk, workload.RequireEphemeralOS) +
+		weights.NestedVirt*boolScore(vm.NestedVirtualization, workload.RequireNestedVirt) +
+		weights.Spot*boolScore(vm.SpotSupported, workload.RequireSpot) +
+		weights.Confidential*boolScore(vm.ConfidentialComputing, workload.RequireConfidential) +
+		weights.Headroom*headroomScore(vm, workload) + weights.Size*sizeScore(vm)
+}
+
+/*
+ScoreInstanceWithConfig scores with the Config's ScoreVersion for its strategy (or the
+workload's class under StrategyAuto), or with its Weights when set, and adds the small family
+preference bonus (see FamilyPreferenceBonus) and the bonus of the workload's satisfied
+preferences (see PreferenceBonus). Under ScoreVersionNormalized the bonuses replace the matching
+share of the score, so the result stays in [0,1] as long as the preference weights sum to less
+than 0.95 and the scoring weights to 1.
+*/
+func ScoreInstanceWithConfig(vm AzureInstanceSpec, workload WorkloadProfile, cfg Config) float64 {
+	weights := cfg.weightsFor(workload)
+	bonus := FamilyPreferenceBonus(vm, cfg.FamilyPreferences)
+	preferred, preferredMax := PreferenceBonus(vm, workload, cfg.PreferenceWeights)
+	bonus += preferred
+	if cfg.ScoreVersion == ScoreVersionNormalized {
+		score := weightedScore(vm, workload, weights, normalizedCostEfficiency(vm))
+		share := preferredMax
+		if len(cfg.FamilyPreferences) > 0 {
+			share += familyPreferenceBonusMax
+		}
+		if share == 0 {
+			return score
+		}
+		return (1-share)*score + bonus
+	}
+	return weightedScore(vm, workload, weights, legacyCostEfficiency(vm)) + bonus
+}
+
 // ComputeFit returns a value in [0,1] for how well the VM fits the workload.
 func ComputeFit(vm AzureInstanceSpec, workload WorkloadProfile) float64 {
 	cpu := cpuFit(vm, workload)
@@ -366,24 +668,44 @@ func memFit(vm AzureInstanceSpec, workload WorkloadProfile) float64 {
 	return min(vm.MemoryGiB/workload.MemoryRequirements, 1.0)
 }
 
+/*
+ioFit returns a value in [0,1] for how well the VM's disks cover the workload: the worst of
+the storage capacity fit (StorageGiB against IORequirements) and the performance fits (uncached
+IOPS and throughput). A SKU that does not declare its disk performance does not fit a workload
+that requires it.
+*/
 func ioFit(vm AzureInstanceSpec, workload WorkloadProfile) float64 {
-	if workload.IORequirements == 0 {
+	fit := ratioFit(vm.StorageGiB, workload.IORequirements)
+	fit = min(fit, ratioFit(vm.UncachedDiskIOPS, workload.IOPSRequirements))
+	return min(fit, ratioFit(vm.DiskMBps, workload.ThroughputMBpsRequirements))
+}
+
+// ratioFit returns have/need capped at 1, or 1 when nothing is needed.
+func ratioFit(have, need float64) float64 {
+	if need <= 0 {
 		return 1.0
 	}
-	return min(vm.StorageGiB/workload.IORequirements, 1.0)
+	return min(have/need, 1.0)
 }
 
+// gpuFit scores the GPUs of vm for workload: enough of them, of its most preferred model, with
+// the least GPU memory beyond its GPUMemoryGiB (see gpuMemoryFit).
 func gpuFit(vm AzureInstanceSpec, workload WorkloadProfile) float64 {
-	if workload.GPURequirements == 0 {
+	if workload.gpusRequired() == 0 {
 		return 1.0
 	}
-	if vm.GPUCount < workload.GPURequirements {
+	if vm.GPUCount < workload.gpusRequired() {
 		return 0.0
 	}
-	if workload.GPUType != "" && !strings.EqualFold(vm.GPUType, workload.GPUType) {
+	memFit := gpuMemoryFit(vm, workload)
+	match, rank := matchGPUType(vm, workload)
+	switch match {
+	case gpuTypeMismatch:
 		return 0.0
+	case gpuTypeUnknown:
+		return unknownGPUTypeFit * memFit
 	}
-	return 1.0
+	return max(1.0-gpuTypePreferenceStep*float64(rank), gpuTypePreferenceFloor) * memFit
 }
 
 func zoneScore(vm AzureInstanceSpec, zone string) float64 {
@@ -398,6 +720,16 @@ func zoneScore(vm AzureInstanceSpec, zone string) float64 {
 	return 0.0
 }
 
+/*
+zonePreference returns 1 when vm is offered in the workload's preferred zone (or it prefers
+none) and 0 otherwise. Unlike zoneScore it is not backed by a filter: it only halves the
+availability term, so a SKU outside the preferred zone still wins when it fits or costs
+enough better.
+*/
+func zonePreference(vm AzureInstanceSpec, preferred string) float64 {
+	return zoneScore(vm, preferred)
+}
+
 func boolScore(vmHas, required bool) float64 {
 	if !required {
 		return 1.0
@@ -420,21 +752,52 @@ func min(a, b float64) float64 {
 // BinPackWorkloads assigns workloads to VMs using a first-fit decreasing bin-packing algorithm.
 // Returns a PackingResult with the list of VMs and their assigned workloads.
 func BinPackWorkloads(workloads WorkloadSet, candidates []AzureInstanceSpec, strategy SelectionStrategy) PackingResult {
-	// Sort workloads by descending CPU+Memory demand (efficient)
+	return BinPackWorkloadsWithConfig(workloads, candidates, Config{Strategy: strategy})
+}
+
+/*
+BinPackWorkloadsWithConfig is BinPackWorkloads driven by a Config.
+Headroom buffer workloads are appended before packing, and packing is delegated to
+BinPackWorkloadsWithQuota when a quota is configured. Without candidates every workload is
+unpacked with ReasonNoInstanceTypes.
+*/
+func BinPackWorkloadsWithConfig(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) PackingResult {
+	cfg = cfg.forRun()
+	workloads = withHeadroom(workloads, cfg.Headroom)
+	if len(candidates) == 0 {
+		var result PackingResult
+		for _, w := range workloads {
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: w, Reason: ReasonNoInstanceTypes})
+		}
+		return result
+	}
+	if cfg.Quota != nil {
+		return binPackWorkloadsWithQuota(workloads, candidates, cfg)
+	}
+	// Sort workloads by descending CPU+Memory demand (efficient), or by cfg.SortKey
 	sorted := make(WorkloadSet, len(workloads))
 	copy(sorted, workloads)
-	// Use sort.Slice for efficiency
-	// Sorting by (CPURequirements + MemoryRequirements) descending
-	// (MemoryRequirements is float64, so we cast to float64 for sum)
-	// If you want to weight CPU/Memory differently, adjust here.
-	// This is much faster than bubble sort for large slices.
-	sort.Slice(sorted, func(i, j int) bool {
-		return float64(sorted[i].CPURequirements)+sorted[i].MemoryRequirements >
-			float64(sorted[j].CPURequirements)+sorted[j].MemoryRequirements
-	})
+	if cfg.SortKey != SortKeyLegacy {
+		cfg.SortKey.sort(sorted, candidates)
+	} else {
+		// Use sort.Slice for efficiency
+		// Sorting by (CPURequirements + MemoryRequirements) descending
+		// (MemoryRequirements is float64, so we cast to float64 for sum)
+		// This is much faster than bubble sort for large slices.
+		// Headroom buffers are low priority and always go after real workloads.
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].Headroom != sorted[j].Headroom {
+				return !sorted[i].Headroom
+			}
+			return float64(sorted[i].CPURequirements)+sorted[i].MemoryRequirements >
+				float64(sorted[j].CPURequirements)+sorted[j].MemoryRequirements
+		})
+	}
 
 	var result PackingResult
 	unpacked := make([]bool, len(sorted))
+	limits := limitTracker{limits: cfg.Limits}
+	reservations := newReservationTracker(cfg.CapacityReservations, candidates)
 
 	for {
 		// Find the next workload not yet packed
@@ -450,37 +813,60 @@ func BinPackWorkloads(workloads WorkloadSet, candidates []AzureInstanceSpec, str
 		}
 		// For this workload, select the best instance type
 		workload := sorted[nextIdx]
-		bestVM, _ := selectWithStrategy(candidates, workload, strategy)
+		bestVM, score, reservation := reservations.selectFor(candidates, workload, cfg)
 		if bestVM.Name == "" {
-			break // no suitable VM found
+			// No suitable VM for this workload; the others may still fit elsewhere
+			unpacked[nextIdx] = true
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: workload, Reason: ReasonNoCandidates})
+			continue
+		}
+		if cfg.poorFit(bestVM, workload, reservation) {
+			// Rather no VM than an absurdly oversized one
+			unpacked[nextIdx] = true
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: workload, Reason: ReasonPoorFit})
+			continue
+		}
+		// Stop provisioning once the next VM would exceed the configured limits
+		if reason := limits.exceeded(bestVM); reason != "" {
+			result.Unpacked = append(result.Unpacked, markUnpacked(sorted, unpacked, reason)...)
+			break
 		}
 		// Try to pack as many workloads as possible onto this VM
 		var packed []WorkloadProfile
-		remainingCPU := bestVM.VCpus
-		remainingMem := bestVM.MemoryGiB
+		remaining := cfg.vmCapacity(bestVM)
 		packedAny := false
 		for i, w := range sorted {
 			if unpacked[i] {
 				continue
 			}
-			if w.CPURequirements <= remainingCPU && w.MemoryRequirements <= remainingMem {
+			if remaining.fits(w) {
 				packed = append(packed, w)
-				remainingCPU -= w.CPURequirements
-				remainingMem -= w.MemoryRequirements
+				remaining.take(w)
 				unpacked[i] = true
 				packedAny = true
 			}
 		}
 		if !packedAny {
-			// Safety: If we couldn't pack any workload, break to avoid infinite loop
-			fmt.Printf("Warning: Could not pack any workloads onto VM type %s for workload %+v\n", bestVM.Name, workload)
-			break
+			// The selected VM cannot hold the workload it was selected for; skip the workload
+			// rather than provisioning an empty VM
+			unpacked[nextIdx] = true
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: workload, Reason: ReasonSelectedTooSmall})
+			continue
 		}
+		limits.add(bestVM)
+		reservations.use(reservation)
+		id := VMID(len(result.VMs) + 1)
 		result.VMs = append(result.VMs, PackedVM{
+			ID:           id,
 			InstanceType: bestVM,
 			Workloads:    packed,
+			Decision:     cfg.audit(candidates, workload, bestVM, score, id),
+			Reserved:     reservation >= 0,
+			CapacityType: capacityTypeFor(bestVM, workload, reservation >= 0),
 		})
 	}
+	assignZones(result.VMs)
+	result.Reservations = reservations.report()
 	return result
 }
 
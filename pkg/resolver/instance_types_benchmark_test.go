@@ -1,7 +1,6 @@
 package resolver
 
 import (
-	"encoding/json"
 	"fmt"
 	"math/rand"
 	"os"
@@ -38,33 +37,14 @@ This will run only the benchmark that uses the Azure trace data, not the synthet
 To visualize results, see the scripts/visualize_benchmark_results.py script.
 */
 
-// WorkloadProfileJSON is a struct for loading preprocessed workloads from JSON.
-type WorkloadProfileJSON struct {
-	CPURequest      int               `json:"cpu_request"`
-	MemoryRequestGi float64           `json:"memory_request_gib"`
-	Labels          map[string]string `json:"labels"`
-	Annotations     map[string]string `json:"annotations"`
-}
-
 // loadAzureWorkloads loads preprocessed workload profiles from a JSON file.
 func loadAzureWorkloads(path string) []WorkloadProfile {
-	f, err := os.Open(path)
+	workloads, err := LoadPreprocessedWorkloads(path)
 	if err != nil {
-		panic(fmt.Sprintf("failed to open workload file: %v", err))
+		panic(fmt.Sprintf("failed to load workload file: %v", err))
 	}
-	defer f.Close()
-	dec := json.NewDecoder(f)
-	var raw []WorkloadProfileJSON
-	if err := dec.Decode(&raw); err != nil {
-		panic(fmt.Sprintf("failed to decode workload file: %v", err))
-	}
-	workloads := make([]WorkloadProfile, 0, len(raw))
-	for _, w := range raw {
-		workloads = append(workloads, WorkloadProfile{
-			CPURequirements:    w.CPURequest,
-			MemoryRequirements: w.MemoryRequestGi,
-			Capabilities:       map[string]string{"AcceleratedNetworking": "true"},
-		})
+	for i := range workloads {
+		workloads[i].Capabilities = map[string]string{"AcceleratedNetworking": "true"}
 	}
 	return workloads
 }
@@ -179,3 +159,55 @@ func BenchmarkInstanceSelection(b *testing.B) {
 		_ = SelectBestInstance(candidates, w)
 	}
 }
+
+// BenchmarkSelectWithPruning compares selection over 1000 synthetic SKUs with and without Config.PruneTopN.
+func BenchmarkSelectWithPruning(b *testing.B) {
+	r := rand.New(rand.NewSource(42))
+	candidates := make([]AzureInstanceSpec, 1000)
+	for i := range candidates {
+		candidates[i] = AzureInstanceSpec{
+			Name:              fmt.Sprintf("Standard_D%d_v4", i),
+			VCpus:             r.Intn(64) + 2,
+			MemoryGiB:         float64(r.Intn(256) + 4),
+			PricePerHour:      r.Float64()*10 + 0.05,
+			AvailabilityZones: []string{"1", "2", "3"},
+		}
+	}
+	workloads := make([]WorkloadProfile, 100)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{CPURequirements: r.Intn(16) + 1, MemoryRequirements: float64(r.Intn(64) + 1)}
+	}
+	for _, bc := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"Full", Config{}},
+		{"PruneTop20", Config{PruneTopN: 20}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = selectWithConfig(candidates, workloads[i%len(workloads)], bc.cfg)
+			}
+		})
+	}
+}
+
+// BenchmarkBinPackScoreCache packs a trace-like workload set with few distinct shapes with and without the score cache.
+func BenchmarkBinPackScoreCache(b *testing.B) {
+	workloads := repeatedShapeWorkloads(2000, 1)
+	candidates := dummyInstanceTypes()
+	for _, bc := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"Uncached", Config{DisableScoreCache: true}},
+		{"Cached", Config{}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				BinPackWorkloadsWithConfig(workloads, candidates, bc.cfg)
+			}
+		})
+	}
+}
@@ -7,35 +7,35 @@ import (
 func TestGeneralPurposeSelector_Simple(t *testing.T) {
 	candidates := []AzureInstanceSpec{
 		{
-			Name:         "Standard_D2_v4",
-			VCpus:        2,
-			MemoryGiB:    8,
-			StorageGiB:   50,
-			PricePerHour: 0.10,
-			Family:       "Standard_D",
-			Capabilities: map[string]string{},
+			Name:              "Standard_D2_v4",
+			VCpus:             2,
+			MemoryGiB:         8,
+			StorageGiB:        50,
+			PricePerHour:      0.10,
+			Family:            "Standard_D",
+			Capabilities:      map[string]string{},
 			AvailabilityZones: []string{"1", "2"},
 		},
 		{
-			Name:         "Standard_E4_v4",
-			VCpus:        4,
-			MemoryGiB:    32,
-			StorageGiB:   100,
-			PricePerHour: 0.20,
-			Family:       "Standard_E",
-			Capabilities: map[string]string{},
+			Name:              "Standard_E4_v4",
+			VCpus:             4,
+			MemoryGiB:         32,
+			StorageGiB:        100,
+			PricePerHour:      0.20,
+			Family:            "Standard_E",
+			Capabilities:      map[string]string{},
 			AvailabilityZones: []string{"1", "2", "3"},
 		},
 		{
-			Name:         "Standard_NC6",
-			VCpus:        6,
-			MemoryGiB:    56,
-			StorageGiB:   380,
-			PricePerHour: 0.90,
-			Family:       "Standard_NC",
-			Capabilities: map[string]string{},
-			GPUCount:     1,
-			GPUType:      "NVIDIA",
+			Name:              "Standard_NC6",
+			VCpus:             6,
+			MemoryGiB:         56,
+			StorageGiB:        380,
+			PricePerHour:      0.90,
+			Family:            "Standard_NC",
+			Capabilities:      map[string]string{},
+			GPUCount:          1,
+			GPUType:           "NVIDIA",
 			AvailabilityZones: []string{"2"},
 		},
 	}
@@ -54,25 +54,25 @@ func TestGeneralPurposeSelector_Simple(t *testing.T) {
 func TestGeneralPurposeSelector_GPU(t *testing.T) {
 	candidates := []AzureInstanceSpec{
 		{
-			Name:         "Standard_D2_v4",
-			VCpus:        2,
-			MemoryGiB:    8,
-			StorageGiB:   50,
-			PricePerHour: 0.10,
-			Family:       "Standard_D",
-			Capabilities: map[string]string{},
+			Name:              "Standard_D2_v4",
+			VCpus:             2,
+			MemoryGiB:         8,
+			StorageGiB:        50,
+			PricePerHour:      0.10,
+			Family:            "Standard_D",
+			Capabilities:      map[string]string{},
 			AvailabilityZones: []string{"1", "2"},
 		},
 		{
-			Name:         "Standard_NC6",
-			VCpus:        6,
-			MemoryGiB:    56,
-			StorageGiB:   380,
-			PricePerHour: 0.90,
-			Family:       "Standard_NC",
-			Capabilities: map[string]string{},
-			GPUCount:     1,
-			GPUType:      "NVIDIA",
+			Name:              "Standard_NC6",
+			VCpus:             6,
+			MemoryGiB:         56,
+			StorageGiB:        380,
+			PricePerHour:      0.90,
+			Family:            "Standard_NC",
+			Capabilities:      map[string]string{},
+			GPUCount:          1,
+			GPUType:           "NVIDIA",
 			AvailabilityZones: []string{"2"},
 		},
 	}
@@ -93,23 +93,23 @@ func TestGeneralPurposeSelector_GPU(t *testing.T) {
 func TestGeneralPurposeSelector_Zone(t *testing.T) {
 	candidates := []AzureInstanceSpec{
 		{
-			Name:         "Standard_D2_v4",
-			VCpus:        2,
-			MemoryGiB:    8,
-			StorageGiB:   50,
-			PricePerHour: 0.10,
-			Family:       "Standard_D",
-			Capabilities: map[string]string{},
+			Name:              "Standard_D2_v4",
+			VCpus:             2,
+			MemoryGiB:         8,
+			StorageGiB:        50,
+			PricePerHour:      0.10,
+			Family:            "Standard_D",
+			Capabilities:      map[string]string{},
 			AvailabilityZones: []string{"1", "2"},
 		},
 		{
-			Name:         "Standard_E4_v4",
-			VCpus:        4,
-			MemoryGiB:    32,
-			StorageGiB:   100,
-			PricePerHour: 0.20,
-			Family:       "Standard_E",
-			Capabilities: map[string]string{},
+			Name:              "Standard_E4_v4",
+			VCpus:             4,
+			MemoryGiB:         32,
+			StorageGiB:        100,
+			PricePerHour:      0.20,
+			Family:            "Standard_E",
+			Capabilities:      map[string]string{},
 			AvailabilityZones: []string{"3"},
 		},
 	}
@@ -1,6 +1,7 @@
 package resolver_test
 
 import (
+	"reflect"
 	"testing"
 
 	. "github.com/Azure/karpenter-provider-azure/pkg/resolver"
@@ -17,9 +18,9 @@ func TestComputeFit(t *testing.T) {
 
 func TestScoreInstance(t *testing.T) {
 	vm := AzureInstanceSpec{
-		Name:        "Standard_D4_v4",
-		VCpus:       8,
-		MemoryGiB:   32,
+		Name:         "Standard_D4_v4",
+		VCpus:        8,
+		MemoryGiB:    32,
 		PricePerHour: 0.2,
 	}
 	workload := WorkloadProfile{CPURequirements: 4, MemoryRequirements: 16}
@@ -88,3 +89,71 @@ func TestBinPackWorkloads(t *testing.T) {
 		t.Errorf("Expected all workloads to be packed, got %d/%d", totalPacked, len(workloads))
 	}
 }
+
+func TestPackingKeepsWorkloadNames(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2, MaxPods: 30}}
+	workloads := []WorkloadProfile{
+		{Name: "web", Namespace: "shop", CPURequirements: 2, MemoryRequirements: 4},
+		{Name: "cache", Namespace: "shop", CPURequirements: 1, MemoryRequirements: 8},
+		{Name: "huge", CPURequirements: 64, MemoryRequirements: 256},
+	}
+	for _, name := range []string{"ffd", "quota"} {
+		pack, _ := PackingAlgorithm(name)
+		result := pack(workloads, candidates, Config{})
+		seen := make(map[string]bool)
+		for _, vm := range result.VMs {
+			for _, w := range vm.Workloads {
+				seen[w.ID()] = true
+			}
+		}
+		for _, u := range result.Unpacked {
+			seen[u.Workload.ID()] = true
+		}
+		for _, id := range []string{"shop/web", "shop/cache", "huge"} {
+			if !seen[id] {
+				t.Errorf("%s: workload %s lost its name in packing, got %v", name, id, seen)
+			}
+		}
+		if len(result.Unpacked) != 1 || result.Unpacked[0].Workload.Name != "huge" {
+			t.Errorf("%s: expected huge to be unpacked, got %+v", name, result.Unpacked)
+		}
+	}
+}
+
+func TestVMIDs_StableAcrossRuns(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D2s_v5", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.096},
+		{Name: "Standard_D8s_v5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.384},
+	}
+	workloads := WorkloadSet{
+		{Name: "a", CPURequirements: 6, MemoryRequirements: 8},
+		{Name: "b", CPURequirements: 2, MemoryRequirements: 4},
+		{Name: "c", CPURequirements: 7, MemoryRequirements: 16},
+		{Name: "d", CPURequirements: 1, MemoryRequirements: 2},
+	}
+	ids := func(result PackingResult) []string {
+		var ids []string
+		for _, vm := range result.VMs {
+			ids = append(ids, vm.ID)
+		}
+		return ids
+	}
+	cfg := Config{WithAudit: true}
+	first := BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+	second := BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+	if got := ids(first); !reflect.DeepEqual(got, ids(second)) || len(got) == 0 || got[0] != "vm-0001" {
+		t.Fatalf("expected the same IDs from vm-0001 in both runs, got %v and %v", got, ids(second))
+	}
+	for _, vm := range first.VMs {
+		if vm.Decision == nil || vm.Decision.VM != vm.ID {
+			t.Errorf("expected the audit of %s to name it, got %+v", vm.ID, vm.Decision)
+		}
+	}
+	if a, b := SimulateArrivals(workloads, candidates, cfg), SimulateArrivals(workloads, candidates, cfg); !reflect.DeepEqual(ids(a.Packing), ids(b.Packing)) {
+		t.Errorf("expected the same arrival IDs in both runs, got %v and %v", ids(a.Packing), ids(b.Packing))
+	}
+	// IDs survive a report round trip.
+	if got := ids(PackingFromDetail(NewSimulationResult(first), candidates)); !reflect.DeepEqual(got, ids(first)) {
+		t.Errorf("expected IDs %v after a round trip, got %v", ids(first), got)
+	}
+}
@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+var (
+	invariantSeed   = flag.Int64("invariant-seed", 0, "run the packing invariant tests for this seed only")
+	invariantRounds = flag.Int("invariant-rounds", 50, "number of random cases per packing algorithm")
+)
+
+const invariantIDLabel = "invariant-id"
+
+// invariantCase builds a random but reproducible packing problem from seed.
+func invariantCase(seed int64) (WorkloadSet, []AzureInstanceSpec, Config) {
+	r := rand.New(rand.NewSource(seed))
+	families := []string{"D", "E", "F"}
+	skus := make([]AzureInstanceSpec, 2+r.Intn(10))
+	for i := range skus {
+		vcpus := 1 << uint(1+r.Intn(5))
+		skus[i] = AzureInstanceSpec{
+			Name:              fmt.Sprintf("sku-%d", i),
+			Family:            families[r.Intn(len(families))],
+			VCpus:             vcpus,
+			MemoryGiB:         float64(vcpus * (2 + 2*r.Intn(4))),
+			PricePerHour:      float64(vcpus) * (0.02 + r.Float64()*0.05),
+			AvailabilityZones: []string{"1", "2", "3"}[:1+r.Intn(3)],
+			UncachedDiskIOPS:  float64(vcpus * 800),
+		}
+		if r.Intn(5) == 0 {
+			skus[i].GPUCount = 1
+		}
+	}
+	workloads := make(WorkloadSet, r.Intn(60))
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{
+			CPURequirements:    r.Intn(9),
+			MemoryRequirements: float64(r.Intn(33)),
+			Labels:             map[string]string{invariantIDLabel: strconv.Itoa(i)},
+		}
+		switch r.Intn(10) {
+		case 0:
+			workloads[i].Zone = strconv.Itoa(1 + r.Intn(3))
+		case 1:
+			workloads[i].GPURequirements = 1
+		case 2:
+			workloads[i].NetworkRequirementsMbps = float64(1000 * (1 + r.Intn(8)))
+		case 3:
+			workloads[i].IOPSRequirements = float64(500 * (1 + r.Intn(8)))
+		}
+	}
+	cfg := Config{Seed: seed}
+	if r.Intn(2) == 0 {
+		cfg.ExplorationTopK, cfg.ExplorationTemperature = 3, 0.5
+	}
+	if r.Intn(3) == 0 {
+		cfg.Quota = QuotaMap{families[r.Intn(len(families))]: 8 + r.Intn(32)}
+	}
+	if r.Intn(3) == 0 {
+		cfg.Limits = Limits{CPU: 16 + r.Intn(64)}
+	}
+	return workloads, skus, cfg
+}
+
+// checkInvariants returns the first invariant the packing of workloads violates, or "".
+func checkInvariants(pack PackFunc, workloads WorkloadSet, skus []AzureInstanceSpec, cfg Config) string {
+	result := pack(workloads, skus, cfg)
+
+	if v := invariantViolation(workloads, result, func(w WorkloadProfile) string { return w.Labels[invariantIDLabel] }); v != "" {
+		return v
+	}
+	ids := make(map[string]bool, len(result.VMs))
+	for i, vm := range result.VMs {
+		if vm.ID == "" || ids[vm.ID] {
+			return fmt.Sprintf("VM %d has a missing or duplicate ID %q", i, vm.ID)
+		}
+		ids[vm.ID] = true
+	}
+	if again := pack(workloads, skus, cfg); !reflect.DeepEqual(result, again) {
+		return "result differs between two runs with the same Config"
+	}
+	return ""
+}
+
+// shrink greedily removes workloads while the invariant violation persists.
+func shrink(pack PackFunc, workloads WorkloadSet, skus []AzureInstanceSpec, cfg Config) (WorkloadSet, string) {
+	failure := checkInvariants(pack, workloads, skus, cfg)
+	for i := 0; i < len(workloads); {
+		candidate := append(append(WorkloadSet{}, workloads[:i]...), workloads[i+1:]...)
+		if f := checkInvariants(pack, candidate, skus, cfg); f != "" {
+			workloads, failure = candidate, f
+			continue
+		}
+		i++
+	}
+	return workloads, failure
+}
+
+// TestPackingInvariants runs every registered packing algorithm over random problems.
+// A failure logs the seed and a shrunk workload set; rerun a single case with
+// go test ./pkg/resolver -run TestPackingInvariants -invariant-seed=<seed>.
+func TestPackingInvariants(t *testing.T) {
+	seeds := make([]int64, *invariantRounds)
+	for i := range seeds {
+		seeds[i] = int64(i + 1)
+	}
+	if *invariantSeed != 0 {
+		seeds = []int64{*invariantSeed}
+	}
+	for _, name := range PackingAlgorithms() {
+		pack, _ := PackingAlgorithm(name)
+		t.Run(name, func(t *testing.T) {
+			for _, seed := range seeds {
+				workloads, skus, cfg := invariantCase(seed)
+				if failure := checkInvariants(pack, workloads, skus, cfg); failure != "" {
+					shrunk, failure := shrink(pack, workloads, skus, cfg)
+					t.Fatalf("seed %d: %s\nshrunk to %d workloads: %+v\nconfig: %+v\nskus: %+v", seed, failure, len(shrunk), shrunk, cfg, skus)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterPackingAlgorithm_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic when registering ffd twice")
+		}
+	}()
+	RegisterPackingAlgorithm("ffd", BinPackWorkloadsWithConfig)
+}
@@ -0,0 +1,293 @@
+/*
+Package kube converts Kubernetes and Karpenter objects for the resolver: it imports a running
+cluster from kubectl output, and turns AKSNodeClass and NodePool specs into the resolver's
+constraints and instance filters. It is separate from package resolver so that the resolver
+does not depend on the Kubernetes modules.
+*/
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/apis/v1alpha2"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// Node labels LoadClusterState reads besides karpv1.CapacityTypeLabelKey.
+const (
+	instanceTypeLabel       = corev1.LabelInstanceTypeStable
+	legacyInstanceTypeLabel = corev1.LabelInstanceType
+	zoneLabel               = corev1.LabelTopologyZone
+	archLabel               = corev1.LabelArchStable
+	// aksSpotLabel marks spot nodes of AKS-managed node pools.
+	aksSpotLabel = "kubernetes.azure.com/scalesetpriority"
+)
+
+// gpuResource is the extended resource GPU pods request on AKS.
+const gpuResource corev1.ResourceName = "nvidia.com/gpu"
+
+/*
+LoadClusterState imports a running cluster from the JSON output of `kubectl get nodes -o json`
+and `kubectl get pods -A -o json`, given as two files or combined in one, e.g. by
+`kubectl get nodes,pods -A -o json`. Each node becomes a VM of the SKU named by its
+node.kubernetes.io/instance-type label, looked up in skus, in the zone of its
+topology.kubernetes.io/zone label ("eastus2-1" is zone "1"; AKS labels nodes outside zones
+"0", which becomes no zone) and spot when karpenter.sh/capacity-type or the AKS scale set
+priority says so. Nodes of other instance types are listed in resolver.ClusterState.UnknownNodes.
+
+Each pod still running or pending becomes a workload requesting what its containers request,
+or its largest init container when that is more, with vCPUs rounded up. Its labels, priority
+and any zone in its node selector are kept, as are the GPU models it selects by the
+karpenter.azure.com/sku-gpu-name label (see podGPUTypes) and, as Preferences, the labels of its
+preferred node affinity (see podPreferences).
+*/
+func LoadClusterState(skus []resolver.AzureInstanceSpec, paths ...string) (resolver.ClusterState, error) {
+	var nodes []corev1.Node
+	var pods []corev1.Pod
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return resolver.ClusterState{}, fmt.Errorf("read cluster state: %w", err)
+		}
+		n, p, err := parseKubectlList(data)
+		if err != nil {
+			return resolver.ClusterState{}, fmt.Errorf("parse cluster state %s: %w", path, err)
+		}
+		nodes, pods = append(nodes, n...), append(pods, p...)
+	}
+	return clusterState(skus, nodes, pods), nil
+}
+
+// parseKubectlList decodes the nodes and pods of a kubectl JSON list, or a single object.
+func parseKubectlList(data []byte) ([]corev1.Node, []corev1.Pod, error) {
+	var list struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, nil, err
+	}
+	items := list.Items
+	if list.Kind == "Node" || list.Kind == "Pod" {
+		items = []json.RawMessage{data}
+	}
+	var nodes []corev1.Node
+	var pods []corev1.Pod
+	for i, item := range items {
+		var meta struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(item, &meta); err != nil {
+			return nil, nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		kind := meta.Kind
+		if kind == "" {
+			// Lists from the API server leave the kind to the list, e.g. "NodeList".
+			kind = strings.TrimSuffix(list.Kind, "List")
+		}
+		switch kind {
+		case "Node":
+			var n corev1.Node
+			if err := json.Unmarshal(item, &n); err != nil {
+				return nil, nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			nodes = append(nodes, n)
+		case "Pod":
+			var p corev1.Pod
+			if err := json.Unmarshal(item, &p); err != nil {
+				return nil, nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			pods = append(pods, p)
+		}
+	}
+	return nodes, pods, nil
+}
+
+func clusterState(skus []resolver.AzureInstanceSpec, nodes []corev1.Node, pods []corev1.Pod) resolver.ClusterState {
+	byName := make(map[string]resolver.AzureInstanceSpec, len(skus))
+	for _, sku := range skus {
+		byName[strings.ToLower(sku.Name)] = sku
+	}
+	var s resolver.ClusterState
+	known := make(map[string]int)   // node name to index in s.Nodes
+	unknown := make(map[string]int) // node name to index in s.UnknownNodes
+	for _, n := range nodes {
+		instanceType := n.Labels[instanceTypeLabel]
+		if instanceType == "" {
+			instanceType = n.Labels[legacyInstanceTypeLabel]
+		}
+		sku, ok := byName[strings.ToLower(instanceType)]
+		if !ok {
+			unknown[n.Name] = len(s.UnknownNodes)
+			s.UnknownNodes = append(s.UnknownNodes, resolver.UnknownNode{Name: n.Name, InstanceType: instanceType})
+			continue
+		}
+		capacityType := resolver.CapacityTypeOnDemand
+		if n.Labels[karpv1.CapacityTypeLabelKey] == karpv1.CapacityTypeSpot || strings.EqualFold(n.Labels[aksSpotLabel], "spot") {
+			capacityType = resolver.CapacityTypeSpot
+		}
+		known[n.Name] = len(s.Nodes)
+		s.Nodes = append(s.Nodes, resolver.ClusterNode{Name: n.Name, PackedVM: resolver.PackedVM{ID: n.Name, InstanceType: sku, Zone: resolver.NodeZone(n.Labels[zoneLabel]), CapacityType: capacityType}})
+	}
+	for _, p := range pods {
+		if p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		w := podWorkload(p)
+		node := p.Spec.NodeName
+		if i, ok := known[node]; ok {
+			s.Nodes[i].Workloads = append(s.Nodes[i].Workloads, w)
+			continue
+		}
+		if node == "" {
+			s.Pending = append(s.Pending, w)
+			continue
+		}
+		i, ok := unknown[node]
+		if !ok {
+			i = len(s.UnknownNodes)
+			unknown[node] = i
+			s.UnknownNodes = append(s.UnknownNodes, resolver.UnknownNode{Name: node, Missing: true})
+		}
+		s.UnknownNodes[i].Pods++
+	}
+	return s
+}
+
+/*
+podGPUTypes returns the GPU models pod accepts, in order of preference: the value of its node
+selector on the SKU GPU name label, or the values of its required node affinity "In"
+requirements on it, in the order written, across all terms.
+*/
+func podGPUTypes(pod corev1.Pod) []string {
+	if t := pod.Spec.NodeSelector[v1alpha2.LabelSKUGPUName]; t != "" {
+		return []string{t}
+	}
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil || pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+	var types []string
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, r := range term.MatchExpressions {
+			if r.Key != v1alpha2.LabelSKUGPUName || r.Operator != corev1.NodeSelectorOpIn {
+				continue
+			}
+			for _, v := range r.Values {
+				if !slices.Contains(types, v) {
+					types = append(types, v)
+				}
+			}
+		}
+	}
+	return types
+}
+
+// podPreferences returns the preferences of pod's preferred node affinity: the values of each
+// "In" requirement, joined by commas, keyed by label. The first term naming a label wins.
+func podPreferences(pod corev1.Pod) map[string]string {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil {
+		return nil
+	}
+	var prefs map[string]string
+	for _, term := range pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		for _, r := range term.Preference.MatchExpressions {
+			if r.Operator != corev1.NodeSelectorOpIn || len(r.Values) == 0 {
+				continue
+			}
+			if _, ok := prefs[r.Key]; ok {
+				continue
+			}
+			if prefs == nil {
+				prefs = make(map[string]string)
+			}
+			prefs[r.Key] = strings.Join(r.Values, ",")
+		}
+	}
+	return prefs
+}
+
+// podWorkload returns the workload of pod: its effective requests and limits, labels, priority
+// and zone.
+func podWorkload(pod corev1.Pod) resolver.WorkloadProfile {
+	requests := effectiveResources(pod, func(r corev1.ResourceRequirements) corev1.ResourceList { return r.Requests })
+	limits := effectiveResources(pod, limitsOrRequests)
+	w := resolver.WorkloadProfile{
+		Name:               pod.Name,
+		Namespace:          pod.Namespace,
+		CPURequirements:    int(math.Ceil(float64(requests.Cpu().MilliValue()) / 1000)),
+		MemoryRequirements: float64(requests.Memory().Value()) / (1 << 30),
+		Zone:               resolver.NodeZone(pod.Spec.NodeSelector[zoneLabel]),
+		Architecture:       pod.Spec.NodeSelector[archLabel],
+		Labels:             pod.Labels,
+		Preferences:        podPreferences(pod),
+	}
+	if hasLimit(pod, corev1.ResourceCPU) {
+		w.LimitCPU = float64(limits.Cpu().MilliValue()) / 1000
+	}
+	if hasLimit(pod, corev1.ResourceMemory) {
+		w.LimitMemory = float64(limits.Memory().Value()) / (1 << 30)
+	}
+	if gpus, ok := requests[gpuResource]; ok {
+		w.GPURequirements = int(gpus.Value())
+		w.GPUType = strings.Join(podGPUTypes(pod), ",")
+	}
+	if pod.Spec.Priority != nil {
+		w.Priority = int(*pod.Spec.Priority)
+	}
+	return w
+}
+
+// effectiveResources returns the effective resources of pod, of each container's as of returns
+// them: the sum over its containers, or the largest of an init container where that is more, as
+// the scheduler computes requests.
+func effectiveResources(pod corev1.Pod, of func(corev1.ResourceRequirements) corev1.ResourceList) corev1.ResourceList {
+	total := corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		for name, q := range of(c.Resources) {
+			sum := total[name]
+			sum.Add(q)
+			total[name] = sum
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range of(c.Resources) {
+			if q.Cmp(total[name]) > 0 {
+				total[name] = q
+			}
+		}
+	}
+	return total
+}
+
+// limitsOrRequests returns the limits of a container, with its request for the resources it
+// does not limit.
+func limitsOrRequests(r corev1.ResourceRequirements) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	for name, q := range r.Requests {
+		list[name] = q
+	}
+	for name, q := range r.Limits {
+		list[name] = q
+	}
+	return list
+}
+
+// hasLimit reports whether any container of pod limits resource.
+func hasLimit(pod corev1.Pod, resource corev1.ResourceName) bool {
+	for _, cs := range [][]corev1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, c := range cs {
+			if _, ok := c.Resources.Limits[resource]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package kube
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/apis/v1alpha2"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func clusterStateSKUs() []resolver.AzureInstanceSpec {
+	return []resolver.AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "standardDSv5Family", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2", "3"}},
+		{Name: "Standard_E8s_v5", Family: "standardESv5Family", VCpus: 8, MemoryGiB: 64, PricePerHour: 0.504, AvailabilityZones: []string{"1", "2", "3"}, SpotSupported: true},
+		{Name: "Standard_NC6s_v3", Family: "standardNCSv3Family", VCpus: 6, MemoryGiB: 112, PricePerHour: 3.06, GPUCount: 1, GPUType: "V100", SpotSupported: true},
+	}
+}
+
+func TestLoadClusterState(t *testing.T) {
+	dir := filepath.Join("testdata", "cluster")
+	state, err := LoadClusterState(clusterStateSKUs(), filepath.Join(dir, "nodes.json"), filepath.Join(dir, "pods.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type node struct {
+		name, sku, zone string
+		capacityType    resolver.CapacityType
+		pods            []string
+	}
+	want := []node{
+		{"aks-system-12345678-vmss000000", "Standard_D4s_v5", "1", resolver.CapacityTypeOnDemand, []string{"kube-system/coredns-789789675-x2x9k"}},
+		{"aks-general-abcde", "Standard_E8s_v5", "3", resolver.CapacityTypeSpot, []string{"shop/web-6d4cf56db6-abcde"}},
+		{"aks-gpu-87654321-vmss000000", "Standard_NC6s_v3", "", resolver.CapacityTypeSpot, []string{"ml/train-0"}},
+	}
+	var got []node
+	for _, n := range state.Nodes {
+		var pods []string
+		for _, w := range n.Workloads {
+			pods = append(pods, w.ID())
+		}
+		got = append(got, node{n.Name, n.InstanceType.Name, n.Zone, n.CapacityType, pods})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected nodes\n%+v\ngot\n%+v", want, got)
+	}
+
+	coredns := state.Nodes[0].Workloads[0]
+	if coredns.CPURequirements != 1 || coredns.MemoryRequirements != 70.0/1024 || coredns.Priority != 2000000000 || coredns.Labels["k8s-app"] != "kube-dns" {
+		t.Errorf("expected 100m rounded up to 1 vCPU, 70Mi, the priority and labels, got %+v", coredns)
+	}
+	// The init container's 2 vCPUs exceed the containers' 1.75; their 3.5Gi exceed its 1Gi.
+	if web := state.Nodes[1].Workloads[0]; web.CPURequirements != 2 || web.MemoryRequirements != 3.5 {
+		t.Errorf("expected the web pod to request 2 vCPUs and 3.5 GiB, got %d and %v", web.CPURequirements, web.MemoryRequirements)
+	}
+	if train := state.Nodes[2].Workloads[0]; train.GPURequirements != 1 || train.CPURequirements != 4 {
+		t.Errorf("expected the training pod to request 1 GPU and 4 vCPUs, got %+v", train)
+	}
+
+	wantUnknown := []resolver.UnknownNode{
+		{Name: "aks-new-11111111-vmss000000", InstanceType: "Standard_D4ds_v6", Pods: 1},
+		{Name: "aks-gone-22222222-vmss000003", Missing: true, Pods: 1},
+	}
+	if !reflect.DeepEqual(state.UnknownNodes, wantUnknown) {
+		t.Errorf("expected unknown nodes %+v, got %+v", wantUnknown, state.UnknownNodes)
+	}
+	if len(state.Pending) != 1 || state.Pending[0].Name != "batch-xyz" || state.Pending[0].Zone != "2" {
+		t.Errorf("expected the batch pod pending in zone 2, got %+v", state.Pending)
+	}
+	if vms := state.VMs(); len(vms) != 3 || math.Abs(resolver.TotalCost(vms)-3.756) > 1e-9 {
+		t.Errorf("expected the 3 known nodes as VMs, got %d costing %v", len(vms), resolver.TotalCost(vms))
+	}
+
+	combined, err := LoadClusterState(clusterStateSKUs(), filepath.Join(dir, "combined.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(combined, state) {
+		t.Error("expected the combined file to import like the separate node and pod files")
+	}
+}
+
+func TestParseKubectlList_APIServerList(t *testing.T) {
+	// The API server's NodeList leaves the kind of its items unset.
+	data := []byte(`{"kind": "NodeList", "apiVersion": "v1", "items": [{"metadata": {"name": "n1", "labels": {"node.kubernetes.io/instance-type": "Standard_D4s_v5"}}}]}`)
+	nodes, pods, err := parseKubectlList(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Name != "n1" || len(pods) != 0 {
+		t.Errorf("expected node n1, got %d nodes and %d pods", len(nodes), len(pods))
+	}
+	if _, _, err := parseKubectlList([]byte(`{"kind": "List", "items": [`)); err == nil {
+		t.Error("expected an error for truncated JSON")
+	}
+}
+
+func TestPodWorkload_GPUTypes(t *testing.T) {
+	gpuPod := func(spec corev1.PodSpec) corev1.Pod {
+		spec.Containers = []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("4"),
+			gpuResource:        resource.MustParse("1"),
+		}}}}
+		return corev1.Pod{Spec: spec}
+	}
+	affinity := func(terms ...[]string) *corev1.Affinity {
+		var selector corev1.NodeSelector
+		for _, values := range terms {
+			selector.NodeSelectorTerms = append(selector.NodeSelectorTerms, corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: v1alpha2.LabelSKUGPUName, Operator: corev1.NodeSelectorOpIn, Values: values},
+			}})
+		}
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &selector}}
+	}
+	for _, tc := range []struct {
+		name string
+		spec corev1.PodSpec
+		want string
+	}{
+		{"none", corev1.PodSpec{}, ""},
+		{"node selector", corev1.PodSpec{NodeSelector: map[string]string{v1alpha2.LabelSKUGPUName: "A100"}}, "A100"},
+		{"affinity", corev1.PodSpec{Affinity: affinity([]string{"A100", "V100"})}, "A100,V100"},
+		{"affinity terms", corev1.PodSpec{Affinity: affinity([]string{"A100"}, []string{"V100", "A100"})}, "A100,V100"},
+	} {
+		if got := podWorkload(gpuPod(tc.spec)).GPUType; got != tc.want {
+			t.Errorf("%s: GPUType = %q, expected %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPodPreferences(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+			{Weight: 50, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: v1alpha2.LabelSKUFamily, Operator: corev1.NodeSelectorOpIn, Values: []string{"D", "E"}},
+				{Key: v1alpha2.LabelSKUCPU, Operator: corev1.NodeSelectorOpGt, Values: []string{"4"}},
+			}}},
+			{Weight: 10, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: v1alpha2.LabelSKUFamily, Operator: corev1.NodeSelectorOpIn, Values: []string{"F"}},
+				{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"eastus2-1"}},
+			}}},
+		},
+	}}}}
+	want := map[string]string{v1alpha2.LabelSKUFamily: "D,E", corev1.LabelTopologyZone: "eastus2-1"}
+	if got := podWorkload(pod).Preferences; !reflect.DeepEqual(got, want) {
+		t.Errorf("Preferences = %v, expected %v", got, want)
+	}
+	if got := podWorkload(corev1.Pod{}).Preferences; got != nil {
+		t.Errorf("expected no preferences without affinity, got %v", got)
+	}
+}
+
+func TestPodWorkload_Limits(t *testing.T) {
+	container := func(cpuReq, cpuLim, memReq, memLim string) corev1.Container {
+		r := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+		for _, q := range []struct {
+			list  corev1.ResourceList
+			name  corev1.ResourceName
+			value string
+		}{{r.Requests, corev1.ResourceCPU, cpuReq}, {r.Limits, corev1.ResourceCPU, cpuLim}, {r.Requests, corev1.ResourceMemory, memReq}, {r.Limits, corev1.ResourceMemory, memLim}} {
+			if q.value != "" {
+				q.list[q.name] = resource.MustParse(q.value)
+			}
+		}
+		return corev1.Container{Resources: r}
+	}
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		container("1", "2", "2Gi", "4Gi"),
+		container("500m", "", "1Gi", "1Gi"), // no CPU limit: counts its request
+	}}}
+	w := podWorkload(pod)
+	if w.CPURequirements != 2 || w.LimitCPU != 2.5 || w.MemoryRequirements != 3 || w.LimitMemory != 5 {
+		t.Errorf("expected requests of 2 vCPUs and 3 GiB with limits of 2.5 vCPUs and 5 GiB, got %+v", w)
+	}
+	if w := podWorkload(corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{container("1", "", "1Gi", "")}}}); w.LimitCPU != 0 || w.LimitMemory != 0 {
+		t.Errorf("expected no limits for a pod without any, got %+v", w)
+	}
+}
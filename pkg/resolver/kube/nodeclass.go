@@ -0,0 +1,126 @@
+package kube
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/apis/v1alpha2"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// Defaults of the AKSNodeClass fields ConstraintsFromAKSNodeClass reads, as set by the CRD.
+const (
+	defaultNodeClassOSDiskSizeGB = 128
+	defaultNodeClassImageFamily  = "Ubuntu2204"
+)
+
+// ConstraintsFromAKSNodeClass returns the constraints of nc, with the CRD defaults for unset fields.
+func ConstraintsFromAKSNodeClass(nc *v1alpha2.AKSNodeClass) resolver.NodeClassConstraints {
+	c := resolver.NodeClassConstraints{OSDiskSizeGB: defaultNodeClassOSDiskSizeGB, ImageFamily: defaultNodeClassImageFamily}
+	if nc == nil {
+		return c
+	}
+	if nc.Spec.OSDiskSizeGB != nil {
+		c.OSDiskSizeGB = int(*nc.Spec.OSDiskSizeGB)
+	}
+	if nc.Spec.MaxPods != nil {
+		c.MaxPods = int(*nc.Spec.MaxPods)
+	}
+	if nc.Spec.ImageFamily != nil {
+		c.ImageFamily = *nc.Spec.ImageFamily
+	}
+	return c
+}
+
+/*
+FiltersFromNodePool returns an instance filter per requirement of np's node template on a label
+the simulation models: instance type, zone, capacity type, and the karpenter.azure.com SKU
+family, vCPU, memory (MiB), GPU count and GPU name. Requirements on other labels are ignored. Zones match
+either as the SKU's zone number or as "<region>-<number>".
+*/
+func FiltersFromNodePool(np *karpv1.NodePool) []func(resolver.AzureInstanceSpec) bool {
+	if np == nil {
+		return nil
+	}
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(np.Spec.Template.Spec.Requirements...)
+	var filters []func(resolver.AzureInstanceSpec) bool
+	for _, key := range sets.List(requirements.Keys()) {
+		if _, ok := resolver.SKULabel(resolver.AzureInstanceSpec{}, key); !ok {
+			continue
+		}
+		r := requirements.Get(key)
+		filters = append(filters, func(vm resolver.AzureInstanceSpec) bool {
+			value, _ := resolver.SKULabel(vm, key)
+			return r.Has(value)
+		})
+	}
+	if requirements.Has(corev1.LabelTopologyZone) {
+		r := requirements.Get(corev1.LabelTopologyZone)
+		filters = append(filters, func(vm resolver.AzureInstanceSpec) bool {
+			for _, z := range vm.AvailabilityZones {
+				if zoneAllowed(r, z) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if requirements.Has(karpv1.CapacityTypeLabelKey) {
+		r := requirements.Get(karpv1.CapacityTypeLabelKey)
+		filters = append(filters, func(vm resolver.AzureInstanceSpec) bool {
+			return r.Has(karpv1.CapacityTypeOnDemand) || vm.SpotSupported && r.Has(karpv1.CapacityTypeSpot)
+		})
+	}
+	return filters
+}
+
+// zoneAllowed reports whether r admits zone z, given as a number or as "<region>-<number>".
+func zoneAllowed(r *scheduling.Requirement, z string) bool {
+	for _, v := range r.Values() {
+		if strings.HasSuffix(v, "-"+z) {
+			return r.Has(v)
+		}
+	}
+	return r.Has(z)
+}
+
+// allOf returns a filter accepting the instance types every filter accepts.
+func allOf(filters []func(resolver.AzureInstanceSpec) bool) func(resolver.AzureInstanceSpec) bool {
+	return func(vm resolver.AzureInstanceSpec) bool {
+		for _, f := range filters {
+			if !f(vm) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+/*
+SimulateForNodeClass packs workloads onto the candidates np admits (see FiltersFromNodePool)
+with the constraints of nc and np's CPU and memory limits on top of cfg. Either may be nil.
+*/
+func SimulateForNodeClass(nc *v1alpha2.AKSNodeClass, np *karpv1.NodePool, workloads resolver.WorkloadSet, candidates []resolver.AzureInstanceSpec, cfg resolver.Config) (resolver.PackingResult, resolver.SimulationResult) {
+	cfg.NodeClass = ConstraintsFromAKSNodeClass(nc)
+	admit := allOf(FiltersFromNodePool(np))
+	var admitted []resolver.AzureInstanceSpec
+	for _, c := range candidates {
+		if admit(c) {
+			admitted = append(admitted, c)
+		}
+	}
+	if np != nil {
+		if cpu, ok := np.Spec.Limits[corev1.ResourceCPU]; ok {
+			cfg.Limits.CPU = int(cpu.Value())
+		}
+		if mem, ok := np.Spec.Limits[corev1.ResourceMemory]; ok {
+			cfg.Limits.MemoryGiB = float64(mem.Value()) / (1 << 30)
+		}
+	}
+	result := resolver.BinPackWorkloadsWithConfig(workloads, admitted, cfg)
+	return result, cfg.Summarize(result)
+}
@@ -0,0 +1,106 @@
+package kube
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/apis/v1alpha2"
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// nodeClassFixture is an AKSNodeClass like the e2e environment's, with a 128 GB OS disk.
+func nodeClassFixture() *v1alpha2.AKSNodeClass {
+	return &v1alpha2.AKSNodeClass{Spec: v1alpha2.AKSNodeClassSpec{
+		OSDiskSizeGB: lo.ToPtr[int32](128),
+		ImageFamily:  lo.ToPtr("AzureLinux"),
+		MaxPods:      lo.ToPtr[int32](30),
+	}}
+}
+
+func nodeClassSKUs() []resolver.AzureInstanceSpec {
+	return []resolver.AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2", "3"}, EphemeralOSDisk: true, MaxEphemeralOSDiskGB: 80},
+		{Name: "Standard_D4ds_v5", Family: "DDSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.226, AvailabilityZones: []string{"1", "2", "3"}, EphemeralOSDisk: true, MaxEphemeralOSDiskGB: 150},
+		{Name: "Standard_E4s_v5", Family: "ESv5", VCpus: 4, MemoryGiB: 32, PricePerHour: 0.252, AvailabilityZones: []string{"1"}, SpotSupported: true},
+	}
+}
+
+func TestConstraintsFromAKSNodeClass(t *testing.T) {
+	want := resolver.NodeClassConstraints{OSDiskSizeGB: 128, MaxPods: 30, ImageFamily: "AzureLinux"}
+	if got := ConstraintsFromAKSNodeClass(nodeClassFixture()); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	defaults := resolver.NodeClassConstraints{OSDiskSizeGB: 128, ImageFamily: "Ubuntu2204"}
+	if got := ConstraintsFromAKSNodeClass(&v1alpha2.AKSNodeClass{}); got != defaults {
+		t.Errorf("expected the CRD defaults %+v, got %+v", defaults, got)
+	}
+}
+
+func TestSimulateForNodeClass_EphemeralOSDiskSize(t *testing.T) {
+	workloads := resolver.WorkloadSet{{Name: "ephemeral", CPURequirements: 2, MemoryRequirements: 4, RequireEphemeralOS: true}}
+	result, sim := SimulateForNodeClass(nodeClassFixture(), nil, workloads, nodeClassSKUs(), resolver.Config{})
+	if sim.VMsUsed != 1 || result.VMs[0].InstanceType.Name != "Standard_D4ds_v5" {
+		t.Fatalf("expected one Standard_D4ds_v5, whose cache holds the 128 GB OS disk, got %+v", result)
+	}
+
+	// A smaller OS disk fits the cheaper SKU's cache
+	nc := nodeClassFixture()
+	nc.Spec.OSDiskSizeGB = lo.ToPtr[int32](64)
+	if result, _ := SimulateForNodeClass(nc, nil, workloads, nodeClassSKUs(), resolver.Config{}); result.VMs[0].InstanceType.Name != "Standard_D4s_v5" {
+		t.Errorf("expected Standard_D4s_v5 for a 64 GB OS disk, got %s", result.VMs[0].InstanceType.Name)
+	}
+
+	// Without ephemeral OS the cache size does not matter
+	workloads[0].RequireEphemeralOS = false
+	if result, _ := SimulateForNodeClass(nodeClassFixture(), nil, workloads, nodeClassSKUs(), resolver.Config{}); result.VMs[0].InstanceType.Name != "Standard_D4s_v5" {
+		t.Errorf("expected the cheapest SKU without ephemeral OS, got %s", result.VMs[0].InstanceType.Name)
+	}
+}
+
+func TestSimulateForNodeClass_MaxPods(t *testing.T) {
+	workloads := resolver.WorkloadSet{
+		{Name: "fits", CPURequirements: 1, MemoryRequirements: 1, Capabilities: map[string]string{"MaxPods": "30"}},
+		{Name: "dense", CPURequirements: 2, MemoryRequirements: 2, Capabilities: map[string]string{"MaxPods": "50"}},
+	}
+	result, _ := SimulateForNodeClass(nodeClassFixture(), nil, workloads, nodeClassSKUs(), resolver.Config{})
+	if len(result.Unpacked) != 1 || result.Unpacked[0].Workload.Name != "dense" {
+		t.Errorf("expected only the workload needing 50 pods per node to be unpacked, got %+v", result.Unpacked)
+	}
+}
+
+func TestFiltersFromNodePool(t *testing.T) {
+	np := &karpv1.NodePool{}
+	np.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+		{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: v1alpha2.LabelSKUFamily, Operator: corev1.NodeSelectorOpIn, Values: []string{"D", "E"}}},
+		{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: v1alpha2.LabelSKUCPU, Operator: corev1.NodeSelectorOpLt, Values: []string{"8"}}},
+		{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpNotIn, Values: []string{"westus2-1"}}},
+		{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: "kubernetes.io/os", Operator: corev1.NodeSelectorOpIn, Values: []string{"linux"}}},
+	}
+	skus := append(nodeClassSKUs(), resolver.AzureInstanceSpec{Name: "Standard_D8s_v5", Family: "DSv5", VCpus: 8, MemoryGiB: 32, AvailabilityZones: []string{"2"}})
+	admit := allOf(FiltersFromNodePool(np))
+	var admitted []string
+	for _, sku := range skus {
+		if admit(sku) {
+			admitted = append(admitted, sku.Name)
+		}
+	}
+	// E4s_v5 is only offered in the excluded zone, D8s_v5 is too large
+	if want := []string{"Standard_D4s_v5", "Standard_D4ds_v5"}; !slices.Equal(admitted, want) {
+		t.Errorf("expected %v admitted, got %v", want, admitted)
+	}
+
+	np.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+		{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: karpv1.CapacityTypeLabelKey, Operator: corev1.NodeSelectorOpIn, Values: []string{karpv1.CapacityTypeSpot}}},
+	}
+	np.Spec.Limits = karpv1.Limits{corev1.ResourceCPU: resource.MustParse("4")}
+	workloads := resolver.WorkloadSet{{CPURequirements: 2, MemoryRequirements: 20}, {CPURequirements: 2, MemoryRequirements: 20}}
+	result, _ := SimulateForNodeClass(nil, np, workloads, skus, resolver.Config{})
+	if len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != "Standard_E4s_v5" || len(result.Unpacked) != 1 {
+		t.Errorf("expected one spot-capable VM within the 4 CPU limit and one unpacked workload, got %+v", result)
+	}
+}
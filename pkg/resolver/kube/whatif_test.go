@@ -0,0 +1,52 @@
+package kube
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func TestCompareToActual(t *testing.T) {
+	skus := []resolver.AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "standardDSv5Family", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2", "3"}},
+		{Name: "Standard_D8s_v5", Family: "standardDSv5Family", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.384, AvailabilityZones: []string{"1", "2", "3"}},
+		{Name: "Standard_E16s_v5", Family: "standardESv5Family", VCpus: 16, MemoryGiB: 128, PricePerHour: 1.008, AvailabilityZones: []string{"1", "2", "3"}},
+	}
+	// Karpenter left three lightly used E16s_v5 nodes and an empty D8s_v5 node behind.
+	state, err := LoadClusterState(skus, filepath.Join("testdata", "cluster", "karpenter.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := resolver.CompareToActual(state, skus, resolver.Config{})
+	if len(r.Simulated.VMs) != 1 || r.Simulated.VMs[0].InstanceType.Name != "Standard_D4s_v5" || len(r.Simulated.Unpacked) != 0 {
+		t.Fatalf("expected the 3 pods repacked onto one D4s_v5, got %+v", r.Simulated)
+	}
+	if savings := r.SavingsPercent(); savings < 90 {
+		t.Errorf("expected over 90%% savings, got %.1f%%", savings)
+	}
+	wantCounts := []resolver.SKUCountDelta{{SKU: "Standard_D4s_v5", A: 0, B: 1}, {SKU: "Standard_D8s_v5", A: 1, B: 0}, {SKU: "Standard_E16s_v5", A: 3, B: 0}}
+	if !reflect.DeepEqual(r.Comparison.SKUCounts, wantCounts) {
+		t.Errorf("expected SKU counts %+v, got %+v", wantCounts, r.Comparison.SKUCounts)
+	}
+	var oversized []string
+	for _, o := range r.Oversized {
+		oversized = append(oversized, o.Name+":"+strings.Join(o.Replacement, ","))
+	}
+	want := []string{"default-a1b2c:Standard_D4s_v5", "default-d3e4f:Standard_D4s_v5", "default-g5h6i:Standard_D4s_v5", "default-j7k8l:"}
+	if !reflect.DeepEqual(oversized, want) {
+		t.Errorf("expected oversized nodes %v, got %v", want, oversized)
+	}
+	if o := r.Oversized[0]; o.CPUUtilization != 100.0/16 || o.Pods != 1 || math.Abs(o.SavingsPerHour-0.816) > 1e-9 {
+		t.Errorf("expected 1 pod using 6.25%% of the node's CPU, saving 0.816/h, got %+v", o)
+	}
+	out := r.String()
+	for _, line := range []string{"actual:    4 VMs, 3.4080/h", "simulated: 1 VMs, 0.1920/h", "4 oversized nodes:", "default-j7k8l (Standard_D8s_v5, 0 pods, CPU 0.0%, memory 0.0%): nothing (empty) saves 0.3840/h"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected the report to contain %q, got:\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package resolver
+
+// Limits caps the total resources provisioned, like NodePool.spec.limits. Zero fields are unlimited.
+type Limits struct {
+	CPU       int
+	MemoryGiB float64
+	VMs       int // maximum number of VMs
+}
+
+// Unpacked reasons reported when Config.Limits stop provisioning.
+const (
+	ReasonCPULimitExceeded    = "limits exceeded: cpu"
+	ReasonMemoryLimitExceeded = "limits exceeded: memory"
+	ReasonVMLimitExceeded     = "limits exceeded: vms"
+)
+
+// Utilization returns the percentage of the CPU and memory limits used by the given VMs.
+// Unlimited dimensions report 0.
+func (l Limits) Utilization(vms []PackedVM) (cpuPct, memPct float64) {
+	var cpu, mem float64
+	for _, vm := range vms {
+		cpu += float64(vm.InstanceType.VCpus)
+		mem += vm.InstanceType.MemoryGiB
+	}
+	if l.CPU > 0 {
+		cpuPct = cpu / float64(l.CPU) * 100
+	}
+	if l.MemoryGiB > 0 {
+		memPct = mem / l.MemoryGiB * 100
+	}
+	return cpuPct, memPct
+}
+
+// limitTracker accumulates provisioned capacity against Limits during packing.
+// This is separate from quota accounting: quotas are per family, limits are per pool.
+type limitTracker struct {
+	limits Limits
+	cpu    int
+	mem    float64
+	vms    int
+}
+
+// exceeded returns the unpacked reason if provisioning vm would exceed the limits, or "".
+func (t *limitTracker) exceeded(vm AzureInstanceSpec) string {
+	if t.limits.CPU > 0 && t.cpu+vm.VCpus > t.limits.CPU {
+		return ReasonCPULimitExceeded
+	}
+	if t.limits.MemoryGiB > 0 && !fitsWithin(t.mem+vm.MemoryGiB, t.limits.MemoryGiB) {
+		return ReasonMemoryLimitExceeded
+	}
+	if t.limits.VMs > 0 && t.vms+1 > t.limits.VMs {
+		return ReasonVMLimitExceeded
+	}
+	return ""
+}
+
+func (t *limitTracker) add(vm AzureInstanceSpec) {
+	t.cpu += vm.VCpus
+	t.mem += vm.MemoryGiB
+	t.vms++
+}
+
+// markUnpacked returns the workloads not yet packed (packed[i] == false) with the given reason.
+func markUnpacked(sorted WorkloadSet, packed []bool, reason string) []UnpackedWorkload {
+	var out []UnpackedWorkload
+	for i, w := range sorted {
+		if !packed[i] {
+			out = append(out, UnpackedWorkload{Workload: w, Reason: reason})
+		}
+	}
+	return out
+}
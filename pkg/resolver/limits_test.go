@@ -0,0 +1,68 @@
+package resolver
+
+import (
+	"testing"
+)
+
+func limitsFixture() ([]AzureInstanceSpec, WorkloadSet) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4_v3", Family: "Dv3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := WorkloadSet{
+		{CPURequirements: 4, MemoryRequirements: 4},
+		{CPURequirements: 4, MemoryRequirements: 4},
+		{CPURequirements: 4, MemoryRequirements: 4},
+	}
+	return candidates, workloads
+}
+
+func TestBinPackWorkloadsWithConfig_CPULimitBeforeMemory(t *testing.T) {
+	candidates, workloads := limitsFixture()
+	cfg := Config{Limits: Limits{CPU: 8, MemoryGiB: 40}}
+	for name, packer := range map[string]func() PackingResult{
+		"ffd":   func() PackingResult { return BinPackWorkloadsWithConfig(workloads, candidates, cfg) },
+		"quota": func() PackingResult { return binPackWorkloadsWithQuota(workloads, candidates, cfg) },
+	} {
+		result := packer()
+		if len(result.VMs) != 2 {
+			t.Errorf("%s: expected 2 VMs within the 8 vCPU limit, got %d", name, len(result.VMs))
+		}
+		if len(result.Unpacked) != 1 || result.Unpacked[0].Reason != ReasonCPULimitExceeded {
+			t.Fatalf("%s: expected one workload unpacked with %q, got %+v", name, ReasonCPULimitExceeded, result.Unpacked)
+		}
+		sim := cfg.Summarize(result)
+		if sim.Unpacked != 1 || sim.LimitCPUUtil != 100 || sim.LimitMemUtil != 80 {
+			t.Errorf("%s: expected 1 unpacked, 100%% CPU and 80%% memory limit utilization, got %d, %v, %v", name, sim.Unpacked, sim.LimitCPUUtil, sim.LimitMemUtil)
+		}
+	}
+}
+
+func TestBinPackWorkloadsWithConfig_MemoryLimit(t *testing.T) {
+	candidates, workloads := limitsFixture()
+	result := BinPackWorkloadsWithConfig(workloads, candidates, Config{Limits: Limits{MemoryGiB: 20}})
+	if len(result.VMs) != 1 || len(result.Unpacked) != 2 {
+		t.Fatalf("expected 1 VM and 2 unpacked workloads, got %d and %d", len(result.VMs), len(result.Unpacked))
+	}
+	for _, u := range result.Unpacked {
+		if u.Reason != ReasonMemoryLimitExceeded {
+			t.Errorf("expected reason %q, got %q", ReasonMemoryLimitExceeded, u.Reason)
+		}
+	}
+}
+
+func TestBinPackWorkloadsWithConfig_VMLimit(t *testing.T) {
+	candidates, workloads := limitsFixture()
+	result := BinPackWorkloadsWithConfig(workloads, candidates, Config{Limits: Limits{VMs: 2}})
+	if len(result.VMs) != 2 || len(result.Unpacked) != 1 || result.Unpacked[0].Reason != ReasonVMLimitExceeded {
+		t.Fatalf("expected 2 VMs and 1 workload unpacked by the VM limit, got %d and %+v", len(result.VMs), result.Unpacked)
+	}
+}
+
+func TestBinPackWorkloadsWithConfig_NoLimits(t *testing.T) {
+	candidates, workloads := limitsFixture()
+	result := BinPackWorkloadsWithConfig(workloads, candidates, Config{})
+	if len(result.VMs) != 3 || len(result.Unpacked) != 0 {
+		t.Errorf("expected 3 VMs and nothing unpacked without limits, got %d and %d", len(result.VMs), len(result.Unpacked))
+	}
+	if cpu, mem := (Limits{}).Utilization(result.VMs); cpu != 0 || mem != 0 {
+		t.Errorf("expected zero utilization for unlimited limits, got %v, %v", cpu, mem)
+	}
+}
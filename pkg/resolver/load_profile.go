@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+/*
+LoadProfile is a daily load pattern: Hourly holds 24 multipliers, from 0 to 1, of the share of
+the workload set active in each hour of the day. The workload set is the peak load, so a flat
+profile of 1 runs every workload all day. It is read from JSON like
+{"hourly": [0.3, 0.3, ..., 1, 1, ...]}.
+*/
+type LoadProfile struct {
+	Hourly []float64 `json:"hourly"`
+}
+
+// Validate returns an error unless p has 24 multipliers from 0 to 1.
+func (p LoadProfile) Validate() error {
+	if len(p.Hourly) != 24 {
+		return fmt.Errorf("load profile needs 24 hourly multipliers, got %d", len(p.Hourly))
+	}
+	for h, m := range p.Hourly {
+		if !(m >= 0 && m <= 1) {
+			return fmt.Errorf("hour %d: multiplier must be between 0 and 1, got %v", h, m)
+		}
+	}
+	return nil
+}
+
+// ReadLoadProfile reads and validates a load profile JSON file.
+func ReadLoadProfile(path string) (*LoadProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read load profile: %w", err)
+	}
+	var p LoadProfile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse load profile %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("load profile %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// HourlyLoad is the packing of one hour of a LoadProfile.
+type HourlyLoad struct {
+	Hour        int
+	Multiplier  float64
+	Workloads   int // active workloads
+	VMs         int
+	CostPerHour float64
+	Unpacked    int
+	// Removed lists the IDs of the previous hour's VMs that consolidation removed: those the
+	// hour's packing has no VM of the same ID, SKU and zone for. Hour 0 follows hour 23.
+	Removed []string `json:",omitempty"`
+}
+
+// LoadProfileResult is the outcome of SimulateLoadProfile.
+type LoadProfileResult struct {
+	Hours []HourlyLoad
+	// DailyCost is the cost of the 24 hours, each packed for its active workloads;
+	// StaticDailyCost that of running the peak packing all day.
+	DailyCost       float64
+	StaticDailyCost float64
+	PeakVMs         int
+	TroughVMs       int
+	// Events logs the VMs each hour provisions and consolidates, at the start of the hour.
+	Events EventLog `json:"-"`
+}
+
+// SavingsPercent returns how much cheaper scaling with the profile is than running the peak
+// packing all day.
+func (r LoadProfileResult) SavingsPercent() float64 {
+	if r.StaticDailyCost == 0 {
+		return 0
+	}
+	return 100 * (r.StaticDailyCost - r.DailyCost) / r.StaticDailyCost
+}
+
+/*
+SimulateLoadProfile steps through the hours of a day, packing for each the share of workloads
+the profile makes active: the multiplier times the workload count, rounded to the nearest
+workload. Which workloads are active is sampled deterministically from cfg.Seed, and a
+workload active at some load is active at every higher one. Each hour is packed afresh, as if
+consolidation scaled the VMs down to what the active workloads need; HourlyLoad.Removed lists
+the VMs it removed and LoadProfileResult.Events logs them together with the VMs provisioned.
+*/
+func SimulateLoadProfile(workloads WorkloadSet, candidates []AzureInstanceSpec, profile LoadProfile, cfg Config) (LoadProfileResult, error) {
+	if err := profile.Validate(); err != nil {
+		return LoadProfileResult{}, err
+	}
+	cfg.ScoreCacheStats, cfg.WithAudit = nil, false
+	order := rand.New(rand.NewSource(cfg.Seed)).Perm(len(workloads))
+	var r LoadProfileResult
+	packings := make([]PackingResult, len(profile.Hourly))
+	for h, m := range profile.Hourly {
+		n := int(math.Round(m * float64(len(workloads))))
+		active := make(WorkloadSet, n)
+		for i := range active {
+			active[i] = workloads[order[i]]
+		}
+		packing := BinPackWorkloadsWithConfig(active, candidates, cfg)
+		packings[h] = packing
+		hour := HourlyLoad{Hour: h, Multiplier: m, Workloads: n, VMs: len(packing.VMs), CostPerHour: TotalCost(packing.VMs), Unpacked: len(packing.Unpacked)}
+		r.Hours = append(r.Hours, hour)
+		r.DailyCost += hour.CostPerHour
+		if h == 0 || hour.VMs > r.PeakVMs {
+			r.PeakVMs = hour.VMs
+		}
+		if h == 0 || hour.VMs < r.TroughVMs {
+			r.TroughVMs = hour.VMs
+		}
+	}
+	for h := range r.Hours {
+		before, after := packings[(h+len(packings)-1)%len(packings)], packings[h]
+		start := float64(h * 3600)
+		for _, vm := range missingVMs(before, after) {
+			r.Hours[h].Removed = append(r.Hours[h].Removed, vm.ID)
+			r.Events.add(start, EventConsolidate, vm.ID, "", vm.InstanceType.Name)
+		}
+		for _, vm := range missingVMs(after, before) {
+			r.Events.add(start, EventProvision, vm.ID, "", vm.InstanceType.Name)
+		}
+	}
+	r.StaticDailyCost = 24 * TotalCost(BinPackWorkloadsWithConfig(workloads, candidates, cfg).VMs)
+	return r, nil
+}
+
+// missingVMs returns the VMs of before that after has no VM of the same ID, SKU and zone for.
+func missingVMs(before, after PackingResult) []PackedVM {
+	type vmKey struct{ id, sku, zone string }
+	kept := make(map[vmKey]bool, len(after.VMs))
+	for _, vm := range after.VMs {
+		kept[vmKey{vm.ID, vm.InstanceType.Name, vm.Zone}] = true
+	}
+	var removed []PackedVM
+	for _, vm := range before.VMs {
+		if !kept[vmKey{vm.ID, vm.InstanceType.Name, vm.Zone}] {
+			removed = append(removed, vm)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSimulateLoadProfile_SquareWave(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := make(WorkloadSet, 16)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{CPURequirements: 4, MemoryRequirements: 8}
+	}
+	// Full load by day (08:00-19:59), a quarter at night
+	profile := LoadProfile{Hourly: make([]float64, 24)}
+	for h := range profile.Hourly {
+		profile.Hourly[h] = 0.25
+		if h >= 8 && h < 20 {
+			profile.Hourly[h] = 1
+		}
+	}
+	r, err := SimulateLoadProfile(workloads, candidates, profile, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PeakVMs != 16 || r.TroughVMs != 4 || r.Hours[12].VMs != 16 || r.Hours[3].VMs != 4 || r.Hours[3].Workloads != 4 {
+		t.Errorf("expected 16 VMs by day and 4 at night, got peak %d, trough %d, hours %+v", r.PeakVMs, r.TroughVMs, r.Hours)
+	}
+	// 12h of 16 VMs and 12h of 4 VMs at 0.2/h, against 24h of 16 VMs
+	if want := 12*16*0.2 + 12*4*0.2; math.Abs(r.DailyCost-want) > 1e-9 {
+		t.Errorf("expected a 24h cost of %v, got %v", want, r.DailyCost)
+	}
+	if math.Abs(r.StaticDailyCost-24*16*0.2) > 1e-9 || math.Abs(r.SavingsPercent()-37.5) > 1e-9 {
+		t.Errorf("expected 37.5%% savings over %v, got %v and %v", 24*16*0.2, r.SavingsPercent(), r.StaticDailyCost)
+	}
+	// Consolidation at 20:00 removes the 12 VMs beyond the night's 4; nothing is removed otherwise.
+	if removed := r.Hours[20].Removed; len(removed) != 12 || removed[0] != "vm-0005" || removed[11] != "vm-0016" {
+		t.Errorf("expected vm-0005 to vm-0016 removed at 20:00, got %v", removed)
+	}
+	for _, h := range []int{0, 8, 12, 21} {
+		if len(r.Hours[h].Removed) != 0 {
+			t.Errorf("hour %d: expected no VMs removed, got %v", h, r.Hours[h].Removed)
+		}
+	}
+
+	if _, err := SimulateLoadProfile(workloads, candidates, LoadProfile{Hourly: []float64{1}}, Config{}); err == nil {
+		t.Error("expected an error for a profile without 24 hours")
+	}
+}
+
+func TestReadLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "profile.json")
+	if err := os.WriteFile(good, []byte(`{"hourly": [0.5,0.5,0.5,0.5,0.5,0.5,0.5,0.5,1,1,1,1,1,1,1,1,1,1,1,1,0.5,0.5,0.5,0.5]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := ReadLoadProfile(good); err != nil || p.Hourly[8] != 1 {
+		t.Errorf("expected the profile read, got %+v, %v", p, err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"hourly": [2,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadLoadProfile(bad); err == nil {
+		t.Error("expected an error for a multiplier above 1")
+	}
+}
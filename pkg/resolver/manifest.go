@@ -0,0 +1,155 @@
+package resolver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/*
+RunManifest records every input of a scenario run, so the run can be reproduced after its SKU,
+workload, quota or reservation files change or disappear: the resolved scenario with its
+weights and seed, and the exact data loaded from its files. RunScenario embeds it in
+SimulationRun.Manifest when Scenario.Manifest is set, and Replay re-runs it.
+*/
+type RunManifest struct {
+	Scenario     Scenario
+	Currency     string
+	SKUs         []AzureInstanceSpec
+	Workloads    WorkloadSet
+	Quota        QuotaMap              `json:",omitempty"`
+	Reservations []CapacityReservation `json:",omitempty"`
+	// Hash is the hex SHA-256 of the JSON of the other fields; Replay refuses manifests that
+	// no longer match it.
+	Hash string
+}
+
+// ErrManifestModified is returned by Replay for manifests whose contents do not match their Hash.
+var ErrManifestModified = errors.New("manifest modified")
+
+// hash returns the Hash of m.
+func (m RunManifest) hash() (string, error) {
+	m.Hash = ""
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// seal sets m.Hash.
+func (m *RunManifest) seal() error {
+	h, err := m.hash()
+	if err != nil {
+		return fmt.Errorf("hash manifest: %w", err)
+	}
+	m.Hash = h
+	return nil
+}
+
+// Verify returns an error wrapping ErrManifestModified unless m matches its Hash.
+func (m RunManifest) Verify() error {
+	h, err := m.hash()
+	if err != nil {
+		return fmt.Errorf("hash manifest: %w", err)
+	}
+	if h != m.Hash {
+		return fmt.Errorf("%w: contents hash to %s, not %s", ErrManifestModified, h, m.Hash)
+	}
+	return nil
+}
+
+// ResultDiff is how one replayed result differs from the recorded one.
+type ResultDiff struct {
+	Name string
+	Diff string
+}
+
+// ReplayMismatchError is the error of Replay when replayed results differ from the recorded ones.
+type ReplayMismatchError struct {
+	Diffs []ResultDiff
+}
+
+func (e *ReplayMismatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "replay differs from the recorded run in %d result(s)", len(e.Diffs))
+	for _, d := range e.Diffs {
+		fmt.Fprintf(&b, "\n%s:\n%s", d.Name, strings.TrimRight(d.Diff, "\n"))
+	}
+	return b.String()
+}
+
+/*
+Replay re-runs the manifest of a recorded run, e.g. one read back from a JSON report, and
+returns the replayed run. It fails with an error wrapping ErrManifestModified when the
+manifest was edited, and with a *ReplayMismatchError listing the differences when a result
+is not identical to the recorded one. Timings and RunMetadata are not compared; the replayed
+run has its own.
+*/
+func Replay(ctx context.Context, recorded SimulationRun) (SimulationRun, error) {
+	m := recorded.Manifest
+	if m == nil {
+		return SimulationRun{}, fmt.Errorf("the run has no manifest; record one with the scenario's manifest option")
+	}
+	if err := m.Verify(); err != nil {
+		return SimulationRun{}, err
+	}
+	start := time.Now()
+	run, err := m.run(ctx)
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	run = withMetadata(run, start)
+	run.Manifest = m
+	replayed := make(map[string]SimulationResult, len(run.Results))
+	for _, nr := range run.Results {
+		replayed[nr.Name] = nr.Result
+	}
+	mismatch := &ReplayMismatchError{}
+	for _, nr := range recorded.Results {
+		got, ok := replayed[nr.Name]
+		if !ok {
+			mismatch.Diffs = append(mismatch.Diffs, ResultDiff{Name: nr.Name, Diff: "not in the replay"})
+			continue
+		}
+		if d, err := diffResults(nr.Result, got, m.SKUs); err != nil {
+			return SimulationRun{}, err
+		} else if d != "" {
+			mismatch.Diffs = append(mismatch.Diffs, ResultDiff{Name: nr.Name, Diff: d})
+		}
+	}
+	if len(mismatch.Diffs) > 0 {
+		return run, mismatch
+	}
+	return run, nil
+}
+
+// diffResults describes how replayed differs from recorded, ignoring timings, or returns "".
+func diffResults(recorded, replayed SimulationResult, skus []AzureInstanceSpec) (string, error) {
+	recorded.Timing, replayed.Timing = TimingReport{}, TimingReport{}
+	a, err := json.Marshal(recorded)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(replayed)
+	if err != nil {
+		return "", err
+	}
+	if string(a) == string(b) {
+		return "", nil
+	}
+	diff := fmt.Sprintf("cost %.4f/h -> %.4f/h, %d -> %d VMs, %d -> %d unpacked\n",
+		recorded.TotalCost, replayed.TotalCost, recorded.VMsUsed, replayed.VMsUsed, recorded.Unpacked, replayed.Unpacked)
+	if c := ComparePackingResults(PackingFromDetail(recorded, skus), PackingFromDetail(replayed, skus)); !c.Identical() {
+		diff += c.String()
+	} else {
+		diff += "same placements, different summary\n"
+	}
+	return diff, nil
+}
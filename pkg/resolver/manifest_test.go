@@ -0,0 +1,94 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordGoldenRun runs the golden fixture as a scenario with a manifest and returns the run
+// as a JSON report reads it back.
+func recordGoldenRun(t *testing.T) SimulationRun {
+	t.Helper()
+	golden, err := filepath.Abs(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	body := "sku: " + filepath.Join(golden, "skus.json") + "\n" +
+		"workloads: " + filepath.Join(golden, "workloads.json") + "\n" +
+		"strategies: [general, memory]\npreferFamilies: [E]\nseed: 3\nmanifest: true\n"
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := RunScenario(context.Background(), sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Manifest == nil || len(run.Manifest.SKUs) == 0 || len(run.Manifest.Workloads) == 0 {
+		t.Fatalf("expected a manifest with the SKUs and workloads, got %+v", run.Manifest)
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded SimulationRun
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	return recorded
+}
+
+func TestReplay(t *testing.T) {
+	recorded := recordGoldenRun(t)
+	// The replay reads nothing from the scenario's files.
+	recorded.Manifest.Scenario.SKU = filepath.Join(t.TempDir(), "missing.json")
+	if err := recorded.Manifest.seal(); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := Replay(context.Background(), recorded)
+	if err != nil {
+		t.Fatalf("expected an identical replay, got %v", err)
+	}
+	if len(replayed.Results) != len(recorded.Results) {
+		t.Errorf("expected %d results, got %d", len(recorded.Results), len(replayed.Results))
+	}
+}
+
+func TestReplay_TamperedManifest(t *testing.T) {
+	recorded := recordGoldenRun(t)
+	recorded.Manifest.SKUs[0].PricePerHour /= 2
+	if _, err := Replay(context.Background(), recorded); !errors.Is(err, ErrManifestModified) {
+		t.Fatalf("expected ErrManifestModified for an edited SKU price, got %v", err)
+	}
+
+	// With the hash fixed up, the results give the edit away.
+	recorded = recordGoldenRun(t)
+	for i := range recorded.Manifest.SKUs {
+		recorded.Manifest.SKUs[i].PricePerHour *= 2
+	}
+	if err := recorded.Manifest.seal(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Replay(context.Background(), recorded)
+	var mismatch *ReplayMismatchError
+	if !errors.As(err, &mismatch) || len(mismatch.Diffs) != len(recorded.Results) {
+		t.Fatalf("expected every result to differ, got %v", err)
+	}
+	if d := mismatch.Diffs[0]; d.Name != "general" || !strings.Contains(d.Diff, "cost ") {
+		t.Errorf("expected a cost diff of the general result, got %+v", d)
+	}
+
+	recorded.Manifest = nil
+	if _, err := Replay(context.Background(), recorded); err == nil {
+		t.Error("expected an error for a run without manifest")
+	}
+}
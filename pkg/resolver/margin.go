@@ -0,0 +1,48 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+FitMargin is the share of a VM's capacity, per resource, that must stay free after packing,
+like the allocatable margin a real node keeps for the kubelet and system daemons. Unlike a
+fixed per-node overhead it scales with the VM size: a workload only fits if it leaves at least
+the given percentage of the VM's vCPUs and memory unused.
+*/
+type FitMargin struct {
+	CPU    float64 // percent of vCPUs to keep free
+	Memory float64 // percent of memory to keep free
+}
+
+// ParseFitMargin parses a CLI fit margin such as "cpu=5%,memory=5%" (see ParseHeadroomSpec).
+func ParseFitMargin(s string) (FitMargin, error) {
+	if strings.TrimSpace(s) == "" {
+		return FitMargin{}, nil
+	}
+	cpu, mem, err := parseResourcePercents(s, "fit margin")
+	if err != nil {
+		return FitMargin{}, err
+	}
+	if cpu >= 100 || mem >= 100 {
+		return FitMargin{}, fmt.Errorf("fit margin must be below 100%%, got %q", s)
+	}
+	return FitMargin{CPU: cpu, Memory: mem}, nil
+}
+
+// enabled reports whether any margin is configured.
+func (m FitMargin) enabled() bool {
+	return m.CPU > 0 || m.Memory > 0
+}
+
+// usable returns the vCPUs and memory of vm that workloads may request.
+func (m FitMargin) usable(vm AzureInstanceSpec) (float64, float64) {
+	return float64(vm.VCpus) * (1 - clamp01(m.CPU/100)), vm.MemoryGiB * (1 - clamp01(m.Memory/100))
+}
+
+// filter rejects instance types that cannot hold the workload with the margin left free.
+func (m FitMargin) filter(inst AzureInstanceSpec, workload WorkloadProfile) bool {
+	cpu, mem := m.usable(inst)
+	return fitsWithin(float64(workload.CPURequirements), cpu) && fitsWithin(workload.MemoryRequirements, mem)
+}
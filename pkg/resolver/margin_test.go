@@ -0,0 +1,50 @@
+package resolver
+
+import "testing"
+
+func TestFitMarginRejectsExactFit(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_E2s_v5", VCpus: 2, MemoryGiB: 16, PricePerHour: 0.126}}
+	workloads := WorkloadSet{{CPURequirements: 1, MemoryRequirements: 16}}
+
+	result := BinPackWorkloadsWithConfig(workloads, candidates, Config{})
+	if len(result.VMs) != 1 || len(result.Unpacked) != 0 {
+		t.Fatalf("expected the workload to fill the VM's memory without a margin, got %d VMs, %d unpacked", len(result.VMs), len(result.Unpacked))
+	}
+
+	cfg := Config{FitMarginPercent: FitMargin{Memory: 5}}
+	if vm, _ := selectWithConfig(candidates, workloads[0], cfg.forRun()); vm.Name != "" {
+		t.Errorf("expected no instance type to be selectable with a 5%% memory margin, got %q", vm.Name)
+	}
+	result = BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+	if len(result.VMs) != 0 || len(result.Unpacked) != 1 || result.Unpacked[0].Reason != ReasonNoCandidates {
+		t.Errorf("expected the workload unpacked with a 5%% memory margin, got %d VMs, %+v", len(result.VMs), result.Unpacked)
+	}
+}
+
+func TestFitMarginLimitsPackingAdmission(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D16s_v5", VCpus: 16, MemoryGiB: 64, PricePerHour: 0.768}}
+	workloads := make(WorkloadSet, 16)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{CPURequirements: 1, MemoryRequirements: 1}
+	}
+	// 10% of 16 vCPUs keeps 1.6 vCPUs free, so only 14 one-vCPU workloads fit per VM.
+	for _, name := range []string{"ffd", "quota"} {
+		pack, _ := PackingAlgorithm(name)
+		result := pack(workloads, candidates, Config{FitMarginPercent: FitMargin{CPU: 10}})
+		if len(result.VMs) != 2 || len(result.VMs[0].Workloads) != 14 {
+			t.Errorf("%s: expected 14 workloads on the first of 2 VMs, got %d VMs", name, len(result.VMs))
+		}
+	}
+}
+
+func TestParseFitMargin(t *testing.T) {
+	m, err := ParseFitMargin("cpu=5%,mem=10")
+	if err != nil || m != (FitMargin{CPU: 5, Memory: 10}) {
+		t.Errorf("expected cpu 5%%, memory 10%%, got %+v, %v", m, err)
+	}
+	for _, bad := range []string{"cpu", "cpu=-1", "disk=5", "memory=100%"} {
+		if _, err := ParseFitMargin(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+maxWorkloads returns how many workloads a VM of vm may hold under c.MaxWorkloadsPerVM: the
+cap, or the pod limit of the SKU or node class where that is stricter. Without a cap the count
+is unlimited, as pod limits only filter instance types then.
+*/
+func (c Config) maxWorkloads(vm AzureInstanceSpec) float64 {
+	if c.MaxWorkloadsPerVM <= 0 {
+		return math.Inf(1)
+	}
+	n := c.MaxWorkloadsPerVM
+	for _, pods := range []int{vm.MaxPods, c.NodeClass.MaxPods} {
+		if pods > 0 && pods < n {
+			n = pods
+		}
+	}
+	return float64(n)
+}
+
+// vmCapacity returns the capacity of a new VM of vm: capacityOf with the fit margin, holding
+// at most maxWorkloads workloads, with the limits capped under a capped Config.Overcommit.
+func (c Config) vmCapacity(vm AzureInstanceSpec) capacity {
+	free := capacityOf(vm, c.FitMarginPercent)
+	free.workloads = c.maxWorkloads(vm)
+	if c.Overcommit.capped() {
+		free.limitCPU = c.Overcommit.MaxLimitRatio * float64(vm.VCpus)
+		free.limitMemoryGiB = c.Overcommit.MaxLimitRatio * vm.MemoryGiB
+	}
+	return free
+}
+
+// ValidateMaxWorkloadsPerVM returns an error if n is negative.
+func ValidateMaxWorkloadsPerVM(n int) error {
+	if n < 0 {
+		return fmt.Errorf("maximum workloads per VM must not be negative, got %d", n)
+	}
+	return nil
+}
+
+// WorkloadCount is how many VMs hold a given number of workloads.
+type WorkloadCount struct {
+	Workloads int
+	VMs       int
+}
+
+// workloadCounts returns the distribution of real workloads per VM, by ascending count.
+func workloadCounts(vms []VMDetail) []WorkloadCount {
+	byCount := make(map[int]int)
+	for _, vm := range vms {
+		byCount[vm.Workloads]++
+	}
+	counts := make([]WorkloadCount, 0, len(byCount))
+	for n, vms := range byCount {
+		counts = append(counts, WorkloadCount{Workloads: n, VMs: vms})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Workloads < counts[j].Workloads })
+	return counts
+}
@@ -0,0 +1,66 @@
+package resolver
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// tinyWorkloads returns n workloads of 1 vCPU and 1 GiB, 16 of which fit a D16s_v5.
+func tinyWorkloads(n int) WorkloadSet {
+	workloads := make(WorkloadSet, n)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{Name: fmt.Sprintf("tiny-%d", i), CPURequirements: 1, MemoryRequirements: 1}
+	}
+	return workloads
+}
+
+func TestMaxWorkloadsPerVM_AllPackers(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D16s_v5", Family: "DSv5", VCpus: 16, MemoryGiB: 64, PricePerHour: 0.768}}
+	workloads := tinyWorkloads(20)
+	cfg := Config{MaxWorkloadsPerVM: 8}
+	for name, packer := range map[string]func() PackingResult{
+		"ffd":      func() PackingResult { return BinPackWorkloadsWithConfig(workloads, candidates, cfg) },
+		"quota":    func() PackingResult { return binPackWorkloadsWithQuota(workloads, candidates, cfg) },
+		"arrivals": func() PackingResult { return SimulateArrivals(workloads, candidates, cfg).Packing },
+	} {
+		result := packer()
+		if len(result.VMs) != 3 || len(result.Unpacked) != 0 {
+			t.Errorf("%s: expected 20 workloads on 3 VMs of at most 8, got %d VMs and %d unpacked", name, len(result.VMs), len(result.Unpacked))
+		}
+		for i, vm := range result.VMs {
+			if len(vm.Workloads) > 8 {
+				t.Errorf("%s: VM %d holds %d workloads, expected at most 8", name, i, len(vm.Workloads))
+			}
+		}
+	}
+	if result := BinPackWorkloadsWithConfig(workloads, candidates, Config{}); len(result.VMs) != 2 {
+		t.Errorf("expected 2 VMs without a cap, got %d", len(result.VMs))
+	}
+}
+
+func TestMaxWorkloadsPerVM_StricterMaxPods(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D16s_v5", Family: "DSv5", VCpus: 16, MemoryGiB: 64, PricePerHour: 0.768, MaxPods: 5}}
+	workloads := tinyWorkloads(20)
+	if result := BinPackWorkloadsWithConfig(workloads, candidates, Config{MaxWorkloadsPerVM: 8}); len(result.VMs) != 4 {
+		t.Errorf("expected the SKU's 5 max pods to apply over the cap of 8, got %d VMs", len(result.VMs))
+	}
+	if result := BinPackWorkloadsWithConfig(workloads, candidates, Config{MaxWorkloadsPerVM: 2}); len(result.VMs) != 10 {
+		t.Errorf("expected the cap of 2 to apply over the SKU's 5 max pods, got %d VMs", len(result.VMs))
+	}
+}
+
+func TestSimulate_WorkloadsPerVM(t *testing.T) {
+	skus := []AzureInstanceSpec{{Name: "Standard_D16s_v5", Family: "DSv5", VCpus: 16, MemoryGiB: 64, PricePerHour: 0.768}}
+	result, _, err := simulate(tinyWorkloads(20), skus, Config{MaxWorkloadsPerVM: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []WorkloadCount{{Workloads: 4, VMs: 1}, {Workloads: 8, VMs: 2}}
+	if !reflect.DeepEqual(result.WorkloadsPerVM, want) {
+		t.Errorf("expected %+v, got %+v", want, result.WorkloadsPerVM)
+	}
+	if err := ValidateMaxWorkloadsPerVM(-1); err == nil {
+		t.Error("expected an error for a negative cap")
+	}
+}
@@ -0,0 +1,72 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+/*
+Version is the version of the build, set at link time with
+
+	-ldflags "-X github.com/Azure/karpenter-provider-azure/pkg/resolver.Version=v1.2.3"
+
+Development builds leave it empty and report BuildVersion "dev".
+*/
+var Version string
+
+// GitRefEnv names the environment variable holding the git ref of the build, as set for the
+// e2e environment; RunMetadata records it when set.
+const GitRefEnv = "GIT_REF"
+
+// RunMetadata identifies the build and the machine that produced a run and when, so result
+// files collected from many runs can be told apart.
+type RunMetadata struct {
+	Version  string // BuildVersion of the build
+	GitRef   string `json:",omitempty"` // $GIT_REF, if set
+	Hostname string `json:",omitempty"`
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+}
+
+// BuildVersion returns Version, or "dev" when it was not set at link time.
+func BuildVersion() string {
+	if Version == "" {
+		return "dev"
+	}
+	return Version
+}
+
+// NewRunMetadata returns the metadata of a run on this host from start to end, in UTC.
+func NewRunMetadata(start, end time.Time) RunMetadata {
+	host, _ := os.Hostname()
+	return RunMetadata{
+		Version:  BuildVersion(),
+		GitRef:   os.Getenv(GitRefEnv),
+		Hostname: host,
+		Start:    start.UTC(),
+		End:      end.UTC(),
+		Duration: end.Sub(start),
+	}
+}
+
+// withMetadata returns run with the metadata of a run from start until now.
+func withMetadata(run SimulationRun, start time.Time) SimulationRun {
+	m := NewRunMetadata(start, time.Now())
+	run.Metadata = &m
+	return run
+}
+
+// String describes m on one line, e.g. "version v1.2.3 (git 1a2b3c4) on host-1, 2025-01-02T03:04:05Z to
+// 2025-01-02T03:04:07Z (2s)".
+func (m RunMetadata) String() string {
+	s := "version " + m.Version
+	if m.GitRef != "" {
+		s += fmt.Sprintf(" (git %s)", m.GitRef)
+	}
+	if m.Hostname != "" {
+		s += " on " + m.Hostname
+	}
+	return s + fmt.Sprintf(", %s to %s (%v)", m.Start.Format(time.RFC3339), m.End.Format(time.RFC3339), m.Duration.Round(time.Millisecond))
+}
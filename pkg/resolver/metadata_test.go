@@ -0,0 +1,35 @@
+package resolver
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRunMetadata(t *testing.T) {
+	defer func(v string) { Version = v }(Version)
+	t.Setenv(GitRefEnv, "1a2b3c4d")
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	Version = ""
+	m := NewRunMetadata(start, start.Add(1500*time.Millisecond))
+	host, _ := os.Hostname()
+	if m.Version != "dev" || m.GitRef != "1a2b3c4d" || m.Hostname != host || m.Duration != 1500*time.Millisecond {
+		t.Errorf("expected version dev, the git ref, hostname and duration, got %+v", m)
+	}
+	if m.Start.Location() != time.UTC || !m.Start.Equal(start) {
+		t.Errorf("expected the start time in UTC, got %v", m.Start)
+	}
+	want := "version dev (git 1a2b3c4d) on " + host + ", 2026-01-02T02:04:05Z to 2026-01-02T02:04:06Z (1.5s)"
+	if host == "" {
+		want = strings.Replace(want, " on ", "", 1)
+	}
+	if got := m.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	Version = "v1.4.0"
+	if m := NewRunMetadata(start, start); m.Version != "v1.4.0" {
+		t.Errorf("expected the linked version, got %q", m.Version)
+	}
+}
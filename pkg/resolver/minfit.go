@@ -0,0 +1,39 @@
+package resolver
+
+import "fmt"
+
+/*
+SelectionFit returns the share of vm the workload would use by itself, in [0,1]: the largest of
+its shares of vm's vCPUs, memory and GPUs. A workload requesting 1 of 32 vCPUs and 2 of 128 GiB
+uses 1/32 of the VM: the VM is 32x oversized for it. A VM with none of a requested resource
+counts as fully used, since it cannot be oversized in it.
+*/
+func SelectionFit(vm AzureInstanceSpec, workload WorkloadProfile) float64 {
+	fit := max(usedShare(float64(workload.CPURequirements), float64(vm.VCpus)), usedShare(workload.MemoryRequirements, vm.MemoryGiB))
+	return min(max(fit, usedShare(float64(workload.gpusRequired()), float64(vm.GPUCount))), 1.0)
+}
+
+// usedShare returns need/have, or 1 when need is positive and have is not.
+func usedShare(need, have float64) float64 {
+	switch {
+	case need <= 0:
+		return 0
+	case have <= 0:
+		return 1
+	}
+	return need / have
+}
+
+// poorFit reports whether vm, selected for workload, fits it below MinFit. Reserved capacity is
+// never a poor fit: it is paid for whether it is used or not.
+func (c Config) poorFit(vm AzureInstanceSpec, workload WorkloadProfile, reservation int) bool {
+	return c.MinFit > 0 && reservation < 0 && SelectionFit(vm, workload) < c.MinFit
+}
+
+// ValidateMinFit returns an error unless fit is a share in [0,1].
+func ValidateMinFit(fit float64) error {
+	if fit < 0 || fit > 1 {
+		return fmt.Errorf("minimum fit must be between 0 and 1, got %g", fit)
+	}
+	return nil
+}
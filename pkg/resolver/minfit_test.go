@@ -0,0 +1,76 @@
+package resolver
+
+import "testing"
+
+func TestSelectionFit(t *testing.T) {
+	vm := AzureInstanceSpec{VCpus: 32, MemoryGiB: 128, GPUCount: 4}
+	for _, tc := range []struct {
+		name     string
+		workload WorkloadProfile
+		want     float64
+	}{
+		{"cpu bound", WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2}, 1.0 / 32},
+		{"memory bound", WorkloadProfile{CPURequirements: 1, MemoryRequirements: 64}, 0.5},
+		{"gpu bound", WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2, GPURequirements: 4}, 1},
+		{"nothing requested", WorkloadProfile{}, 0},
+	} {
+		if got := SelectionFit(vm, tc.workload); got != tc.want {
+			t.Errorf("%s: expected %g, got %g", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestMinFitRejectsOversizedSelections(t *testing.T) {
+	// The only SKU left is 32x oversized for the small workload and a 1/2 fit for the large one
+	skus := []AzureInstanceSpec{{Name: "Standard_D32s_v5", Family: "DSv5", VCpus: 32, MemoryGiB: 128, PricePerHour: 1.536}}
+	small := WorkloadProfile{Name: "small", CPURequirements: 1, MemoryRequirements: 2}
+	large := WorkloadProfile{Name: "large", CPURequirements: 16, MemoryRequirements: 32}
+
+	for _, tc := range []struct {
+		minFit                 float64
+		smallAlone, bothPacked bool
+	}{
+		{0, true, true},
+		{1.0 / 32, true, true}, // the boundary is accepted
+		{0.04, false, true},    // once the large workload justifies the VM, the small one rides along
+		{0.5, false, true},
+		{0.51, false, false},
+	} {
+		for _, quota := range []QuotaMap{nil, {"DSv5": 64}} {
+			cfg := Config{MinFit: tc.minFit, Quota: quota}
+			for _, c := range []struct {
+				workloads WorkloadSet
+				packed    bool
+			}{{WorkloadSet{small}, tc.smallAlone}, {WorkloadSet{small, large}, tc.bothPacked}} {
+				result := BinPackWorkloadsWithConfig(c.workloads, skus, cfg)
+				if c.packed {
+					if len(result.Unpacked) != 0 {
+						t.Errorf("min fit %g, quota %v: expected %d workloads packed, got %+v", tc.minFit, quota, len(c.workloads), result.Unpacked)
+					}
+					continue
+				}
+				if len(result.VMs) != 0 || len(result.Unpacked) != len(c.workloads) {
+					t.Errorf("min fit %g, quota %v: expected %d workloads unpacked, got %+v", tc.minFit, quota, len(c.workloads), result)
+				}
+				for _, u := range result.Unpacked {
+					if u.Reason != ReasonPoorFit {
+						t.Errorf("min fit %g: expected reason %q, got %q", tc.minFit, ReasonPoorFit, u.Reason)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestValidateMinFit(t *testing.T) {
+	for _, fit := range []float64{0, 0.25, 1} {
+		if err := ValidateMinFit(fit); err != nil {
+			t.Errorf("ValidateMinFit(%g): %v", fit, err)
+		}
+	}
+	for _, fit := range []float64{-0.1, 1.5} {
+		if err := ValidateMinFit(fit); err == nil {
+			t.Errorf("ValidateMinFit(%g): expected an error", fit)
+		}
+	}
+}
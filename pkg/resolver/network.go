@@ -0,0 +1,89 @@
+package resolver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// bandwidthStep is one row of a bandwidth table: VMs with at least vcpus vCPUs get mbps.
+type bandwidthStep struct {
+	vcpus int
+	mbps  float64
+}
+
+/*
+bandwidthByGeneration approximates the documented expected network bandwidth of the
+general purpose and memory optimized series (Dv4/Ev4, Dv5/Ev5, ...) by VM generation.
+Bandwidth scales with VM size, so each table is a step function of the vCPU count.
+Generations not listed use perVCPUBandwidthMbps, which matches v3 and older sizes.
+*/
+var bandwidthByGeneration = map[int][]bandwidthStep{
+	4: {{2, 5000}, {4, 10000}, {8, 12500}, {32, 16000}, {48, 24000}, {64, 30000}},
+	5: {{2, 12500}, {32, 16000}, {48, 24000}, {64, 30000}, {96, 35000}},
+	6: {{2, 12500}, {32, 16000}, {48, 24000}, {64, 30000}, {96, 40000}},
+}
+
+const (
+	// perVCPUBandwidthMbps is the bandwidth per vCPU of SKUs without a generation table.
+	perVCPUBandwidthMbps = 500
+	// maxDerivedBandwidthMbps caps the bandwidth derived with perVCPUBandwidthMbps.
+	maxDerivedBandwidthMbps = 30000
+)
+
+/*
+ExpectedBandwidthMbps returns the expected NIC bandwidth of vm: NetworkBandwidthMbps when the
+SKU data provides it, otherwise an estimate from the VM generation and size (see
+bandwidthByGeneration).
+*/
+func ExpectedBandwidthMbps(vm AzureInstanceSpec) float64 {
+	if vm.NetworkBandwidthMbps > 0 {
+		return vm.NetworkBandwidthMbps
+	}
+	if steps, ok := bandwidthByGeneration[vmGeneration(vm)]; ok {
+		var mbps float64
+		for _, s := range steps {
+			if vm.VCpus >= s.vcpus {
+				mbps = s.mbps
+			}
+		}
+		return mbps
+	}
+	mbps := float64(vm.VCpus) * perVCPUBandwidthMbps
+	if mbps > maxDerivedBandwidthMbps {
+		mbps = maxDerivedBandwidthMbps
+	}
+	return mbps
+}
+
+// vmGeneration returns the version of a VM size, e.g. 5 for "Standard_D4s_v5" or family
+// "Dsv5", or 0 when neither the name nor the family carries one.
+func vmGeneration(vm AzureInstanceSpec) int {
+	if i := strings.LastIndex(vm.Name, "_v"); i >= 0 {
+		if v, err := strconv.Atoi(vm.Name[i+2:]); err == nil {
+			return v
+		}
+	}
+	if i := strings.LastIndex(vm.Family, "v"); i >= 0 {
+		if v, err := strconv.Atoi(vm.Family[i+1:]); err == nil {
+			return v
+		}
+	}
+	return 0
+}
+
+// FilterByBandwidth rejects instance types whose expected network bandwidth is below the
+// workload's NetworkRequirementsMbps.
+func FilterByBandwidth(inst AzureInstanceSpec, workload WorkloadProfile) bool {
+	if workload.NetworkRequirementsMbps <= 0 {
+		return true
+	}
+	return fitsWithin(workload.NetworkRequirementsMbps, ExpectedBandwidthMbps(inst))
+}
+
+// networkFit returns a value in [0,1] for how well the VM's expected bandwidth covers the workload's.
+func networkFit(vm AzureInstanceSpec, workload WorkloadProfile) float64 {
+	if workload.NetworkRequirementsMbps <= 0 {
+		return 1.0
+	}
+	return min(ExpectedBandwidthMbps(vm)/workload.NetworkRequirementsMbps, 1.0)
+}
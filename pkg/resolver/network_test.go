@@ -0,0 +1,78 @@
+package resolver
+
+import "testing"
+
+func TestExpectedBandwidthMbps(t *testing.T) {
+	for _, tc := range []struct {
+		vm   AzureInstanceSpec
+		want float64
+	}{
+		{AzureInstanceSpec{Name: "Standard_D2s_v3", VCpus: 2}, 1000},
+		{AzureInstanceSpec{Name: "Standard_D64s_v3", VCpus: 64}, 30000},
+		{AzureInstanceSpec{Name: "Standard_D4s_v4", VCpus: 4}, 10000},
+		{AzureInstanceSpec{Name: "Standard_D2s_v5", VCpus: 2}, 12500},
+		{AzureInstanceSpec{Name: "Standard_D48s_v5", VCpus: 48}, 24000},
+		{AzureInstanceSpec{Family: "Esv5", VCpus: 96}, 35000},
+		{AzureInstanceSpec{Name: "Standard_D2s_v5", VCpus: 2, NetworkBandwidthMbps: 3000}, 3000},
+	} {
+		if got := ExpectedBandwidthMbps(tc.vm); got != tc.want {
+			t.Errorf("ExpectedBandwidthMbps(%s %s, %d vCPUs) = %.0f, want %.0f", tc.vm.Name, tc.vm.Family, tc.vm.VCpus, got, tc.want)
+		}
+	}
+}
+
+func TestBandwidthRequirementSkipsSmallSKUs(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D2s_v3", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1, AvailabilityZones: []string{"1"}},
+		{Name: "Standard_D8s_v3", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.4, AvailabilityZones: []string{"1"}},
+		{Name: "Standard_D32s_v3", VCpus: 32, MemoryGiB: 128, PricePerHour: 1.6, AvailabilityZones: []string{"1"}},
+	}
+	workload := WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2, NetworkRequirementsMbps: 10000}
+	for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyIOIntensive} {
+		if got := SelectBestInstanceWithStrategy(candidates, workload, strategy); got.Name != "Standard_D32s_v3" {
+			t.Errorf("%s: expected the only SKU with 10 Gbps, got %q", strategy, got.Name)
+		}
+	}
+	if got := SelectBestInstance(candidates, WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2}); got.Name != "Standard_D2s_v3" {
+		t.Errorf("expected the cheapest SKU without a bandwidth requirement, got %q", got.Name)
+	}
+}
+
+func TestNetworkFitFavorsBandwidth(t *testing.T) {
+	workload := WorkloadProfile{CPURequirements: 2, MemoryRequirements: 4, NetworkRequirementsMbps: 12500}
+	older := AzureInstanceSpec{Name: "Standard_D2s_v3", VCpus: 2, MemoryGiB: 8}
+	newer := AzureInstanceSpec{Name: "Standard_D2s_v5", VCpus: 2, MemoryGiB: 8}
+	if networkFit(newer, workload) != 1 || networkFit(older, workload) >= 1 {
+		t.Errorf("expected a full fit only for the v5 SKU, got v3 %.2f, v5 %.2f", networkFit(older, workload), networkFit(newer, workload))
+	}
+	if ScoreInstance(newer, workload, StrategyIOIntensive) <= ScoreInstance(older, workload, StrategyIOIntensive) {
+		t.Error("expected the IO strategy to score the higher-bandwidth SKU higher at equal price")
+	}
+}
+
+func TestPackingNeverOversubscribesBandwidth(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D16s_v5", VCpus: 16, MemoryGiB: 64, PricePerHour: 0.8},
+	}
+	workloads := make(WorkloadSet, 6)
+	for i := range workloads {
+		// Six 5 Gbps workloads fit the 16 vCPUs and 64 GiB of one VM, but not its 12.5 Gbps.
+		workloads[i] = WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2, NetworkRequirementsMbps: 5000}
+	}
+	for _, name := range []string{"ffd", "quota"} {
+		pack, _ := PackingAlgorithm(name)
+		result := pack(workloads, candidates, Config{})
+		if len(result.VMs) != 3 || len(result.Unpacked) != 0 {
+			t.Fatalf("%s: expected 3 VMs of two workloads each, got %d VMs and %d unpacked", name, len(result.VMs), len(result.Unpacked))
+		}
+		for i, vm := range result.VMs {
+			var mbps float64
+			for _, w := range vm.Workloads {
+				mbps += w.NetworkRequirementsMbps
+			}
+			if mbps > ExpectedBandwidthMbps(vm.InstanceType) {
+				t.Errorf("%s: VM %d oversubscribed: %.0f Mbps on %.0f", name, i, mbps, ExpectedBandwidthMbps(vm.InstanceType))
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package resolver
+
+import "fmt"
+
+// NodeClassConstraints are the AKSNodeClass settings that constrain simulated nodes (see
+// kube.ConstraintsFromAKSNodeClass).
+type NodeClassConstraints struct {
+	// OSDiskSizeGB is the OS disk size. Workloads requiring an ephemeral OS disk only get
+	// instance types whose cache or temp disk holds it. 0 does not check the size.
+	OSDiskSizeGB int
+	// MaxPods is the pod limit of every node. Workloads whose MaxPods capability exceeds
+	// it are not packed. 0 leaves the limit to the instance types.
+	MaxPods int
+	// ImageFamily is the node image, e.g. "Ubuntu2204" or "AzureLinux". It is carried for
+	// reports; no simulated instance property depends on it.
+	ImageFamily string
+}
+
+// ephemeralOSDiskFilter rejects, for workloads requiring an ephemeral OS disk, instance types
+// whose cache or temp disk is smaller than sizeGB. SKUs that do not declare it pass.
+func ephemeralOSDiskFilter(sizeGB int) FilterFunc {
+	return func(inst AzureInstanceSpec, workload WorkloadProfile) bool {
+		return !workload.RequireEphemeralOS || inst.MaxEphemeralOSDiskGB <= 0 || inst.MaxEphemeralOSDiskGB >= float64(sizeGB)
+	}
+}
+
+// nodeClassMaxPodsFilter is FilterByMaxPods against the node class's pod limit.
+func nodeClassMaxPodsFilter(maxPods int) FilterFunc {
+	return func(_ AzureInstanceSpec, workload WorkloadProfile) bool {
+		var req int
+		if val, ok := workload.Capabilities["MaxPods"]; ok {
+			if _, err := fmt.Sscanf(val, "%d", &req); err == nil {
+				return req <= maxPods
+			}
+		}
+		return true
+	}
+}
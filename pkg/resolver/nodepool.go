@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"sort"
+)
+
+/*
+SimulatedNodePool models a karpenter NodePool for simulation purposes.
+
+Real clusters usually run several NodePools (general purpose, GPU, spot, ...), each with its
+own constraints. SimulateNodePools routes every workload to the highest-weight pool that
+accepts it and packs each pool independently with its own strategy and limits.
+*/
+type SimulatedNodePool struct {
+	Name string
+	// Matches reports whether a workload may be scheduled onto this pool; nil accepts every workload.
+	Matches func(WorkloadProfile) bool
+	// InstanceFilter restricts the instance types this pool may provision; nil allows all candidates.
+	InstanceFilter func(AzureInstanceSpec) bool
+	// Strategy is the selection strategy used when packing this pool.
+	Strategy SelectionStrategy
+	// Weight orders pools like NodePool.spec.weight: higher weights are tried first.
+	Weight int
+	// Limits caps the total capacity the pool may provision; the zero value means unlimited.
+	Limits Limits
+}
+
+// NodePoolResult is the outcome of packing the workloads routed to one pool.
+type NodePoolResult struct {
+	Pool     string
+	Routed   int                // workloads routed to the pool
+	Packing  PackingResult      // VMs provisioned by the pool
+	Summary  SimulationResult   // summary of Packing
+	Unpacked []UnpackedWorkload // routed workloads the pool could not pack, e.g. due to its limits
+}
+
+// NodePoolSimulation aggregates a multi-pool simulation.
+type NodePoolSimulation struct {
+	Pools     []NodePoolResult // in the order the pools were given
+	Overall   SimulationResult // summary across all pools
+	Unmatched WorkloadSet      // workloads accepted by no pool
+}
+
+/*
+SimulateNodePools routes each workload to the highest-weight pool whose Matches accepts it
+(pools with equal weight keep their input order), packs every pool with its strategy, instance
+filter and limits, and aggregates the results per pool and overall. VM IDs are prefixed with
+the pool name, e.g. "gpu/vm-0001".
+*/
+func SimulateNodePools(pools []SimulatedNodePool, workloads WorkloadSet, candidates []AzureInstanceSpec) NodePoolSimulation {
+	order := make([]int, len(pools))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return pools[order[i]].Weight > pools[order[j]].Weight
+	})
+
+	var sim NodePoolSimulation
+	routed := make([]WorkloadSet, len(pools))
+	for _, w := range workloads {
+		matched := false
+		for _, i := range order {
+			if pools[i].Matches == nil || pools[i].Matches(w) {
+				routed[i] = append(routed[i], w)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			sim.Unmatched = append(sim.Unmatched, w)
+		}
+	}
+
+	var all []PackedVM
+	for i, pool := range pools {
+		poolCandidates := candidates
+		if pool.InstanceFilter != nil {
+			poolCandidates = nil
+			for _, c := range candidates {
+				if pool.InstanceFilter(c) {
+					poolCandidates = append(poolCandidates, c)
+				}
+			}
+		}
+		cfg := Config{Strategy: pool.Strategy, Limits: pool.Limits}
+		packing := BinPackWorkloadsWithConfig(routed[i], poolCandidates, cfg)
+		for j := range packing.VMs {
+			// Qualify the IDs so the VMs of different pools stay apart in Overall.
+			packing.VMs[j].ID = pool.Name + "/" + packing.VMs[j].ID
+		}
+		sim.Pools = append(sim.Pools, NodePoolResult{
+			Pool:     pool.Name,
+			Routed:   len(routed[i]),
+			Packing:  packing,
+			Summary:  cfg.Summarize(packing),
+			Unpacked: packing.Unpacked,
+		})
+		all = append(all, packing.VMs...)
+	}
+	sim.Overall = NewSimulationResult(PackingResult{VMs: all})
+	return sim
+}
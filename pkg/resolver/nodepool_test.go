@@ -0,0 +1,98 @@
+package resolver
+
+import (
+	"testing"
+)
+
+func nodePoolFixture() []AzureInstanceSpec {
+	return []AzureInstanceSpec{
+		{Name: "Standard_D4_v3", Family: "Dv3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		{Name: "Standard_D8_v3", Family: "Dv3", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.4},
+		{Name: "Standard_NC6", Family: "NC", VCpus: 6, MemoryGiB: 56, GPUCount: 1, GPUType: "K80", PricePerHour: 0.9},
+	}
+}
+
+func isGPUWorkload(w WorkloadProfile) bool { return w.GPURequirements > 0 }
+
+func TestSimulateNodePools_RoutingByWeight(t *testing.T) {
+	pools := []SimulatedNodePool{
+		{Name: "general", Weight: 10, InstanceFilter: func(vm AzureInstanceSpec) bool { return vm.GPUCount == 0 }},
+		{Name: "gpu", Weight: 50, Matches: isGPUWorkload, InstanceFilter: func(vm AzureInstanceSpec) bool { return vm.GPUCount > 0 }},
+		{Name: "spot", Weight: 100, Matches: func(w WorkloadProfile) bool { return w.RequireSpot }},
+	}
+	workloads := WorkloadSet{
+		{CPURequirements: 2, MemoryRequirements: 8},
+		{CPURequirements: 2, MemoryRequirements: 8, RequireSpot: true},
+		{CPURequirements: 4, MemoryRequirements: 16, GPURequirements: 1},
+		// Matches both gpu and spot: spot has the higher weight.
+		{CPURequirements: 4, MemoryRequirements: 16, GPURequirements: 1, RequireSpot: true},
+	}
+	sim := SimulateNodePools(pools, workloads, nodePoolFixture())
+
+	routed := map[string]int{}
+	for _, p := range sim.Pools {
+		routed[p.Pool] = p.Routed
+	}
+	if routed["general"] != 1 || routed["gpu"] != 1 || routed["spot"] != 2 {
+		t.Errorf("unexpected routing: %v", routed)
+	}
+	if sim.Pools[0].Pool != "general" || sim.Pools[2].Pool != "spot" {
+		t.Errorf("expected pool results in input order, got %s, %s, %s", sim.Pools[0].Pool, sim.Pools[1].Pool, sim.Pools[2].Pool)
+	}
+	for _, vm := range sim.Pools[1].Packing.VMs {
+		if vm.InstanceType.GPUCount == 0 {
+			t.Errorf("gpu pool provisioned non-GPU instance %s", vm.InstanceType.Name)
+		}
+		if vm.ID != "gpu/vm-0001" {
+			t.Errorf("expected the gpu pool's VM to be gpu/vm-0001, got %q", vm.ID)
+		}
+	}
+	for _, vm := range sim.Pools[0].Packing.VMs {
+		if vm.InstanceType.GPUCount > 0 {
+			t.Errorf("general pool provisioned GPU instance %s", vm.InstanceType.Name)
+		}
+	}
+	total := 0
+	for _, p := range sim.Pools {
+		total += p.Summary.VMsUsed
+	}
+	if sim.Overall.VMsUsed != total {
+		t.Errorf("expected overall VMs %d to equal the per-pool sum %d", sim.Overall.VMsUsed, total)
+	}
+	if len(sim.Unmatched) != 0 {
+		t.Errorf("expected no unmatched workloads, got %d", len(sim.Unmatched))
+	}
+}
+
+func TestSimulateNodePools_Unmatched(t *testing.T) {
+	pools := []SimulatedNodePool{{Name: "gpu", Matches: isGPUWorkload}}
+	workloads := WorkloadSet{{CPURequirements: 2, MemoryRequirements: 8}}
+	sim := SimulateNodePools(pools, workloads, nodePoolFixture())
+	if len(sim.Unmatched) != 1 || sim.Overall.VMsUsed != 0 {
+		t.Errorf("expected the CPU workload to match no pool, got %d unmatched and %d VMs", len(sim.Unmatched), sim.Overall.VMsUsed)
+	}
+}
+
+func TestSimulateNodePools_LimitsEnforced(t *testing.T) {
+	pools := []SimulatedNodePool{{
+		Name:           "general",
+		InstanceFilter: func(vm AzureInstanceSpec) bool { return vm.Name == "Standard_D4_v3" },
+		Limits:         Limits{CPU: 8},
+	}}
+	workloads := WorkloadSet{
+		{CPURequirements: 4, MemoryRequirements: 16},
+		{CPURequirements: 4, MemoryRequirements: 16},
+		{CPURequirements: 4, MemoryRequirements: 16},
+	}
+	sim := SimulateNodePools(pools, workloads, nodePoolFixture())
+	pool := sim.Pools[0]
+	if pool.Summary.VMsUsed != 2 {
+		t.Errorf("expected the 8 vCPU limit to allow 2 VMs, got %d", pool.Summary.VMsUsed)
+	}
+	if len(pool.Unpacked) != 1 || pool.Unpacked[0].Reason != ReasonCPULimitExceeded {
+		t.Errorf("expected 1 workload left unpacked by the CPU limit, got %+v", pool.Unpacked)
+	}
+	if pool.Summary.LimitCPUUtil != 100 {
+		t.Errorf("expected the pool to use 100%% of its CPU limit, got %v", pool.Summary.LimitCPUUtil)
+	}
+}
@@ -0,0 +1,25 @@
+package resolver
+
+/*
+BinPackWorkloadsOnline packs workloads strictly in the order given, as a controller sees them
+arrive: each goes onto the first open VM with room for it, in the order the VMs were opened,
+or onto a new VM of the best instance type for it. Unlike BinPackWorkloadsWithConfig it never
+sorts, so comparing the two separates what offline sorting gains from what selection does.
+
+It adds all workloads to one Packer, which is SimulateArrivals without preemption, a warm pool
+or provisioning failures, and shares its limits: headroom and capacity reservations are not
+modelled.
+*/
+func BinPackWorkloadsOnline(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) PackingResult {
+	if len(candidates) == 0 {
+		var result PackingResult
+		for _, w := range workloads {
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: w, Reason: ReasonNoInstanceTypes})
+		}
+		return result
+	}
+	cfg.Preemption, cfg.WarmPool, cfg.Availability = false, nil, nil
+	p := newPacker(candidates, cfg)
+	p.AddWorkloads(workloads) // capacity errors are reported as unpacked workloads
+	return p.Result()
+}
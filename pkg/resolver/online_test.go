@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// sizedWorkloads returns workloads of the given vCPUs, named after their position, with 2 GiB
+// per vCPU.
+func sizedWorkloads(cpus ...int) WorkloadSet {
+	workloads := make(WorkloadSet, len(cpus))
+	for i, c := range cpus {
+		workloads[i] = WorkloadProfile{Name: fmt.Sprintf("w%d", i), CPURequirements: c, MemoryRequirements: float64(2 * c)}
+	}
+	return workloads
+}
+
+func TestBinPackWorkloadsOnline_KeepsArrivalOrder(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	workloads := sizedWorkloads(1, 3, 1, 2, 2, 1, 3)
+	result := BinPackWorkloadsOnline(workloads, candidates, Config{})
+	if len(result.Unpacked) != 0 {
+		t.Fatalf("expected every workload packed, got %d unpacked", len(result.Unpacked))
+	}
+	// VMs open in arrival order and each holds its workloads in arrival order, so the first
+	// workload of every VM arrived later than the first of the previous VM.
+	position := make(map[string]int, len(workloads))
+	for i, w := range workloads {
+		position[w.Name] = i
+	}
+	prevFirst := -1
+	for i, vm := range result.VMs {
+		for j := 1; j < len(vm.Workloads); j++ {
+			if position[vm.Workloads[j].Name] < position[vm.Workloads[j-1].Name] {
+				t.Errorf("VM %d holds %s before %s", i, vm.Workloads[j-1].Name, vm.Workloads[j].Name)
+			}
+		}
+		if first := position[vm.Workloads[0].Name]; first <= prevFirst {
+			t.Errorf("VM %d opened for %s, which arrived before the first workload of VM %d", i, vm.Workloads[0].Name, i-1)
+		} else {
+			prevFirst = first
+		}
+	}
+	// First fit: w0+w1, w2+w3+w5, w4, w6.
+	want := [][]string{{"w0", "w1"}, {"w2", "w3", "w5"}, {"w4"}, {"w6"}}
+	var got [][]string
+	for _, vm := range result.VMs {
+		var names []string
+		for _, w := range vm.Workloads {
+			names = append(names, w.Name)
+		}
+		got = append(got, names)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected VMs holding %v, got %v", want, got)
+	}
+}
+
+func TestBinPackWorkloadsOnline_VersusFFD(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	// Small workloads first leave gaps a sorted packing fills with them instead.
+	workloads := sizedWorkloads(1, 1, 3, 3)
+	online := BinPackWorkloadsOnline(workloads, candidates, Config{})
+	ffd := BinPackWorkloadsWithConfig(workloads, candidates, Config{})
+	if len(online.VMs) != 3 || len(ffd.VMs) != 2 {
+		t.Errorf("expected 3 VMs online and 2 with FFD, got %d and %d", len(online.VMs), len(ffd.VMs))
+	}
+	if pack, ok := PackingAlgorithm("online"); !ok || len(pack(workloads, candidates, Config{}).VMs) != 3 {
+		t.Error("expected the online packer registered as \"online\"")
+	}
+}
+
+func TestBinPackWorkloadsOnline_NoCandidates(t *testing.T) {
+	result := BinPackWorkloadsOnline(sizedWorkloads(1, 2), nil, Config{})
+	if len(result.VMs) != 0 || len(result.Unpacked) != 2 || result.Unpacked[0].Reason != ReasonNoInstanceTypes {
+		t.Errorf("expected both workloads unpacked with %q, got %+v", ReasonNoInstanceTypes, result.Unpacked)
+	}
+}
+
+func TestSimulate_Algorithm(t *testing.T) {
+	skus := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	workloads := sizedWorkloads(1, 1, 3, 3)
+	for algorithm, want := range map[string]int{"": 2, "ffd": 2, "online": 3} {
+		result, _, err := simulate(workloads, skus, Config{Algorithm: algorithm, progress: io.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.VMsUsed != want {
+			t.Errorf("algorithm %q: expected %d VMs, got %d", algorithm, want, result.VMsUsed)
+		}
+	}
+	if err := ValidatePackingAlgorithm("best-fit"); err == nil {
+		t.Error("expected an unknown algorithm to be rejected")
+	}
+}
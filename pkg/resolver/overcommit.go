@@ -0,0 +1,134 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+OvercommitPolicy is how the limits of workloads (LimitCPU and LimitMemory) bound packing (see
+Config.Overcommit). Kubernetes schedules pods by their requests alone, so nodes packed full by
+requests may be overcommitted several times over by limits, and pods bursting up to them
+contend for CPU or are OOM-killed. A workload without a limit counts its request. The zero
+value is OvercommitRequests.
+*/
+type OvercommitPolicy struct {
+	// Limits sizes workloads by their limits where those exceed their requests, so no VM is
+	// overcommitted.
+	Limits bool
+	// MaxLimitRatio caps the limits of the workloads on a VM at this multiple of its vCPUs and
+	// memory, e.g. 2, while workloads are still sized by their requests. 0 leaves limits uncapped.
+	MaxLimitRatio float64
+}
+
+// OvercommitRequests packs by requests alone, OvercommitLimits by limits.
+var (
+	OvercommitRequests = OvercommitPolicy{}
+	OvercommitLimits   = OvercommitPolicy{Limits: true}
+)
+
+// OvercommitCapped packs by requests with the limits on each VM capped at ratio times its capacity.
+func OvercommitCapped(ratio float64) OvercommitPolicy {
+	return OvercommitPolicy{MaxLimitRatio: ratio}
+}
+
+// ParseOvercommitPolicy parses the --overcommit and --limit-ratio flags: "requests", "limits"
+// or "capped", and the limit ratio of the capped policy.
+func ParseOvercommitPolicy(policy string, ratio float64) (OvercommitPolicy, error) {
+	switch policy {
+	case "", "requests", "limits":
+		if ratio != 0 {
+			return OvercommitPolicy{}, fmt.Errorf("a limit ratio only applies to the capped policy")
+		}
+		if policy == "limits" {
+			return OvercommitLimits, nil
+		}
+		return OvercommitRequests, nil
+	case "capped":
+		if !(ratio >= 1 && ratio <= 100) {
+			return OvercommitPolicy{}, fmt.Errorf("limit ratio must be between 1 and 100, got %v", ratio)
+		}
+		return OvercommitCapped(ratio), nil
+	}
+	return OvercommitPolicy{}, fmt.Errorf("unknown overcommit policy %q, expected requests, limits or capped", policy)
+}
+
+// String returns "requests", "limits" or e.g. "capped at 2x".
+func (p OvercommitPolicy) String() string {
+	switch {
+	case p.Limits:
+		return "limits"
+	case p.capped():
+		return fmt.Sprintf("capped at %gx", p.MaxLimitRatio)
+	}
+	return "requests"
+}
+
+// capped reports whether p caps the limits on each VM.
+func (p OvercommitPolicy) capped() bool {
+	return !p.Limits && p.MaxLimitRatio > 0
+}
+
+/*
+Apply returns workloads sized by p. Under OvercommitLimits a workload's CPU requirement becomes
+its LimitCPU rounded up to whole vCPUs and its memory requirement its LimitMemory, where they
+exceed the requests. Under the other policies workloads itself is returned.
+*/
+func (p OvercommitPolicy) Apply(workloads WorkloadSet) WorkloadSet {
+	if !p.Limits {
+		return workloads
+	}
+	sized := make(WorkloadSet, len(workloads))
+	for i, w := range workloads {
+		cpu, mem := limitsOf(w)
+		w.CPURequirements = int(math.Ceil(cpu - capacityEpsilon))
+		w.MemoryRequirements = mem
+		sized[i] = w
+	}
+	return sized
+}
+
+// filter rejects instance types whose capped limits cannot hold the limits of a workload by itself.
+func (p OvercommitPolicy) filter(inst AzureInstanceSpec, w WorkloadProfile) bool {
+	cpu, mem := limitsOf(w)
+	return fitsWithin(cpu, p.MaxLimitRatio*float64(inst.VCpus)) && fitsWithin(mem, p.MaxLimitRatio*inst.MemoryGiB)
+}
+
+// limitsOf returns the vCPU and GiB limits of w, its requests where it has no higher limit.
+func limitsOf(w WorkloadProfile) (cpu, mem float64) {
+	return max(float64(w.CPURequirements), w.LimitCPU), max(w.MemoryRequirements, w.LimitMemory)
+}
+
+/*
+LimitOvercommit returns the sum of the limits of the workloads on vm divided by its vCPUs and
+memory, e.g. 2 when they may burst to twice its capacity. Workloads without a limit count their
+request. It returns 0, 0 when none of them has a limit.
+*/
+func LimitOvercommit(vm PackedVM) (cpu, mem float64) {
+	var limited bool
+	var cpuSum, memSum float64
+	for _, w := range vm.Workloads {
+		limited = limited || w.LimitCPU > 0 || w.LimitMemory > 0
+		c, m := limitsOf(w)
+		cpuSum += c
+		memSum += m
+	}
+	if !limited {
+		return 0, 0
+	}
+	if vm.InstanceType.VCpus > 0 {
+		cpu = cpuSum / float64(vm.InstanceType.VCpus)
+	}
+	if vm.InstanceType.MemoryGiB > 0 {
+		mem = memSum / vm.InstanceType.MemoryGiB
+	}
+	return cpu, mem
+}
+
+// peakLimitOvercommit returns the highest LimitOvercommitCPU and LimitOvercommitMem of vms.
+func peakLimitOvercommit(vms []VMDetail) (cpu, mem float64) {
+	for _, vm := range vms {
+		cpu, mem = max(cpu, vm.LimitOvercommitCPU), max(mem, vm.LimitOvercommitMem)
+	}
+	return cpu, mem
+}
@@ -0,0 +1,94 @@
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+// bursting returns workloads requesting 1 vCPU and 2 GiB each, limited at twice that.
+func bursting(n int) WorkloadSet {
+	workloads := make(WorkloadSet, n)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{Name: fmt.Sprintf("w%d", i), CPURequirements: 1, MemoryRequirements: 2, LimitCPU: 2, LimitMemory: 4}
+	}
+	return workloads
+}
+
+func TestOvercommitPolicies(t *testing.T) {
+	skus := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	for _, tc := range []struct {
+		policy  OvercommitPolicy
+		vms     int
+		peakCPU float64
+	}{
+		// 4 workloads by requests fill a VM and may burst to twice its vCPUs
+		{OvercommitRequests, 2, 2},
+		// 2 workloads by limits fill a VM, which is never overcommitted
+		{OvercommitLimits, 4, 1},
+		// limits of 6 vCPUs admit 3 workloads per VM
+		{OvercommitCapped(1.5), 3, 1.5},
+	} {
+		cfg := Config{Overcommit: tc.policy, progress: io.Discard}
+		result, _, err := simulate(bursting(8), skus, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.VMsUsed != tc.vms || result.Unpacked != 0 {
+			t.Errorf("%s: expected %d VMs and nothing unpacked, got %d VMs and %d unpacked", tc.policy, tc.vms, result.VMsUsed, result.Unpacked)
+		}
+		if cpu, mem := peakLimitOvercommit(result.VMs); cpu != tc.peakCPU || mem != tc.peakCPU/2 {
+			t.Errorf("%s: expected a peak limit overcommit of %gx CPU and %gx memory, got %g and %g", tc.policy, tc.peakCPU, tc.peakCPU/2, cpu, mem)
+		}
+	}
+
+	// The packers enforce the cap without the simulation functions.
+	packed := BinPackWorkloadsWithConfig(bursting(8), skus, Config{Overcommit: OvercommitCapped(1.5)})
+	for _, vm := range packed.VMs {
+		if cpu, _ := LimitOvercommit(vm); cpu > 1.5 {
+			t.Errorf("expected at most 1.5x CPU limits on %s, got %g", vm.ID, cpu)
+		}
+	}
+	if cpu, mem := LimitOvercommit(PackedVM{InstanceType: skus[0], Workloads: WorkloadSet{{CPURequirements: 2, MemoryRequirements: 4}}}); cpu != 0 || mem != 0 {
+		t.Errorf("expected no overcommit ratio for workloads without limits, got %g and %g", cpu, mem)
+	}
+}
+
+func TestOvercommitCapped_OversizedLimits(t *testing.T) {
+	skus := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192},
+		{Name: "Standard_D8s_v5", Family: "DSv5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.384},
+	}
+	w := WorkloadProfile{Name: "spiky", CPURequirements: 1, MemoryRequirements: 2, LimitCPU: 12}
+	cfg := Config{Overcommit: OvercommitCapped(2)}
+	if vm, _ := selectWithConfig(skus, w, cfg.forRun()); vm.Name != "Standard_D8s_v5" {
+		t.Errorf("expected the 4 vCPU SKU to be filtered out for limits above twice its vCPUs, got %q", vm.Name)
+	}
+	doomed := precheck(WorkloadSet{w}, skus[:1], cfg.filters())
+	if len(doomed) != 1 || doomed[0].Constraint != "overcommit" {
+		t.Errorf("expected the overcommit cap to doom the workload on the 4 vCPU SKU alone, got %v", doomed)
+	}
+}
+
+func TestParseOvercommitPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy string
+		ratio  float64
+		want   OvercommitPolicy
+		err    bool
+	}{
+		{"", 0, OvercommitRequests, false},
+		{"requests", 0, OvercommitRequests, false},
+		{"limits", 0, OvercommitLimits, false},
+		{"capped", 2, OvercommitCapped(2), false},
+		{"capped", 0, OvercommitPolicy{}, true},
+		{"capped", 0.5, OvercommitPolicy{}, true},
+		{"limits", 2, OvercommitPolicy{}, true},
+		{"burst", 0, OvercommitPolicy{}, true},
+	} {
+		got, err := ParseOvercommitPolicy(tc.policy, tc.ratio)
+		if (err != nil) != tc.err || got != tc.want {
+			t.Errorf("ParseOvercommitPolicy(%q, %g) = %+v, %v; expected %+v, error %v", tc.policy, tc.ratio, got, err, tc.want, tc.err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrCapacityExhausted is wrapped by Packer.AddWorkloads when Config.Limits or Config.Quota
+// left workloads of the batch unpacked, so callers can stop feeding more.
+var ErrCapacityExhausted = errors.New("capacity exhausted")
+
+/*
+Packer packs workloads batch by batch in the order they are added, as BinPackWorkloadsOnline
+packs them all at once: each goes onto the first open VM with room for it or onto a new VM of
+the best instance type. Adding the batches of a workload set one after another gives the same
+result as packing the whole set, so very large traces can be packed in chunks, inspected with
+Result between chunks, and checkpointed to resume later.
+
+Preemption, warm pools and provisioning failures reorder or revisit earlier placements and are
+not supported; use SimulateArrivals for them.
+*/
+type Packer struct {
+	sim arrivalSim
+}
+
+// NewPacker returns a Packer of candidates under cfg, with no VM open yet.
+func NewPacker(candidates []AzureInstanceSpec, cfg Config) (*Packer, error) {
+	if cfg.Preemption || cfg.WarmPool != nil || cfg.Availability != nil {
+		return nil, fmt.Errorf("the packer does not support preemption, warm pools or availability models")
+	}
+	return newPacker(candidates, cfg), nil
+}
+
+// newPacker is NewPacker for a cfg without the options the packer does not support.
+func newPacker(candidates []AzureInstanceSpec, cfg Config) *Packer {
+	cfg = cfg.forRun()
+	return &Packer{sim: arrivalSim{
+		candidates: candidates,
+		cfg:        cfg,
+		limits:     limitTracker{limits: cfg.Limits},
+		quota:      quotaTracker{quota: cfg.Quota, families: make(map[string]int)},
+	}}
+}
+
+// AddWorkloads packs batch after the workloads already added. Workloads that cannot be placed
+// are unpacked as in BinPackWorkloadsOnline; when limits or quota are the cause, the error
+// wraps ErrCapacityExhausted. Either way the whole batch has been packed.
+func (p *Packer) AddWorkloads(batch WorkloadSet) error {
+	s := &p.sim
+	exhausted := 0
+	for _, w := range batch {
+		s.now = max(s.now, w.ArrivalSeconds)
+		reason := s.place(w)
+		if reason == "" {
+			continue
+		}
+		if capacityExhausted(reason) {
+			exhausted++
+		}
+		s.result.Unpacked = append(s.result.Unpacked, UnpackedWorkload{Workload: w, Reason: reason})
+	}
+	if exhausted > 0 {
+		return fmt.Errorf("%w: %d of %d workloads of the batch unpacked", ErrCapacityExhausted, exhausted, len(batch))
+	}
+	return nil
+}
+
+// Result returns the packing of the workloads added so far. Later batches do not change it.
+func (p *Packer) Result() PackingResult {
+	s := &p.sim
+	result := PackingResult{Unpacked: s.result.Unpacked[:len(s.result.Unpacked):len(s.result.Unpacked)]}
+	for _, vm := range s.vms {
+		vm.Workloads = vm.Workloads[:len(vm.Workloads):len(vm.Workloads)]
+		result.VMs = append(result.VMs, vm.PackedVM)
+	}
+	if len(s.cfg.Quota) > 0 {
+		result.QuotaUsage = s.quota.usage()
+	}
+	return result
+}
+
+// packerCheckpoint is the serialized state of a Packer.
+type packerCheckpoint struct {
+	Version  int
+	Now      float64 `json:",omitempty"`
+	VMs      []checkpointVM
+	Unpacked []UnpackedWorkload `json:",omitempty"`
+	// VCpus and MemoryGiB are provisioned so far, as counted against Config.Limits.
+	VCpus     int
+	MemoryGiB float64
+	// QuotaFamilies, SpotVCpus, GPUFamilies and GPUs are charged against Config.Quota.
+	QuotaFamilies map[string]int `json:",omitempty"`
+	SpotVCpus     int            `json:",omitempty"`
+	GPUFamilies   map[string]int `json:",omitempty"`
+	GPUs          int            `json:",omitempty"`
+}
+
+// checkpointVM is an open VM of a packerCheckpoint. The instance type is stored by name and
+// looked up among the candidates on restore.
+type checkpointVM struct {
+	ID   string
+	SKU  string
+	Spot bool `json:",omitempty"`
+	// Free is the remaining CPU, memory GiB, bandwidth Mbps, disk IOPS, disk MBps, workload
+	// count, and CPU and memory GiB limits; negative means unlimited.
+	Free      [8]float64
+	Workloads WorkloadSet
+	Decision  *Decision `json:",omitempty"`
+}
+
+// packerCheckpointVersion is the version of the Checkpoint format.
+const packerCheckpointVersion = 3
+
+// Checkpoint serializes the state of p: the open VMs with their remaining capacity and
+// workloads, the unpacked workloads, and the limit and quota usage. Restore resumes from it.
+func (p *Packer) Checkpoint() ([]byte, error) {
+	s := &p.sim
+	cp := packerCheckpoint{
+		Version:       packerCheckpointVersion,
+		Now:           s.now,
+		Unpacked:      s.result.Unpacked,
+		VCpus:         s.limits.cpu,
+		MemoryGiB:     s.limits.mem,
+		QuotaFamilies: s.quota.families,
+		SpotVCpus:     s.quota.spot,
+		GPUFamilies:   s.quota.gpus.families,
+		GPUs:          s.quota.gpus.total,
+	}
+	for _, vm := range s.vms {
+		cp.VMs = append(cp.VMs, checkpointVM{
+			ID:        vm.ID,
+			SKU:       vm.InstanceType.Name,
+			Spot:      vm.spot,
+			Free:      vm.free.encode(),
+			Workloads: vm.Workloads,
+			Decision:  vm.Decision,
+		})
+	}
+	return json.Marshal(cp)
+}
+
+// Restore replaces the state of p with a Checkpoint of a Packer created with the same
+// candidates and Config.
+func (p *Packer) Restore(data []byte) error {
+	var cp packerCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("parse checkpoint: %w", err)
+	}
+	if cp.Version != packerCheckpointVersion {
+		return fmt.Errorf("checkpoint version %d, expected %d", cp.Version, packerCheckpointVersion)
+	}
+	skus := make(map[string]AzureInstanceSpec, len(p.sim.candidates))
+	for _, c := range p.sim.candidates {
+		skus[c.Name] = c
+	}
+	s := arrivalSim{
+		candidates: p.sim.candidates,
+		cfg:        p.sim.cfg,
+		now:        cp.Now,
+		limits:     limitTracker{limits: p.sim.cfg.Limits, cpu: cp.VCpus, mem: cp.MemoryGiB, vms: len(cp.VMs)},
+		quota:      quotaTracker{quota: p.sim.cfg.Quota, families: cp.QuotaFamilies, spot: cp.SpotVCpus, gpus: gpuQuota{families: cp.GPUFamilies, total: cp.GPUs}},
+		result:     PackingResult{Unpacked: cp.Unpacked},
+	}
+	if s.quota.families == nil {
+		s.quota.families = make(map[string]int)
+	}
+	for _, vm := range cp.VMs {
+		sku, ok := skus[vm.SKU]
+		if !ok {
+			return fmt.Errorf("checkpoint VM %s has instance type %s, which is not a candidate", vm.ID, vm.SKU)
+		}
+		s.vms = append(s.vms, arrivalVM{
+			PackedVM: PackedVM{ID: vm.ID, InstanceType: sku, Workloads: vm.Workloads, Decision: vm.Decision},
+			spot:     vm.Spot,
+			free:     decodeCapacity(vm.Free),
+		})
+	}
+	p.sim = s
+	return nil
+}
+
+// encode returns c in checkpointVM.Free order, with unlimited dimensions as -1 and the rounding
+// error fitsWithin tolerates below zero as 0.
+func (c capacity) encode() [8]float64 {
+	out := [8]float64{c.cpu, c.memoryGiB, c.bandwidthMbps, c.diskIOPS, c.diskMBps, c.workloads, c.limitCPU, c.limitMemoryGiB}
+	for i, v := range out {
+		switch {
+		case math.IsInf(v, 1):
+			out[i] = -1
+		case v < 0:
+			out[i] = 0
+		}
+	}
+	return out
+}
+
+// decodeCapacity is the inverse of capacity.encode.
+func decodeCapacity(v [8]float64) capacity {
+	for i := range v {
+		if v[i] < 0 {
+			v[i] = math.Inf(1)
+		}
+	}
+	return capacity{
+		cpu: v[0], memoryGiB: v[1], bandwidthMbps: v[2], diskIOPS: v[3], diskMBps: v[4], workloads: v[5],
+		limitCPU: v[6], limitMemoryGiB: v[7],
+	}
+}
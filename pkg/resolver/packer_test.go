@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPacker_CheckpointRestore(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D2s_v5", Family: "standardDSv5Family", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.096},
+		{Name: "Standard_D8s_v5", Family: "standardDSv5Family", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.384, UncachedDiskIOPS: 12800},
+		{Name: "Standard_E8s_v5", Family: "standardESv5Family", VCpus: 8, MemoryGiB: 64, PricePerHour: 0.504},
+	}
+	var workloads WorkloadSet
+	for i := range 40 {
+		workloads = append(workloads, WorkloadProfile{
+			Name:               VMID(i),
+			CPURequirements:    1 + i%5,
+			MemoryRequirements: float64(2 + 3*(i%7)),
+			IOPSRequirements:   float64(100 * (i % 3)),
+		})
+	}
+	cfg := Config{WithAudit: true, Quota: QuotaMap{"standardDSv5Family": 48}, MaxWorkloadsPerVM: 6}
+	want := BinPackWorkloadsOnline(workloads, candidates, cfg)
+
+	first, err := NewPacker(candidates, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.AddWorkloads(workloads[:15]); err != nil {
+		t.Fatal(err)
+	}
+	partial := first.Result()
+	if err := first.AddWorkloads(workloads[15:25]); err != nil && !errors.Is(err, ErrCapacityExhausted) {
+		t.Fatal(err)
+	}
+	data, err := first.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewPacker(candidates, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.AddWorkloads(workloads[25:]); err != nil && !errors.Is(err, ErrCapacityExhausted) {
+		t.Fatal(err)
+	}
+	if got := second.Result(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the resumed packing to equal an uninterrupted one\nwant %+v\ngot  %+v", want, got)
+	}
+	if n := len(partial.VMs); n == 0 || n > len(want.VMs) || partial.VMs[0].ID != want.VMs[0].ID {
+		t.Errorf("expected the intermediate result to hold the first VMs, got %d VMs", n)
+	}
+
+	if err := second.Restore([]byte(`{"Version":3,"VMs":[{"ID":"vm-0001","SKU":"Standard_L8s_v3"}]}`)); err == nil {
+		t.Error("expected an error for a checkpoint of another candidate set")
+	}
+}
+
+func TestPacker_BackPressure(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	p, err := NewPacker(candidates, Config{Limits: Limits{VMs: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddWorkloads(WorkloadSet{{CPURequirements: 4, MemoryRequirements: 4}}); err != nil {
+		t.Fatalf("expected the first batch to fit, got %v", err)
+	}
+	err = p.AddWorkloads(WorkloadSet{{CPURequirements: 4, MemoryRequirements: 4}, {CPURequirements: 64}})
+	if !errors.Is(err, ErrCapacityExhausted) {
+		t.Fatalf("expected ErrCapacityExhausted once the VM limit is reached, got %v", err)
+	}
+	if got := p.Result(); len(got.VMs) != 1 || len(got.Unpacked) != 2 {
+		t.Errorf("expected 1 VM and 2 unpacked workloads, got %+v", got)
+	}
+
+	if _, err := NewPacker(candidates, Config{Preemption: true}); err == nil {
+		t.Error("expected an error for preemption")
+	}
+}
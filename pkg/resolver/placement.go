@@ -0,0 +1,96 @@
+package resolver
+
+// CapacityType is how a VM is billed, named like Karpenter's karpenter.sh/capacity-type values.
+type CapacityType string
+
+const (
+	CapacityTypeOnDemand CapacityType = "on-demand"
+	CapacityTypeSpot     CapacityType = "spot"
+)
+
+// capacityTypeFor returns the capacity type of a new VM of sku selected for w: spot when w
+// requires spot and the SKU offers it, except for reserved capacity, which is always on-demand.
+func capacityTypeFor(sku AzureInstanceSpec, w WorkloadProfile, reserved bool) CapacityType {
+	if requiresSpot(w) && sku.SpotSupported && !reserved {
+		return CapacityTypeSpot
+	}
+	return CapacityTypeOnDemand
+}
+
+/*
+assignZones sets the Zone of every VM: the zone of the first workload on it pinned to one of
+the SKU's zones, else the preferred zone of the first workload preferring one of them, or
+otherwise the SKU zone with the fewest VMs so far (the first such zone in the SKU's order),
+spreading unpinned capacity across zones. SKUs without zones get "".
+*/
+func assignZones(vms []PackedVM) {
+	perZone := make(map[string]int)
+	for i := range vms {
+		vm := &vms[i]
+		vm.Zone = pinnedZone(*vm)
+		if vm.Zone == "" {
+			vm.Zone = preferredZone(*vm)
+		}
+		if vm.Zone == "" {
+			for _, z := range vm.InstanceType.AvailabilityZones {
+				if vm.Zone == "" || perZone[z] < perZone[vm.Zone] {
+					vm.Zone = z
+				}
+			}
+		}
+		if vm.Zone != "" {
+			perZone[vm.Zone]++
+		}
+	}
+}
+
+// pinnedZone returns the zone of the first workload on vm pinned to one of its SKU's zones, or "".
+func pinnedZone(vm PackedVM) string {
+	return firstOfferedZone(vm, func(w WorkloadProfile) string { return w.Zone })
+}
+
+// preferredZone returns the preferred zone of the first workload on vm preferring one of its
+// SKU's zones, or "".
+func preferredZone(vm PackedVM) string {
+	return firstOfferedZone(vm, func(w WorkloadProfile) string { return w.PreferredZone })
+}
+
+// firstOfferedZone returns the first zone(w) of the workloads on vm that is one of its SKU's zones, or "".
+func firstOfferedZone(vm PackedVM, zone func(WorkloadProfile) string) string {
+	for _, w := range vm.Workloads {
+		for _, z := range vm.InstanceType.AvailabilityZones {
+			if zone(w) != "" && zone(w) == z {
+				return z
+			}
+		}
+	}
+	return ""
+}
+
+// PlacementCount is the number and hourly cost of the VMs in one zone or of one capacity type.
+type PlacementCount struct {
+	VMs         int     `json:"vms"`
+	CostPerHour float64 `json:"costPerHour"`
+}
+
+// PlacementSummary counts the VMs of vms per zone and per capacity type. VMs without a zone
+// are counted under "".
+func PlacementSummary(vms []PackedVM) (byZone map[string]PlacementCount, byCapacityType map[CapacityType]PlacementCount) {
+	byZone = make(map[string]PlacementCount)
+	byCapacityType = make(map[CapacityType]PlacementCount)
+	for _, vm := range vms {
+		z := byZone[vm.Zone]
+		z.VMs++
+		z.CostPerHour += vm.InstanceType.PricePerHour
+		byZone[vm.Zone] = z
+		ct := vm.CapacityType
+		if ct == "" {
+			ct = CapacityTypeOnDemand
+		}
+		c := byCapacityType[ct]
+		c.VMs++
+		c.CostPerHour += vm.InstanceType.PricePerHour
+		byCapacityType[ct] = c
+	}
+	return byZone, byCapacityType
+}
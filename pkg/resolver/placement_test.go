@@ -0,0 +1,73 @@
+package resolver
+
+import "testing"
+
+func TestAssignZonesAndPlacementSummary(t *testing.T) {
+	sku := AzureInstanceSpec{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2, AvailabilityZones: []string{"1", "2", "3"}, SpotSupported: true}
+	noZones := AzureInstanceSpec{Name: "Standard_B2s", VCpus: 2, MemoryGiB: 4, PricePerHour: 0.04}
+	workloads := WorkloadSet{
+		{Name: "pinned", CPURequirements: 3, MemoryRequirements: 4, Zone: "3"},
+		{Name: "spot", CPURequirements: 3, MemoryRequirements: 4, RequireSpot: true},
+		{Name: "spot-2", CPURequirements: 3, MemoryRequirements: 4, RequireSpot: true},
+	}
+	result := BinPackWorkloadsWithConfig(workloads, []AzureInstanceSpec{sku}, Config{})
+	if len(result.VMs) != 3 {
+		t.Fatalf("expected 3 VMs, got %+v", result)
+	}
+	zones := make(map[string]string)
+	types := make(map[string]CapacityType)
+	for _, vm := range result.VMs {
+		zones[vm.Workloads[0].Name] = vm.Zone
+		types[vm.Workloads[0].Name] = vm.CapacityType
+	}
+	if zones["pinned"] != "3" || zones["spot"] == zones["spot-2"] || zones["spot"] == "3" || zones["spot-2"] == "3" {
+		t.Errorf("expected the pinned VM in zone 3 and the others spread over zones 1 and 2, got %v", zones)
+	}
+	if types["pinned"] != CapacityTypeOnDemand || types["spot"] != CapacityTypeSpot || types["spot-2"] != CapacityTypeSpot {
+		t.Errorf("expected spot only for the workloads requiring it, got %v", types)
+	}
+
+	byZone, byCapacityType := PlacementSummary(append(result.VMs, PackedVM{InstanceType: noZones}))
+	if byZone["3"].VMs != 1 || byZone[""].VMs != 1 || len(byZone) != 4 {
+		t.Errorf("expected one VM per zone plus one without a zone, got %+v", byZone)
+	}
+	if c := byCapacityType[CapacityTypeSpot]; c.VMs != 2 || c.CostPerHour < 0.399 || c.CostPerHour > 0.401 {
+		t.Errorf("expected 2 spot VMs costing $0.40/h, got %+v", c)
+	}
+	if byCapacityType[CapacityTypeOnDemand].VMs != 2 {
+		t.Errorf("expected the VM without a capacity type counted as on-demand, got %+v", byCapacityType)
+	}
+}
+
+func TestPreferredZone(t *testing.T) {
+	zones12 := AzureInstanceSpec{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2, AvailabilityZones: []string{"1", "2"}}
+	zones23 := AzureInstanceSpec{Name: "Standard_D4as_v5", Family: "DASv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.205, AvailabilityZones: []string{"2", "3"}}
+	candidates := []AzureInstanceSpec{zones12, zones23}
+	w := WorkloadProfile{Name: "local", CPURequirements: 3, MemoryRequirements: 8}
+
+	if result := BinPackWorkloadsWithConfig(WorkloadSet{w}, candidates, Config{}); result.VMs[0].InstanceType.Name != zones12.Name {
+		t.Fatalf("expected the cheaper SKU without a preference, got %s", result.VMs[0].InstanceType.Name)
+	}
+	w.PreferredZone = "3"
+	result := BinPackWorkloadsWithConfig(WorkloadSet{w}, candidates, Config{})
+	if vm := result.VMs[0]; vm.InstanceType.Name != zones23.Name || vm.Zone != "3" {
+		t.Errorf("expected the slightly dearer SKU placed in preferred zone 3, got %s in zone %q", vm.InstanceType.Name, vm.Zone)
+	}
+
+	// The preferred zone is not a filter: a SKU outside it is still selected, in its own zones
+	result = BinPackWorkloadsWithConfig(WorkloadSet{w}, []AzureInstanceSpec{zones12}, Config{})
+	if len(result.VMs) != 1 || len(result.Unpacked) != 0 || result.VMs[0].Zone != "1" {
+		t.Errorf("expected a fallback VM in zone 1, got %+v", result)
+	}
+
+	// Among the SKU's zones the preferred one beats spreading, which would pick zone 1
+	w.PreferredZone = "2"
+	if result := BinPackWorkloadsWithConfig(WorkloadSet{w}, []AzureInstanceSpec{zones12}, Config{}); result.VMs[0].Zone != "2" {
+		t.Errorf("expected the VM in preferred zone 2, got %q", result.VMs[0].Zone)
+	}
+	// A hard zone takes precedence over a preference
+	w.Zone = "1"
+	if result := BinPackWorkloadsWithConfig(WorkloadSet{w}, []AzureInstanceSpec{zones12}, Config{}); result.VMs[0].Zone != "1" {
+		t.Errorf("expected the VM in pinned zone 1, got %q", result.VMs[0].Zone)
+	}
+}
@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DoomedWorkload is a workload no candidate can ever run, see PrecheckWorkloads.
+type DoomedWorkload struct {
+	Workload WorkloadProfile
+	// Constraint is the limiting requirement: "cpu", "memory" or the name of the filter that
+	// removed the last candidates, e.g. "gpu", "zone" or "architecture".
+	Constraint string
+	// Detail says what the workload requests and why no candidate has it.
+	Detail string
+}
+
+func (d DoomedWorkload) String() string {
+	name := d.Workload.ID()
+	if name == "" {
+		name = "workload"
+	}
+	return fmt.Sprintf("%s: %s: %s", name, d.Constraint, d.Detail)
+}
+
+// maxDoomedPrinted caps the doomed workloads the simulations list in their warning.
+const maxDoomedPrinted = 20
+
+// capacityFilters check that an instance type is large enough for a workload on its own,
+// before the filters of its other hard constraints.
+var capacityFilters = []NamedFilter{
+	{"cpu", func(inst AzureInstanceSpec, w WorkloadProfile) bool { return inst.VCpus >= w.CPURequirements }},
+	{"memory", func(inst AzureInstanceSpec, w WorkloadProfile) bool {
+		return fitsWithin(w.MemoryRequirements, inst.MemoryGiB)
+	}},
+}
+
+/*
+PrecheckWorkloads returns the workloads no candidate satisfies the hard constraints of, which
+every packing would leave unpacked: the vCPUs and memory they request, and the requirements of
+the default filters (GPUs, zones, architecture, capabilities and so on). The constraints are
+checked in that order, and the one that rules out the last remaining candidates is named as
+the limiting one. Costs, limits and quota are not considered.
+*/
+func PrecheckWorkloads(workloads WorkloadSet, candidates []AzureInstanceSpec) []DoomedWorkload {
+	return precheck(workloads, candidates, defaultFilters)
+}
+
+// precheck is PrecheckWorkloads with the given filters after the capacity checks.
+func precheck(workloads WorkloadSet, candidates []AzureInstanceSpec, filters []NamedFilter) []DoomedWorkload {
+	chain := append(capacityFilters[:len(capacityFilters):len(capacityFilters)], filters...)
+	var doomed []DoomedWorkload
+	for _, w := range workloads {
+		if w.Headroom {
+			continue
+		}
+		remaining := candidates
+		for _, f := range chain {
+			remaining = FilterInstanceTypes(remaining, w, f.Filter)
+			if len(remaining) == 0 {
+				doomed = append(doomed, DoomedWorkload{Workload: w, Constraint: f.Name, Detail: limitingDetail(f, w, candidates)})
+				break
+			}
+		}
+	}
+	return doomed
+}
+
+// limitingDetail explains why f rules out the last candidates for w.
+func limitingDetail(f NamedFilter, w WorkloadProfile, candidates []AzureInstanceSpec) string {
+	switch f.Name {
+	case "cpu", "memory":
+		var cpus int
+		var mem float64
+		for _, c := range candidates {
+			cpus, mem = max(cpus, c.VCpus), max(mem, c.MemoryGiB)
+		}
+		if f.Name == "cpu" {
+			return fmt.Sprintf("requests %d vCPUs, the largest candidate has %d", w.CPURequirements, cpus)
+		}
+		return fmt.Sprintf("requests %g GiB memory, the largest candidate has %g GiB", w.MemoryRequirements, mem)
+	}
+	if len(candidates) == 0 || len(FilterInstanceTypes(candidates, w, f.Filter)) == 0 {
+		return fmt.Sprintf("requests %s, which no candidate has", requirementOf(f.Name, w))
+	}
+	return fmt.Sprintf("requests %s, which no candidate large enough and meeting its other requirements has", requirementOf(f.Name, w))
+}
+
+// requirementOf describes what w requires of the filter named name.
+func requirementOf(name string, w WorkloadProfile) string {
+	switch name {
+	case "zone":
+		return "zone " + w.Zone
+	case "min-zones":
+		return fmt.Sprintf("%d zones", w.MinZones)
+	case "gpu":
+		gpus := fmt.Sprintf("%d GPUs", w.gpusRequired())
+		if w.GPUMemoryGiB > 0 {
+			gpus += fmt.Sprintf(" with %g GiB memory", w.GPUMemoryGiB)
+		}
+		if types := w.GPUTypes(); len(types) > 0 {
+			gpus += " of type " + strings.Join(types, " or ")
+		}
+		return gpus
+	case "ephemeral-os":
+		return "an ephemeral OS disk"
+	case "trusted-launch":
+		return "trusted launch"
+	case "accelerated-networking":
+		return "accelerated networking"
+	case "max-pods":
+		return w.Capabilities["MaxPods"] + " pods"
+	case "network-bandwidth":
+		return fmt.Sprintf("%g Mbps of network bandwidth", w.NetworkRequirementsMbps)
+	case "disk-performance":
+		return fmt.Sprintf("%g disk IOPS and %g MBps", w.IOPSRequirements, w.ThroughputMBpsRequirements)
+	case "architecture":
+		return w.Architecture
+	case "overcommit":
+		cpu, mem := limitsOf(w)
+		return fmt.Sprintf("limits of %g vCPUs and %g GiB under the overcommit cap", cpu, mem)
+	}
+	return "the " + name + " requirement"
+}
@@ -0,0 +1,49 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrecheckWorkloads(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, AvailabilityZones: []string{"1", "2"}, MaxPods: 30},
+		{Name: "Standard_E32s_v5", VCpus: 32, MemoryGiB: 256, AvailabilityZones: []string{"1"}, TrustedLaunch: true, MaxPods: 110},
+		{Name: "Standard_NC24ads_A100_v4", VCpus: 24, MemoryGiB: 220, GPUCount: 1, GPUType: "A100", MaxPods: 30},
+		{Name: "Standard_D4ps_v5", VCpus: 4, MemoryGiB: 16, MaxPods: 30},
+	}
+	for _, tc := range []struct {
+		workload   WorkloadProfile
+		constraint string
+		detail     string
+	}{
+		{WorkloadProfile{Name: "cpu", CPURequirements: 64, MemoryRequirements: 8}, "cpu", "requests 64 vCPUs, the largest candidate has 32"},
+		{WorkloadProfile{Name: "memory", CPURequirements: 2, MemoryRequirements: 512}, "memory", "requests 512 GiB memory, the largest candidate has 256 GiB"},
+		{WorkloadProfile{Name: "zone", CPURequirements: 2, MemoryRequirements: 4, Zone: "3"}, "zone", "requests zone 3, which no candidate has"},
+		{WorkloadProfile{Name: "gpus", CPURequirements: 2, MemoryRequirements: 4, GPURequirements: 2}, "gpu", "requests 2 GPUs, which no candidate has"},
+		{WorkloadProfile{Name: "gpu-type", CPURequirements: 2, MemoryRequirements: 4, GPURequirements: 1, GPUType: "H100"}, "gpu", "requests 1 GPUs of type H100"},
+		{WorkloadProfile{Name: "trusted", CPURequirements: 2, MemoryRequirements: 4, Capabilities: map[string]string{"TrustedLaunch": "true"}, Zone: "2"}, "trusted-launch", "which no candidate large enough and meeting its other requirements has"},
+		{WorkloadProfile{Name: "pods", CPURequirements: 2, MemoryRequirements: 4, Capabilities: map[string]string{"MaxPods": "250"}}, "max-pods", "requests 250 pods"},
+		{WorkloadProfile{Name: "arm", CPURequirements: 8, MemoryRequirements: 4, Architecture: ArchARM64}, "architecture", "requests arm64, which no candidate large enough"},
+		{WorkloadProfile{Name: "ephemeral", CPURequirements: 2, MemoryRequirements: 4, RequireEphemeralOS: true}, "ephemeral-os", "requests an ephemeral OS disk, which no candidate has"},
+	} {
+		doomed := PrecheckWorkloads(WorkloadSet{tc.workload}, candidates)
+		if len(doomed) != 1 {
+			t.Errorf("%s: expected the workload to be doomed, got %+v", tc.workload.Name, doomed)
+			continue
+		}
+		if d := doomed[0]; d.Constraint != tc.constraint || !strings.Contains(d.Detail, tc.detail) {
+			t.Errorf("%s: expected limiting factor %s (%q), got %s (%q)", tc.workload.Name, tc.constraint, tc.detail, d.Constraint, d.Detail)
+		}
+	}
+
+	feasible := WorkloadSet{
+		{CPURequirements: 30, MemoryRequirements: 200, Zone: "1"},
+		{CPURequirements: 4, MemoryRequirements: 16, Architecture: ArchARM64},
+		{CPURequirements: 8, MemoryRequirements: 64, GPURequirements: 1, GPUType: "A100"},
+		{CPURequirements: 512, Headroom: true},
+	}
+	if doomed := PrecheckWorkloads(feasible, candidates); len(doomed) != 0 {
+		t.Errorf("expected no doomed workloads, got %v", doomed)
+	}
+}
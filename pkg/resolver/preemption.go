@@ -0,0 +1,356 @@
+package resolver
+
+import (
+	"maps"
+	"sort"
+)
+
+// ReasonPreempted is reported for preempted workloads that could not be placed again.
+const ReasonPreempted = "preempted by a higher-priority workload"
+
+// PreemptedWorkload is a workload evicted to make room for a higher-priority one.
+type PreemptedWorkload struct {
+	Workload    WorkloadProfile
+	By          WorkloadProfile // the workload it made room for
+	Rescheduled bool            // placed again after all arrivals
+}
+
+// ArrivalResult is the outcome of SimulateArrivals.
+type ArrivalResult struct {
+	// Packing is the final placement; Unpacked lists the workloads finally left unscheduled.
+	Packing   PackingResult
+	Preempted []PreemptedWorkload // in preemption order
+	// PreemptionsByPriority and UnscheduledByPriority count Preempted and Packing.Unpacked by
+	// workload priority.
+	PreemptionsByPriority map[int]int
+	UnscheduledByPriority map[int]int
+	// Instant counts the placements onto running VMs and Provisioned those that waited for a
+	// new VM.
+	Instant     int
+	Provisioned int
+	// WarmPool is set when Config.WarmPool is.
+	WarmPool *WarmPoolResult
+	// Exclusions lists the SKUs excluded after failing to provision under Config.Availability,
+	// in simulated time order.
+	Exclusions []ExclusionEvent `json:",omitempty"`
+	// Events is the event log of the run, timed by the arrivals.
+	Events EventLog `json:",omitempty"`
+}
+
+/*
+SimulateArrivals places workloads one at a time in the order given, like a provisioner that
+sees pods arrive: each goes onto the first VM with room for it, or onto a new VM of the best
+instance type while Config.Limits and Config.Quota allow one.
+
+When capacity is exhausted and Config.Preemption is set, a workload that cannot be placed
+evicts strictly lower-priority workloads (see WorkloadProfile.Priority) from the VM where the
+fewest evictions make room, lowest priority first. Once every workload has arrived, the
+preempted ones are placed again, highest priority first, without preempting others; those
+that still do not fit are unpacked with ReasonPreempted.
+
+Spot and on-demand workloads do not share VMs, and spot VMs are charged against the spot
+quota, as in BinPackWorkloadsWithQuota. Headroom and capacity reservations are not modelled.
+
+With Config.WarmPool, the warm VMs run empty from t=0 and take arrivals they suit before any
+new VM is provisioned; ArrivalResult.WarmPool weighs the workloads they absorbed against what
+they cost standing.
+
+With Config.Availability, provisioning fails during the model's outages, at the arrival time
+of the workload (see WorkloadProfile.ArrivalSeconds). The failed SKU is excluded until its TTL
+expires and the next best instance type is tried; ArrivalResult.Exclusions lists the failures.
+
+ArrivalResult.Events logs every provisioning, placement and preemption at the time of the
+arrival that caused it, and the expiry of each exclusion. Preempted workloads placed again
+after all arrivals are placed at the time of the last one.
+*/
+func SimulateArrivals(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) ArrivalResult {
+	cfg = cfg.forRun()
+	s := arrivalSim{
+		candidates: candidates,
+		cfg:        cfg,
+		limits:     limitTracker{limits: cfg.Limits},
+		quota:      quotaTracker{quota: cfg.Quota, families: make(map[string]int)},
+	}
+	if cfg.Availability != nil {
+		s.unavailable = NewUnavailabilityCache(cfg.Availability.ttl())
+	}
+	var warm *WarmPoolResult
+	if cfg.WarmPool != nil {
+		warm = s.warmUp(workloads)
+	}
+	for _, w := range workloads {
+		s.now = max(s.now, w.ArrivalSeconds)
+		reason := s.place(w)
+		if reason == "" {
+			continue
+		}
+		if cfg.Preemption && capacityExhausted(reason) && s.preempt(w) {
+			continue
+		}
+		s.result.Unpacked = append(s.result.Unpacked, UnpackedWorkload{Workload: w, Reason: reason})
+	}
+
+	order := make([]int, len(s.preempted))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return s.preempted[order[i]].Workload.Priority > s.preempted[order[j]].Workload.Priority
+	})
+	for _, i := range order {
+		p := &s.preempted[i]
+		if s.place(p.Workload) == "" {
+			p.Rescheduled = true
+			continue
+		}
+		s.result.Unpacked = append(s.result.Unpacked, UnpackedWorkload{Workload: p.Workload, Reason: ReasonPreempted})
+	}
+
+	out := ArrivalResult{
+		Preempted:             s.preempted,
+		PreemptionsByPriority: make(map[int]int),
+		UnscheduledByPriority: make(map[int]int),
+		Instant:               s.instant,
+		Provisioned:           s.provisioned,
+		WarmPool:              warm,
+		Exclusions:            s.exclusions,
+		Events:                s.events,
+	}
+	out.Events.sort()
+	for _, vm := range s.vms {
+		s.result.VMs = append(s.result.VMs, vm.PackedVM)
+		if vm.warm && len(vm.Workloads) > 0 {
+			warm.Used++
+			warm.Absorbed += len(vm.Workloads)
+		}
+	}
+	if len(cfg.Quota) > 0 {
+		s.result.QuotaUsage = s.quota.usage()
+	}
+	out.Packing = s.result
+	for _, p := range out.Preempted {
+		out.PreemptionsByPriority[p.Workload.Priority]++
+	}
+	for _, u := range out.Packing.Unpacked {
+		out.UnscheduledByPriority[u.Workload.Priority]++
+	}
+	return out
+}
+
+// capacityExhausted reports whether an unpacked reason means limits or quota ran out, as
+// opposed to no instance type suiting the workload.
+func capacityExhausted(reason string) bool {
+	return reason != ReasonNoCandidates && reason != ReasonSelectedTooSmall && reason != ReasonPoorFit
+}
+
+// arrivalSim is the state of SimulateArrivals.
+type arrivalSim struct {
+	candidates []AzureInstanceSpec
+	cfg        Config
+	limits     limitTracker
+	quota      quotaTracker
+	vms        []arrivalVM
+	preempted  []PreemptedWorkload
+	result     PackingResult
+	// instant and provisioned count placements onto running and new VMs.
+	instant, provisioned int
+	// now is the simulated time of the latest arrival; unavailable and exclusions are set
+	// under Config.Availability.
+	now         float64
+	unavailable *UnavailabilityCache
+	exclusions  []ExclusionEvent
+	events      EventLog
+}
+
+// arrivalVM is a provisioned VM with its free capacity.
+type arrivalVM struct {
+	PackedVM
+	spot bool
+	free capacity
+	warm bool   // part of the warm pool
+	zone string // set on warm VMs pinned to a zone
+}
+
+// place puts w onto the first VM with room or a new VM, returning "" on success and the
+// unpacked reason otherwise.
+func (s *arrivalSim) place(w WorkloadProfile) string {
+	spot := requiresSpot(w)
+	for i := range s.vms {
+		vm := &s.vms[i]
+		if vm.spot == spot && vm.free.fits(w) && s.suits(*vm, w) {
+			vm.Workloads = append(vm.Workloads, w)
+			vm.free.take(w)
+			s.instant++
+			s.events.add(s.now, EventPlace, vm.ID, w.ID(), "")
+			return ""
+		}
+	}
+	candidates := s.candidates
+	var best AzureInstanceSpec
+	var score float64
+	var free capacity
+	for {
+		if s.unavailable != nil {
+			candidates = s.unavailable.available(s.candidates, w.Zone, s.now)
+		}
+		best, score = selectWithConfig(candidates, w, s.cfg)
+		if best.Name == "" {
+			if len(candidates) < len(s.candidates) {
+				return ReasonCapacityUnavailable
+			}
+			return ReasonNoCandidates
+		}
+		if s.cfg.poorFit(best, w, -1) {
+			return ReasonPoorFit
+		}
+		free = s.cfg.vmCapacity(best)
+		if !free.fits(w) {
+			return ReasonSelectedTooSmall
+		}
+		if reason := s.limits.exceeded(best); reason != "" {
+			return reason
+		}
+		if reason := s.quota.exceeded(best, spot); reason != "" {
+			return reason
+		}
+		if s.unavailable == nil || !s.cfg.Availability.fails(best, w.Zone, s.now) {
+			break
+		}
+		until := s.unavailable.MarkUnavailable(best.Name, w.Zone, s.now)
+		s.exclusions = append(s.exclusions, ExclusionEvent{AtSeconds: s.now, SKU: best.Name, Zone: w.Zone, UntilSeconds: until, Workload: w.ID()})
+		s.events.add(until, EventExpire, "", "", best.Name)
+	}
+	s.limits.add(best)
+	s.quota.add(best, spot)
+	s.provisioned++
+	free.take(w)
+	id := VMID(len(s.vms) + 1)
+	s.vms = append(s.vms, arrivalVM{
+		PackedVM: PackedVM{ID: id, InstanceType: best, Workloads: []WorkloadProfile{w}, Decision: s.cfg.audit(s.candidates, w, best, score, id)},
+		spot:     spot,
+		free:     free,
+	})
+	s.events.add(s.now, EventProvision, id, "", best.Name)
+	s.events.add(s.now, EventPlace, id, w.ID(), "")
+	return ""
+}
+
+// preempt evicts lower-priority workloads from the VM where the fewest evictions make room
+// for w and places w there. It reports false, evicting nothing, when no VM can make room.
+func (s *arrivalSim) preempt(w WorkloadProfile) bool {
+	bestVM, bestEvict := -1, []int(nil)
+	for i, vm := range s.vms {
+		if vm.spot != requiresSpot(w) || !s.suits(vm, w) {
+			continue
+		}
+		evict, ok := evictionsFor(vm, w)
+		if ok && (bestVM < 0 || len(evict) < len(bestEvict)) {
+			bestVM, bestEvict = i, evict
+		}
+	}
+	if bestVM < 0 {
+		return false
+	}
+	vm := &s.vms[bestVM]
+	evicted := make(map[int]bool, len(bestEvict))
+	for _, j := range bestEvict {
+		evicted[j] = true
+		s.preempted = append(s.preempted, PreemptedWorkload{Workload: vm.Workloads[j], By: w})
+		s.events.add(s.now, EventEvict, vm.ID, vm.Workloads[j].ID(), "preempted by "+w.ID())
+	}
+	var kept []WorkloadProfile
+	for j, v := range vm.Workloads {
+		if !evicted[j] {
+			kept = append(kept, v)
+		}
+	}
+	vm.Workloads = append(kept, w)
+	vm.free = s.cfg.vmCapacity(vm.InstanceType)
+	for _, v := range vm.Workloads {
+		vm.free.take(v)
+	}
+	s.instant++
+	s.events.add(s.now, EventPlace, vm.ID, w.ID(), "")
+	return true
+}
+
+// evictionsFor returns the indexes of the workloads of vm to evict to make room for w: those
+// of lower priority than w, lowest priority and then largest first, until w fits.
+func evictionsFor(vm arrivalVM, w WorkloadProfile) ([]int, bool) {
+	var lower []int
+	for j, v := range vm.Workloads {
+		if v.Priority < w.Priority {
+			lower = append(lower, j)
+		}
+	}
+	sort.SliceStable(lower, func(a, b int) bool {
+		va, vb := vm.Workloads[lower[a]], vm.Workloads[lower[b]]
+		if va.Priority != vb.Priority {
+			return va.Priority < vb.Priority
+		}
+		return float64(va.CPURequirements)+va.MemoryRequirements > float64(vb.CPURequirements)+vb.MemoryRequirements
+	})
+	free := vm.free
+	for n, j := range lower {
+		free = capacityWithout(free, vm.Workloads[j])
+		if free.fits(w) {
+			return lower[:n+1], true
+		}
+	}
+	return nil, false
+}
+
+// capacityWithout returns c with v's requirements given back.
+func capacityWithout(c capacity, v WorkloadProfile) capacity {
+	c.cpu += float64(v.CPURequirements)
+	c.memoryGiB += v.MemoryRequirements
+	c.bandwidthMbps += v.NetworkRequirementsMbps
+	c.diskIOPS += v.IOPSRequirements
+	c.diskMBps += v.ThroughputMBpsRequirements
+	limitCPU, limitMem := limitsOf(v)
+	c.limitCPU += limitCPU
+	c.limitMemoryGiB += limitMem
+	if !v.Headroom {
+		c.workloads++
+	}
+	return c
+}
+
+// quotaTracker accumulates the vCPUs charged against Config.Quota: spot VMs against the spot
+// quota, others against their family's. The GPUs of both count against the GPU quotas.
+type quotaTracker struct {
+	quota    QuotaMap
+	families map[string]int
+	spot     int
+	gpus     gpuQuota
+}
+
+// exceeded returns the unpacked reason if provisioning vm would exceed the quota, or "".
+func (t *quotaTracker) exceeded(vm AzureInstanceSpec, spot bool) string {
+	if spot {
+		if limit, ok := t.quota.SpotTotalVCpus(); ok && t.spot+vm.VCpus > limit {
+			return ReasonSpotQuotaExhausted
+		}
+	} else if key, limit, ok := t.quota.limit(vm.Family); ok && t.families[key]+vm.VCpus > limit {
+		return ReasonQuotaExhausted
+	}
+	if t.gpus.exceeded(t.quota, vm) {
+		return ReasonGPUQuotaExhausted
+	}
+	return ""
+}
+
+func (t *quotaTracker) add(vm AzureInstanceSpec, spot bool) {
+	t.gpus.add(t.quota, vm)
+	if spot {
+		t.spot += vm.VCpus
+		return
+	}
+	key, _, _ := t.quota.limit(vm.Family)
+	t.families[key] += vm.VCpus
+}
+
+// usage returns the quota usage to report.
+func (t *quotaTracker) usage() *QuotaUsage {
+	return &QuotaUsage{Families: maps.Clone(t.families), SpotVCpus: t.spot, GPUFamilies: maps.Clone(t.gpus.families), GPUs: t.gpus.total}
+}
@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulateArrivalsPreemption(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := WorkloadSet{
+		{Name: "batch-a", CPURequirements: 2, MemoryRequirements: 4},
+		{Name: "batch-b", CPURequirements: 2, MemoryRequirements: 4},
+		{Name: "cache", CPURequirements: 1, MemoryRequirements: 2, Priority: 10},
+		{Name: "batch-d", CPURequirements: 3, MemoryRequirements: 4},
+		// Both VMs are full and the limit allows no more: api evicts batch-a from the first
+		// VM, then web can only make room on the second by evicting batch-d, not cache.
+		{Name: "api", CPURequirements: 2, MemoryRequirements: 4, Priority: 100},
+		{Name: "web", CPURequirements: 3, MemoryRequirements: 4, Priority: 5},
+	}
+	cfg := Config{Limits: Limits{VMs: 2}, Preemption: true}
+	got := SimulateArrivals(workloads, candidates, cfg)
+
+	var preempted []string
+	for _, p := range got.Preempted {
+		preempted = append(preempted, p.Workload.Name+" by "+p.By.Name)
+		if p.Rescheduled {
+			t.Errorf("expected %s not to fit again", p.Workload.Name)
+		}
+	}
+	if want := []string{"batch-a by api", "batch-d by web"}; !reflect.DeepEqual(preempted, want) {
+		t.Errorf("expected preemptions %v, got %v", want, preempted)
+	}
+	var placed [][]string
+	for _, vm := range got.Packing.VMs {
+		var names []string
+		for _, w := range vm.Workloads {
+			names = append(names, w.Name)
+		}
+		placed = append(placed, names)
+	}
+	if want := [][]string{{"batch-b", "api"}, {"cache", "web"}}; !reflect.DeepEqual(placed, want) {
+		t.Errorf("expected placement %v, got %v", want, placed)
+	}
+	for _, u := range got.Packing.Unpacked {
+		if u.Reason != ReasonPreempted {
+			t.Errorf("expected %s to be unpacked as preempted, got %q", u.Workload.Name, u.Reason)
+		}
+	}
+	if want := map[int]int{0: 2}; !reflect.DeepEqual(got.PreemptionsByPriority, want) || !reflect.DeepEqual(got.UnscheduledByPriority, want) {
+		t.Errorf("expected 2 preempted and unscheduled priority-0 workloads, got %v and %v", got.PreemptionsByPriority, got.UnscheduledByPriority)
+	}
+
+	cfg.Preemption = false
+	got = SimulateArrivals(workloads, candidates, cfg)
+	if len(got.Preempted) != 0 || !reflect.DeepEqual(got.UnscheduledByPriority, map[int]int{100: 1, 5: 1}) {
+		t.Errorf("expected api and web unscheduled without preemption, got %+v", got)
+	}
+	for _, u := range got.Packing.Unpacked {
+		if u.Reason != ReasonVMLimitExceeded {
+			t.Errorf("expected the VM limit to stop %s, got %q", u.Workload.Name, u.Reason)
+		}
+	}
+}
+
+func TestSimulateArrivalsReschedulesPreempted(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := WorkloadSet{
+		{Name: "low", CPURequirements: 4, MemoryRequirements: 4},
+		{Name: "high", CPURequirements: 3, MemoryRequirements: 4, Priority: 1},
+		{Name: "small", CPURequirements: 1, MemoryRequirements: 1, Priority: 1},
+	}
+	// Quota for a single VM; low is preempted by high and cannot come back.
+	got := SimulateArrivals(workloads, candidates, Config{Quota: QuotaMap{"Dsv5": 4}, Preemption: true})
+	if len(got.Preempted) != 1 || got.Preempted[0].Workload.Name != "low" || got.Preempted[0].Rescheduled {
+		t.Fatalf("expected low to be preempted for good, got %+v", got.Preempted)
+	}
+	if len(got.Packing.VMs) != 1 || len(got.Packing.VMs[0].Workloads) != 2 {
+		t.Errorf("expected high and small to share the only VM, got %+v", got.Packing.VMs)
+	}
+	// With quota for two VMs the preempted workload is placed again.
+	got = SimulateArrivals(workloads, candidates, Config{Quota: QuotaMap{"Dsv5": 8}, Preemption: true})
+	if len(got.Preempted) != 0 || len(got.Packing.Unpacked) != 0 {
+		t.Errorf("expected no preemption with enough quota, got %+v", got)
+	}
+}
+
+// TestSimulateArrivalsPreemptionFreesLimits checks that evicting a workload gives back its limits
+// under a capped overcommit policy, not only its requests.
+func TestSimulateArrivalsPreemptionFreesLimits(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := WorkloadSet{
+		{Name: "low", CPURequirements: 1, MemoryRequirements: 2, LimitCPU: 8},
+		{Name: "high", CPURequirements: 1, MemoryRequirements: 2, LimitCPU: 8, Priority: 10},
+	}
+	cfg := Config{Limits: Limits{CPU: 4}, Overcommit: OvercommitCapped(2), Preemption: true}
+	got := SimulateArrivals(workloads, candidates, cfg)
+	if len(got.Preempted) != 1 || got.Preempted[0].Workload.Name != "low" || got.Preempted[0].By.Name != "high" {
+		t.Fatalf("expected high to preempt low for its limit headroom, got %+v", got.Preempted)
+	}
+	if len(got.Packing.VMs) != 1 || len(got.Packing.VMs[0].Workloads) != 1 || got.Packing.VMs[0].Workloads[0].Name != "high" {
+		t.Errorf("expected high alone on the VM, got %+v", got.Packing.VMs)
+	}
+}
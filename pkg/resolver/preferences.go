@@ -0,0 +1,149 @@
+package resolver
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Node labels the simulation models, as Kubernetes, Karpenter and the provider's API define
+// them; spelled out so the resolver does not depend on their Go modules.
+const (
+	LabelInstanceType = "node.kubernetes.io/instance-type"
+	LabelZone         = "topology.kubernetes.io/zone"
+	LabelCapacityType = "karpenter.sh/capacity-type"
+	LabelSKUFamily    = "karpenter.azure.com/sku-family"
+	LabelSKUCPU       = "karpenter.azure.com/sku-cpu"
+	LabelSKUMemory    = "karpenter.azure.com/sku-memory"
+	LabelSKUGPUCount  = "karpenter.azure.com/sku-gpu-count"
+	LabelSKUGPUName   = "karpenter.azure.com/sku-gpu-name"
+)
+
+// DefaultPreferenceBonus is the score bonus of a satisfied preference whose key has no weight
+// in Config.PreferenceWeights. Like familyPreferenceBonusMax it is small compared to the cost
+// term of ScoreInstance, so preferences only decide near-ties.
+const DefaultPreferenceBonus = 0.02
+
+// skuLabels are the node labels the simulation models, with their value for an instance type.
+// Zones and capacity types have several values per SKU and are handled by skuHasLabel.
+var skuLabels = []struct {
+	key   string
+	value func(AzureInstanceSpec) string
+}{
+	{LabelInstanceType, func(vm AzureInstanceSpec) string { return vm.Name }},
+	{LabelSKUFamily, func(vm AzureInstanceSpec) string { return FamilySeries(AzureInstanceSpec{Name: vm.Name}) }}, // "D" for Standard_D4s_v5, as labelled
+	{LabelSKUCPU, func(vm AzureInstanceSpec) string { return strconv.Itoa(vm.VCpus) }},
+	{LabelSKUMemory, func(vm AzureInstanceSpec) string { return strconv.Itoa(int(vm.MemoryGiB * 1024)) }},
+	{LabelSKUGPUCount, func(vm AzureInstanceSpec) string { return strconv.Itoa(vm.GPUCount) }},
+	{LabelSKUGPUName, func(vm AzureInstanceSpec) string { return vm.GPUType }},
+}
+
+// SKULabel returns the value of the single-valued label key on nodes of vm, such as its
+// instance type or karpenter.azure.com/sku-cpu. ok is false, whatever vm, for zones, capacity
+// types and labels the simulation does not model.
+func SKULabel(vm AzureInstanceSpec, key string) (value string, ok bool) {
+	for _, l := range skuLabels {
+		if l.key == key {
+			return l.value(vm), true
+		}
+	}
+	return "", false
+}
+
+// skuHasLabel reports whether a node of vm can carry the label key=value. ok is false for
+// labels the simulation does not model.
+func skuHasLabel(vm AzureInstanceSpec, key, value string) (has, ok bool) {
+	if v, ok := SKULabel(vm, key); ok {
+		return strings.EqualFold(v, value), true
+	}
+	switch key {
+	case LabelZone:
+		return slices.Contains(vm.AvailabilityZones, NodeZone(value)), true
+	case LabelCapacityType:
+		return value == string(CapacityTypeOnDemand) || value == string(CapacityTypeSpot) && vm.SpotSupported, true
+	}
+	return false, false
+}
+
+/*
+PreferenceSatisfied reports whether vm has the capability a WorkloadProfile.Preferences entry
+asks for. Keys are either capability names as in Capabilities ("EphemeralOSDisk",
+"AcceleratedNetworking", "TrustedLaunch", "NestedVirtualization", "ConfidentialComputing",
+"UltraSSDEnabled", "ProximityPlacement" with "true" or "false", or "MaxPods" with a minimum)
+or node labels the simulation models, such as karpenter.azure.com/sku-family; a label value
+may list alternatives separated by commas. Other keys are looked up in vm.Capabilities.
+*/
+func PreferenceSatisfied(vm AzureInstanceSpec, key, value string) bool {
+	flag := func(has bool) bool {
+		want, err := strconv.ParseBool(value)
+		return err == nil && has == want
+	}
+	switch key {
+	case "EphemeralOSDisk":
+		return flag(vm.EphemeralOSDisk)
+	case "AcceleratedNetworking":
+		return flag(vm.AcceleratedNetworking)
+	case "TrustedLaunch":
+		return flag(vm.TrustedLaunch)
+	case "NestedVirtualization":
+		return flag(vm.NestedVirtualization)
+	case "ConfidentialComputing":
+		return flag(vm.ConfidentialComputing)
+	case "UltraSSDEnabled":
+		return flag(vm.UltraSSDEnabled)
+	case "ProximityPlacement":
+		return flag(vm.ProximityPlacement)
+	case "MaxPods":
+		var req int
+		_, err := fmt.Sscanf(value, "%d", &req)
+		return err == nil && vm.MaxPods >= req
+	}
+	for _, v := range strings.Split(value, ",") {
+		has, ok := skuHasLabel(vm, key, strings.TrimSpace(v))
+		if !ok {
+			return vm.Capabilities[key] == value
+		}
+		if has {
+			return true
+		}
+	}
+	return false
+}
+
+// SatisfiedPreferences returns the keys of workload's preferences vm satisfies, sorted.
+func SatisfiedPreferences(vm AzureInstanceSpec, workload WorkloadProfile) []string {
+	var keys []string
+	for key, value := range workload.Preferences {
+		if PreferenceSatisfied(vm, key, value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// preferenceWeight returns the bonus of satisfying the preference key.
+func preferenceWeight(weights map[string]float64, key string) float64 {
+	if w, ok := weights[key]; ok {
+		return w
+	}
+	return DefaultPreferenceBonus
+}
+
+// PreferenceBonus returns the score bonus of the preferences of workload vm satisfies, and the
+// bonus of satisfying them all, weighted by weights (see Config.PreferenceWeights).
+func PreferenceBonus(vm AzureInstanceSpec, workload WorkloadProfile, weights map[string]float64) (bonus, max float64) {
+	// Sum in key order: float addition in map order could change the last bit of a score.
+	for _, key := range slices.Sorted(maps.Keys(workload.Preferences)) {
+		value := workload.Preferences[key]
+		w := preferenceWeight(weights, key)
+		max += w
+		if PreferenceSatisfied(vm, key, value) {
+			bonus += w
+		}
+	}
+	return bonus, max
+}
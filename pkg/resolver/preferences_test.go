@@ -0,0 +1,84 @@
+package resolver
+
+import "testing"
+
+func TestPreferenceSatisfied(t *testing.T) {
+	vm := AzureInstanceSpec{
+		Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, AvailabilityZones: []string{"1", "2"},
+		AcceleratedNetworking: true, MaxPods: 110, Capabilities: map[string]string{"HyperVGenerations": "V1,V2"},
+	}
+	for _, tc := range []struct {
+		key, value string
+		want       bool
+	}{
+		{"AcceleratedNetworking", "true", true},
+		{"EphemeralOSDisk", "true", false},
+		{"EphemeralOSDisk", "false", true},
+		{"MaxPods", "50", true},
+		{"MaxPods", "250", false},
+		{LabelSKUFamily, "D", true},
+		{LabelSKUFamily, "E,D", true},
+		{LabelSKUFamily, "E", false},
+		{LabelSKUCPU, "4", true},
+		{LabelZone, "eastus2-2", true},
+		{LabelZone, "3", false},
+		{"HyperVGenerations", "V1,V2", true},
+		{"unknown", "x", false},
+	} {
+		if got := PreferenceSatisfied(vm, tc.key, tc.value); got != tc.want {
+			t.Errorf("PreferenceSatisfied(%s=%s) = %v, expected %v", tc.key, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestPreferences_BreakNearTie(t *testing.T) {
+	plain := AzureInstanceSpec{Name: "Standard_D4_v3", Family: "Dv3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.200}
+	accelerated := AzureInstanceSpec{Name: "Standard_D4s_v3", Family: "DSv3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.201, AcceleratedNetworking: true}
+	candidates := []AzureInstanceSpec{plain, accelerated}
+	workload := WorkloadProfile{CPURequirements: 4, MemoryRequirements: 8}
+	preferring := workload
+	preferring.Preferences = map[string]string{"AcceleratedNetworking": "true"}
+
+	for _, version := range []ScoreVersion{ScoreVersionLegacy, ScoreVersionNormalized} {
+		cfg := Config{ScoreVersion: version}
+		if best, _ := selectWithConfig(candidates, workload, cfg); best.Name != plain.Name {
+			t.Fatalf("%s: expected the cheaper SKU without preferences, got %s", version, best.Name)
+		}
+		if best, _ := selectWithConfig(candidates, preferring, cfg); best.Name != accelerated.Name {
+			t.Errorf("%s: expected the preference to break the near-tie, got %s", version, best.Name)
+		}
+		// A weight of 0 turns the preference off.
+		cfg.PreferenceWeights = map[string]float64{"AcceleratedNetworking": 0}
+		if best, _ := selectWithConfig(candidates, preferring, cfg); best.Name != plain.Name {
+			t.Errorf("%s: expected a zero weight to ignore the preference, got %s", version, best.Name)
+		}
+	}
+}
+
+func TestPreferences_NeverExclude(t *testing.T) {
+	only := AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}
+	workload := WorkloadProfile{CPURequirements: 2, MemoryRequirements: 4, Preferences: map[string]string{
+		"AcceleratedNetworking": "true",
+		"EphemeralOSDisk":       "true",
+		LabelSKUFamily:          "E",
+	}}
+	result := BinPackWorkloadsWithConfig(WorkloadSet{workload}, []AzureInstanceSpec{only}, Config{WithAudit: true})
+	if len(result.Unpacked) != 0 || len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != only.Name {
+		t.Fatalf("expected the only feasible SKU despite unmet preferences, got %+v", result)
+	}
+	if d := result.VMs[0].Decision; d == nil || len(d.Preferences) != 0 {
+		t.Errorf("expected an audit with no satisfied preferences, got %+v", d)
+	}
+}
+
+func TestPreferenceBonusOrderIndependent(t *testing.T) {
+	vm := AzureInstanceSpec{Name: "Standard_D2s_v3", AcceleratedNetworking: true, EphemeralOSDisk: true, TrustedLaunch: true}
+	workload := WorkloadProfile{Preferences: map[string]string{"AcceleratedNetworking": "true", "EphemeralOSDisk": "true", "TrustedLaunch": "true", "a": "1", "b": "2"}}
+	weights := map[string]float64{"AcceleratedNetworking": 0.1, "EphemeralOSDisk": 0.2, "TrustedLaunch": 0.3, "a": 0.7, "b": 1e-17}
+	bonus, max := PreferenceBonus(vm, workload, weights)
+	for i := 0; i < 100; i++ {
+		if b, m := PreferenceBonus(vm, workload, weights); b != bonus || m != max {
+			t.Fatalf("expected the same bonus on every call, got %v/%v then %v/%v", bonus, max, b, m)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WorkloadFormat is the schema of a custom workloads file.
+type WorkloadFormat string
+
+const (
+	// WorkloadFormatProfile is a list of WorkloadProfile objects, or one object per line in a
+	// .jsonl file (see WriteWorkloads).
+	WorkloadFormatProfile WorkloadFormat = ""
+	// WorkloadFormatPreprocessed is the workloads_preprocessed.json schema written by
+	// scripts/preprocess_azure_traces.py (see PreprocessedWorkload).
+	WorkloadFormatPreprocessed WorkloadFormat = "preprocessed"
+)
+
+// ParseWorkloadFormat parses a --workload-format value: "profile" (or empty) or "preprocessed".
+func ParseWorkloadFormat(s string) (WorkloadFormat, error) {
+	switch s {
+	case "", "profile":
+		return WorkloadFormatProfile, nil
+	case string(WorkloadFormatPreprocessed):
+		return WorkloadFormatPreprocessed, nil
+	}
+	return "", fmt.Errorf("unknown workload format %q, expected profile or preprocessed", s)
+}
+
+/*
+PreprocessedWorkload is a pod-like workload of workloads_preprocessed.json, derived from a VM
+of the Azure public trace. The requests are what the VM reserved; the usage fields are its
+average utilization in percent of them.
+*/
+type PreprocessedWorkload struct {
+	Name             string            `json:"name"`
+	Namespace        string            `json:"namespace,omitempty"`
+	CPURequest       int               `json:"cpu_request"`
+	MemoryRequestGiB float64           `json:"memory_request_gib"`
+	CPUUsage         float64           `json:"cpu_usage"`
+	MemUsage         float64           `json:"mem_usage"`
+	StartTime        string            `json:"start_time"` // RFC 3339, e.g. "2020-01-01T00:00:00Z"
+	EndTime          string            `json:"end_time"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Annotations      map[string]string `json:"annotations,omitempty"`
+}
+
+/*
+LoadPreprocessedWorkloads loads a workloads_preprocessed.json file. Workloads require their
+requests, not their usage: nodes are sized for what pods reserve, and sizing by average usage
+would overcommit every VM at its peaks. The usage percentages are converted to vCPUs and GiB
+in UsageCPU and UsageMemory, for rightsizing estimates (see PackingBasis). Labels are kept
+for cost attribution. Start times become arrivals counted from the earliest start, and end
+times durations; either may be empty. Workloads without a name are named after their position, e.g. "workload-3".
+*/
+func LoadPreprocessedWorkloads(path string) (WorkloadSet, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("read workloads: %w", err)
+	}
+	var raw []PreprocessedWorkload
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse workloads: %w", err)
+	}
+	starts := make([]time.Time, len(raw))
+	var first time.Time
+	for i, p := range raw {
+		if p.StartTime == "" {
+			continue
+		}
+		if starts[i], err = time.Parse(time.RFC3339, p.StartTime); err != nil {
+			return nil, fmt.Errorf("workload %d: start_time: %w", i, err)
+		}
+		if first.IsZero() || starts[i].Before(first) {
+			first = starts[i]
+		}
+	}
+	workloads := make(WorkloadSet, 0, len(raw))
+	for i, p := range raw {
+		w := WorkloadProfile{
+			Name:               p.Name,
+			Namespace:          p.Namespace,
+			CPURequirements:    p.CPURequest,
+			MemoryRequirements: p.MemoryRequestGiB,
+			UsageCPU:           float64(p.CPURequest) * p.CPUUsage / 100,
+			UsageMemory:        p.MemoryRequestGiB * p.MemUsage / 100,
+			Labels:             p.Labels,
+		}
+		if w.Name == "" {
+			w.Name = fmt.Sprintf("workload-%d", i)
+		}
+		if !starts[i].IsZero() {
+			w.ArrivalSeconds = starts[i].Sub(first).Seconds()
+		}
+		if p.EndTime != "" {
+			end, err := time.Parse(time.RFC3339, p.EndTime)
+			if err != nil {
+				return nil, fmt.Errorf("workload %d: end_time: %w", i, err)
+			}
+			if !starts[i].IsZero() && end.After(starts[i]) {
+				w.DurationSeconds = end.Sub(starts[i]).Seconds()
+			}
+		}
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+// LoadWorkloads loads a custom workloads file of the given format, as the -workloads flag does.
+func LoadWorkloads(path string, format WorkloadFormat) (WorkloadSet, error) {
+	if format == WorkloadFormatPreprocessed {
+		return LoadPreprocessedWorkloads(path)
+	}
+	return loadCustomWorkloads(path)
+}
@@ -0,0 +1,58 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPreprocessedWorkloads(t *testing.T) {
+	workloads, err := LoadPreprocessedWorkloads("testdata/preprocessed/workloads.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Requests, not usage, become requirements, with usage converted from percent; arrivals count from the earliest start
+	want := WorkloadSet{
+		{Name: "workload-vm-a", CPURequirements: 2, MemoryRequirements: 3.5, UsageCPU: 0.25, UsageMemory: 1.4, ArrivalSeconds: 300, DurationSeconds: 3600, Labels: map[string]string{"workload_type": "Interactive"}},
+		{Name: "workload-vm-b", CPURequirements: 8, MemoryRequirements: 56, UsageCPU: 6.8, UsageMemory: 39.2, DurationSeconds: 86400, Labels: map[string]string{"workload_type": "Delay-insensitive"}},
+		{Name: "workload-2", CPURequirements: 1, MemoryRequirements: 0.75, UsageCPU: 0.03, UsageMemory: 0.075, ArrivalSeconds: 600, Labels: map[string]string{"workload_type": "Unknown"}},
+	}
+	if !reflect.DeepEqual(workloads, want) {
+		t.Errorf("expected\n%+v\ngot\n%+v", want, workloads)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(bad, []byte(`[{"cpu_request": 1, "start_time": "yesterday"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPreprocessedWorkloads(bad); err == nil {
+		t.Error("expected an error for an unparsable start_time")
+	}
+}
+
+func TestRunCustomWorkloadSimulation_Preprocessed(t *testing.T) {
+	skus := filepath.Join(t.TempDir(), "skus.json")
+	if err := os.WriteFile(skus, []byte(`[{"Name":"Standard_D8s_v5","Family":"DSv5","VCpus":8,"MemoryGiB":64,"PricePerHour":0.4}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{WorkloadFormat: WorkloadFormatPreprocessed, CostLabelKey: "workload_type"}
+	result, _, err := RunCustomWorkloadSimulationWithConfig("testdata/preprocessed/workloads.json", skus, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unpacked != 0 || result.CostByLabel == nil || result.CostByLabel.Costs["Interactive"] == 0 {
+		t.Errorf("expected every workload packed and its cost attributed by workload_type, got %d unpacked and %+v", result.Unpacked, result.CostByLabel)
+	}
+}
+
+func TestParseWorkloadFormat(t *testing.T) {
+	for in, want := range map[string]WorkloadFormat{"": WorkloadFormatProfile, "profile": WorkloadFormatProfile, "preprocessed": WorkloadFormatPreprocessed} {
+		if got, err := ParseWorkloadFormat(in); err != nil || got != want {
+			t.Errorf("ParseWorkloadFormat(%q) = %q, %v; expected %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseWorkloadFormat("csv"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
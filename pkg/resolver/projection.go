@@ -0,0 +1,111 @@
+package resolver
+
+// DefaultHoursPerMonth is the average number of hours in a month (8760 / 12).
+const DefaultHoursPerMonth = 730.0
+
+// Capacity types reported by cost projections.
+const (
+	CapacityOnDemand = "on-demand"
+	CapacityReserved = "reserved"
+	CapacitySpot     = "spot"
+)
+
+// ReservedCoverage describes reservations applied to on-demand capacity.
+type ReservedCoverage struct {
+	Fraction float64 // share of on-demand spend covered by reservations, in [0,1]
+	Discount float64 // discount of reserved over on-demand prices, in [0,1]
+}
+
+// ProjectionOptions configures cost projections. The zero value projects list prices
+// for DefaultHoursPerMonth hours of uptime.
+type ProjectionOptions struct {
+	HoursPerMonth float64 // uptime per month; <= 0 means DefaultHoursPerMonth
+	SpotDiscount  float64 // discount of spot over list prices, in [0,1]
+	Reserved      ReservedCoverage
+}
+
+// Projection is a monthly and annual cost projection.
+type Projection struct {
+	HoursPerMonth  float64
+	Hourly         float64 // effective hourly cost after discounts
+	Monthly        float64
+	Annual         float64
+	ByCapacityType []CapacityCost // on-demand, reserved and spot, in that order
+}
+
+// CapacityCost is the part of a Projection attributed to one capacity type.
+type CapacityCost struct {
+	CapacityType string
+	Hourly       float64
+	Monthly      float64
+	Annual       float64
+}
+
+/*
+CostProjection projects the steady-state cost of a packing over a month and a year.
+
+VMs hosting a workload that requires spot are priced as spot with spotDiscount off their list
+price; the remaining (on-demand) spend is split into a reserved share, discounted by
+reserved.Discount, and a pay-as-you-go share. hoursPerMonth <= 0 means DefaultHoursPerMonth.
+*/
+func CostProjection(result PackingResult, hoursPerMonth, spotDiscount float64, reserved ReservedCoverage) Projection {
+	var onDemand, spot float64
+	for _, vm := range result.VMs {
+		if isSpotVM(vm) {
+			spot += vm.InstanceType.PricePerHour
+		} else {
+			onDemand += vm.InstanceType.PricePerHour
+		}
+	}
+	return ProjectCost(onDemand, spot, ProjectionOptions{HoursPerMonth: hoursPerMonth, SpotDiscount: spotDiscount, Reserved: reserved})
+}
+
+/*
+ProjectCost projects hourly on-demand and spot list-price costs over a month and a year.
+CostProjection feeds it the steady-state cost of a packing; simulations whose fleet changes
+over time should feed it their time-averaged hourly costs instead.
+*/
+func ProjectCost(onDemandHourly, spotHourly float64, opts ProjectionOptions) Projection {
+	hours := opts.HoursPerMonth
+	if hours <= 0 {
+		hours = DefaultHoursPerMonth
+	}
+	reservedFraction := clamp01(opts.Reserved.Fraction)
+	hourly := []struct {
+		capacityType string
+		cost         float64
+	}{
+		{CapacityOnDemand, onDemandHourly * (1 - reservedFraction)},
+		{CapacityReserved, onDemandHourly * reservedFraction * (1 - clamp01(opts.Reserved.Discount))},
+		{CapacitySpot, spotHourly * (1 - clamp01(opts.SpotDiscount))},
+	}
+	p := Projection{HoursPerMonth: hours}
+	for _, h := range hourly {
+		c := CapacityCost{CapacityType: h.capacityType, Hourly: h.cost, Monthly: h.cost * hours, Annual: h.cost * hours * 12}
+		p.ByCapacityType = append(p.ByCapacityType, c)
+		p.Hourly += c.Hourly
+		p.Monthly += c.Monthly
+		p.Annual += c.Annual
+	}
+	return p
+}
+
+// isSpotVM reports whether a VM must be spot capacity, i.e. hosts a workload requiring spot.
+func isSpotVM(vm PackedVM) bool {
+	for _, w := range vm.Workloads {
+		if requiresSpot(w) {
+			return true
+		}
+	}
+	return false
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
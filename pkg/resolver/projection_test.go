@@ -0,0 +1,39 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCostProjection(t *testing.T) {
+	result := PackingResult{VMs: []PackedVM{
+		{InstanceType: AzureInstanceSpec{PricePerHour: 1.0}, Workloads: []WorkloadProfile{{CPURequirements: 1}}},
+		{InstanceType: AzureInstanceSpec{PricePerHour: 0.5}, Workloads: []WorkloadProfile{{CPURequirements: 1, RequireSpot: true}}},
+	}}
+
+	p := CostProjection(result, 0, 0, ReservedCoverage{})
+	if p.HoursPerMonth != DefaultHoursPerMonth || math.Abs(p.Monthly-1.5*730) > 1e-9 || math.Abs(p.Annual-1.5*730*12) > 1e-9 {
+		t.Errorf("unexpected list-price projection: %+v", p)
+	}
+
+	// 50% of the $1/h on-demand VM reserved at 40% off, spot at 80% off, 200h/month uptime.
+	p = CostProjection(result, 200, 0.8, ReservedCoverage{Fraction: 0.5, Discount: 0.4})
+	want := map[string]float64{
+		CapacityOnDemand: 0.5,
+		CapacityReserved: 0.5 * 0.6,
+		CapacitySpot:     0.5 * 0.2,
+	}
+	var sum float64
+	for _, c := range p.ByCapacityType {
+		if math.Abs(c.Hourly-want[c.CapacityType]) > 1e-9 {
+			t.Errorf("%s: expected %v/h, got %v/h", c.CapacityType, want[c.CapacityType], c.Hourly)
+		}
+		if math.Abs(c.Monthly-c.Hourly*200) > 1e-9 || math.Abs(c.Annual-c.Monthly*12) > 1e-9 {
+			t.Errorf("%s: inconsistent projection %+v", c.CapacityType, c)
+		}
+		sum += c.Monthly
+	}
+	if math.Abs(p.Monthly-sum) > 1e-9 || math.Abs(p.Hourly-0.9) > 1e-9 {
+		t.Errorf("expected breakdown to sum to the total 0.9/h, got %+v", p)
+	}
+}
@@ -0,0 +1,93 @@
+package resolver
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+/*
+pruneCheapest keeps the n cheapest candidates that can hold the workload on their own,
+mirroring how karpenter truncates its instance type list before launching. Candidates
+too small for the workload are never plausible winners of a packing decision, so they
+are dropped first; if no candidate can hold the workload the input is returned unchanged
+so that selection behaves exactly as without pruning.
+
+Because the unpruned ranking may pick an undersized SKU (cheapness outweighs a partial
+fit in ScoreInstance), pruned and unpruned winners can differ; with VerifyPruning on,
+such selections are counted in PruneStats.Divergences.
+*/
+func pruneCheapest(candidates []AzureInstanceSpec, workload WorkloadProfile, n int) []AzureInstanceSpec {
+	var feasible []int
+	for i, c := range candidates {
+		if c.VCpus >= workload.CPURequirements && fitsWithin(workload.MemoryRequirements, c.MemoryGiB) {
+			feasible = append(feasible, i)
+		}
+	}
+	if len(feasible) == 0 {
+		return candidates
+	}
+	sort.SliceStable(feasible, func(i, j int) bool {
+		return candidates[feasible[i]].PricePerHour < candidates[feasible[j]].PricePerHour
+	})
+	if len(feasible) > n {
+		feasible = feasible[:n]
+	}
+	out := make([]AzureInstanceSpec, len(feasible))
+	for i, idx := range feasible {
+		out[i] = candidates[idx]
+	}
+	return out
+}
+
+/*
+PruneStats collects metrics about candidate pruning (Config.PruneTopN).
+It is safe for concurrent use; pass the same pointer to every Config that should report into it.
+*/
+type PruneStats struct {
+	selections  atomic.Int64
+	considered  atomic.Int64
+	kept        atomic.Int64
+	verified    atomic.Int64
+	divergences atomic.Int64
+}
+
+// PruneSummary is a point-in-time copy of PruneStats.
+type PruneSummary struct {
+	Selections  int64 // selections that went through pruning
+	Considered  int64 // candidates that passed the filters, summed over selections
+	Kept        int64 // candidates left after pruning, summed over selections
+	Verified    int64 // selections compared against the unpruned winner (Config.VerifyPruning)
+	Divergences int64 // verified selections whose pruned winner differed from the unpruned winner
+}
+
+func (p *PruneStats) record(considered, kept int) {
+	p.selections.Add(1)
+	p.considered.Add(int64(considered))
+	p.kept.Add(int64(kept))
+}
+
+func (p *PruneStats) verify(diverged bool) {
+	p.verified.Add(1)
+	if diverged {
+		p.divergences.Add(1)
+	}
+}
+
+// Summary returns the current values of the counters.
+func (p *PruneStats) Summary() PruneSummary {
+	return PruneSummary{
+		Selections:  p.selections.Load(),
+		Considered:  p.considered.Load(),
+		Kept:        p.kept.Load(),
+		Verified:    p.verified.Load(),
+		Divergences: p.divergences.Load(),
+	}
+}
+
+// DivergenceRate returns the fraction of verified selections whose winner changed due to pruning.
+func (s PruneSummary) DivergenceRate() float64 {
+	if s.Verified == 0 {
+		return 0
+	}
+	return float64(s.Divergences) / float64(s.Verified)
+}
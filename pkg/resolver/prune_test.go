@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	"testing"
+)
+
+func pruningFixtureWorkloads() WorkloadSet {
+	var workloads WorkloadSet
+	for cpu := 1; cpu <= 16; cpu *= 2 {
+		for _, memPerCPU := range []float64{1, 2, 4, 8} {
+			workloads = append(workloads, WorkloadProfile{CPURequirements: cpu, MemoryRequirements: float64(cpu) * memPerCPU})
+		}
+	}
+	return workloads
+}
+
+func TestPruneCheapest(t *testing.T) {
+	candidates := dummyInstanceTypes()
+	pruned := pruneCheapest(candidates, WorkloadProfile{CPURequirements: 8, MemoryRequirements: 32}, 3)
+	if len(pruned) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(pruned))
+	}
+	want := []string{"Standard_D8_v3", "Standard_E8s_v3", "Standard_D16_v3"}
+	for i, c := range pruned {
+		if c.Name != want[i] {
+			t.Errorf("pruned[%d]: expected %s, got %s", i, want[i], c.Name)
+		}
+	}
+	// Nothing can hold the workload: pruning must not change the candidate set.
+	huge := WorkloadProfile{CPURequirements: 1024}
+	if got := pruneCheapest(candidates, huge, 3); len(got) != len(candidates) {
+		t.Errorf("expected all %d candidates when none is feasible, got %d", len(candidates), len(got))
+	}
+}
+
+// The unpruned ranking can pick a candidate that is too small for the workload, because
+// ScoreInstance rewards cheap SKUs more than it penalizes a partial fit. Pruning only keeps
+// candidates that can hold the workload, so the documented divergence is limited to
+// selections where the unpruned winner is undersized.
+func TestSelectWithConfig_PruneVerification(t *testing.T) {
+	candidates := dummyInstanceTypes()
+	for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive} {
+		stats := &PruneStats{}
+		cfg := Config{Strategy: strategy, PruneTopN: 5, VerifyPruning: true, PruneStats: stats}
+		undersized := 0
+		for _, w := range pruningFixtureWorkloads() {
+			pruned, _ := selectWithConfig(candidates, w, cfg)
+			full, _ := selectWithStrategy(candidates, w, strategy)
+			if pruned.Name == full.Name {
+				continue
+			}
+			if full.VCpus >= w.CPURequirements && full.MemoryGiB >= w.MemoryRequirements {
+				t.Errorf("%s: pruned winner %s differs from feasible full winner %s for %+v", strategy, pruned.Name, full.Name, w)
+			}
+			undersized++
+		}
+		sum := stats.Summary()
+		if sum.Selections != int64(len(pruningFixtureWorkloads())) || sum.Verified != sum.Selections {
+			t.Errorf("%s: expected every selection to be pruned and verified, got %+v", strategy, sum)
+		}
+		if sum.Divergences != int64(undersized) {
+			t.Errorf("%s: expected %d divergences, got %d", strategy, undersized, sum.Divergences)
+		}
+		if sum.Kept >= sum.Considered {
+			t.Errorf("%s: expected pruning to reduce the candidate set, got %+v", strategy, sum)
+		}
+	}
+}
+
+func TestSelectWithConfig_PruneDisabledMatchesDefault(t *testing.T) {
+	candidates := dummyInstanceTypes()
+	stats := &PruneStats{}
+	for _, w := range pruningFixtureWorkloads() {
+		got, gotScore := selectWithConfig(candidates, w, Config{PruneStats: stats})
+		want, wantScore := selectWithStrategy(candidates, w, StrategyGeneralPurpose)
+		if got.Name != want.Name || gotScore != wantScore {
+			t.Errorf("expected %s (%v), got %s (%v)", want.Name, wantScore, got.Name, gotScore)
+		}
+	}
+	if sum := stats.Summary(); sum.Selections != 0 {
+		t.Errorf("expected no pruning metrics when PruneTopN is 0, got %+v", sum)
+	}
+}
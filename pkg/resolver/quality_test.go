@@ -0,0 +1,129 @@
+package resolver
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateQuality = flag.Bool("update-quality", false, "rewrite testdata/quality_baselines.json from the current packing results")
+
+const (
+	qualityBaselinesPath = "testdata/quality_baselines.json"
+	qualityUpdateCommand = "go test ./pkg/resolver -run TestPackingQuality -update-quality"
+	// qualityCostTolerance and qualityVMTolerance are the relative deviations allowed from the baseline.
+	qualityCostTolerance = 0.01
+	qualityVMTolerance   = 0.02
+	// qualityFloorMargin is how many percentage points below the recorded utilization the floor is set.
+	qualityFloorMargin = 5.0
+)
+
+// qualityBaseline is the recorded packing quality of one algorithm on the golden fixtures.
+type qualityBaseline struct {
+	TotalCost    float64
+	VMsUsed      int
+	MinAvgCPU    float64 // utilization floors, in percent
+	MinAvgMemory float64
+	MaxUnpacked  int // cost must not improve by leaving more workloads unpacked
+}
+
+/*
+TestPackingQuality packs the committed fixture trace with every registered algorithm's default
+Config and checks total cost, VM count and unpacked workloads against
+testdata/quality_baselines.json, and that utilization stays above the recorded floors. Each algorithm is tracked independently; a newly
+registered algorithm fails until its baseline is recorded. Every other SortKey is tracked too,
+under e.g. "ffd/drf", so benchmarks of the orderings stay comparable.
+*/
+func TestPackingQuality(t *testing.T) {
+	skus, err := LoadAzureInstanceSpecs(filepath.Join("testdata", "golden", "skus.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	workloads, err := loadCustomWorkloads(filepath.Join("testdata", "golden", "workloads.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baselines := make(map[string]qualityBaseline)
+	if data, err := os.ReadFile(qualityBaselinesPath); err == nil {
+		if err := json.Unmarshal(data, &baselines); err != nil {
+			t.Fatalf("parse %s: %v", qualityBaselinesPath, err)
+		}
+	} else if !*updateQuality {
+		t.Fatalf("%v; record baselines with: %s", err, qualityUpdateCommand)
+	}
+
+	var runs []qualityRun
+	for _, algorithm := range PackingAlgorithms() {
+		for _, key := range []SortKey{SortKeyLegacy, SortKeyDominantShare, SortKeyCPU, SortKeyMemory} {
+			runs = append(runs, qualityRun{algorithm, key})
+		}
+	}
+	for _, run := range runs {
+		name := run.name()
+		pack, _ := PackingAlgorithm(run.algorithm)
+		sim := NewSimulationResult(pack(workloads, skus, Config{SortKey: run.key}))
+		if *updateQuality {
+			baselines[name] = qualityBaseline{
+				TotalCost:    sim.TotalCost,
+				VMsUsed:      sim.VMsUsed,
+				MinAvgCPU:    math.Max(sim.AvgCPU-qualityFloorMargin, 0),
+				MinAvgMemory: math.Max(sim.AvgMem-qualityFloorMargin, 0),
+				MaxUnpacked:  sim.Unpacked,
+			}
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			base, ok := baselines[name]
+			if !ok {
+				t.Fatalf("no quality baseline for %q; record it with: %s", name, qualityUpdateCommand)
+			}
+			if delta := sim.TotalCost - base.TotalCost; math.Abs(delta) > qualityCostTolerance*base.TotalCost {
+				t.Errorf("total cost $%.4f/h deviates from baseline $%.4f/h by %+.4f (%+.2f%%, tolerance %.0f%%); if intended, update with: %s",
+					sim.TotalCost, base.TotalCost, delta, 100*delta/base.TotalCost, 100*qualityCostTolerance, qualityUpdateCommand)
+			}
+			if delta := sim.VMsUsed - base.VMsUsed; math.Abs(float64(delta)) > math.Max(1, qualityVMTolerance*float64(base.VMsUsed)) {
+				t.Errorf("VM count %d deviates from baseline %d by %+d (tolerance %.0f%%); if intended, update with: %s",
+					sim.VMsUsed, base.VMsUsed, delta, 100*qualityVMTolerance, qualityUpdateCommand)
+			}
+			if sim.Unpacked > base.MaxUnpacked {
+				t.Errorf("%d workloads unpacked, baseline allows %d (%+d); if intended, update with: %s",
+					sim.Unpacked, base.MaxUnpacked, sim.Unpacked-base.MaxUnpacked, qualityUpdateCommand)
+			}
+			if sim.AvgCPU < base.MinAvgCPU {
+				t.Errorf("average CPU utilization %.2f%% is below the floor %.2f%% (%+.2f points); if intended, update with: %s",
+					sim.AvgCPU, base.MinAvgCPU, sim.AvgCPU-base.MinAvgCPU, qualityUpdateCommand)
+			}
+			if sim.AvgMem < base.MinAvgMemory {
+				t.Errorf("average memory utilization %.2f%% is below the floor %.2f%% (%+.2f points); if intended, update with: %s",
+					sim.AvgMem, base.MinAvgMemory, sim.AvgMem-base.MinAvgMemory, qualityUpdateCommand)
+			}
+		})
+	}
+
+	if *updateQuality {
+		data, err := json.MarshalIndent(baselines, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(qualityBaselinesPath, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// qualityRun is a packing algorithm with the order it packs workloads in.
+type qualityRun struct {
+	algorithm string
+	key       SortKey
+}
+
+// name returns the baseline name of r: the algorithm's, followed by the sort key unless legacy.
+func (r qualityRun) name() string {
+	if r.key == SortKeyLegacy {
+		return r.algorithm
+	}
+	return r.algorithm + "/" + string(r.key)
+}
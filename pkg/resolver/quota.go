@@ -0,0 +1,500 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+azureQuotaFamilies translates Azure's per-family vCPU quota names, as reported by
+"az quota list" and "az vm list-usage" (lower-cased), to the VM family names used in
+AzureInstanceSpec.Family and flat quota files.
+*/
+var azureQuotaFamilies = map[string]string{
+	"standardav2family":         "Av2",
+	"standardbsfamily":          "Bs",
+	"standardbsv2family":        "Bsv2",
+	"standarddv2family":         "Dv2",
+	"standarddsv2family":        "Dsv2",
+	"standarddv3family":         "Dv3",
+	"standarddsv3family":        "Dsv3",
+	"standarddav4family":        "Dav4",
+	"standarddasv4family":       "Dasv4",
+	"standarddv4family":         "Dv4",
+	"standarddsv4family":        "Dsv4",
+	"standardddv4family":        "Ddv4",
+	"standardddsv4family":       "Ddsv4",
+	"standarddv5family":         "Dv5",
+	"standarddsv5family":        "Dsv5",
+	"standardddv5family":        "Ddv5",
+	"standardddsv5family":       "Ddsv5",
+	"standarddasv5family":       "Dasv5",
+	"standarddadsv5family":      "Dadsv5",
+	"standarddpsv5family":       "Dpsv5",
+	"standardev3family":         "Ev3",
+	"standardesv3family":        "Esv3",
+	"standardeav4family":        "Eav4",
+	"standardeasv4family":       "Easv4",
+	"standardev4family":         "Ev4",
+	"standardesv4family":        "Esv4",
+	"standardedsv4family":       "Edsv4",
+	"standardev5family":         "Ev5",
+	"standardesv5family":        "Esv5",
+	"standardedsv5family":       "Edsv5",
+	"standardeasv5family":       "Easv5",
+	"standardeadsv5family":      "Eadsv5",
+	"standardfsv2family":        "Fsv2",
+	"standardlsv2family":        "Lsv2",
+	"standardlsv3family":        "Lsv3",
+	"standardmsfamily":          "Ms",
+	"standardmsv2family":        "Msv2",
+	"standardncsv3family":       "NCsv3",
+	"standardncasv3_t4family":   "NCasT4_v3",
+	"standardncadsa100v4family": "NCads_A100_v4",
+	"standardndsv2family":       "NDsv2",
+	"standardndasv4_a100family": "NDasrA100_v4",
+	"standardnvsv3family":       "NVsv3",
+	"standardnvadsa10v5family":  "NVadsA10_v5",
+	"standardhbrsv2family":      "HBrsv2",
+	"standarddcsv2family":       "DCsv2",
+	"standarddcasv5family":      "DCasv5",
+	"standardecasv5family":      "ECasv5",
+}
+
+// SpotQuotaKey is the QuotaMap entry limiting the vCPUs of all spot VMs together, named after
+// Azure's regional "Total Regional Low-priority vCPUs" quota. Spot VMs do not count against
+// their family's quota.
+const SpotQuotaKey = "lowPriorityCores"
+
+// SpotTotalVCpus returns the spot vCPU limit, and false when spot capacity is unlimited.
+func (q QuotaMap) SpotTotalVCpus() (int, bool) {
+	limit, ok := q[SpotQuotaKey]
+	return limit, ok
+}
+
+/*
+GPUQuotaKey is the QuotaMap entry limiting the GPUs of all VMs together, and GPUQuotaPrefix
+prefixes the entries limiting the GPUs of one family, e.g. {"gpus": 8, "gpus:NCasT4_v3": 4}.
+Azure limits GPU SKUs by their family's vCPUs only, but teams budget GPUs in GPUs; GPU quotas
+apply on top of the vCPU quotas, to spot and on-demand VMs alike.
+*/
+const (
+	GPUQuotaKey    = "gpus"
+	GPUQuotaPrefix = "gpus:"
+)
+
+// TotalGPUs returns the limit of GPUs of all VMs together, and false when it is unlimited.
+func (q QuotaMap) TotalGPUs() (int, bool) {
+	limit, ok := q[GPUQuotaKey]
+	return limit, ok
+}
+
+// QuotaUsage is the vCPUs and GPUs a packing charged against each quota.
+type QuotaUsage struct {
+	Families    map[string]int // on-demand vCPUs per quota family
+	SpotVCpus   int            // vCPUs of spot VMs, charged against SpotQuotaKey
+	GPUFamilies map[string]int `json:",omitempty"` // GPUs per quota family, charged against its GPUQuotaPrefix entry
+	GPUs        int            `json:",omitempty"` // GPUs of all VMs, charged against GPUQuotaKey
+}
+
+// QuotaFamily returns the VM family an Azure quota name such as "standardDSv3Family" limits.
+func QuotaFamily(azureName string) (string, bool) {
+	family, ok := azureQuotaFamilies[strings.ToLower(azureName)]
+	return family, ok
+}
+
+// azureQuotaUsage is one entry of "az vm list-usage" or "az quota list" output. The CLI
+// prints the numbers as strings in some versions, hence quotaNumber.
+type azureQuotaUsage struct {
+	Name struct {
+		Value string `json:"value"`
+	} `json:"name"`
+	Limit        quotaNumber `json:"limit"`
+	CurrentValue quotaNumber `json:"currentValue"`
+}
+
+// quotaNumber is a JSON number that may also be quoted.
+type quotaNumber int
+
+func (n *quotaNumber) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("quota value %s: %w", data, err)
+	}
+	*n = quotaNumber(v)
+	return nil
+}
+
+/*
+LoadQuotaWithWarnings loads a quota file in either format:
+
+  - a flat object mapping family to max vCPUs, e.g. {"Dsv3": 100}, which may also hold the
+    spot and GPU quotas (see SpotQuotaKey and GPUQuotaKey);
+  - Azure's usage output, a JSON array (or an {"value": [...]} REST response) of entries with
+    name.value, limit and currentValue, as printed by "az vm list-usage" or "az quota list".
+
+Azure quota names are translated to families with QuotaFamily, and only the remaining quota,
+limit minus currentValue, is available to the simulator. The spot quota "lowPriorityCores"
+becomes SpotQuotaKey; other quotas that do not limit a family, such as the regional "cores"
+total, are skipped. Family quotas missing from the translation
+table are kept under their Azure name, which matches SKU files that use Azure's family names,
+and reported as warnings.
+*/
+func LoadQuotaWithWarnings(path string) (QuotaMap, []string, error) {
+	if path == "" {
+		return nil, nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	q, warnings, err := parseQuota(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return q, warnings, nil
+}
+
+// parseQuota decodes either quota file format, see LoadQuotaWithWarnings.
+func parseQuota(data []byte) (QuotaMap, []string, error) {
+	trimmed := bytes.TrimSpace(data)
+	var usages []azureQuotaUsage
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &usages); err != nil {
+			return nil, nil, err
+		}
+	default:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &fields); err != nil {
+			return nil, nil, err
+		}
+		value, ok := fields["value"]
+		if !ok || !bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+			var q QuotaMap
+			if err := json.Unmarshal(trimmed, &q); err != nil {
+				return nil, nil, err
+			}
+			return q, nil, nil
+		}
+		if err := json.Unmarshal(value, &usages); err != nil {
+			return nil, nil, err
+		}
+	}
+	return quotaFromUsages(usages)
+}
+
+// quotaFromUsages builds the remaining per-family quota from Azure usage entries.
+func quotaFromUsages(usages []azureQuotaUsage) (QuotaMap, []string, error) {
+	q := make(QuotaMap)
+	var unknown []string
+	for _, u := range usages {
+		name := u.Name.Value
+		if name == "" {
+			return nil, nil, fmt.Errorf("quota entry without name.value")
+		}
+		family, ok := QuotaFamily(name)
+		switch {
+		case strings.EqualFold(name, SpotQuotaKey):
+			family = SpotQuotaKey
+		case !strings.HasSuffix(strings.ToLower(name), "family"):
+			continue
+		case !ok:
+			family = name
+			unknown = append(unknown, name)
+		}
+		remaining := int(u.Limit) - int(u.CurrentValue)
+		if remaining < 0 {
+			remaining = 0
+		}
+		q[family] += remaining
+	}
+	sort.Strings(unknown)
+	var warnings []string
+	for _, name := range unknown {
+		warnings = append(warnings, fmt.Sprintf("unknown quota family %q, kept under its Azure name", name))
+	}
+	return q, warnings, nil
+}
+
+/*
+limit returns the quota key and vCPU limit that apply to VMs of family: the family itself when
+q has it, otherwise the family QuotaFamily translates it to, so SKU files using Azure's family
+names ("standardDSv3Family") are limited by quotas for "Dsv3". ok is false for unlimited families.
+*/
+func (q QuotaMap) limit(family string) (key string, limit int, ok bool) {
+	if limit, ok := q[family]; ok {
+		return family, limit, true
+	}
+	if short, known := QuotaFamily(family); known {
+		if limit, ok := q[short]; ok {
+			return short, limit, true
+		}
+	}
+	return family, 0, false
+}
+
+// gpuLimit is limit for the GPU quota of family (see GPUQuotaPrefix): the key is the family the
+// entry names, without the prefix.
+func (q QuotaMap) gpuLimit(family string) (key string, limit int, ok bool) {
+	if limit, ok := q[GPUQuotaPrefix+family]; ok {
+		return family, limit, true
+	}
+	if short, known := QuotaFamily(family); known {
+		if limit, ok := q[GPUQuotaPrefix+short]; ok {
+			return short, limit, true
+		}
+	}
+	return family, 0, false
+}
+
+// gpuQuota accumulates the GPUs charged against the GPU quotas of a QuotaMap.
+type gpuQuota struct {
+	families map[string]int
+	total    int
+}
+
+// exceeded reports whether provisioning vm would exceed the total or its family's GPU quota.
+func (g *gpuQuota) exceeded(q QuotaMap, vm AzureInstanceSpec) bool {
+	if vm.GPUCount == 0 {
+		return false
+	}
+	if limit, ok := q.TotalGPUs(); ok && g.total+vm.GPUCount > limit {
+		return true
+	}
+	key, limit, ok := q.gpuLimit(vm.Family)
+	return ok && g.families[key]+vm.GPUCount > limit
+}
+
+func (g *gpuQuota) add(q QuotaMap, vm AzureInstanceSpec) {
+	if vm.GPUCount == 0 {
+		return
+	}
+	if g.families == nil {
+		g.families = make(map[string]int)
+	}
+	key, _, _ := q.gpuLimit(vm.Family)
+	g.families[key] += vm.GPUCount
+	g.total += vm.GPUCount
+}
+
+/*
+AllowedFamiliesFromQuota models a subscription that only has the VM series enabled that it has
+quota for: it keeps the candidates whose family has a quota above 0 and returns the families of
+the others, sorted. Unlike QuotaMap's limits, a family without an entry is not unlimited here
+but unavailable, and spot VMs are not exempt. With a nil quota every candidate is kept.
+*/
+func AllowedFamiliesFromQuota(candidates []AzureInstanceSpec, quota QuotaMap) (allowed []AzureInstanceSpec, excluded []string) {
+	if quota == nil {
+		return candidates, nil
+	}
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if _, limit, ok := quota.limit(c.Family); ok && limit > 0 {
+			allowed = append(allowed, c)
+			continue
+		}
+		if !seen[c.Family] {
+			seen[c.Family] = true
+			excluded = append(excluded, c.Family)
+		}
+	}
+	sort.Strings(excluded)
+	return allowed, excluded
+}
+
+// QuotaShortfall is a group of workloads needing more vCPUs, or GPUs, than the quota of the
+// only families able to host them.
+type QuotaShortfall struct {
+	Families       []string // quota families able to host the workloads
+	Workloads      int
+	RequiredVCpus  int
+	AvailableVCpus int
+	// RequiredGPUs and AvailableGPUs are set instead of the vCPUs for a shortfall of GPU quota
+	// (see GPUQuotaKey); Families is then GPUQuotaKey for the total.
+	RequiredGPUs  int `json:",omitempty"`
+	AvailableGPUs int `json:",omitempty"`
+}
+
+func (s QuotaShortfall) String() string {
+	if s.RequiredGPUs > 0 {
+		return fmt.Sprintf("%d workloads need %d GPUs but only fit families %s with %d GPUs of quota",
+			s.Workloads, s.RequiredGPUs, strings.Join(s.Families, ","), s.AvailableGPUs)
+	}
+	return fmt.Sprintf("%d workloads need %d vCPUs but only fit families %s with %d vCPUs of quota",
+		s.Workloads, s.RequiredVCpus, strings.Join(s.Families, ","), s.AvailableVCpus)
+}
+
+// deficit returns how many vCPUs or GPUs the shortfall lacks.
+func (s QuotaShortfall) deficit() int {
+	return s.RequiredVCpus - s.AvailableVCpus + s.RequiredGPUs - s.AvailableGPUs
+}
+
+/*
+CheckQuotaFeasibility reports whether workloads can possibly fit under quota before packing
+them. Every workload can only run on the families of the candidates that pass the default
+filters and have room for it, so every set of families must have at least as much quota as
+the vCPUs requested by the workloads that fit nowhere else. The check covers all families
+together and each set of families some workload is confined to, e.g. the GPU families of GPU
+workloads, which the overall total can hide. Workloads requiring spot capacity are checked
+against the spot quota (SpotQuotaKey) instead. GPU quotas are checked the same way against
+the GPUs workloads request, of spot and on-demand workloads alike: per set of families with a
+GPU quota (see GPUQuotaPrefix) and in total (see GPUQuotaKey).
+
+Requested vCPUs are a lower bound of the vCPUs the VMs will use, so an empty report does not
+guarantee that packing succeeds. Workloads that fit an unlimited family or no candidate at
+all, and headroom buffers, are not counted.
+*/
+func CheckQuotaFeasibility(workloads WorkloadSet, candidates []AzureInstanceSpec, quota QuotaMap) []QuotaShortfall {
+	if quota == nil {
+		return nil
+	}
+	spotLimit, spotLimited := quota.SpotTotalVCpus()
+	spot := QuotaShortfall{Families: []string{SpotQuotaKey}, AvailableVCpus: spotLimit}
+	gpuLimit, gpuLimited := quota.TotalGPUs()
+	gpus := QuotaShortfall{Families: []string{GPUQuotaKey}, AvailableGPUs: gpuLimit}
+	var onDemand, gpuWorkloads WorkloadSet
+	for _, w := range workloads {
+		if w.Headroom {
+			continue
+		}
+		families, unlimited := quotaFamiliesFor(w, candidates, quota.limit)
+		fits := unlimited || len(families) > 0
+		if n := w.gpusRequired(); n > 0 && fits {
+			gpuWorkloads = append(gpuWorkloads, w)
+			if gpuLimited {
+				gpus.Workloads++
+				gpus.RequiredGPUs += n
+			}
+		}
+		if requiresSpot(w) {
+			if spotLimited && fits {
+				spot.Workloads++
+				spot.RequiredVCpus += w.CPURequirements
+			}
+			continue
+		}
+		onDemand = append(onDemand, w)
+	}
+
+	var shortfalls []QuotaShortfall
+	if spot.RequiredVCpus > spot.AvailableVCpus {
+		shortfalls = append(shortfalls, spot)
+	}
+	if gpus.RequiredGPUs > gpus.AvailableGPUs {
+		shortfalls = append(shortfalls, gpus)
+	}
+	shortfalls = append(shortfalls, confinedShortfalls(onDemand, candidates, quota.limit, func(w WorkloadProfile) int { return w.CPURequirements })...)
+	for _, s := range confinedShortfalls(gpuWorkloads, candidates, quota.gpuLimit, WorkloadProfile.gpusRequired) {
+		s.RequiredGPUs, s.AvailableGPUs, s.RequiredVCpus, s.AvailableVCpus = s.RequiredVCpus, s.AvailableVCpus, 0, 0
+		shortfalls = append(shortfalls, s)
+	}
+	sort.Slice(shortfalls, func(i, j int) bool {
+		if di, dj := shortfalls[i].deficit(), shortfalls[j].deficit(); di != dj {
+			return di > dj
+		}
+		return strings.Join(shortfalls[i].Families, ",") < strings.Join(shortfalls[j].Families, ",")
+	})
+	return shortfalls
+}
+
+/*
+confinedShortfalls checks the quota that limit returns per family against need, summed over the
+workloads confined to each set of families and over all families together (see
+CheckQuotaFeasibility). It reports the shortfalls in RequiredVCpus and AvailableVCpus, whatever
+the quota counts.
+*/
+func confinedShortfalls(workloads WorkloadSet, candidates []AzureInstanceSpec, limit func(family string) (string, int, bool), need func(WorkloadProfile) int) []QuotaShortfall {
+	type confined struct {
+		families map[string]bool
+		need     int
+	}
+	var groups []confined
+	sets := make(map[string][]string) // joined family set -> sorted families
+	all := make(map[string]bool)
+	limits := make(map[string]int)
+	for _, w := range workloads {
+		families, unlimited := quotaFamiliesFor(w, candidates, limit)
+		if unlimited || len(families) == 0 {
+			continue
+		}
+		groups = append(groups, confined{families: families, need: need(w)})
+		sorted := make([]string, 0, len(families))
+		for f := range families {
+			sorted = append(sorted, f)
+			all[f] = true
+		}
+		sort.Strings(sorted)
+		sets[strings.Join(sorted, ",")] = sorted
+	}
+	if len(all) > 0 {
+		union := make([]string, 0, len(all))
+		for f := range all {
+			union = append(union, f)
+		}
+		sort.Strings(union)
+		sets[strings.Join(union, ",")] = union
+	}
+	for _, c := range candidates {
+		if key, l, ok := limit(c.Family); ok {
+			limits[key] = l
+		}
+	}
+
+	var shortfalls []QuotaShortfall
+	for _, set := range sets {
+		in := make(map[string]bool, len(set))
+		s := QuotaShortfall{Families: set}
+		for _, f := range set {
+			in[f] = true
+			s.AvailableVCpus += limits[f]
+		}
+		for _, g := range groups {
+			if subsetOf(g.families, in) {
+				s.Workloads++
+				s.RequiredVCpus += g.need
+			}
+		}
+		if s.RequiredVCpus > s.AvailableVCpus {
+			shortfalls = append(shortfalls, s)
+		}
+	}
+	return shortfalls
+}
+
+// quotaFamiliesFor returns the quota families, as keyed by limit, of the candidates able to host
+// w, and whether one of them has no quota limit.
+func quotaFamiliesFor(w WorkloadProfile, candidates []AzureInstanceSpec, limit func(family string) (string, int, bool)) (map[string]bool, bool) {
+	families := make(map[string]bool)
+	for _, c := range FilterInstanceTypes(candidates, w, defaultFilterFuncs...) {
+		if !capacityOf(c, FitMargin{}).fits(w) {
+			continue
+		}
+		key, _, limited := limit(c.Family)
+		if !limited {
+			return nil, true
+		}
+		families[key] = true
+	}
+	return families, false
+}
+
+// subsetOf reports whether every key of a is in b.
+func subsetOf(a, b map[string]bool) bool {
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadQuotaAzureUsage(t *testing.T) {
+	q, warnings, err := LoadQuotaWithWarnings(filepath.Join("testdata", "quota", "az_vm_list_usage.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := QuotaMap{"Dsv3": 84, "Esv5": 0, "NCasT4_v3": 24, "standardNVADSA10v6Family": 10}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("expected remaining quota %v, got %v", want, q)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "standardNVADSA10v6Family") {
+		t.Errorf("expected one warning for the unknown family, got %v", warnings)
+	}
+}
+
+func TestLoadQuotaRESTResponse(t *testing.T) {
+	q, warnings, err := LoadQuotaWithWarnings(filepath.Join("testdata", "quota", "microsoft_quota_usages.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (QuotaMap{"Fsv2": 16, SpotQuotaKey: 10}); !reflect.DeepEqual(q, want) || len(warnings) != 0 {
+		t.Errorf("expected %v without warnings, got %v %v", want, q, warnings)
+	}
+}
+
+func TestLoadQuotaFlatMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	if err := os.WriteFile(path, []byte(`{"Dsv3": 100, "value": 8}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	q, err := LoadQuota(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (QuotaMap{"Dsv3": 100, "value": 8}); !reflect.DeepEqual(q, want) {
+		t.Errorf("expected %v, got %v", want, q)
+	}
+}
+
+func TestQuotaAppliesToAzureFamilyNames(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_E2s_v5", Family: "standardESv5Family", VCpus: 2, MemoryGiB: 16, PricePerHour: 0.05},
+		{Name: "Standard_D2s_v3", Family: "standardDSv3Family", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1},
+	}
+	workloads := WorkloadSet{{Name: "web", CPURequirements: 2, MemoryRequirements: 4}}
+	// The ESv5 quota is used up, so its remaining 0 vCPUs must not count as unlimited.
+	result := BinPackWorkloadsWithQuota(workloads, candidates, StrategyGeneralPurpose, QuotaMap{"Esv5": 0, "Dsv3": 84})
+	if len(result.VMs) != 1 || result.VMs[0].InstanceType.Name != "Standard_D2s_v3" {
+		t.Errorf("expected the exhausted ESv5 family to be skipped, got %+v", result.VMs)
+	}
+}
+
+func TestCheckQuotaFeasibilityGPUShortfall(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D8s_v5", Family: "standardDSv5Family", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.4},
+		{Name: "Standard_NC8as_T4_v3", Family: "standardNCASv3_T4Family", VCpus: 8, MemoryGiB: 56, PricePerHour: 0.75, GPUCount: 1, GPUType: "T4"},
+	}
+	var workloads WorkloadSet
+	for i := 0; i < 20; i++ {
+		workloads = append(workloads, WorkloadProfile{CPURequirements: 4, MemoryRequirements: 8})
+	}
+	workloads = append(workloads,
+		WorkloadProfile{Name: "train-0", CPURequirements: 8, MemoryRequirements: 32, GPURequirements: 1},
+		WorkloadProfile{Name: "train-1", CPURequirements: 8, MemoryRequirements: 32, GPURequirements: 1},
+	)
+	// 96 vCPUs requested against 1008 in total: only the GPU families fall short.
+	quota := QuotaMap{"Dsv5": 1000, "NCasT4_v3": 8}
+	shortfalls := CheckQuotaFeasibility(workloads, candidates, quota)
+	want := []QuotaShortfall{{Families: []string{"NCasT4_v3"}, Workloads: 2, RequiredVCpus: 16, AvailableVCpus: 8}}
+	if !reflect.DeepEqual(shortfalls, want) {
+		t.Fatalf("expected %+v, got %+v", want, shortfalls)
+	}
+	if got := CheckQuotaFeasibility(workloads, candidates, QuotaMap{"Dsv5": 1000, "NCasT4_v3": 16}); len(got) != 0 {
+		t.Errorf("expected enough GPU quota, got %+v", got)
+	}
+	// Overall shortfalls are reported too, and a family without quota is unlimited.
+	if got := CheckQuotaFeasibility(workloads, candidates, QuotaMap{"Dsv5": 40, "NCasT4_v3": 16}); len(got) != 1 || got[0].RequiredVCpus != 96 || got[0].AvailableVCpus != 56 {
+		t.Errorf("expected an overall shortfall, got %+v", got)
+	}
+	if got := CheckQuotaFeasibility(workloads, candidates, QuotaMap{"NCasT4_v3": 8}); len(got) != 1 || got[0].Workloads != 2 {
+		t.Errorf("expected only the GPU workloads to be confined to quota, got %+v", got)
+	}
+}
+
+func TestSimulateStrictQuota(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := WorkloadSet{{CPURequirements: 4, MemoryRequirements: 8}, {CPURequirements: 4, MemoryRequirements: 8}}
+	cfg := Config{Strategy: StrategyGeneralPurpose, Quota: QuotaMap{"Dsv5": 4}}
+	if _, _, err := simulate(workloads, candidates, cfg); err != nil {
+		t.Fatalf("expected a warning only, got %v", err)
+	}
+	cfg.StrictQuota = true
+	if _, _, err := simulate(workloads, candidates, cfg); err == nil || !strings.Contains(err.Error(), "8 vCPUs") {
+		t.Errorf("expected strict quota to fail, got %v", err)
+	}
+}
+
+func TestQuotaSpotPool(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2, SpotSupported: true}}
+	var workloads WorkloadSet
+	for i := 0; i < 4; i++ {
+		workloads = append(workloads, WorkloadProfile{Name: fmt.Sprintf("batch-%d", i), CPURequirements: 4, MemoryRequirements: 8, RequireSpot: true})
+	}
+	for i := 0; i < 3; i++ {
+		workloads = append(workloads, WorkloadProfile{Name: fmt.Sprintf("web-%d", i), CPURequirements: 4, MemoryRequirements: 8})
+	}
+	quota := QuotaMap{"Dsv5": 100, SpotQuotaKey: 8}
+	result := BinPackWorkloadsWithQuota(workloads, candidates, StrategyGeneralPurpose, quota)
+
+	if len(result.VMs) != 5 {
+		t.Errorf("expected 2 spot and 3 on-demand VMs, got %d", len(result.VMs))
+	}
+	if len(result.Unpacked) != 2 {
+		t.Fatalf("expected 2 spot workloads unpacked, got %+v", result.Unpacked)
+	}
+	for _, u := range result.Unpacked {
+		if !u.Workload.RequireSpot || u.Reason != ReasonSpotQuotaExhausted {
+			t.Errorf("expected only spot workloads to run out of spot quota, got %+v", u)
+		}
+	}
+	want := &QuotaUsage{Families: map[string]int{"Dsv5": 12}, SpotVCpus: 8}
+	if !reflect.DeepEqual(result.QuotaUsage, want) {
+		t.Errorf("expected usage %+v, got %+v", want, result.QuotaUsage)
+	}
+	for _, vm := range result.VMs {
+		for _, w := range vm.Workloads[1:] {
+			if w.RequireSpot != vm.Workloads[0].RequireSpot {
+				t.Errorf("spot and on-demand workloads share VM %+v", vm)
+			}
+		}
+	}
+
+	shortfalls := CheckQuotaFeasibility(workloads, candidates, quota)
+	wantShort := []QuotaShortfall{{Families: []string{SpotQuotaKey}, Workloads: 4, RequiredVCpus: 16, AvailableVCpus: 8}}
+	if !reflect.DeepEqual(shortfalls, wantShort) {
+		t.Errorf("expected spot shortfall %+v, got %+v", wantShort, shortfalls)
+	}
+}
+
+func TestQuotaGPUCount(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_NC6", Family: "NC", VCpus: 6, MemoryGiB: 56, PricePerHour: 0.9, GPUCount: 1, GPUType: "K80", AvailabilityZones: []string{"1", "2"}},
+		{Name: "Standard_D8s_v5", Family: "Dsv5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.4},
+	}
+	var workloads WorkloadSet
+	for i := 0; i < 5; i++ {
+		workloads = append(workloads, WorkloadProfile{Name: fmt.Sprintf("train-%d", i), CPURequirements: 6, MemoryRequirements: 40, GPURequirements: 1})
+	}
+	workloads = append(workloads, WorkloadProfile{Name: "web", CPURequirements: 4, MemoryRequirements: 8})
+	for _, quota := range []QuotaMap{{GPUQuotaKey: 4}, {GPUQuotaPrefix + "NC": 4, "NC": 100}} {
+		for _, name := range PackingAlgorithms() {
+			pack, _ := PackingAlgorithm(name)
+			result := pack(workloads, candidates, Config{Quota: quota})
+			if len(result.VMs) != 5 || len(result.Unpacked) != 1 {
+				t.Fatalf("%s, %v: expected four NC6 VMs and one for web, and one workload unpacked, got %d VMs and %+v", name, quota, len(result.VMs), result.Unpacked)
+			}
+			if u := result.Unpacked[0]; u.Workload.GPURequirements != 1 || u.Reason != ReasonGPUQuotaExhausted {
+				t.Errorf("%s, %v: expected the fifth GPU workload unpacked for GPU quota, got %+v", name, quota, u)
+			}
+			if u := result.QuotaUsage; u == nil || u.GPUs != 4 || !reflect.DeepEqual(u.GPUFamilies, map[string]int{"NC": 4}) {
+				t.Errorf("%s, %v: expected 4 GPUs of NC charged, got %+v", name, quota, u)
+			}
+		}
+	}
+
+	want := []QuotaShortfall{{Families: []string{GPUQuotaKey}, Workloads: 5, RequiredGPUs: 5, AvailableGPUs: 4}}
+	if got := CheckQuotaFeasibility(workloads, candidates, QuotaMap{GPUQuotaKey: 4}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected total GPU shortfall %+v, got %+v", want, got)
+	}
+	want = []QuotaShortfall{{Families: []string{"NC"}, Workloads: 5, RequiredGPUs: 5, AvailableGPUs: 4}}
+	if got := CheckQuotaFeasibility(workloads, candidates, QuotaMap{GPUQuotaPrefix + "NC": 4}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected family GPU shortfall %+v, got %+v", want, got)
+	} else if s := got[0].String(); !strings.Contains(s, "need 5 GPUs") {
+		t.Errorf("expected the shortfall in GPUs, got %q", s)
+	}
+	if got := CheckQuotaFeasibility(workloads, candidates, QuotaMap{GPUQuotaKey: 5}); len(got) != 0 {
+		t.Errorf("expected enough GPU quota, got %+v", got)
+	}
+}
+
+func TestAllowedFamiliesFromQuota(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v3", Family: "standardDSv3Family"},
+		{Name: "Standard_E4s_v5", Family: "Esv5"},
+		{Name: "Standard_F4s_v2", Family: "Fsv2"},
+		{Name: "Standard_F8s_v2", Family: "Fsv2"},
+	}
+	allowed, excluded := AllowedFamiliesFromQuota(candidates, QuotaMap{"Dsv3": 8, "Esv5": 0, SpotQuotaKey: 100})
+	if len(allowed) != 1 || allowed[0].Name != "Standard_D4s_v3" {
+		t.Errorf("expected only the D-series with quota, got %v", allowed)
+	}
+	if want := []string{"Esv5", "Fsv2"}; !reflect.DeepEqual(excluded, want) {
+		t.Errorf("expected excluded families %v, got %v", want, excluded)
+	}
+	if allowed, excluded := AllowedFamiliesFromQuota(candidates, nil); len(allowed) != len(candidates) || excluded != nil {
+		t.Errorf("expected every candidate without a quota, got %v excluding %v", allowed, excluded)
+	}
+}
+
+func TestSimulateQuotaFamiliesOnly(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D8s_v5", Family: "Dsv5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.4},
+		{Name: "Standard_NC4as_T4_v3", Family: "NCasT4_v3", VCpus: 4, MemoryGiB: 28, PricePerHour: 0.526, GPUCount: 1, GPUType: "T4"},
+	}
+	workloads := WorkloadSet{
+		{Name: "web", CPURequirements: 2, MemoryRequirements: 4},
+		{Name: "train", CPURequirements: 4, MemoryRequirements: 16, GPURequirements: 1},
+	}
+	for _, tc := range []struct {
+		name     string
+		quota    QuotaMap
+		strict   bool
+		unpacked int
+	}{
+		{"NC absent, lenient", QuotaMap{"Dsv5": 16}, false, 0},
+		{"NC absent, strict", QuotaMap{"Dsv5": 16}, true, 1},
+		{"NC zero, strict", QuotaMap{"Dsv5": 16, "NCasT4_v3": 0}, true, 1},
+		{"NC granted, strict", QuotaMap{"Dsv5": 16, "NCasT4_v3": 4}, true, 0},
+	} {
+		result, _, err := simulate(workloads, candidates, Config{Quota: tc.quota, QuotaFamiliesOnly: tc.strict})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if result.Unpacked != tc.unpacked {
+			t.Errorf("%s: expected %d unpacked, got %d", tc.name, tc.unpacked, result.Unpacked)
+		}
+		if tc.unpacked > 0 && !reflect.DeepEqual(result.ExcludedFamilies, []string{"NCasT4_v3"}) {
+			t.Errorf("%s: expected NCasT4_v3 reported excluded, got %v", tc.name, result.ExcludedFamilies)
+		}
+	}
+}
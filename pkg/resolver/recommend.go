@@ -0,0 +1,79 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ShapeRecommendation is one candidate SKU evaluated as a homogeneous fleet by
+// RecommendInstanceShapes.
+type ShapeRecommendation struct {
+	SKU       string
+	VCpus     int
+	MemoryGiB float64
+	// VMs is the fleet size and CostPerHour its cost.
+	VMs         int
+	CostPerHour float64
+	// CPUUtil and MemUtil are the achieved utilization of the fleet in percent.
+	CPUUtil float64
+	MemUtil float64
+	// WastedCostPerHour is the cost of the idle capacity (see ComputeWaste), including what the
+	// target utilization keeps free.
+	WastedCostPerHour float64
+	// Unpacked counts the workloads the SKU cannot hold at the target utilization.
+	Unpacked int
+}
+
+func (r ShapeRecommendation) String() string {
+	s := fmt.Sprintf("%d vCPU / %g GiB (%s): %d VMs, %.4f/h at %.0f%% CPU and %.0f%% memory, %.4f/h idle",
+		r.VCpus, r.MemoryGiB, r.SKU, r.VMs, r.CostPerHour, r.CPUUtil, r.MemUtil, r.WastedCostPerHour)
+	if r.Unpacked > 0 {
+		s += fmt.Sprintf(", %d unpacked", r.Unpacked)
+	}
+	return s
+}
+
+/*
+RecommendInstanceShapes answers which single node shape suits workloads best: it packs all of
+them onto each candidate alone, as a homogeneous fleet filled to targetUtil of every VM's
+vCPUs and memory (e.g. 0.8; values outside (0, 1] mean 1), and ranks the fleets by cost.
+Fleets that hold every workload come first, cheapest first; those leaving workloads unpacked
+follow, fewest unpacked first. Ties go to the smaller fleet, then the SKU name.
+*/
+func RecommendInstanceShapes(workloads WorkloadSet, candidates []AzureInstanceSpec, targetUtil float64) []ShapeRecommendation {
+	if targetUtil <= 0 || targetUtil > 1 {
+		targetUtil = 1
+	}
+	free := (1 - targetUtil) * 100
+	cfg := Config{FitMarginPercent: FitMargin{CPU: free, Memory: free}}
+	recs := make([]ShapeRecommendation, 0, len(candidates))
+	for _, c := range candidates {
+		result := BinPackWorkloadsWithConfig(workloads, []AzureInstanceSpec{c}, cfg)
+		cpu, mem := AverageUtilization(result.VMs)
+		recs = append(recs, ShapeRecommendation{
+			SKU:               c.Name,
+			VCpus:             c.VCpus,
+			MemoryGiB:         c.MemoryGiB,
+			VMs:               len(result.VMs),
+			CostPerHour:       TotalCost(result.VMs),
+			CPUUtil:           cpu,
+			MemUtil:           mem,
+			WastedCostPerHour: ComputeWaste(result).TotalWastedCostPerHour,
+			Unpacked:          len(result.Unpacked),
+		})
+	}
+	sort.SliceStable(recs, func(i, j int) bool {
+		a, b := recs[i], recs[j]
+		if a.Unpacked != b.Unpacked {
+			return a.Unpacked < b.Unpacked
+		}
+		if a.CostPerHour != b.CostPerHour {
+			return a.CostPerHour < b.CostPerHour
+		}
+		if a.VMs != b.VMs {
+			return a.VMs < b.VMs
+		}
+		return a.SKU < b.SKU
+	})
+	return recs
+}
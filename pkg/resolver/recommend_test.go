@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRecommendInstanceShapes(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		{Name: "Standard_D8s_v5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.4},
+		{Name: "Standard_F16s_v2", VCpus: 16, MemoryGiB: 32, PricePerHour: 0.7},
+		{Name: "Standard_E2s_v5", VCpus: 2, MemoryGiB: 16, PricePerHour: 0.15},
+	}
+	var workloads WorkloadSet
+	for range 6 {
+		workloads = append(workloads, WorkloadProfile{CPURequirements: 2, MemoryRequirements: 4})
+	}
+
+	// At 80%, a D4s_v5 keeps 3.2 vCPUs usable and holds one workload, a D8s_v5 6.4 and three,
+	// an F16s_v2 12.8 and all six; an E2s_v5 holds none.
+	recs := RecommendInstanceShapes(workloads, candidates, 0.8)
+	want := []struct {
+		sku      string
+		vms      int
+		cost     float64
+		unpacked int
+	}{
+		{"Standard_F16s_v2", 1, 0.7, 0},
+		{"Standard_D8s_v5", 2, 0.8, 0},
+		{"Standard_D4s_v5", 6, 1.2, 0},
+		{"Standard_E2s_v5", 0, 0, 6},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("expected %d recommendations, got %+v", len(want), recs)
+	}
+	for i, w := range want {
+		r := recs[i]
+		if r.SKU != w.sku || r.VMs != w.vms || math.Abs(r.CostPerHour-w.cost) > 1e-9 || r.Unpacked != w.unpacked {
+			t.Errorf("rank %d: expected %s with %d VMs at %.2f/h and %d unpacked, got %s", i+1, w.sku, w.vms, w.cost, w.unpacked, r)
+		}
+	}
+	best := recs[0]
+	if best.CPUUtil != 75 || best.MemUtil != 75 || math.Abs(best.WastedCostPerHour-0.175) > 1e-9 {
+		t.Errorf("expected the F16s_v2 fleet at 75%% CPU and memory with 0.175/h idle, got %s", best)
+	}
+
+	// Filled completely, two workloads share each D4s_v5 and it becomes the cheapest.
+	if recs := RecommendInstanceShapes(workloads, candidates, 1); recs[0].SKU != "Standard_D4s_v5" || recs[0].VMs != 3 {
+		t.Errorf("expected 3 D4s_v5 at 100%%, got %s", recs[0])
+	}
+}
@@ -0,0 +1,137 @@
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// csvSchemaPrefix starts the first line of CSV reports, followed by the schema version.
+const csvSchemaPrefix = "# instance-selection-sim results, schema "
+
+// csvMetadataPrefix starts the comment line after the schema line of CSV reports of runs with
+// metadata, followed by the JSON of the RunMetadata.
+const csvMetadataPrefix = "# metadata "
+
+// csvColumns are the columns of CSV reports after Strategy, with how to format and parse them.
+// The cost columns are labelled with the currency code in place of CUR.
+var csvColumns = []struct {
+	header string
+	format func(resolver.SimulationResult) string
+	parse  func(*resolver.SimulationResult, float64)
+}{
+	{"VMs Used", func(r resolver.SimulationResult) string { return strconv.Itoa(r.VMsUsed) }, func(r *resolver.SimulationResult, v float64) { r.VMsUsed = int(v) }},
+	{"Total Cost (CUR/h)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.2f", r.TotalCost) }, func(r *resolver.SimulationResult, v float64) { r.TotalCost = v }},
+	{"Avg CPU Util (%)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.1f", r.AvgCPU) }, func(r *resolver.SimulationResult, v float64) { r.AvgCPU = v }},
+	{"Avg Mem Util (%)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.1f", r.AvgMem) }, func(r *resolver.SimulationResult, v float64) { r.AvgMem = v }},
+	{"Headroom Cost (CUR/h)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.2f", r.HeadroomCost) }, func(r *resolver.SimulationResult, v float64) { r.HeadroomCost = v }},
+	{"Wasted Cost (CUR/h)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.2f", r.Waste.TotalWastedCostPerHour) }, func(r *resolver.SimulationResult, v float64) { r.Waste.TotalWastedCostPerHour = v }},
+	{"Distinct SKUs", func(r resolver.SimulationResult) string { return strconv.Itoa(r.DistinctSKUs) }, func(r *resolver.SimulationResult, v float64) { r.DistinctSKUs = int(v) }},
+	{"SKU Entropy", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.2f", r.SKUEntropy) }, func(r *resolver.SimulationResult, v float64) { r.SKUEntropy = v }},
+	{"GPU Util (%)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.1f", r.Utilization.GPU) }, func(r *resolver.SimulationResult, v float64) { r.Utilization.GPU = v }},
+	{"Storage Util (%)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.1f", r.Utilization.Storage) }, func(r *resolver.SimulationResult, v float64) { r.Utilization.Storage = v }},
+	{"Pod Slot Util (%)", func(r resolver.SimulationResult) string { return fmt.Sprintf("%.1f", r.Utilization.PodSlots) }, func(r *resolver.SimulationResult, v float64) { r.Utilization.PodSlots = v }},
+	{"Unpacked", func(r resolver.SimulationResult) string { return strconv.Itoa(r.Unpacked) }, func(r *resolver.SimulationResult, v float64) { r.Unpacked = int(v) }},
+}
+
+// WriteCSV writes the summary of every result of run as CSV, one row per result, after a
+// comment line with the schema version and one with the run's metadata, if any.
+func WriteCSV(w io.Writer, run resolver.SimulationRun) error {
+	cur := run.Currency()
+	fmt.Fprintf(w, "%s%d\n", csvSchemaPrefix, SchemaVersion)
+	if run.Metadata != nil {
+		data, err := json.Marshal(run.Metadata)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s%s\n", csvMetadataPrefix, data)
+	}
+	cw := csv.NewWriter(w)
+	header := []string{"Strategy"}
+	for _, c := range csvColumns {
+		header = append(header, strings.ReplaceAll(c.header, "CUR", cur))
+	}
+	cw.Write(header)
+	for _, nr := range run.Results {
+		row := []string{nr.Name}
+		for _, c := range csvColumns {
+			row = append(row, c.format(nr.Result))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+/*
+ReadCSV reads the summary rows of a CSV report, and its metadata line if any, into a run
+without per-VM detail. Reports without the schema line are version 1. Columns are matched by
+header, so reports with fewer columns read as zeros, and the currency is taken from the cost
+headers.
+*/
+func ReadCSV(r io.Reader) (resolver.SimulationRun, error) {
+	br := bufio.NewReader(r)
+	run := resolver.SimulationRun{SchemaVersion: 1}
+	if line, err := br.Peek(len(csvSchemaPrefix)); err == nil && string(line) == csvSchemaPrefix {
+		first, _ := br.ReadString('\n')
+		v, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(first, csvSchemaPrefix)))
+		if err != nil {
+			return resolver.SimulationRun{}, fmt.Errorf("bad schema line %q", strings.TrimSpace(first))
+		}
+		if v > SchemaVersion {
+			return resolver.SimulationRun{}, fmt.Errorf("report schema version %d is newer than %d", v, SchemaVersion)
+		}
+		run.SchemaVersion = v
+	}
+	if line, err := br.Peek(len(csvMetadataPrefix)); err == nil && string(line) == csvMetadataPrefix {
+		text, _ := br.ReadString('\n')
+		var m resolver.RunMetadata
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(text, csvMetadataPrefix)), &m); err != nil {
+			return resolver.SimulationRun{}, fmt.Errorf("bad metadata line: %w", err)
+		}
+		run.Metadata = &m
+	}
+	rows, err := csv.NewReader(br).ReadAll()
+	if err != nil {
+		return resolver.SimulationRun{}, err
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 || rows[0][0] != "Strategy" {
+		return resolver.SimulationRun{}, fmt.Errorf("not a results CSV: missing Strategy header")
+	}
+	header, currency := rows[0], ""
+	columns := make([]int, len(header)) // index into csvColumns, or -1
+	for i, h := range header {
+		columns[i] = -1
+		for j, c := range csvColumns {
+			before, after, ok := strings.Cut(c.header, "CUR")
+			if h == c.header || ok && strings.HasPrefix(h, before) && strings.HasSuffix(h, after) && len(h) > len(before)+len(after) {
+				columns[i] = j
+				if ok {
+					currency = h[len(before) : len(h)-len(after)]
+				}
+			}
+		}
+	}
+	for _, row := range rows[1:] {
+		nr := resolver.NamedResult{Name: row[0]}
+		nr.Result.Currency = currency
+		for i := 1; i < len(row) && i < len(header); i++ {
+			if columns[i] < 0 {
+				continue
+			}
+			v, err := strconv.ParseFloat(row[i], 64)
+			if err != nil {
+				return resolver.SimulationRun{}, fmt.Errorf("%s of %s: %w", header[i], row[0], err)
+			}
+			csvColumns[columns[i]].parse(&nr.Result, v)
+		}
+		run.Results = append(run.Results, nr)
+	}
+	return run, nil
+}
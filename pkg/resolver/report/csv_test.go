@@ -0,0 +1,112 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func TestParseRunVersions(t *testing.T) {
+	for _, tc := range []struct {
+		file    string
+		version int
+		detail  bool // per-VM detail, only in JSON
+		wasted  float64
+	}{
+		{"run_v1.json", 1, true, 0.048},
+		{"run_v2.json", 2, true, 0.048},
+		{"run_v3.json", 3, true, 0.048},
+		{"results_v1.csv", 1, false, 0}, // no wasted cost column yet
+		{"results_v2.csv", 2, false, 0.05},
+		{"results_v3.csv", 3, false, 0.05},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			run, err := ParseRun(f)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if run.SchemaVersion != tc.version || len(run.Results) != 2 {
+				t.Fatalf("expected 2 results of schema version %d, got %d of version %d", tc.version, len(run.Results), run.SchemaVersion)
+			}
+			got, naive := run.Results[0], run.Results[1]
+			if got.Name != "NewAlgorithm" || got.Result.VMsUsed != 1 || got.Result.AvgCPU != 75 || naive.Name != "Naive" || naive.Result.VMsUsed != 2 {
+				t.Errorf("expected NewAlgorithm on 1 VM at 75%% CPU and Naive on 2, got %+v and %+v", got, naive)
+			}
+			if run.Currency() != "USD" || (len(got.Result.VMs) > 0) != tc.detail {
+				t.Errorf("expected USD prices and per-VM detail %v, got %s and %d VMs", tc.detail, run.Currency(), len(got.Result.VMs))
+			}
+			if w := got.Result.Waste.TotalWastedCostPerHour; w < tc.wasted-0.005 || w > tc.wasted+0.005 {
+				t.Errorf("expected wasted cost %.3f, got %.3f", tc.wasted, w)
+			}
+			if m := run.Metadata; (m != nil) != (tc.version >= 3) || m != nil && (m.Version != "v1.4.0" || m.GitRef != "1a2b3c4d" || m.Duration != 2*time.Second) {
+				t.Errorf("expected the metadata of version 3 reports, got %+v", m)
+			}
+		})
+	}
+}
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{
+		{Name: "general", Result: resolver.SimulationResult{VMsUsed: 3, TotalCost: 1.25, DistinctSKUs: 2, Currency: "EUR"}},
+		{Name: "Naive", Result: resolver.SimulationResult{VMsUsed: 5, TotalCost: 2.5, DistinctSKUs: 1, Unpacked: 1, Currency: "EUR"}},
+	}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, run); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "# instance-selection-sim results, schema 3\nStrategy,VMs Used,Total Cost (EUR/h)") {
+		t.Errorf("expected the schema line and EUR cost headers, got:\n%s", buf.String())
+	}
+	got, err := ParseRun(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, nr := range got.Results {
+		want := run.Results[i]
+		if nr.Name != want.Name || nr.Result.VMsUsed != want.Result.VMsUsed || nr.Result.TotalCost != want.Result.TotalCost ||
+			nr.Result.DistinctSKUs != want.Result.DistinctSKUs || nr.Result.Unpacked != want.Result.Unpacked || nr.Result.Currency != "EUR" {
+			t.Errorf("row %d: expected %+v, got %+v", i, want, nr)
+		}
+	}
+}
+
+func TestWriteCSVMetadata(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := resolver.RunMetadata{Version: "v1.4.0", Hostname: "bench-1", Start: start, End: start.Add(90 * time.Second), Duration: 90 * time.Second}
+	run := resolver.SimulationRun{Metadata: &m, Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{VMsUsed: 3}}}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, run); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\n# metadata {\"Version\":\"v1.4.0\",\"Hostname\":\"bench-1\",") {
+		t.Errorf("expected a metadata line after the schema line, got:\n%s", buf.String())
+	}
+	got, err := ParseRun(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata == nil || *got.Metadata != m || len(got.Results) != 1 || got.Results[0].Result.VMsUsed != 3 {
+		t.Errorf("expected the metadata and result to survive a CSV round trip, got %+v and %+v", got.Metadata, got.Results)
+	}
+}
+
+func TestParseRunNewerVersion(t *testing.T) {
+	for _, report := range []string{
+		`{"SchemaVersion": 99, "Results": []}`,
+		"# instance-selection-sim results, schema 99\nStrategy,VMs Used\n",
+	} {
+		if _, err := ParseRun(strings.NewReader(report)); err == nil || !strings.Contains(err.Error(), "newer") {
+			t.Errorf("expected a newer schema version to be rejected, got %v", err)
+		}
+	}
+}
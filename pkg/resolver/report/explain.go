@@ -0,0 +1,107 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// WriteExplanation writes, for every VM of an audited result (see resolver.Config.WithAudit),
+// the workload that seeded it, the filters that trimmed the candidates and the top alternatives.
+func WriteExplanation(w io.Writer, result resolver.SimulationResult) error {
+	ew := &errWriter{w: w}
+	for i, vm := range result.VMs {
+		d := vm.Decision
+		if d == nil {
+			continue
+		}
+		ew.printf("VM %d: %s (", i, d.Selected)
+		if d.VM != "" {
+			ew.printf("%s, ", d.VM)
+		}
+		ew.printf("score %.4f", d.Score)
+		if d.Explored {
+			ew.printf(", explored")
+		}
+		ew.printf(")\n")
+		s := d.SeedWorkload
+		ew.printf("  seeded by workload: ")
+		writeWorkload(ew, s)
+		if len(s.Preferences) > 0 {
+			ew.printf("\n  preferences satisfied: %d of %d", len(d.Preferences), len(s.Preferences))
+			if len(d.Preferences) > 0 {
+				ew.printf(" (%s)", strings.Join(d.Preferences, ", "))
+			}
+		}
+		ew.printf("\n  ")
+		writeFilterSteps(ew, d.Candidates, d.FilterSteps)
+		for rank, alt := range d.Alternatives {
+			ew.printf("  #%d %s score %.4f price %.4f/h\n", rank+1, alt.Name, alt.Score, alt.PricePerHour)
+		}
+	}
+	return ew.err
+}
+
+/*
+WriteSelection writes a selection explained by resolver.ExplainSelection: the workload, the
+filters that trimmed the candidates and a table of the ranked candidates, best first, with
+prices in currency and, when the workload has preferences, the ones each satisfies. Without
+candidates it names the limiting requirement instead.
+*/
+func WriteSelection(w io.Writer, e resolver.SelectionExplanation, currency string) error {
+	ew := &errWriter{w: w}
+	ew.printf("workload: ")
+	writeWorkload(ew, e.Workload)
+	ew.printf("\n")
+	writeFilterSteps(ew, e.Candidates, e.FilterSteps)
+	if len(e.Ranked) == 0 {
+		if e.Doomed != nil {
+			ew.printf("no instance type can hold the workload: %s\n", e.Doomed.Detail)
+		} else {
+			ew.printf("no instance type can hold the workload\n")
+		}
+		return ew.err
+	}
+	cur := resolver.CurrencySymbol(currency)
+	ew.printf("\n%4s  %-28s %5s %8s %4s %10s %8s", "rank", "SKU", "vCPU", "GiB", "GPU", "price/h", "score")
+	prefs := len(e.Workload.Preferences) > 0
+	if prefs {
+		ew.printf("  %s", "preferences")
+	}
+	ew.printf("\n")
+	for i, r := range e.Ranked {
+		vm := r.Instance
+		ew.printf("%4d  %-28s %5d %8.1f %4d %10s %8.4f", i+1, vm.Name, vm.VCpus, vm.MemoryGiB, vm.GPUCount,
+			fmt.Sprintf("%s%.4f", cur, vm.PricePerHour), r.Score)
+		if prefs {
+			ew.printf("  %s", strings.Join(e.Preferences[i], ", "))
+		}
+		ew.printf("\n")
+	}
+	return ew.err
+}
+
+// writeWorkload writes w's identity and main requirements on one line, without a newline.
+func writeWorkload(ew *errWriter, w resolver.WorkloadProfile) {
+	if id := w.ID(); id != "" {
+		ew.printf("%s, ", id)
+	}
+	ew.printf("%d vCPU, %.1f GiB", w.CPURequirements, w.MemoryRequirements)
+	if w.GPURequirements > 0 {
+		ew.printf(", %d GPU", w.GPURequirements)
+	}
+	if w.Zone != "" {
+		ew.printf(", zone %s", w.Zone)
+	}
+}
+
+// writeFilterSteps writes the candidate count and how each filter step trimmed it, on one line.
+func writeFilterSteps(ew *errWriter, candidates int, steps []resolver.FilterStep) {
+	ew.printf("candidates: %d", candidates)
+	for _, step := range steps {
+		ew.printf(" -> %s: %d", step.Filter, step.Remaining)
+	}
+	ew.printf("\n")
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func TestWriteExplanation(t *testing.T) {
+	candidates := []resolver.AzureInstanceSpec{
+		{Name: "Standard_D2_v3", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1},
+		{Name: "Standard_NC6", VCpus: 6, MemoryGiB: 56, PricePerHour: 0.9, GPUCount: 1},
+	}
+	packing := resolver.BinPackWorkloadsWithConfig(resolver.WorkloadSet{{CPURequirements: 1, MemoryRequirements: 2}}, candidates, resolver.Config{WithAudit: true})
+	var buf bytes.Buffer
+	if err := WriteExplanation(&buf, resolver.NewSimulationResult(packing)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"VM 0: Standard_D2_v3", "seeded by workload: 1 vCPU, 2.0 GiB", "candidates: 2", "#1 Standard_D2_v3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in explanation:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteExplanation_Preferences(t *testing.T) {
+	candidates := []resolver.AzureInstanceSpec{{Name: "Standard_D2s_v3", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1, AcceleratedNetworking: true}}
+	workload := resolver.WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2, Preferences: map[string]string{"AcceleratedNetworking": "true", "EphemeralOSDisk": "true"}}
+	packing := resolver.BinPackWorkloadsWithConfig(resolver.WorkloadSet{workload}, candidates, resolver.Config{WithAudit: true})
+	var buf bytes.Buffer
+	if err := WriteExplanation(&buf, resolver.NewSimulationResult(packing)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "preferences satisfied: 1 of 2 (AcceleratedNetworking)"; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q in explanation:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteSelection(t *testing.T) {
+	candidates := []resolver.AzureInstanceSpec{
+		{Name: "Standard_D2_v3", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1},
+		{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+	}
+	e := resolver.ExplainSelection(candidates, resolver.WorkloadProfile{CPURequirements: 3, MemoryRequirements: 4}, resolver.Config{}, 0)
+	var buf bytes.Buffer
+	if err := WriteSelection(&buf, e, "USD"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"workload: 3 vCPU, 4.0 GiB", "candidates: 2 -> capacity: 1", "   1  Standard_D4_v3", "$0.2000"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in selection:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Standard_D2_v3") || strings.Contains(out, "preferences") {
+		t.Errorf("expected only the candidate that fits, without a preferences column:\n%s", out)
+	}
+}
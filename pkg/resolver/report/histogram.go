@@ -0,0 +1,47 @@
+package report
+
+import (
+	"io"
+	"strings"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// histogramWidth is the length of the longest bar of a histogram chart.
+const histogramWidth = 40
+
+// WriteHistogram writes the CPU and memory utilization histograms of a result as ASCII bar charts,
+// one line per bucket with bars scaled to the fullest bucket.
+func WriteHistogram(w io.Writer, h resolver.Histogram) error {
+	ew := &errWriter{w: w}
+	writeHistogram(ew, h)
+	return ew.err
+}
+
+func writeHistogram(ew *errWriter, h resolver.Histogram) {
+	labelWidth := 0
+	for i := range h.CPU {
+		if n := len(h.Label(i)); n > labelWidth {
+			labelWidth = n
+		}
+	}
+	for _, series := range []struct {
+		name   string
+		counts []int
+	}{{"CPU", h.CPU}, {"Memory", h.Memory}} {
+		ew.printf("%s utilization (VMs)\n", series.name)
+		most := 0
+		for _, n := range series.counts {
+			if n > most {
+				most = n
+			}
+		}
+		for i, n := range series.counts {
+			bar := 0
+			if most > 0 {
+				bar = (n*histogramWidth + most - 1) / most
+			}
+			ew.printf("  %*s |%-*s %d\n", labelWidth, h.Label(i), histogramWidth, strings.Repeat("#", bar), n)
+		}
+	}
+}
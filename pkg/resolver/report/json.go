@@ -0,0 +1,65 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+/*
+SchemaVersion is the version of the JSON and CSV report schemas. Bump it whenever fields or
+columns change meaning or go away, and keep ParseRun reading the versions before it.
+
+  - 1: reports written before versioning, without SchemaVersion or the CSV schema line.
+  - 2: JSON reports carry SchemaVersion and CSV reports start with a schema line.
+  - 3: reports carry the RunMetadata of the run, in CSV reports as a comment line after the
+    schema line.
+*/
+const SchemaVersion = 3
+
+// WriteJSON writes run as indented JSON, including per-VM detail and cost attribution.
+func WriteJSON(w io.Writer, run resolver.SimulationRun) error {
+	run.SchemaVersion = SchemaVersion
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(run)
+}
+
+// ReadJSON reads a run written by WriteJSON, of this or an earlier schema version.
+func ReadJSON(r io.Reader) (resolver.SimulationRun, error) {
+	var run resolver.SimulationRun
+	if err := json.NewDecoder(r).Decode(&run); err != nil {
+		return resolver.SimulationRun{}, err
+	}
+	if run.SchemaVersion == 0 {
+		run.SchemaVersion = 1
+	}
+	if run.SchemaVersion > SchemaVersion {
+		return resolver.SimulationRun{}, fmt.Errorf("report schema version %d is newer than %d", run.SchemaVersion, SchemaVersion)
+	}
+	return run, nil
+}
+
+// ParseRun reads a JSON report (see ReadJSON) or a CSV report (see ReadCSV), telling them apart
+// by their first character.
+func ParseRun(r io.Reader) (resolver.SimulationRun, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return resolver.SimulationRun{}, fmt.Errorf("empty report: %w", err)
+		}
+		if !bytes.ContainsAny(b, " \t\r\n") {
+			break
+		}
+		br.ReadByte()
+	}
+	if b, _ := br.Peek(1); b[0] == '{' {
+		return ReadJSON(br)
+	}
+	return ReadCSV(br)
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func TestWriteJSON_CostByLabel(t *testing.T) {
+	attribution := resolver.AttributeCosts(resolver.PackingResult{VMs: []resolver.PackedVM{{
+		InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		Workloads:    []resolver.WorkloadProfile{{CPURequirements: 2, MemoryRequirements: 4, Labels: map[string]string{"team": "web"}}},
+	}}}, "team")
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{
+		{Name: "NewAlgorithm", Result: resolver.SimulationResult{VMsUsed: 1, CostByLabel: &attribution}},
+	}}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded resolver.SimulationRun
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	got := decoded.Results[0].Result.CostByLabel
+	if got == nil || got.LabelKey != "team" || math.Abs(got.Costs["web"]-0.1) > 1e-9 {
+		t.Errorf("expected web cost 0.1 in the JSON report, got %+v", got)
+	}
+}
+
+func TestReadJSON_RoundTripsDetail(t *testing.T) {
+	packing := resolver.PackingResult{VMs: []resolver.PackedVM{{
+		InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		Workloads:    []resolver.WorkloadProfile{{Name: "web", CPURequirements: 2, MemoryRequirements: 4}},
+	}}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.NewSimulationResult(packing)}}}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rebuilt := resolver.PackingFromDetail(decoded.Results[0].Result, []resolver.AzureInstanceSpec{packing.VMs[0].InstanceType})
+	if c := resolver.ComparePackingResults(packing, rebuilt); !c.Identical() {
+		t.Errorf("expected the packing to survive a JSON round trip, got:\n%s", c)
+	}
+}
+
+func TestWriteJSON_WorkloadNames(t *testing.T) {
+	packing := resolver.PackingResult{
+		VMs: []resolver.PackedVM{{
+			InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+			Workloads:    []resolver.WorkloadProfile{{Name: "web", Namespace: "shop", CPURequirements: 2, MemoryRequirements: 4}},
+		}},
+		Unpacked: []resolver.UnpackedWorkload{{Workload: resolver.WorkloadProfile{Name: "huge", CPURequirements: 64}, Reason: "no instance type fits"}},
+	}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.NewSimulationResult(packing)}}}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{`"Name": "web"`, `"Namespace": "shop"`, `"Name": "huge"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in the JSON report, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteJSON_Groups(t *testing.T) {
+	groups := resolver.SummarizeByLabel(resolver.PackingResult{VMs: []resolver.PackedVM{{
+		InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		Workloads:    []resolver.WorkloadProfile{{CPURequirements: 4, MemoryRequirements: 4, Labels: map[string]string{resolver.WorkloadTypeLabel: "Interactive"}}},
+	}}}, resolver.WorkloadTypeLabel)
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{VMsUsed: 1, Groups: &groups}}}}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded resolver.SimulationRun
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	got := decoded.Results[0].Result.Groups
+	if got == nil || got.LabelKey != resolver.WorkloadTypeLabel || len(got.Groups) == 0 || got.Groups[0].Value != "Interactive" || math.Abs(got.Groups[0].CostShare-100) > 1e-9 {
+		t.Errorf("expected Interactive with all the cost in the JSON report, got %+v", got)
+	}
+}
@@ -0,0 +1,453 @@
+// Package report renders simulation runs for humans and downstream tooling.
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// WriteMarkdown writes a markdown report of run: a summary table comparing every result,
+// their cost projection, per-resource utilization and timing, and the most wasteful VMs of each result.
+func WriteMarkdown(w io.Writer, run resolver.SimulationRun) error {
+	ew := &errWriter{w: w}
+	cur := resolver.CurrencySymbol(run.Currency())
+	ew.printf("# Instance selection simulation\n\n")
+	ew.printf("Prices in %s.\n\n", run.Currency())
+	ew.printf("| Strategy | VMs Used | Total Cost (%s/h) | Avg CPU Util (%%) | Avg Mem Util (%%) | Headroom Cost (%s/h) | Wasted Cost (%s/h) | Unpacked |\n", cur, cur, cur)
+	ew.printf("|---|---:|---:|---:|---:|---:|---:|---:|\n")
+	for _, nr := range run.Results {
+		r := nr.Result
+		ew.printf("| %s | %d | %.2f | %.1f | %.1f | %.2f | %.2f | %d |\n",
+			nr.Name, r.VMsUsed, r.TotalCost, r.AvgCPU, r.AvgMem, r.HeadroomCost, r.Waste.TotalWastedCostPerHour, r.Unpacked)
+	}
+	ew.printf("\n## Cost projection\n\n")
+	ew.printf("| Strategy | Capacity Type | Hourly (%s) | Monthly (%s) | Annual (%s) |\n", cur, cur, cur)
+	ew.printf("|---|---|---:|---:|---:|\n")
+	for _, nr := range run.Results {
+		p := nr.Result.Projection
+		for _, c := range p.ByCapacityType {
+			ew.printf("| %s | %s | %.2f | %.2f | %.2f |\n", nr.Name, c.CapacityType, c.Hourly, c.Monthly, c.Annual)
+		}
+		ew.printf("| %s | total (%.0f h/month) | %.2f | %.2f | %.2f |\n", nr.Name, p.HoursPerMonth, p.Hourly, p.Monthly, p.Annual)
+	}
+	ew.printf("\n## Utilization\n\n")
+	ew.printf("| Strategy | CPU (%%) | Memory (%%) | GPU (%%) | Storage (%%) | Pod Slots (%%) |\n")
+	ew.printf("|---|---:|---:|---:|---:|---:|\n")
+	for _, nr := range run.Results {
+		u := nr.Result.Utilization
+		ew.printf("| %s | %.1f | %.1f | %.1f | %.1f | %.1f |\n", nr.Name, u.CPU, u.Memory, u.GPU, u.Storage, u.PodSlots)
+	}
+	for _, nr := range run.Results {
+		if nr.Result.VMsUsed == 0 || len(nr.Result.Histogram.CPU) == 0 {
+			continue
+		}
+		ew.printf("\n### Utilization histogram: %s\n\n```text\n", nr.Name)
+		writeHistogram(ew, nr.Result.Histogram)
+		ew.printf("```\n")
+	}
+	writeStrategyMix(ew, run)
+	writeGroups(ew, run, cur)
+	writeWorkloadsPerVM(ew, run)
+	writeBasisComparison(ew, run, cur)
+	writeSensitivity(ew, run, cur)
+	writeLoadProfile(ew, run, cur)
+	writeEvents(ew, run)
+	writeArchComparison(ew, run, cur)
+	writeBaseline(ew, run, cur)
+	writeLimitOvercommit(ew, run)
+	writeReservations(ew, run, cur)
+	writeApprox(ew, run, cur)
+	ew.printf("\n## Timing\n\n")
+	ew.printf("| Strategy | Packing Time | Score Cache Hits | Score Cache Misses | Hit Rate (%%) | Selection Cache Hits |\n")
+	ew.printf("|---|---:|---:|---:|---:|---:|\n")
+	for _, nr := range run.Results {
+		t := nr.Result.Timing
+		ew.printf("| %s | %v | %d | %d | %.1f | %d |\n", nr.Name, t.PackingTime, t.ScoreCacheHits, t.ScoreCacheMisses, t.ScoreCacheHitRate()*100, t.SelectionCacheHits)
+	}
+	for _, nr := range run.Results {
+		if len(nr.Result.Waste.TopVMs) == 0 {
+			continue
+		}
+		ew.printf("\n## Most wasteful VMs: %s\n\n", nr.Name)
+		ew.printf("| VM | SKU | Price (%s/h) | Idle CPU (%%) | Idle Mem (%%) | Wasted (%s/h) |\n", cur, cur)
+		ew.printf("|---:|---|---:|---:|---:|---:|\n")
+		for _, vw := range nr.Result.Waste.TopVMs {
+			ew.printf("| %d | %s | %.3f | %.1f | %.1f | %.3f |\n",
+				vw.Index, vw.SKU, vw.PricePerHour, vw.IdleCPU*100, vw.IdleMemory*100, vw.WastedCostPerHour)
+		}
+	}
+	writeUnpacked(ew, run)
+	writeZoneWarnings(ew, run)
+	writeQuotaUsage(ew, run)
+	return ew.err
+}
+
+// writeQuotaUsage lists, per strategy, the vCPUs charged against each family quota and the spot quota,
+// the GPUs charged against the GPU quotas, and the families excluded for lack of quota.
+func writeQuotaUsage(ew *errWriter, run resolver.SimulationRun) {
+	for _, nr := range run.Results {
+		usage := nr.Result.QuotaUsage
+		if usage == nil {
+			continue
+		}
+		families := make([]string, 0, len(usage.Families))
+		for f := range usage.Families {
+			families = append(families, f)
+		}
+		sort.Strings(families)
+		ew.printf("\n## Quota usage: %s\n\n", nr.Name)
+		ew.printf("| Quota | vCPUs |\n")
+		ew.printf("|---|---:|\n")
+		for _, f := range families {
+			ew.printf("| %s | %d |\n", f, usage.Families[f])
+		}
+		ew.printf("| spot (%s) | %d |\n", resolver.SpotQuotaKey, usage.SpotVCpus)
+		if usage.GPUs > 0 {
+			ew.printf("\n| GPU quota | GPUs |\n")
+			ew.printf("|---|---:|\n")
+			gpuFamilies := make([]string, 0, len(usage.GPUFamilies))
+			for f := range usage.GPUFamilies {
+				gpuFamilies = append(gpuFamilies, f)
+			}
+			sort.Strings(gpuFamilies)
+			for _, f := range gpuFamilies {
+				ew.printf("| %s | %d |\n", f, usage.GPUFamilies[f])
+			}
+			ew.printf("| total (%s) | %d |\n", resolver.GPUQuotaKey, usage.GPUs)
+		}
+		if excluded := nr.Result.ExcludedFamilies; len(excluded) > 0 {
+			ew.printf("\nFamilies without quota, excluded from the candidates: %s\n", strings.Join(excluded, ", "))
+		}
+	}
+}
+
+// writeZoneWarnings lists, per strategy, the VMs whose SKU is offered in too few zones.
+func writeZoneWarnings(ew *errWriter, run resolver.SimulationRun) {
+	for _, nr := range run.Results {
+		if len(nr.Result.ZoneWarnings) == 0 {
+			continue
+		}
+		ew.printf("\n## Zone resilience warnings: %s\n\n", nr.Name)
+		ew.printf("| VM | SKU | Zones | Required |\n")
+		ew.printf("|---:|---|---:|---:|\n")
+		for _, zw := range nr.Result.ZoneWarnings {
+			ew.printf("| %d | %s | %d | %d |\n", zw.VM, zw.SKU, zw.Zones, zw.Required)
+		}
+	}
+}
+
+// maxUnpackedListed caps the unpacked workloads listed per strategy.
+const maxUnpackedListed = 20
+
+// writeUnpacked lists, per strategy, the workloads that could not be packed and why.
+func writeUnpacked(ew *errWriter, run resolver.SimulationRun) {
+	for _, nr := range run.Results {
+		var unpacked []resolver.WorkloadDetail
+		for _, d := range nr.Result.Workloads {
+			if d.VM < 0 {
+				unpacked = append(unpacked, d)
+			}
+		}
+		if len(unpacked) == 0 {
+			continue
+		}
+		ew.printf("\n## Unpacked workloads: %s\n\n", nr.Name)
+		ew.printf("| Workload | vCPU | Mem (GiB) | Reason |\n")
+		ew.printf("|---|---:|---:|---|\n")
+		for i, d := range unpacked {
+			if i == maxUnpackedListed {
+				ew.printf("| %d more | | | |\n", len(unpacked)-i)
+				break
+			}
+			name := d.Name
+			if d.Namespace != "" && name != "" {
+				name = d.Namespace + "/" + name
+			}
+			if name == "" {
+				name = "-"
+			}
+			ew.printf("| %s | %d | %.1f | %s |\n", name, d.CPU, d.MemoryGiB, d.UnpackedReason)
+		}
+	}
+}
+
+// writeStrategyMix writes how many workloads each auto-classified result scored with each strategy.
+func writeStrategyMix(ew *errWriter, run resolver.SimulationRun) {
+	header := false
+	for _, nr := range run.Results {
+		if len(nr.Result.StrategyMix) == 0 {
+			continue
+		}
+		if !header {
+			ew.printf("\n## Workload classes\n\n")
+			ew.printf("| Strategy | Class | Workloads |\n")
+			ew.printf("|---|---|---:|\n")
+			header = true
+		}
+		for _, c := range nr.Result.StrategyMix {
+			ew.printf("| %s | %s | %d |\n", nr.Name, c.Strategy, c.Workloads)
+		}
+	}
+}
+
+// writeGroups writes the VMs, cost share and utilization per label value of the results that were grouped.
+func writeGroups(ew *errWriter, run resolver.SimulationRun, cur string) {
+	for _, nr := range run.Results {
+		g := nr.Result.Groups
+		if g == nil {
+			continue
+		}
+		ew.printf("\n## Cost by %s: %s\n\n", g.LabelKey, nr.Name)
+		ew.printf("| %s | Workloads | VMs | Cost (%s/h) | Cost Share (%%) | Avg CPU Util (%%) | Avg Mem Util (%%) |\n", g.LabelKey, cur)
+		ew.printf("|---|---:|---:|---:|---:|---:|---:|\n")
+		for _, s := range g.Groups {
+			ew.printf("| %s | %d | %d | %.2f | %.1f | %.1f | %.1f |\n", s.Value, s.Workloads, s.VMs, s.Cost, s.CostShare, s.CPUUtilization, s.MemoryUtilization)
+		}
+	}
+}
+
+// writeWorkloadsPerVM writes how many VMs of each result hold each number of workloads.
+func writeWorkloadsPerVM(ew *errWriter, run resolver.SimulationRun) {
+	header := false
+	for _, nr := range run.Results {
+		if len(nr.Result.WorkloadsPerVM) == 0 {
+			continue
+		}
+		if !header {
+			ew.printf("\n## Workloads per VM\n\n")
+			ew.printf("| Strategy | Workloads | VMs |\n")
+			ew.printf("|---|---:|---:|\n")
+			header = true
+		}
+		for _, c := range nr.Result.WorkloadsPerVM {
+			ew.printf("| %s | %d | %d |\n", nr.Name, c.Workloads, c.VMs)
+		}
+	}
+}
+
+// writeApprox writes the error bound of the results packed approximately.
+func writeApprox(ew *errWriter, run resolver.SimulationRun, cur string) {
+	header := false
+	for _, nr := range run.Results {
+		a := nr.Result.Approx
+		if a == nil {
+			continue
+		}
+		if !header {
+			ew.printf("\n## Approximation\n\n")
+			ew.printf("| Strategy | Workloads | Shape Groups | Cost (%s/h) | Lower Bound (%s/h) | Error Bound (%s/h) |\n", cur, cur, cur)
+			ew.printf("|---|---:|---:|---:|---:|---:|\n")
+			header = true
+		}
+		ew.printf("| %s | %d | %d | %.2f | %.2f | %.2f |\n", nr.Name, a.Workloads, a.Groups, nr.Result.TotalCost, a.LowerBound, a.ErrorBound)
+	}
+}
+
+// writeBasisComparison writes the cost of the results packed by usage under each packing basis.
+func writeBasisComparison(ew *errWriter, run resolver.SimulationRun, cur string) {
+	for _, nr := range run.Results {
+		if len(nr.Result.BasisComparison) == 0 {
+			continue
+		}
+		ew.printf("\n## Rightsizing: %s\n\n", nr.Name)
+		ew.printf("| Basis | VMs Used | Total Cost (%s/h) | Unpacked | Savings (%%) |\n", cur)
+		ew.printf("|---|---:|---:|---:|---:|\n")
+		for _, c := range nr.Result.BasisComparison {
+			ew.printf("| %s | %d | %.2f | %d | %.1f |\n", c.Basis, c.VMsUsed, c.TotalCost, c.Unpacked, c.SavingsPercent)
+		}
+	}
+}
+
+// writeSensitivity writes one row per price perturbation level of the results that were analyzed.
+func writeSensitivity(ew *errWriter, run resolver.SimulationRun, cur string) {
+	for _, nr := range run.Results {
+		if len(nr.Result.Sensitivity) == 0 {
+			continue
+		}
+		ew.printf("\n## Price sensitivity: %s\n\n", nr.Name)
+		ew.printf("| Perturbation (%%) | Trials | SKU Mix Changed (%%) | Mean Cost (%s/h) | Std Dev (%s/h) | Baseline (%s/h) |\n", cur, cur, cur)
+		ew.printf("|---:|---:|---:|---:|---:|---:|\n")
+		for _, p := range nr.Result.Sensitivity {
+			ew.printf("| %g | %d | %.1f | %.2f | %.4f | %.2f |\n", p.PerturbationPercent, p.Trials, 100*p.MixChangeRate(), p.MeanCost, p.CostStdDev, p.BaselineCost)
+		}
+	}
+}
+
+// writeLoadProfile writes the hourly VM counts and daily cost of the results packed under a load profile.
+func writeLoadProfile(ew *errWriter, run resolver.SimulationRun, cur string) {
+	for _, nr := range run.Results {
+		lp := nr.Result.LoadProfile
+		if lp == nil {
+			continue
+		}
+		ew.printf("\n## Load profile: %s\n\n", nr.Name)
+		ew.printf("24h cost: %.2f %s (%.2f without scaling, %.1f%% savings), %d to %d VMs\n\n", lp.DailyCost, cur, lp.StaticDailyCost, lp.SavingsPercent(), lp.TroughVMs, lp.PeakVMs)
+		ew.printf("| Hour | Multiplier | Workloads | VMs | Cost (%s/h) | Unpacked |\n", cur)
+		ew.printf("|---:|---:|---:|---:|---:|---:|\n")
+		for _, h := range lp.Hours {
+			ew.printf("| %02d | %g | %d | %d | %.2f | %d |\n", h.Hour, h.Multiplier, h.Workloads, h.VMs, h.CostPerHour, h.Unpacked)
+		}
+	}
+}
+
+// writeEvents writes the events of each result with an event log, per type and hour.
+func writeEvents(ew *errWriter, run resolver.SimulationRun) {
+	for _, nr := range run.Results {
+		if len(nr.Result.Events) == 0 {
+			continue
+		}
+		ew.printf("\n## Events: %s\n\n| Hour |", nr.Name)
+		for _, t := range resolver.EventTypes {
+			ew.printf(" %s |", t)
+		}
+		ew.printf("\n|---:|%s\n", strings.Repeat("---:|", len(resolver.EventTypes)))
+		for _, h := range nr.Result.Events.Hourly() {
+			ew.printf("| %02d |", h.Hour)
+			for _, t := range resolver.EventTypes {
+				ew.printf(" %d |", h.Counts[t])
+			}
+			ew.printf("\n")
+		}
+	}
+}
+
+// writeBaseline writes the cost and capacity of the results compared to a baseline fleet, the
+// headroom each leaves over the workloads' requests, and whether the workloads fit on the baseline.
+func writeBaseline(ew *errWriter, run resolver.SimulationRun, cur string) {
+	for _, nr := range run.Results {
+		cmp := nr.Result.Baseline
+		if cmp == nil {
+			continue
+		}
+		b, s := cmp.Baseline, cmp.Simulated
+		ew.printf("\n## Baseline fleet: %s\n\n", nr.Name)
+		ew.printf("| Fleet | VMs | vCPUs | Memory (GiB) | GPUs | Cost (%s/h) |\n", cur)
+		ew.printf("|---|---:|---:|---:|---:|---:|\n")
+		ew.printf("| baseline | %d | %d | %.1f | %d | %.2f |\n", b.VMs, b.VCpus, b.MemoryGiB, b.GPUs, b.CostPerHour)
+		ew.printf("| simulated | %d | %d | %.1f | %d | %.2f |\n", s.VMs, s.VCpus, s.MemoryGiB, s.GPUs, s.CostPerHour)
+		ew.printf("| requested | | %d | %.1f | %d | |\n", cmp.RequestedVCpus, cmp.RequestedMemoryGiB, cmp.RequestedGPUs)
+		ew.printf("\n| Headroom | Baseline | Simulated |\n")
+		ew.printf("|---|---:|---:|\n")
+		ew.printf("| vCPUs | %s | %s |\n", headroom(float64(b.VCpus), float64(cmp.RequestedVCpus)), headroom(float64(s.VCpus), float64(cmp.RequestedVCpus)))
+		ew.printf("| Memory (GiB) | %s | %s |\n", headroom(b.MemoryGiB, cmp.RequestedMemoryGiB), headroom(s.MemoryGiB, cmp.RequestedMemoryGiB))
+		if b.GPUs > 0 || s.GPUs > 0 {
+			ew.printf("| GPUs | %s | %s |\n", headroom(float64(b.GPUs), float64(cmp.RequestedGPUs)), headroom(float64(s.GPUs), float64(cmp.RequestedGPUs)))
+		}
+		ew.printf("\nDelta: %.2f %s/h (%.1f%% savings)\n", cmp.CostDelta, cur, cmp.SavingsPercent)
+		if cmp.Fits() {
+			ew.printf("\nAll %d workloads fit on the baseline fleet.\n", cmp.Workloads)
+		} else {
+			ew.printf("\n**The workloads do not fit on the baseline fleet:** %d of %d workloads cannot be placed on it.\n", cmp.Unplaced, cmp.Workloads)
+		}
+	}
+}
+
+// headroom formats the capacity left over requested, and its share of capacity.
+func headroom(capacity, requested float64) string {
+	if capacity == 0 {
+		return fmt.Sprintf("%.1f", capacity-requested)
+	}
+	return fmt.Sprintf("%.1f (%.1f%%)", capacity-requested, 100*(capacity-requested)/capacity)
+}
+
+// writeArchComparison writes the amd64-only and multi-arch cost of the results with an arch
+// comparison, and the workloads that would need arm64 images.
+func writeArchComparison(ew *errWriter, run resolver.SimulationRun, cur string) {
+	for _, nr := range run.Results {
+		cmp := nr.Result.ArchComparison
+		if cmp == nil {
+			continue
+		}
+		ew.printf("\n## Arch comparison: %s\n\n", nr.Name)
+		ew.printf("| Packing | VMs | Cost (%s/h) | Unpacked |\n", cur)
+		ew.printf("|---|---:|---:|---:|\n")
+		ew.printf("| amd64 only | %d | %.2f | %d |\n", cmp.AMD64.VMsUsed, cmp.AMD64.TotalCost, cmp.AMD64.Unpacked)
+		ew.printf("| multi-arch | %d | %.2f | %d |\n", cmp.MultiArch.VMsUsed, cmp.MultiArch.TotalCost, cmp.MultiArch.Unpacked)
+		ew.printf("\nDelta: %.2f %s/h (%.1f%% savings)\n", cmp.CostDelta, cur, cmp.SavingsPercent)
+		if len(cmp.Migrations) == 0 {
+			continue
+		}
+		ew.printf("\n| Workload needing arm64 | VM | SKU |\n")
+		ew.printf("|---|---|---|\n")
+		for i, m := range cmp.Migrations {
+			if i == maxUnpackedListed {
+				ew.printf("| %d more | | |\n", len(cmp.Migrations)-i)
+				break
+			}
+			name := m.Workload
+			if name == "" {
+				name = "-"
+			}
+			ew.printf("| %s | %s | %s |\n", name, m.VM, m.SKU)
+		}
+	}
+}
+
+// writeLimitOvercommit writes the limit overcommit ratio of the VMs of each result whose workloads
+// have limits, the most overcommitted first.
+func writeLimitOvercommit(ew *errWriter, run resolver.SimulationRun) {
+	for _, nr := range run.Results {
+		var vms []int
+		for i, vm := range nr.Result.VMs {
+			if vm.LimitOvercommitCPU > 0 || vm.LimitOvercommitMem > 0 {
+				vms = append(vms, i)
+			}
+		}
+		if len(vms) == 0 {
+			continue
+		}
+		peak := func(i int) float64 {
+			return max(nr.Result.VMs[i].LimitOvercommitCPU, nr.Result.VMs[i].LimitOvercommitMem)
+		}
+		sort.SliceStable(vms, func(a, b int) bool { return peak(vms[a]) > peak(vms[b]) })
+		ew.printf("\n## Limit overcommit: %s\n\n", nr.Name)
+		ew.printf("| VM | SKU | CPU Limits (x) | Memory Limits (x) |\n")
+		ew.printf("|---:|---|---:|---:|\n")
+		for n, i := range vms {
+			if n == maxUnpackedListed {
+				ew.printf("| %d more | | | |\n", len(vms)-n)
+				break
+			}
+			vm := nr.Result.VMs[i]
+			ew.printf("| %d | %s | %.2f | %.2f |\n", i, vm.SKU, vm.LimitOvercommitCPU, vm.LimitOvercommitMem)
+		}
+	}
+}
+
+// writeReservations writes the capacity reservation usage of the results that had reservations.
+func writeReservations(ew *errWriter, run resolver.SimulationRun, cur string) {
+	header := false
+	for _, nr := range run.Results {
+		rr := nr.Result.Reservations
+		if rr == nil {
+			continue
+		}
+		if !header {
+			ew.printf("\n## Capacity reservations\n\n")
+			ew.printf("| Strategy | SKU | Zone | Used | Reserved | Unused Cost (%s/h) |\n", cur)
+			ew.printf("|---|---|---|---:|---:|---:|\n")
+			header = true
+		}
+		for _, u := range rr.Reservations {
+			ew.printf("| %s | %s | %s | %d | %d | %.2f |\n", nr.Name, u.SKU, u.Zone, u.Used, u.Count, u.UnusedCostPerHour())
+		}
+		ew.printf("| %s | total (%.1f%% used) | | | | %.2f |\n", nr.Name, rr.Utilization, rr.UnusedCostPerHour)
+	}
+}
+
+// errWriter remembers the first write error so report writers can check it once at the end.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -0,0 +1,353 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	packing := resolver.PackingResult{VMs: []resolver.PackedVM{{
+		InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		Workloads:    []resolver.WorkloadProfile{{CPURequirements: 1, MemoryRequirements: 4}},
+	}}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{
+		{Name: "NewAlgorithm", Result: resolver.NewSimulationResult(packing)},
+	}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"| NewAlgorithm | 1 | 0.20 |",
+		"## Most wasteful VMs: NewAlgorithm",
+		"| NewAlgorithm | 25.0 | 25.0 | 0.0 | 0.0 | 0.0 |",
+		"| 0 | Standard_D4_v3 | 0.200 | 75.0 | 75.0 | 0.150 |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownCurrency(t *testing.T) {
+	packing := resolver.PackingResult{VMs: []resolver.PackedVM{{
+		InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		Workloads:    []resolver.WorkloadProfile{{CPURequirements: 1, MemoryRequirements: 4}},
+	}}}
+	result := resolver.NewSimulationResult(packing)
+	result.Currency = "EUR"
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Prices in EUR.", "Total Cost (€/h)", "Monthly (€)", "Price (€/h)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "$") {
+		t.Errorf("expected no dollar sign in a EUR report, got:\n%s", out)
+	}
+}
+
+func TestWriteMarkdownReservations(t *testing.T) {
+	packing := resolver.PackingResult{Reservations: []resolver.ReservationUsage{
+		{SKU: "Standard_E4s_v5", Zone: "2", Count: 2, Used: 1, SlotPricePerHour: 0.25},
+	}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.NewSimulationResult(packing)}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"## Capacity reservations",
+		"| NewAlgorithm | Standard_E4s_v5 | 2 | 1 | 2 | 0.25 |",
+		"| NewAlgorithm | total (50.0% used) | | | | 0.25 |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownUnpacked(t *testing.T) {
+	packing := resolver.PackingResult{Unpacked: []resolver.UnpackedWorkload{
+		{Workload: resolver.WorkloadProfile{Name: "huge", Namespace: "batch", CPURequirements: 64, MemoryRequirements: 256}, Reason: "no instance type fits"},
+	}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.NewSimulationResult(packing)}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Unpacked workloads: NewAlgorithm", "| batch/huge | 64 | 256.0 | no instance type fits |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownBasisComparison(t *testing.T) {
+	result := resolver.NewSimulationResult(resolver.PackingResult{})
+	result.BasisComparison = []resolver.BasisCost{
+		{Basis: "requests", VMsUsed: 4, TotalCost: 0.768},
+		{Basis: "usage+20%", VMsUsed: 1, TotalCost: 0.192, SavingsPercent: 75},
+	}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Rightsizing: NewAlgorithm", "| requests | 4 | 0.77 | 0 | 0.0 |", "| usage+20% | 1 | 0.19 | 0 | 75.0 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownHistogram(t *testing.T) {
+	vm := func(cpu int) resolver.PackedVM {
+		return resolver.PackedVM{
+			InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+			Workloads:    []resolver.WorkloadProfile{{CPURequirements: cpu, MemoryRequirements: 16}},
+		}
+	}
+	packing := resolver.PackingResult{VMs: []resolver.PackedVM{vm(4), vm(4), vm(1)}}
+	result := resolver.NewSimulationResult(packing)
+	result.Histogram = resolver.UtilizationHistogramWithEdges(packing, []float64{0, 50, 100})
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"### Utilization histogram: NewAlgorithm",
+		"CPU utilization (VMs)\n    0-50% |" + strings.Repeat("#", 20) + strings.Repeat(" ", 20) + " 1\n  50-100% |" + strings.Repeat("#", 40) + " 2\n",
+		"Memory utilization (VMs)\n    0-50% |" + strings.Repeat(" ", 40) + " 0\n  50-100% |" + strings.Repeat("#", 40) + " 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownZoneWarnings(t *testing.T) {
+	result := resolver.SimulationResult{ZoneWarnings: []resolver.ZoneWarning{{VM: 3, SKU: "Standard_D4s_v5", Zones: 1, Required: 2}}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Zone resilience warnings: NewAlgorithm", "| 3 | Standard_D4s_v5 | 1 | 2 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownQuotaUsage(t *testing.T) {
+	result := resolver.SimulationResult{QuotaUsage: &resolver.QuotaUsage{Families: map[string]int{"Esv5": 4, "Dsv5": 12}, SpotVCpus: 8, GPUFamilies: map[string]int{"NC": 3}, GPUs: 3}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Quota usage: NewAlgorithm", "| Dsv5 | 12 |\n| Esv5 | 4 |", "| spot (lowPriorityCores) | 8 |", "| NC | 3 |\n| total (gpus) | 3 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownStrategyMix(t *testing.T) {
+	result := resolver.SimulationResult{StrategyMix: []resolver.StrategyCount{{Strategy: resolver.StrategyCPUIntensive, Workloads: 3}}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Workload classes", "| NewAlgorithm | cpu | 3 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownWorkloadsPerVM(t *testing.T) {
+	result := resolver.SimulationResult{WorkloadsPerVM: []resolver.WorkloadCount{{Workloads: 4, VMs: 1}, {Workloads: 8, VMs: 2}}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Workloads per VM", "| NewAlgorithm | 4 | 1 |", "| NewAlgorithm | 8 | 2 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownSensitivity(t *testing.T) {
+	result := resolver.SimulationResult{Sensitivity: []resolver.PriceSensitivity{{PerturbationPercent: 5, Trials: 20, MixChanges: 5, BaselineCost: 0.77, MeanCost: 0.78, CostStdDev: 0.01}}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Price sensitivity: NewAlgorithm", "| 5 | 20 | 25.0 | 0.78 | 0.0100 | 0.77 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownLoadProfile(t *testing.T) {
+	lp := &resolver.LoadProfileResult{
+		Hours:           []resolver.HourlyLoad{{Hour: 3, Multiplier: 0.25, Workloads: 4, VMs: 4, CostPerHour: 0.8}},
+		DailyCost:       48,
+		StaticDailyCost: 76.8,
+		PeakVMs:         16,
+		TroughVMs:       4,
+	}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{LoadProfile: lp}}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Load profile: NewAlgorithm", "37.5% savings), 4 to 16 VMs", "| 03 | 0.25 | 4 | 4 | 0.80 | 0 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownGroups(t *testing.T) {
+	groups := resolver.SummarizeByLabel(resolver.PackingResult{VMs: []resolver.PackedVM{{
+		InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+		Workloads:    []resolver.WorkloadProfile{{CPURequirements: 2, MemoryRequirements: 4, Labels: map[string]string{resolver.WorkloadTypeLabel: "Interactive"}}},
+	}}}, resolver.WorkloadTypeLabel)
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{Groups: &groups}}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Cost by workload_type: NewAlgorithm", "| Interactive | 1 | 1 | 0.10 | 50.0 | 50.0 | 25.0 |", "| unallocated | 0 | 0 | 0.10 | 50.0 | 0.0 | 0.0 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownEvents(t *testing.T) {
+	events := resolver.EventLog{
+		{Time: 0, Type: resolver.EventProvision, VMID: "vm-0001"},
+		{Time: 0, Type: resolver.EventPlace, VMID: "vm-0001", WorkloadName: "web"},
+		{Time: 7300, Type: resolver.EventConsolidate, VMID: "vm-0001"},
+	}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{Events: events}}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Events: NewAlgorithm", "| Hour | provision | place | evict | consolidate | expire |", "| 00 | 1 | 1 | 0 | 0 | 0 |", "| 02 | 0 | 0 | 0 | 1 | 0 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownArchComparison(t *testing.T) {
+	cmp := &resolver.ArchComparison{
+		AMD64:          resolver.SimulationResult{VMsUsed: 2, TotalCost: 0.4},
+		MultiArch:      resolver.SimulationResult{VMsUsed: 2, TotalCost: 0.32},
+		CostDelta:      -0.08,
+		SavingsPercent: 20,
+		Migrations:     []resolver.ArchMigration{{Workload: "shop/web", VM: "vm-0001", SKU: "Standard_D4ps_v5"}},
+	}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{ArchComparison: cmp}}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Arch comparison: NewAlgorithm", "| amd64 only | 2 | 0.40 | 0 |", "| multi-arch | 2 | 0.32 | 0 |", "(20.0% savings)", "| shop/web | vm-0001 | Standard_D4ps_v5 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownBaseline(t *testing.T) {
+	cmp := &resolver.BaselineComparison{
+		Baseline:           resolver.FleetCapacity{VMs: 2, VCpus: 8, MemoryGiB: 32, CostPerHour: 0.4},
+		Simulated:          resolver.FleetCapacity{VMs: 2, VCpus: 16, MemoryGiB: 64, CostPerHour: 0.8},
+		RequestedVCpus:     12,
+		RequestedMemoryGiB: 24,
+		Workloads:          6,
+		Unplaced:           2,
+		CostDelta:          0.4,
+		SavingsPercent:     -100,
+	}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{Baseline: cmp}}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Baseline fleet: NewAlgorithm", "| baseline | 2 | 8 | 32.0 | 0 | 0.40 |", "| vCPUs | -4.0 (-50.0%) | 4.0 (25.0%) |", "**The workloads do not fit on the baseline fleet:** 2 of 6 workloads"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownApprox(t *testing.T) {
+	result := resolver.SimulationResult{TotalCost: 11.42, Approx: &resolver.ApproxReport{Workloads: 126, Groups: 28, LowerBound: 7.73, ErrorBound: 3.69}}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: result}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"## Approximation", "| NewAlgorithm | 126 | 28 | 11.42 | 7.73 | 3.69 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteMarkdownLimitOvercommit(t *testing.T) {
+	vms := []resolver.VMDetail{
+		{SKU: "Standard_D4s_v5", LimitOvercommitCPU: 1.5, LimitOvercommitMem: 0.75},
+		{SKU: "Standard_D4s_v5"},
+		{SKU: "Standard_D8s_v5", LimitOvercommitCPU: 2, LimitOvercommitMem: 1},
+	}
+	run := resolver.SimulationRun{Results: []resolver.NamedResult{{Name: "NewAlgorithm", Result: resolver.SimulationResult{VMs: vms}}}}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	first, second := strings.Index(out, "| 2 | Standard_D8s_v5 | 2.00 | 1.00 |"), strings.Index(out, "| 0 | Standard_D4s_v5 | 1.50 | 0.75 |")
+	if !strings.Contains(out, "## Limit overcommit: NewAlgorithm") || first < 0 || second < first {
+		t.Errorf("expected the VMs with limits, most overcommitted first, got:\n%s", out)
+	}
+	if strings.Contains(out, "| 1 | Standard_D4s_v5 |") {
+		t.Errorf("expected the VM without limits to be left out, got:\n%s", out)
+	}
+}
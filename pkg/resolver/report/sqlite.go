@@ -0,0 +1,130 @@
+package report
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS strategy_results (
+	run_id        INTEGER NOT NULL REFERENCES runs(id),
+	strategy      TEXT NOT NULL,
+	vms_used      INTEGER NOT NULL,
+	total_cost    REAL NOT NULL,
+	avg_cpu_util  REAL NOT NULL,
+	avg_mem_util  REAL NOT NULL,
+	headroom_cost REAL NOT NULL,
+	wasted_cost   REAL NOT NULL,
+	unpacked      INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS vms (
+	run_id         INTEGER NOT NULL REFERENCES runs(id),
+	strategy       TEXT NOT NULL,
+	vm_index       INTEGER NOT NULL,
+	sku            TEXT NOT NULL,
+	zone           TEXT NOT NULL,
+	price_per_hour REAL NOT NULL,
+	cpu_util       REAL NOT NULL,
+	mem_util       REAL NOT NULL,
+	vm_id          TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS workloads (
+	run_id          INTEGER NOT NULL REFERENCES runs(id),
+	strategy        TEXT NOT NULL,
+	cpu             INTEGER NOT NULL,
+	memory_gib      REAL NOT NULL,
+	gpu             INTEGER NOT NULL,
+	zone            TEXT NOT NULL,
+	headroom        INTEGER NOT NULL,
+	vm_index        INTEGER,
+	unpacked_reason TEXT
+);`
+
+/*
+ExportSQLite writes run into the SQLite database at path, creating it and its tables
+(runs, strategy_results, vms, workloads) if needed. Every call appends a new row to runs;
+all other rows reference it by run_id, so repeated runs can be compared with SQL.
+Unpacked workloads have a NULL vm_index and their reason in unpacked_reason.
+
+It needs a database/sql driver registered as "sqlite", such as the pure-Go modernc.org/sqlite;
+importing one is up to the program, so the report package does not depend on it.
+*/
+func ExportSQLite(path string, run resolver.SimulationRun) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("create schema: %w", err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		return fmt.Errorf("migrate schema: %w", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := insertRun(tx, run); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateSQLite adds the columns databases created by earlier versions lack.
+func migrateSQLite(db *sql.DB) error {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('vms') WHERE name = 'vm_id'`).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		_, err := db.Exec(`ALTER TABLE vms ADD COLUMN vm_id TEXT NOT NULL DEFAULT ''`)
+		return err
+	}
+	return nil
+}
+
+func insertRun(tx *sql.Tx, run resolver.SimulationRun) error {
+	res, err := tx.Exec(`INSERT INTO runs (created_at) VALUES (?)`, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("insert run: %w", err)
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, nr := range run.Results {
+		r := nr.Result
+		if _, err := tx.Exec(`INSERT INTO strategy_results VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, nr.Name, r.VMsUsed, r.TotalCost, r.AvgCPU, r.AvgMem, r.HeadroomCost, r.Waste.TotalWastedCostPerHour, r.Unpacked); err != nil {
+			return fmt.Errorf("insert strategy result: %w", err)
+		}
+		for i, vm := range r.VMs {
+			if _, err := tx.Exec(`INSERT INTO vms VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				runID, nr.Name, i, vm.SKU, vm.Zone, vm.PricePerHour, vm.CPUUtil, vm.MemUtil, vm.ID); err != nil {
+				return fmt.Errorf("insert vm: %w", err)
+			}
+		}
+		for _, w := range r.Workloads {
+			var vmIndex, reason interface{}
+			if w.VM >= 0 {
+				vmIndex = w.VM
+			} else {
+				reason = w.UnpackedReason
+			}
+			if _, err := tx.Exec(`INSERT INTO workloads VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				runID, nr.Name, w.CPU, w.MemoryGiB, w.GPU, w.Zone, w.Headroom, vmIndex, reason); err != nil {
+				return fmt.Errorf("insert workload: %w", err)
+			}
+		}
+	}
+	return nil
+}
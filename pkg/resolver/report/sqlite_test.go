@@ -0,0 +1,110 @@
+package report
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func sqliteFixtureRun() resolver.SimulationRun {
+	packing := resolver.PackingResult{
+		VMs: []resolver.PackedVM{
+			{
+				ID:           "vm-0001",
+				InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2},
+				Workloads: []resolver.WorkloadProfile{
+					{CPURequirements: 2, MemoryRequirements: 4, Zone: "1"},
+					{CPURequirements: 1, MemoryRequirements: 2},
+				},
+			},
+			{
+				ID:           "vm-0002",
+				InstanceType: resolver.AzureInstanceSpec{Name: "Standard_D2_v3", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1},
+				Workloads:    []resolver.WorkloadProfile{{CPURequirements: 1, MemoryRequirements: 2}},
+			},
+		},
+		Unpacked: []resolver.UnpackedWorkload{{Workload: resolver.WorkloadProfile{CPURequirements: 8}, Reason: resolver.ReasonCPULimitExceeded}},
+	}
+	result := resolver.NewSimulationResult(packing)
+	return resolver.SimulationRun{Results: []resolver.NamedResult{
+		{Name: "NewAlgorithm", Result: result},
+		{Name: "Naive", Result: result},
+	}}
+}
+
+func TestExportSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.db")
+	for i := 0; i < 2; i++ {
+		if err := ExportSQLite(path, sqliteFixtureRun()); err != nil {
+			t.Fatalf("export %d: %v", i, err)
+		}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	count := func(query string, args ...interface{}) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return n
+	}
+	for table, want := range map[string]int{"runs": 2, "strategy_results": 4, "vms": 8, "workloads": 16} {
+		if got := count("SELECT COUNT(*) FROM " + table); got != want {
+			t.Errorf("%s: expected %d rows, got %d", table, want, got)
+		}
+	}
+
+	// Workloads joined to their VMs for the second run.
+	if got := count(`SELECT COUNT(*) FROM workloads w JOIN vms v
+		ON v.run_id = w.run_id AND v.strategy = w.strategy AND v.vm_index = w.vm_index
+		WHERE w.run_id = 2 AND v.sku = 'Standard_D4_v3'`); got != 4 {
+		t.Errorf("expected 4 workloads on D4 VMs in run 2, got %d", got)
+	}
+	var reason string
+	if err := db.QueryRow(`SELECT w.unpacked_reason FROM workloads w JOIN strategy_results s
+		ON s.run_id = w.run_id AND s.strategy = w.strategy
+		WHERE w.vm_index IS NULL AND s.strategy = 'Naive' AND s.run_id = 1`).Scan(&reason); err != nil {
+		t.Fatal(err)
+	}
+	if reason != resolver.ReasonCPULimitExceeded {
+		t.Errorf("expected unpacked reason %q, got %q", resolver.ReasonCPULimitExceeded, reason)
+	}
+	var zone string
+	if err := db.QueryRow(`SELECT zone FROM vms WHERE run_id = 1 AND vm_index = 0 LIMIT 1`).Scan(&zone); err != nil || zone != "1" {
+		t.Errorf("expected VM 0 in zone 1, got %q (%v)", zone, err)
+	}
+	var id string
+	if err := db.QueryRow(`SELECT vm_id FROM vms WHERE run_id = 2 AND vm_index = 1 LIMIT 1`).Scan(&id); err != nil || id != "vm-0002" {
+		t.Errorf("expected VM 1 to be vm-0002, got %q (%v)", id, err)
+	}
+}
+
+func TestExportSQLite_MigratesVMIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// The vms table as created before VMs had IDs.
+	if _, err := db.Exec(`CREATE TABLE vms (run_id INTEGER NOT NULL, strategy TEXT NOT NULL, vm_index INTEGER NOT NULL,
+		sku TEXT NOT NULL, zone TEXT NOT NULL, price_per_hour REAL NOT NULL, cpu_util REAL NOT NULL, mem_util REAL NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportSQLite(path, sqliteFixtureRun()); err != nil {
+		t.Fatalf("export into an old database: %v", err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM vms WHERE vm_id = 'vm-0001'`).Scan(&n); err != nil || n != 2 {
+		t.Errorf("expected vm-0001 for both strategies, got %d (%v)", n, err)
+	}
+}
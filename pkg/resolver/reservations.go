@@ -0,0 +1,179 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// CapacityReservation is a block of on-demand capacity reserved for one SKU, optionally in one zone.
+type CapacityReservation struct {
+	SKU   string
+	Zone  string // empty for a regional reservation
+	Count int    // number of VMs reserved
+	// PricePerHour is what a reserved VM costs in the simulation. The reservation is paid for
+	// either way, so the default of 0 treats its VMs as free at the margin.
+	PricePerHour float64
+}
+
+// ReservationUsage reports how many slots of one reservation a packing used.
+type ReservationUsage struct {
+	SKU   string
+	Zone  string
+	Count int
+	Used  int
+	// SlotPricePerHour is the cost of one unused slot: the reservation's PricePerHour, or the
+	// SKU's list price when that is 0, since an idle reservation bills at the pay-as-you-go rate.
+	SlotPricePerHour float64
+}
+
+// UnusedCostPerHour returns the hourly cost of the slots no VM used.
+func (u ReservationUsage) UnusedCostPerHour() float64 {
+	if u.Used >= u.Count {
+		return 0
+	}
+	return float64(u.Count-u.Used) * u.SlotPricePerHour
+}
+
+// ReservationReport summarizes the reservation usage of a packing.
+type ReservationReport struct {
+	Reservations      []ReservationUsage
+	Utilization       float64 // percentage of reserved slots used
+	UnusedCostPerHour float64 // cost of the unused slots, also counted as waste
+}
+
+// newReservationReport summarizes usage, or returns nil when no reservations were configured.
+func newReservationReport(usage []ReservationUsage) *ReservationReport {
+	if len(usage) == 0 {
+		return nil
+	}
+	report := &ReservationReport{Reservations: usage}
+	var slots, used int
+	for _, u := range usage {
+		slots += u.Count
+		used += u.Used
+	}
+	report.Utilization = percentOf(float64(used), float64(slots))
+	report.UnusedCostPerHour = unusedReservationCost(usage)
+	return report
+}
+
+// unusedReservationCost returns the hourly cost of the reserved slots a packing left unused.
+func unusedReservationCost(usage []ReservationUsage) float64 {
+	var cost float64
+	for _, u := range usage {
+		cost += u.UnusedCostPerHour()
+	}
+	return cost
+}
+
+// reservationTracker hands out the reserved slots of one packing run.
+type reservationTracker struct {
+	reservations []CapacityReservation
+	specs        []AzureInstanceSpec // spec of each reservation's SKU; Name is "" when not a candidate
+	usage        []ReservationUsage
+}
+
+// newReservationTracker returns a tracker for reservations, or nil when there are none.
+func newReservationTracker(reservations []CapacityReservation, candidates []AzureInstanceSpec) *reservationTracker {
+	if len(reservations) == 0 {
+		return nil
+	}
+	t := &reservationTracker{
+		reservations: reservations,
+		specs:        make([]AzureInstanceSpec, len(reservations)),
+		usage:        make([]ReservationUsage, len(reservations)),
+	}
+	for i, r := range reservations {
+		var list float64
+		for _, c := range candidates {
+			if c.Name == r.SKU {
+				t.specs[i] = c
+				list = c.PricePerHour
+				break
+			}
+		}
+		slot := r.PricePerHour
+		if slot == 0 {
+			slot = list
+		}
+		t.usage[i] = ReservationUsage{SKU: r.SKU, Zone: r.Zone, Count: r.Count, SlotPricePerHour: slot}
+	}
+	return t
+}
+
+// selectFor returns a reserved VM for workload and its reservation index when a reservation has a
+// suitable free slot (see find), and otherwise selectWithConfig's choice with index -1.
+func (t *reservationTracker) selectFor(candidates []AzureInstanceSpec, workload WorkloadProfile, cfg Config) (AzureInstanceSpec, float64, int) {
+	if i, vm, ok := t.find(workload, cfg); ok {
+		return vm, ScoreInstanceWithConfig(vm, workload, cfg), i
+	}
+	vm, score := selectWithConfig(candidates, workload, cfg)
+	return vm, score, -1
+}
+
+/*
+find returns the first reservation, in configuration order, with a free slot whose SKU passes
+the selection filters for workload, can hold it, and is in the workload's zone. The returned
+spec is the reserved VM: priced at the reservation's rate and pinned to its zone.
+*/
+func (t *reservationTracker) find(workload WorkloadProfile, cfg Config) (int, AzureInstanceSpec, bool) {
+	if t == nil {
+		return -1, AzureInstanceSpec{}, false
+	}
+	for i, r := range t.reservations {
+		spec := t.specs[i]
+		if spec.Name == "" || t.usage[i].Used >= r.Count {
+			continue
+		}
+		if r.Zone != "" && workload.Zone != "" && workload.Zone != r.Zone {
+			continue
+		}
+		// Filter the SKU as offered, so zone count constraints see all of its zones.
+		if len(FilterInstanceTypes([]AzureInstanceSpec{spec}, workload, cfg.filterFuncs()...)) == 0 || !capacityOf(spec, cfg.FitMarginPercent).fits(workload) {
+			continue
+		}
+		if r.Zone != "" {
+			spec.AvailabilityZones = []string{r.Zone}
+		}
+		spec.PricePerHour = r.PricePerHour
+		return i, spec, true
+	}
+	return -1, AzureInstanceSpec{}, false
+}
+
+// use consumes a slot of reservation i; -1 (not reserved) is ignored.
+func (t *reservationTracker) use(i int) {
+	if i >= 0 {
+		t.usage[i].Used++
+	}
+}
+
+// report returns the usage of every reservation, or nil when there are none.
+func (t *reservationTracker) report() []ReservationUsage {
+	if t == nil {
+		return nil
+	}
+	return t.usage
+}
+
+// LoadCapacityReservations loads a JSON list of CapacityReservation. An empty path means none.
+func LoadCapacityReservations(path string) ([]CapacityReservation, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var reservations []CapacityReservation
+	if err := json.Unmarshal(data, &reservations); err != nil {
+		return nil, err
+	}
+	for i, r := range reservations {
+		if r.SKU == "" || r.Count < 0 || r.PricePerHour < 0 {
+			return nil, fmt.Errorf("reservation %d: SKU required, count and price must not be negative", i)
+		}
+	}
+	return reservations, nil
+}
@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func reservationSKUs() []AzureInstanceSpec {
+	return []AzureInstanceSpec{
+		{Name: "Standard_D2s_v5", Family: "Dsv5", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.096, AvailabilityZones: []string{"1", "2", "3"}},
+		{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2", "3"}},
+		{Name: "Standard_E4s_v5", Family: "Esv5", VCpus: 4, MemoryGiB: 32, PricePerHour: 0.252, AvailabilityZones: []string{"1", "2", "3"}},
+	}
+}
+
+func TestCapacityReservationsFilledFirst(t *testing.T) {
+	workloads := make(WorkloadSet, 8)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{CPURequirements: 2, MemoryRequirements: 4}
+	}
+	cfg := Config{CapacityReservations: []CapacityReservation{{SKU: "Standard_E4s_v5", Zone: "2", Count: 2}}}
+	for _, name := range []string{"ffd", "quota"} {
+		pack, _ := PackingAlgorithm(name)
+		result := pack(workloads, reservationSKUs(), cfg)
+		if len(result.VMs) < 2 || !result.VMs[0].Reserved || !result.VMs[1].Reserved {
+			t.Fatalf("%s: expected the first two VMs to fill the reservation, got %+v", name, result.VMs)
+		}
+		for i, vm := range result.VMs {
+			if vm.Reserved != (i < 2) {
+				t.Errorf("%s: VM %d reserved = %v", name, i, vm.Reserved)
+			}
+		}
+		if got := result.VMs[0].InstanceType; got.Name != "Standard_E4s_v5" || got.PricePerHour != 0 || got.AvailabilityZones[0] != "2" {
+			t.Errorf("%s: expected a free E4s_v5 pinned to zone 2, got %+v", name, got)
+		}
+		if u := result.Reservations[0]; u.Used != 2 || u.Count != 2 {
+			t.Errorf("%s: expected the reservation fully used, got %+v", name, u)
+		}
+		// 4 workloads on free reserved VMs, 4 on two pay-as-you-go D4s_v5.
+		if got := TotalCost(result.VMs); math.Abs(got-2*0.192) > 1e-9 {
+			t.Errorf("%s: expected only the pay-as-you-go VMs to cost, got %.3f", name, got)
+		}
+	}
+}
+
+func TestCapacityReservationsRespectWorkloadZone(t *testing.T) {
+	workloads := WorkloadSet{{CPURequirements: 1, MemoryRequirements: 1, Zone: "1"}}
+	cfg := Config{CapacityReservations: []CapacityReservation{{SKU: "Standard_E4s_v5", Zone: "2", Count: 1}}}
+	result := BinPackWorkloadsWithConfig(workloads, reservationSKUs(), cfg)
+	if len(result.VMs) != 1 || result.VMs[0].Reserved {
+		t.Fatalf("expected a pay-as-you-go VM for a workload in another zone, got %+v", result.VMs)
+	}
+}
+
+func TestReservationUtilizationAndWaste(t *testing.T) {
+	workloads := WorkloadSet{{CPURequirements: 4, MemoryRequirements: 16}}
+	cfg := Config{CapacityReservations: []CapacityReservation{
+		{SKU: "Standard_E4s_v5", Count: 3},
+		{SKU: "Standard_D4s_v5", Count: 1, PricePerHour: 0.1},
+	}}
+	sim := cfg.Summarize(BinPackWorkloadsWithConfig(workloads, reservationSKUs(), cfg))
+	rr := sim.Reservations
+	if rr == nil {
+		t.Fatal("expected a reservation report")
+	}
+	// One of four slots used.
+	if rr.Utilization != 25 {
+		t.Errorf("expected 25%% utilization, got %.1f", rr.Utilization)
+	}
+	// Two idle E4s_v5 slots bill at the list price, the idle D4s_v5 slot at its configured rate.
+	wantUnused := 2*0.252 + 0.1
+	if math.Abs(rr.UnusedCostPerHour-wantUnused) > 1e-9 || math.Abs(sim.Waste.UnusedReservationCostPerHour-wantUnused) > 1e-9 {
+		t.Errorf("expected unused reservation cost %.3f, got %.3f (waste %.3f)", wantUnused, rr.UnusedCostPerHour, sim.Waste.UnusedReservationCostPerHour)
+	}
+	// The reserved VM is free, so all waste is unused reservations.
+	if math.Abs(sim.Waste.TotalWastedCostPerHour-wantUnused) > 1e-9 {
+		t.Errorf("expected total waste %.3f, got %.3f", wantUnused, sim.Waste.TotalWastedCostPerHour)
+	}
+	if sim.TotalCost != 0 || !sim.VMs[0].Reserved {
+		t.Errorf("expected a single free reserved VM, got cost %.3f, %+v", sim.TotalCost, sim.VMs)
+	}
+	if NewSimulationResult(PackingResult{}).Reservations != nil {
+		t.Error("expected no reservation report without reservations")
+	}
+}
@@ -0,0 +1,300 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+/*
+Scenario describes a whole simulation declaratively, so it can be reproduced from one file
+instead of a long command line. Its fields mirror the instance-selection-sim flags of the same
+names; zero values mean the flags' defaults.
+
+Scenarios are YAML or JSON files (see LoadScenario). Paths are relative to the scenario file.
+*/
+type Scenario struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// SKU is the SKU file, or a comma-separated list of files priced in the same currency.
+	SKU string `json:"sku" yaml:"sku"`
+	// Trace is google, azure, alibaba or custom; custom reads Workloads. Empty means custom
+	// when Workloads is set and google otherwise.
+	Trace     string `json:"trace,omitempty" yaml:"trace,omitempty"`
+	Workloads string `json:"workloads,omitempty" yaml:"workloads,omitempty"`
+	// TraceURL overrides the download URL of Trace (see TraceOptions).
+	TraceURL string `json:"traceURL,omitempty" yaml:"traceURL,omitempty"`
+	MaxRows  int    `json:"maxRows,omitempty" yaml:"maxRows,omitempty"`
+	// Strategies are packed into the same run, concurrently on up to Parallelism goroutines
+	// (0 means one per CPU). Empty means general.
+	Strategies  []SelectionStrategy `json:"strategies,omitempty" yaml:"strategies,omitempty"`
+	Parallelism int                 `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+
+	Quota       string `json:"quota,omitempty" yaml:"quota,omitempty"`
+	StrictQuota bool   `json:"strictQuota,omitempty" yaml:"strictQuota,omitempty"`
+	// StrictRequirements fails the run when some workloads can never be packed (see PrecheckWorkloads).
+	StrictRequirements bool   `json:"strictRequirements,omitempty" yaml:"strictRequirements,omitempty"`
+	Reservations       string `json:"reservations,omitempty" yaml:"reservations,omitempty"`
+	// Headroom and FitMargin use the flag syntax, e.g. "cpu=10%,memory=10%". FitMargin is the
+	// per-VM overhead kept free when packing.
+	Headroom          string  `json:"headroom,omitempty" yaml:"headroom,omitempty"`
+	FitMargin         string  `json:"fitMargin,omitempty" yaml:"fitMargin,omitempty"`
+	SplitMaxCPU       int     `json:"splitMaxCPU,omitempty" yaml:"splitMaxCPU,omitempty"`
+	SplitMaxMemoryGiB float64 `json:"splitMaxMemoryGiB,omitempty" yaml:"splitMaxMemoryGiB,omitempty"`
+	MinZones          int     `json:"minZones,omitempty" yaml:"minZones,omitempty"`
+	// LimitCPU, LimitMemoryGiB and LimitVMs are Config.Limits.
+	LimitCPU       int     `json:"limitCPU,omitempty" yaml:"limitCPU,omitempty"`
+	LimitMemoryGiB float64 `json:"limitMemoryGiB,omitempty" yaml:"limitMemoryGiB,omitempty"`
+	LimitVMs       int     `json:"limitVMs,omitempty" yaml:"limitVMs,omitempty"`
+	// PreferFamilies weights scoring towards these families or series, most preferred first.
+	PreferFamilies     []string `json:"preferFamilies,omitempty" yaml:"preferFamilies,omitempty"`
+	ExploreTopK        int      `json:"exploreTopK,omitempty" yaml:"exploreTopK,omitempty"`
+	ExploreTemperature float64  `json:"exploreTemperature,omitempty" yaml:"exploreTemperature,omitempty"`
+	Seed               int64    `json:"seed,omitempty" yaml:"seed,omitempty"`
+	CostByLabel        string   `json:"costByLabel,omitempty" yaml:"costByLabel,omitempty"`
+	HistogramBuckets   string   `json:"histogramBuckets,omitempty" yaml:"histogramBuckets,omitempty"`
+	HoursPerMonth      float64  `json:"hoursPerMonth,omitempty" yaml:"hoursPerMonth,omitempty"`
+	SpotDiscount       float64  `json:"spotDiscount,omitempty" yaml:"spotDiscount,omitempty"`
+	// Manifest embeds a RunManifest of the run's inputs in the run, so Replay can reproduce it.
+	Manifest bool `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+
+	Outputs ScenarioOutputs `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// ScenarioOutputs are the report files a scenario writes; empty paths are skipped.
+type ScenarioOutputs struct {
+	CSV      string `json:"csv,omitempty" yaml:"csv,omitempty"`
+	Markdown string `json:"markdown,omitempty" yaml:"markdown,omitempty"`
+	JSON     string `json:"json,omitempty" yaml:"json,omitempty"`
+	SQLite   string `json:"sqlite,omitempty" yaml:"sqlite,omitempty"`
+}
+
+// Defaults of Scenario.
+const (
+	DefaultScenarioTrace   = "google"
+	DefaultScenarioMaxRows = 1000
+)
+
+/*
+LoadScenario loads a scenario from a .json file, or from YAML otherwise, and resolves it: paths
+become relative to the working directory instead of the scenario file, and defaults are filled
+in, so the result records exactly what RunScenario runs.
+*/
+func LoadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var sc Scenario
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &sc)
+	} else {
+		err = yaml.UnmarshalStrict(data, &sc)
+	}
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	sc, err = sc.resolve(filepath.Dir(path))
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return sc, nil
+}
+
+// resolve fills in defaults, validates sc and makes its paths relative to dir.
+func (sc Scenario) resolve(dir string) (Scenario, error) {
+	if sc.SKU == "" {
+		return Scenario{}, fmt.Errorf("sku is required")
+	}
+	if sc.Trace == "" {
+		sc.Trace = DefaultScenarioTrace
+		if sc.Workloads != "" {
+			sc.Trace = "custom"
+		}
+	}
+	switch TraceSource(sc.Trace) {
+	case TraceGoogle, TraceAzure, TraceAlibaba:
+	case TraceCustom:
+		if sc.Workloads == "" {
+			return Scenario{}, fmt.Errorf("trace custom requires workloads")
+		}
+	default:
+		return Scenario{}, fmt.Errorf("unknown trace %q", sc.Trace)
+	}
+	if sc.MaxRows <= 0 {
+		sc.MaxRows = DefaultScenarioMaxRows
+	}
+	if len(sc.Strategies) == 0 {
+		sc.Strategies = []SelectionStrategy{StrategyGeneralPurpose}
+	}
+	for _, s := range sc.Strategies {
+		switch s {
+		case StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive, StrategyAuto:
+		default:
+			return Scenario{}, fmt.Errorf("unknown strategy %q", s)
+		}
+	}
+	if sc.HoursPerMonth <= 0 {
+		sc.HoursPerMonth = DefaultHoursPerMonth
+	}
+	rel := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	skus := strings.Split(sc.SKU, ",")
+	for i, p := range skus {
+		skus[i] = rel(strings.TrimSpace(p))
+	}
+	sc.SKU = strings.Join(skus, ",")
+	for _, p := range []*string{&sc.Workloads, &sc.Quota, &sc.Reservations,
+		&sc.Outputs.CSV, &sc.Outputs.Markdown, &sc.Outputs.JSON, &sc.Outputs.SQLite} {
+		*p = rel(*p)
+	}
+	return sc, nil
+}
+
+// Config returns the packing Config of the scenario for strategy, loading its quota and
+// reservation files.
+func (sc Scenario) Config(strategy SelectionStrategy) (Config, error) {
+	quota, err := LoadQuota(sc.Quota)
+	if err != nil {
+		return Config{}, fmt.Errorf("load quota: %w", err)
+	}
+	reservations, err := LoadCapacityReservations(sc.Reservations)
+	if err != nil {
+		return Config{}, fmt.Errorf("load capacity reservations: %w", err)
+	}
+	return sc.config(strategy, quota, reservations)
+}
+
+// config is Config with the quota and reservations already loaded.
+func (sc Scenario) config(strategy SelectionStrategy, quota QuotaMap, reservations []CapacityReservation) (Config, error) {
+	headroom, err := ParseHeadroomSpec(sc.Headroom)
+	if err != nil {
+		return Config{}, err
+	}
+	margin, err := ParseFitMargin(sc.FitMargin)
+	if err != nil {
+		return Config{}, err
+	}
+	edges, err := ParseHistogramEdges(sc.HistogramBuckets)
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{
+		Strategy:               strategy,
+		Quota:                  quota,
+		StrictQuota:            sc.StrictQuota,
+		StrictRequirements:     sc.StrictRequirements,
+		CapacityReservations:   reservations,
+		Headroom:               headroom,
+		FitMarginPercent:       margin,
+		Limits:                 Limits{CPU: sc.LimitCPU, MemoryGiB: sc.LimitMemoryGiB, VMs: sc.LimitVMs},
+		MinZones:               sc.MinZones,
+		SplitMaxCPU:            sc.SplitMaxCPU,
+		SplitMaxMemoryGiB:      sc.SplitMaxMemoryGiB,
+		ExplorationTopK:        sc.ExploreTopK,
+		ExplorationTemperature: sc.ExploreTemperature,
+		Seed:                   sc.Seed,
+		FamilyPreferences:      sc.PreferFamilies,
+		CostLabelKey:           sc.CostByLabel,
+		HistogramEdges:         edges,
+		Projection:             ProjectionOptions{HoursPerMonth: sc.HoursPerMonth, SpotDiscount: sc.SpotDiscount},
+		Trace:                  sc.traceOptions(),
+	}, nil
+}
+
+// traceOptions returns the TraceOptions of TraceURL.
+func (sc Scenario) traceOptions() TraceOptions {
+	if sc.TraceURL == "" {
+		return TraceOptions{}
+	}
+	return TraceOptions{URLs: map[TraceSource]string{TraceSource(sc.Trace): sc.TraceURL}}
+}
+
+/*
+RunScenario runs a resolved scenario (see LoadScenario) and returns its results, with the
+scenario embedded for provenance, its RunMetadata, and a RunManifest when sc.Manifest is set.
+With one strategy the results are named "NewAlgorithm" and "Naive" like the CLI's; with
+several, each strategy's result is named after it and followed by "Naive" for the first
+strategy. Strategies run concurrently (see RunStrategyComparison) and ctx is checked before
+each starts.
+*/
+func RunScenario(ctx context.Context, sc Scenario) (SimulationRun, error) {
+	start := time.Now()
+	m, err := sc.load()
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	run, err := m.run(ctx)
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	if sc.Manifest {
+		if err := m.seal(); err != nil {
+			return SimulationRun{}, err
+		}
+		run.Manifest = &m
+	}
+	return withMetadata(run, start), nil
+}
+
+// load loads the inputs of sc from its files.
+func (sc Scenario) load() (RunManifest, error) {
+	m := RunManifest{Scenario: sc}
+	var err error
+	if sc.Trace == "custom" {
+		m.Workloads, err = loadCustomWorkloads(sc.Workloads)
+	} else {
+		m.Workloads, err = loadTraceWorkloads(TraceSource(sc.Trace), sc.MaxRows, sc.traceOptions(), os.Stdout)
+	}
+	if err != nil {
+		return RunManifest{}, err
+	}
+	skus, err := LoadSKUDatasets(sc.SKU)
+	if err != nil {
+		return RunManifest{}, fmt.Errorf("load skus: %w", err)
+	}
+	m.SKUs, m.Currency = skus.SKUs, skus.Currency
+	if m.Quota, err = LoadQuota(sc.Quota); err != nil {
+		return RunManifest{}, fmt.Errorf("load quota: %w", err)
+	}
+	if m.Reservations, err = LoadCapacityReservations(sc.Reservations); err != nil {
+		return RunManifest{}, fmt.Errorf("load capacity reservations: %w", err)
+	}
+	return m, nil
+}
+
+// run packs the manifest's workloads as RunScenario does, without reading any file.
+func (m RunManifest) run(ctx context.Context) (SimulationRun, error) {
+	sc := m.Scenario
+	configs := make([]Config, len(sc.Strategies))
+	var err error
+	for i, strategy := range sc.Strategies {
+		if configs[i], err = sc.config(strategy, m.Quota, m.Reservations); err != nil {
+			return SimulationRun{}, err
+		}
+		configs[i].Currency = m.Currency
+	}
+	runs, err := RunStrategyComparison(ctx, m.Workloads, m.SKUs, configs, sc.Parallelism, nil)
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	run := SimulationRun{Scenario: &sc}
+	for i, r := range runs {
+		name := string(sc.Strategies[i])
+		if len(sc.Strategies) == 1 {
+			name = "NewAlgorithm"
+		}
+		run.Results = append(run.Results, NamedResult{Name: name, Result: r.Result})
+	}
+	run.Results = append(run.Results, NamedResult{Name: "Naive", Result: runs[0].Naive})
+	return run, nil
+}
@@ -0,0 +1,124 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestLoadScenarioRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	sc := Scenario{
+		Name:           "nightly",
+		SKU:            "skus.json,skus_extra.json",
+		Workloads:      "workloads.json",
+		Strategies:     []SelectionStrategy{StrategyGeneralPurpose, StrategyAuto},
+		Quota:          "/etc/quota.json",
+		FitMargin:      "cpu=5%,memory=5%",
+		LimitVMs:       40,
+		PreferFamilies: []string{"D", "E"},
+		Seed:           7,
+		Outputs:        ScenarioOutputs{JSON: "out/run.json"},
+	}
+	want := sc
+	want.SKU = filepath.Join(dir, "skus.json") + "," + filepath.Join(dir, "skus_extra.json")
+	want.Workloads = filepath.Join(dir, "workloads.json")
+	want.Trace = "custom"
+	want.MaxRows = DefaultScenarioMaxRows
+	want.HoursPerMonth = DefaultHoursPerMonth
+	want.Outputs.JSON = filepath.Join(dir, "out", "run.json")
+
+	jsonData, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlData, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"scenario.json": jsonData, "scenario.yaml": yamlData} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadScenario(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %+v, got %+v", name, want, got)
+		}
+	}
+}
+
+func TestLoadScenarioErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"no-sku.yaml":   "trace: google\n",
+		"strategy.yaml": "sku: skus.json\nstrategies: [fastest]\n",
+		"custom.yaml":   "sku: skus.json\ntrace: custom\n",
+		"typo.yaml":     "sku: skus.json\nmaxrow: 10\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScenario(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestRunScenarioMatchesFlags checks that a scenario runs exactly like the equivalent
+// "-trace custom -workloads ... -sku ... -strategy memory -fit-margin ... -seed ..." flags.
+func TestRunScenarioMatchesFlags(t *testing.T) {
+	golden, err := filepath.Abs(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	body := "sku: " + filepath.Join(golden, "skus.json") + "\n" +
+		"workloads: " + filepath.Join(golden, "workloads.json") + "\n" +
+		"strategies: [memory]\nfitMargin: cpu=10%\nlimitCPU: 64\nseed: 3\n"
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := LoadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := RunScenario(context.Background(), sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Scenario == nil || !reflect.DeepEqual(*run.Scenario, sc) {
+		t.Errorf("expected the run to embed the resolved scenario, got %+v", run.Scenario)
+	}
+
+	cfg := Config{
+		Strategy:         StrategyMemoryIntensive,
+		FitMarginPercent: FitMargin{CPU: 10},
+		Limits:           Limits{CPU: 64},
+		Seed:             3,
+	}
+	result, naive, err := RunCustomWorkloadSimulationWithConfig(filepath.Join(golden, "workloads.json"), filepath.Join(golden, "skus.json"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NamedResult{{Name: "NewAlgorithm", Result: result}, {Name: "Naive", Result: naive}}
+	if len(run.Results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(run.Results))
+	}
+	for i := range want {
+		got := run.Results[i]
+		got.Result.Timing, want[i].Result.Timing = TimingReport{}, TimingReport{}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("result %s differs from the explicit flags' result", want[i].Name)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// ScoreCacheStats counts score cache lookups. It is safe for concurrent use.
+type ScoreCacheStats struct {
+	hits      atomic.Int64
+	misses    atomic.Int64
+	persisted atomic.Int64
+}
+
+// Hits returns the number of selections answered from the cache.
+func (s *ScoreCacheStats) Hits() int64 { return s.hits.Load() }
+
+// PersistedHits returns the number of Hits answered from a SelectionCache of an earlier run.
+func (s *ScoreCacheStats) PersistedHits() int64 { return s.persisted.Load() }
+
+// Misses returns the number of selections that had to filter and score the candidates.
+func (s *ScoreCacheStats) Misses() int64 { return s.misses.Load() }
+
+// HitRate returns the fraction of lookups answered from the cache.
+func (s *ScoreCacheStats) HitRate() float64 {
+	total := s.Hits() + s.Misses()
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits()) / float64(total)
+}
+
+/*
+workloadShape is the canonical form of everything about a workload that filtering and
+scoring look at. Traces contain thousands of workloads with identical shapes, so rankings
+are computed once per shape. Fields that never affect selection (labels, the headroom
+marker) are left out so such workloads share entries.
+*/
+type workloadShape struct {
+	cpu          int
+	mem          float64
+	io           float64
+	networkMbps  float64
+	iops         float64
+	diskMBps     float64
+	limitCPU     float64
+	limitMem     float64
+	gpu          int
+	gpuType      string
+	gpuMem       float64
+	zone         string
+	preferred    string
+	minZones     int
+	ephemeralOS  bool
+	nestedVirt   bool
+	spot         bool
+	confidential bool
+	arch         string
+	capabilities string // sorted key=value pairs
+	preferences  string // sorted key=value pairs
+}
+
+func shapeOf(w WorkloadProfile) workloadShape {
+	shape := workloadShape{
+		cpu:          w.CPURequirements,
+		mem:          w.MemoryRequirements,
+		io:           w.IORequirements,
+		networkMbps:  w.NetworkRequirementsMbps,
+		iops:         w.IOPSRequirements,
+		diskMBps:     w.ThroughputMBpsRequirements,
+		limitCPU:     w.LimitCPU,
+		limitMem:     w.LimitMemory,
+		gpu:          w.GPURequirements,
+		gpuType:      w.GPUType,
+		gpuMem:       w.GPUMemoryGiB,
+		zone:         w.Zone,
+		preferred:    w.PreferredZone,
+		minZones:     w.MinZones,
+		ephemeralOS:  w.RequireEphemeralOS,
+		nestedVirt:   w.RequireNestedVirt,
+		spot:         w.RequireSpot,
+		confidential: w.RequireConfidential,
+		arch:         w.Architecture,
+	}
+	shape.capabilities = sortedPairs(w.Capabilities)
+	shape.preferences = sortedPairs(w.Preferences)
+	return shape
+}
+
+// sortedPairs returns the entries of m sorted by key, each as its length-prefixed key and value,
+// so that no key or value containing the separators reads as several entries.
+func sortedPairs(m map[string]string) string {
+	if len(m) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%d:%s=%d:%s;", len(k), k, len(m[k]), m[k])
+	}
+	return b.String()
+}
+
+/*
+scoreCache memoizes rankTop per workload shape for one packing run.
+
+Entries are only valid for the candidate set and Config they were computed with. The Config
+is fixed for a run (Config.forRun creates a fresh cache), and the cache drops all entries as
+soon as it is queried with a different candidate slice, e.g. after the quota packer removes
+an exhausted family. A nil *scoreCache disables caching. It is not safe for concurrent use.
+
+With a persistent SelectionCache, misses are looked up there, in the section of the current
+candidates and configKey, and rankings computed in this run are added to it.
+*/
+type scoreCache struct {
+	first   *AzureInstanceSpec // identity of the candidate slice the entries belong to
+	n       int
+	entries map[workloadShape][]RankedCandidate
+	stats   *ScoreCacheStats
+
+	persistent *SelectionCache
+	configKey  []byte              // Config.selectionKey of the run
+	section    string              // SelectionCache section of the current candidates
+	candidates []AzureInstanceSpec // the current candidates
+}
+
+func newScoreCache(stats *ScoreCacheStats, persistent *SelectionCache, configKey []byte) *scoreCache {
+	return &scoreCache{entries: make(map[workloadShape][]RankedCandidate), stats: stats, persistent: persistent, configKey: configKey}
+}
+
+// sync drops all entries when candidates is not the slice they were computed for.
+func (c *scoreCache) sync(candidates []AzureInstanceSpec) {
+	var first *AzureInstanceSpec
+	if len(candidates) > 0 {
+		first = &candidates[0]
+	}
+	if first != c.first || len(candidates) != c.n {
+		c.first, c.n = first, len(candidates)
+		c.entries = make(map[workloadShape][]RankedCandidate)
+		if c.persistent != nil {
+			c.section, c.candidates = sectionKey(c.configKey, candidates), candidates
+		}
+	}
+}
+
+func (c *scoreCache) get(candidates []AzureInstanceSpec, w WorkloadProfile) ([]RankedCandidate, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.sync(candidates)
+	shape := shapeOf(w)
+	top, ok := c.entries[shape]
+	persisted := false
+	if !ok && c.persistent != nil {
+		if top, ok = c.persistent.get(c.section, shapeKey(shape), c.candidates); ok {
+			c.entries[shape] = top
+			persisted = true
+		}
+	}
+	if c.stats != nil {
+		if ok {
+			c.stats.hits.Add(1)
+		} else {
+			c.stats.misses.Add(1)
+		}
+		if persisted {
+			c.stats.persisted.Add(1)
+		}
+	}
+	return top, ok
+}
+
+func (c *scoreCache) put(candidates []AzureInstanceSpec, w WorkloadProfile, top []RankedCandidate) {
+	if c == nil {
+		return
+	}
+	c.sync(candidates)
+	c.entries[shapeOf(w)] = top
+	if c.persistent != nil {
+		c.persistent.put(c.section, shapeKey(shapeOf(w)), c.candidates, top)
+	}
+}
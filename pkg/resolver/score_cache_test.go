@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// repeatedShapeWorkloads returns n workloads drawn from a handful of shapes, like a real trace.
+func repeatedShapeWorkloads(n int, seed int64) WorkloadSet {
+	shapes := []WorkloadProfile{
+		{CPURequirements: 1, MemoryRequirements: 2},
+		{CPURequirements: 1, MemoryRequirements: 4},
+		{CPURequirements: 2, MemoryRequirements: 4},
+		{CPURequirements: 2, MemoryRequirements: 8, Zone: "1"},
+		{CPURequirements: 1, MemoryRequirements: 1, Capabilities: map[string]string{"TrustedLaunch": "true"}},
+	}
+	r := rand.New(rand.NewSource(seed))
+	workloads := make(WorkloadSet, n)
+	for i := range workloads {
+		workloads[i] = shapes[r.Intn(len(shapes))]
+	}
+	return workloads
+}
+
+func TestScoreCache_MatchesUncached(t *testing.T) {
+	workloads := repeatedShapeWorkloads(300, 1)
+	for _, cfg := range []Config{
+		{},
+		{Strategy: StrategyMemoryIntensive},
+		{ExplorationTopK: 3, ExplorationTemperature: 0.5, Seed: 5},
+		{Quota: QuotaMap{"D": 8}},
+		{PruneTopN: 3},
+	} {
+		stats := &ScoreCacheStats{}
+		cached := cfg
+		cached.ScoreCacheStats = stats
+		uncached := cfg
+		uncached.DisableScoreCache = true
+
+		got := BinPackWorkloadsWithConfig(workloads, dummyInstanceTypes(), cached)
+		want := BinPackWorkloadsWithConfig(workloads, dummyInstanceTypes(), uncached)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v: cached packing differs from the uncached packing", cfg)
+		}
+		if stats.Hits() == 0 {
+			t.Errorf("%+v: expected cache hits for repeated shapes, got %d hits, %d misses", cfg, stats.Hits(), stats.Misses())
+		}
+	}
+}
+
+func TestScoreCache_InvalidatedOnCandidateChange(t *testing.T) {
+	cache := newScoreCache(nil, nil, nil)
+	w := WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2}
+	candidates := dummyInstanceTypes()
+	cache.put(candidates, w, []RankedCandidate{{Instance: candidates[0]}})
+	if _, ok := cache.get(candidates, w); !ok {
+		t.Fatalf("expected a hit for the same candidate set")
+	}
+	if _, ok := cache.get(candidates[1:], w); ok {
+		t.Errorf("expected a miss after the candidate set changed")
+	}
+	if _, ok := cache.get(candidates, w); ok {
+		t.Errorf("expected entries of the previous candidate set to be dropped")
+	}
+}
+
+func TestShapeOf_IgnoresLabels(t *testing.T) {
+	a := WorkloadProfile{CPURequirements: 1, Capabilities: map[string]string{"a": "1", "b": "2"}, Labels: map[string]string{"team": "x"}}
+	b := WorkloadProfile{CPURequirements: 1, Capabilities: map[string]string{"b": "2", "a": "1"}, Headroom: true}
+	if shapeOf(a) != shapeOf(b) {
+		t.Errorf("expected equal shapes for workloads differing only in labels and headroom")
+	}
+	if shapeOf(a) == shapeOf(WorkloadProfile{CPURequirements: 1}) {
+		t.Errorf("expected capabilities to be part of the shape")
+	}
+}
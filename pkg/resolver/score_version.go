@@ -0,0 +1,26 @@
+package resolver
+
+import "fmt"
+
+// ScoreVersion selects the formula ScoreInstanceWithConfig scores instance types with.
+type ScoreVersion string
+
+const (
+	// ScoreVersionLegacy is ScoreInstance. It is the default: its cost term exceeds 30 for
+	// cheap SKUs while the fit terms are bounded by 1, so scores only rank one candidate set.
+	ScoreVersionLegacy ScoreVersion = ""
+	// ScoreVersionNormalized is ScoreInstanceNormalized: every score is in [0,1] and
+	// comparable across runs, so thresholds can be set on it.
+	ScoreVersionNormalized ScoreVersion = "normalized"
+)
+
+// ParseScoreVersion parses a CLI score version: "legacy" (or empty) or "normalized".
+func ParseScoreVersion(s string) (ScoreVersion, error) {
+	switch s {
+	case "", "legacy":
+		return ScoreVersionLegacy, nil
+	case string(ScoreVersionNormalized):
+		return ScoreVersionNormalized, nil
+	}
+	return "", fmt.Errorf("unknown score version %q, expected legacy or normalized", s)
+}
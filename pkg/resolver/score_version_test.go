@@ -0,0 +1,39 @@
+package resolver
+
+import "testing"
+
+func TestScoreInstanceNormalizedBounds(t *testing.T) {
+	workload := WorkloadProfile{CPURequirements: 2, MemoryRequirements: 8, RequireSpot: true, Zone: "1"}
+	skus := []AzureInstanceSpec{
+		{Name: "free", VCpus: 64, MemoryGiB: 256, StorageGiB: 1000, AvailabilityZones: []string{"1"}, SpotSupported: true},
+		{Name: "cheap", VCpus: 2, MemoryGiB: 4, PricePerHour: 0.02},
+		{Name: "expensive", VCpus: 96, MemoryGiB: 672, PricePerHour: 40, AvailabilityZones: []string{"1", "2", "3"}},
+	}
+	for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive, StrategyAuto} {
+		for _, sku := range skus {
+			cfg := Config{Strategy: strategy, ScoreVersion: ScoreVersionNormalized, FamilyPreferences: []string{"D"}}
+			if s := ScoreInstanceWithConfig(sku, workload, cfg); s < 0 || s > 1 {
+				t.Errorf("%s/%s: expected a score in [0,1], got %f", strategy, sku.Name, s)
+			}
+		}
+		// A free SKU satisfying every requirement scores exactly 1: the weights sum to 1
+		if s := ScoreInstanceNormalized(skus[0], workload, strategy); s < 1-1e-9 {
+			t.Errorf("%s: expected a perfect SKU to score 1, got %f", strategy, s)
+		}
+	}
+	// The legacy cost term alone exceeds 1 for cheap SKUs
+	if s := ScoreInstance(skus[1], workload, StrategyGeneralPurpose); s <= 1 {
+		t.Errorf("expected the legacy score of a cheap SKU above 1, got %f", s)
+	}
+}
+
+func TestParseScoreVersion(t *testing.T) {
+	for in, want := range map[string]ScoreVersion{"": ScoreVersionLegacy, "legacy": ScoreVersionLegacy, "normalized": ScoreVersionNormalized} {
+		if got, err := ParseScoreVersion(in); err != nil || got != want {
+			t.Errorf("ParseScoreVersion(%q) = %q, %v; expected %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseScoreVersion("v3"); err == nil {
+		t.Error("expected an error for an unknown score version")
+	}
+}
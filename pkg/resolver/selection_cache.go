@@ -0,0 +1,203 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+/*
+selectionCacheFormula identifies the filters and scoring weights compiled into this package.
+Rankings cached by a build with different weights must not be reused, and the code cannot hash
+itself, so bump it whenever filtering or ScoreInstance* change.
+*/
+const selectionCacheFormula = 4
+
+// selectionCacheFileVersion is the format of the selection cache file.
+const selectionCacheFileVersion = 1
+
+/*
+SelectionCache persists the per-shape rankings of the score cache between runs, e.g. of the CLI
+while tuning weights on the same trace. Rankings are stored per section, keyed by a hash of the
+candidate SKUs and of every Config field filtering and scoring depend on, so a change to any
+input misses the cache instead of returning stale rankings. Save writes only the sections used
+since LoadSelectionCache, dropping the invalidated ones. It is safe for concurrent use.
+*/
+type SelectionCache struct {
+	path     string
+	mu       sync.Mutex
+	sections map[string]map[string][]cachedRanking // by inputs hash, then by workload shape
+	used     map[string]bool
+}
+
+// cachedRanking is one RankedCandidate, stored as the index of its SKU among the candidates.
+type cachedRanking struct {
+	Index int     `json:"i"`
+	Score float64 `json:"s"`
+}
+
+type selectionCacheFile struct {
+	Version  int
+	Sections map[string]map[string][]cachedRanking
+}
+
+/*
+LoadSelectionCache loads the selection cache at path. A missing file, or one written in another
+format, starts an empty cache; Save creates or replaces it. Other read errors are returned.
+*/
+func LoadSelectionCache(path string) (*SelectionCache, error) {
+	c := &SelectionCache{path: path, sections: make(map[string]map[string][]cachedRanking), used: make(map[string]bool)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("selection cache: %w", err)
+	}
+	var file selectionCacheFile
+	if json.Unmarshal(data, &file) == nil && file.Version == selectionCacheFileVersion && file.Sections != nil {
+		c.sections = file.Sections
+	}
+	return c, nil
+}
+
+// Save writes the sections used since the cache was loaded to its file, atomically.
+func (c *SelectionCache) Save() error {
+	c.mu.Lock()
+	file := selectionCacheFile{Version: selectionCacheFileVersion, Sections: make(map[string]map[string][]cachedRanking)}
+	for key := range c.used {
+		file.Sections[key] = c.sections[key]
+	}
+	data, err := json.Marshal(file)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("selection cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("selection cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("selection cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("selection cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("selection cache: %w", err)
+	}
+	return nil
+}
+
+// get returns the cached ranking of shape in section key, rebuilt from candidates.
+func (c *SelectionCache) get(key, shape string, candidates []AzureInstanceSpec) ([]RankedCandidate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used[key] = true
+	cached, ok := c.sections[key][shape]
+	if !ok {
+		return nil, false
+	}
+	top := make([]RankedCandidate, len(cached))
+	for i, r := range cached {
+		if r.Index < 0 || r.Index >= len(candidates) {
+			return nil, false // hash collision or a hand-edited file
+		}
+		top[i] = RankedCandidate{Instance: candidates[r.Index], Score: r.Score}
+	}
+	return top, true
+}
+
+// put stores the ranking of shape in section key. Every ranked instance must be one of candidates.
+func (c *SelectionCache) put(key, shape string, candidates []AzureInstanceSpec, top []RankedCandidate) {
+	index := make(map[string]int, len(candidates))
+	for i, vm := range candidates {
+		if _, ok := index[vm.Name]; !ok {
+			index[vm.Name] = i
+		}
+	}
+	cached := make([]cachedRanking, len(top))
+	for i, r := range top {
+		idx, ok := index[r.Instance.Name]
+		if !ok {
+			return
+		}
+		cached[i] = cachedRanking{Index: idx, Score: r.Score}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used[key] = true
+	if c.sections[key] == nil {
+		c.sections[key] = make(map[string][]cachedRanking)
+	}
+	c.sections[key][shape] = cached
+}
+
+// selectionInputs are the Config fields rankTop's result depends on, besides the candidates.
+type selectionInputs struct {
+	Formula            int
+	Strategy           SelectionStrategy
+	StrategyThresholds StrategyThresholds
+	FitMargin          FitMargin
+	MinZones           int
+	NodeClass          NodeClassConstraints
+	AllowUnknownGPU    bool
+	PruneTopN          int
+	TopK               int
+	FamilyPreferences  []string
+	PreferenceWeights  map[string]float64
+	ScoreVersion       ScoreVersion
+	Weights            *ScoringWeights `json:",omitempty"`
+	MaxLimitRatio      float64         `json:",omitempty"`
+	DisableFilters     []string        `json:",omitempty"`
+}
+
+// selectionKey returns the hash of the ranking inputs, which c fixes for a run.
+func (c Config) selectionKey() []byte {
+	in := selectionInputs{
+		Formula:            selectionCacheFormula,
+		Strategy:           c.strategy(),
+		StrategyThresholds: c.StrategyThresholds,
+		FitMargin:          c.FitMarginPercent,
+		MinZones:           c.MinZones,
+		NodeClass:          c.NodeClass,
+		AllowUnknownGPU:    c.AllowUnknownGPUType,
+		PruneTopN:          c.PruneTopN,
+		TopK:               1,
+		FamilyPreferences:  c.FamilyPreferences,
+		PreferenceWeights:  c.PreferenceWeights,
+		ScoreVersion:       c.ScoreVersion,
+		Weights:            c.Weights,
+		DisableFilters:     c.DisableFilters,
+	}
+	if c.Overcommit.capped() {
+		in.MaxLimitRatio = c.Overcommit.MaxLimitRatio
+	}
+	if c.exploring() {
+		in.TopK = c.ExplorationTopK
+	}
+	data, _ := json.Marshal(in) // plain data, cannot fail
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// sectionKey returns the SelectionCache section of the rankings of candidates under configKey.
+func sectionKey(configKey []byte, candidates []AzureInstanceSpec) string {
+	h := sha256.New()
+	h.Write(configKey)
+	_ = json.NewEncoder(h).Encode(candidates) // plain data, cannot fail
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// shapeKey is the string form of a workload shape in the selection cache file.
+func shapeKey(s workloadShape) string {
+	return fmt.Sprintf("%+v", s)
+}
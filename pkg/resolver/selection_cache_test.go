@@ -0,0 +1,135 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// packWithSelectionCache packs with the selection cache at path, as one CLI run would, and
+// returns the result with its cache stats.
+func packWithSelectionCache(t *testing.T, path string, workloads WorkloadSet, cfg Config) (PackingResult, *ScoreCacheStats) {
+	t.Helper()
+	cache, err := LoadSelectionCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := &ScoreCacheStats{}
+	cfg.SelectionCache, cfg.ScoreCacheStats = cache, stats
+	result := BinPackWorkloadsWithConfig(workloads, dummyInstanceTypes(), cfg)
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return result, stats
+}
+
+func TestSelectionCache_SecondRunHits(t *testing.T) {
+	workloads := repeatedShapeWorkloads(500, 1)
+	for name, cfg := range map[string]Config{
+		"default":     {},
+		"exploration": {ExplorationTopK: 3, ExplorationTemperature: 0.5, Seed: 5},
+		"quota":       {Quota: QuotaMap{"D": 8}},
+	} {
+		path := filepath.Join(t.TempDir(), name+".json")
+		first, firstStats := packWithSelectionCache(t, path, workloads, cfg)
+		if firstStats.PersistedHits() != 0 {
+			t.Errorf("%s: expected no selection cache hits on the first run, got %d", name, firstStats.PersistedHits())
+		}
+		second, secondStats := packWithSelectionCache(t, path, workloads, cfg)
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("%s: packing from the selection cache differs from the first run", name)
+		}
+		// The second run ranks nothing: every selection is answered from the cache
+		if secondStats.Misses() != 0 || secondStats.PersistedHits() == 0 {
+			t.Errorf("%s: expected every shape from the selection cache, got %d misses and %d persisted hits", name, secondStats.Misses(), secondStats.PersistedHits())
+		}
+	}
+}
+
+func TestSelectionCache_ShortensSelection(t *testing.T) {
+	// Many SKUs and distinct shapes, so that ranking dominates the selection stage
+	var skus []AzureInstanceSpec
+	for i := 1; i <= 300; i++ {
+		skus = append(skus, AzureInstanceSpec{Name: fmt.Sprintf("Standard_D%d_v5", i), Family: "DSv5", VCpus: 2 * i, MemoryGiB: float64(8 * i), PricePerHour: 0.096 * float64(i)})
+	}
+	var workloads WorkloadSet
+	for cpu := 1; cpu <= 20; cpu++ {
+		for mem := 1; mem <= 40; mem++ {
+			workloads = append(workloads, WorkloadProfile{CPURequirements: cpu, MemoryRequirements: float64(mem)})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "selection-cache.json")
+	selectAll := func() ([]string, time.Duration) {
+		cache, err := LoadSelectionCache(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := Config{SelectionCache: cache}.forRun()
+		selected := make([]string, len(workloads))
+		start := time.Now()
+		for i, w := range workloads {
+			best, _ := selectWithConfig(skus, w, cfg)
+			selected[i] = best.Name
+		}
+		elapsed := time.Since(start)
+		if err := cache.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return selected, elapsed
+	}
+	first, firstTime := selectAll()
+	second, secondTime := selectAll()
+	if !reflect.DeepEqual(first, second) {
+		t.Error("selections from the selection cache differ from the first run")
+	}
+	if secondTime >= firstTime {
+		t.Errorf("expected the cached selection stage to be shorter, got %v then %v", firstTime, secondTime)
+	}
+}
+
+func TestSelectionCache_InvalidatedByInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selection-cache.json")
+	workloads := repeatedShapeWorkloads(100, 1)
+	packWithSelectionCache(t, path, workloads, Config{})
+	for _, cfg := range []Config{
+		{FamilyPreferences: []string{"E"}},
+		{Strategy: StrategyCPUIntensive},
+		{ScoreVersion: ScoreVersionNormalized},
+		{MinZones: 2},
+	} {
+		if _, stats := packWithSelectionCache(t, path, workloads, cfg); stats.PersistedHits() != 0 {
+			t.Errorf("%+v: expected a changed input to miss the selection cache, got %d hits", cfg, stats.PersistedHits())
+		}
+	}
+
+	// Other SKUs miss too
+	cache, err := LoadSelectionCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := &ScoreCacheStats{}
+	skus := dummyInstanceTypes()
+	skus[0].PricePerHour *= 2
+	BinPackWorkloadsWithConfig(workloads, skus, Config{SelectionCache: cache, ScoreCacheStats: stats})
+	if stats.PersistedHits() != 0 {
+		t.Errorf("expected changed SKUs to miss the selection cache, got %d hits", stats.PersistedHits())
+	}
+}
+
+func TestLoadSelectionCache_IgnoresForeignFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selection-cache.json")
+	for _, content := range []string{"not json", `{"Version": 99, "Sections": {}}`} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, stats := packWithSelectionCache(t, path, repeatedShapeWorkloads(10, 1), Config{}); stats.PersistedHits() != 0 {
+			t.Errorf("%q: expected an empty cache, got %d hits", content, stats.PersistedHits())
+		}
+	}
+	if _, err := LoadSelectionCache(t.TempDir()); err == nil {
+		t.Error("expected an error reading a directory")
+	}
+}
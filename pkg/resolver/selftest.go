@@ -0,0 +1,173 @@
+package resolver
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// selfTestFiles are the fixtures of SelfTest: a few SKUs, a few workloads including a GPU one
+// and one no SKU fits, and the expected outcome of every case.
+//
+//go:embed selftest/skus.json selftest/workloads.json selftest/expected.json
+var selfTestFiles embed.FS
+
+// selfTestStrategies are the strategies SelfTest packs with, each with every packing algorithm.
+var selfTestStrategies = []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive, StrategyAuto}
+
+// selfTestOutcome is what selftest/expected.json records for one case.
+type selfTestOutcome struct {
+	VMs       int
+	Unpacked  int
+	TotalCost string // per hour, rounded so float noise does not fail the test
+}
+
+/*
+SelfTest packs embedded fixtures with every strategy and registered packing algorithm, checks
+the packing invariants and the expected outcome of each case, and prints PASS or FAIL per case
+with its timing to w. It needs no network or files, so it verifies a build end to end, and
+returns an error if any case failed.
+*/
+func SelfTest(w io.Writer) error {
+	start := time.Now()
+	outcomes, failures, err := runSelfTest(w)
+	if err != nil {
+		fmt.Fprintf(w, "FAIL selftest: %v\n", err)
+		return err
+	}
+	expected, err := selfTestExpected()
+	if err != nil {
+		fmt.Fprintf(w, "FAIL selftest: %v\n", err)
+		return err
+	}
+	for _, name := range sortedKeys(outcomes) {
+		if want, ok := expected[name]; !ok || want != outcomes[name] {
+			fmt.Fprintf(w, "FAIL %s: expected %+v, got %+v\n", name, want, outcomes[name])
+			failures++
+		}
+	}
+	if failures > 0 {
+		fmt.Fprintf(w, "FAIL selftest: %d of %d cases failed (%s)\n", failures, len(outcomes), time.Since(start).Round(time.Microsecond))
+		return fmt.Errorf("selftest: %d of %d cases failed", failures, len(outcomes))
+	}
+	fmt.Fprintf(w, "PASS selftest: %d cases (%s)\n", len(outcomes), time.Since(start).Round(time.Microsecond))
+	return nil
+}
+
+// runSelfTest packs every case, printing each with the invariant check's verdict, and returns
+// the outcomes by "strategy/algorithm" and the number of invariant failures.
+func runSelfTest(w io.Writer) (map[string]selfTestOutcome, int, error) {
+	var skus []AzureInstanceSpec
+	var workloads WorkloadSet
+	for file, v := range map[string]any{"selftest/skus.json": &skus, "selftest/workloads.json": &workloads} {
+		data, err := selfTestFiles.ReadFile(file)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	outcomes := make(map[string]selfTestOutcome)
+	failures := 0
+	for _, strategy := range selfTestStrategies {
+		for _, algorithm := range PackingAlgorithms() {
+			pack, _ := PackingAlgorithm(algorithm)
+			name := string(strategy) + "/" + algorithm
+			cfg := Config{Strategy: strategy, Seed: 1}
+			start := time.Now()
+			result := pack(workloads, skus, cfg)
+			sim := cfg.Summarize(result)
+			elapsed := time.Since(start).Round(time.Microsecond)
+			outcome := selfTestOutcome{VMs: sim.VMsUsed, Unpacked: sim.Unpacked, TotalCost: fmt.Sprintf("%.4f", sim.TotalCost)}
+			outcomes[name] = outcome
+			violation := invariantViolation(workloads, result, func(w WorkloadProfile) string { return w.Name })
+			if violation == "" {
+				if err := sim.CheckPacked(); err != nil {
+					violation = err.Error()
+				}
+			}
+			if violation != "" {
+				fmt.Fprintf(w, "FAIL %s: %s\n", name, violation)
+				failures++
+				continue
+			}
+			fmt.Fprintf(w, "PASS %-14s %2d VMs, %d unpacked, %s/h (%s)\n", name, outcome.VMs, outcome.Unpacked, outcome.TotalCost, elapsed)
+		}
+	}
+	return outcomes, failures, nil
+}
+
+func selfTestExpected() (map[string]selfTestOutcome, error) {
+	data, err := selfTestFiles.ReadFile("selftest/expected.json")
+	if err != nil {
+		return nil, err
+	}
+	var expected map[string]selfTestOutcome
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("selftest/expected.json: %w", err)
+	}
+	return expected, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+invariantViolation returns the first invariant result violates as a packing of workloads, or
+"": no VM is overcommitted in CPU, memory, bandwidth or disk IOPS, every workload (identified by
+id) is packed exactly once or unpacked with a reason, and TotalCost is the sum of VM prices.
+*/
+func invariantViolation(workloads WorkloadSet, result PackingResult, id func(WorkloadProfile) string) string {
+	seen := make(map[string]int)
+	var sum float64
+	for i, vm := range result.VMs {
+		var cpu int
+		var mem, mbps, iops float64
+		for _, w := range vm.Workloads {
+			seen[id(w)]++
+			cpu += w.CPURequirements
+			mem += w.MemoryRequirements
+			mbps += w.NetworkRequirementsMbps
+			iops += w.IOPSRequirements
+		}
+		if cpu > vm.InstanceType.VCpus || !fitsWithin(mem, vm.InstanceType.MemoryGiB) {
+			return fmt.Sprintf("VM %d (%s) overcommitted: %d/%d vCPUs, %.1f/%.1f GiB", i, vm.InstanceType.Name, cpu, vm.InstanceType.VCpus, mem, vm.InstanceType.MemoryGiB)
+		}
+		if bw := ExpectedBandwidthMbps(vm.InstanceType); !fitsWithin(mbps, bw) {
+			return fmt.Sprintf("VM %d (%s) bandwidth oversubscribed: %.0f/%.0f Mbps", i, vm.InstanceType.Name, mbps, bw)
+		}
+		if !fitsWithin(iops, knownOrUnlimited(vm.InstanceType.UncachedDiskIOPS)) {
+			return fmt.Sprintf("VM %d (%s) disk IOPS oversubscribed: %.0f/%.0f", i, vm.InstanceType.Name, iops, vm.InstanceType.UncachedDiskIOPS)
+		}
+		sum += vm.InstanceType.PricePerHour
+	}
+	for _, u := range result.Unpacked {
+		if u.Reason == "" {
+			return fmt.Sprintf("workload %s unpacked without a reason", id(u.Workload))
+		}
+		seen[id(u.Workload)]++
+	}
+	for _, w := range workloads {
+		if n := seen[id(w)]; n != 1 {
+			return fmt.Sprintf("workload %s appears %d times across VMs and Unpacked", id(w), n)
+		}
+	}
+	if len(seen) != len(workloads) {
+		return fmt.Sprintf("%d distinct workloads in the result, %d in the input", len(seen), len(workloads))
+	}
+	if math.Abs(TotalCost(result.VMs)-sum) > 1e-9 {
+		return fmt.Sprintf("TotalCost %.6f differs from the sum of VM prices %.6f", TotalCost(result.VMs), sum)
+	}
+	return ""
+}
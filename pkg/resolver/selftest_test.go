@@ -0,0 +1,36 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSelfTest runs the selftest command's checks; -update rewrites selftest/expected.json.
+func TestSelfTest(t *testing.T) {
+	if *update {
+		outcomes, failures, err := runSelfTest(&bytes.Buffer{})
+		if err != nil || failures > 0 {
+			t.Fatalf("not updating selftest/expected.json: %d invariant failures, %v", failures, err)
+		}
+		data, err := json.MarshalIndent(outcomes, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile("selftest/expected.json", append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Skip("updated selftest/expected.json; rerun without -update")
+	}
+	var out bytes.Buffer
+	start := time.Now()
+	err := SelfTest(&out)
+	if err != nil {
+		t.Errorf("%v (run with -update if the change is intended):\n%s", err, out.String())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the selftest to run in under a second, took %s", elapsed)
+	}
+}
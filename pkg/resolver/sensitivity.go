@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// sensitivityTrials is how many perturbed price sets SensitivityAnalysis packs per level.
+const sensitivityTrials = 20
+
+/*
+PriceSensitivity is how robust a packing is to prices drifting by up to PerturbationPercent:
+over Trials packings with every SKU's price perturbed, how often the SKU mix differed from
+the packing at list prices, and how the cost at the perturbed prices varied.
+*/
+type PriceSensitivity struct {
+	PerturbationPercent float64
+	Trials              int
+	MixChanges          int // trials whose VM count per SKU differs from the baseline
+	BaselineCost        float64
+	MeanCost            float64
+	CostStdDev          float64
+}
+
+// MixChangeRate returns the share of trials that changed the SKU mix, from 0 to 1.
+func (p PriceSensitivity) MixChangeRate() float64 {
+	if p.Trials == 0 {
+		return 0
+	}
+	return float64(p.MixChanges) / float64(p.Trials)
+}
+
+/*
+SensitivityAnalysis packs workloads with strategy at list prices and then, for each
+perturbation level in percent, with every SKU's price scaled by a factor drawn uniformly from
+[1-X/100, 1+X/100]. Perturbations are deterministic per SKU and trial (see Config.Seed for
+seeding them through a Config), so reruns report the same rows.
+*/
+func SensitivityAnalysis(workloads WorkloadSet, candidates []AzureInstanceSpec, strategy SelectionStrategy, perturbations []float64) []PriceSensitivity {
+	return Config{Strategy: strategy}.sensitivity(workloads, candidates, perturbations)
+}
+
+// sensitivity is SensitivityAnalysis driven by a Config. The selection cache is not used, as
+// every perturbed candidate set would add a section to it.
+func (c Config) sensitivity(workloads WorkloadSet, candidates []AzureInstanceSpec, perturbations []float64) []PriceSensitivity {
+	c.SelectionCache, c.ScoreCacheStats, c.WithAudit = nil, nil, false
+	baseline := BinPackWorkloadsWithConfig(workloads, candidates, c)
+	baseMix := skuMix(baseline.VMs)
+	rows := make([]PriceSensitivity, 0, len(perturbations))
+	for _, pct := range perturbations {
+		row := PriceSensitivity{PerturbationPercent: pct, Trials: sensitivityTrials, BaselineCost: TotalCost(baseline.VMs)}
+		costs := make([]float64, sensitivityTrials)
+		for trial := range costs {
+			result := BinPackWorkloadsWithConfig(workloads, perturbPrices(candidates, pct, c.Seed, trial), c)
+			costs[trial] = TotalCost(result.VMs)
+			if !sameMix(baseMix, skuMix(result.VMs)) {
+				row.MixChanges++
+			}
+		}
+		row.MeanCost, row.CostStdDev = meanStdDev(costs)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// perturbPrices returns a copy of candidates with each price scaled by a factor in
+// [1-pct/100, 1+pct/100], seeded by seed, trial and the SKU name.
+func perturbPrices(candidates []AzureInstanceSpec, pct float64, seed int64, trial int) []AzureInstanceSpec {
+	perturbed := make([]AzureInstanceSpec, len(candidates))
+	for i, vm := range candidates {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%d/%d/%s", seed, trial, vm.Name)
+		u := rand.New(rand.NewSource(int64(h.Sum64()))).Float64()*2 - 1
+		vm.PricePerHour *= 1 + u*pct/100
+		perturbed[i] = vm
+	}
+	return perturbed
+}
+
+// skuMix counts vms by SKU name.
+func skuMix(vms []PackedVM) map[string]int {
+	mix := make(map[string]int)
+	for _, vm := range vms {
+		mix[vm.InstanceType.Name]++
+	}
+	return mix
+}
+
+func sameMix(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for sku, n := range a {
+		if b[sku] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// meanStdDev returns the mean and population standard deviation of xs.
+func meanStdDev(xs []float64) (float64, float64) {
+	if len(xs) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+	var sq float64
+	for _, x := range xs {
+		sq += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(xs)))
+}
+
+// ParseSensitivity parses a --sensitivity value, comma-separated perturbations in percent,
+// e.g. "5,10". Each must be above 0 and below 100.
+func ParseSensitivity(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var levels []float64
+	for _, part := range strings.Split(s, ",") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(part), "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("perturbation %q: %w", part, err)
+		}
+		if !(pct > 0 && pct < 100) {
+			return nil, fmt.Errorf("perturbation must be above 0 and below 100 percent, got %v", pct)
+		}
+		levels = append(levels, pct)
+	}
+	return levels, nil
+}
@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sensitivityWorkloads() WorkloadSet {
+	workloads := make(WorkloadSet, 4)
+	for i := range workloads {
+		workloads[i] = WorkloadProfile{CPURequirements: 4, MemoryRequirements: 8}
+	}
+	return workloads
+}
+
+func TestSensitivityAnalysis_NearTieFlips(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192},
+		{Name: "Standard_D4as_v5", Family: "DASv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.193},
+	}
+	rows := SensitivityAnalysis(sensitivityWorkloads(), candidates, StrategyGeneralPurpose, []float64{5, 10})
+	if len(rows) != 2 || rows[0].PerturbationPercent != 5 || rows[1].PerturbationPercent != 10 {
+		t.Fatalf("expected a row per perturbation level, got %+v", rows)
+	}
+	for _, p := range rows {
+		if p.MixChanges == 0 || p.MixChanges == p.Trials {
+			t.Errorf("expected a near tie to flip the winner in some but not all trials, got %+v", p)
+		}
+		if p.CostStdDev == 0 || p.BaselineCost != 4*0.192 {
+			t.Errorf("expected varying costs against a 0.768/h baseline, got %+v", p)
+		}
+	}
+	if again := SensitivityAnalysis(sensitivityWorkloads(), candidates, StrategyGeneralPurpose, []float64{5, 10}); !reflect.DeepEqual(rows, again) {
+		t.Errorf("expected deterministic perturbations, got %+v and %+v", rows, again)
+	}
+}
+
+func TestSensitivityAnalysis_DominantStable(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192},
+		{Name: "Standard_D4as_v5", Family: "DASv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.4},
+	}
+	for _, p := range SensitivityAnalysis(sensitivityWorkloads(), candidates, StrategyGeneralPurpose, []float64{5, 10}) {
+		if p.MixChanges != 0 || p.MixChangeRate() != 0 {
+			t.Errorf("expected a dominant SKU to win every trial, got %+v", p)
+		}
+		if p.MeanCost < p.BaselineCost*(1-p.PerturbationPercent/100) || p.MeanCost > p.BaselineCost*(1+p.PerturbationPercent/100) {
+			t.Errorf("expected the mean cost within the perturbation of the baseline, got %+v", p)
+		}
+	}
+}
+
+func TestParseSensitivity(t *testing.T) {
+	if got, err := ParseSensitivity("5, 10%"); err != nil || !reflect.DeepEqual(got, []float64{5, 10}) {
+		t.Errorf("expected [5 10], got %v, %v", got, err)
+	}
+	if got, err := ParseSensitivity(""); err != nil || got != nil {
+		t.Errorf("expected no levels for an empty value, got %v, %v", got, err)
+	}
+	for _, bad := range []string{"0", "-5", "100", "five"} {
+		if _, err := ParseSensitivity(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
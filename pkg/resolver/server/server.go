@@ -0,0 +1,175 @@
+/*
+Package server exposes the resolver over a JSON REST API so non-Go tooling can call it.
+
+	POST /v1/select  WorkloadProfile in, ranked feasible candidates with scores out (?limit=N, default 10)
+	POST /v1/pack    list of WorkloadProfile in, SimulationResult summary out
+	GET  /v1/skus    the active SKU catalog (SKU filters applied)
+
+Workloads use the same JSON shape as the simulator's custom workloads file.
+*/
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+const (
+	// DefaultMaxBodyBytes limits the size of request bodies.
+	DefaultMaxBodyBytes = 1 << 20
+	// defaultSelectLimit is the number of candidates /v1/select returns without ?limit.
+	defaultSelectLimit = 10
+	// shutdownTimeout bounds how long ListenAndServe waits for in-flight requests on shutdown.
+	shutdownTimeout = 10 * time.Second
+)
+
+// Server serves the resolver REST API on top of a SelectorService.
+type Server struct {
+	svc          *resolver.SelectorService
+	maxBodyBytes int64
+	mux          *http.ServeMux
+}
+
+// New returns a Server backed by svc. maxBodyBytes <= 0 means DefaultMaxBodyBytes.
+func New(svc *resolver.SelectorService, maxBodyBytes int64) *Server {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	s := &Server{svc: svc, maxBodyBytes: maxBodyBytes, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /v1/select", s.handleSelect)
+	s.mux.HandleFunc("POST /v1/pack", s.handlePack)
+	s.mux.HandleFunc("GET /v1/skus", s.handleSKUs)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves on addr until ctx is cancelled, then shuts down gracefully.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("shutdown: %w", err)
+		}
+		return nil
+	}
+}
+
+// SelectResponse is the body returned by POST /v1/select.
+type SelectResponse struct {
+	Candidates []resolver.RankedCandidate `json:"candidates"`
+}
+
+// PackResponse is the body returned by POST /v1/pack.
+type PackResponse struct {
+	Summary  resolver.SimulationResult   `json:"summary"`
+	Unpacked []resolver.UnpackedWorkload `json:"unpacked,omitempty"`
+}
+
+// SKUsResponse is the body returned by GET /v1/skus.
+type SKUsResponse struct {
+	SKUs []resolver.AzureInstanceSpec `json:"skus"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleSelect(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSelectLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+		limit = n
+	}
+	var workload resolver.WorkloadProfile
+	if !s.decode(w, r, &workload) {
+		return
+	}
+	if err := validateWorkload(workload); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SelectResponse{Candidates: s.svc.Select(workload, limit)})
+}
+
+func (s *Server) handlePack(w http.ResponseWriter, r *http.Request) {
+	var workloads resolver.WorkloadSet
+	if !s.decode(w, r, &workloads) {
+		return
+	}
+	if len(workloads) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no workloads given"))
+		return
+	}
+	for i, workload := range workloads {
+		if err := validateWorkload(workload); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("workload %d: %w", i, err))
+			return
+		}
+	}
+	result, summary := s.svc.Pack(workloads)
+	summary.VMs = nil // per-VM detail can be large; the summary is what callers need
+	writeJSON(w, http.StatusOK, PackResponse{Summary: summary, Unpacked: result.Unpacked})
+}
+
+func (s *Server) handleSKUs(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, SKUsResponse{SKUs: s.svc.SKUs()})
+}
+
+// decode reads a size-limited JSON body into v, writing an error response on failure.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", s.maxBodyBytes))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+		return false
+	}
+	return true
+}
+
+// validateWorkload rejects workloads that cannot be scheduled anywhere.
+func validateWorkload(w resolver.WorkloadProfile) error {
+	switch {
+	case w.CPURequirements < 0 || w.MemoryRequirements < 0 || w.GPURequirements < 0:
+		return errors.New("resource requirements must not be negative")
+	case w.CPURequirements == 0 && w.MemoryRequirements == 0:
+		return errors.New("workload must request CPU or memory")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
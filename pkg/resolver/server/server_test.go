@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func testServer() *Server {
+	skus := []resolver.AzureInstanceSpec{
+		{Name: "Standard_D2_v3", VCpus: 2, MemoryGiB: 8, PricePerHour: 0.1, Family: "D"},
+		{Name: "Standard_D4_v3", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2, Family: "D"},
+		{Name: "Standard_NC6", VCpus: 6, MemoryGiB: 56, PricePerHour: 0.9, Family: "NC", GPUCount: 1},
+	}
+	noGPU := func(vm resolver.AzureInstanceSpec) bool { return vm.GPUCount == 0 }
+	return New(resolver.NewSelectorService(skus, resolver.Config{}, noGPU), 1024)
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestSelect(t *testing.T) {
+	rec := do(t, testServer(), http.MethodPost, "/v1/select?limit=1", `{"CPURequirements": 1, "MemoryRequirements": 2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp SelectResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Candidates) != 1 || resp.Candidates[0].Score <= 0 {
+		t.Errorf("expected one scored candidate, got %+v", resp.Candidates)
+	}
+}
+
+func TestPack(t *testing.T) {
+	rec := do(t, testServer(), http.MethodPost, "/v1/pack", `[{"CPURequirements": 1, "MemoryRequirements": 2}, {"CPURequirements": 1, "MemoryRequirements": 2}]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp PackResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Summary.VMsUsed == 0 || resp.Summary.TotalCost <= 0 {
+		t.Errorf("expected a non-empty packing summary, got %+v", resp.Summary)
+	}
+}
+
+func TestSKUs(t *testing.T) {
+	rec := do(t, testServer(), http.MethodGet, "/v1/skus", "")
+	var resp SKUsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.SKUs) != 2 {
+		t.Errorf("expected the GPU SKU to be filtered out, got %d SKUs", len(resp.SKUs))
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	srv := testServer()
+	for _, tc := range []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/v1/select", `{"CPURequirements": -1}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/select", `{}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/select", `{"Bogus": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/select?limit=x", `{"CPURequirements": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/pack", `[]`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/pack", `not json`, http.StatusBadRequest},
+		{http.MethodGet, "/v1/select", "", http.StatusMethodNotAllowed},
+	} {
+		if rec := do(t, srv, tc.method, tc.path, tc.body); rec.Code != tc.want {
+			t.Errorf("%s %s %q: expected %d, got %d", tc.method, tc.path, tc.body, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestLargePayloadRejected(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i := 0; i < 100; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(`{"CPURequirements": 1, "MemoryRequirements": 2}`)
+	}
+	buf.WriteString("]")
+	if rec := do(t, testServer(), http.MethodPost, "/v1/pack", buf.String()); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", rec.Code)
+	}
+}
+
+func TestSelect_OnlyFeasibleCandidates(t *testing.T) {
+	rec := do(t, testServer(), http.MethodPost, "/v1/select", `{"CPURequirements": 3, "MemoryRequirements": 8}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp SelectResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Candidates) != 1 || resp.Candidates[0].Instance.Name != "Standard_D4_v3" {
+		t.Errorf("expected only Standard_D4_v3 to hold 3 vCPUs, got %+v", resp.Candidates)
+	}
+}
@@ -0,0 +1,77 @@
+package resolver
+
+import "sync"
+
+// RankedCandidate is an instance type together with its score for a workload.
+type RankedCandidate struct {
+	Instance AzureInstanceSpec
+	Score    float64
+}
+
+/*
+SelectorService answers selection and packing requests against a loaded SKU catalog.
+
+The catalog is narrowed once by the active SKU filters, so callers such as the HTTP server
+do not reload or refilter SKUs per request. A SelectorService is safe for concurrent use;
+the catalog and Config can be swapped at runtime with Update.
+*/
+type SelectorService struct {
+	mu   sync.RWMutex
+	skus []AzureInstanceSpec
+	cfg  Config
+}
+
+// NewSelectorService returns a service over the skus accepted by every filter.
+func NewSelectorService(skus []AzureInstanceSpec, cfg Config, filters ...func(AzureInstanceSpec) bool) *SelectorService {
+	s := &SelectorService{}
+	s.Update(skus, cfg, filters...)
+	return s
+}
+
+// Update replaces the catalog and Config; in-flight requests finish with the previous ones.
+func (s *SelectorService) Update(skus []AzureInstanceSpec, cfg Config, filters ...func(AzureInstanceSpec) bool) {
+	var active []AzureInstanceSpec
+	for _, sku := range skus {
+		ok := true
+		for _, filter := range filters {
+			if !filter(sku) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			active = append(active, sku)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skus = active
+	s.cfg = cfg
+}
+
+// snapshot returns the current catalog and Config. The catalog slice is never mutated in place.
+func (s *SelectorService) snapshot() ([]AzureInstanceSpec, Config) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.skus, s.cfg
+}
+
+// SKUs returns a copy of the active SKU catalog.
+func (s *SelectorService) SKUs() []AzureInstanceSpec {
+	skus, _ := s.snapshot()
+	return append([]AzureInstanceSpec(nil), skus...)
+}
+
+// Select returns the capacity-feasible instance types for workload ranked best first, at most
+// limit of them (limit <= 0 returns all). See SelectTopN.
+func (s *SelectorService) Select(workload WorkloadProfile, limit int) []RankedCandidate {
+	skus, cfg := s.snapshot()
+	return cfg.topN(skus, workload, limit)
+}
+
+// Pack bin-packs workloads onto the active catalog and summarizes the result.
+func (s *SelectorService) Pack(workloads WorkloadSet) (PackingResult, SimulationResult) {
+	skus, cfg := s.snapshot()
+	result := BinPackWorkloadsWithConfig(workloads, skus, cfg)
+	return result, cfg.Summarize(result)
+}
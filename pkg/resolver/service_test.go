@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSelectorService(t *testing.T) {
+	svc := NewSelectorService(dummyInstanceTypes(), Config{}, func(vm AzureInstanceSpec) bool {
+		return vm.GPUCount == 0
+	})
+	for _, sku := range svc.SKUs() {
+		if sku.GPUCount > 0 {
+			t.Fatalf("expected GPU SKUs to be filtered out, got %s", sku.Name)
+		}
+	}
+
+	ranked := svc.Select(WorkloadProfile{CPURequirements: 2, MemoryRequirements: 4}, 3)
+	if len(ranked) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(ranked))
+	}
+	for i := 1; i < len(ranked); i++ {
+		if ranked[i].Score > ranked[i-1].Score {
+			t.Errorf("candidates not sorted by score: %+v", ranked)
+		}
+	}
+
+	// Concurrent requests and updates must be race free.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%4 == 0 {
+				svc.Update(dummyInstanceTypes(), Config{})
+			}
+			_, sim := svc.Pack(WorkloadSet{{CPURequirements: 1, MemoryRequirements: 2}})
+			if sim.VMsUsed != 1 {
+				t.Errorf("expected 1 VM, got %d", sim.VMsUsed)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSelectTopN(t *testing.T) {
+	candidates := dummyInstanceTypes()
+	workload := WorkloadProfile{CPURequirements: 6, MemoryRequirements: 20}
+	for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive} {
+		top := SelectTopN(candidates, workload, strategy, 4)
+		if len(top) != 4 {
+			t.Fatalf("%v: expected 4 candidates, got %d", strategy, len(top))
+		}
+		for i, c := range top {
+			if c.Instance.VCpus < workload.CPURequirements || c.Instance.MemoryGiB < workload.MemoryRequirements {
+				t.Errorf("%v: candidate %d (%s) cannot hold the workload", strategy, i, c.Instance.Name)
+			}
+			if i > 0 && c.Score > top[i-1].Score {
+				t.Errorf("%v: candidates not sorted by score: %+v", strategy, top)
+			}
+		}
+		// Every candidate can hold a small workload, so the best instance is the first candidate.
+		small := WorkloadProfile{CPURequirements: 1, MemoryRequirements: 2}
+		if best, top := SelectBestInstanceWithStrategy(candidates, small, strategy), SelectTopN(candidates, small, strategy, 3); top[0].Instance.Name != best.Name {
+			t.Errorf("%v: expected the first candidate to be the best instance %s, got %s", strategy, best.Name, top[0].Instance.Name)
+		}
+	}
+
+	// Only the SKUs with two or more K80s survive filtering and capacity checks.
+	gpu := WorkloadProfile{CPURequirements: 8, MemoryRequirements: 64, GPURequirements: 2, GPUType: "K80"}
+	if top := SelectTopN(candidates, gpu, StrategyGeneralPurpose, 10); len(top) != 2 {
+		t.Errorf("expected the 2 feasible GPU candidates, got %+v", top)
+	}
+	if top := SelectTopN(candidates, WorkloadProfile{CPURequirements: 64}, StrategyGeneralPurpose, 3); len(top) != 0 {
+		t.Errorf("expected no candidates for an oversized workload, got %+v", top)
+	}
+}
@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+/*
+ShapeHash returns a stable 64-bit key of everything about w that filtering, scoring and packing
+look at: its resource requests and limits, GPU, zone, architecture and other requirements,
+capabilities and preferences. Workloads with the same shape are interchangeable to the
+packers, so external systems can key caches, deduplication or grouping on it.
+
+Fields that never affect selection are left out: the name, namespace and parent, labels,
+priority, arrival and duration, local storage, observed usage and the headroom marker.
+Renaming a workload never changes its hash; changing any other field does.
+
+The hash is FNV-1a over the fields by name in sorted order, each with its length-prefixed
+value, so it does not depend on the order of the fields in WorkloadProfile. Capabilities and
+preferences are length-prefixed per key and value in turn, sorted by key. Fields at their
+zero value are skipped, so adding a field keeps the hashes of workloads that do not set it.
+Hashes are equal across builds and platforms unless this algorithm changes.
+*/
+func (w WorkloadProfile) ShapeHash() uint64 {
+	s := shapeOf(w)
+	fields := map[string]string{
+		"cpu":          strconv.Itoa(s.cpu),
+		"memory":       formatShapeFloat(s.mem),
+		"io":           formatShapeFloat(s.io),
+		"networkMbps":  formatShapeFloat(s.networkMbps),
+		"iops":         formatShapeFloat(s.iops),
+		"diskMBps":     formatShapeFloat(s.diskMBps),
+		"limitCPU":     formatShapeFloat(s.limitCPU),
+		"limitMemory":  formatShapeFloat(s.limitMem),
+		"gpu":          strconv.Itoa(s.gpu),
+		"gpuType":      s.gpuType,
+		"gpuMemory":    formatShapeFloat(s.gpuMem),
+		"zone":         s.zone,
+		"preferred":    s.preferred,
+		"minZones":     strconv.Itoa(s.minZones),
+		"ephemeralOS":  strconv.FormatBool(s.ephemeralOS),
+		"nestedVirt":   strconv.FormatBool(s.nestedVirt),
+		"spot":         strconv.FormatBool(s.spot),
+		"confidential": strconv.FormatBool(s.confidential),
+		"arch":         s.arch,
+		"capabilities": s.capabilities,
+		"preferences":  s.preferences,
+	}
+	names := make([]string, 0, len(fields))
+	for name, v := range fields {
+		if v != "" && v != "0" && v != "false" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	h := fnv.New64a()
+	for _, name := range names {
+		v := fields[name]
+		fmt.Fprintf(h, "%s=%d:%s;", name, len(v), v)
+	}
+	return h.Sum64()
+}
+
+// formatShapeFloat formats v exactly, with "0" for zero.
+func formatShapeFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,86 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+// shapeHashIgnored are the WorkloadProfile fields ShapeHash leaves out.
+var shapeHashIgnored = map[string]bool{
+	"Name": true, "Namespace": true, "Parent": true, "Labels": true, "Priority": true,
+	"ArrivalSeconds": true, "DurationSeconds": true, "StorageRequirements": true,
+	"UsageCPU": true, "UsageMemory": true, "Headroom": true,
+}
+
+func TestShapeHash_EveryField(t *testing.T) {
+	base := WorkloadProfile{Name: "web", CPURequirements: 2, MemoryRequirements: 4}
+	want := base.ShapeHash()
+	v := reflect.ValueOf(base)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		changed := base
+		f := reflect.ValueOf(&changed).Elem().Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString("x")
+		case reflect.Int:
+			f.SetInt(f.Int() + 1)
+		case reflect.Float64:
+			f.SetFloat(f.Float() + 0.5)
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Map:
+			f.Set(reflect.ValueOf(map[string]string{"k": "v"}))
+		default:
+			t.Fatalf("field %s of kind %s: extend the test", field.Name, f.Kind())
+		}
+		if got := changed.ShapeHash(); (got != want) == shapeHashIgnored[field.Name] {
+			if shapeHashIgnored[field.Name] {
+				t.Errorf("expected changing %s to keep the hash", field.Name)
+			} else {
+				t.Errorf("expected changing %s to change the hash; add it to shapeOf or to shapeHashIgnored", field.Name)
+			}
+		}
+	}
+	// A map value holding the separators must not read as several entries.
+	joined, split := map[string]string{"a": "b;c=d"}, map[string]string{"a": "b", "c": "d"}
+	if (WorkloadProfile{Capabilities: joined}).ShapeHash() == (WorkloadProfile{Capabilities: split}).ShapeHash() {
+		t.Error("expected capabilities {a: b;c=d} and {a: b, c: d} to hash differently")
+	}
+	if (WorkloadProfile{Preferences: joined}).ShapeHash() == (WorkloadProfile{Preferences: split}).ShapeHash() {
+		t.Error("expected preferences {a: b;c=d} and {a: b, c: d} to hash differently")
+	}
+}
+
+func TestShapeHash_MapOrderAndZeroValues(t *testing.T) {
+	a := WorkloadProfile{CPURequirements: 1, Capabilities: map[string]string{"MaxPods": "30", "TrustedLaunch": "true"}}
+	b := WorkloadProfile{CPURequirements: 1, Capabilities: map[string]string{"TrustedLaunch": "true", "MaxPods": "30"}}
+	if a.ShapeHash() != b.ShapeHash() {
+		t.Error("expected equal capabilities to hash equally")
+	}
+	if (WorkloadProfile{CPURequirements: 1, Capabilities: map[string]string{}}).ShapeHash() != (WorkloadProfile{CPURequirements: 1}).ShapeHash() {
+		t.Error("expected an empty map to hash like a nil one")
+	}
+	// A zone must not be confused with a GPU type of the same value.
+	if (WorkloadProfile{Zone: "1"}).ShapeHash() == (WorkloadProfile{GPUType: "1"}).ShapeHash() {
+		t.Error("expected the field name to be part of the hash")
+	}
+}
+
+// TestShapeHash_Stable pins the hashes of fixture workloads. A failure means ShapeHash changed,
+// which invalidates every cache keyed on it: only update the values for a deliberate change.
+func TestShapeHash_Stable(t *testing.T) {
+	for _, tc := range []struct {
+		w    WorkloadProfile
+		want uint64
+	}{
+		{WorkloadProfile{}, 0xcbf29ce484222325},
+		{WorkloadProfile{Name: "web", CPURequirements: 2, MemoryRequirements: 4}, 0x9ef3b0ae63f64de0},
+		{WorkloadProfile{CPURequirements: 8, MemoryRequirements: 32, GPURequirements: 1, GPUType: "A100,V100", GPUMemoryGiB: 40, Zone: "eastus-1"}, 0x582d228eb6658f52},
+		{WorkloadProfile{CPURequirements: 1, MemoryRequirements: 0.5, LimitCPU: 2, Architecture: ArchARM64, Capabilities: map[string]string{"MaxPods": "30"}}, 0xa5454a096e9828b1},
+	} {
+		if got := tc.w.ShapeHash(); got != tc.want {
+			t.Errorf("%+v: expected hash %#x, got %#x", tc.w, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+/*
+Simulator runs simulations of workloads against a SKU catalog with one Config. Its methods do
+no IO besides loading the inputs they name and never print: progress goes to Log, and the
+results are returned as a SimulationRun of the new and the naive algorithm, named
+"NewAlgorithm" and "Naive", with the RunMetadata of the call. The RunTraceSimulation and RunCustomWorkloadSimulation functions
+are wrappers printing to stdout.
+*/
+type Simulator struct {
+	SKUs []AzureInstanceSpec
+	// Config is the packing Config; NewSimulator sets its Currency to that of the SKUs.
+	Config Config
+	// Log receives progress lines; nil discards them.
+	Log io.Writer
+}
+
+// NewSimulator returns a Simulator of the SKUs in skuPath, a file or comma-separated list of
+// files priced in the same currency (see LoadSKUDatasets).
+func NewSimulator(skuPath string, cfg Config, log io.Writer) (*Simulator, error) {
+	s := &Simulator{Config: cfg, Log: log}
+	if err := s.loadSKUs(skuPath); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RunTrace downloads a public trace into .trace_cache unless cached and simulates up to maxRows
+// of its workloads. TraceCustom is not a downloadable trace; see RunCustom.
+func (s *Simulator) RunTrace(ctx context.Context, source TraceSource, maxRows int) (SimulationRun, error) {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		return SimulationRun{}, err
+	}
+	workloads, err := s.traceWorkloads(source, maxRows)
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	return s.runWorkloads(ctx, workloads, start)
+}
+
+// RunCustom simulates the workloads of a custom workloads file in Config.WorkloadFormat.
+func (s *Simulator) RunCustom(ctx context.Context, workloadsPath string) (SimulationRun, error) {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		return SimulationRun{}, err
+	}
+	workloads, err := s.customWorkloads(workloadsPath)
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	return s.runWorkloads(ctx, workloads, start)
+}
+
+// RunWorkloads simulates workloads. ctx is checked before packing starts.
+func (s *Simulator) RunWorkloads(ctx context.Context, workloads WorkloadSet) (SimulationRun, error) {
+	return s.runWorkloads(ctx, workloads, time.Now())
+}
+
+// runWorkloads is RunWorkloads for a call started at start.
+func (s *Simulator) runWorkloads(ctx context.Context, workloads WorkloadSet, start time.Time) (SimulationRun, error) {
+	runs, err := RunStrategyComparison(ctx, workloads, s.SKUs, []Config{s.Config}, 1, s.log())
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	return withMetadata(SimulationRun{Results: []NamedResult{
+		{Name: "NewAlgorithm", Result: runs[0].Result},
+		{Name: "Naive", Result: runs[0].Naive},
+	}}, start), nil
+}
+
+// log returns where progress goes.
+func (s *Simulator) log() io.Writer {
+	if s.Log == nil {
+		return io.Discard
+	}
+	return s.Log
+}
+
+// loadSKUs loads the SKUs of skuPath and takes over their currency.
+func (s *Simulator) loadSKUs(skuPath string) error {
+	fmt.Fprintf(s.log(), "Loading Azure instance specs from %s...\n", skuPath)
+	ds, err := LoadSKUDatasets(skuPath)
+	if err != nil {
+		return fmt.Errorf("load skus: %w", err)
+	}
+	for _, w := range ds.Warnings {
+		fmt.Fprintf(s.log(), "Warning: %s\n", w)
+	}
+	if s.Config.StrictSKUs && len(ds.Warnings) > 0 {
+		return fmt.Errorf("load skus: suspicious SKU data: %s", ds.Warnings[0])
+	}
+	s.SKUs, s.Config.Currency = ds.SKUs, ds.Currency
+	return nil
+}
+
+func (s *Simulator) traceWorkloads(source TraceSource, maxRows int) (WorkloadSet, error) {
+	if source == TraceCustom {
+		return nil, fmt.Errorf("custom trace not supported here, use RunCustom")
+	}
+	return loadTraceWorkloads(source, maxRows, s.Config.Trace, s.log())
+}
+
+func (s *Simulator) customWorkloads(path string) (WorkloadSet, error) {
+	workloads, err := LoadWorkloads(path, s.Config.WorkloadFormat)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(s.log(), "Loaded %d custom workloads from %s\n", len(workloads), path)
+	return workloads, nil
+}
@@ -0,0 +1,117 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The free functions keep their signatures as wrappers of Simulator.
+var (
+	_ func(TraceSource, string, int, string) (SimulationResult, SimulationResult, error) = RunTraceSimulationWithQuota
+	_ func(TraceSource, string, int, Config) (SimulationResult, SimulationResult, error) = RunTraceSimulationWithConfig
+	_ func(TraceSource, string, int, Config) (SimulationRun, error)                      = RunTraceSimulationWithResults
+	_ func(string, string, string) (SimulationResult, SimulationResult, error)           = RunCustomWorkloadSimulationWithQuota
+	_ func(string, string, Config) (SimulationResult, SimulationResult, error)           = RunCustomWorkloadSimulationWithConfig
+)
+
+func goldenSimulator(t *testing.T, log io.Writer) *Simulator {
+	t.Helper()
+	s, err := NewSimulator(filepath.Join("testdata", "golden", "skus.json"), Config{Strategy: StrategyCPUIntensive}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSimulator_RunWorkloads(t *testing.T) {
+	var log bytes.Buffer
+	s := goldenSimulator(t, &log)
+	workloads, err := loadCustomWorkloads(filepath.Join("testdata", "golden", "workloads.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := s.RunWorkloads(context.Background(), workloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Results) != 2 || run.Results[0].Name != "NewAlgorithm" || run.Results[1].Name != "Naive" {
+		t.Fatalf("expected the NewAlgorithm and Naive results, got %+v", run.Results)
+	}
+	want, _, err := simulate(workloads, s.SKUs, Config{Strategy: StrategyCPUIntensive, Currency: s.Config.Currency, progress: &bytes.Buffer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := run.Results[0].Result
+	got.Timing, want.Timing = TimingReport{}, TimingReport{}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("expected the same result as packing directly")
+	}
+	if !strings.Contains(log.String(), "Simulating bin-packing with new algorithm") {
+		t.Errorf("expected progress in the log, got %q", log.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.RunWorkloads(ctx, workloads); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled context to stop the run, got %v", err)
+	}
+}
+
+func TestSimulator_RunCustom(t *testing.T) {
+	path := filepath.Join("testdata", "golden", "workloads.json")
+	s := goldenSimulator(t, nil)
+	run, err := s.RunCustom(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The wrapper prints to stdout; its results match the method's.
+	result, naive, err := RunCustomWorkloadSimulationWithConfig(path, filepath.Join("testdata", "golden", "skus.json"), Config{Strategy: StrategyCPUIntensive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalCost != run.Results[0].Result.TotalCost || naive.VMsUsed != run.Results[1].Result.VMsUsed {
+		t.Errorf("expected the wrapper to match Simulator.RunCustom, got %v/%d and %v/%d",
+			result.TotalCost, naive.VMsUsed, run.Results[0].Result.TotalCost, run.Results[1].Result.VMsUsed)
+	}
+	if m := run.Metadata; m == nil || m.Version != BuildVersion() || m.Start.IsZero() || m.End.Before(m.Start) || m.Duration <= 0 {
+		t.Errorf("expected the run's metadata, got %+v", m)
+	}
+	if _, err := s.RunCustom(context.Background(), filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing workloads file")
+	}
+}
+
+func TestSimulator_RunTrace(t *testing.T) {
+	trace, err := os.ReadFile(filepath.Join("testdata", "traces", "azure.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write(trace) }))
+	defer srv.Close()
+	var log bytes.Buffer
+	s := goldenSimulator(t, &log)
+	s.Config.Trace.URLs = map[TraceSource]string{TraceAzure: srv.URL + "/azure.csv"}
+	t.Chdir(t.TempDir()) // the trace is cached in .trace_cache
+	run, err := s.RunTrace(context.Background(), TraceAzure, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := run.Results[0].Result; res.VMsUsed == 0 {
+		t.Errorf("expected the trace's workloads packed, got %+v", res)
+	}
+	if !strings.Contains(log.String(), "Downloading "+srv.URL) {
+		t.Errorf("expected the download in the log, got %q", log.String())
+	}
+	if _, err := s.RunTrace(context.Background(), TraceCustom, 0); err == nil {
+		t.Error("expected the custom trace to be rejected")
+	}
+}
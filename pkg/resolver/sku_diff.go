@@ -0,0 +1,211 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SKUPriceChange is a SKU whose hourly price changed.
+type SKUPriceChange struct {
+	SKU      string
+	Old, New float64
+}
+
+// Percent returns the price change relative to the old price, e.g. 10 for a 10% increase.
+// A SKU that used to be free has an infinite change.
+func (c SKUPriceChange) Percent() float64 {
+	if c.Old == 0 {
+		return math.Inf(1)
+	}
+	return (c.New - c.Old) / c.Old * 100
+}
+
+// SKUZoneChange is a SKU whose availability zones changed.
+type SKUZoneChange struct {
+	SKU            string
+	Added, Removed []string
+}
+
+// SKUFieldChange is any other change to a SKU: a capability flag that flipped, a Capabilities
+// entry, or a size. Field names the AzureInstanceSpec field, or "Capabilities[key]".
+type SKUFieldChange struct {
+	SKU      string
+	Field    string
+	Old, New string // "" when a Capabilities entry was added or removed
+}
+
+// InstanceSpecDiff is the structured difference between two SKU datasets. Every list is sorted by SKU.
+type InstanceSpecDiff struct {
+	Added        []string // SKUs only in the new dataset
+	Removed      []string // SKUs only in the old dataset
+	PriceChanges []SKUPriceChange
+	ZoneChanges  []SKUZoneChange
+	FieldChanges []SKUFieldChange
+}
+
+// Empty reports whether the datasets describe the same SKUs.
+func (d InstanceSpecDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.PriceChanges) == 0 && len(d.ZoneChanges) == 0 && len(d.FieldChanges) == 0
+}
+
+// PriceIncreasesAbove returns the price changes that raised a price by more than percent.
+func (d InstanceSpecDiff) PriceIncreasesAbove(percent float64) []SKUPriceChange {
+	var over []SKUPriceChange
+	for _, c := range d.PriceChanges {
+		if c.New > c.Old && c.Percent() > percent {
+			over = append(over, c)
+		}
+	}
+	return over
+}
+
+/*
+DiffInstanceSpecs compares two SKU datasets, e.g. before and after an Azure update, by SKU
+name. Zones are compared as sets. When a dataset lists a SKU more than once, its first entry
+is used, as FilterInstanceTypes would see it first.
+*/
+func DiffInstanceSpecs(oldSpecs, newSpecs []AzureInstanceSpec) InstanceSpecDiff {
+	oldByName, newByName := specsByName(oldSpecs), specsByName(newSpecs)
+	var d InstanceSpecDiff
+	for _, name := range unionKeys(oldByName, newByName) {
+		o, inOld := oldByName[name]
+		n, inNew := newByName[name]
+		switch {
+		case !inOld:
+			d.Added = append(d.Added, name)
+			continue
+		case !inNew:
+			d.Removed = append(d.Removed, name)
+			continue
+		}
+		if o.PricePerHour != n.PricePerHour {
+			d.PriceChanges = append(d.PriceChanges, SKUPriceChange{SKU: name, Old: o.PricePerHour, New: n.PricePerHour})
+		}
+		added, removed := stringSetDiff(o.AvailabilityZones, n.AvailabilityZones)
+		if len(added) > 0 || len(removed) > 0 {
+			d.ZoneChanges = append(d.ZoneChanges, SKUZoneChange{SKU: name, Added: added, Removed: removed})
+		}
+		for _, f := range specFields {
+			if ov, nv := f.value(o), f.value(n); ov != nv {
+				d.FieldChanges = append(d.FieldChanges, SKUFieldChange{SKU: name, Field: f.name, Old: ov, New: nv})
+			}
+		}
+		for _, key := range unionKeys(o.Capabilities, n.Capabilities) {
+			if ov, nv := o.Capabilities[key], n.Capabilities[key]; ov != nv {
+				d.FieldChanges = append(d.FieldChanges, SKUFieldChange{SKU: name, Field: "Capabilities[" + key + "]", Old: ov, New: nv})
+			}
+		}
+	}
+	return d
+}
+
+func specsByName(specs []AzureInstanceSpec) map[string]AzureInstanceSpec {
+	byName := make(map[string]AzureInstanceSpec, len(specs))
+	for _, s := range specs {
+		if _, ok := byName[s.Name]; !ok {
+			byName[s.Name] = s
+		}
+	}
+	return byName
+}
+
+// stringSetDiff returns the sorted values only in b and only in a.
+func stringSetDiff(a, b []string) (added, removed []string) {
+	inA, inB := make(map[string]bool), make(map[string]bool)
+	for _, v := range a {
+		inA[v] = true
+	}
+	for _, v := range b {
+		inB[v] = true
+		if !inA[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range a {
+		if !inB[v] {
+			removed = append(removed, v)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return dedupSorted(added), dedupSorted(removed)
+}
+
+func dedupSorted(values []string) []string {
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// specFields are the AzureInstanceSpec fields DiffInstanceSpecs reports as SKUFieldChange.
+var specFields = []struct {
+	name  string
+	value func(AzureInstanceSpec) string
+}{
+	{"VCpus", func(s AzureInstanceSpec) string { return strconv.Itoa(s.VCpus) }},
+	{"MemoryGiB", func(s AzureInstanceSpec) string { return formatFloat(s.MemoryGiB) }},
+	{"StorageGiB", func(s AzureInstanceSpec) string { return formatFloat(s.StorageGiB) }},
+	{"Family", func(s AzureInstanceSpec) string { return s.Family }},
+	{"GPUCount", func(s AzureInstanceSpec) string { return strconv.Itoa(s.GPUCount) }},
+	{"GPUType", func(s AzureInstanceSpec) string { return s.GPUType }},
+	{"GPUMemoryGiBPerGPU", func(s AzureInstanceSpec) string { return formatFloat(s.GPUMemoryGiBPerGPU) }},
+	{"EphemeralOSDisk", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.EphemeralOSDisk) }},
+	{"NestedVirtualization", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.NestedVirtualization) }},
+	{"SpotSupported", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.SpotSupported) }},
+	{"ConfidentialComputing", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.ConfidentialComputing) }},
+	{"TrustedLaunch", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.TrustedLaunch) }},
+	{"AcceleratedNetworking", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.AcceleratedNetworking) }},
+	{"MaxPods", func(s AzureInstanceSpec) string { return strconv.Itoa(s.MaxPods) }},
+	{"UltraSSDEnabled", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.UltraSSDEnabled) }},
+	{"ProximityPlacement", func(s AzureInstanceSpec) string { return strconv.FormatBool(s.ProximityPlacement) }},
+	{"NetworkBandwidthMbps", func(s AzureInstanceSpec) string { return formatFloat(s.NetworkBandwidthMbps) }},
+	{"UncachedDiskIOPS", func(s AzureInstanceSpec) string { return formatFloat(s.UncachedDiskIOPS) }},
+	{"DiskMBps", func(s AzureInstanceSpec) string { return formatFloat(s.DiskMBps) }},
+	{"MaxEphemeralOSDiskGB", func(s AzureInstanceSpec) string { return formatFloat(s.MaxEphemeralOSDiskGB) }},
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// String formats the diff for humans, one line per change.
+func (d InstanceSpecDiff) String() string {
+	var b strings.Builder
+	if d.Empty() {
+		b.WriteString("no SKU changes\n")
+		return b.String()
+	}
+	for _, name := range d.Added {
+		fmt.Fprintf(&b, "+ %s\n", name)
+	}
+	for _, name := range d.Removed {
+		fmt.Fprintf(&b, "- %s\n", name)
+	}
+	for _, c := range d.PriceChanges {
+		fmt.Fprintf(&b, "~ %s price %.4f -> %.4f (%+.1f%%)\n", c.SKU, c.Old, c.New, c.Percent())
+	}
+	for _, c := range d.ZoneChanges {
+		fmt.Fprintf(&b, "~ %s zones", c.SKU)
+		if len(c.Added) > 0 {
+			fmt.Fprintf(&b, " +%s", strings.Join(c.Added, ","))
+		}
+		if len(c.Removed) > 0 {
+			fmt.Fprintf(&b, " -%s", strings.Join(c.Removed, ","))
+		}
+		b.WriteString("\n")
+	}
+	for _, c := range d.FieldChanges {
+		fmt.Fprintf(&b, "~ %s %s %q -> %q\n", c.SKU, c.Field, c.Old, c.New)
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package resolver
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffInstanceSpecs(t *testing.T) {
+	d4 := AzureInstanceSpec{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2", "3"}, SpotSupported: true}
+	e4 := AzureInstanceSpec{Name: "Standard_E4s_v5", VCpus: 4, MemoryGiB: 32, PricePerHour: 0.252, Capabilities: map[string]string{"AcceleratedNetworking": "true"}}
+	f4 := AzureInstanceSpec{Name: "Standard_F4s_v2", VCpus: 4, MemoryGiB: 8, PricePerHour: 0.169}
+	d4New, e4New := d4, e4
+	d4New.PricePerHour = 0.2112
+	d4New.AvailabilityZones = []string{"3", "1", "4"}
+	d4New.SpotSupported = false
+	e4New.Capabilities = map[string]string{"AcceleratedNetworking": "false", "UltraSSD": "true"}
+	added := AzureInstanceSpec{Name: "Standard_D4s_v6", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}
+
+	d := DiffInstanceSpecs([]AzureInstanceSpec{d4, e4, f4}, []AzureInstanceSpec{e4New, d4New, added})
+	if !reflect.DeepEqual(d.Added, []string{"Standard_D4s_v6"}) {
+		t.Errorf("expected Standard_D4s_v6 added, got %v", d.Added)
+	}
+	if !reflect.DeepEqual(d.Removed, []string{"Standard_F4s_v2"}) {
+		t.Errorf("expected Standard_F4s_v2 removed, got %v", d.Removed)
+	}
+	if len(d.PriceChanges) != 1 || d.PriceChanges[0].SKU != d4.Name || math.Abs(d.PriceChanges[0].Percent()-10) > 1e-9 {
+		t.Errorf("expected a 10%% price increase of %s, got %+v", d4.Name, d.PriceChanges)
+	}
+	wantZones := []SKUZoneChange{{SKU: d4.Name, Added: []string{"4"}, Removed: []string{"2"}}}
+	if !reflect.DeepEqual(d.ZoneChanges, wantZones) {
+		t.Errorf("expected zone changes %+v, got %+v", wantZones, d.ZoneChanges)
+	}
+	wantFields := []SKUFieldChange{
+		{SKU: d4.Name, Field: "SpotSupported", Old: "true", New: "false"},
+		{SKU: e4.Name, Field: "Capabilities[AcceleratedNetworking]", Old: "true", New: "false"},
+		{SKU: e4.Name, Field: "Capabilities[UltraSSD]", Old: "", New: "true"},
+	}
+	if !reflect.DeepEqual(d.FieldChanges, wantFields) {
+		t.Errorf("expected field changes %+v, got %+v", wantFields, d.FieldChanges)
+	}
+	out := d.String()
+	for _, want := range []string{"+ Standard_D4s_v6", "- Standard_F4s_v2", "~ Standard_D4s_v5 price 0.1920 -> 0.2112 (+10.0%)", "~ Standard_D4s_v5 zones +4 -2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	if same := DiffInstanceSpecs([]AzureInstanceSpec{d4, e4}, []AzureInstanceSpec{e4, d4}); !same.Empty() {
+		t.Errorf("expected reordered datasets to be identical, got:\n%s", same)
+	}
+}
+
+func TestInstanceSpecDiff_PriceIncreasesAbove(t *testing.T) {
+	d := InstanceSpecDiff{PriceChanges: []SKUPriceChange{
+		{SKU: "cheaper", Old: 1, New: 0.5},
+		{SKU: "six", Old: 1, New: 1.0625},
+		{SKU: "twenty", Old: 1, New: 1.2},
+		{SKU: "was-free", Old: 0, New: 0.1},
+	}}
+	for _, tc := range []struct {
+		threshold float64
+		want      []string
+	}{
+		{threshold: 0, want: []string{"six", "twenty", "was-free"}},
+		{threshold: 6.25, want: []string{"twenty", "was-free"}}, // exactly the threshold passes
+		{threshold: 50, want: []string{"was-free"}},
+	} {
+		var got []string
+		for _, c := range d.PriceIncreasesAbove(tc.threshold) {
+			got = append(got, c.SKU)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("threshold %v%%: expected %v, got %v", tc.threshold, tc.want, got)
+		}
+	}
+}
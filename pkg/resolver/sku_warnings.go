@@ -0,0 +1,92 @@
+package resolver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxSuspiciousMemoryGiB is the memory above which a SKU's MemoryGiB more likely holds MB.
+// Only a few M-series SKUs legitimately have more.
+const maxSuspiciousMemoryGiB = 2048
+
+// maxWarnedSKUs caps the SKUs named in one SKU data warning.
+const maxWarnedSKUs = 5
+
+// regionZone matches a zone prefixed with its region, e.g. "eastus-1" or "westeurope_2".
+var regionZone = regexp.MustCompile(`^[a-z][a-z0-9]*[-_ ]([0-9]+)$`)
+
+/*
+checkSKUs flags SKU data that is probably wrong in real SKU dumps and normalizes what it safely
+can, in place: zones listed with their region, e.g. "eastus-1", become "1". It returns one
+warning per heuristic that fired, naming the SKUs:
+
+  - a price of 0, which makes the SKU win every selection it can;
+  - more than 2048 GiB of memory, which is likely in MB, e.g. 16384 for 16 GiB. It is not
+    converted: the largest M-series SKUs have more;
+  - zones that are neither numbers nor region-prefixed numbers, which never match a workload's.
+*/
+func checkSKUs(skus []AzureInstanceSpec) []string {
+	var free, huge, regional, unknown []string
+	for i := range skus {
+		s := &skus[i]
+		if s.PricePerHour == 0 {
+			free = append(free, s.Name)
+		}
+		if s.MemoryGiB > maxSuspiciousMemoryGiB {
+			huge = append(huge, fmt.Sprintf("%s (%g)", s.Name, s.MemoryGiB))
+		}
+		var normalized, bad bool
+		for j, z := range s.AvailabilityZones {
+			if isZoneNumber(z) {
+				continue
+			}
+			if m := regionZone.FindStringSubmatch(strings.ToLower(strings.TrimSpace(z))); m != nil {
+				s.AvailabilityZones[j], normalized = m[1], true
+				continue
+			}
+			bad = true
+		}
+		if normalized {
+			regional = append(regional, s.Name)
+		}
+		if bad {
+			unknown = append(unknown, fmt.Sprintf("%s (%s)", s.Name, strings.Join(s.AvailabilityZones, ", ")))
+		}
+	}
+	var warnings []string
+	if len(free) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d SKUs have a price of 0 and will win every selection they can: %s", len(free), listSKUs(free)))
+	}
+	if len(huge) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d SKUs have more than %d GiB of memory, possibly listed in MB: %s", len(huge), maxSuspiciousMemoryGiB, listSKUs(huge)))
+	}
+	if len(regional) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d SKUs list zones with their region, e.g. \"eastus-1\", normalized to the zone number: %s", len(regional), listSKUs(regional)))
+	}
+	if len(unknown) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d SKUs list zones that are not zone numbers and match no workload zone: %s", len(unknown), listSKUs(unknown)))
+	}
+	return warnings
+}
+
+// isZoneNumber reports whether z is an availability zone number, e.g. "1".
+func isZoneNumber(z string) bool {
+	if z == "" {
+		return false
+	}
+	for _, r := range z {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// listSKUs joins up to maxWarnedSKUs names, saying how many more there are.
+func listSKUs(names []string) string {
+	if len(names) <= maxWarnedSKUs {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxWarnedSKUs], ", "), len(names)-maxWarnedSKUs)
+}
@@ -0,0 +1,68 @@
+package resolver
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadAzureInstanceSpecsWithWarnings(t *testing.T) {
+	skus, warnings, err := LoadAzureInstanceSpecsWithWarnings(filepath.Join("testdata", "skus", "suspicious.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"1 SKUs have a price of 0 and will win every selection they can: Standard_D8s_v5",
+		"1 SKUs have more than 2048 GiB of memory, possibly listed in MB: Standard_E4s_v5 (32768)",
+		`1 SKUs list zones with their region, e.g. "eastus-1", normalized to the zone number: Standard_F4s_v2`,
+		"1 SKUs list zones that are not zone numbers and match no workload zone: Standard_B2s (zone-a)",
+	} {
+		if !strings.Contains(strings.Join(warnings, "\n"), want) {
+			t.Errorf("expected the warning %q, got %q", want, warnings)
+		}
+	}
+	if len(warnings) != 4 {
+		t.Errorf("expected one warning per heuristic, got %d: %q", len(warnings), warnings)
+	}
+	if got := skus[3].AvailabilityZones; !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Errorf("expected the region prefixes stripped from the zones, got %q", got)
+	}
+	if skus[2].MemoryGiB != 32768 || skus[1].PricePerHour != 0 || skus[4].AvailabilityZones[0] != "zone-a" {
+		t.Errorf("expected values that cannot be fixed safely to be kept, got %+v", skus)
+	}
+
+	// The normalized zones match workloads pinned to the zone number.
+	w := WorkloadProfile{Name: "w", CPURequirements: 4, MemoryRequirements: 8, Zone: "2"}
+	if vm, _ := selectWithConfig(skus[3:4], w, Config{}.forRun()); vm.Name != "Standard_F4s_v2" {
+		t.Errorf("expected the normalized zone 2 to be selectable, got %q", vm.Name)
+	}
+
+	if _, warnings, err := LoadAzureInstanceSpecsWithWarnings(filepath.Join("testdata", "golden", "skus.json")); err != nil || len(warnings) != 0 {
+		t.Errorf("expected no warnings for the golden SKUs, got %q, %v", warnings, err)
+	}
+}
+
+func TestCheckSKUs_ListsManySKUs(t *testing.T) {
+	skus := make([]AzureInstanceSpec, 8)
+	for i := range skus {
+		skus[i] = AzureInstanceSpec{Name: fmt.Sprintf("sku%d", i), VCpus: 2, MemoryGiB: 4}
+	}
+	warnings := checkSKUs(skus)
+	if len(warnings) != 1 || !strings.HasSuffix(warnings[0], "sku0, sku1, sku2, sku3, sku4 and 3 more") {
+		t.Errorf("expected one warning naming 5 of the 8 free SKUs, got %q", warnings)
+	}
+}
+
+func TestSimulatorStrictSKUs(t *testing.T) {
+	path := filepath.Join("testdata", "skus", "suspicious.json")
+	var log bytes.Buffer
+	if _, err := NewSimulator(path, Config{}, &log); err != nil || !strings.Contains(log.String(), "Warning: 1 SKUs have a price of 0") {
+		t.Errorf("expected the SKU warnings logged, got %v and:\n%s", err, log.String())
+	}
+	if _, err := NewSimulator(path, Config{StrictSKUs: true}, nil); err == nil || !strings.Contains(err.Error(), "suspicious SKU data") {
+		t.Errorf("expected StrictSKUs to fail on suspicious SKU data, got %v", err)
+	}
+}
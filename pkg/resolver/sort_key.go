@@ -0,0 +1,98 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+SortKey is the order the decreasing packers (BinPackWorkloadsWithConfig and the quota packer)
+take workloads in, largest first (see Config.SortKey). Headroom buffers always go last. The
+online packers keep arrival order whatever the key.
+*/
+type SortKey string
+
+const (
+	// SortKeyLegacy orders by vCPUs plus GiB of memory. It is the default, so earlier packings
+	// reproduce, but adds up different units: 1 vCPU weighs as much as 1 GiB, so memory-heavy
+	// workloads go first whatever the SKUs' ratio of memory to vCPUs.
+	SortKeyLegacy SortKey = ""
+	// SortKeyDominantShare orders by dominant resource share, as dominant resource fairness
+	// does: a workload's largest share of the largest candidate's vCPUs, memory or GPUs, each
+	// resource taken at its largest among the candidates. Ties go to the larger sum of shares.
+	// It weighs resources in comparable units and is to become the default once benchmarks
+	// show it packing as cheaply as SortKeyLegacy; on the golden fixture it provisions more
+	// VMs at a higher memory utilization (see testdata/quality_baselines.json).
+	SortKeyDominantShare SortKey = "drf"
+	// SortKeyCPU orders by vCPUs, then by memory.
+	SortKeyCPU SortKey = "cpu"
+	// SortKeyMemory orders by memory, then by vCPUs.
+	SortKeyMemory SortKey = "memory"
+)
+
+// ParseSortKey parses a CLI sort key: "legacy" (or empty), "drf", "cpu" or "memory".
+func ParseSortKey(s string) (SortKey, error) {
+	switch SortKey(s) {
+	case "", "legacy":
+		return SortKeyLegacy, nil
+	case SortKeyDominantShare, SortKeyCPU, SortKeyMemory:
+		return SortKey(s), nil
+	}
+	return "", fmt.Errorf("unknown sort key %q, expected legacy, drf, cpu or memory", s)
+}
+
+/*
+sort orders workloads by k for packing onto candidates, largest first with headroom buffers
+last, keeping the input order of ties. It must not be called with SortKeyLegacy, which each
+packer sorts by itself to keep its results unchanged.
+*/
+func (k SortKey) sort(workloads WorkloadSet, candidates []AzureInstanceSpec) {
+	keys := make([][2]float64, len(workloads))
+	var maxCPU, maxMem, maxGPU float64
+	for _, c := range candidates {
+		maxCPU, maxMem, maxGPU = max(maxCPU, float64(c.VCpus)), max(maxMem, c.MemoryGiB), max(maxGPU, float64(c.GPUCount))
+	}
+	for i, w := range workloads {
+		cpu, mem := float64(w.CPURequirements), w.MemoryRequirements
+		switch k {
+		case SortKeyCPU:
+			keys[i] = [2]float64{cpu, mem}
+		case SortKeyMemory:
+			keys[i] = [2]float64{mem, cpu}
+		default:
+			shares := []float64{share(cpu, maxCPU), share(mem, maxMem), share(float64(w.gpusRequired()), maxGPU)}
+			for _, s := range shares {
+				keys[i][0] = max(keys[i][0], s)
+				keys[i][1] += s
+			}
+		}
+	}
+	order := make([]int, len(workloads))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		wa, wb := workloads[order[a]], workloads[order[b]]
+		if wa.Headroom != wb.Headroom {
+			return !wa.Headroom
+		}
+		ka, kb := keys[order[a]], keys[order[b]]
+		if ka[0] != kb[0] {
+			return ka[0] > kb[0]
+		}
+		return ka[1] > kb[1]
+	})
+	sorted := make(WorkloadSet, len(workloads))
+	for i, idx := range order {
+		sorted[i] = workloads[idx]
+	}
+	copy(workloads, sorted)
+}
+
+// share returns need as a share of capacity, 0 without capacity.
+func share(need, capacity float64) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	return need / capacity
+}
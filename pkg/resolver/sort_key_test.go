@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortKeyOrders(t *testing.T) {
+	// A single VM holds them all, in the order they are packed.
+	skus := []AzureInstanceSpec{{Name: "Standard_E64s_v5", Family: "ESv5", VCpus: 64, MemoryGiB: 256, PricePerHour: 4.032}}
+	workloads := WorkloadSet{
+		{Name: "buffer", CPURequirements: 8, MemoryRequirements: 8, Headroom: true},
+		{Name: "d", CPURequirements: 4, MemoryRequirements: 4},
+		{Name: "a", CPURequirements: 8, MemoryRequirements: 8},
+		{Name: "c", CPURequirements: 4, MemoryRequirements: 16},
+		{Name: "b", CPURequirements: 2, MemoryRequirements: 48},
+	}
+	for _, tc := range []struct {
+		key  SortKey
+		want []string
+	}{
+		// b 2+48, c 4+16, a 8+8, d 4+4
+		{SortKeyLegacy, []string{"b", "c", "a", "d", "buffer"}},
+		// dominant shares: b 48/256, a 8/64, then c and d at 4/64, c with the larger memory share
+		{SortKeyDominantShare, []string{"b", "a", "c", "d", "buffer"}},
+		{SortKeyCPU, []string{"a", "c", "d", "b", "buffer"}},
+		{SortKeyMemory, []string{"b", "c", "a", "d", "buffer"}},
+	} {
+		for name, quota := range map[string]QuotaMap{"ffd": nil, "quota": {}} {
+			result := BinPackWorkloadsWithConfig(workloads, skus, Config{SortKey: tc.key, Quota: quota})
+			if len(result.VMs) != 1 {
+				t.Fatalf("%s %q: expected one VM, got %d", name, tc.key, len(result.VMs))
+			}
+			var got []string
+			for _, w := range result.VMs[0].Workloads {
+				got = append(got, w.Name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s %q: expected the order %v, got %v", name, tc.key, tc.want, got)
+			}
+		}
+	}
+}
+
+func TestSortKeyDominantShare_GPU(t *testing.T) {
+	skus := []AzureInstanceSpec{
+		{Name: "Standard_D16s_v5", VCpus: 16, MemoryGiB: 64},
+		{Name: "Standard_NC24ads_A100_v4", VCpus: 24, MemoryGiB: 220, GPUCount: 1},
+	}
+	workloads := WorkloadSet{
+		{Name: "cpu", CPURequirements: 12, MemoryRequirements: 8},
+		{Name: "gpu", CPURequirements: 1, MemoryRequirements: 4, GPURequirements: 1},
+	}
+	SortKeyDominantShare.sort(workloads, skus)
+	if workloads[0].Name != "gpu" {
+		t.Errorf("expected the workload needing the only GPU to dominate 12 of 24 vCPUs, got %s first", workloads[0].Name)
+	}
+}
+
+func TestParseSortKey(t *testing.T) {
+	for in, want := range map[string]SortKey{"": SortKeyLegacy, "legacy": SortKeyLegacy, "drf": SortKeyDominantShare, "cpu": SortKeyCPU, "memory": SortKeyMemory} {
+		if got, err := ParseSortKey(in); err != nil || got != want {
+			t.Errorf("ParseSortKey(%q) = %q, %v; expected %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseSortKey("size"); err == nil {
+		t.Error("expected an unknown sort key to be rejected")
+	}
+}
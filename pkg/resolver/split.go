@@ -0,0 +1,71 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+SplitOversized divides every workload requesting more than maxCPU vCPUs or maxMem GiB into the
+fewest equal replicas within both limits, like the replicas of a Deployment: trace rows often
+record aggregate demand that as one workload would force a huge SKU. A limit <= 0 is unlimited.
+
+Replicas share the parent's other requirements, divided the same way, and are named after it
+with a "-0", "-1", ... suffix; Parent records the parent's name. vCPUs are whole, so the first
+replicas get one more when they do not divide evenly, and totals are preserved.
+Workloads with GPUs are never split, since their GPUs cannot be divided, and neither are
+headroom buffers. Without limits workloads itself is returned.
+*/
+func SplitOversized(workloads WorkloadSet, maxCPU int, maxMem float64) WorkloadSet {
+	if maxCPU <= 0 && maxMem <= 0 {
+		return workloads
+	}
+	var out WorkloadSet
+	for _, w := range workloads {
+		n := replicasFor(w, maxCPU, maxMem)
+		if n <= 1 || w.gpusRequired() > 0 || w.Headroom {
+			out = append(out, w)
+			continue
+		}
+		for i := 0; i < n; i++ {
+			r := w
+			r.Parent = w.Name
+			if w.Name != "" {
+				r.Name = fmt.Sprintf("%s-%d", w.Name, i)
+			}
+			r.CPURequirements = splitInt(w.CPURequirements, n, i)
+			f := float64(n)
+			r.MemoryRequirements = w.MemoryRequirements / f
+			r.IORequirements = w.IORequirements / f
+			r.StorageRequirements = w.StorageRequirements / f
+			r.NetworkRequirementsMbps = w.NetworkRequirementsMbps / f
+			r.IOPSRequirements = w.IOPSRequirements / f
+			r.ThroughputMBpsRequirements = w.ThroughputMBpsRequirements / f
+			r.LimitCPU = w.LimitCPU / f
+			r.LimitMemory = w.LimitMemory / f
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// replicasFor returns how many replicas w must be split into to fit both limits.
+func replicasFor(w WorkloadProfile, maxCPU int, maxMem float64) int {
+	n := 1
+	if maxCPU > 0 && w.CPURequirements > maxCPU {
+		n = (w.CPURequirements + maxCPU - 1) / maxCPU
+	}
+	if maxMem > 0 && w.MemoryRequirements > maxMem {
+		n = maxInt(n, int(math.Ceil(w.MemoryRequirements/maxMem)))
+	}
+	return n
+}
+
+// splitInt returns replica i's share of total split into n nearly equal whole parts.
+func splitInt(total, n, i int) int {
+	share := total / n
+	if i < total%n {
+		share++
+	}
+	return share
+}
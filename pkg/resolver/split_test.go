@@ -0,0 +1,85 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSplitOversized(t *testing.T) {
+	workloads := WorkloadSet{
+		{Name: "batch", CPURequirements: 10, MemoryRequirements: 40, IOPSRequirements: 900},
+		{Name: "cache", CPURequirements: 2, MemoryRequirements: 96},
+		{Name: "small", CPURequirements: 2, MemoryRequirements: 8},
+		{Name: "gpu", CPURequirements: 24, MemoryRequirements: 224, GPURequirements: 4},
+	}
+	split := SplitOversized(workloads, 4, 32)
+
+	want := []struct {
+		name   string
+		cpu    int
+		mem    float64
+		parent string
+	}{
+		{"batch-0", 4, 40.0 / 3, "batch"},
+		{"batch-1", 3, 40.0 / 3, "batch"},
+		{"batch-2", 3, 40.0 / 3, "batch"},
+		{"cache-0", 1, 32, "cache"}, // memory needs three replicas, which outvote CPU
+		{"cache-1", 1, 32, "cache"},
+		{"cache-2", 0, 32, "cache"},
+		{"small", 2, 8, ""},
+		{"gpu", 24, 224, ""}, // GPUs cannot be divided
+	}
+	if len(split) != len(want) {
+		t.Fatalf("expected %d workloads, got %+v", len(want), split)
+	}
+	for i, w := range want {
+		got := split[i]
+		if got.Name != w.name || got.CPURequirements != w.cpu || math.Abs(got.MemoryRequirements-w.mem) > 1e-9 || got.Parent != w.parent {
+			t.Errorf("workload %d: expected %s (%d vCPU, %.2f GiB, parent %q), got %+v", i, w.name, w.cpu, w.mem, w.parent, got)
+		}
+	}
+
+	total := func(ws WorkloadSet) (cpu int, mem, iops float64) {
+		for _, w := range ws {
+			cpu += w.CPURequirements
+			mem += w.MemoryRequirements
+			iops += w.IOPSRequirements
+		}
+		return
+	}
+	cpu0, mem0, iops0 := total(workloads)
+	cpu1, mem1, iops1 := total(split)
+	if cpu0 != cpu1 || math.Abs(mem0-mem1) > 1e-9 || math.Abs(iops0-iops1) > 1e-9 {
+		t.Errorf("expected totals %d vCPU, %.1f GiB, %.0f IOPS to be preserved, got %d, %.1f, %.0f", cpu0, mem0, iops0, cpu1, mem1, iops1)
+	}
+	if same := SplitOversized(workloads, 0, 0); len(same) != len(workloads) {
+		t.Errorf("expected no limits to leave workloads whole, got %d", len(same))
+	}
+}
+
+func TestSplitOversizedPacksSmallerSKUs(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192},
+		{Name: "Standard_D8s_v5", VCpus: 8, MemoryGiB: 32, PricePerHour: 0.384},
+		{Name: "Standard_D64s_v5", VCpus: 64, MemoryGiB: 256, PricePerHour: 3.072},
+	}
+	// 36 vCPUs of aggregate demand only fit the largest SKU, which would idle at 44%.
+	workloads := WorkloadSet{{Name: "web", CPURequirements: 36, MemoryRequirements: 72}}
+	split := BinPackWorkloads(SplitOversized(workloads, 4, 16), candidates, StrategyGeneralPurpose)
+	if len(split.Unpacked) != 0 {
+		t.Fatalf("expected every replica to be packed, got %+v", split.Unpacked)
+	}
+	for _, vm := range split.VMs {
+		if vm.InstanceType.VCpus >= 64 {
+			t.Errorf("expected replicas to be packed onto smaller SKUs, got %s", vm.InstanceType.Name)
+		}
+		for _, w := range vm.Workloads {
+			if w.Parent != "web" {
+				t.Errorf("expected replica %s to record its parent, got %q", w.Name, w.Parent)
+			}
+		}
+	}
+	if cost, whole := TotalCost(split.VMs), candidates[2].PricePerHour; cost >= whole {
+		t.Errorf("expected splitting to be cheaper than %.3f/h, got %.3f/h", whole, cost)
+	}
+}
@@ -0,0 +1,178 @@
+package resolver
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// EvictionPolicy is what Azure does with an evicted spot VM.
+type EvictionPolicy string
+
+const (
+	// EvictionPolicyDelete deletes the VM and its disks; its workloads are repacked onto new capacity.
+	EvictionPolicyDelete EvictionPolicy = "Delete"
+	// EvictionPolicyDeallocate stops the VM but keeps its disks, which keep billing. The VM
+	// restarts in place once spot capacity returns, so its workloads are not repacked.
+	EvictionPolicyDeallocate EvictionPolicy = "Deallocate"
+)
+
+// Defaults of SpotSimulationOptions.
+const (
+	DefaultOSDiskGiB            = 128  // AKS default managed OS disk size
+	DefaultDiskCostPerGiBMonth  = 0.15 // roughly Premium SSD list price
+	DefaultSpotRepackDelay      = 5 * time.Minute
+	DefaultSpotRestartDelay     = 2 * time.Minute
+	DefaultSpotSimulationPeriod = 24 * time.Hour
+)
+
+// SpotEviction is one entry of an eviction schedule.
+type SpotEviction struct {
+	VM   int           // index into PackingResult.VMs
+	VMID string        // PackedVM.ID of the evicted VM; takes precedence over VM when set
+	At   time.Duration // offset from the start of the simulated period
+	// CapacityReturnsAfter is how long after the eviction spot capacity for the SKU is available again.
+	CapacityReturnsAfter time.Duration
+}
+
+// SpotSimulationOptions configures SimulateSpotEvictions. Zero values use the defaults above.
+type SpotSimulationOptions struct {
+	Policy EvictionPolicy // empty means EvictionPolicyDelete, Azure's default
+	Period time.Duration  // simulated period
+	// SpotDiscount is the discount of spot over list prices, e.g. 0.8.
+	SpotDiscount float64
+	OSDiskGiB    float64
+	// DiskCostPerGiBMonth is the managed disk price that keeps billing while a VM is deallocated.
+	DiskCostPerGiBMonth float64
+	// RepackDelay is the time to provision replacement capacity and reschedule a deleted VM's workloads.
+	RepackDelay time.Duration
+	// RestartDelay is the time to restart a deallocated VM once capacity returns.
+	RestartDelay time.Duration
+}
+
+func (o SpotSimulationOptions) withDefaults() SpotSimulationOptions {
+	if o.Policy == "" {
+		o.Policy = EvictionPolicyDelete
+	}
+	if o.Period <= 0 {
+		o.Period = DefaultSpotSimulationPeriod
+	}
+	if o.OSDiskGiB <= 0 {
+		o.OSDiskGiB = DefaultOSDiskGiB
+	}
+	if o.DiskCostPerGiBMonth <= 0 {
+		o.DiskCostPerGiBMonth = DefaultDiskCostPerGiBMonth
+	}
+	if o.RepackDelay <= 0 {
+		o.RepackDelay = DefaultSpotRepackDelay
+	}
+	if o.RestartDelay <= 0 {
+		o.RestartDelay = DefaultSpotRestartDelay
+	}
+	return o
+}
+
+// SpotSimulationResult is the cost and disruption of a spot eviction schedule under one policy.
+type SpotSimulationResult struct {
+	Policy    EvictionPolicy
+	Evictions int
+	// ComputeCost is the spot compute cost over the period; VMs do not bill compute while down.
+	ComputeCost float64
+	// ResidualStorageCost is what the disks of deallocated VMs billed while they were down.
+	ResidualStorageCost float64
+	TotalCost           float64
+	// Downtime sums, over all evictions, how long the evicted VM's workloads were not running.
+	Downtime         time.Duration
+	MeanRecoveryTime time.Duration
+	MaxRecoveryTime  time.Duration
+	// RepackedWorkloads counts the workloads moved onto replacement capacity.
+	RepackedWorkloads int
+	// Evicted lists the IDs of the VMs of the counted evictions, in time order.
+	Evicted []string `json:",omitempty"`
+	// Events logs the counted evictions in time order.
+	Events EventLog `json:",omitempty"`
+}
+
+/*
+SimulateSpotEvictions replays an eviction schedule against a packing whose VMs all run as spot
+capacity and reports the resulting cost and disruption.
+
+With EvictionPolicyDelete, an evicted VM's workloads are repacked onto replacement capacity,
+which takes RepackDelay regardless of when the SKU's capacity returns. With
+EvictionPolicyDeallocate, the VM restarts in place: its workloads wait for the capacity to
+return plus RestartDelay, and its OS disk keeps billing meanwhile. Down intervals are clipped to
+the simulated period. The schedule is replayed in time order, whatever its order, and
+evictions of a VM that is still down are ignored.
+*/
+func SimulateSpotEvictions(result PackingResult, schedule []SpotEviction, opts SpotSimulationOptions) (SpotSimulationResult, error) {
+	opts = opts.withDefaults()
+	if opts.Policy != EvictionPolicyDelete && opts.Policy != EvictionPolicyDeallocate {
+		return SpotSimulationResult{}, fmt.Errorf("unknown eviction policy %q", opts.Policy)
+	}
+	sim := SpotSimulationResult{Policy: opts.Policy}
+	downUntil := make([]time.Duration, len(result.VMs))
+	down := make([]time.Duration, len(result.VMs))
+	// Resolve VM IDs, then replay in time order: whether an eviction finds its VM still down
+	// must not depend on the order of the schedule.
+	evictions := make([]SpotEviction, len(schedule))
+	for n, e := range schedule {
+		if e.VMID != "" {
+			i := slices.IndexFunc(result.VMs, func(vm PackedVM) bool { return vm.ID == e.VMID })
+			if i < 0 {
+				return SpotSimulationResult{}, fmt.Errorf("eviction of VM %s, which is not in the packing", e.VMID)
+			}
+			e.VM = i
+		}
+		if e.VM < 0 || e.VM >= len(result.VMs) {
+			return SpotSimulationResult{}, fmt.Errorf("eviction of VM %d, but the packing has %d VMs", e.VM, len(result.VMs))
+		}
+		evictions[n] = e
+	}
+	slices.SortStableFunc(evictions, func(a, b SpotEviction) int {
+		if a.At != b.At {
+			return cmp.Compare(a.At, b.At)
+		}
+		return cmp.Compare(a.VM, b.VM)
+	})
+	var recovery time.Duration
+	for _, e := range evictions {
+		if e.At < 0 || e.At >= opts.Period || e.At < downUntil[e.VM] {
+			continue
+		}
+		sim.Evicted = append(sim.Evicted, result.VMs[e.VM].ID)
+		sim.Events.add(e.At.Seconds(), EventEvict, result.VMs[e.VM].ID, "", string(opts.Policy))
+		outage := opts.RepackDelay
+		if opts.Policy == EvictionPolicyDeallocate {
+			outage = e.CapacityReturnsAfter + opts.RestartDelay
+		} else {
+			sim.RepackedWorkloads += len(result.VMs[e.VM].Workloads)
+		}
+		end := e.At + outage
+		if end > opts.Period {
+			end = opts.Period
+		}
+		downUntil[e.VM] = end
+		down[e.VM] += end - e.At
+		sim.Evictions++
+		sim.Downtime += end - e.At
+		recovery += outage
+		if outage > sim.MaxRecoveryTime {
+			sim.MaxRecoveryTime = outage
+		}
+	}
+	sim.Events.sort()
+	if sim.Evictions > 0 {
+		sim.MeanRecoveryTime = recovery / time.Duration(sim.Evictions)
+	}
+	diskCostPerHour := opts.OSDiskGiB * opts.DiskCostPerGiBMonth / DefaultHoursPerMonth
+	for i, vm := range result.VMs {
+		spotPrice := vm.InstanceType.PricePerHour * (1 - clamp01(opts.SpotDiscount))
+		sim.ComputeCost += spotPrice * (opts.Period - down[i]).Hours()
+		if opts.Policy == EvictionPolicyDeallocate {
+			sim.ResidualStorageCost += diskCostPerHour * down[i].Hours()
+		}
+	}
+	sim.TotalCost = sim.ComputeCost + sim.ResidualStorageCost
+	return sim, nil
+}
@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSimulateSpotEvictionPolicies(t *testing.T) {
+	packing := PackingResult{VMs: []PackedVM{
+		{InstanceType: AzureInstanceSpec{Name: "Standard_D4s_v5", PricePerHour: 0.2}, Workloads: make([]WorkloadProfile, 3)},
+		{InstanceType: AzureInstanceSpec{Name: "Standard_D8s_v5", PricePerHour: 0.4}, Workloads: make([]WorkloadProfile, 5)},
+	}}
+	schedule := []SpotEviction{
+		{VM: 0, At: 2 * time.Hour, CapacityReturnsAfter: time.Hour},
+		{VM: 1, At: 10 * time.Hour, CapacityReturnsAfter: 30 * time.Minute},
+		{VM: 1, At: 10*time.Hour + time.Minute}, // VM 1 is still down; ignored
+	}
+	opts := SpotSimulationOptions{Period: 24 * time.Hour, RepackDelay: 5 * time.Minute, RestartDelay: 2 * time.Minute}
+
+	opts.Policy = EvictionPolicyDelete
+	del, err := SimulateSpotEvictions(packing, schedule, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts.Policy = EvictionPolicyDeallocate
+	dealloc, err := SimulateSpotEvictions(packing, schedule, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if del.Evictions != 2 || dealloc.Evictions != 2 {
+		t.Fatalf("expected 2 evictions under both policies, got %d and %d", del.Evictions, dealloc.Evictions)
+	}
+	// Delete repacks every evicted workload within RepackDelay and leaves no disks behind.
+	if del.RepackedWorkloads != 8 || del.MaxRecoveryTime != 5*time.Minute || del.ResidualStorageCost != 0 {
+		t.Errorf("Delete: expected 8 repacked workloads, 5m recovery, no residual storage, got %+v", del)
+	}
+	// Deallocate waits for capacity (1h and 30m) plus the restart, but repacks nothing.
+	if dealloc.RepackedWorkloads != 0 || dealloc.MaxRecoveryTime != 62*time.Minute || dealloc.MeanRecoveryTime != 47*time.Minute {
+		t.Errorf("Deallocate: expected no repacking, 62m max and 47m mean recovery, got %+v", dealloc)
+	}
+	if dealloc.Downtime <= del.Downtime {
+		t.Errorf("expected Deallocate downtime %v to exceed Delete downtime %v", dealloc.Downtime, del.Downtime)
+	}
+	// Deallocated VMs bill no compute while down, but their 128 GiB OS disks keep billing.
+	wantResidual := DefaultOSDiskGiB * DefaultDiskCostPerGiBMonth / DefaultHoursPerMonth * (94 * time.Minute).Hours()
+	if diff := dealloc.ResidualStorageCost - wantResidual; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected residual storage cost %.6f, got %.6f", wantResidual, dealloc.ResidualStorageCost)
+	}
+	if dealloc.ComputeCost >= del.ComputeCost {
+		t.Errorf("expected less compute cost when deallocated longer, got %.4f vs %.4f", dealloc.ComputeCost, del.ComputeCost)
+	}
+	if dealloc.TotalCost != dealloc.ComputeCost+dealloc.ResidualStorageCost {
+		t.Errorf("expected total cost to be compute plus residual storage, got %+v", dealloc)
+	}
+}
+
+func TestSimulateSpotEvictionsErrors(t *testing.T) {
+	packing := PackingResult{VMs: []PackedVM{{InstanceType: AzureInstanceSpec{PricePerHour: 0.1}}}}
+	if _, err := SimulateSpotEvictions(packing, []SpotEviction{{VM: 1}}, SpotSimulationOptions{}); err == nil {
+		t.Error("expected an error for an eviction of a VM outside the packing")
+	}
+	if _, err := SimulateSpotEvictions(packing, nil, SpotSimulationOptions{Policy: "Hibernate"}); err == nil {
+		t.Error("expected an error for an unknown eviction policy")
+	}
+}
+
+func TestSimulateSpotEvictionsByID(t *testing.T) {
+	packing := BinPackWorkloadsWithConfig(WorkloadSet{
+		{CPURequirements: 4, MemoryRequirements: 8},
+		{CPURequirements: 4, MemoryRequirements: 8},
+	}, []AzureInstanceSpec{{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}, Config{})
+	schedule := []SpotEviction{{VMID: "vm-0002", At: time.Hour}, {VMID: "vm-0002", At: time.Hour + time.Minute}}
+	sim, err := SimulateSpotEvictions(packing, schedule, SpotSimulationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.Evictions != 1 || len(sim.Evicted) != 1 || sim.Evicted[0] != "vm-0002" {
+		t.Errorf("expected one eviction of vm-0002, got %d of %v", sim.Evictions, sim.Evicted)
+	}
+	if _, err := SimulateSpotEvictions(packing, []SpotEviction{{VMID: "vm-0003"}}, SpotSimulationOptions{}); err == nil {
+		t.Error("expected an error for an eviction of an unknown VM ID")
+	}
+}
+
+func TestSimulateSpotEvictionsUnsortedSchedule(t *testing.T) {
+	packing := BinPackWorkloadsWithConfig(WorkloadSet{{CPURequirements: 4, MemoryRequirements: 8}},
+		[]AzureInstanceSpec{{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}, Config{})
+	sorted := []SpotEviction{{VM: 0, At: 10 * time.Minute}, {VM: 0, At: 100 * time.Minute}}
+	unsorted := []SpotEviction{sorted[1], sorted[0]}
+	opts := SpotSimulationOptions{RepackDelay: 2 * time.Hour}
+	want, err := SimulateSpotEvictions(packing, sorted, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := SimulateSpotEvictions(packing, unsorted, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The eviction at 10 minutes keeps the VM down past 100 minutes, whatever the order.
+	if got.Evictions != 1 || got.Downtime != 2*time.Hour || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the eviction at 10 minutes alone, as for the sorted schedule %+v, got %+v", want, got)
+	}
+}
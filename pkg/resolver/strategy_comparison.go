@@ -0,0 +1,115 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// StrategyRun is the outcome of one Config of RunStrategyComparison.
+type StrategyRun struct {
+	Strategy SelectionStrategy
+	// Result and Naive are what simulate returns for the Config.
+	Result, Naive SimulationResult
+	// Elapsed is the wall time of the whole simulation, including the naive packing and any
+	// comparisons the Config asks for.
+	Elapsed time.Duration
+}
+
+/*
+RunStrategyComparison simulates workloads on skus once per Config, typically one per strategy,
+on up to workers goroutines (runtime.GOMAXPROCS when workers is 0 or less). Workloads and skus
+are shared read-only between the runs, and each run packs with its own Config, so the results
+are those of running the Configs one after another and are returned in their order.
+
+Progress goes to progress, or stdout when nil. With several Configs every line is prefixed
+with the Config's strategy, e.g. "[cpu] ", and written whole, so concurrent runs interleave by
+line. ctx is checked before each run starts; the first error in Config order is returned.
+*/
+func RunStrategyComparison(ctx context.Context, workloads WorkloadSet, skus []AzureInstanceSpec, configs []Config, workers int, progress io.Writer) ([]StrategyRun, error) {
+	if progress == nil {
+		progress = os.Stdout
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = minInt(workers, len(configs))
+	runs := make([]StrategyRun, len(configs))
+	errs := make([]error, len(configs))
+	jobs := make(chan int)
+	var mu sync.Mutex // serializes progress lines
+	var wg sync.WaitGroup
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if errs[i] = ctx.Err(); errs[i] != nil {
+					continue
+				}
+				cfg := configs[i]
+				lines := &lineWriter{mu: &mu, w: progress}
+				if len(configs) > 1 {
+					lines.prefix = fmt.Sprintf("[%s] ", cfg.strategy())
+				}
+				cfg.progress = lines
+				start := time.Now()
+				result, naive, err := simulate(workloads, skus, cfg)
+				lines.flush()
+				runs[i] = StrategyRun{Strategy: cfg.strategy(), Result: result, Naive: naive, Elapsed: time.Since(start)}
+				errs[i] = err
+			}
+		}()
+	}
+	for i := range configs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+// lineWriter writes complete lines to w, each prefixed with prefix, holding mu while writing
+// so lines of concurrent writers do not mix. Partial lines are buffered until flush.
+type lineWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		l.writeLine(l.buf[:i+1])
+		l.buf = l.buf[i+1:]
+	}
+}
+
+// flush writes a pending partial line, terminated.
+func (l *lineWriter) flush() {
+	if len(l.buf) > 0 {
+		l.writeLine(append(l.buf, '\n'))
+		l.buf = nil
+	}
+}
+
+func (l *lineWriter) writeLine(line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "%s%s", l.prefix, line)
+}
@@ -0,0 +1,108 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var comparedStrategies = []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive}
+
+func strategyConfigs() []Config {
+	configs := make([]Config, len(comparedStrategies))
+	for i, s := range comparedStrategies {
+		configs[i] = Config{Strategy: s, Sensitivity: []float64{5}}
+	}
+	return configs
+}
+
+// TestRunStrategyComparison_MatchesSerial shares one workload and candidate set between
+// concurrent runs; run it with -race to check they only read it.
+func TestRunStrategyComparison_MatchesSerial(t *testing.T) {
+	workloads, err := loadCustomWorkloads("testdata/golden/workloads.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := LoadSKUDatasets("testdata/golden/skus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := append(WorkloadSet(nil), workloads...)
+	var progress bytes.Buffer
+	runs, err := RunStrategyComparison(context.Background(), workloads, ds.SKUs, strategyConfigs(), 4, &progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != len(comparedStrategies) {
+		t.Fatalf("expected %d runs, got %d", len(comparedStrategies), len(runs))
+	}
+	for i, cfg := range strategyConfigs() {
+		cfg.progress = io.Discard
+		result, naive, err := simulate(workloads, ds.SKUs, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := runs[i]
+		if got.Strategy != cfg.Strategy || got.Elapsed <= 0 {
+			t.Errorf("expected run %d timed for %s, got %s in %v", i, cfg.Strategy, got.Strategy, got.Elapsed)
+		}
+		for _, r := range []*SimulationResult{&result, &naive, &got.Result, &got.Naive} {
+			r.Timing = TimingReport{}
+		}
+		if !reflect.DeepEqual(got.Result, result) || !reflect.DeepEqual(got.Naive, naive) {
+			t.Errorf("%s: expected the concurrent result to match a serial one", cfg.Strategy)
+		}
+	}
+	if !reflect.DeepEqual(workloads, before) {
+		t.Error("expected the shared workloads unchanged")
+	}
+
+	// Lines of concurrent runs interleave whole, each tagged with its strategy
+	counts := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSuffix(progress.String(), "\n"), "\n") {
+		tag, _, ok := strings.Cut(line, " ")
+		if !ok || !strings.HasPrefix(tag, "[") {
+			t.Fatalf("expected every progress line tagged with a strategy, got %q", line)
+		}
+		counts[tag]++
+	}
+	for _, s := range comparedStrategies {
+		if tag := fmt.Sprintf("[%s]", s); counts[tag] != counts["[general]"] || counts[tag] == 0 {
+			t.Errorf("expected the same progress lines for every strategy, got %v", counts)
+		}
+	}
+}
+
+func TestRunStrategyComparison_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunStrategyComparison(ctx, WorkloadSet{{CPURequirements: 1, MemoryRequirements: 1}}, nil, strategyConfigs(), 2, io.Discard); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// BenchmarkRunStrategyComparison compares 1 to 4 workers on the 4 strategies; the speedup
+// should be near-linear up to the number of strategies on a machine with as many cores.
+func BenchmarkRunStrategyComparison(b *testing.B) {
+	workloads, err := loadCustomWorkloads("testdata/golden/workloads.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	ds, err := LoadSKUDatasets("testdata/golden/skus.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, workers := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := RunStrategyComparison(context.Background(), workloads, ds.SKUs, strategyConfigs(), workers, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
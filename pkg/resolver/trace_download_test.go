@@ -0,0 +1,156 @@
+package resolver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serveTrace points the google trace at a test server serving body and counts its downloads.
+func serveTrace(t *testing.T, body []byte) *atomic.Int32 {
+	t.Helper()
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		time.Sleep(50 * time.Millisecond) // keep the other callers waiting
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	orig := traceFiles[TraceGoogle]
+	traceFiles[TraceGoogle] = struct{ url, filename string }{srv.URL + "/trace.csv.gz", orig.filename}
+	t.Cleanup(func() { traceFiles[TraceGoogle] = orig })
+	return &downloads
+}
+
+// downloadConcurrently calls DownloadTrace from n goroutines and returns the paths they got.
+func downloadConcurrently(t *testing.T, dir string, n int) []string {
+	t.Helper()
+	paths := make([]string, n)
+	var wg sync.WaitGroup
+	for i := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, err := DownloadTrace(TraceGoogle, dir)
+			if err != nil {
+				t.Error(err)
+			}
+			paths[i] = path
+		}()
+	}
+	wg.Wait()
+	return paths
+}
+
+func TestDownloadTraceConcurrent(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("time,instance_events_type\n"))
+	zw.Close()
+	downloads := serveTrace(t, gz.Bytes())
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	paths := downloadConcurrently(t, dir, 8)
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("expected exactly one download, got %d", n)
+	}
+	for _, path := range paths {
+		if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, gz.Bytes()) {
+			t.Errorf("expected every caller to get the complete gzip file, got %s: %v", path, err)
+		}
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) != 0 {
+		t.Errorf("expected no temporary files left behind, got %v", tmp)
+	}
+}
+
+func TestDownloadTraceConcurrentNotGzipped(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "traces", "google.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloads := serveTrace(t, body)
+	dir := t.TempDir()
+
+	paths := downloadConcurrently(t, dir, 8)
+	gzPath := filepath.Join(dir, traceFiles[TraceGoogle].filename)
+	for _, path := range paths {
+		if path != gzPath {
+			t.Errorf("expected the uncompressed download to keep its cache name %s, got %s", gzPath, path)
+		}
+	}
+	if got, err := os.ReadFile(gzPath); err != nil || !bytes.Equal(got, body) || downloads.Load() != 1 {
+		t.Errorf("expected one download cached intact, got %d downloads and %q: %v", downloads.Load(), got, err)
+	}
+	// The loader sniffs the content instead of trusting the extension.
+	if workloads, err := LoadWorkloadsFromTrace(gzPath, TraceGoogle, 100); err != nil || len(workloads) == 0 {
+		t.Errorf("expected the misnamed trace to load, got %d workloads, %v", len(workloads), err)
+	}
+}
+
+func TestTraceURLPrecedence(t *testing.T) {
+	serve := func(body string) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL + "/trace.csv"
+	}
+	flagURL, envURL := serve("from flag\n"), serve("from env\n")
+	download := func(opts TraceOptions) string {
+		t.Helper()
+		path, err := DownloadTraceWithOptions(TraceAzure, t.TempDir(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if got, err := (TraceOptions{}).URL(TraceAzure); err != nil || got != traceFiles[TraceAzure].url {
+		t.Errorf("expected the default URL without overrides, got %q: %v", got, err)
+	}
+	t.Setenv(TraceURLEnv(TraceAzure), envURL)
+	if got := download(TraceOptions{}); got != "from env\n" {
+		t.Errorf("expected %s to override the default, got %q", TraceURLEnv(TraceAzure), got)
+	}
+	if got := download(TraceOptions{URLs: map[TraceSource]string{TraceAzure: flagURL}}); got != "from flag\n" {
+		t.Errorf("expected the option to override %s, got %q", TraceURLEnv(TraceAzure), got)
+	}
+	if got, err := (TraceOptions{}).URL(TraceGoogle); err != nil || got != traceFiles[TraceGoogle].url {
+		t.Errorf("expected other sources to keep their default URL, got %q: %v", got, err)
+	}
+}
+
+func TestTraceURLInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts TraceOptions
+		env  string
+	}{
+		{"option scheme", TraceOptions{URLs: map[TraceSource]string{TraceAlibaba: "ftp://mirror/trace.csv"}}, ""},
+		{"option relative", TraceOptions{URLs: map[TraceSource]string{TraceAlibaba: "mirror/trace.csv"}}, ""},
+		{"env unparsable", TraceOptions{}, "http://mirror:port/trace.csv"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(TraceURLEnv(TraceAlibaba), tc.env)
+			dir := filepath.Join(t.TempDir(), "cache")
+			if _, err := DownloadTraceWithOptions(TraceAlibaba, dir, tc.opts); err == nil {
+				t.Fatal("expected an invalid URL to be rejected")
+			}
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("expected nothing to be created before the URL is validated, got %v", err)
+			}
+		})
+	}
+}
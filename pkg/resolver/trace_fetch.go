@@ -0,0 +1,113 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TraceSASTokenEnv is the environment variable holding the default TraceOptions.SASToken.
+const TraceSASTokenEnv = "TRACE_SAS_TOKEN"
+
+// ErrTraceAuth is wrapped by download errors caused by missing or rejected credentials, as
+// opposed to network errors.
+var ErrTraceAuth = errors.New("trace download not authorized")
+
+// storageScope is the token scope of Azure Storage.
+const storageScope = "https://storage.azure.com/.default"
+
+/*
+fetchTrace GETs traceURL for a successful response. Azure blob URLs get the SAS token appended
+and, with a Credential, a bearer token. Rejected requests return ErrTraceAuth with a hint on
+what to check; tokens never appear in errors.
+*/
+func (o TraceOptions) fetchTrace(traceURL string) (*http.Response, error) {
+	u, err := url.Parse(traceURL)
+	if err != nil {
+		return nil, err
+	}
+	blob := isBlobHost(u.Host)
+	sas := o.SASToken
+	if sas == "" {
+		sas = os.Getenv(TraceSASTokenEnv)
+	}
+	target := traceURL
+	if blob && sas != "" {
+		target = appendSAS(u, sas)
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if blob && o.Credential != nil {
+		tok, err := o.Credential.Token(context.Background(), storageScope)
+		if err != nil {
+			return nil, fmt.Errorf("%w: get a storage token for %s: %v", ErrTraceAuth, traceURL, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+		req.Header.Set("x-ms-version", "2021-08-06") // bearer tokens need a recent API version
+	}
+	resp, err := o.client().Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err // its URL may carry the SAS token
+		}
+		return nil, fmt.Errorf("network error fetching %s: %w", traceURL, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: GET %s: %s; %s", ErrTraceAuth, traceURL, resp.Status, authHint(blob, sas != "", o.Credential != nil))
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", traceURL, resp.Status)
+	}
+}
+
+// client returns Client, or a client honouring the proxy environment variables.
+func (o TraceOptions) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	return &http.Client{Transport: t}
+}
+
+// isBlobHost reports whether host is an Azure Blob Storage endpoint.
+func isBlobHost(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), ".blob.core.windows.net")
+}
+
+// appendSAS returns u with the SAS token, with or without its leading "?", added to its query.
+func appendSAS(u *url.URL, sas string) string {
+	v := *u
+	sas = strings.TrimPrefix(sas, "?")
+	if v.RawQuery == "" {
+		v.RawQuery = sas
+	} else {
+		v.RawQuery += "&" + sas
+	}
+	return v.String()
+}
+
+// authHint says what to check when a download was rejected.
+func authHint(blob, sas, credential bool) string {
+	switch {
+	case !blob:
+		return "the server requires credentials; use a URL that does not (see --trace-url)"
+	case credential:
+		return "check that the signed-in identity has the Storage Blob Data Reader role on the container"
+	case sas:
+		return "check that the SAS token grants read permission and has not expired"
+	default:
+		return "the container is private; pass a SAS token with --trace-sas or " + TraceSASTokenEnv + ", or use --trace-azure-auth"
+	}
+}
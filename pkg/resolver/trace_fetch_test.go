@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// recordingTransport answers every request with status and records it.
+type recordingTransport struct {
+	status int
+	reqs   []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.reqs = append(t.reqs, req)
+	return &http.Response{StatusCode: t.status, Status: http.StatusText(t.status), Body: io.NopCloser(strings.NewReader("ok")), Request: req}, nil
+}
+
+type staticCredential string
+
+func (c staticCredential) Token(context.Context, string) (string, error) {
+	return string(c), nil
+}
+
+func TestAppendSAS(t *testing.T) {
+	for _, tc := range []struct {
+		url, sas, want string
+	}{
+		{"https://acct.blob.core.windows.net/traces/azure.csv", "sv=2022-11-02&sig=abc", "https://acct.blob.core.windows.net/traces/azure.csv?sv=2022-11-02&sig=abc"},
+		{"https://acct.blob.core.windows.net/traces/azure.csv", "?sv=2022-11-02&sig=abc", "https://acct.blob.core.windows.net/traces/azure.csv?sv=2022-11-02&sig=abc"},
+		{"https://acct.blob.core.windows.net/traces/azure.csv?snapshot=1", "sig=abc", "https://acct.blob.core.windows.net/traces/azure.csv?snapshot=1&sig=abc"},
+	} {
+		u, err := url.Parse(tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := appendSAS(u, tc.sas); got != tc.want {
+			t.Errorf("appendSAS(%s, %s) = %s, want %s", tc.url, tc.sas, got, tc.want)
+		}
+	}
+}
+
+func TestFetchTraceSAS(t *testing.T) {
+	const blobURL = "https://acct.blob.core.windows.net/traces/azure.csv"
+	rt := &recordingTransport{status: http.StatusOK}
+	opts := TraceOptions{Client: &http.Client{Transport: rt}, SASToken: "sig=secret"}
+	if _, err := opts.fetchTrace(blobURL); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(TraceSASTokenEnv, "sig=fromenv")
+	opts.SASToken = ""
+	if _, err := opts.fetchTrace(blobURL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.fetchTrace("https://storage.googleapis.com/trace.csv.gz"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{blobURL + "?sig=secret", blobURL + "?sig=fromenv", "https://storage.googleapis.com/trace.csv.gz"}
+	for i, req := range rt.reqs {
+		if got := req.URL.String(); got != want[i] {
+			t.Errorf("request %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+}
+
+func TestFetchTraceBearerToken(t *testing.T) {
+	rt := &recordingTransport{status: http.StatusOK}
+	opts := TraceOptions{Client: &http.Client{Transport: rt}, Credential: staticCredential("tok")}
+	if _, err := opts.fetchTrace("https://acct.blob.core.windows.net/traces/azure.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rt.reqs[0].Header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("expected a bearer token for a blob URL, got %q", got)
+	}
+	if _, err := opts.fetchTrace("https://example.com/trace.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rt.reqs[1].Header.Get("Authorization"); got != "" {
+		t.Errorf("expected no token sent outside Azure Storage, got %q", got)
+	}
+}
+
+func TestFetchTraceForbidden(t *testing.T) {
+	rt := &recordingTransport{status: http.StatusForbidden}
+	opts := TraceOptions{Client: &http.Client{Transport: rt}, SASToken: "sig=secret"}
+	_, err := opts.fetchTrace("https://acct.blob.core.windows.net/traces/azure.csv")
+	if !errors.Is(err, ErrTraceAuth) {
+		t.Fatalf("expected ErrTraceAuth for a 403, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "SAS token grants read permission") || strings.Contains(msg, "secret") {
+		t.Errorf("expected an actionable message without the token, got %q", msg)
+	}
+
+	opts.SASToken = ""
+	t.Setenv(TraceSASTokenEnv, "")
+	_, err = opts.fetchTrace("https://acct.blob.core.windows.net/traces/azure.csv")
+	if err == nil || !strings.Contains(err.Error(), "--trace-sas") {
+		t.Errorf("expected a hint to pass a SAS token, got %v", err)
+	}
+}
+
+func TestFetchTraceNetworkError(t *testing.T) {
+	opts := TraceOptions{Client: &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})}, SASToken: "sig=secret"}
+	_, err := opts.fetchTrace("https://acct.blob.core.windows.net/traces/azure.csv")
+	if err == nil || errors.Is(err, ErrTraceAuth) || !strings.Contains(err.Error(), "network error") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected a network error without the token, got %v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
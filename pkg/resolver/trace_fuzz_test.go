@@ -0,0 +1,86 @@
+package resolver
+
+import (
+	"bytes"
+	"testing"
+)
+
+func fuzzTrace(f *testing.F, source TraceSource, seeds ...string) {
+	for _, seed := range seeds {
+		f.Add([]byte(seed), 10)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, maxRows int) {
+		workloads, err := readWorkloadsFromTrace(bytes.NewReader(data), source, maxRows)
+		if err != nil {
+			return
+		}
+		for _, w := range workloads {
+			if w.CPURequirements < 0 || !(w.MemoryRequirements >= 0) {
+				t.Fatalf("invalid workload parsed: %+v", w)
+			}
+		}
+	})
+}
+
+func FuzzLoadWorkloadsFromTrace_Google(f *testing.F) {
+	fuzzTrace(f, TraceGoogle,
+		"time,requested_cpu,requested_memory\n0,2000,4096\n1,500,1024\n",
+		"cpu_request,memory_request\n1000,2048\n",
+	)
+}
+
+func FuzzLoadWorkloadsFromTrace_Azure(f *testing.F) {
+	fuzzTrace(f, TraceAzure, "vmId,vCPUs,memoryGB\na,2,8\nb,4,16\n")
+}
+
+func FuzzLoadWorkloadsFromTrace_Alibaba(f *testing.F) {
+	fuzzTrace(f, TraceAlibaba, "id,cpu,mem\n1,2,4\n2,8,32\n")
+}
+
+func FuzzLoadAzureInstanceSpecs(f *testing.F) {
+	f.Add([]byte(`[{"Name":"Standard_D2_v3","VCpus":2,"MemoryGiB":8,"PricePerHour":0.1,"Family":"D"}]`))
+	f.Add([]byte(`[]`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		specs, err := parseAzureInstanceSpecs(data)
+		if err != nil {
+			return
+		}
+		for _, s := range specs {
+			if s.VCpus < 0 || s.MemoryGiB < 0 || s.PricePerHour < 0 {
+				t.Fatalf("invalid spec parsed: %+v", s)
+			}
+		}
+	})
+}
+
+func TestReadWorkloadsFromTrace_Malformed(t *testing.T) {
+	in := "id,cpu,mem\n1,2\n2,NaN,4\n3,-1,Inf\n4,2,8\n5,x,8\n"
+	workloads, err := readWorkloadsFromTrace(bytes.NewReader([]byte(in)), TraceAlibaba, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The short row is skipped; NaN, negative, Inf and unparsable values read as 0.
+	// Names follow the data row, so they stay stable when earlier rows are skipped.
+	want := []WorkloadProfile{
+		{Name: "alibaba-1", CPURequirements: 0, MemoryRequirements: 4},
+		{Name: "alibaba-3", CPURequirements: 2, MemoryRequirements: 8},
+		{Name: "alibaba-4", CPURequirements: 0, MemoryRequirements: 8},
+	}
+	if len(workloads) != len(want) {
+		t.Fatalf("expected %d workloads, got %+v", len(want), workloads)
+	}
+	for i := range want {
+		if workloads[i].Name != want[i].Name || workloads[i].CPURequirements != want[i].CPURequirements || workloads[i].MemoryRequirements != want[i].MemoryRequirements {
+			t.Errorf("workload %d: expected %+v, got %+v", i, want[i], workloads[i])
+		}
+	}
+	if _, err := readWorkloadsFromTrace(bytes.NewReader([]byte(in)), TraceAlibaba, -1); err == nil {
+		t.Errorf("expected an error for a negative maxRows")
+	}
+}
+
+func TestParseAzureInstanceSpecs_Invalid(t *testing.T) {
+	if _, err := parseAzureInstanceSpecs([]byte(`[{"Name":"x","VCpus":-2}]`)); err == nil {
+		t.Errorf("expected an error for negative vCPUs")
+	}
+}
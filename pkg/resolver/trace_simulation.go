@@ -1,254 +1,264 @@
 package resolver
 
 import (
-	"compress/gzip"
-	"encoding/csv"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // TraceSource represents a public trace dataset.
 type TraceSource string
 
 const (
-	TraceGoogle   TraceSource = "google"
-	TraceAzure    TraceSource = "azure"
-	TraceAlibaba  TraceSource = "alibaba"
+	TraceGoogle  TraceSource = "google"
+	TraceAzure   TraceSource = "azure"
+	TraceAlibaba TraceSource = "alibaba"
+	// TraceCustom is a custom workloads JSON file, or as a trace CSV for ConvertTrace, columns
+	// named after WorkloadProfile fields, e.g. "cpu,memory,gpus,gpu_type,priority,duration".
+	TraceCustom TraceSource = "custom"
 )
 
+// traceFiles are the download URL and cache file name of each trace source.
+var traceFiles = map[TraceSource]struct{ url, filename string }{
+	TraceGoogle:  {"https://storage.googleapis.com/clusterdata-2019-2/clusterdata-2019-2-task-events.csv.gz", "google_clusterdata_2019.csv.gz"},
+	TraceAzure:   {"https://azureopendatastorage.blob.core.windows.net/azurepublicdataset/azure_vm_workload.csv", "azure_vm_workload.csv"},
+	TraceAlibaba: {"https://github.com/alibaba/clusterdata/raw/master/cluster-trace-micro-2018.csv", "alibaba_cluster_trace_2018.csv"},
+}
+
 /*
-DownloadTrace downloads and caches a trace file from a public dataset.
-If the file is a .gz, but the download is not actually gzipped (e.g. due to proxy or error), it will
-detect and fix the file extension to avoid gzip: invalid header errors.
+TraceOptions overrides the download URLs of the trace sources, e.g. with internal mirrors. A
+source's URL is taken from URLs, then from the TRACE_URL_<SOURCE> environment variable (e.g.
+TRACE_URL_GOOGLE), then from the built-in default.
+
+Downloads from Azure Blob Storage can authenticate with a SAS token or a Credential. Rejected
+downloads return ErrTraceAuth, telling them apart from network errors.
 */
-func DownloadTrace(source TraceSource, destDir string) (string, error) {
-	var url, filename string
-	switch source {
-	case TraceGoogle:
-		url = "https://storage.googleapis.com/clusterdata-2019-2/clusterdata-2019-2-task-events.csv.gz"
-		filename = "google_clusterdata_2019.csv.gz"
-	case TraceAzure:
-		url = "https://azureopendatastorage.blob.core.windows.net/azurepublicdataset/azure_vm_workload.csv"
-		filename = "azure_vm_workload.csv"
-	case TraceAlibaba:
-		url = "https://github.com/alibaba/clusterdata/raw/master/cluster-trace-micro-2018.csv"
-		filename = "alibaba_cluster_trace_2018.csv"
-	default:
+type TraceOptions struct {
+	URLs map[TraceSource]string
+	// Client makes the downloads. nil means a client that honours HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY.
+	Client *http.Client
+	// SASToken is appended to Azure blob URLs. Empty means $TRACE_SAS_TOKEN.
+	SASToken string
+	// Credential, when set, authenticates Azure blob downloads with bearer tokens, e.g. from
+	// azidentity.NewDefaultAzureCredential through azureauth.TokenSource, for private containers.
+	Credential TokenSource
+}
+
+// TokenSource issues bearer tokens for an OAuth scope, such as Azure Storage's.
+type TokenSource interface {
+	Token(ctx context.Context, scope string) (string, error)
+}
+
+// TraceURLEnv returns the environment variable overriding the URL of source.
+func TraceURLEnv(source TraceSource) string {
+	return "TRACE_URL_" + strings.ToUpper(string(source))
+}
+
+// URL returns the download URL of source, erroring on unknown sources and on overrides that
+// are not absolute http(s) URLs.
+func (o TraceOptions) URL(source TraceSource) (string, error) {
+	tf, ok := traceFiles[source]
+	if !ok {
 		return "", errors.New("unknown trace source")
 	}
+	raw, from := o.URLs[source], "trace URL"
+	if raw == "" {
+		raw, from = os.Getenv(TraceURLEnv(source)), TraceURLEnv(source)
+	}
+	if raw == "" {
+		return tf.url, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", from, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%s %q: want an absolute http(s) URL", from, raw)
+	}
+	return raw, nil
+}
+
+// DownloadTrace is DownloadTraceWithOptions without options.
+func DownloadTrace(source TraceSource, destDir string) (string, error) {
+	return DownloadTraceWithOptions(source, destDir, TraceOptions{})
+}
+
+/*
+DownloadTraceWithOptions downloads and caches a trace file from a public dataset, or the URL
+opts gives for it, which is validated before anything is downloaded. The file keeps its cache
+name whatever the server sent, e.g. an uncompressed .gz from a proxy: the loaders sniff the
+compression of their input rather than trust its extension.
+
+Concurrent callers, in this process or others sharing destDir, hold a lock file next to the
+cache file, so only the first downloads and the others wait for its result. Downloads are
+written to a temporary file and renamed into place, so the cache never holds a partial file.
+*/
+func DownloadTraceWithOptions(source TraceSource, destDir string, opts TraceOptions) (string, error) {
+	return downloadTrace(source, destDir, opts, os.Stdout)
+}
+
+// downloadTrace is DownloadTraceWithOptions reporting the download to log.
+func downloadTrace(source TraceSource, destDir string, opts TraceOptions, log io.Writer) (string, error) {
+	traceURL, err := opts.URL(source)
+	if err != nil {
+		return "", err
+	}
+	filename := traceFiles[source].filename
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", err
+	}
 	destPath := filepath.Join(destDir, filename)
-	// If a .csv version exists, prefer it (fix for previous renames)
-	if strings.HasSuffix(destPath, ".gz") {
-		csvPath := strings.TrimSuffix(destPath, ".gz") + ".csv"
-		if _, err := os.Stat(csvPath); err == nil {
-			return csvPath, nil
-		}
+	unlock, err := lockFile(destPath + ".lock")
+	if err != nil {
+		return "", fmt.Errorf("lock trace cache: %w", err)
 	}
+	defer unlock()
+
 	if _, err := os.Stat(destPath); err == nil {
-		// Check if .gz file is actually not gzipped (fix for invalid header)
-		if strings.HasSuffix(destPath, ".gz") {
-			isGz, err := isGzipFile(destPath)
-			if err == nil && !isGz {
-				// Rename to .csv and use that
-				newPath := strings.TrimSuffix(destPath, ".gz") + ".csv"
-				os.Rename(destPath, newPath)
-				return newPath, nil
-			}
-		}
-		return destPath, nil // already downloaded and valid
+		return destPath, nil
 	}
-	fmt.Printf("Downloading %s to %s...\n", url, destPath)
-	resp, err := http.Get(url)
+	fmt.Fprintf(log, "Downloading %s to %s...\n", traceURL, destPath)
+	resp, err := opts.fetchTrace(traceURL)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
-	out, err := os.Create(destPath)
+	tmp, err := os.CreateTemp(destDir, filename+".*.tmp")
 	if err != nil {
 		return "", err
 	}
-	defer out.Close()
-	_, err = io.Copy(out, resp.Body)
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	_, err = io.Copy(tmp, resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
 		return "", err
 	}
-	// Check if .gz file is actually not gzipped (fix for invalid header)
-	if strings.HasSuffix(destPath, ".gz") {
-		isGz, err := isGzipFile(destPath)
-		if err == nil && !isGz {
-			newPath := strings.TrimSuffix(destPath, ".gz") + ".csv"
-			os.Rename(destPath, newPath)
-			return newPath, nil
-		}
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return "", err
 	}
 	return destPath, nil
 }
 
-// isGzipFile checks if a file is a valid gzip file by reading its header.
-func isGzipFile(path string) (bool, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return false, err
-	}
-	defer f.Close()
-	var buf [2]byte
-	_, err = f.Read(buf[:])
-	if err != nil {
-		return false, err
-	}
-	// Gzip files start with 0x1f 0x8b
-	return buf[0] == 0x1f && buf[1] == 0x8b, nil
-}
-
 /*
 LoadWorkloadsFromTrace parses a trace file into a slice of WorkloadProfile.
 Supports Google, Azure, and Alibaba public traces (robust parsing).
-Handles .gz files for Google trace.
+Files of any source may be gzip or zstd compressed, whatever their extension.
 */
 func LoadWorkloadsFromTrace(tracePath string, source TraceSource, maxRows int) ([]WorkloadProfile, error) {
-	var r io.Reader
-	f, err := os.Open(tracePath)
+	r, err := openInput(tracePath)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	r = f
+	defer r.Close()
+	return readWorkloadsFromTrace(r, source, maxRows)
+}
 
-	// Handle .gz for Google trace
-	if source == TraceGoogle && strings.HasSuffix(tracePath, ".gz") {
-		gzr, err := gzip.NewReader(f)
-		if err != nil {
-			return nil, err
-		}
-		defer gzr.Close()
-		r = gzr
+// maxTracePrealloc caps the workloads preallocated for maxRows, so a huge limit does not
+// allocate memory for rows the trace may not have.
+const maxTracePrealloc = 1 << 16
+
+// maxTraceValue bounds parsed trace values so conversions to int cannot overflow.
+const maxTraceValue = 1 << 31
+
+/*
+readWorkloadsFromTrace parses an uncompressed trace CSV stream (see LoadWorkloadsFromTrace).
+
+Malformed input yields an error or skipped rows, never a panic: rows shorter than the
+detected columns are skipped, and unparsable, non-finite, negative or absurdly large values
+read as 0.
+*/
+func readWorkloadsFromTrace(r io.Reader, source TraceSource, maxRows int) ([]WorkloadProfile, error) {
+	if maxRows < 0 {
+		return nil, fmt.Errorf("maxRows must not be negative, got %d", maxRows)
 	}
+	return parseTrace(r, source, maxRows, ConvertOptions{}, false)
+}
 
-	workloads := make([]WorkloadProfile, 0, maxRows)
-	csvr := csv.NewReader(r)
-	header, err := csvr.Read()
-	if err != nil {
-		return nil, err
+// traceWorkloadName names a trace workload after its source and data row, e.g. "google-17".
+func traceWorkloadName(source TraceSource, row int) string {
+	return fmt.Sprintf("%s-%d", source, row)
+}
+
+// traceFloat parses a trace value; unparsable, non-finite, negative or absurdly large values read as 0.
+func traceFloat(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || !(v >= 0 && v <= maxTraceValue) { // also rejects NaN
+		return 0
 	}
+	return v
+}
 
-	switch source {
-	case TraceGoogle:
-		// Google trace: columns: ... requested_cpu, requested_memory, ... OR cpu_request, memory_request, ...
-		// Try to find either set of columns for robustness
-		cpuIdx, memIdx := -1, -1
-		for i, col := range header {
-			lc := strings.ToLower(col)
-			if lc == "requested_cpu" || lc == "cpu_request" {
-				cpuIdx = i
-			}
-			if lc == "requested_memory" || lc == "memory_request" {
-				memIdx = i
-			}
-		}
-		if cpuIdx == -1 || memIdx == -1 {
-			return nil, fmt.Errorf("could not find requested_cpu/requested_memory or cpu_request/memory_request columns (found header: %v)", header)
-		}
-		for i := 0; i < maxRows; i++ {
-			row, err := csvr.Read()
-			if err != nil {
-				break
-			}
-			cpu, _ := strconv.ParseFloat(row[cpuIdx], 64)
-			mem, _ := strconv.ParseFloat(row[memIdx], 64)
-			if cpu == 0 && mem == 0 {
-				continue
-			}
-			workloads = append(workloads, WorkloadProfile{
-				CPURequirements:    int(cpu / 1000), // convert to cores
-				MemoryRequirements: mem / 1024,      // convert to GiB
-			})
-		}
-	case TraceAzure:
-		// Azure trace: columns: vCPUs, memoryGB, ...
-		cpuIdx, memIdx := -1, -1
-		for i, col := range header {
-			if strings.Contains(strings.ToLower(col), "vcpu") {
-				cpuIdx = i
-			}
-			if strings.Contains(strings.ToLower(col), "memory") {
-				memIdx = i
-			}
-		}
-		if cpuIdx == -1 || memIdx == -1 {
-			return nil, errors.New("could not find vCPU/memory columns")
-		}
-		for i := 0; i < maxRows; i++ {
-			row, err := csvr.Read()
-			if err != nil {
-				break
-			}
-			cpu, _ := strconv.Atoi(row[cpuIdx])
-			mem, _ := strconv.ParseFloat(row[memIdx], 64)
-			if cpu == 0 && mem == 0 {
-				continue
-			}
-			workloads = append(workloads, WorkloadProfile{
-				CPURequirements:    cpu,
-				MemoryRequirements: mem,
-			})
-		}
-	case TraceAlibaba:
-		// Alibaba trace: columns: ... cpu, mem, ...
-		cpuIdx, memIdx := -1, -1
-		for i, col := range header {
-			if strings.ToLower(col) == "cpu" {
-				cpuIdx = i
-			}
-			if strings.ToLower(col) == "mem" {
-				memIdx = i
-			}
-		}
-		if cpuIdx == -1 || memIdx == -1 {
-			return nil, errors.New("could not find cpu/mem columns")
-		}
-		for i := 0; i < maxRows; i++ {
-			row, err := csvr.Read()
-			if err != nil {
-				break
-			}
-			cpu, _ := strconv.Atoi(row[cpuIdx])
-			mem, _ := strconv.ParseFloat(row[memIdx], 64)
-			if cpu == 0 && mem == 0 {
-				continue
-			}
-			workloads = append(workloads, WorkloadProfile{
-				CPURequirements:    cpu,
-				MemoryRequirements: mem,
-			})
-		}
-	default:
-		return nil, errors.New("unknown trace source")
+// traceInt parses an integer trace value; unparsable, negative or absurdly large values read as 0.
+func traceInt(s string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || v < 0 || v > maxTraceValue {
+		return 0
 	}
-	return workloads, nil
+	return v
 }
 
-// LoadAzureInstanceSpecs loads Azure VM SKUs from a JSON file.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// LoadAzureInstanceSpecs loads Azure VM SKUs from a JSON file, dropping its currency (see LoadSKUDataset).
 func LoadAzureInstanceSpecs(jsonPath string) ([]AzureInstanceSpec, error) {
-	data, err := ioutil.ReadFile(jsonPath)
+	ds, err := LoadSKUDataset(jsonPath)
 	if err != nil {
 		return nil, err
 	}
-	var specs []AzureInstanceSpec
-	if err := json.Unmarshal(data, &specs); err != nil {
+	return ds.SKUs, nil
+}
+
+/*
+LoadAzureInstanceSpecsWithWarnings is LoadAzureInstanceSpecs returning warnings about SKU data
+that is probably wrong, as real SKU dumps contain: prices of 0, memory apparently in MB rather
+than GiB, and zones that are not zone numbers. Zones listed with their region, e.g. "eastus-1",
+are normalized to the zone number and reported too.
+*/
+func LoadAzureInstanceSpecsWithWarnings(jsonPath string) ([]AzureInstanceSpec, []string, error) {
+	ds, err := LoadSKUDataset(jsonPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ds.SKUs, ds.Warnings, nil
+}
+
+// parseAzureInstanceSpecs decodes a SKU JSON document (see LoadSKUDataset).
+func parseAzureInstanceSpecs(data []byte) ([]AzureInstanceSpec, error) {
+	ds, err := parseSKUDataset(data)
+	if err != nil {
 		return nil, err
 	}
-	return specs, nil
+	return ds.SKUs, nil
 }
 
 // BinPackWorkloadsNaive is a naive bin-packing: assign each workload to the smallest VM that fits.
+// Workloads no candidate fits are unpacked with ReasonNoCandidates, or ReasonNoInstanceTypes
+// without candidates, like BinPackWorkloads, so comparisons against it count them.
 func BinPackWorkloadsNaive(workloads WorkloadSet, candidates []AzureInstanceSpec) PackingResult {
 	var result PackingResult
 	for _, w := range workloads {
@@ -256,7 +266,7 @@ func BinPackWorkloadsNaive(workloads WorkloadSet, candidates []AzureInstanceSpec
 		var best AzureInstanceSpec
 		bestFound := false
 		for _, vm := range candidates {
-			if vm.VCpus >= w.CPURequirements && vm.MemoryGiB >= w.MemoryRequirements {
+			if vm.VCpus >= w.CPURequirements && fitsWithin(w.MemoryRequirements, vm.MemoryGiB) {
 				if !bestFound || (vm.VCpus < best.VCpus || (vm.VCpus == best.VCpus && vm.MemoryGiB < best.MemoryGiB)) {
 					best = vm
 					bestFound = true
@@ -265,10 +275,17 @@ func BinPackWorkloadsNaive(workloads WorkloadSet, candidates []AzureInstanceSpec
 		}
 		if bestFound {
 			result.VMs = append(result.VMs, PackedVM{
+				ID:           VMID(len(result.VMs) + 1),
 				InstanceType: best,
 				Workloads:    []WorkloadProfile{w},
 			})
+			continue
+		}
+		reason := ReasonNoCandidates
+		if len(candidates) == 0 {
+			reason = ReasonNoInstanceTypes
 		}
+		result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: w, Reason: reason})
 	}
 	return result
 }
@@ -283,6 +300,7 @@ func TotalCost(vms []PackedVM) float64 {
 }
 
 // AverageUtilization computes average CPU and memory utilization for a packing result.
+// Headroom buffer workloads are not counted as used capacity.
 func AverageUtilization(vms []PackedVM) (cpuUtil, memUtil float64) {
 	var totalCPU, usedCPU float64
 	var totalMem, usedMem float64
@@ -290,6 +308,9 @@ func AverageUtilization(vms []PackedVM) (cpuUtil, memUtil float64) {
 		totalCPU += float64(vm.InstanceType.VCpus)
 		totalMem += vm.InstanceType.MemoryGiB
 		for _, w := range vm.Workloads {
+			if w.Headroom {
+				continue
+			}
 			usedCPU += float64(w.CPURequirements)
 			usedMem += w.MemoryRequirements
 		}
@@ -303,41 +324,230 @@ func AverageUtilization(vms []PackedVM) (cpuUtil, memUtil float64) {
 	return
 }
 
+// SimulationResult summarizes a packing run. Utilization only counts real workloads;
+// the cost of headroom buffers is reported separately in HeadroomCost.
 type SimulationResult struct {
-	VMsUsed   int
-	TotalCost float64
-	AvgCPU    float64
-	AvgMem    float64
+	VMsUsed      int
+	TotalCost    float64
+	Currency     string // ISO 4217 code of TotalCost and every other price in the result
+	AvgCPU       float64
+	AvgMem       float64
+	Utilization  Utilization // per-resource utilization, including GPU, storage and pod slots
+	Histogram    Histogram   // VMs by utilization; buckets use Config.HistogramEdges when set
+	HeadroomCost float64
+	DistinctSKUs int     // number of different SKUs used
+	SKUEntropy   float64 // Shannon entropy (bits) of the SKU distribution, see SKUDiversity
+	Unpacked     int     // workloads that could not be placed
+	LimitCPUUtil float64 // percentage of Config.Limits.CPU provisioned (0 when unlimited)
+	LimitMemUtil float64 // percentage of Config.Limits.MemoryGiB provisioned (0 when unlimited)
+	Waste        WasteReport
+	Reservations *ReservationReport `json:",omitempty"` // set when Config.CapacityReservations is
+	Projection   Projection         // monthly/annual cost; uses Config.Projection when set
+	CostByLabel  *CostAttribution   `json:",omitempty"` // set when Config.CostLabelKey is set
+	Groups       *GroupSummary      `json:",omitempty"` // by Config.CostLabelKey, else WorkloadTypeLabel if present; see SummarizeByLabel
+	ZoneWarnings []ZoneWarning      `json:",omitempty"` // VMs of SKUs in fewer zones than required, see ZoneWarnings
+	QuotaUsage   *QuotaUsage        `json:",omitempty"` // vCPUs charged against each quota; set when Config.Quota is
+	// ExcludedFamilies are the candidate families without quota; set under Config.QuotaFamiliesOnly.
+	ExcludedFamilies []string `json:",omitempty"`
+	// BasisComparison is the cost of packing by requests and by usage; set when Config.Basis is a usage basis.
+	BasisComparison []BasisCost `json:",omitempty"`
+	// Sensitivity has one row per perturbation level; set when Config.Sensitivity is.
+	Sensitivity []PriceSensitivity `json:",omitempty"`
+	// LoadProfile is the hourly packing under Config.LoadProfile; set when that is.
+	LoadProfile *LoadProfileResult `json:",omitempty"`
+	// Events is the event log of the run's timeline, the hours of Config.LoadProfile; reports
+	// summarize it per hour and EventLog.WriteJSONL exports it.
+	Events EventLog `json:"-"`
+	// ArchComparison compares amd64-only and multi-arch packing; set when Config.ArchComparison is.
+	ArchComparison *ArchComparison `json:",omitempty"`
+	// Approx is the error bound of the approximate packing; set when Config.Approx is.
+	Approx *ApproxReport `json:",omitempty"`
+	// Baseline compares the packing to Config.Baseline; set when that is.
+	Baseline    *BaselineComparison `json:",omitempty"`
+	StrategyMix []StrategyCount     `json:",omitempty"` // workloads per class; set when Config.Strategy is StrategyAuto
+	// WorkloadsPerVM is the distribution of real workloads per VM, by ascending count.
+	WorkloadsPerVM []WorkloadCount `json:",omitempty"`
+	VMs            []VMDetail
+	Workloads      []WorkloadDetail // packed workloads in VM order, then unpacked workloads
+	Timing         TimingReport
 }
 
-// QuotaMap maps VM family to max vCPUs allowed.
-type QuotaMap map[string]int
+// TimingReport records how long a packing run took and how effective the score cache was.
+type TimingReport struct {
+	PackingTime      time.Duration
+	ScoreCacheHits   int64
+	ScoreCacheMisses int64
+	// SelectionCacheHits are the ScoreCacheHits answered from Config.SelectionCache, i.e.
+	// ranked by an earlier run.
+	SelectionCacheHits int64 `json:",omitempty"`
+}
 
-// LoadQuota loads a quota.json file mapping family to max vCPUs.
-func LoadQuota(path string) (QuotaMap, error) {
-	if path == "" {
-		return nil, nil
+// ScoreCacheHitRate returns the share of selections answered from the score cache, from 0 to 1.
+func (t TimingReport) ScoreCacheHitRate() float64 {
+	if t.ScoreCacheHits+t.ScoreCacheMisses == 0 {
+		return 0
 	}
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
+	return float64(t.ScoreCacheHits) / float64(t.ScoreCacheHits+t.ScoreCacheMisses)
+}
+
+// SimulationRun records the results of every algorithm compared in one simulation.
+type SimulationRun struct {
+	// SchemaVersion is the report schema version (see report.SchemaVersion), set when the run
+	// is written or parsed.
+	SchemaVersion int `json:",omitempty"`
+	Results       []NamedResult
+	Scenario      *Scenario `json:",omitempty"` // the resolved scenario of runs started with RunScenario
+	// Manifest records the inputs of scenario runs with Scenario.Manifest, for Replay.
+	Manifest *RunManifest `json:",omitempty"`
+	// Metadata records the build, host and time of runs of the Simulator, RunScenario and Replay.
+	Metadata *RunMetadata `json:",omitempty"`
+}
+
+// Currency returns the currency of the run's prices. Every result of a run is priced from the
+// same SKU dataset, so this is the currency of the first result, or DefaultCurrency if there is none.
+func (r SimulationRun) Currency() string {
+	if len(r.Results) == 0 {
+		return DefaultCurrency
 	}
-	var q QuotaMap
-	if err := json.Unmarshal(data, &q); err != nil {
-		return nil, err
+	return currencyOrDefault(r.Results[0].Result.Currency)
+}
+
+// NamedResult is the SimulationResult of one algorithm or strategy, e.g. "NewAlgorithm" or "Naive".
+type NamedResult struct {
+	Name   string
+	Result SimulationResult
+}
+
+// VMDetail is the per-VM detail of a SimulationResult.
+type VMDetail struct {
+	ID                string `json:",omitempty"` // PackedVM.ID
+	SKU               string
+	Zone              string // zone required by the VM's workloads, if any
+	PricePerHour      float64
+	Workloads         int // real workloads packed on the VM
+	HeadroomWorkloads int // headroom buffer workloads packed on the VM
+	CPUUtil           float64
+	MemUtil           float64
+	// LimitOvercommitCPU and LimitOvercommitMem are the limits of the VM's workloads over its
+	// capacity (see LimitOvercommit), 0 when none has a limit.
+	LimitOvercommitCPU float64   `json:",omitempty"`
+	LimitOvercommitMem float64   `json:",omitempty"`
+	Reserved           bool      `json:",omitempty"` // fills a capacity reservation slot
+	Decision           *Decision `json:",omitempty"` // set when the run was audited (Config.WithAudit)
+}
+
+// WorkloadDetail is the per-workload detail of a SimulationResult.
+type WorkloadDetail struct {
+	Name           string `json:",omitempty"`
+	Namespace      string `json:",omitempty"`
+	Parent         string `json:",omitempty"`
+	CPU            int
+	MemoryGiB      float64
+	GPU            int
+	Zone           string
+	Headroom       bool
+	VM             int    // index into SimulationResult.VMs, -1 when unpacked
+	UnpackedReason string // set when VM is -1
+}
+
+// NewSimulationResult summarizes a packing result.
+func NewSimulationResult(result PackingResult) SimulationResult {
+	cpuU, memU := AverageUtilization(result.VMs)
+	sim := SimulationResult{
+		VMsUsed:      len(result.VMs),
+		TotalCost:    TotalCost(result.VMs),
+		Currency:     DefaultCurrency,
+		AvgCPU:       cpuU,
+		AvgMem:       memU,
+		Utilization:  AverageUtilizationV2(result.VMs),
+		Histogram:    UtilizationHistogram(result, DefaultHistogramBuckets),
+		HeadroomCost: HeadroomCost(result.VMs),
+		Unpacked:     len(result.Unpacked),
+		Waste:        ComputeWaste(result),
+		ZoneWarnings: ZoneWarnings(result, 0),
+		Reservations: newReservationReport(result.Reservations),
+		QuotaUsage:   result.QuotaUsage,
+		Projection:   CostProjection(result, DefaultHoursPerMonth, 0, ReservedCoverage{}),
+	}
+	sim.DistinctSKUs, sim.SKUEntropy = SKUDiversity(result.VMs)
+	for i, vm := range result.VMs {
+		d := VMDetail{ID: vm.ID, SKU: vm.InstanceType.Name, PricePerHour: vm.InstanceType.PricePerHour, Reserved: vm.Reserved, Decision: vm.Decision}
+		if vm.Reserved && len(vm.InstanceType.AvailabilityZones) == 1 {
+			d.Zone = vm.InstanceType.AvailabilityZones[0] // zonal reservation
+		}
+		for _, w := range vm.Workloads {
+			if w.Headroom {
+				d.HeadroomWorkloads++
+			} else {
+				d.Workloads++
+			}
+			if d.Zone == "" {
+				d.Zone = w.Zone
+			}
+			sim.Workloads = append(sim.Workloads, newWorkloadDetail(w, i, ""))
+		}
+		d.CPUUtil, d.MemUtil = AverageUtilization([]PackedVM{vm})
+		d.LimitOvercommitCPU, d.LimitOvercommitMem = LimitOvercommit(vm)
+		sim.VMs = append(sim.VMs, d)
+	}
+	for _, u := range result.Unpacked {
+		sim.Workloads = append(sim.Workloads, newWorkloadDetail(u.Workload, -1, u.Reason))
+	}
+	sim.WorkloadsPerVM = workloadCounts(sim.VMs)
+	return sim
+}
+
+func newWorkloadDetail(w WorkloadProfile, vm int, reason string) WorkloadDetail {
+	return WorkloadDetail{
+		Name:           w.Name,
+		Namespace:      w.Namespace,
+		Parent:         w.Parent,
+		CPU:            w.CPURequirements,
+		MemoryGiB:      w.MemoryRequirements,
+		GPU:            w.GPURequirements,
+		Zone:           w.Zone,
+		Headroom:       w.Headroom,
+		VM:             vm,
+		UnpackedReason: reason,
 	}
-	return q, nil
 }
 
-// BinPackWorkloadsWithQuota is like BinPackWorkloads but enforces vCPU quotas per family.
+// QuotaMap maps VM family to max vCPUs allowed. Families without an entry are unlimited.
+type QuotaMap map[string]int
+
+// LoadQuota loads a quota file, see LoadQuotaWithWarnings for the accepted formats.
+func LoadQuota(path string) (QuotaMap, error) {
+	q, _, err := LoadQuotaWithWarnings(path)
+	return q, err
+}
+
+// BinPackWorkloadsWithQuota is like BinPackWorkloads but enforces vCPU quotas per family, and
+// GPU quotas per family and in total (see GPUQuotaKey).
 func BinPackWorkloadsWithQuota(workloads WorkloadSet, candidates []AzureInstanceSpec, strategy SelectionStrategy, quota QuotaMap) PackingResult {
-	// Sort workloads by descending CPU+Memory demand (naive, can be improved)
+	return binPackWorkloadsWithQuota(workloads, candidates, Config{Strategy: strategy, Quota: quota})
+}
+
+// binPackWorkloadsWithQuota implements BinPackWorkloadsWithQuota for a Config; cfg.Quota may be nil.
+func binPackWorkloadsWithQuota(workloads WorkloadSet, candidates []AzureInstanceSpec, cfg Config) PackingResult {
+	cfg = cfg.forRun()
+	quota := cfg.Quota
+	// Sort workloads by descending CPU+Memory demand (naive, can be improved), or by cfg.SortKey
+	// Headroom buffers are low priority and always go after real workloads.
 	sorted := make(WorkloadSet, len(workloads))
 	copy(sorted, workloads)
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[j].CPURequirements+int(sorted[j].MemoryRequirements) > sorted[i].CPURequirements+int(sorted[i].MemoryRequirements) {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
+	if cfg.SortKey != SortKeyLegacy {
+		cfg.SortKey.sort(sorted, candidates)
+	} else {
+		for i := 0; i < len(sorted); i++ {
+			for j := i + 1; j < len(sorted); j++ {
+				if sorted[i].Headroom && !sorted[j].Headroom {
+					sorted[i], sorted[j] = sorted[j], sorted[i]
+					continue
+				}
+				if sorted[i].Headroom == sorted[j].Headroom &&
+					sorted[j].CPURequirements+int(sorted[j].MemoryRequirements) > sorted[i].CPURequirements+int(sorted[i].MemoryRequirements) {
+					sorted[i], sorted[j] = sorted[j], sorted[i]
+				}
 			}
 		}
 	}
@@ -345,6 +555,12 @@ func BinPackWorkloadsWithQuota(workloads WorkloadSet, candidates []AzureInstance
 	var result PackingResult
 	unpacked := make([]bool, len(sorted))
 	usedVCpus := make(map[string]int)
+	usedSpot := 0
+	var usedGPUs gpuQuota
+	quotaExhausted := false    // some family was removed for exceeding its quota
+	gpuQuotaExhausted := false // some GPU instance types were removed for exceeding a GPU quota
+	limits := limitTracker{limits: cfg.Limits}
+	reservations := newReservationTracker(cfg.CapacityReservations, candidates)
 
 	for {
 		// Find the next workload not yet packed
@@ -360,619 +576,355 @@ func BinPackWorkloadsWithQuota(workloads WorkloadSet, candidates []AzureInstance
 		}
 		// For this workload, select the best instance type
 		workload := sorted[nextIdx]
-		bestVM, _ := selectWithStrategy(candidates, workload, strategy)
+		bestVM, score, reservation := reservations.selectFor(candidates, workload, cfg)
 		if bestVM.Name == "" {
-			break // no suitable VM found
+			// No suitable VM for this workload; the others may still fit elsewhere
+			reason := ReasonNoCandidates
+			switch {
+			case gpuQuotaExhausted && workload.gpusRequired() > 0:
+				reason = ReasonGPUQuotaExhausted
+			case quotaExhausted:
+				reason = ReasonQuotaExhausted
+			}
+			unpacked[nextIdx] = true
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: workload, Reason: reason})
+			continue
+		}
+		if cfg.poorFit(bestVM, workload, reservation) {
+			unpacked[nextIdx] = true
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: workload, Reason: ReasonPoorFit})
+			continue
+		}
+		// Spot VMs are charged against the spot pool instead of their family's quota
+		spot := requiresSpot(workload)
+		if spotLimit, limited := quota.SpotTotalVCpus(); spot && reservation < 0 && limited && usedSpot+bestVM.VCpus > spotLimit {
+			unpacked[nextIdx] = true
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: workload, Reason: ReasonSpotQuotaExhausted})
+			continue
 		}
-		// Check quota for this family
-		fam := bestVM.Family
-		if quota != nil && quota[fam] > 0 && usedVCpus[fam]+bestVM.VCpus > quota[fam] {
+		// Check quota for this family; reserved capacity was already allocated against it
+		fam, limit, limited := quota.limit(bestVM.Family)
+		if !spot && reservation < 0 && limited && usedVCpus[fam]+bestVM.VCpus > limit {
 			// Can't use this family anymore, remove from candidates and retry
 			var newCandidates []AzureInstanceSpec
 			for _, c := range candidates {
-				if c.Family != fam {
+				if key, _, _ := quota.limit(c.Family); key != fam {
 					newCandidates = append(newCandidates, c)
 				}
 			}
 			candidates = newCandidates
+			quotaExhausted = true
 			continue
 		}
-		// Try to pack as many workloads as possible onto this VM
+		// GPU quotas count spot VMs too; drop the instance types with more GPUs than are left
+		if reservation < 0 && usedGPUs.exceeded(quota, bestVM) {
+			var newCandidates []AzureInstanceSpec
+			for _, c := range candidates {
+				if !usedGPUs.exceeded(quota, c) {
+					newCandidates = append(newCandidates, c)
+				}
+			}
+			candidates = newCandidates
+			gpuQuotaExhausted = true
+			continue
+		}
+		// Stop provisioning once the next VM would exceed the configured limits
+		if reason := limits.exceeded(bestVM); reason != "" {
+			result.Unpacked = append(result.Unpacked, markUnpacked(sorted, unpacked, reason)...)
+			break
+		}
+		// Try to pack as many workloads as possible onto this VM; spot and on-demand workloads
+		// do not share VMs, so every VM is charged against one quota
 		var packed []WorkloadProfile
-		remainingCPU := bestVM.VCpus
-		remainingMem := bestVM.MemoryGiB
+		remaining := cfg.vmCapacity(bestVM)
 		for i, w := range sorted {
-			if unpacked[i] {
+			if unpacked[i] || (!w.Headroom && requiresSpot(w) != spot) {
 				continue
 			}
-			if w.CPURequirements <= remainingCPU && w.MemoryRequirements <= remainingMem {
+			if remaining.fits(w) {
 				packed = append(packed, w)
-				remainingCPU -= w.CPURequirements
-				remainingMem -= w.MemoryRequirements
+				remaining.take(w)
 				unpacked[i] = true
 			}
 		}
-		usedVCpus[fam] += bestVM.VCpus
+		if len(packed) == 0 {
+			// The selected VM cannot hold the workload it was selected for
+			unpacked[nextIdx] = true
+			result.Unpacked = append(result.Unpacked, UnpackedWorkload{Workload: workload, Reason: ReasonSelectedTooSmall})
+			continue
+		}
+		switch {
+		case reservation >= 0:
+		case spot:
+			usedSpot += bestVM.VCpus
+		default:
+			usedVCpus[fam] += bestVM.VCpus
+		}
+		if reservation < 0 {
+			usedGPUs.add(quota, bestVM)
+		}
+		limits.add(bestVM)
+		reservations.use(reservation)
+		id := VMID(len(result.VMs) + 1)
 		result.VMs = append(result.VMs, PackedVM{
+			ID:           id,
 			InstanceType: bestVM,
 			Workloads:    packed,
+			Decision:     cfg.audit(candidates, workload, bestVM, score, id),
+			Reserved:     reservation >= 0,
+			CapacityType: capacityTypeFor(bestVM, workload, reservation >= 0),
 		})
 	}
+	assignZones(result.VMs)
+	result.Reservations = reservations.report()
+	if len(quota) > 0 {
+		result.QuotaUsage = &QuotaUsage{Families: usedVCpus, SpotVCpus: usedSpot, GPUFamilies: usedGPUs.families, GPUs: usedGPUs.total}
+	}
 	return result
 }
 
+// requiresSpot reports whether w must run on spot capacity; headroom buffers never do.
+func requiresSpot(w WorkloadProfile) bool {
+	return w.RequireSpot && !w.Headroom
+}
+
 // RunTraceSimulationWithQuota runs the simulation with an optional quota file.
 func RunTraceSimulationWithQuota(trace TraceSource, skuPath string, maxRows int, quotaPath string) (SimulationResult, SimulationResult, error) {
-	if trace == "custom" {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("custom trace not supported here, use RunCustomWorkloadSimulationWithQuota")
+	quota, err := LoadQuota(quotaPath)
+	if err != nil {
+		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load quota: %w", err)
+	}
+	return RunTraceSimulationWithConfig(trace, skuPath, maxRows, Config{Strategy: StrategyGeneralPurpose, Quota: quota})
+}
+
+// RunTraceSimulationWithConfig runs the trace simulation with the given packing Config and
+// returns the results of the new and the naive algorithm (see RunTraceSimulationWithResults).
+func RunTraceSimulationWithConfig(trace TraceSource, skuPath string, maxRows int, cfg Config) (SimulationResult, SimulationResult, error) {
+	return resultPair(RunTraceSimulationWithResults(trace, skuPath, maxRows, cfg))
+}
+
+/*
+RunTraceSimulationWithResults is Simulator.RunTrace for a SKU file, printing progress to
+stdout. The trace is loaded before the SKUs, so a failed download is reported as such even
+when the SKU file is missing too.
+*/
+func RunTraceSimulationWithResults(trace TraceSource, skuPath string, maxRows int, cfg Config) (SimulationRun, error) {
+	s := &Simulator{Config: cfg, Log: os.Stdout}
+	workloads, err := s.traceWorkloads(trace, maxRows)
+	if err != nil {
+		return SimulationRun{}, err
+	}
+	if err := s.loadSKUs(skuPath); err != nil {
+		return SimulationRun{}, err
+	}
+	return s.RunWorkloads(context.Background(), workloads)
+}
+
+// loadTraceWorkloads downloads trace into .trace_cache unless cached and loads up to maxRows
+// workloads, reporting progress to log.
+func loadTraceWorkloads(trace TraceSource, maxRows int, opts TraceOptions, log io.Writer) (WorkloadSet, error) {
+	// Bad URLs are input errors, not failed downloads
+	if _, err := opts.URL(trace); err != nil {
+		return nil, err
 	}
 	cacheDir := ".trace_cache"
-	os.MkdirAll(cacheDir, 0755)
-	tracePath, err := DownloadTrace(trace, cacheDir)
+	tracePath, err := downloadTrace(trace, cacheDir, opts, log)
 	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("download trace: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrDownload, err)
 	}
-	fmt.Printf("Parsing workloads from %s...\n", tracePath)
+	fmt.Fprintf(log, "Parsing workloads from %s...\n", tracePath)
 	workloads, err := LoadWorkloadsFromTrace(tracePath, trace, maxRows)
 	if err != nil {
 		// Check for XML error (e.g. bucket not found or download failed)
 		if strings.Contains(err.Error(), "<?xml") || strings.Contains(err.Error(), "<Error>") {
-			return SimulationResult{}, SimulationResult{}, fmt.Errorf("parse trace: trace file is not a valid CSV (possible download error or missing bucket): %w", err)
+			return nil, fmt.Errorf("parse trace: trace file is not a valid CSV (possible download error or missing bucket): %w", err)
 		}
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("parse trace: %w", err)
-	}
-	fmt.Printf("Loading Azure instance specs from %s...\n", skuPath)
-	skus, err := LoadAzureInstanceSpecs(skuPath)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load skus: %w", err)
+		return nil, fmt.Errorf("parse trace: %w", err)
 	}
-	quota, err := LoadQuota(quotaPath)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load quota: %w", err)
-	}
-	fmt.Printf("Simulating bin-packing with new algorithm...\n")
-	result := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota)
-	fmt.Printf("Simulating bin-packing with naive algorithm...\n")
-	naive := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota) // For naive, could use BinPackWorkloadsNaive with quota logic if desired
-	cpuU, memU := AverageUtilization(result.VMs)
-	cpuU2, memU2 := AverageUtilization(naive.VMs)
-	return SimulationResult{
-			VMsUsed:   len(result.VMs),
-			TotalCost: TotalCost(result.VMs),
-			AvgCPU:    cpuU,
-			AvgMem:    memU,
-		}, SimulationResult{
-			VMsUsed:   len(naive.VMs),
-			TotalCost: TotalCost(naive.VMs),
-			AvgCPU:    cpuU2,
-			AvgMem:    memU2,
-		}, nil
+	return workloads, nil
 }
 
 // RunCustomWorkloadSimulationWithQuota loads a custom workload JSON file and runs the simulation with quota.
 func RunCustomWorkloadSimulationWithQuota(workloadsFile string, skuPath string, quotaPath string) (SimulationResult, SimulationResult, error) {
-	data, err := ioutil.ReadFile(workloadsFile)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("read workloads: %w", err)
-	}
-	var workloads []WorkloadProfile
-	if err := json.Unmarshal(data, &workloads); err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("parse workloads: %w", err)
-	}
-	fmt.Printf("Loaded %d custom workloads from %s\n", len(workloads), workloadsFile)
-	fmt.Printf("Loading Azure instance specs from %s...\n", skuPath)
-	skus, err := LoadAzureInstanceSpecs(skuPath)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load skus: %w", err)
-	}
 	quota, err := LoadQuota(quotaPath)
 	if err != nil {
 		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load quota: %w", err)
 	}
-	fmt.Printf("Simulating bin-packing with new algorithm...\n")
-	result := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota)
-	fmt.Printf("Simulating bin-packing with naive algorithm...\n")
-	naive := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota)
-	cpuU, memU := AverageUtilization(result.VMs)
-	cpuU2, memU2 := AverageUtilization(naive.VMs)
-	return SimulationResult{
-			VMsUsed:   len(result.VMs),
-			TotalCost: TotalCost(result.VMs),
-			AvgCPU:    cpuU,
-			AvgMem:    memU,
-		}, SimulationResult{
-			VMsUsed:   len(naive.VMs),
-			TotalCost: TotalCost(naive.VMs),
-			AvgCPU:    cpuU2,
-			AvgMem:    memU2,
-		}, nil
+	return RunCustomWorkloadSimulationWithConfig(workloadsFile, skuPath, Config{Strategy: StrategyGeneralPurpose, Quota: quota})
 }
-package resolver
-
-import (
-	"compress/gzip"
-	"encoding/csv"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
-	"io/ioutil"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
-)
 
-// TraceSource represents a public trace dataset.
-type TraceSource string
-
-const (
-	TraceGoogle   TraceSource = "google"
-	TraceAzure    TraceSource = "azure"
-	TraceAlibaba  TraceSource = "alibaba"
-)
-
-/*
-DownloadTrace downloads and caches a trace file from a public dataset.
-If the file is a .gz, but the download is not actually gzipped (e.g. due to proxy or error), it will
-detect and fix the file extension to avoid gzip: invalid header errors.
-*/
-func DownloadTrace(source TraceSource, destDir string) (string, error) {
-	var url, filename string
-	switch source {
-	case TraceGoogle:
-		url = "https://storage.googleapis.com/clusterdata-2019-2/clusterdata-2019-2-task-events.csv.gz"
-		filename = "google_clusterdata_2019.csv.gz"
-	case TraceAzure:
-		url = "https://azureopendatastorage.blob.core.windows.net/azurepublicdataset/azure_vm_workload.csv"
-		filename = "azure_vm_workload.csv"
-	case TraceAlibaba:
-		url = "https://github.com/alibaba/clusterdata/raw/master/cluster-trace-micro-2018.csv"
-		filename = "alibaba_cluster_trace_2018.csv"
-	default:
-		return "", errors.New("unknown trace source")
-	}
-	destPath := filepath.Join(destDir, filename)
-	// If a .csv version exists, prefer it (fix for previous renames)
-	if strings.HasSuffix(destPath, ".gz") {
-		csvPath := strings.TrimSuffix(destPath, ".gz") + ".csv"
-		if _, err := os.Stat(csvPath); err == nil {
-			return csvPath, nil
-		}
-	}
-	if _, err := os.Stat(destPath); err == nil {
-		// Check if .gz file is actually not gzipped (fix for invalid header)
-		if strings.HasSuffix(destPath, ".gz") {
-			isGz, err := isGzipFile(destPath)
-			if err == nil && !isGz {
-				// Rename to .csv and use that
-				newPath := strings.TrimSuffix(destPath, ".gz") + ".csv"
-				os.Rename(destPath, newPath)
-				return newPath, nil
-			}
-		}
-		return destPath, nil // already downloaded and valid
-	}
-	fmt.Printf("Downloading %s to %s...\n", url, destPath)
-	resp, err := http.Get(url)
+// RunCustomWorkloadSimulationWithConfig loads a custom workload JSON file in cfg.WorkloadFormat and runs the simulation with the given packing Config.
+// It is Simulator.RunCustom for a SKU file, printing progress to stdout.
+func RunCustomWorkloadSimulationWithConfig(workloadsFile string, skuPath string, cfg Config) (SimulationResult, SimulationResult, error) {
+	s := &Simulator{Config: cfg, Log: os.Stdout}
+	workloads, err := s.customWorkloads(workloadsFile)
 	if err != nil {
-		return "", err
+		return SimulationResult{}, SimulationResult{}, err
 	}
-	defer resp.Body.Close()
-	out, err := os.Create(destPath)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return "", err
+	if err := s.loadSKUs(skuPath); err != nil {
+		return SimulationResult{}, SimulationResult{}, err
 	}
-	// Check if .gz file is actually not gzipped (fix for invalid header)
-	if strings.HasSuffix(destPath, ".gz") {
-		isGz, err := isGzipFile(destPath)
-		if err == nil && !isGz {
-			newPath := strings.TrimSuffix(destPath, ".gz") + ".csv"
-			os.Rename(destPath, newPath)
-			return newPath, nil
-		}
-	}
-	return destPath, nil
+	return resultPair(s.RunWorkloads(context.Background(), workloads))
 }
 
-// isGzipFile checks if a file is a valid gzip file by reading its header.
-func isGzipFile(path string) (bool, error) {
-	f, err := os.Open(path)
+// resultPair returns the results of the new and the naive algorithm of a Simulator run.
+func resultPair(run SimulationRun, err error) (SimulationResult, SimulationResult, error) {
 	if err != nil {
-		return false, err
+		return SimulationResult{}, SimulationResult{}, err
 	}
-	defer f.Close()
-	var buf [2]byte
-	_, err = f.Read(buf[:])
-	if err != nil {
-		return false, err
-	}
-	// Gzip files start with 0x1f 0x8b
-	return buf[0] == 0x1f && buf[1] == 0x8b, nil
+	return run.Results[0].Result, run.Results[1].Result, nil
 }
 
-/*
-LoadWorkloadsFromTrace parses a trace file into a slice of WorkloadProfile.
-Supports Google, Azure, and Alibaba public traces (robust parsing).
-Handles .gz files for Google trace.
-*/
-func LoadWorkloadsFromTrace(tracePath string, source TraceSource, maxRows int) ([]WorkloadProfile, error) {
-	var r io.Reader
-	f, err := os.Open(tracePath)
+// loadCustomWorkloads loads a custom workload JSON file: a list of WorkloadProfile objects, or
+// with a .jsonl extension one object per line (see WriteWorkloads), optionally compressed, e.g.
+// workloads.jsonl.gz. Workloads without a "name" are named after their position, e.g. "workload-3".
+func loadCustomWorkloads(path string) (WorkloadSet, error) {
+	data, err := readInput(path)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("read workloads: %w", err)
 	}
-	defer f.Close()
-	r = f
-
-	// Handle .gz for Google trace
-	if source == TraceGoogle && strings.HasSuffix(tracePath, ".gz") {
-		gzr, err := gzip.NewReader(f)
-		if err != nil {
-			return nil, err
-		}
-		defer gzr.Close()
-		r = gzr
+	var workloads WorkloadSet
+	if strings.HasSuffix(trimCompressionExt(path), ".jsonl") {
+		workloads, err = readWorkloadsJSONL(bytes.NewReader(data))
+	} else {
+		err = json.Unmarshal(data, &workloads)
 	}
-
-	workloads := make([]WorkloadProfile, 0, maxRows)
-	csvr := csv.NewReader(r)
-	header, err := csvr.Read()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parse workloads: %w", err)
 	}
-
-	switch source {
-	case TraceGoogle:
-		// Google trace: columns: ... requested_cpu, requested_memory, ... OR cpu_request, memory_request, ...
-		// Try to find either set of columns for robustness
-		cpuIdx, memIdx := -1, -1
-		for i, col := range header {
-			lc := strings.ToLower(col)
-			if lc == "requested_cpu" || lc == "cpu_request" {
-				cpuIdx = i
-			}
-			if lc == "requested_memory" || lc == "memory_request" {
-				memIdx = i
-			}
-		}
-		if cpuIdx == -1 || memIdx == -1 {
-			return nil, fmt.Errorf("could not find requested_cpu/requested_memory or cpu_request/memory_request columns (found header: %v)", header)
-		}
-		for i := 0; i < maxRows; i++ {
-			row, err := csvr.Read()
-			if err != nil {
-				break
-			}
-			cpu, _ := strconv.ParseFloat(row[cpuIdx], 64)
-			mem, _ := strconv.ParseFloat(row[memIdx], 64)
-			if cpu == 0 && mem == 0 {
-				continue
-			}
-			workloads = append(workloads, WorkloadProfile{
-				CPURequirements:    int(cpu / 1000), // convert to cores
-				MemoryRequirements: mem / 1024,      // convert to GiB
-			})
-		}
-	case TraceAzure:
-		// Azure trace: columns: vCPUs, memoryGB, ...
-		cpuIdx, memIdx := -1, -1
-		for i, col := range header {
-			if strings.Contains(strings.ToLower(col), "vcpu") {
-				cpuIdx = i
-			}
-			if strings.Contains(strings.ToLower(col), "memory") {
-				memIdx = i
-			}
-		}
-		if cpuIdx == -1 || memIdx == -1 {
-			return nil, errors.New("could not find vCPU/memory columns")
-		}
-		for i := 0; i < maxRows; i++ {
-			row, err := csvr.Read()
-			if err != nil {
-				break
-			}
-			cpu, _ := strconv.Atoi(row[cpuIdx])
-			mem, _ := strconv.ParseFloat(row[memIdx], 64)
-			if cpu == 0 && mem == 0 {
-				continue
-			}
-			workloads = append(workloads, WorkloadProfile{
-				CPURequirements:    cpu,
-				MemoryRequirements: mem,
-			})
+	for i := range workloads {
+		if workloads[i].Name == "" {
+			workloads[i].Name = fmt.Sprintf("workload-%d", i)
 		}
-	case TraceAlibaba:
-		// Alibaba trace: columns: ... cpu, mem, ...
-		cpuIdx, memIdx := -1, -1
-		for i, col := range header {
-			if strings.ToLower(col) == "cpu" {
-				cpuIdx = i
-			}
-			if strings.ToLower(col) == "mem" {
-				memIdx = i
-			}
-		}
-		if cpuIdx == -1 || memIdx == -1 {
-			return nil, errors.New("could not find cpu/mem columns")
-		}
-		for i := 0; i < maxRows; i++ {
-			row, err := csvr.Read()
-			if err != nil {
-				break
-			}
-			cpu, _ := strconv.Atoi(row[cpuIdx])
-			mem, _ := strconv.ParseFloat(row[memIdx], 64)
-			if cpu == 0 && mem == 0 {
-				continue
-			}
-			workloads = append(workloads, WorkloadProfile{
-				CPURequirements:    cpu,
-				MemoryRequirements: mem,
-			})
-		}
-	default:
-		return nil, errors.New("unknown trace source")
 	}
 	return workloads, nil
 }
 
-// LoadAzureInstanceSpecs loads Azure VM SKUs from a JSON file.
-func LoadAzureInstanceSpecs(jsonPath string) ([]AzureInstanceSpec, error) {
-	data, err := ioutil.ReadFile(jsonPath)
-	if err != nil {
-		return nil, err
-	}
-	var specs []AzureInstanceSpec
-	if err := json.Unmarshal(data, &specs); err != nil {
-		return nil, err
-	}
-	return specs, nil
-}
-
-// BinPackWorkloadsNaive is a naive bin-packing: assign each workload to the smallest VM that fits.
-func BinPackWorkloadsNaive(workloads WorkloadSet, candidates []AzureInstanceSpec) PackingResult {
-	var result PackingResult
-	for _, w := range workloads {
-		// Find the smallest VM that fits
-		var best AzureInstanceSpec
-		bestFound := false
-		for _, vm := range candidates {
-			if vm.VCpus >= w.CPURequirements && vm.MemoryGiB >= w.MemoryRequirements {
-				if !bestFound || (vm.VCpus < best.VCpus || (vm.VCpus == best.VCpus && vm.MemoryGiB < best.MemoryGiB)) {
-					best = vm
-					bestFound = true
-				}
+/*
+simulate packs workloads with the new algorithm and, as the naive baseline, with
+BinPackWorkloadsNaive, one VM per workload, and summarizes both runs. It first warns about the
+workloads no candidate can run (see PrecheckWorkloads), or fails when cfg.StrictRequirements is
+set, and with a quota checks CheckQuotaFeasibility and warns about shortfalls, or fails when
+cfg.StrictQuota is set.
+*/
+func simulate(workloads WorkloadSet, skus []AzureInstanceSpec, cfg Config) (SimulationResult, SimulationResult, error) {
+	if err := cfg.checkDisabledFilters(); err != nil {
+		return SimulationResult{}, SimulationResult{}, err
+	}
+	original := workloads
+	workloads = SplitOversized(cfg.Overcommit.Apply(cfg.Basis.Apply(workloads)), cfg.SplitMaxCPU, cfg.SplitMaxMemoryGiB)
+	var excluded []string
+	if cfg.QuotaFamiliesOnly {
+		skus, excluded = AllowedFamiliesFromQuota(skus, cfg.Quota)
+		for _, f := range excluded {
+			cfg.printf("Excluded family %s: no quota\n", f)
+		}
+	}
+	if doomed := precheck(workloads, skus, cfg.filters()); len(doomed) > 0 {
+		if cfg.StrictRequirements {
+			return SimulationResult{}, SimulationResult{}, fmt.Errorf("%d workloads can never be packed, e.g. %s", len(doomed), doomed[0])
+		}
+		cfg.printf("Warning: %d workloads can never be packed:\n", len(doomed))
+		for i, d := range doomed {
+			if i == maxDoomedPrinted {
+				cfg.printf("  ... and %d more\n", len(doomed)-i)
+				break
 			}
+			cfg.printf("  %s\n", d)
 		}
-		if bestFound {
-			result.VMs = append(result.VMs, PackedVM{
-				InstanceType: best,
-				Workloads:    []WorkloadProfile{w},
-			})
-		}
-	}
-	return result
-}
-
-// TotalCost computes the total cost per hour for a packing result.
-func TotalCost(vms []PackedVM) float64 {
-	var sum float64
-	for _, vm := range vms {
-		sum += vm.InstanceType.PricePerHour
 	}
-	return sum
-}
-
-// AverageUtilization computes average CPU and memory utilization for a packing result.
-func AverageUtilization(vms []PackedVM) (cpuUtil, memUtil float64) {
-	var totalCPU, usedCPU float64
-	var totalMem, usedMem float64
-	for _, vm := range vms {
-		totalCPU += float64(vm.InstanceType.VCpus)
-		totalMem += vm.InstanceType.MemoryGiB
-		for _, w := range vm.Workloads {
-			usedCPU += float64(w.CPURequirements)
-			usedMem += w.MemoryRequirements
+	if shortfalls := CheckQuotaFeasibility(workloads, skus, cfg.Quota); len(shortfalls) > 0 {
+		if cfg.StrictQuota {
+			return SimulationResult{}, SimulationResult{}, fmt.Errorf("quota too small: %s", shortfalls[0])
+		}
+		for _, s := range shortfalls {
+			cfg.printf("Warning: quota too small: %s\n", s)
 		}
 	}
-	if totalCPU > 0 {
-		cpuUtil = usedCPU / totalCPU * 100
+	for _, w := range GPUTypeWarnings(skus) {
+		cfg.printf("Warning: %s\n", w)
 	}
-	if totalMem > 0 {
-		memUtil = usedMem / totalMem * 100
+	if cfg.Approx && !cfg.approximable() {
+		cfg.printf("Warning: approximate packing does not support quotas, limits or capacity reservations; packing exactly\n")
 	}
-	return
-}
-
-type SimulationResult struct {
-	VMsUsed   int
-	TotalCost float64
-	AvgCPU    float64
-	AvgMem    float64
-}
-
-// QuotaMap maps VM family to max vCPUs allowed.
-type QuotaMap map[string]int
-
-// LoadQuota loads a quota.json file mapping family to max vCPUs.
-func LoadQuota(path string) (QuotaMap, error) {
-	if path == "" {
-		return nil, nil
-	}
-	data, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
+	if cfg.Weights != nil && cfg.ScoreVersion == ScoreVersionLegacy {
+		cfg.printf("Warning: the legacy score's cost term outweighs the other scoring weights; use the normalized score version\n")
 	}
-	var q QuotaMap
-	if err := json.Unmarshal(data, &q); err != nil {
-		return nil, err
+	cfg.printf("Simulating bin-packing with new algorithm...\n")
+	result := packTimed(workloads, skus, cfg)
+	cfg.printf("Simulating bin-packing with naive algorithm...\n")
+	naiveStart := time.Now()
+	naive := cfg.Summarize(BinPackWorkloadsNaive(workloads, skus))
+	naive.Timing = TimingReport{PackingTime: time.Since(naiveStart)}
+	cfg.printf("  packed in %v\n", naive.Timing.PackingTime)
+	result.ExcludedFamilies, naive.ExcludedFamilies = excluded, excluded
+	if cpu, mem := peakLimitOvercommit(result.VMs); cpu > 0 || mem > 0 {
+		cfg.printf("Limit overcommit (%s): up to %.2fx CPU and %.2fx memory per VM\n", cfg.Overcommit, cpu, mem)
 	}
-	return q, nil
-}
-
-// BinPackWorkloadsWithQuota is like BinPackWorkloads but enforces vCPU quotas per family.
-func BinPackWorkloadsWithQuota(workloads WorkloadSet, candidates []AzureInstanceSpec, strategy SelectionStrategy, quota QuotaMap) PackingResult {
-	// Sort workloads by descending CPU+Memory demand (naive, can be improved)
-	sorted := make(WorkloadSet, len(workloads))
-	copy(sorted, workloads)
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[j].CPURequirements+int(sorted[j].MemoryRequirements) > sorted[i].CPURequirements+int(sorted[i].MemoryRequirements) {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
+	if bases := cfg.Basis.comparedBases(); len(bases) > 0 {
+		result.BasisComparison = CompareBases(original, skus, cfg, bases...)
+		cfg.printf("Packing basis comparison:\n")
+		for _, c := range result.BasisComparison {
+			cfg.printf("  %-12s %4d VMs, %.4f/h, %d unpacked, %.1f%% savings\n", c.Basis, c.VMsUsed, c.TotalCost, c.Unpacked, c.SavingsPercent)
 		}
 	}
-
-	var result PackingResult
-	unpacked := make([]bool, len(sorted))
-	usedVCpus := make(map[string]int)
-
-	for {
-		// Find the next workload not yet packed
-		nextIdx := -1
-		for i, packed := range unpacked {
-			if !packed {
-				nextIdx = i
-				break
-			}
-		}
-		if nextIdx == -1 {
-			break // all packed
+	if len(cfg.Sensitivity) > 0 {
+		result.Sensitivity = cfg.sensitivity(workloads, skus, cfg.Sensitivity)
+		cfg.printf("Price sensitivity:\n")
+		for _, p := range result.Sensitivity {
+			cfg.printf("  +/-%g%%: SKU mix changed in %d of %d trials, cost %.4f/h +/- %.4f (baseline %.4f/h)\n",
+				p.PerturbationPercent, p.MixChanges, p.Trials, p.MeanCost, p.CostStdDev, p.BaselineCost)
 		}
-		// For this workload, select the best instance type
-		workload := sorted[nextIdx]
-		bestVM, _ := selectWithStrategy(candidates, workload, strategy)
-		if bestVM.Name == "" {
-			break // no suitable VM found
-		}
-		// Check quota for this family
-		fam := bestVM.Family
-		if quota != nil && quota[fam] > 0 && usedVCpus[fam]+bestVM.VCpus > quota[fam] {
-			// Can't use this family anymore, remove from candidates and retry
-			var newCandidates []AzureInstanceSpec
-			for _, c := range candidates {
-				if c.Family != fam {
-					newCandidates = append(newCandidates, c)
-				}
-			}
-			candidates = newCandidates
-			continue
-		}
-		// Try to pack as many workloads as possible onto this VM
-		var packed []WorkloadProfile
-		remainingCPU := bestVM.VCpus
-		remainingMem := bestVM.MemoryGiB
-		for i, w := range sorted {
-			if unpacked[i] {
-				continue
-			}
-			if w.CPURequirements <= remainingCPU && w.MemoryRequirements <= remainingMem {
-				packed = append(packed, w)
-				remainingCPU -= w.CPURequirements
-				remainingMem -= w.MemoryRequirements
-				unpacked[i] = true
-			}
-		}
-		usedVCpus[fam] += bestVM.VCpus
-		result.VMs = append(result.VMs, PackedVM{
-			InstanceType: bestVM,
-			Workloads:    packed,
-		})
 	}
-	return result
-}
-
-// RunTraceSimulationWithQuota runs the simulation with an optional quota file.
-func RunTraceSimulationWithQuota(trace TraceSource, skuPath string, maxRows int, quotaPath string) (SimulationResult, SimulationResult, error) {
-	if trace == "custom" {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("custom trace not supported here, use RunCustomWorkloadSimulationWithQuota")
-	}
-	cacheDir := ".trace_cache"
-	os.MkdirAll(cacheDir, 0755)
-	tracePath, err := DownloadTrace(trace, cacheDir)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("download trace: %w", err)
-	}
-	fmt.Printf("Parsing workloads from %s...\n", tracePath)
-	workloads, err := LoadWorkloadsFromTrace(tracePath, trace, maxRows)
-	if err != nil {
-		// Check for XML error (e.g. bucket not found or download failed)
-		if strings.Contains(err.Error(), "<?xml") || strings.Contains(err.Error(), "<Error>") {
-			return SimulationResult{}, SimulationResult{}, fmt.Errorf("parse trace: trace file is not a valid CSV (possible download error or missing bucket): %w", err)
+	if cfg.LoadProfile != nil {
+		lp, err := SimulateLoadProfile(workloads, skus, *cfg.LoadProfile, cfg)
+		if err != nil {
+			return SimulationResult{}, SimulationResult{}, err
 		}
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("parse trace: %w", err)
+		result.LoadProfile = &lp
+		result.Events = lp.Events
+		cfg.printf("Load profile: %d to %d VMs, %.4f per day (%.4f without scaling, %.1f%% savings)\n",
+			lp.TroughVMs, lp.PeakVMs, lp.DailyCost, lp.StaticDailyCost, lp.SavingsPercent())
 	}
-	fmt.Printf("Loading Azure instance specs from %s...\n", skuPath)
-	skus, err := LoadAzureInstanceSpecs(skuPath)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load skus: %w", err)
+	if cfg.ArchComparison {
+		cmp := RunArchComparison(workloads, skus, cfg)
+		result.ArchComparison = &cmp
+		cfg.printf("Arch comparison: %.4f/h amd64-only, %.4f/h multi-arch (%.1f%% savings), %d workloads need arm64 images\n",
+			cmp.AMD64.TotalCost, cmp.MultiArch.TotalCost, cmp.SavingsPercent, len(cmp.Migrations))
 	}
-	quota, err := LoadQuota(quotaPath)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load quota: %w", err)
+	if cfg.Baseline != nil {
+		cmp, err := CompareToBaseline(cfg.Baseline, workloads, skus, simulatedVMs(result, skus), cfg)
+		if err != nil {
+			return SimulationResult{}, SimulationResult{}, err
+		}
+		result.Baseline = &cmp
+		cfg.printf("Baseline fleet: %d VMs, %.4f/h; simulated %d VMs, %.4f/h (%.1f%% savings)\n",
+			cmp.Baseline.VMs, cmp.Baseline.CostPerHour, cmp.Simulated.VMs, cmp.Simulated.CostPerHour, cmp.SavingsPercent)
+		if !cmp.Fits() {
+			cfg.printf("Warning: %d of %d workloads do not fit on the baseline fleet\n", cmp.Unplaced, cmp.Workloads)
+		}
 	}
-	fmt.Printf("Simulating bin-packing with new algorithm...\n")
-	result := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota)
-	fmt.Printf("Simulating bin-packing with naive algorithm...\n")
-	naive := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota) // For naive, could use BinPackWorkloadsNaive with quota logic if desired
-	cpuU, memU := AverageUtilization(result.VMs)
-	cpuU2, memU2 := AverageUtilization(naive.VMs)
-	return SimulationResult{
-			VMsUsed:   len(result.VMs),
-			TotalCost: TotalCost(result.VMs),
-			AvgCPU:    cpuU,
-			AvgMem:    memU,
-		}, SimulationResult{
-			VMsUsed:   len(naive.VMs),
-			TotalCost: TotalCost(naive.VMs),
-			AvgCPU:    cpuU2,
-			AvgMem:    memU2,
-		}, nil
+	return result, naive, nil
 }
 
-// RunCustomWorkloadSimulationWithQuota loads a custom workload JSON file and runs the simulation with quota.
-func RunCustomWorkloadSimulationWithQuota(workloadsFile string, skuPath string, quotaPath string) (SimulationResult, SimulationResult, error) {
-	data, err := ioutil.ReadFile(workloadsFile)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("read workloads: %w", err)
-	}
-	var workloads []WorkloadProfile
-	if err := json.Unmarshal(data, &workloads); err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("parse workloads: %w", err)
-	}
-	fmt.Printf("Loaded %d custom workloads from %s\n", len(workloads), workloadsFile)
-	fmt.Printf("Loading Azure instance specs from %s...\n", skuPath)
-	skus, err := LoadAzureInstanceSpecs(skuPath)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load skus: %w", err)
-	}
-	quota, err := LoadQuota(quotaPath)
-	if err != nil {
-		return SimulationResult{}, SimulationResult{}, fmt.Errorf("load quota: %w", err)
-	}
-	fmt.Printf("Simulating bin-packing with new algorithm...\n")
-	result := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota)
-	fmt.Printf("Simulating bin-packing with naive algorithm...\n")
-	naive := BinPackWorkloadsWithQuota(workloads, skus, StrategyGeneralPurpose, quota)
-	cpuU, memU := AverageUtilization(result.VMs)
-	cpuU2, memU2 := AverageUtilization(naive.VMs)
-	return SimulationResult{
-			VMsUsed:   len(result.VMs),
-			TotalCost: TotalCost(result.VMs),
-			AvgCPU:    cpuU,
-			AvgMem:    memU,
-		}, SimulationResult{
-			VMsUsed:   len(naive.VMs),
-			TotalCost: TotalCost(naive.VMs),
-			AvgCPU:    cpuU2,
-			AvgMem:    memU2,
-		}, nil
+// packTimed packs workloads and summarizes the result together with its TimingReport.
+func packTimed(workloads WorkloadSet, skus []AzureInstanceSpec, cfg Config) SimulationResult {
+	stats := &ScoreCacheStats{}
+	cfg.ScoreCacheStats = stats
+	start := time.Now()
+	result := cfg.packer()(workloads, skus, cfg)
+	elapsed := time.Since(start)
+	sim := cfg.Summarize(result)
+	sim.Timing = TimingReport{PackingTime: elapsed, ScoreCacheHits: stats.Hits(), ScoreCacheMisses: stats.Misses(), SelectionCacheHits: stats.PersistedHits()}
+	cfg.printf("  packed in %v (score cache: %d hits, %d misses, %.1f%% hit rate", elapsed, stats.Hits(), stats.Misses(), sim.Timing.ScoreCacheHitRate()*100)
+	if cfg.SelectionCache != nil {
+		cfg.printf(", %d from the selection cache", stats.PersistedHits())
+	}
+	cfg.printf(")\n")
+	if cfg.Approx && cfg.approximable() {
+		approx := NewApproxReport(result, skus, cfg)
+		sim.Approx = &approx
+		cfg.printf("  approximated %d workloads as %d shape groups, at most %.4f/h above exact packing\n", approx.Workloads, approx.Groups, approx.ErrorBound)
+	}
+	return sim
 }
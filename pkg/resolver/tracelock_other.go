@@ -0,0 +1,26 @@
+//go:build !unix
+
+package resolver
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// lockFile blocks until it creates path exclusively and returns the function removing it.
+// Without flock a lock file left behind by a killed process must be removed by hand.
+func lockFile(path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,139 @@
+package resolver
+
+import "fmt"
+
+// ReasonCapacityUnavailable is reported for workloads whose suitable instance types all failed
+// to provision in Config.Availability, or are excluded after failing.
+const ReasonCapacityUnavailable = "insufficient capacity for every suitable instance type"
+
+// DefaultUnavailabilityTTLSeconds is how long a failed SKU is excluded when
+// AvailabilityModel.TTLSeconds is 0, as long as Karpenter caches insufficient capacity errors.
+const DefaultUnavailabilityTTLSeconds = 180
+
+/*
+AvailabilityModel simulates provisioning failures in SimulateArrivals: provisioning a VM of a
+SKU fails during its outages. Like Karpenter after an insufficient capacity error, the failed
+SKU is then excluded from selection for TTLSeconds of simulated time (see UnavailabilityCache)
+and the next best instance type is tried.
+*/
+type AvailabilityModel struct {
+	Outages []CapacityOutage
+	// TTLSeconds is how long a failed SKU stays excluded; 0 means
+	// DefaultUnavailabilityTTLSeconds.
+	TTLSeconds float64
+}
+
+/*
+CapacityOutage is a window of simulated time, from StartSeconds up to EndSeconds, in which VMs
+of SKU cannot be provisioned in Zone. An empty Zone covers every zone. A workload not pinned to
+a zone only fails when all of the SKU's zones are out.
+*/
+type CapacityOutage struct {
+	SKU          string
+	Zone         string
+	StartSeconds float64
+	EndSeconds   float64
+}
+
+// ExclusionEvent records a SKU excluded after a simulated provisioning failure.
+type ExclusionEvent struct {
+	AtSeconds    float64
+	SKU          string
+	Zone         string // empty when the SKU was excluded in every zone
+	UntilSeconds float64
+	Workload     string // ID of the workload whose VM failed to provision
+}
+
+func (e ExclusionEvent) String() string {
+	zone := e.Zone
+	if zone == "" {
+		zone = "any zone"
+	}
+	return fmt.Sprintf("t=%gs %s in %s excluded until t=%gs after provisioning for %q failed", e.AtSeconds, e.SKU, zone, e.UntilSeconds, e.Workload)
+}
+
+// ttl returns the exclusion TTL in seconds.
+func (m AvailabilityModel) ttl() float64 {
+	if m.TTLSeconds > 0 {
+		return m.TTLSeconds
+	}
+	return DefaultUnavailabilityTTLSeconds
+}
+
+// fails reports whether provisioning sku in zone fails at now; with an empty zone, whether
+// it fails in every zone the SKU is offered in.
+func (m AvailabilityModel) fails(sku AzureInstanceSpec, zone string, now float64) bool {
+	out := func(z string) bool {
+		for _, o := range m.Outages {
+			if o.SKU == sku.Name && (o.Zone == "" || o.Zone == z) && now >= o.StartSeconds && now < o.EndSeconds {
+				return true
+			}
+		}
+		return false
+	}
+	if zone != "" || len(sku.AvailabilityZones) == 0 {
+		return out(zone)
+	}
+	for _, z := range sku.AvailabilityZones {
+		if !out(z) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+UnavailabilityCache is the exclusion list of SKUs that failed to provision, each entry expiring
+TTL seconds of simulated time after the failure. An entry without a zone excludes the SKU in
+every zone.
+*/
+type UnavailabilityCache struct {
+	ttl     float64
+	expires map[offering]float64
+}
+
+// offering is a SKU in a zone, or in any zone when zone is empty.
+type offering struct {
+	sku, zone string
+}
+
+// NewUnavailabilityCache returns an empty cache whose entries expire after ttlSeconds.
+func NewUnavailabilityCache(ttlSeconds float64) *UnavailabilityCache {
+	return &UnavailabilityCache{ttl: ttlSeconds, expires: make(map[offering]float64)}
+}
+
+// MarkUnavailable excludes sku in zone from now until now plus the TTL, which it returns.
+func (c *UnavailabilityCache) MarkUnavailable(sku, zone string, now float64) float64 {
+	until := now + c.ttl
+	c.expires[offering{sku, zone}] = until
+	return until
+}
+
+// IsUnavailable reports whether sku is excluded in zone at now, or in every zone.
+func (c *UnavailabilityCache) IsUnavailable(sku, zone string, now float64) bool {
+	if now < c.expires[offering{sku, ""}] {
+		return true
+	}
+	return zone != "" && now < c.expires[offering{sku, zone}]
+}
+
+// available returns the candidates not excluded for zone at now; candidates itself when none
+// is, so the score cache keeps its entries.
+func (c *UnavailabilityCache) available(candidates []AzureInstanceSpec, zone string, now float64) []AzureInstanceSpec {
+	var kept []AzureInstanceSpec
+	for i, vm := range candidates {
+		if !c.IsUnavailable(vm.Name, zone, now) {
+			if kept != nil {
+				kept = append(kept, vm)
+			}
+			continue
+		}
+		if kept == nil {
+			kept = append(make([]AzureInstanceSpec, 0, len(candidates)), candidates[:i]...)
+		}
+	}
+	if kept == nil {
+		return candidates
+	}
+	return kept
+}
@@ -0,0 +1,62 @@
+package resolver
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSimulateArrivalsUnavailability(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2"}},
+		{Name: "Standard_D4as_v5", Family: "DASv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.3, AvailabilityZones: []string{"1", "2"}},
+	}
+	// Each workload fills a VM, so every arrival provisions one
+	var workloads WorkloadSet
+	for _, at := range []float64{0, 50, 150, 1200} {
+		workloads = append(workloads, WorkloadProfile{Name: fmt.Sprintf("at-%g", at), CPURequirements: 4, MemoryRequirements: 8, ArrivalSeconds: at})
+	}
+	cfg := Config{Availability: &AvailabilityModel{
+		Outages:    []CapacityOutage{{SKU: "Standard_D4s_v5", StartSeconds: 0, EndSeconds: 1000}},
+		TTLSeconds: 100,
+	}}
+	result := SimulateArrivals(workloads, candidates, cfg)
+	var skus []string
+	for _, vm := range result.Packing.VMs {
+		skus = append(skus, vm.InstanceType.Name)
+	}
+	// t=0 fails and excludes D4s until 100; t=50 skips it without an attempt; t=150 retries it
+	// and fails again; t=1200 retries it after the outage and succeeds
+	want := []string{"Standard_D4as_v5", "Standard_D4as_v5", "Standard_D4as_v5", "Standard_D4s_v5"}
+	if fmt.Sprint(skus) != fmt.Sprint(want) {
+		t.Errorf("expected VMs %v, got %v", want, skus)
+	}
+	if len(result.Exclusions) != 2 {
+		t.Fatalf("expected 2 exclusions, got %+v", result.Exclusions)
+	}
+	if e := result.Exclusions[1]; e.AtSeconds != 150 || e.UntilSeconds != 250 || e.SKU != "Standard_D4s_v5" || e.Workload != "at-150" {
+		t.Errorf("expected D4s excluded at t=150 until t=250 for at-150, got %+v", e)
+	}
+
+	// Without an alternative the workload is unpacked while the SKU is excluded
+	result = SimulateArrivals(workloads[:2], candidates[:1], cfg)
+	if len(result.Packing.Unpacked) != 2 || result.Packing.Unpacked[1].Reason != ReasonCapacityUnavailable {
+		t.Errorf("expected both workloads unpacked with %q, got %+v", ReasonCapacityUnavailable, result.Packing.Unpacked)
+	}
+}
+
+func TestSimulateArrivalsUnavailabilityZones(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192, AvailabilityZones: []string{"1", "2"}}}
+	cfg := Config{Availability: &AvailabilityModel{Outages: []CapacityOutage{{SKU: "Standard_D4s_v5", Zone: "1", EndSeconds: 1000}}}}
+	workloads := WorkloadSet{
+		{Name: "pinned", CPURequirements: 4, MemoryRequirements: 8, Zone: "1"},
+		{Name: "anywhere", CPURequirements: 4, MemoryRequirements: 8},
+		{Name: "pinned-2", CPURequirements: 4, MemoryRequirements: 8, Zone: "2"},
+	}
+	result := SimulateArrivals(workloads, candidates, cfg)
+	if len(result.Packing.VMs) != 2 || len(result.Packing.Unpacked) != 1 || result.Packing.Unpacked[0].Workload.Name != "pinned" {
+		t.Fatalf("expected only the workload pinned to zone 1 unpacked, got %+v", result.Packing)
+	}
+	if len(result.Exclusions) != 1 || result.Exclusions[0].Zone != "1" || result.Exclusions[0].UntilSeconds != DefaultUnavailabilityTTLSeconds {
+		t.Errorf("expected D4s excluded in zone 1 for the default TTL, got %+v", result.Exclusions)
+	}
+}
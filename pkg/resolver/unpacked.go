@@ -0,0 +1,67 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNothingPacked is wrapped by the errors of CheckPacked.
+var ErrNothingPacked = errors.New("no workload was packed")
+
+// CheckPacked returns an error when result has workloads but packed none of them, listing why,
+// so that an empty packing is not mistaken for a free one. It returns nil otherwise.
+func CheckPacked(result PackingResult) error {
+	if len(result.VMs) > 0 || len(result.Unpacked) == 0 {
+		return nil
+	}
+	reasons := make([]string, len(result.Unpacked))
+	for i, u := range result.Unpacked {
+		reasons[i] = u.Reason
+	}
+	return nothingPacked(reasons)
+}
+
+// CheckPacked is CheckPacked for a summarized result.
+func (r SimulationResult) CheckPacked() error {
+	if r.VMsUsed > 0 || r.Unpacked == 0 {
+		return nil
+	}
+	var reasons []string
+	for _, d := range r.Workloads {
+		if d.VM < 0 {
+			reasons = append(reasons, d.UnpackedReason)
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = make([]string, r.Unpacked) // detail not recorded
+	}
+	return nothingPacked(reasons)
+}
+
+// nothingPacked returns ErrNothingPacked with the unpacked reasons, most common first.
+func nothingPacked(reasons []string) error {
+	counts := make(map[string]int)
+	for _, r := range reasons {
+		if r == "" {
+			r = "unknown reason"
+		}
+		counts[r]++
+	}
+	distinct := make([]string, 0, len(counts))
+	for r := range counts {
+		distinct = append(distinct, r)
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		if counts[distinct[i]] != counts[distinct[j]] {
+			return counts[distinct[i]] > counts[distinct[j]]
+		}
+		return distinct[i] < distinct[j]
+	})
+	parts := make([]string, len(distinct))
+	for i, r := range distinct {
+		parts[i] = fmt.Sprintf("%s (%d)", r, counts[r])
+	}
+	return fmt.Errorf("%w (%d workloads): %s", ErrNothingPacked, len(reasons), strings.Join(parts, ", "))
+}
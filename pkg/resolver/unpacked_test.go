@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadAzureInstanceSpecsEmpty(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{"empty.json": "[]", "dataset.json": `{"Currency": "EUR", "SKUs": []}`} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadAzureInstanceSpecs(path); !errors.Is(err, ErrNoSKUs) {
+			t.Errorf("%s: expected ErrNoSKUs, got %v", name, err)
+		}
+	}
+}
+
+func TestBinPackWorkloadsNoInstanceTypes(t *testing.T) {
+	workloads := WorkloadSet{{Name: "web", CPURequirements: 2, MemoryRequirements: 4}, {Name: "db", CPURequirements: 4, MemoryRequirements: 16}}
+	result := BinPackWorkloads(workloads, nil, StrategyGeneralPurpose)
+	if len(result.VMs) != 0 || len(result.Unpacked) != 2 {
+		t.Fatalf("expected every workload unpacked, got %+v", result)
+	}
+	for _, u := range result.Unpacked {
+		if u.Reason != ReasonNoInstanceTypes {
+			t.Errorf("expected %q, got %q", ReasonNoInstanceTypes, u.Reason)
+		}
+	}
+	err := CheckPacked(result)
+	if !errors.Is(err, ErrNothingPacked) || !strings.Contains(err.Error(), ReasonNoInstanceTypes+" (2)") {
+		t.Errorf("expected a nothing-packed error naming the reason, got %v", err)
+	}
+}
+
+func TestCheckPackedImpossibleConstraints(t *testing.T) {
+	// Every workload needs a GPU or a zone the catalog does not have, as with a wrong-region SKU file.
+	skus := []AzureInstanceSpec{{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2, AvailabilityZones: []string{"1", "2", "3"}}}
+	workloads := WorkloadSet{
+		{Name: "train", CPURequirements: 4, MemoryRequirements: 16, GPURequirements: 1},
+		{Name: "web", CPURequirements: 2, MemoryRequirements: 4, Zone: "4"},
+	}
+	cfg := Config{Strategy: StrategyGeneralPurpose}
+	result := BinPackWorkloadsWithConfig(workloads, skus, cfg)
+	err := CheckPacked(result)
+	if !errors.Is(err, ErrNothingPacked) || !strings.Contains(err.Error(), ReasonNoCandidates+" (2)") {
+		t.Errorf("expected every workload to be filtered out, got %v", err)
+	}
+	sim := cfg.Summarize(result)
+	if sim.VMsUsed != 0 || sim.TotalCost != 0 {
+		t.Fatalf("expected an empty packing, got %+v", sim)
+	}
+	if err := sim.CheckPacked(); !errors.Is(err, ErrNothingPacked) || !strings.Contains(err.Error(), ReasonNoCandidates) {
+		t.Errorf("expected the summary to report nothing packed, got %v", err)
+	}
+
+	if err := CheckPacked(BinPackWorkloads(WorkloadSet{{CPURequirements: 1, MemoryRequirements: 1}}, skus, StrategyGeneralPurpose)); err != nil {
+		t.Errorf("expected a packed workload to pass, got %v", err)
+	}
+	if err := CheckPacked(PackingResult{}); err != nil {
+		t.Errorf("expected no workloads to pass, got %v", err)
+	}
+}
+
+func TestBinPackWorkloadsNaiveUnpacked(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	workloads := WorkloadSet{{Name: "web", CPURequirements: 2, MemoryRequirements: 4}, {Name: "huge", CPURequirements: 64, MemoryRequirements: 512}}
+	for name, result := range map[string]PackingResult{
+		"new":   BinPackWorkloads(workloads, candidates, StrategyGeneralPurpose),
+		"naive": BinPackWorkloadsNaive(workloads, candidates),
+	} {
+		if len(result.VMs) != 1 || len(result.Unpacked) != 1 || result.Unpacked[0].Workload.Name != "huge" || result.Unpacked[0].Reason == "" {
+			t.Errorf("%s: expected web packed and huge unpacked with a reason, got %d VMs and %+v", name, len(result.VMs), result.Unpacked)
+		}
+	}
+	if naive := BinPackWorkloadsNaive(workloads, candidates); naive.Unpacked[0].Reason != ReasonNoCandidates {
+		t.Errorf("expected the naive baseline to report %q, got %q", ReasonNoCandidates, naive.Unpacked[0].Reason)
+	}
+	if result := BinPackWorkloadsNaive(workloads, nil); len(result.Unpacked) != 2 || result.Unpacked[0].Reason != ReasonNoInstanceTypes {
+		t.Errorf("expected every workload unpacked with %q without candidates, got %+v", ReasonNoInstanceTypes, result.Unpacked)
+	}
+}
+
+// TestSimulateNaiveBaseline checks that the naive row of a simulation is BinPackWorkloadsNaive,
+// one VM per workload, rather than the main packer again.
+func TestSimulateNaiveBaseline(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "DSv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	workloads := WorkloadSet{
+		{Name: "a", CPURequirements: 1, MemoryRequirements: 2},
+		{Name: "b", CPURequirements: 1, MemoryRequirements: 2},
+		{Name: "c", CPURequirements: 1, MemoryRequirements: 2},
+		{Name: "huge", CPURequirements: 64, MemoryRequirements: 512},
+	}
+	result, naive, err := simulate(workloads, candidates, Config{progress: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.VMsUsed != 1 || naive.VMsUsed != 3 {
+		t.Errorf("expected 1 VM packed and 3 for the naive baseline, got %d and %d", result.VMsUsed, naive.VMsUsed)
+	}
+	if result.Unpacked != 1 || naive.Unpacked != 1 {
+		t.Errorf("expected huge unpacked in both rows, got %d and %d", result.Unpacked, naive.Unpacked)
+	}
+}
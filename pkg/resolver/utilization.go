@@ -0,0 +1,56 @@
+package resolver
+
+// Utilization is the percentage of provisioned capacity requested by real workloads, per
+// resource. Each dimension only counts VMs that have that resource: GPU utilization is over
+// GPU-bearing VMs, storage over VMs with a known StorageGiB and pod slots over VMs with a
+// known MaxPods. A dimension no VM has is 0.
+type Utilization struct {
+	CPU      float64
+	Memory   float64
+	GPU      float64
+	Storage  float64
+	PodSlots float64
+}
+
+// AverageUtilizationV2 is AverageUtilization extended with GPU, storage and pod-slot
+// utilization. Headroom buffer workloads are not counted as used capacity.
+func AverageUtilizationV2(vms []PackedVM) Utilization {
+	var u Utilization
+	u.CPU, u.Memory = AverageUtilization(vms)
+	var totalGPU, usedGPU, totalStorage, usedStorage, totalPods, usedPods float64
+	for _, vm := range vms {
+		it := vm.InstanceType
+		for _, w := range vm.Workloads {
+			if w.Headroom {
+				continue
+			}
+			if it.GPUCount > 0 {
+				usedGPU += float64(w.gpusRequired())
+			}
+			if it.StorageGiB > 0 {
+				usedStorage += w.StorageRequirements
+			}
+			if it.MaxPods > 0 {
+				usedPods++
+			}
+		}
+		if it.GPUCount > 0 {
+			totalGPU += float64(it.GPUCount)
+		}
+		totalStorage += it.StorageGiB
+		if it.MaxPods > 0 {
+			totalPods += float64(it.MaxPods)
+		}
+	}
+	u.GPU = percentOf(usedGPU, totalGPU)
+	u.Storage = percentOf(usedStorage, totalStorage)
+	u.PodSlots = percentOf(usedPods, totalPods)
+	return u
+}
+
+func percentOf(used, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return used / total * 100
+}
@@ -0,0 +1,39 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAverageUtilizationV2(t *testing.T) {
+	vms := []PackedVM{
+		{
+			InstanceType: AzureInstanceSpec{VCpus: 6, MemoryGiB: 56, GPUCount: 2, StorageGiB: 100, MaxPods: 30},
+			Workloads: []WorkloadProfile{
+				{CPURequirements: 3, MemoryRequirements: 28, GPURequirements: 1, StorageRequirements: 50},
+				{CPURequirements: 1, MemoryRequirements: 1, GPURequirements: 1, Headroom: true},
+			},
+		},
+		{
+			// Not GPU-bearing: its capacity must not dilute GPU utilization.
+			InstanceType: AzureInstanceSpec{VCpus: 2, MemoryGiB: 8, MaxPods: 10},
+			Workloads:    []WorkloadProfile{{CPURequirements: 1, MemoryRequirements: 4}},
+		},
+	}
+	u := AverageUtilizationV2(vms)
+	cpu, mem := AverageUtilization(vms)
+	for name, c := range map[string][2]float64{
+		"cpu":      {u.CPU, cpu},
+		"memory":   {u.Memory, mem},
+		"gpu":      {u.GPU, 50},     // 1 of 2 GPUs; headroom and the non-GPU VM excluded
+		"storage":  {u.Storage, 50}, // 50 of 100 GiB
+		"podslots": {u.PodSlots, 5}, // 2 of 40 slots
+	} {
+		if math.Abs(c[0]-c[1]) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", name, c[1], c[0])
+		}
+	}
+	if got := AverageUtilizationV2(vms[1:]).GPU; got != 0 {
+		t.Errorf("expected 0 GPU utilization without GPU VMs, got %v", got)
+	}
+}
@@ -0,0 +1,132 @@
+package resolver
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+/*
+WarmPoolSpec describes standby VMs provisioned empty before the first arrival, so bursts are
+placed without waiting for new VMs. Count VMs of SKU are spread round-robin over Zones; with no
+Zones they are not pinned to a zone.
+*/
+type WarmPoolSpec struct {
+	SKU   string
+	Count int
+	Zones []string
+}
+
+// WarmPoolResult quantifies what a warm pool bought in SimulateArrivals.
+type WarmPoolResult struct {
+	VMs  int // warm VMs provisioned
+	Used int // warm VMs that received a workload
+	// Absorbed counts the workloads placed on warm VMs, without waiting for provisioning.
+	Absorbed int
+	// CostPerHour is what the pool costs while standing; StandingCost is that over the span
+	// from t=0 to the last arrival (see WorkloadProfile.ArrivalSeconds).
+	CostPerHour  float64
+	StandingCost float64
+}
+
+func (r WarmPoolResult) String() string {
+	return fmt.Sprintf("%d warm VMs (%d used) absorbed %d workloads for %.2f/hr, %.2f over the trace",
+		r.VMs, r.Used, r.Absorbed, r.CostPerHour, r.StandingCost)
+}
+
+// warmUp provisions the warm pool of s.cfg before any arrival. Nothing is provisioned when the
+// SKU is not a candidate, nor beyond Config.Limits or Config.Quota.
+func (s *arrivalSim) warmUp(workloads WorkloadSet) *WarmPoolResult {
+	spec := s.cfg.WarmPool
+	var sku AzureInstanceSpec
+	for _, c := range s.candidates {
+		if c.Name == spec.SKU {
+			sku = c
+			break
+		}
+	}
+	r := &WarmPoolResult{}
+	for i := 0; sku.Name != "" && i < spec.Count; i++ {
+		if s.limits.exceeded(sku) != "" || s.quota.exceeded(sku, false) != "" {
+			break
+		}
+		s.limits.add(sku)
+		s.quota.add(sku, false)
+		vm := arrivalVM{PackedVM: PackedVM{ID: VMID(len(s.vms) + 1), InstanceType: sku}, warm: true, free: s.cfg.vmCapacity(sku)}
+		s.events.add(0, EventProvision, vm.ID, "", sku.Name)
+		if len(spec.Zones) > 0 {
+			vm.zone = spec.Zones[i%len(spec.Zones)]
+		}
+		s.vms = append(s.vms, vm)
+		r.VMs++
+		r.CostPerHour += sku.PricePerHour
+	}
+	r.StandingCost = r.CostPerHour * arrivalSpan(workloads).Hours()
+	return r
+}
+
+// suits reports whether w may be placed on the warm VM vm: its SKU passes the filters and its
+// zone, if pinned, is the one w requires.
+func (s *arrivalSim) suits(vm arrivalVM, w WorkloadProfile) bool {
+	if !vm.warm {
+		return true
+	}
+	if w.Zone != "" && vm.zone != "" && w.Zone != vm.zone {
+		return false
+	}
+	return len(FilterInstanceTypes([]AzureInstanceSpec{vm.InstanceType}, w, s.cfg.filterFuncs()...)) > 0
+}
+
+// arrivalSpan returns the time from t=0 to the last arrival.
+func arrivalSpan(workloads WorkloadSet) time.Duration {
+	var last float64
+	for _, w := range workloads {
+		last = math.Max(last, w.ArrivalSeconds)
+	}
+	return time.Duration(last * float64(time.Second))
+}
+
+/*
+RecommendWarmPoolSize recommends how many VMs of sku to keep warm for workloads, from the
+burstiness of their arrivals (see WorkloadProfile.ArrivalSeconds): the CPU and memory arriving
+in the busiest window, less what arrives in an average window, in VMs of sku. When all
+workloads arrive within one window, as without arrival times, the pool covers them all. It
+returns 0 for steady arrivals.
+*/
+func RecommendWarmPoolSize(workloads WorkloadSet, sku AzureInstanceSpec, window time.Duration) int {
+	if len(workloads) == 0 || sku.VCpus == 0 || sku.MemoryGiB == 0 || window <= 0 {
+		return 0
+	}
+	sorted := make(WorkloadSet, len(workloads))
+	copy(sorted, workloads)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ArrivalSeconds < sorted[j].ArrivalSeconds })
+
+	var totalCPU, totalMem, cpu, mem, peakCPU, peakMem float64
+	start := 0
+	for _, w := range sorted {
+		totalCPU += float64(w.CPURequirements)
+		totalMem += w.MemoryRequirements
+	}
+	for _, w := range sorted {
+		cpu += float64(w.CPURequirements)
+		mem += w.MemoryRequirements
+		for sorted[start].ArrivalSeconds <= w.ArrivalSeconds-window.Seconds() {
+			cpu -= float64(sorted[start].CPURequirements)
+			mem -= sorted[start].MemoryRequirements
+			start++
+		}
+		peakCPU, peakMem = math.Max(peakCPU, cpu), math.Max(peakMem, mem)
+	}
+	// The average window; arrivals within one window are all burst
+	var avgCPU, avgMem float64
+	if windows := arrivalSpan(sorted).Seconds() / window.Seconds(); windows > 1 {
+		avgCPU, avgMem = totalCPU/windows, totalMem/windows
+	}
+	burstCPU, burstMem := peakCPU-avgCPU, peakMem-avgMem
+	vms := math.Max(burstCPU/float64(sku.VCpus), burstMem/sku.MemoryGiB)
+	if vms <= 0 {
+		return 0
+	}
+	return int(math.Ceil(vms - 1e-9))
+}
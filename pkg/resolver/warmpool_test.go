@@ -0,0 +1,76 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// burstyArrivals returns 12 workloads arriving within 11 seconds, then 8 arriving every 10 minutes.
+func burstyArrivals() WorkloadSet {
+	var workloads WorkloadSet
+	for i := 0; i < 12; i++ {
+		workloads = append(workloads, WorkloadProfile{CPURequirements: 2, MemoryRequirements: 4, ArrivalSeconds: float64(i)})
+	}
+	for i := 1; i <= 8; i++ {
+		workloads = append(workloads, WorkloadProfile{CPURequirements: 2, MemoryRequirements: 4, ArrivalSeconds: 10 + float64(i)*600})
+	}
+	return workloads
+}
+
+func TestSimulateArrivalsWarmPool(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2}}
+	workloads := burstyArrivals()
+
+	cold := SimulateArrivals(workloads, candidates, Config{})
+	if cold.Provisioned != 10 || cold.Instant != 10 || cold.WarmPool != nil {
+		t.Fatalf("expected every other workload to wait for one of 10 new VMs without a warm pool, got %d waited, %d instant", cold.Provisioned, cold.Instant)
+	}
+
+	size := RecommendWarmPoolSize(workloads, candidates[0], time.Minute)
+	if size != 6 {
+		t.Fatalf("expected a warm pool of 6 VMs for the 24 vCPU burst, got %d", size)
+	}
+	warm := SimulateArrivals(workloads, candidates, Config{WarmPool: &WarmPoolSpec{SKU: candidates[0].Name, Count: size}})
+	if warm.Provisioned != 4 || warm.Instant != 16 {
+		t.Errorf("expected the burst to be placed instantly and 4 VMs provisioned afterwards, got %d waited, %d instant", warm.Provisioned, warm.Instant)
+	}
+	p := warm.WarmPool
+	if p == nil || p.VMs != 6 || p.Used != 6 || p.Absorbed != 12 {
+		t.Fatalf("expected 6 used warm VMs absorbing the 12 burst workloads, got %+v", p)
+	}
+	if want := 6 * 0.2 * 4810.0 / 3600; math.Abs(p.StandingCost-want) > 1e-9 || math.Abs(p.CostPerHour-1.2) > 1e-9 {
+		t.Errorf("expected the pool to cost 1.20/hr and %.4f over the trace, got %+v", want, p)
+	}
+	if len(warm.Packing.VMs) != len(cold.Packing.VMs) {
+		t.Errorf("expected the warm pool to replace VMs rather than add to them, got %d and %d VMs", len(warm.Packing.VMs), len(cold.Packing.VMs))
+	}
+}
+
+func TestSimulateArrivalsWarmPoolZones(t *testing.T) {
+	candidates := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "Dsv5", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.2, AvailabilityZones: []string{"1", "2"}}}
+	workloads := WorkloadSet{{CPURequirements: 2, MemoryRequirements: 4, Zone: "2"}}
+	got := SimulateArrivals(workloads, candidates, Config{WarmPool: &WarmPoolSpec{SKU: candidates[0].Name, Count: 1, Zones: []string{"1"}}})
+	if got.WarmPool.Absorbed != 0 || got.Provisioned != 1 {
+		t.Errorf("expected a zone 2 workload to skip the zone 1 warm VM, got %+v", got.WarmPool)
+	}
+	got = SimulateArrivals(workloads, candidates, Config{WarmPool: &WarmPoolSpec{SKU: "Standard_E4s_v5", Count: 3}})
+	if got.WarmPool.VMs != 0 {
+		t.Errorf("expected no warm VMs of a SKU that is not a candidate, got %d", got.WarmPool.VMs)
+	}
+}
+
+func TestRecommendWarmPoolSize(t *testing.T) {
+	sku := AzureInstanceSpec{VCpus: 4, MemoryGiB: 16}
+	var steady WorkloadSet
+	for i := 0; i < 10; i++ {
+		steady = append(steady, WorkloadProfile{CPURequirements: 1, MemoryRequirements: 1, ArrivalSeconds: float64(i) * 60})
+	}
+	if got := RecommendWarmPoolSize(steady, sku, time.Minute); got != 0 {
+		t.Errorf("expected no warm pool for steady arrivals, got %d", got)
+	}
+	untimed := WorkloadSet{{CPURequirements: 3, MemoryRequirements: 1}, {CPURequirements: 3, MemoryRequirements: 1}}
+	if got := RecommendWarmPoolSize(untimed, sku, time.Minute); got != 2 {
+		t.Errorf("expected workloads without arrival times to be one burst of 2 VMs, got %d", got)
+	}
+}
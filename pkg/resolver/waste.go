@@ -0,0 +1,101 @@
+package resolver
+
+import (
+	"math"
+	"sort"
+)
+
+// WasteMode selects how idle CPU and memory are combined into a VM's idle fraction.
+type WasteMode string
+
+const (
+	WasteMax     WasteMode = "max"     // the larger of the idle CPU and idle memory fractions (default)
+	WasteCPU     WasteMode = "cpu"     // idle CPU fraction only
+	WasteMemory  WasteMode = "memory"  // idle memory fraction only
+	WasteAverage WasteMode = "average" // mean of the idle CPU and idle memory fractions
+)
+
+// topWastefulVMs is the number of VMs listed in WasteReport.TopVMs.
+const topWastefulVMs = 10
+
+// VMWaste is the idle capacity and its cost for one VM.
+type VMWaste struct {
+	Index             int // position of the VM in PackingResult.VMs
+	SKU               string
+	PricePerHour      float64
+	IdleCPU           float64 // fraction of vCPUs not requested by any workload
+	IdleMemory        float64 // fraction of memory not requested by any workload
+	WastedCostPerHour float64
+}
+
+// WasteReport translates idle capacity into dollars.
+type WasteReport struct {
+	TotalWastedCostPerHour float64
+	TopVMs                 []VMWaste // most wasteful VMs first, at most 10
+	// UnusedReservationCostPerHour is the cost of reserved slots no VM used, included in
+	// TotalWastedCostPerHour (see ReservationUsage.SlotPricePerHour).
+	UnusedReservationCostPerHour float64 `json:",omitempty"`
+}
+
+// ComputeWaste computes the wasted cost of a packing using WasteMax.
+func ComputeWaste(result PackingResult) WasteReport {
+	return ComputeWasteWithMode(result, WasteMax)
+}
+
+/*
+ComputeWasteWithMode computes, for each VM, the idle fraction of its capacity (combined per
+mode) multiplied by its hourly price, and sums it together with the cost of unused capacity
+reservation slots into TotalWastedCostPerHour.
+Headroom buffer workloads count as used capacity, since their cost is already reported
+separately as HeadroomCost.
+*/
+func ComputeWasteWithMode(result PackingResult, mode WasteMode) WasteReport {
+	var report WasteReport
+	all := make([]VMWaste, 0, len(result.VMs))
+	for i, vm := range result.VMs {
+		var cpu, mem float64
+		for _, w := range vm.Workloads {
+			cpu += float64(w.CPURequirements)
+			mem += w.MemoryRequirements
+		}
+		vw := VMWaste{
+			Index:        i,
+			SKU:          vm.InstanceType.Name,
+			PricePerHour: vm.InstanceType.PricePerHour,
+			IdleCPU:      idleFraction(cpu, float64(vm.InstanceType.VCpus)),
+			IdleMemory:   idleFraction(mem, vm.InstanceType.MemoryGiB),
+		}
+		var idle float64
+		switch mode {
+		case WasteCPU:
+			idle = vw.IdleCPU
+		case WasteMemory:
+			idle = vw.IdleMemory
+		case WasteAverage:
+			idle = (vw.IdleCPU + vw.IdleMemory) / 2
+		default:
+			idle = math.Max(vw.IdleCPU, vw.IdleMemory)
+		}
+		vw.WastedCostPerHour = idle * vw.PricePerHour
+		report.TotalWastedCostPerHour += vw.WastedCostPerHour
+		all = append(all, vw)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].WastedCostPerHour > all[j].WastedCostPerHour
+	})
+	if len(all) > topWastefulVMs {
+		all = all[:topWastefulVMs]
+	}
+	report.TopVMs = all
+	report.UnusedReservationCostPerHour = unusedReservationCost(result.Reservations)
+	report.TotalWastedCostPerHour += report.UnusedReservationCostPerHour
+	return report
+}
+
+// idleFraction returns the unused fraction of capacity, clamped to [0,1].
+func idleFraction(used, capacity float64) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	return math.Min(math.Max(1-used/capacity, 0), 1)
+}
@@ -0,0 +1,59 @@
+package resolver
+
+import (
+	"math"
+	"testing"
+)
+
+func wasteFixture() PackingResult {
+	return PackingResult{VMs: []PackedVM{
+		{
+			// 50% CPU and 25% memory used: idle 0.5 / 0.75.
+			InstanceType: AzureInstanceSpec{Name: "a", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.4},
+			Workloads:    []WorkloadProfile{{CPURequirements: 2, MemoryRequirements: 4}},
+		},
+		{
+			// Fully used CPU, half the memory: idle 0 / 0.5.
+			InstanceType: AzureInstanceSpec{Name: "b", VCpus: 2, MemoryGiB: 8, PricePerHour: 1.0},
+			Workloads:    []WorkloadProfile{{CPURequirements: 2, MemoryRequirements: 4}},
+		},
+	}}
+}
+
+func TestComputeWaste(t *testing.T) {
+	report := ComputeWaste(wasteFixture())
+	// max mode: 0.75*0.4 + 0.5*1.0
+	if math.Abs(report.TotalWastedCostPerHour-0.8) > 1e-9 {
+		t.Errorf("expected total wasted cost 0.8, got %v", report.TotalWastedCostPerHour)
+	}
+	if len(report.TopVMs) != 2 || report.TopVMs[0].SKU != "b" || report.TopVMs[1].SKU != "a" {
+		t.Fatalf("expected VMs ordered b, a by wasted cost, got %+v", report.TopVMs)
+	}
+	if a := report.TopVMs[1]; a.Index != 0 || math.Abs(a.IdleCPU-0.5) > 1e-9 || math.Abs(a.IdleMemory-0.75) > 1e-9 {
+		t.Errorf("unexpected idle fractions for VM a: %+v", a)
+	}
+
+	for mode, want := range map[WasteMode]float64{
+		WasteCPU:     0.5 * 0.4,
+		WasteMemory:  0.75*0.4 + 0.5*1.0,
+		WasteAverage: 0.625*0.4 + 0.25*1.0,
+	} {
+		if got := ComputeWasteWithMode(wasteFixture(), mode).TotalWastedCostPerHour; math.Abs(got-want) > 1e-9 {
+			t.Errorf("mode %s: expected %v, got %v", mode, want, got)
+		}
+	}
+}
+
+func TestComputeWaste_TopTen(t *testing.T) {
+	var result PackingResult
+	for i := 0; i < 15; i++ {
+		result.VMs = append(result.VMs, PackedVM{InstanceType: AzureInstanceSpec{VCpus: 2, MemoryGiB: 8, PricePerHour: float64(i + 1)}})
+	}
+	report := ComputeWaste(result)
+	if len(report.TopVMs) != 10 {
+		t.Fatalf("expected 10 VMs, got %d", len(report.TopVMs))
+	}
+	if report.TopVMs[0].Index != 14 || math.Abs(report.TotalWastedCostPerHour-120) > 1e-9 {
+		t.Errorf("expected the most expensive idle VM first and total 120, got %+v", report)
+	}
+}
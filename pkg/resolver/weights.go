@@ -0,0 +1,175 @@
+package resolver
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+/*
+ScoringWeights weighs the components of an instance type's score for a workload, each in [0,1]
+(see Config.Weights). The built-in strategies are fixed weights of the first twelve; Headroom
+and Size only matter to custom weights and presets. Under ScoreVersionNormalized, scores stay in
+[0,1] when the weights sum to 1.
+*/
+type ScoringWeights struct {
+	CPUFit       float64 // vCPUs covering the workload's request
+	MemoryFit    float64 // memory covering the workload's request
+	IOFit        float64 // disk capacity and performance covering the workload's (see ioFit)
+	NetworkFit   float64 // NIC bandwidth covering the workload's
+	Cost         float64 // cost efficiency, from the hourly price and the ScoreVersion
+	Fit          float64 // the worst of the CPU, memory and IO fits (see ComputeFit)
+	Availability float64 // offered in the workload's zone and preferred zone
+	GPU          float64 // GPU count, model and memory (see gpuFit)
+	EphemeralOS  float64 // ephemeral OS disk support, where required
+	NestedVirt   float64 // nested virtualization support, where required
+	Spot         float64 // spot support, where required
+	Confidential float64 // confidential computing support, where required
+	Headroom     float64 // share of the VM's vCPUs and memory the workload leaves free
+	Size         float64 // vCPUs on a log scale, up to sizeScoreVCPUs
+}
+
+// sizeScoreVCPUs is the vCPU count from which sizeScore is 1.
+const sizeScoreVCPUs = 64
+
+// headroomScore returns the share of vm's vCPUs and memory, whichever is less, left free by
+// the workload alone.
+func headroomScore(vm AzureInstanceSpec, workload WorkloadProfile) float64 {
+	used := max(share(float64(workload.CPURequirements), float64(vm.VCpus)), share(workload.MemoryRequirements, vm.MemoryGiB))
+	return min(max(1-used, 0), 1)
+}
+
+// sizeScore returns log2 of vm's vCPUs relative to sizeScoreVCPUs: 0 for one vCPU, 1 from
+// sizeScoreVCPUs up.
+func sizeScore(vm AzureInstanceSpec) float64 {
+	return min(math.Log2(float64(max(vm.VCpus, 1)))/math.Log2(sizeScoreVCPUs), 1)
+}
+
+// validate returns an error for negative weights or weights that are all zero.
+func (w ScoringWeights) validate() error {
+	if w == (ScoringWeights{}) {
+		return fmt.Errorf("scoring weights are all zero")
+	}
+	for name, v := range w.fields() {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("scoring weight %s is %v, expected a finite non-negative number", name, v)
+		}
+	}
+	return nil
+}
+
+// fields returns the weights by field name.
+func (w ScoringWeights) fields() map[string]float64 {
+	return map[string]float64{
+		"CPUFit": w.CPUFit, "MemoryFit": w.MemoryFit, "IOFit": w.IOFit, "NetworkFit": w.NetworkFit,
+		"Cost": w.Cost, "Fit": w.Fit, "Availability": w.Availability, "GPU": w.GPU,
+		"EphemeralOS": w.EphemeralOS, "NestedVirt": w.NestedVirt, "Spot": w.Spot, "Confidential": w.Confidential,
+		"Headroom": w.Headroom, "Size": w.Size,
+	}
+}
+
+// weightsFor returns the scoring weights for w: Weights when set, else those of the
+// configured strategy or w's class.
+func (c Config) weightsFor(w WorkloadProfile) ScoringWeights {
+	if c.Weights != nil {
+		return *c.Weights
+	}
+	return strategyWeights(c.strategyFor(w), w)
+}
+
+// WeightsPreset is named ScoringWeights shipped in the binary, with what they favour.
+type WeightsPreset struct {
+	Name    string
+	Intent  string
+	Weights ScoringWeights
+}
+
+// presetsJSON holds the presets ListPresets returns, by name.
+//
+//go:embed weights/presets.json
+var presetsJSON []byte
+
+/*
+ListPresets returns the scoring weight presets, sorted by name:
+
+  - cost-first: the cheapest instance type that fits, fit only breaking near-ties.
+  - balanced: mostly price, with some spare capacity for the workload to grow into.
+  - performance-first: the most spare vCPUs and memory beyond the requests, for bursty or
+    latency-sensitive workloads.
+  - consolidation: large instance types, so later workloads share few nodes.
+
+Every preset's weights sum to 1, and all but Cost, Headroom and Size weigh the same, so presets
+are comparable under ScoreVersionNormalized. Under ScoreVersionLegacy the cost term dwarfs the
+others and every preset selects much like cost-first.
+*/
+func ListPresets() []WeightsPreset {
+	var byName map[string]WeightsPreset
+	if err := json.Unmarshal(presetsJSON, &byName); err != nil {
+		panic(fmt.Sprintf("embedded weight presets: %v", err)) // covered by TestListPresets
+	}
+	presets := make([]WeightsPreset, 0, len(byName))
+	for name, p := range byName {
+		p.Name = name
+		presets = append(presets, p)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets
+}
+
+// presetNames returns the names of ListPresets.
+func presetNames() []string {
+	var names []string
+	for _, p := range ListPresets() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// PresetWeights returns the weights of the named preset.
+func PresetWeights(name string) (ScoringWeights, error) {
+	for _, p := range ListPresets() {
+		if p.Name == name {
+			return p.Weights, nil
+		}
+	}
+	return ScoringWeights{}, fmt.Errorf("unknown weights preset %q, expected one of %s", name, strings.Join(presetNames(), ", "))
+}
+
+/*
+LoadScoringWeights returns the weights of the named preset with the fields set in the JSON
+file at path overriding it, e.g. {"Cost": 0.6, "Headroom": 0.1} keeps the preset's other
+weights. Without a preset the file gives the weights in full, fields left out weighing 0. With
+neither it returns nil, so the strategies' weights apply. Unknown fields are rejected.
+*/
+func LoadScoringWeights(preset, path string) (*ScoringWeights, error) {
+	if preset == "" && path == "" {
+		return nil, nil
+	}
+	var weights ScoringWeights
+	if preset != "" {
+		w, err := PresetWeights(preset)
+		if err != nil {
+			return nil, err
+		}
+		weights = w
+	}
+	if path != "" {
+		data, err := readInput(path)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&weights); err != nil {
+			return nil, fmt.Errorf("scoring weights %s: %w", path, err)
+		}
+	}
+	if err := weights.validate(); err != nil {
+		return nil, err
+	}
+	return &weights, nil
+}
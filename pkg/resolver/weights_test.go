@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestListPresets(t *testing.T) {
+	if names := presetNames(); !reflect.DeepEqual(names, []string{"balanced", "consolidation", "cost-first", "performance-first"}) {
+		t.Fatalf("expected the four presets sorted by name, got %v", names)
+	}
+	for _, p := range ListPresets() {
+		if p.Intent == "" || p.Weights.validate() != nil {
+			t.Errorf("%s: expected an intent and valid weights, got %+v", p.Name, p)
+		}
+		sum := 0.0
+		for _, v := range p.Weights.fields() {
+			sum += v
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("%s: expected weights summing to 1, got %f", p.Name, sum)
+		}
+	}
+}
+
+// TestPresetsSelectDistinctly selects for one workload among SKUs each preset should favour:
+// a tight cheap one, a mid-priced one with some room, one with the most room and a large one.
+func TestPresetsSelectDistinctly(t *testing.T) {
+	skus := []AzureInstanceSpec{
+		{Name: "tight", VCpus: 2, MemoryGiB: 16, PricePerHour: 0.15},
+		{Name: "roomy", VCpus: 4, MemoryGiB: 32, PricePerHour: 0.3},
+		{Name: "spacious", VCpus: 16, MemoryGiB: 128, PricePerHour: 0.8},
+		{Name: "large", VCpus: 64, MemoryGiB: 64, PricePerHour: 0.9},
+	}
+	workload := WorkloadProfile{Name: "w", CPURequirements: 2, MemoryRequirements: 16}
+	want := map[string]string{"cost-first": "tight", "balanced": "roomy", "performance-first": "spacious", "consolidation": "large"}
+	for preset, sku := range want {
+		weights, err := PresetWeights(preset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := Config{ScoreVersion: ScoreVersionNormalized, Weights: &weights}
+		if got, _ := selectWithConfig(skus, workload, cfg.forRun()); got.Name != sku {
+			t.Errorf("%s: expected %s, got %s", preset, sku, got.Name)
+		}
+	}
+}
+
+func TestScoringWeightsMatchStrategies(t *testing.T) {
+	vm := AzureInstanceSpec{Name: "Standard_D4s_v5", VCpus: 4, MemoryGiB: 16, StorageGiB: 150, PricePerHour: 0.2, AvailabilityZones: []string{"1"}}
+	workload := WorkloadProfile{CPURequirements: 2, MemoryRequirements: 8, Zone: "1", RequireSpot: true}
+	for _, strategy := range []SelectionStrategy{StrategyGeneralPurpose, StrategyCPUIntensive, StrategyMemoryIntensive, StrategyIOIntensive} {
+		weights := strategyWeights(strategy, workload)
+		cfg := Config{Weights: &weights}
+		if got, want := ScoreInstanceWithConfig(vm, workload, cfg), ScoreInstance(vm, workload, strategy); got != want {
+			t.Errorf("%s: expected the strategy's weights to score %v, got %v", strategy, want, got)
+		}
+	}
+}
+
+func TestLoadScoringWeights(t *testing.T) {
+	if w, err := LoadScoringWeights("", ""); w != nil || err != nil {
+		t.Errorf("expected no weights without a preset or file, got %+v, %v", w, err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "weights.json")
+	if err := os.WriteFile(path, []byte(`{"Cost": 0.6, "Size": 0.05}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadScoringWeights("balanced", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := PresetWeights("balanced")
+	want.Cost, want.Size = 0.6, 0.05
+	if *got != want {
+		t.Errorf("expected the file's fields over the balanced preset, got %+v", *got)
+	}
+	if got, err = LoadScoringWeights("", path); err != nil || *got != (ScoringWeights{Cost: 0.6, Size: 0.05}) {
+		t.Errorf("expected only the file's weights without a preset, got %+v, %v", got, err)
+	}
+
+	if _, err := LoadScoringWeights("fastest", ""); err == nil {
+		t.Error("expected an error for an unknown preset")
+	}
+	for _, bad := range []string{`{"Price": 1}`, `{"Cost": -1}`, `{}`} {
+		if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadScoringWeights("", path); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
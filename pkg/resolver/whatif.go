@@ -0,0 +1,117 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WhatIfReport compares the nodes of a running cluster to how the resolver would have packed
+// their pods (see CompareToActual).
+type WhatIfReport struct {
+	// Actual is the cluster's packing and Simulated the resolver's of the same pods;
+	// Comparison goes from Actual to Simulated, so negative cost deltas are savings.
+	Actual     PackingResult
+	Simulated  PackingResult
+	Comparison PackingComparison
+	// Oversized lists the nodes whose pods the resolver packs onto cheaper VMs, in node order.
+	Oversized []OversizedNode
+	// Pending counts the pods not bound to a node, which are not repacked.
+	Pending int
+	// UnknownNodes are those of the state, whose pods are not repacked.
+	UnknownNodes []UnknownNode
+}
+
+// SavingsPercent returns how much cheaper the simulated packing is than the actual one.
+func (r WhatIfReport) SavingsPercent() float64 {
+	actual := TotalCost(r.Actual.VMs)
+	if actual == 0 {
+		return 0
+	}
+	return -100 * r.Comparison.CostDelta / actual
+}
+
+/*
+OversizedNode is a node whose pods, repacked alone, fit on VMs cheaper than the node. An empty
+node is oversized with no replacement at all.
+*/
+type OversizedNode struct {
+	Name string
+	SKU  string
+	Pods int
+	// CPUUtilization and MemoryUtilization are the pods' requests in percent of the node.
+	CPUUtilization, MemoryUtilization float64
+	// Replacement lists the SKUs the resolver selects for the node's pods.
+	Replacement    []string
+	SavingsPerHour float64
+}
+
+/*
+CompareToActual repacks the pods running on the known nodes of state from scratch with cfg,
+as if the cluster were provisioned anew, and compares the result to the actual nodes: cost and
+SKU mix deltas, and the nodes the resolver considers oversized because their pods alone pack
+onto cheaper VMs. Pending pods and the pods of unknown nodes are left out, so both packings
+hold the same pods.
+*/
+func CompareToActual(state ClusterState, candidates []AzureInstanceSpec, cfg Config) WhatIfReport {
+	r := WhatIfReport{
+		Actual:       PackingResult{VMs: state.VMs()},
+		Pending:      len(state.Pending),
+		UnknownNodes: state.UnknownNodes,
+	}
+	var workloads WorkloadSet
+	for _, n := range state.Nodes {
+		workloads = append(workloads, n.Workloads...)
+	}
+	r.Simulated = BinPackWorkloadsWithConfig(workloads, candidates, cfg)
+	r.Comparison = ComparePackingResults(r.Actual, r.Simulated)
+	for _, n := range state.Nodes {
+		price := n.InstanceType.PricePerHour
+		repacked := BinPackWorkloadsWithConfig(n.Workloads, candidates, cfg)
+		cost := TotalCost(repacked.VMs)
+		if len(repacked.Unpacked) > 0 || cost >= price-1e-9 {
+			continue
+		}
+		o := OversizedNode{Name: n.Name, SKU: n.InstanceType.Name, Pods: len(n.Workloads), SavingsPerHour: price - cost}
+		o.CPUUtilization, o.MemoryUtilization = AverageUtilization([]PackedVM{n.PackedVM})
+		for _, vm := range repacked.VMs {
+			o.Replacement = append(o.Replacement, vm.InstanceType.Name)
+		}
+		r.Oversized = append(r.Oversized, o)
+	}
+	return r
+}
+
+// String formats the report for humans: totals, the SKU mix change, then the oversized nodes.
+func (r WhatIfReport) String() string {
+	var b strings.Builder
+	actual, simulated := TotalCost(r.Actual.VMs), TotalCost(r.Simulated.VMs)
+	fmt.Fprintf(&b, "actual:    %d VMs, %.4f/h\n", len(r.Actual.VMs), actual)
+	fmt.Fprintf(&b, "simulated: %d VMs, %.4f/h (%.1f%% savings)\n", len(r.Simulated.VMs), simulated, r.SavingsPercent())
+	if len(r.Simulated.Unpacked) > 0 {
+		fmt.Fprintf(&b, "%d pods could not be repacked\n", len(r.Simulated.Unpacked))
+	}
+	if len(r.Comparison.SKUCounts) > 0 {
+		b.WriteString("SKU mix (actual -> simulated):\n")
+	}
+	for _, d := range r.Comparison.SKUCounts {
+		fmt.Fprintf(&b, "  %-24s %d -> %d\n", d.SKU, d.A, d.B)
+	}
+	if len(r.Oversized) > 0 {
+		fmt.Fprintf(&b, "%d oversized nodes:\n", len(r.Oversized))
+	}
+	for _, o := range r.Oversized {
+		replacement := "nothing (empty)"
+		if len(o.Replacement) > 0 {
+			replacement = strings.Join(o.Replacement, ", ")
+		}
+		fmt.Fprintf(&b, "  %s (%s, %d pods, CPU %.1f%%, memory %.1f%%): %s saves %.4f/h\n",
+			o.Name, o.SKU, o.Pods, o.CPUUtilization, o.MemoryUtilization, replacement, o.SavingsPerHour)
+	}
+	for _, n := range r.UnknownNodes {
+		fmt.Fprintf(&b, "skipped: %s\n", n)
+	}
+	if r.Pending > 0 {
+		fmt.Fprintf(&b, "skipped: %d pending pods\n", r.Pending)
+	}
+	return b.String()
+}
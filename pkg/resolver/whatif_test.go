@@ -0,0 +1,21 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareToActual_RightSized(t *testing.T) {
+	skus := []AzureInstanceSpec{{Name: "Standard_D4s_v5", Family: "standardDSv5Family", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.192}}
+	state := ClusterState{
+		Nodes:   []ClusterNode{{Name: "n1", PackedVM: PackedVM{InstanceType: skus[0], Workloads: sizedWorkloads(2, 2)}}},
+		Pending: sizedWorkloads(1),
+	}
+	r := CompareToActual(state, skus, Config{})
+	if len(r.Oversized) != 0 || !r.Comparison.Identical() || r.SavingsPercent() != 0 {
+		t.Errorf("expected a full node to match the simulation, got %d oversized and comparison %+v", len(r.Oversized), r.Comparison)
+	}
+	if r.Pending != 1 || !strings.Contains(r.String(), "skipped: 1 pending pods") {
+		t.Errorf("expected the pending pod reported as skipped, got:\n%s", r)
+	}
+}
@@ -0,0 +1,58 @@
+package resolver
+
+// ZoneCount returns the number of distinct availability zones inst is offered in. A SKU
+// without zones (e.g. in a region without availability zones) counts as 0.
+func ZoneCount(inst AzureInstanceSpec) int {
+	seen := make(map[string]bool, len(inst.AvailabilityZones))
+	for _, z := range inst.AvailabilityZones {
+		if z != "" {
+			seen[z] = true
+		}
+	}
+	return len(seen)
+}
+
+// FilterByMinZones rejects instance types offered in fewer zones than workload.MinZones. A SKU
+// offered in one zone is a resilience risk even when the workload does not pin a zone.
+func FilterByMinZones(inst AzureInstanceSpec, workload WorkloadProfile) bool {
+	return workload.MinZones <= 0 || ZoneCount(inst) >= workload.MinZones
+}
+
+// minZonesFilter is FilterByMinZones for a Config-level minimum.
+func minZonesFilter(minZones int) FilterFunc {
+	return func(inst AzureInstanceSpec, _ WorkloadProfile) bool {
+		return ZoneCount(inst) >= minZones
+	}
+}
+
+// ZoneWarning is a provisioned VM whose SKU is offered in fewer zones than required.
+type ZoneWarning struct {
+	VM       int // index into PackingResult.VMs
+	SKU      string
+	Zones    int
+	Required int // the larger of the configured minimum and its workloads' MinZones
+}
+
+/*
+ZoneWarnings lists the VMs of result whose SKU is offered in fewer zones than required: at
+least minZones, and at least the MinZones of every workload on the VM. Selection only filters
+on the workload a VM is provisioned for, so a workload requiring more zones can still be
+co-packed onto a VM with fewer. Reserved VMs are exempt: their capacity is guaranteed in the
+reservation's zone.
+*/
+func ZoneWarnings(result PackingResult, minZones int) []ZoneWarning {
+	var warnings []ZoneWarning
+	for i, vm := range result.VMs {
+		if vm.Reserved {
+			continue
+		}
+		required := minZones
+		for _, w := range vm.Workloads {
+			required = maxInt(required, w.MinZones)
+		}
+		if n := ZoneCount(vm.InstanceType); n < required {
+			warnings = append(warnings, ZoneWarning{VM: i, SKU: vm.InstanceType.Name, Zones: n, Required: required})
+		}
+	}
+	return warnings
+}
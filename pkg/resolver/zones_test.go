@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterByMinZones(t *testing.T) {
+	single := AzureInstanceSpec{Name: "single", AvailabilityZones: []string{"1"}}
+	three := AzureInstanceSpec{Name: "three", AvailabilityZones: []string{"1", "2", "3", "3"}}
+	regional := AzureInstanceSpec{Name: "regional"}
+	for _, tc := range []struct {
+		inst     AzureInstanceSpec
+		minZones int
+		want     bool
+	}{
+		{single, 0, true},
+		{single, 2, false},
+		{three, 2, true},
+		{three, 4, false}, // duplicate zones count once
+		{regional, 0, true},
+		{regional, 1, false},
+	} {
+		if got := FilterByMinZones(tc.inst, WorkloadProfile{MinZones: tc.minZones}); got != tc.want {
+			t.Errorf("%s with MinZones=%d: expected %v, got %v", tc.inst.Name, tc.minZones, tc.want, got)
+		}
+	}
+}
+
+func TestMinZonesSelection(t *testing.T) {
+	candidates := []AzureInstanceSpec{
+		{Name: "cheap-single-zone", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.1, AvailabilityZones: []string{"1"}},
+		{Name: "zonal", VCpus: 4, MemoryGiB: 16, PricePerHour: 0.3, AvailabilityZones: []string{"1", "2", "3"}},
+	}
+	w := WorkloadProfile{CPURequirements: 4, MemoryRequirements: 16}
+	pick := func(w WorkloadProfile, cfg Config) string {
+		result := BinPackWorkloadsWithConfig([]WorkloadProfile{w}, candidates, cfg)
+		if len(result.VMs) != 1 {
+			t.Fatalf("expected one VM, got %+v", result)
+		}
+		return result.VMs[0].InstanceType.Name
+	}
+	if got := pick(w, Config{}); got != "cheap-single-zone" {
+		t.Errorf("expected the single-zone SKU when MinZones is unset, got %s", got)
+	}
+	if got := pick(w, Config{MinZones: 2}); got != "zonal" {
+		t.Errorf("expected Config.MinZones=2 to filter out the single-zone SKU, got %s", got)
+	}
+	strict := w
+	strict.MinZones = 2
+	if got := pick(strict, Config{}); got != "zonal" {
+		t.Errorf("expected workload MinZones=2 to filter out the single-zone SKU, got %s", got)
+	}
+}
+
+func TestZoneWarnings(t *testing.T) {
+	single := AzureInstanceSpec{Name: "single", AvailabilityZones: []string{"1"}}
+	three := AzureInstanceSpec{Name: "three", AvailabilityZones: []string{"1", "2", "3"}}
+	result := PackingResult{VMs: []PackedVM{
+		{InstanceType: single, Workloads: []WorkloadProfile{{}}},
+		{InstanceType: three, Workloads: []WorkloadProfile{{}}},
+		// A workload requiring three zones co-packed onto a two-zone VM.
+		{InstanceType: AzureInstanceSpec{Name: "two", AvailabilityZones: []string{"1", "2"}}, Workloads: []WorkloadProfile{{}, {MinZones: 3}}},
+		{InstanceType: single, Reserved: true},
+	}}
+	if got := ZoneWarnings(result, 0); !reflect.DeepEqual(got, []ZoneWarning{{VM: 2, SKU: "two", Zones: 2, Required: 3}}) {
+		t.Errorf("expected only the co-packed VM without a configured minimum, got %+v", got)
+	}
+	want := []ZoneWarning{{VM: 0, SKU: "single", Zones: 1, Required: 2}, {VM: 2, SKU: "two", Zones: 2, Required: 3}}
+	if got := ZoneWarnings(result, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
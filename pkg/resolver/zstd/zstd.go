@@ -0,0 +1,29 @@
+/*
+Package zstd registers a zstd decompressor with the resolver, so that the trace and workload
+loaders read zstd-compressed files, e.g. trace.csv.zst, without the resolver itself depending
+on a zstd implementation:
+
+	import _ "github.com/Azure/karpenter-provider-azure/pkg/resolver/zstd"
+*/
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+// magic starts every zstd frame.
+var magic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+func init() {
+	resolver.RegisterDecompressor("zstd", magic, func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	})
+}
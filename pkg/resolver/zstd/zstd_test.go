@@ -0,0 +1,41 @@
+package zstd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/resolver"
+)
+
+func TestZstdTraces(t *testing.T) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	for _, source := range []resolver.TraceSource{resolver.TraceGoogle, resolver.TraceAzure, resolver.TraceAlibaba, resolver.TraceCustom} {
+		plain := filepath.Join("..", "testdata", "traces", string(source)+".csv")
+		data, err := os.ReadFile(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := resolver.ConvertTraceFile(plain, source, resolver.ConvertOptions{})
+		if err != nil || len(want) == 0 {
+			t.Fatalf("%s: expected the plain trace to convert, got %d workloads, %v", source, len(want), err)
+		}
+		path := filepath.Join(t.TempDir(), "trace.csv.zst")
+		if err := os.WriteFile(path, enc.EncodeAll(data, nil), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := resolver.ConvertTraceFile(path, source, resolver.ConvertOptions{}); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected the zstd trace to convert like the plain one, got %d workloads, %v", source, len(got), err)
+		}
+		if got, err := resolver.LoadWorkloadsFromTrace(path, source, 100); err != nil || len(got) == 0 {
+			t.Errorf("%s: expected the zstd trace to load, got %d workloads, %v", source, len(got), err)
+		}
+	}
+}
@@ -4,19 +4,27 @@ Fetch Azure VM SKU data and output as JSON for simulation.
 
 Usage:
   python3 scripts/fetch_azure_skus.py > azure_skus.json
+  python3 scripts/fetch_azure_skus.py --currency EUR > azure_skus_eur.json
+
+With --currency the output is {"Currency": ..., "SKUs": [...]} so the simulator
+labels costs correctly; without it, a plain list of SKUs priced in USD.
 """
 
+import argparse
 import json
+import sys
 import requests
 
 # Azure VM sizes API (public, no auth required for this endpoint)
 API = "https://prices.azure.com/api/retail/prices?$filter=serviceName eq 'Virtual Machines' and armRegionName eq 'eastus'"
 
-def fetch_all_skus():
+def fetch_all_skus(currency=None):
     skus = []
     url = API
+    if currency:
+        url += f"&currencyCode='{currency}'"
     while url:
-        print(f"Fetching {url} ...")
+        print(f"Fetching {url} ...", file=sys.stderr)
         resp = requests.get(url)
         data = resp.json()
         for item in data.get("Items", []):
@@ -57,6 +65,13 @@ def to_sim_format(skus):
     return out
 
 if __name__ == "__main__":
-    skus = fetch_all_skus()
+    parser = argparse.ArgumentParser(description=__doc__, formatter_class=argparse.RawDescriptionHelpFormatter)
+    parser.add_argument("--currency", help="ISO 4217 currency code of the retail prices, e.g. EUR (default: USD)")
+    args = parser.parse_args()
+    currency = args.currency.upper() if args.currency else None
+    skus = fetch_all_skus(currency)
     sim_skus = to_sim_format(skus)
-    print(json.dumps(sim_skus, indent=2))
+    if currency:
+        print(json.dumps({"Currency": currency, "SKUs": sim_skus}, indent=2))
+    else:
+        print(json.dumps(sim_skus, indent=2))
@@ -13,7 +13,7 @@ import pandas as pd
 import matplotlib.pyplot as plt
 
 def main(csv_path):
-    df = pd.read_csv(csv_path)
+    df = pd.read_csv(csv_path, comment="#")  # skip the schema line
     strategies = df['Strategy']
     vms = df['VMs Used']
     cost = df['Total Cost']
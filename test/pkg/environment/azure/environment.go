@@ -62,9 +62,9 @@ type Environment struct {
 	AKSManagedClusterClient *containerservice.ManagedClustersClient
 }
 
-func NewEnvironment(t *testing.T) *Environment {
+func NewEnvironment(t *testing.T, opts ...common.Option) *Environment {
 	azureEnv := &Environment{
-		Environment:          common.NewEnvironment(t),
+		Environment:          common.NewEnvironment(t, opts...),
 		SubscriptionID:       lo.Must(os.LookupEnv("AZURE_SUBSCRIPTION_ID")),
 		ClusterName:          lo.Must(os.LookupEnv("AZURE_CLUSTER_NAME")),
 		ClusterResourceGroup: lo.Must(os.LookupEnv("AZURE_RESOURCE_GROUP")),
@@ -23,13 +23,14 @@ import (
 	"os"
 	"strconv"
 	"testing"
-	"time"
 
 	"github.com/awslabs/operatorpkg/object"
 	"github.com/onsi/gomega"
 	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -54,6 +55,10 @@ const (
 
 	NetworkDataplaneCilium = "cilium"
 	NetworkDataplaneAzure  = "azure"
+
+	// ResourceNvidiaGPU is the extended resource NVIDIA GPUs are requested by, and the key of the
+	// taint GPUNodePool puts on its nodes.
+	ResourceNvidiaGPU = corev1.ResourceName("nvidia.com/gpu")
 )
 
 type Environment struct {
@@ -68,29 +73,50 @@ type Environment struct {
 	// Resolved from cluster
 	InClusterController bool
 	NetworkDataplane    string
+	// KubeClusterName is the kubeconfig name of the cluster under test, "" when unknown
+	// (e.g. in-cluster). It is logged when the environment starts and by the Monitor.
+	KubeClusterName string
+
+	// Timeouts are the default Eventually timeout and polling interval (see TimeoutsFromEnv).
+	Timeouts Timeouts
 
 	StartingNodeCount int
 }
 
-func NewEnvironment(t *testing.T) *Environment {
+// NewEnvironment connects to the cluster selected by opts, KubeconfigEnv and KubeContextEnv, or
+// to the default one as controller-runtime resolves it.
+func NewEnvironment(t *testing.T, opts ...Option) *Environment {
 	ctx := TestContextWithLogger(t)
 	ctx, cancel := context.WithCancel(ctx)
-	config := NewConfig()
+	o := resolveOptions(opts...)
+	config, cluster, err := ResolveConfig(o.kubeconfig, o.kubeContext)
+	if err != nil {
+		log.Fatalf("resolving the cluster under test: %v", err)
+	}
+	configureClient(config)
 	client := NewClient(ctx, config)
+	log.Printf("e2e environment targeting cluster %q at %s", cluster, config.Host)
 
 	if val, ok := os.LookupEnv("GIT_REF"); ok {
 		ctx = context.WithValue(ctx, GitRefContextKey, val)
 	}
 
-	gomega.SetDefaultEventuallyTimeout(16 * time.Minute)
-	gomega.SetDefaultEventuallyPollingInterval(1 * time.Second)
+	timeouts, err := TimeoutsFromEnv()
+	if err != nil {
+		log.Fatalf("configuring timeouts: %v", err)
+	}
+	gomega.SetDefaultEventuallyTimeout(timeouts.Eventually)
+	gomega.SetDefaultEventuallyPollingInterval(timeouts.Poll)
 	env := &Environment{
 		Context:    ctx,
 		cancel:     cancel,
 		Config:     config,
 		Client:     client,
 		KubeClient: kubernetes.NewForConfigOrDie(config),
-		Monitor:    NewMonitor(ctx, client),
+		Monitor:    newMonitor(ctx, client, timeouts, cluster),
+		Timeouts:   timeouts,
+
+		KubeClusterName: cluster,
 	}
 	env.InClusterController = env.getInClusterController()
 	env.NetworkDataplane = lo.Ternary(env.IsCilium(), NetworkDataplaneCilium, NetworkDataplaneAzure)
@@ -103,10 +129,15 @@ func (env *Environment) Stop() {
 
 func NewConfig() *rest.Config {
 	config := controllerruntime.GetConfigOrDie()
+	configureClient(config)
+	return config
+}
+
+// configureClient sets the user agent and lifts the rate limits of test clients.
+func configureClient(config *rest.Config) {
 	config.UserAgent = fmt.Sprintf("testing-%s", operator.Version)
 	config.QPS = 1e6
 	config.Burst = 1e6
-	return config
 }
 
 func NewClient(ctx context.Context, config *rest.Config) client.Client {
@@ -194,8 +225,9 @@ func (env *Environment) DefaultNodePool(nodeClass *v1alpha2.AKSNodeClass) *karpv
 // AdaptToClusterConfig modifies NodePool to match the cluster configuration.
 // It has to be applied to any custom node pools constructed by tests;
 // is already applied by default test NodePool constructors.
+// Windows node pools get no Cilium adaptations, as Cilium does not run on Windows nodes.
 func (env *Environment) AdaptToClusterConfig(nodePool *karpv1.NodePool) *karpv1.NodePool {
-	if env.NetworkDataplane == NetworkDataplaneCilium {
+	if env.NetworkDataplane == NetworkDataplaneCilium && !IsWindowsNodePool(nodePool) {
 		// https://karpenter.sh/docs/concepts/nodepools/#cilium-startup-taint
 		nodePool.Spec.Template.Spec.StartupTaints = append(nodePool.Spec.Template.Spec.StartupTaints, corev1.Taint{
 			Key:    "node.cilium.io/agent-not-ready",
@@ -220,3 +252,94 @@ func (env *Environment) ArmNodepool(nodeClass *v1alpha2.AKSNodeClass) *karpv1.No
 		}})
 	return nodePool
 }
+
+// WindowsNodePool returns DefaultNodePool provisioning Windows nodes, tainted so that only
+// pods tolerating WindowsTaint schedule onto them, as AKS recommends for mixed clusters.
+func (env *Environment) WindowsNodePool(nodeClass *v1alpha2.AKSNodeClass) *karpv1.NodePool {
+	nodePool := env.DefaultNodePool(nodeClass)
+	coretest.ReplaceRequirements(nodePool, karpv1.NodeSelectorRequirementWithMinValues{
+		NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelOSStable,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{string(corev1.Windows)},
+		}})
+	// DefaultNodePool adapted the pool as a Linux one; drop what does not apply to Windows
+	nodePool.Spec.Template.Spec.StartupTaints = lo.Reject(nodePool.Spec.Template.Spec.StartupTaints, func(t corev1.Taint, _ int) bool {
+		return t.Key == "node.cilium.io/agent-not-ready"
+	})
+	delete(nodePool.Spec.Template.Labels, "kubernetes.azure.com/ebpf-dataplane")
+	nodePool.Spec.Template.Spec.Taints = append(nodePool.Spec.Template.Spec.Taints, WindowsTaint)
+	return env.AdaptToClusterConfig(nodePool)
+}
+
+// WindowsTaint keeps pods that do not tolerate it off the nodes of WindowsNodePool.
+var WindowsTaint = corev1.Taint{
+	Key:    corev1.LabelOSStable,
+	Value:  string(corev1.Windows),
+	Effect: corev1.TaintEffectNoSchedule,
+}
+
+// IsWindowsNodePool reports whether nodePool requires the windows OS.
+func IsWindowsNodePool(nodePool *karpv1.NodePool) bool {
+	return lo.ContainsBy(nodePool.Spec.Template.Spec.Requirements, func(r karpv1.NodeSelectorRequirementWithMinValues) bool {
+		return r.Key == corev1.LabelOSStable && r.Operator == corev1.NodeSelectorOpIn && lo.Contains(r.Values, string(corev1.Windows))
+	})
+}
+
+// SpotNodePool returns DefaultNodePool provisioning spot capacity only. Consolidation only
+// removes empty nodes, after a minute, so that spot nodes are not replaced while a test
+// observes them, yet nodes emptied by an eviction do not linger.
+func (env *Environment) SpotNodePool(nodeClass *v1alpha2.AKSNodeClass) *karpv1.NodePool {
+	nodePool := env.DefaultNodePool(nodeClass)
+	coretest.ReplaceRequirements(nodePool, karpv1.NodeSelectorRequirementWithMinValues{
+		NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+			Key:      karpv1.CapacityTypeLabelKey,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{karpv1.CapacityTypeSpot},
+		}})
+	nodePool.Spec.Disruption.ConsolidationPolicy = karpv1.ConsolidationPolicyWhenEmpty
+	nodePool.Spec.Disruption.ConsolidateAfter = karpv1.MustParseNillableDuration("1m")
+	return nodePool
+}
+
+// GPUNodePool returns a NodePool provisioning only the GPU SKU family gpuFamily (e.g. "NC", "ND"
+// or "NV"), with nodes tainted so that only pods tolerating nvidia.com/gpu schedule onto them,
+// and a memory limit that leaves room for the large GPU VM sizes.
+func (env *Environment) GPUNodePool(nodeClass *v1alpha2.AKSNodeClass, gpuFamily string) *karpv1.NodePool {
+	nodePool := env.DefaultNodePool(nodeClass)
+	coretest.ReplaceRequirements(nodePool, karpv1.NodeSelectorRequirementWithMinValues{
+		NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+			Key:      v1alpha2.LabelSKUFamily,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{gpuFamily},
+		}})
+	nodePool.Spec.Template.Spec.Taints = append(nodePool.Spec.Template.Spec.Taints, corev1.Taint{
+		Key:    string(ResourceNvidiaGPU),
+		Effect: corev1.TaintEffectNoSchedule,
+		Value:  "true",
+	})
+	nodePool.Spec.Limits[corev1.ResourceMemory] = resource.MustParse("4000Gi")
+	// DefaultNodePool has adapted the pool already; adapting again would duplicate its taints
+	return nodePool
+}
+
+// GPUDeployment returns a Deployment of replicas pods, each requesting gpus NVIDIA GPUs and
+// tolerating the taint of GPUNodePool.
+func GPUDeployment(replicas int32, gpus int64) *appsv1.Deployment {
+	return coretest.Deployment(coretest.DeploymentOptions{
+		Replicas: replicas,
+		PodOptions: coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"app": "gpu-workload"},
+			},
+			ResourceRequirements: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{ResourceNvidiaGPU: *resource.NewQuantity(gpus, resource.DecimalSI)},
+			},
+			Tolerations: []corev1.Toleration{{
+				Key:      string(ResourceNvidiaGPU),
+				Operator: corev1.TolerationOpExists,
+				Effect:   corev1.TaintEffectNoSchedule,
+			}},
+		},
+	})
+}
@@ -0,0 +1,56 @@
+/*
+Portions Copyright (c) Microsoft Corporation.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	"github.com/Azure/karpenter-provider-azure/pkg/test"
+)
+
+func TestAdaptToClusterConfigIsOSAware(t *testing.T) {
+	env := &Environment{NetworkDataplane: NetworkDataplaneCilium}
+	nodeClass := test.AKSNodeClass()
+
+	linux := env.DefaultNodePool(nodeClass)
+	if IsWindowsNodePool(linux) || len(linux.Spec.Template.Spec.StartupTaints) != 1 {
+		t.Errorf("expected a Linux pool with the Cilium startup taint, got %+v", linux.Spec.Template.Spec)
+	}
+
+	windows := env.WindowsNodePool(nodeClass)
+	if !IsWindowsNodePool(windows) {
+		t.Errorf("expected a Windows pool, got requirements %+v", windows.Spec.Template.Spec.Requirements)
+	}
+	if len(windows.Spec.Template.Spec.StartupTaints) != 0 || windows.Spec.Template.Labels["kubernetes.azure.com/ebpf-dataplane"] != "" {
+		t.Errorf("expected no Cilium adaptations on a Windows pool, got %+v", windows.Spec.Template)
+	}
+	if !lo.Contains(windows.Spec.Template.Spec.Taints, WindowsTaint) {
+		t.Errorf("expected the Windows taint, got %v", windows.Spec.Template.Spec.Taints)
+	}
+	if os := lo.Filter(windows.Spec.Template.Spec.Requirements, func(r karpv1.NodeSelectorRequirementWithMinValues, _ int) bool { return r.Key == corev1.LabelOSStable }); len(os) != 1 {
+		t.Errorf("expected one OS requirement, got %+v", os)
+	}
+
+	env.AdaptToClusterConfig(windows)
+	if len(windows.Spec.Template.Spec.StartupTaints) != 0 {
+		t.Errorf("expected adapting a Windows pool again to add no Cilium taint, got %v", windows.Spec.Template.Spec.StartupTaints)
+	}
+}
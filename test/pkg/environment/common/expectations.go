@@ -669,6 +669,42 @@ func (env *Environment) EventuallyExpectTaintedNodeCount(comparator string, coun
 	return lo.ToSlicePtr(nodeList.Items)
 }
 
+// EventuallyExpectSpotNode waits for a spot node to be created and returns it.
+func (env *Environment) EventuallyExpectSpotNode() *corev1.Node {
+	GinkgoHelper()
+	selector := labels.SelectorFromSet(labels.Set{karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeSpot})
+	return env.EventuallyExpectNodeCountWithSelector(">=", 1, selector)[0]
+}
+
+// ExpectCapacityTypeLabel expects node to be labelled with capacityType, e.g. karpv1.CapacityTypeSpot.
+func (env *Environment) ExpectCapacityTypeLabel(node *corev1.Node, capacityType string) {
+	GinkgoHelper()
+	Expect(node.Labels).To(HaveKeyWithValue(karpv1.CapacityTypeLabelKey, capacityType))
+}
+
+// EventuallyExpectDisruptedTaint waits for the nodes to carry the karpenter.sh/disrupted taint,
+// which Karpenter adds before draining a node, e.g. after a spot eviction.
+func (env *Environment) EventuallyExpectDisruptedTaint(nodes ...*corev1.Node) {
+	GinkgoHelper()
+	By(fmt.Sprintf("waiting for %d nodes to be tainted as disrupted", len(nodes)))
+	nodeList := &corev1.NodeList{}
+	Eventually(func(g Gomega) {
+		g.Expect(env.Client.List(env, nodeList, client.MatchingFields{"spec.taints[*].karpenter.sh/disrupted": "true"})).To(Succeed())
+		taintedNodeNames := lo.Map(nodeList.Items, func(n corev1.Node, _ int) string { return n.Name })
+		g.Expect(taintedNodeNames).To(ContainElements(lo.Map(nodes, func(n *corev1.Node, _ int) interface{} { return n.Name })...))
+	}).Should(Succeed())
+}
+
+// ExpectSpotEvictionHandled simulates the eviction of a spot node by deleting it, as the
+// interruption handling does, and expects Karpenter to taint it as disrupted and remove it.
+func (env *Environment) ExpectSpotEvictionHandled(node *corev1.Node) {
+	GinkgoHelper()
+	env.ExpectCapacityTypeLabel(node, karpv1.CapacityTypeSpot)
+	env.ExpectDeleted(node)
+	env.EventuallyExpectDisruptedTaint(node)
+	env.EventuallyExpectNotFound(node)
+}
+
 func (env *Environment) EventuallyExpectNodesUntaintedWithTimeout(timeout time.Duration, nodes ...*corev1.Node) {
 	GinkgoHelper()
 	By(fmt.Sprintf("waiting for %d nodes to be untainted", len(nodes)))
@@ -0,0 +1,100 @@
+/*
+Portions Copyright (c) Microsoft Corporation.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// KubeconfigEnv selects the kubeconfig file of the cluster under test.
+	KubeconfigEnv = "E2E_KUBECONFIG"
+	// KubeContextEnv selects the kubeconfig context of the cluster under test.
+	KubeContextEnv = "E2E_KUBE_CONTEXT"
+)
+
+// Option configures NewEnvironment.
+type Option func(*options)
+
+type options struct {
+	kubeconfig  string
+	kubeContext string
+}
+
+// WithKubeconfig targets the cluster of the kubeconfig file at path, overriding KubeconfigEnv.
+func WithKubeconfig(path string) Option {
+	return func(o *options) { o.kubeconfig = path }
+}
+
+// WithKubeContext targets the cluster of the kubeconfig context name, overriding KubeContextEnv.
+func WithKubeContext(name string) Option {
+	return func(o *options) { o.kubeContext = name }
+}
+
+// resolveOptions applies opts on top of the KubeconfigEnv and KubeContextEnv defaults.
+func resolveOptions(opts ...Option) options {
+	o := options{kubeconfig: os.Getenv(KubeconfigEnv), kubeContext: os.Getenv(KubeContextEnv)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+/*
+ResolveConfig returns the rest.Config of the kubeconfig context kubeContext in the file
+kubeconfig, and the name of the context's cluster. An empty kubeconfig uses the default loading
+rules ($KUBECONFIG, then ~/.kube/config), and an empty kubeContext the current context. With
+neither set, the config is resolved as controller-runtime does, which also covers in-cluster
+runs, and the cluster name is taken from the current context when there is one.
+*/
+func ResolveConfig(kubeconfig, kubeContext string) (*rest.Config, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	if kubeconfig == "" && kubeContext == "" {
+		config, err := controllerruntime.GetConfig()
+		if err != nil {
+			return nil, "", err
+		}
+		return config, clusterName(clientConfig, ""), nil
+	}
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("loading kubeconfig %q context %q: %w", kubeconfig, kubeContext, err)
+	}
+	return config, clusterName(clientConfig, kubeContext), nil
+}
+
+// clusterName returns the cluster of kubeContext, or of the current context, or "".
+func clusterName(clientConfig clientcmd.ClientConfig, kubeContext string) string {
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return ""
+	}
+	if kubeContext == "" {
+		kubeContext = raw.CurrentContext
+	}
+	if c, ok := raw.Contexts[kubeContext]; ok {
+		return c.Cluster
+	}
+	return ""
+}
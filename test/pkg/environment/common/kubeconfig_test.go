@@ -0,0 +1,108 @@
+/*
+Portions Copyright (c) Microsoft Corporation.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: tester
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: tester
+users:
+- name: tester
+  user:
+    token: test-token
+`
+
+func writeKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveConfig(t *testing.T) {
+	path := writeKubeconfig(t)
+	for _, tc := range []struct {
+		name        string
+		kubeContext string
+		wantHost    string
+		wantCluster string
+		wantErr     bool
+	}{
+		{name: "current context", wantHost: "https://dev.example.com", wantCluster: "dev-cluster"},
+		{name: "explicit context", kubeContext: "prod", wantHost: "https://prod.example.com", wantCluster: "prod-cluster"},
+		{name: "unknown context", kubeContext: "staging", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config, cluster, err := ResolveConfig(path, tc.kubeContext)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %s", config.Host)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Host != tc.wantHost || cluster != tc.wantCluster {
+				t.Errorf("expected %s of %s, got %s of %s", tc.wantHost, tc.wantCluster, config.Host, cluster)
+			}
+		})
+	}
+}
+
+func TestResolveConfigDefaultLoadingRules(t *testing.T) {
+	// A context without a kubeconfig file falls back to $KUBECONFIG
+	t.Setenv("KUBECONFIG", writeKubeconfig(t))
+	config, cluster, err := ResolveConfig("", "prod")
+	if err != nil || config.Host != "https://prod.example.com" || cluster != "prod-cluster" {
+		t.Errorf("expected the prod cluster from $KUBECONFIG, got %v, %q, %v", config, cluster, err)
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	t.Setenv(KubeconfigEnv, "/env/kubeconfig")
+	t.Setenv(KubeContextEnv, "env-context")
+	if o := resolveOptions(); o.kubeconfig != "/env/kubeconfig" || o.kubeContext != "env-context" {
+		t.Errorf("expected the environment variables, got %+v", o)
+	}
+	if o := resolveOptions(WithKubeContext("prod")); o.kubeconfig != "/env/kubeconfig" || o.kubeContext != "prod" {
+		t.Errorf("expected the option to override %s only, got %+v", KubeContextEnv, o)
+	}
+}
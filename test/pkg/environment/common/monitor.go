@@ -25,6 +25,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -40,6 +41,10 @@ type Monitor struct {
 	kubeClient client.Client
 
 	mu sync.RWMutex
+	// timeouts bound the retries of failed List calls
+	timeouts Timeouts
+	// clusterName identifies the cluster under test in logs
+	clusterName string
 
 	nodesAtReset map[string]*corev1.Node
 }
@@ -52,15 +57,32 @@ type state struct {
 }
 
 func NewMonitor(ctx context.Context, kubeClient client.Client) *Monitor {
+	return NewMonitorWithTimeouts(ctx, kubeClient, Timeouts{Eventually: DefaultEventuallyTimeout, Poll: DefaultPollInterval})
+}
+
+// NewMonitorWithTimeouts returns a Monitor that retries failed List calls every timeouts.Poll
+// for up to timeouts.Eventually.
+func NewMonitorWithTimeouts(ctx context.Context, kubeClient client.Client, timeouts Timeouts) *Monitor {
+	return newMonitor(ctx, kubeClient, timeouts, "")
+}
+
+func newMonitor(ctx context.Context, kubeClient client.Client, timeouts Timeouts, clusterName string) *Monitor {
 	m := &Monitor{
 		ctx:          ctx,
 		kubeClient:   kubeClient,
+		timeouts:     timeouts,
+		clusterName:  clusterName,
 		nodesAtReset: map[string]*corev1.Node{},
 	}
 	m.Reset()
 	return m
 }
 
+// ClusterName returns the kubeconfig name of the monitored cluster, "" when unknown.
+func (m *Monitor) ClusterName() string {
+	return m.clusterName
+}
+
 // Reset resets the cluster monitor prior to running a test.
 func (m *Monitor) Reset() {
 	m.mu.Lock()
@@ -169,12 +191,12 @@ func (m *Monitor) RunningPodsCount(selector labels.Selector) int {
 
 func (m *Monitor) poll() state {
 	var nodes corev1.NodeList
-	if err := m.kubeClient.List(m.ctx, &nodes); err != nil {
-		log.FromContext(m.ctx).Error(err, "failed listing nodes")
+	if err := m.list(&nodes); err != nil {
+		log.FromContext(m.ctx).Error(err, "failed listing nodes", "cluster", m.clusterName)
 	}
 	var pods corev1.PodList
-	if err := m.kubeClient.List(m.ctx, &pods); err != nil {
-		log.FromContext(m.ctx).Error(err, "failing listing pods")
+	if err := m.list(&pods); err != nil {
+		log.FromContext(m.ctx).Error(err, "failing listing pods", "cluster", m.clusterName)
 	}
 	st := state{
 		nodes:        map[string]*corev1.Node{},
@@ -200,6 +222,16 @@ func (m *Monitor) poll() state {
 	return st
 }
 
+// list lists into obj, retrying failures every poll interval until the Eventually timeout.
+func (m *Monitor) list(obj client.ObjectList) error {
+	var err error
+	_ = wait.PollUntilContextTimeout(m.ctx, m.timeouts.Poll, m.timeouts.Eventually, true, func(ctx context.Context) (bool, error) {
+		err = m.kubeClient.List(ctx, obj)
+		return err == nil, nil
+	})
+	return err
+}
+
 func (m *Monitor) AvgUtilization(resource corev1.ResourceName) float64 {
 	utilization := m.nodeUtilization(resource)
 	sum := 0.0
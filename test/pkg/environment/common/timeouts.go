@@ -0,0 +1,77 @@
+/*
+Portions Copyright (c) Microsoft Corporation.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	. "github.com/onsi/gomega" //nolint:stylecheck
+)
+
+const (
+	// EventuallyTimeoutEnv overrides the default Eventually timeout, e.g. "5m" for smoke suites
+	// or "30m" for slow GPU SKUs.
+	EventuallyTimeoutEnv = "E2E_EVENTUALLY_TIMEOUT"
+	// PollIntervalEnv overrides the default Eventually polling interval.
+	PollIntervalEnv = "E2E_POLL_INTERVAL"
+
+	DefaultEventuallyTimeout = 16 * time.Minute
+	DefaultPollInterval      = 1 * time.Second
+)
+
+// Timeouts are the defaults of Eventually assertions and of the Monitor's retries.
+type Timeouts struct {
+	Eventually time.Duration
+	Poll       time.Duration
+}
+
+// TimeoutsFromEnv reads Timeouts from EventuallyTimeoutEnv and PollIntervalEnv, falling back to
+// DefaultEventuallyTimeout and DefaultPollInterval for unset variables.
+func TimeoutsFromEnv() (Timeouts, error) {
+	t := Timeouts{Eventually: DefaultEventuallyTimeout, Poll: DefaultPollInterval}
+	for name, d := range map[string]*time.Duration{EventuallyTimeoutEnv: &t.Eventually, PollIntervalEnv: &t.Poll} {
+		val, ok := os.LookupEnv(name)
+		if !ok || val == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			return Timeouts{}, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		if parsed <= 0 {
+			return Timeouts{}, fmt.Errorf("%s must be positive, got %s", name, val)
+		}
+		*d = parsed
+	}
+	if t.Poll > t.Eventually {
+		return Timeouts{}, fmt.Errorf("%s (%s) must not exceed %s (%s)", PollIntervalEnv, t.Poll, EventuallyTimeoutEnv, t.Eventually)
+	}
+	return t, nil
+}
+
+// EventuallyWithTimeout is Eventually with a timeout other than the default, polling at the
+// configured interval, e.g. for slow GPU provisioning.
+func (env *Environment) EventuallyWithTimeout(timeout time.Duration, actual interface{}) AsyncAssertion {
+	return Eventually(actual).WithTimeout(timeout).WithPolling(env.Timeouts.Poll)
+}
+
+// EventuallyWithPolling is Eventually with a polling interval other than the default.
+func (env *Environment) EventuallyWithPolling(interval time.Duration, actual interface{}) AsyncAssertion {
+	return Eventually(actual).WithTimeout(env.Timeouts.Eventually).WithPolling(interval)
+}
@@ -0,0 +1,55 @@
+/*
+Portions Copyright (c) Microsoft Corporation.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutsFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		eventually string
+		poll       string
+		want       Timeouts
+		wantErr    bool
+	}{
+		{name: "defaults", want: Timeouts{Eventually: 16 * time.Minute, Poll: time.Second}},
+		{name: "smoke suite", eventually: "5m", poll: "500ms", want: Timeouts{Eventually: 5 * time.Minute, Poll: 500 * time.Millisecond}},
+		{name: "slow GPU SKUs", eventually: "45m", want: Timeouts{Eventually: 45 * time.Minute, Poll: time.Second}},
+		{name: "invalid timeout", eventually: "ten minutes", wantErr: true},
+		{name: "invalid poll interval", poll: "5", wantErr: true},
+		{name: "non-positive timeout", eventually: "0s", wantErr: true},
+		{name: "poll interval above timeout", eventually: "1s", poll: "1m", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EventuallyTimeoutEnv, tc.eventually)
+			t.Setenv(PollIntervalEnv, tc.poll)
+			got, err := TimeoutsFromEnv()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("expected %+v, got %+v (%v)", tc.want, got, err)
+			}
+		})
+	}
+}
@@ -29,11 +29,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
-	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
-	"sigs.k8s.io/karpenter/pkg/test"
-
 	"github.com/Azure/karpenter-provider-azure/pkg/apis/v1alpha2"
 	"github.com/Azure/karpenter-provider-azure/test/pkg/environment/azure"
+	"github.com/Azure/karpenter-provider-azure/test/pkg/environment/common"
 )
 
 var env *azure.Environment
@@ -61,36 +59,9 @@ var _ = Describe("GPU", func() {
 				// already rule out node repair scenario.
 				env.ExpectSettingsOverridden(corev1.EnvVar{Name: "FEATURE_GATES", Value: "NodeRepair=True"})
 			}
-			nodePool := env.DefaultNodePool(nodeClass)
-			test.ReplaceRequirements(nodePool, karpv1.NodeSelectorRequirementWithMinValues{
-				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
-					Key:      v1alpha2.LabelSKUFamily,
-					Operator: corev1.NodeSelectorOpExists,
-				},
-			})
-
-			nodePool.Spec.Limits = karpv1.Limits{
-				corev1.ResourceCPU:                    resource.MustParse("25"),
-				corev1.ResourceName("nvidia.com/gpu"): resource.MustParse("1"),
-			}
-
-			minstPodOptions := test.PodOptions{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "samples-fake-minst",
-					Labels: map[string]string{
-						"app": "samples-tf-mnist-demo",
-					},
-				},
-				ResourceRequirements: corev1.ResourceRequirements{
-					Limits: corev1.ResourceList{
-						"nvidia.com/gpu": resource.MustParse("1"),
-					},
-				},
-			}
-			deployment := test.Deployment(test.DeploymentOptions{
-				Replicas:   1,
-				PodOptions: minstPodOptions,
-			})
+			nodePool := env.GPUNodePool(nodeClass, "NC")
+			nodePool.Spec.Limits[common.ResourceNvidiaGPU] = resource.MustParse("1")
+			deployment := common.GPUDeployment(1, 1)
 
 			devicePlugin := createNVIDIADevicePluginDaemonSet()
 			env.ExpectCreated(nodeClass, nodePool, deployment, devicePlugin)
@@ -100,7 +71,9 @@ var _ = Describe("GPU", func() {
 				labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels),
 				int(*deployment.Spec.Replicas),
 			)
-			env.ExpectCreatedNodeCount("==", int(*deployment.Spec.Replicas))
+			node := env.ExpectCreatedNodeCount("==", int(*deployment.Spec.Replicas))[0]
+			Expect(node.Labels).To(HaveKeyWithValue(v1alpha2.LabelSKUFamily, "NC"))
+			Expect(node.Spec.Taints).To(ContainElement(HaveField("Key", string(common.ResourceNvidiaGPU))))
 		},
 		Entry("should provision one GPU Node and one GPU Pod (AzureLinux)", env.AZLinuxNodeClass()),
 		Entry("should provision one GPU Node and one GPU Pod (Ubuntu2204)", env.DefaultAKSNodeClass()),
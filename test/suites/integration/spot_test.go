@@ -0,0 +1,39 @@
+/*
+Portions Copyright (c) Microsoft Corporation.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/test"
+)
+
+var _ = Describe("Spot", func() {
+	It("should provision a spot node and replace it after an eviction", func() {
+		nodePool = env.SpotNodePool(nodeClass)
+		deployment := test.Deployment(test.DeploymentOptions{Replicas: 1})
+		env.ExpectCreated(nodeClass, nodePool, deployment)
+
+		node := env.EventuallyExpectSpotNode()
+		env.ExpectCapacityTypeLabel(node, karpv1.CapacityTypeSpot)
+
+		env.ExpectSpotEvictionHandled(node)
+		replacement := env.EventuallyExpectSpotNode()
+		env.ExpectCapacityTypeLabel(replacement, karpv1.CapacityTypeSpot)
+	})
+})
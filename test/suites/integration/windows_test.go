@@ -0,0 +1,56 @@
+/*
+Portions Copyright (c) Microsoft Corporation.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/Azure/karpenter-provider-azure/test/pkg/environment/common"
+)
+
+// windowsEnv enables the Windows specs, which need a cluster with Windows node support.
+const windowsEnv = "E2E_WINDOWS"
+
+var _ = Describe("Windows", Label("windows"), func() {
+	BeforeEach(func() {
+		if os.Getenv(windowsEnv) != "true" {
+			Skip(windowsEnv + " is not set to true")
+		}
+	})
+	It("should provision a Windows node for a Windows pod", func() {
+		nodePool = env.WindowsNodePool(nodeClass)
+		pod := test.Pod(test.PodOptions{
+			NodeSelector: map[string]string{corev1.LabelOSStable: string(corev1.Windows)},
+			Tolerations: []corev1.Toleration{{
+				Key:      common.WindowsTaint.Key,
+				Operator: corev1.TolerationOpEqual,
+				Value:    common.WindowsTaint.Value,
+				Effect:   common.WindowsTaint.Effect,
+			}},
+		})
+		env.ExpectCreated(nodeClass, nodePool, pod)
+		env.EventuallyExpectHealthy(pod)
+		node := env.ExpectCreatedNodeCount("==", 1)[0]
+		Expect(node.Labels).To(HaveKeyWithValue(corev1.LabelOSStable, string(corev1.Windows)))
+	})
+})